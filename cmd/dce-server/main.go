@@ -0,0 +1,199 @@
+/*
+The dce-server command serves the whole DCE API from a single process,
+without API Gateway or Lambda.
+
+By default, accounts, leases, history, usage, queues and notifications
+are kept in memory, so the API may be run on a local machine
+or in integration tests:
+
+	go run ./cmd/dce-server -addr 127.0.0.1:8080
+
+With `-backend aws`, the server uses the same DynamoDB tables,
+SQS queues and SNS topics as the API Lambdas, configured from the same
+environment variables (ACCOUNT_DB, LEASE_DB, HISTORY_DB, RESET_REPORTS_DB, USAGE_CACHE_DB, etc.)
+
+Requests are not authenticated, and are handled as admin requests.
+So the server listens on the loopback interface by default,
+and refuses to serve the aws backend on any other address
+unless run with `-allow-remote`.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/Optum/dce/pkg/api/accounts"
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/currency"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/leaserule"
+	"github.com/Optum/dce/pkg/policyprofile"
+	"github.com/Optum/dce/pkg/resetreport"
+	"github.com/Optum/dce/pkg/rolemanager"
+	"github.com/Optum/dce/pkg/usage"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/codebuild"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+const (
+	memoryBackend = "memory"
+	awsBackend    = "aws"
+)
+
+// memoryEnvDefaults are the default configuration
+// used with the in-memory backend
+var memoryEnvDefaults = map[string]string{
+	"RESET_SQS_URL":             "reset-queue",
+	"RESET_DLQ_URL":             "reset-dlq",
+	"RESET_BUILD_NAME":          "reset",
+	"ACCOUNT_CREATED_TOPIC_ARN": "account-created",
+	"ACCOUNT_DELETED_TOPIC_ARN": "account-deleted",
+	"LEASE_ADDED_TOPIC":         "lease-added",
+	"LEASE_EXTENDED_TOPIC":      "lease-extended",
+	"DECOMMISSION_TOPIC":        "lease-removed",
+	"PRINCIPAL_BUDGET_AMOUNT":   "1000",
+	"PRINCIPAL_BUDGET_PERIOD":   "WEEKLY",
+	"MAX_LEASE_BUDGET_AMOUNT":   "1000",
+	"MAX_LEASE_PERIOD":          "604800",
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8080", "Address to listen on")
+	backend := flag.String("backend", memoryBackend, "Backend for accounts, leases, history, usage, queues and notifications: \"memory\" or \"aws\"")
+	accountID := flag.String("account-id", "", "AWS Account ID of the DCE master account")
+	allowRemote := flag.Bool("allow-remote", false, "Allow serving the aws backend on a non-loopback address")
+	flag.Parse()
+
+	// Requests are not authenticated, so anyone who can reach
+	// the server has admin access to the backend
+	if !isLoopback(*addr) {
+		if *backend == awsBackend && !*allowRemote {
+			log.Fatalf("Refusing to serve the %s backend on non-loopback address %s, "+
+				"where unauthenticated requests have admin access to the DCE deployment. "+
+				"Run with -allow-remote to serve it anyway.", *backend, *addr)
+		}
+		log.Printf("WARNING: Serving the DCE API on non-loopback address %s. "+
+			"Requests are NOT authenticated, and are handled as admin requests.", *addr)
+	}
+
+	svc, err := newServices(*backend)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Serving the DCE API on %s, with the %s backend", *addr, *backend)
+	log.Fatal(http.ListenAndServe(*addr, newServer(svc, *accountID)))
+}
+
+// isLoopback returns true if the listen address
+// only accepts connections from the local machine
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newServices creates the backends for the API controllers
+func newServices(backend string) (services, error) {
+	awsSession, err := session.NewSession()
+	if err != nil {
+		return services{}, fmt.Errorf("Failed to create AWS session: %s", err)
+	}
+
+	// Account creation always works against AWS,
+	// to verify and configure IAM roles in the child account
+	svc := services{
+		AWSSession: awsSession,
+		TokenSvc:   common.STS{Client: sts.New(awsSession)},
+		StorageSvc: common.S3{
+			Client:  s3.New(awsSession),
+			Manager: s3manager.NewDownloader(awsSession),
+		},
+		RoleManager: &rolemanager.IAMRoleManager{},
+	}
+
+	var dbSvc db.DBer
+	switch backend {
+	case memoryBackend:
+		setEnvDefaults(memoryEnvDefaults)
+		dbSvc = db.NewMemoryDB(common.GetEnvInt("DEFAULT_LEASE_LENGTH_IN_DAYS", 7))
+		svc.History = history.NewMemory()
+		svc.ResetReports = resetreport.NewMemory()
+		svc.UsageSvc = usage.NewMemory()
+		svc.Queue = common.NewMemoryQueue()
+		svc.SNS = common.NewMemoryNotifier()
+		// Reset previews are recorded, but not run
+		svc.ResetBuild = common.NewMemoryBuilder()
+	case awsBackend:
+		dbSvc, err = db.NewFromEnv()
+		if err != nil {
+			return services{}, fmt.Errorf("Failed to initialize database: %s", err)
+		}
+		svc.History, err = history.NewFromEnv()
+		if err != nil {
+			return services{}, fmt.Errorf("Failed to initialize history service: %s", err)
+		}
+		svc.ResetReports, err = resetreport.NewFromEnv()
+		if err != nil {
+			return services{}, fmt.Errorf("Failed to initialize reset report service: %s", err)
+		}
+		svc.UsageSvc, err = usage.NewFromEnv()
+		if err != nil {
+			return services{}, fmt.Errorf("Failed to initialize usage service: %s", err)
+		}
+		svc.Queue = common.SQSQueue{Client: sqs.New(awsSession)}
+		svc.SNS = &common.SNS{Client: sns.New(awsSession)}
+		svc.ResetBuild = &common.CodeBuild{Client: codebuild.New(awsSession)}
+	default:
+		return services{}, fmt.Errorf("Invalid backend %q: must be %q or %q", backend, memoryBackend, awsBackend)
+	}
+
+	svc.PolicyProfiles, err = policyprofile.NewConfigFromEnv()
+	if err != nil {
+		return services{}, err
+	}
+
+	svc.Rates, err = currency.NewFromEnv()
+	if err != nil {
+		return services{}, err
+	}
+
+	svc.LeaseRules, err = leaserule.NewFromEnv()
+	if err != nil {
+		return services{}, err
+	}
+
+	// Record changes made via the server in the history
+	svc.Dao = history.NewRecorder(dbSvc, svc.History, "dce-server")
+
+	// Reload the accounts configuration, in case env vars were set
+	accounts.InitConfig()
+
+	return svc, nil
+}
+
+// setEnvDefaults sets any of the env vars which are not already set
+func setEnvDefaults(defaults map[string]string) {
+	for key, val := range defaults {
+		if _, ok := os.LookupEnv(key); !ok {
+			os.Setenv(key, val)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path"
+
+	"github.com/Optum/dce/pkg/api"
+	"github.com/Optum/dce/pkg/api/accounts"
+	"github.com/Optum/dce/pkg/api/leaseauth"
+	"github.com/Optum/dce/pkg/api/leases"
+	usageapi "github.com/Optum/dce/pkg/api/usage"
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/currency"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/leaserule"
+	"github.com/Optum/dce/pkg/policyprofile"
+	"github.com/Optum/dce/pkg/resetreport"
+	"github.com/Optum/dce/pkg/rolemanager"
+	"github.com/Optum/dce/pkg/usage"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// services contains the backends used by the API controllers
+type services struct {
+	Dao            db.DBer
+	History        history.Service
+	ResetReports   resetreport.Service
+	Queue          common.Queue
+	SNS            common.Notificationer
+	ResetBuild     common.Builder
+	UsageSvc       usage.Service
+	TokenSvc       common.TokenService
+	StorageSvc     common.Storager
+	RoleManager    rolemanager.RoleManager
+	AWSSession     *session.Session
+	PolicyProfiles *policyprofile.Config
+	Rates          currency.RateProvider
+	LeaseRules     []*leaserule.Rule
+}
+
+// newServer creates an HTTP handler which serves the
+// `/accounts`, `/leases`, `/leases/{id}/auth` and `/usage` endpoints,
+// in the same way as the API Lambda functions.
+//
+// masterAccountID is the AWS Account ID of the DCE master account,
+// which API Gateway would otherwise provide with each request.
+func newServer(svc services, masterAccountID string) http.Handler {
+	// The accounts and usage handlers are configured with package variables
+	accounts.Dao = svc.Dao
+	accounts.History = svc.History
+	accounts.ResetReports = svc.ResetReports
+	accounts.Queue = svc.Queue
+	accounts.SnsSvc = svc.SNS
+	accounts.ResetBuild = svc.ResetBuild
+	accounts.TokenSvc = svc.TokenSvc
+	accounts.StorageSvc = svc.StorageSvc
+	accounts.RoleManager = svc.RoleManager
+	accounts.AWSSession = svc.AWSSession
+	usageapi.UsageSvc = svc.UsageSvc
+	usageapi.AWSSession = svc.AWSSession
+
+	leasesRouter := leases.NewRouter(leases.RouterConfig{
+		Dao:                      svc.Dao,
+		History:                  svc.History,
+		SNS:                      svc.SNS,
+		Queue:                    svc.Queue,
+		UsageSvc:                 svc.UsageSvc,
+		LeaseAddedTopicARN:       common.RequireEnv("LEASE_ADDED_TOPIC"),
+		LeaseExtendedTopicARN:    common.RequireEnv("LEASE_EXTENDED_TOPIC"),
+		AccountDeletedTopicARN:   common.RequireEnv("DECOMMISSION_TOPIC"),
+		ResetQueueURL:            common.RequireEnv("RESET_SQS_URL"),
+		PrincipalBudgetAmount:    common.RequireEnvFloat("PRINCIPAL_BUDGET_AMOUNT"),
+		PrincipalBudgetPeriod:    common.RequireEnv("PRINCIPAL_BUDGET_PERIOD"),
+		MaxLeaseBudgetAmount:     common.RequireEnvFloat("MAX_LEASE_BUDGET_AMOUNT"),
+		MaxLeasePeriod:           common.RequireEnvInt("MAX_LEASE_PERIOD"),
+		DefaultLeaseLengthInDays: common.GetEnvInt("DEFAULT_LEASE_LENGTH_IN_DAYS", 7),
+		PendingLeaseTimeout:      common.GetEnvInt("PENDING_LEASE_TIMEOUT", 86400),
+		PolicyProfiles:           svc.PolicyProfiles,
+		Rates:                    svc.Rates,
+		LeaseRules:               svc.LeaseRules,
+	})
+	// Requests without Cognito credentials are handled as admin requests
+	authRouter := leaseauth.NewRouter(svc.Dao, svc.TokenSvc, &api.UserDetails{})
+
+	accountsHandler := api.HTTPHandler(func(ctx context.Context, req *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		req.RequestContext.AccountID = masterAccountID
+		return accounts.Handler(ctx, *req)
+	})
+
+	leasesHandler := api.HTTPHandler(func(ctx context.Context, req *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		// `POST /leases/{id}/auth` is served by the lease_auth Lambda
+		if path.Base(req.Path) == "auth" && path.Dir(path.Dir(req.Path)) == "/leases" {
+			req.PathParameters["id"] = path.Base(path.Dir(req.Path))
+			return authRouter.Route(ctx, req)
+		}
+		return leasesRouter.Route(ctx, req)
+	})
+
+	usageHandler := api.HTTPHandler(func(ctx context.Context, req *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		return usageapi.Handler(ctx, *req)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/accounts", accountsHandler)
+	mux.Handle("/accounts/", accountsHandler)
+	mux.Handle("/leases", leasesHandler)
+	mux.Handle("/leases/", leasesHandler)
+	mux.Handle("/usage", usageHandler)
+	return mux
+}
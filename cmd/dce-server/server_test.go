@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Optum/dce/pkg/api/accounts"
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/resetreport"
+	"github.com/Optum/dce/pkg/usage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	setEnvDefaults(memoryEnvDefaults)
+	accounts.InitConfig()
+	os.Exit(m.Run())
+}
+
+func TestServer(t *testing.T) {
+	dbSvc := db.NewMemoryDB(7)
+	historySvc := history.NewMemory()
+	snsSvc := common.NewMemoryNotifier()
+	queue := common.NewMemoryQueue()
+	usageSvc := usage.NewMemory()
+	server := httptest.NewServer(newServer(services{
+		Dao:          history.NewRecorder(dbSvc, historySvc, "test"),
+		History:      historySvc,
+		ResetReports: resetreport.NewMemory(),
+		Queue:        queue,
+		SNS:          snsSvc,
+		UsageSvc:     usageSvc,
+	}, "123456789012"))
+	defer server.Close()
+
+	require.Nil(t, dbSvc.PutAccount(db.Account{
+		ID:               "111111111111",
+		AccountStatus:    db.Ready,
+		PrincipalRoleArn: "arn:aws:iam::111111111111:role/DCEPrincipal",
+	}))
+
+	request := func(t *testing.T, method string, path string, body interface{}) (int, []byte) {
+		var reqBody bytes.Buffer
+		if body != nil {
+			require.Nil(t, json.NewEncoder(&reqBody).Encode(body))
+		}
+		req, err := http.NewRequest(method, server.URL+path, &reqBody)
+		require.Nil(t, err)
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		defer res.Body.Close()
+
+		var resBody bytes.Buffer
+		_, err = resBody.ReadFrom(res.Body)
+		require.Nil(t, err)
+		return res.StatusCode, resBody.Bytes()
+	}
+
+	var lease response.LeaseResponse
+
+	t.Run("should create a lease", func(t *testing.T) {
+		status, body := request(t, http.MethodPost, "/leases", map[string]interface{}{
+			"principalId":              "jdoe",
+			"budgetAmount":             100,
+			"budgetCurrency":           "USD",
+			"budgetNotificationEmails": []string{"jdoe@example.com"},
+			"expiresOn":                time.Now().AddDate(0, 0, 2).Unix(),
+		})
+		require.Equal(t, http.StatusCreated, status, string(body))
+		require.Nil(t, json.Unmarshal(body, &lease))
+		require.Equal(t, "111111111111", lease.AccountID)
+		require.Len(t, snsSvc.Messages("lease-added"), 1)
+	})
+
+	t.Run("should get the leased account", func(t *testing.T) {
+		status, body := request(t, http.MethodGet, "/accounts/111111111111", nil)
+		require.Equal(t, http.StatusOK, status, string(body))
+
+		var account response.AccountResponse
+		require.Nil(t, json.Unmarshal(body, &account))
+		require.Equal(t, db.Leased, account.AccountStatus)
+	})
+
+	t.Run("should list leases", func(t *testing.T) {
+		status, body := request(t, http.MethodGet, "/leases?principalId=jdoe", nil)
+		require.Equal(t, http.StatusOK, status, string(body))
+
+		var leases []*response.LeaseResponse
+		require.Nil(t, json.Unmarshal(body, &leases))
+		require.Len(t, leases, 1)
+	})
+
+	t.Run("should get the lease history", func(t *testing.T) {
+		status, body := request(t, http.MethodGet, fmt.Sprintf("/leases/%s/history", lease.ID), nil)
+		require.Equal(t, http.StatusOK, status, string(body))
+
+		var records []*response.HistoryRecordResponse
+		require.Nil(t, json.Unmarshal(body, &records))
+		require.Len(t, records, 1)
+		require.Equal(t, history.Created, records[0].Event)
+	})
+
+	t.Run("should route lease auth requests", func(t *testing.T) {
+		require.Nil(t, dbSvc.PutAccount(db.Account{ID: "222222222222", AccountStatus: db.Leased}))
+		_, err := dbSvc.UpsertLease(db.Lease{
+			ID:          "inactive-lease",
+			AccountID:   "222222222222",
+			PrincipalID: "jdoe",
+			LeaseStatus: db.Inactive,
+			ExpiresOn:   time.Now().Unix(),
+		})
+		require.Nil(t, err)
+
+		// Auth is only available for active leases
+		status, body := request(t, http.MethodPost, "/leases/inactive-lease/auth", nil)
+		require.Equal(t, http.StatusUnauthorized, status, string(body))
+	})
+
+	t.Run("should destroy the lease", func(t *testing.T) {
+		status, body := request(t, http.MethodDelete, "/leases", map[string]interface{}{
+			"principalId": "jdoe",
+			"accountId":   "111111111111",
+		})
+		require.Equal(t, http.StatusOK, status, string(body))
+
+		status, body = request(t, http.MethodGet, "/leases/"+lease.ID, nil)
+		require.Equal(t, http.StatusOK, status, string(body))
+		require.Nil(t, json.Unmarshal(body, &lease))
+		require.Equal(t, db.Inactive, lease.LeaseStatus)
+	})
+
+	t.Run("should get usage", func(t *testing.T) {
+		now := time.Now()
+		startDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		require.Nil(t, usageSvc.PutUsage(usage.Usage{
+			PrincipalID:  "jdoe",
+			AccountID:    "111111111111",
+			StartDate:    startDate.Unix(),
+			EndDate:      startDate.AddDate(0, 0, 1).Unix(),
+			CostAmount:   12,
+			CostCurrency: "USD",
+		}))
+
+		status, body := request(t, http.MethodGet, fmt.Sprintf("/usage?startDate=%d&endDate=%d", startDate.Unix(), now.Unix()), nil)
+		require.Equal(t, http.StatusOK, status, string(body))
+
+		var usageRecords []*response.UsageResponse
+		require.Nil(t, json.Unmarshal(body, &usageRecords))
+		require.Len(t, usageRecords, 1)
+		require.Equal(t, float64(12), usageRecords[0].CostAmount)
+	})
+
+	t.Run("should return 404 for unknown resources", func(t *testing.T) {
+		status, _ := request(t, http.MethodGet, "/unknown", nil)
+		require.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+func TestIsLoopback(t *testing.T) {
+	for addr, expected := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.5:8080":  false,
+		"example.com:80": false,
+		"not-an-address": false,
+	} {
+		require.Equal(t, expected, isLoopback(addr), addr)
+	}
+}
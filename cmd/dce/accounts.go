@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/client"
+	"github.com/Optum/dce/pkg/db"
+)
+
+// accountsAdd adds an account to the account pool
+func accountsAdd(c *cli, args []string) error {
+	fs := newFlagSet("accounts add")
+	adminRoleArn := fs.String("admin-role-arn", "", "ARN of the role assumed by DCE to manage the account (required)")
+	pool := fs.String("pool", "", "Pool to add the account to")
+	labels := stringMapFlag{}
+	fs.Var(labels, "label", "Account label, as key=value. May be repeated.")
+	nukeConfigFile := fs.String("nuke-config", "", "JSON file with aws-nuke config overrides for the account")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireArgs(fs, args, 1, "<account-id>"); err != nil {
+		return err
+	}
+	if *adminRoleArn == "" {
+		return fmt.Errorf("-admin-role-arn is required")
+	}
+	var nukeConfig *db.NukeConfig
+	if *nukeConfigFile != "" {
+		nukeConfig = &db.NukeConfig{}
+		if err := readJSONFile(*nukeConfigFile, nukeConfig); err != nil {
+			return fmt.Errorf("invalid -nuke-config: %s", err)
+		}
+	}
+
+	account, err := c.client.CreateAccount(client.CreateAccountInput{
+		ID:           args[0],
+		AdminRoleArn: *adminRoleArn,
+		Pool:         *pool,
+		Labels:       labels,
+		NukeConfig:   nukeConfig,
+	})
+	if err != nil {
+		return err
+	}
+	return c.printAccounts(account, account)
+}
+
+// accountsList lists accounts
+func accountsList(c *cli, args []string) error {
+	fs := newFlagSet("accounts list")
+	status := fs.String("status", "", "Only list accounts with this status, eg. Ready")
+	pool := fs.String("pool", "", "Only list accounts in this pool")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	accounts, err := c.client.ListAccounts(client.ListAccountsInput{
+		AccountStatus: *status,
+		Pool:          *pool,
+	})
+	if err != nil {
+		return err
+	}
+	return c.printAccounts(accounts, accounts...)
+}
+
+// accountsRemove removes an account from the account pool
+func accountsRemove(c *cli, args []string) error {
+	fs := newFlagSet("accounts remove")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireArgs(fs, args, 1, "<account-id>"); err != nil {
+		return err
+	}
+
+	err = c.client.DeleteAccount(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Account %s removed\n", args[0])
+	return nil
+}
+
+// printAccounts prints v, with a table row for each account
+func (c *cli) printAccounts(v interface{}, accounts ...*response.AccountResponse) error {
+	t := table{header: []string{"ID", "STATUS", "POOL", "LABELS", "ADMIN ROLE ARN", "LAST MODIFIED"}}
+	for _, account := range accounts {
+		t.rows = append(t.rows, []string{
+			account.ID,
+			string(account.AccountStatus),
+			account.Pool,
+			formatLabels(account.Labels),
+			account.AdminRoleArn,
+			formatTime(account.LastModifiedOn),
+		})
+	}
+	return c.print(v, t)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Optum/dce/pkg/api/response"
+)
+
+// deadLettersList lists reset requests in the reset dead-letter queue
+func deadLettersList(c *cli, args []string) error {
+	fs := newFlagSet("deadletters list")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	letters, err := c.client.ListResetDeadLetters()
+	if err != nil {
+		return err
+	}
+	return c.printDeadLetters(letters, letters...)
+}
+
+// deadLettersShow shows a reset request in the reset dead-letter queue
+func deadLettersShow(c *cli, args []string) error {
+	fs := newFlagSet("deadletters show")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireArgs(fs, args, 1, "<id>"); err != nil {
+		return err
+	}
+
+	letter, err := c.client.GetResetDeadLetter(args[0])
+	if err != nil {
+		return err
+	}
+	return c.printDeadLetters(letter, letter)
+}
+
+// deadLettersReplay sends a reset request from the reset
+// dead-letter queue back to the reset queue
+func deadLettersReplay(c *cli, args []string) error {
+	fs := newFlagSet("deadletters replay")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireArgs(fs, args, 1, "<id>"); err != nil {
+		return err
+	}
+
+	letter, err := c.client.ReplayResetDeadLetter(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Reset of account %s sent to the reset queue\n", letter.AccountID)
+	return nil
+}
+
+// printDeadLetters prints v, with a table row for each dead letter
+func (c *cli) printDeadLetters(v interface{}, letters ...*response.ResetDeadLetterResponse) error {
+	t := table{header: []string{"ID", "ACCOUNT ID", "RECEIVES", "DEAD LETTERED ON", "ERROR"}}
+	for _, letter := range letters {
+		t.rows = append(t.rows, []string{
+			letter.ID,
+			letter.AccountID,
+			strconv.Itoa(letter.ReceiveCount),
+			formatTime(letter.DeadLetteredOn),
+			letter.Error,
+		})
+	}
+	return c.print(v, t)
+}
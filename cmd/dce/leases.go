@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/client"
+)
+
+// leasesCreate leases an account to a principal
+func leasesCreate(c *cli, args []string) error {
+	fs := newFlagSet("leases create")
+	principalID := fs.String("principal-id", "", "Principal to lease an account to (required)")
+	budgetAmount := fs.Float64("budget-amount", 0, "Budget for the lease (required)")
+	budgetCurrency := fs.String("budget-currency", "USD", "Currency of the budget")
+	days := fs.Int("days", 0, "Length of the lease in days (default: the API's default lease length)")
+	pool := fs.String("pool", "", "Only lease an account from this pool")
+	waitlist := fs.Bool("waitlist", false, "Add the lease to the waitlist if no accounts are available")
+	emails := stringListFlag{}
+	fs.Var(&emails, "email", "Email address for budget notifications. May be repeated.")
+	labels := stringMapFlag{}
+	fs.Var(labels, "label", "Only lease an account with this label, as key=value. May be repeated.")
+	policyProfile := fs.String("policy-profile", "", "Add the statements of this policy profile to the principal policy")
+	policyStatementsFile := fs.String("policy-statements", "", "JSON file with a list of IAM statements to add to the principal policy")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireArgs(fs, args, 0); err != nil {
+		return err
+	}
+	if *principalID == "" {
+		return fmt.Errorf("-principal-id is required")
+	}
+	if *budgetAmount <= 0 {
+		return fmt.Errorf("-budget-amount is required")
+	}
+
+	input := client.CreateLeaseInput{
+		PrincipalID:              *principalID,
+		BudgetAmount:             *budgetAmount,
+		BudgetCurrency:           *budgetCurrency,
+		BudgetNotificationEmails: emails,
+		Waitlist:                 *waitlist,
+		Pool:                     *pool,
+		Labels:                   labels,
+		PolicyProfile:            *policyProfile,
+	}
+	if *policyStatementsFile != "" {
+		if err := readJSONFile(*policyStatementsFile, &input.PolicyStatements); err != nil {
+			return fmt.Errorf("invalid -policy-statements: %s", err)
+		}
+	}
+	if *days > 0 {
+		input.ExpiresOn = time.Now().AddDate(0, 0, *days).Unix()
+	}
+
+	lease, err := c.client.CreateLease(input)
+	if err != nil {
+		return err
+	}
+	return c.printLeases(lease, lease)
+}
+
+// leasesList lists leases
+func leasesList(c *cli, args []string) error {
+	fs := newFlagSet("leases list")
+	principalID := fs.String("principal-id", "", "Only list leases for this principal")
+	accountID := fs.String("account-id", "", "Only list leases of this account")
+	status := fs.String("status", "", "Only list leases with this status: Active or Inactive")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	leases, err := c.client.ListLeases(client.ListLeasesInput{
+		PrincipalID: *principalID,
+		AccountID:   *accountID,
+		Status:      *status,
+	})
+	if err != nil {
+		return err
+	}
+	return c.printLeases(leases, leases...)
+}
+
+// leasesDestroy ends a lease, given its ID
+// or its principal and account IDs
+func leasesDestroy(c *cli, args []string) error {
+	fs := newFlagSet("leases destroy")
+	principalID := fs.String("principal-id", "", "Principal of the lease, instead of a lease ID")
+	accountID := fs.String("account-id", "", "Account of the lease, instead of a lease ID")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		lease, err := c.client.GetLease(args[0])
+		if err != nil {
+			return err
+		}
+		*principalID = lease.PrincipalID
+		*accountID = lease.AccountID
+	} else if len(args) != 0 || *principalID == "" || *accountID == "" {
+		return fmt.Errorf("Usage: %s <lease-id> | -principal-id <id> -account-id <id>", fs.Name())
+	}
+
+	lease, err := c.client.DestroyLease(*principalID, *accountID)
+	if err != nil {
+		return err
+	}
+	return c.printLeases(lease, lease)
+}
+
+// printLeases prints v, with a table row for each lease
+func (c *cli) printLeases(v interface{}, leases ...*response.LeaseResponse) error {
+	t := table{header: []string{"ID", "PRINCIPAL ID", "ACCOUNT ID", "STATUS", "BUDGET", "EXPIRES ON"}}
+	for _, lease := range leases {
+		t.rows = append(t.rows, []string{
+			lease.ID,
+			lease.PrincipalID,
+			lease.AccountID,
+			string(lease.LeaseStatus),
+			fmt.Sprintf("%.2f %s", lease.BudgetAmount, lease.BudgetCurrency),
+			formatTime(lease.ExpiresOn),
+		})
+	}
+	return c.print(v, t)
+}
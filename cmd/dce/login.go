@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Optum/dce/pkg/api/response"
+)
+
+// leasesLogin gets credentials for the account of a lease,
+// and writes them to an AWS profile or opens the AWS console
+func leasesLogin(c *cli, args []string) error {
+	fs := newFlagSet("leases login")
+	profile := fs.String("profile", "dce", "AWS profile to write the credentials to")
+	openConsole := fs.Bool("open", false, "Open the AWS console in a browser, instead of writing credentials")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireArgs(fs, args, 1, "<lease-id>"); err != nil {
+		return err
+	}
+
+	auth, err := c.client.GetLeaseAuth(args[0])
+	if err != nil {
+		return err
+	}
+
+	if *openConsole {
+		err = openBrowser(auth.ConsoleURL)
+		if err != nil {
+			return fmt.Errorf("Failed to open the AWS console: %s\nConsole URL: %s", err, auth.ConsoleURL)
+		}
+		fmt.Fprintf(c.out, "Opened the AWS console for lease %s\n", args[0])
+		return nil
+	}
+
+	credentialsFile := awsCredentialsFile()
+	err = writeAWSProfile(credentialsFile, *profile, auth)
+	if err != nil {
+		return fmt.Errorf("Failed to write credentials to %s: %s", credentialsFile, err)
+	}
+	fmt.Fprintf(c.out, "Credentials for lease %s written to the %q AWS profile\n", args[0], *profile)
+	return nil
+}
+
+// awsCredentialsFile returns the path of the AWS shared credentials file
+func awsCredentialsFile() string {
+	if path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".aws", "credentials")
+}
+
+// writeAWSProfile writes lease credentials to a profile in an
+// AWS shared credentials file, replacing any existing profile
+// of the same name and keeping all other profiles.
+func writeAWSProfile(path string, profile string, auth *response.LeaseAuthResponse) error {
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var out bytes.Buffer
+	inProfile := false
+	scanner := bufio.NewScanner(bytes.NewReader(existing))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			inProfile = strings.TrimSpace(trimmed[1:len(trimmed)-1]) == profile
+		}
+		if !inProfile {
+			out.WriteString(line + "\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n\n")) {
+		out.WriteString("\n")
+	}
+	fmt.Fprintf(&out, "[%s]\n", profile)
+	fmt.Fprintf(&out, "aws_access_key_id = %s\n", auth.AccessKeyID)
+	fmt.Fprintf(&out, "aws_secret_access_key = %s\n", auth.SecretAccessKey)
+	fmt.Fprintf(&out, "aws_session_token = %s\n", auth.SessionToken)
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, out.Bytes(), 0600)
+}
+
+// openBrowser opens a URL in the default browser
+var openBrowser = func(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
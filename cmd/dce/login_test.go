@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAWSProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dce-credentials")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	auth := &response.LeaseAuthResponse{
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "SECRET",
+		SessionToken:    "TOKEN",
+	}
+
+	t.Run("should create the credentials file", func(t *testing.T) {
+		path := filepath.Join(dir, "new", "credentials")
+		require.Nil(t, writeAWSProfile(path, "dce", auth))
+
+		out, err := ioutil.ReadFile(path)
+		require.Nil(t, err)
+		require.Equal(t, "[dce]\n"+
+			"aws_access_key_id = AKID\n"+
+			"aws_secret_access_key = SECRET\n"+
+			"aws_session_token = TOKEN\n", string(out))
+
+		info, err := os.Stat(path)
+		require.Nil(t, err)
+		require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+
+	t.Run("should replace the profile and keep other profiles", func(t *testing.T) {
+		path := filepath.Join(dir, "credentials")
+		require.Nil(t, ioutil.WriteFile(path, []byte("[default]\n"+
+			"aws_access_key_id = DEFAULT\n"+
+			"\n"+
+			"[dce]\n"+
+			"aws_access_key_id = OLD\n"+
+			"aws_secret_access_key = OLD\n"+
+			"\n"+
+			"[other]\n"+
+			"aws_access_key_id = OTHER\n"), 0600))
+
+		require.Nil(t, writeAWSProfile(path, "dce", auth))
+
+		out, err := ioutil.ReadFile(path)
+		require.Nil(t, err)
+		require.Equal(t, "[default]\n"+
+			"aws_access_key_id = DEFAULT\n"+
+			"\n"+
+			"[other]\n"+
+			"aws_access_key_id = OTHER\n"+
+			"\n"+
+			"[dce]\n"+
+			"aws_access_key_id = AKID\n"+
+			"aws_secret_access_key = SECRET\n"+
+			"aws_session_token = TOKEN\n", string(out))
+	})
+}
@@ -0,0 +1,234 @@
+/*
+The dce command is an admin client for the DCE API.
+
+Usage:
+
+	dce [flags] <command> [command flags] [args]
+
+Commands:
+
+	accounts add <id> -admin-role-arn <arn>   Add an account to the account pool
+	accounts list                            List accounts
+	accounts remove <id>                     Remove an account from the account pool
+	accounts reset <id>                      Reset an account. Use -dry-run to preview
+	                                         the resources a reset would delete, and
+	                                         -wait to wait for the preview to complete
+	accounts resets <id> [<run-id>]          List the resets of an account, or show
+	                                         the resources of a reset
+	leases create -principal-id <id>         Lease an account to a principal
+	leases list                              List leases
+	leases destroy <lease-id>                End a lease
+	leases login <lease-id>                  Write lease credentials to an AWS profile,
+	                                         or open the AWS console
+	usage show                               Show account usage
+	deadletters list                         List failed reset requests in the
+	                                         reset dead-letter queue
+	deadletters show <id>                    Show a failed reset request
+	deadletters replay <id>                  Send a failed reset request back to the
+	                                         reset queue
+
+The API URL is set with the `-api-url` flag or the DCE_API_URL env var.
+Requests are signed with the default AWS credentials, as required by
+API Gateway. Use `-unsigned` for APIs without IAM auth, such as dce-server.
+
+Results are printed as a table, or as JSON or YAML with `-output`.
+*/
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Optum/dce/pkg/client"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// cli runs commands against the DCE API
+type cli struct {
+	client *client.Client
+	out    io.Writer
+	// output is the output format: "table", "json" or "yaml"
+	output string
+}
+
+// command is a `dce` subcommand, eg. "leases create"
+type command struct {
+	name        string
+	description string
+	run         func(c *cli, args []string) error
+}
+
+var commands = []command{
+	{"accounts add", "Add an account to the account pool", accountsAdd},
+	{"accounts list", "List accounts", accountsList},
+	{"accounts remove", "Remove an account from the account pool", accountsRemove},
+	{"accounts reset", "Reset an account, or preview the resources a reset would delete", accountsReset},
+	{"accounts resets", "List the resets of an account, or show the resources of a reset", accountsResets},
+	{"leases create", "Lease an account to a principal", leasesCreate},
+	{"leases list", "List leases", leasesList},
+	{"leases destroy", "End a lease", leasesDestroy},
+	{"leases login", "Write lease credentials to an AWS profile, or open the AWS console", leasesLogin},
+	{"usage show", "Show account usage", usageShow},
+	{"deadletters list", "List failed reset requests in the reset dead-letter queue", deadLettersList},
+	{"deadletters show", "Show a failed reset request", deadLettersShow},
+	{"deadletters replay", "Send a failed reset request back to the reset queue", deadLettersReplay},
+}
+
+func main() {
+	apiURL := flag.String("api-url", os.Getenv("DCE_API_URL"), "URL of the DCE API (default $DCE_API_URL)")
+	output := flag.String("output", tableOutput, "Output format: table, json or yaml")
+	unsigned := flag.Bool("unsigned", false, "Send requests without signing them with AWS credentials")
+	flag.Usage = func() {
+		printUsage(flag.CommandLine.Output())
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *apiURL == "" {
+		fmt.Fprintln(os.Stderr, "The DCE API URL must be set with -api-url or DCE_API_URL")
+		os.Exit(2)
+	}
+
+	c := &cli{
+		client: client.New(*apiURL),
+		out:    os.Stdout,
+		output: *output,
+	}
+
+	if !*unsigned {
+		awsSession, err := session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create AWS session: %s\n", err)
+			os.Exit(1)
+		}
+		c.client.Credentials = awsSession.Config.Credentials
+		if awsSession.Config.Region != nil {
+			c.client.Region = *awsSession.Config.Region
+		}
+	}
+
+	err := c.run(flag.Args())
+	if err == errUsage {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// errUsage is returned for unknown commands
+var errUsage = errors.New("unknown command")
+
+// run runs the command named by the first two args
+func (c *cli) run(args []string) error {
+	switch c.output {
+	case tableOutput, jsonOutput, yamlOutput:
+	default:
+		return fmt.Errorf("Invalid output format %q: must be %s, %s or %s", c.output, tableOutput, jsonOutput, yamlOutput)
+	}
+
+	if len(args) < 2 {
+		return errUsage
+	}
+	name := args[0] + " " + args[1]
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(c, args[2:])
+		}
+	}
+	return errUsage
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: dce [flags] <command> [command flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-18s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintf(w, "\nRun `dce <command> -h` for command flags.\n\nFlags:\n")
+}
+
+// newFlagSet creates the flags for a command.
+// Errors are returned rather than exiting, so they may be tested.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("dce "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parseArgs parses command flags which may come before or after
+// positional args, and returns the positional args
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// requireArgs checks that a command has exactly n positional args
+func requireArgs(fs *flag.FlagSet, args []string, n int, names ...string) error {
+	if len(args) != n {
+		return fmt.Errorf("Usage: %s %s [flags]", fs.Name(), strings.Join(names, " "))
+	}
+	return nil
+}
+
+// stringMapFlag is a repeatable `key=value` flag, eg. for labels
+type stringMapFlag map[string]string
+
+func (f stringMapFlag) String() string {
+	pairs := []string{}
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f stringMapFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	f[parts[0]] = parts[1]
+	return nil
+}
+
+// stringListFlag is a repeatable flag, eg. for email addresses
+type stringListFlag []string
+
+func (f *stringListFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// readJSONFile decodes the JSON file at path into out
+func readJSONFile(path string, out interface{}) error {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/client"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCLI(t *testing.T) {
+	lease := response.LeaseResponse{
+		ID:             "lease-1",
+		PrincipalID:    "jdoe",
+		AccountID:      "123456789012",
+		LeaseStatus:    db.Active,
+		BudgetAmount:   100,
+		BudgetCurrency: "USD",
+		ExpiresOn:      1570000000,
+	}
+
+	var requests []string
+	var requestBodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		requestBodies = append(requestBodies, string(body))
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /leases":
+			json.NewEncoder(w).Encode([]response.LeaseResponse{lease})
+		case "GET /leases/lease-1":
+			json.NewEncoder(w).Encode(lease)
+		case "DELETE /leases":
+			json.NewEncoder(w).Encode(lease)
+		case "POST /leases":
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(lease)
+		case "POST /accounts":
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(response.AccountResponse{ID: "123456789012", AccountStatus: db.NotReady})
+		case "DELETE /accounts/123456789012":
+			w.WriteHeader(http.StatusNoContent)
+		case "POST /accounts/123456789012/reset":
+			w.WriteHeader(http.StatusAccepted)
+			if r.URL.Query().Get("dryRun") == "true" {
+				json.NewEncoder(w).Encode(response.ResetReportResponse{AccountID: "123456789012", RunID: "run-1", Outcome: "Running", DryRun: true})
+			} else {
+				json.NewEncoder(w).Encode(response.AccountResponse{ID: "123456789012", AccountStatus: db.NotReady})
+			}
+		case "GET /accounts/123456789012/resets/run-1":
+			json.NewEncoder(w).Encode(response.ResetReportResponse{
+				AccountID:         "123456789012",
+				RunID:             "run-1",
+				Outcome:           "Succeeded",
+				DryRun:            true,
+				ResourcesFound:    []string{"us-east-1 - EC2Instance - i-1"},
+				ResourcesFiltered: []string{"global - IAMRole - DCEAdmin"},
+			})
+		case "POST /accounts/resets/deadletters/msg-1/replay":
+			json.NewEncoder(w).Encode(response.ResetDeadLetterResponse{ID: "msg-1", AccountID: "123456789012"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(response.CreateErrorResponse("NotFound", "The requested resource could not be found."))
+		}
+	}))
+	defer server.Close()
+
+	run := func(t *testing.T, output string, args ...string) (string, error) {
+		requests = nil
+		requestBodies = nil
+		var out bytes.Buffer
+		c := &cli{client: client.New(server.URL), out: &out, output: output}
+		err := c.run(args)
+		return out.String(), err
+	}
+
+	t.Run("should print leases as a table", func(t *testing.T) {
+		out, err := run(t, tableOutput, "leases", "list", "-principal-id", "jdoe")
+		require.Nil(t, err)
+		require.Equal(t, []string{"GET /leases?principalId=jdoe"}, requests)
+		require.Equal(t, ""+
+			"ID       PRINCIPAL ID  ACCOUNT ID    STATUS  BUDGET      EXPIRES ON\n"+
+			"lease-1  jdoe          123456789012  Active  100.00 USD  2019-10-02T07:06:40Z\n", out)
+	})
+
+	t.Run("should print leases as JSON", func(t *testing.T) {
+		out, err := run(t, jsonOutput, "leases", "list")
+		require.Nil(t, err)
+
+		var leases []response.LeaseResponse
+		require.Nil(t, json.Unmarshal([]byte(out), &leases))
+		require.Equal(t, []response.LeaseResponse{lease}, leases)
+	})
+
+	t.Run("should print leases as YAML, with API field names", func(t *testing.T) {
+		out, err := run(t, yamlOutput, "leases", "list")
+		require.Nil(t, err)
+		require.Contains(t, out, "- accountId: \"123456789012\"\n")
+		require.Contains(t, out, "  budgetAmount: 100\n")
+		require.Contains(t, out, "  expiresOn: 1570000000\n")
+		require.Contains(t, out, "  leaseStatus: Active\n")
+	})
+
+	t.Run("should destroy a lease by ID", func(t *testing.T) {
+		_, err := run(t, tableOutput, "leases", "destroy", "lease-1")
+		require.Nil(t, err)
+		require.Equal(t, []string{"GET /leases/lease-1", "DELETE /leases"}, requests)
+		require.JSONEq(t, `{"principalId": "jdoe", "accountId": "123456789012"}`, requestBodies[1])
+	})
+
+	t.Run("should create a lease with a policy profile and statements", func(t *testing.T) {
+		statements, err := ioutil.TempFile("", "policy-statements")
+		require.Nil(t, err)
+		defer os.Remove(statements.Name())
+		_, err = statements.WriteString(`[{"Effect": "Allow", "Action": "glue:*", "Resource": "*"}]`)
+		require.Nil(t, err)
+		require.Nil(t, statements.Close())
+
+		_, err = run(t, tableOutput, "leases", "create", "-principal-id", "jdoe", "-budget-amount", "100",
+			"-policy-profile", "analytics", "-policy-statements", statements.Name())
+		require.Nil(t, err)
+		require.Equal(t, []string{"POST /leases"}, requests)
+		require.JSONEq(t, `{
+			"principalId": "jdoe",
+			"budgetAmount": 100,
+			"budgetCurrency": "USD",
+			"budgetNotificationEmails": [],
+			"policyProfile": "analytics",
+			"policyStatements": [{"Effect": "Allow", "Action": "glue:*", "Resource": "*"}]
+		}`, requestBodies[0])
+	})
+
+	t.Run("should accept flags after args", func(t *testing.T) {
+		_, err := run(t, tableOutput, "accounts", "add", "123456789012", "-label", "team=a")
+		require.EqualError(t, err, "-admin-role-arn is required")
+	})
+
+	t.Run("should add an account with nuke config overrides", func(t *testing.T) {
+		nukeConfig, err := ioutil.TempFile("", "nuke-config")
+		require.Nil(t, err)
+		defer os.Remove(nukeConfig.Name())
+		_, err = nukeConfig.WriteString(`{"excludeResourceTypes": ["Route53HostedZone"]}`)
+		require.Nil(t, err)
+		require.Nil(t, nukeConfig.Close())
+
+		_, err = run(t, tableOutput, "accounts", "add", "123456789012",
+			"-admin-role-arn", "arn:aws:iam::123456789012:role/DCEAdmin",
+			"-nuke-config", nukeConfig.Name())
+		require.Nil(t, err)
+		require.Equal(t, []string{"POST /accounts"}, requests)
+		require.JSONEq(t, `{
+			"id": "123456789012",
+			"adminRoleArn": "arn:aws:iam::123456789012:role/DCEAdmin",
+			"nukeConfig": {"excludeResourceTypes": ["Route53HostedZone"]}
+		}`, requestBodies[0])
+	})
+
+	t.Run("should remove an account", func(t *testing.T) {
+		out, err := run(t, tableOutput, "accounts", "remove", "123456789012")
+		require.Nil(t, err)
+		require.Equal(t, "Account 123456789012 removed\n", out)
+	})
+
+	t.Run("should preview an account reset", func(t *testing.T) {
+		out, err := run(t, tableOutput, "accounts", "reset", "123456789012", "-dry-run")
+		require.Nil(t, err)
+		require.Equal(t, []string{"POST /accounts/123456789012/reset?dryRun=true"}, requests)
+		require.Equal(t, ""+
+			"Started reset preview run-1 for account 123456789012\n"+
+			"The preview has not listed any resources yet. Once its outcome is no longer Running,\n"+
+			"run `dce accounts resets 123456789012 run-1` for the resources to be deleted\n", out)
+	})
+
+	t.Run("should wait for an account reset preview", func(t *testing.T) {
+		out, err := run(t, tableOutput, "accounts", "reset", "123456789012", "-dry-run", "-wait", "-poll-interval", "1ms")
+		require.Nil(t, err)
+		require.Equal(t, []string{
+			"POST /accounts/123456789012/reset?dryRun=true",
+			"GET /accounts/123456789012/resets/run-1",
+		}, requests)
+		require.Equal(t, ""+
+			"RESOURCE                       STATE\n"+
+			"us-east-1 - EC2Instance - i-1  Found\n"+
+			"global - IAMRole - DCEAdmin    Filtered\n", out)
+	})
+
+	t.Run("should reset an account", func(t *testing.T) {
+		out, err := run(t, tableOutput, "accounts", "reset", "123456789012", "-force")
+		require.Nil(t, err)
+		require.Equal(t, []string{"POST /accounts/123456789012/reset?force=true"}, requests)
+		require.Contains(t, out, "123456789012  NotReady")
+	})
+
+	t.Run("should show the resources of a reset", func(t *testing.T) {
+		out, err := run(t, tableOutput, "accounts", "resets", "123456789012", "run-1")
+		require.Nil(t, err)
+		require.Equal(t, ""+
+			"RESOURCE                       STATE\n"+
+			"us-east-1 - EC2Instance - i-1  Found\n"+
+			"global - IAMRole - DCEAdmin    Filtered\n", out)
+	})
+
+	t.Run("should replay a dead-lettered reset", func(t *testing.T) {
+		out, err := run(t, tableOutput, "deadletters", "replay", "msg-1")
+		require.Nil(t, err)
+		require.Equal(t, []string{"POST /accounts/resets/deadletters/msg-1/replay"}, requests)
+		require.Equal(t, "Reset of account 123456789012 sent to the reset queue\n", out)
+	})
+
+	t.Run("should return API errors", func(t *testing.T) {
+		_, err := run(t, tableOutput, "accounts", "remove", "000000000000")
+		require.EqualError(t, err, "DCE API request failed with status 404: NotFound: The requested resource could not be found.")
+	})
+
+	t.Run("should reject unknown commands and output formats", func(t *testing.T) {
+		_, err := run(t, tableOutput, "leases", "unknown")
+		require.Equal(t, errUsage, err)
+
+		_, err = run(t, "xml", "leases", "list")
+		require.EqualError(t, err, "Invalid output format \"xml\": must be table, json or yaml")
+	})
+}
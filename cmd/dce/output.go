@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	tableOutput = "table"
+	jsonOutput  = "json"
+	yamlOutput  = "yaml"
+)
+
+// table is the table output of a command
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// print writes the result of a command in the output format.
+// JSON and YAML output use the API field names.
+func (c *cli) print(v interface{}, t table) error {
+	switch c.output {
+	case jsonOutput:
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(c.out, string(out))
+		return err
+	case yamlOutput:
+		out, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = c.out.Write(out)
+		return err
+	default:
+		w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(t.header, "\t"))
+		for _, row := range t.rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return w.Flush()
+	}
+}
+
+// toYAML converts v to YAML, using its JSON field names
+func toYAML(v interface{}) ([]byte, error) {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj interface{}
+	dec := json.NewDecoder(bytes.NewReader(jsonBytes))
+	dec.UseNumber()
+	err = dec.Decode(&obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(yamlNumbers(obj))
+}
+
+// yamlNumbers replaces JSON numbers with ints or floats,
+// so that they are not quoted in YAML
+func yamlNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = yamlNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = yamlNumbers(item)
+		}
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+// formatTime formats an epoch timestamp for table output
+func formatTime(epoch int64) string {
+	if epoch == 0 {
+		return ""
+	}
+	return time.Unix(epoch, 0).UTC().Format(time.RFC3339)
+}
+
+// formatLabels formats labels for table output
+func formatLabels(labels map[string]string) string {
+	return stringMapFlag(labels).String()
+}
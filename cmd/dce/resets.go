@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/client"
+	"github.com/Optum/dce/pkg/resetreport"
+)
+
+// accountsReset resets an account, or previews the reset.
+//
+// Previews run asynchronously: the API returns the preview's report with
+// the Running outcome, and no resources. The resources are listed once
+// the report has a Succeeded or Failed outcome, so with -wait,
+// the report is polled until the preview completes.
+func accountsReset(c *cli, args []string) error {
+	fs := newFlagSet("accounts reset")
+	dryRun := fs.Bool("dry-run", false, "List the resources which a reset would delete, without deleting them")
+	wait := fs.Bool("wait", false, "With -dry-run, wait for the preview to complete, and list its resources")
+	pollInterval := fs.Duration("poll-interval", 15*time.Second, "How often to check if the preview completed, with -wait")
+	force := fs.Bool("force", false, "End the lease of a Leased account, to reset it")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireArgs(fs, args, 1, "<account-id>"); err != nil {
+		return err
+	}
+	if !*dryRun {
+		account, err := c.client.ResetAccount(args[0], client.ResetAccountInput{Force: *force})
+		if err != nil {
+			return err
+		}
+		return c.printAccounts(account, account)
+	}
+
+	report, err := c.client.PreviewAccountReset(args[0])
+	if err != nil {
+		return err
+	}
+	if *wait {
+		for report.Outcome == resetreport.Running {
+			time.Sleep(*pollInterval)
+			report, err = c.client.GetAccountReset(report.AccountID, report.RunID)
+			if err != nil {
+				return err
+			}
+		}
+		if report.Outcome == resetreport.Failed {
+			return fmt.Errorf("reset preview %s for account %s failed: %s", report.RunID, report.AccountID, report.Error)
+		}
+		return c.printResetResources(report)
+	}
+	if c.output != tableOutput {
+		return c.print(report, table{})
+	}
+	fmt.Fprintf(c.out, "Started reset preview %s for account %s\n", report.RunID, report.AccountID)
+	fmt.Fprintf(c.out, "The preview has not listed any resources yet. Once its outcome is no longer Running,\n")
+	fmt.Fprintf(c.out, "run `dce accounts resets %s %s` for the resources to be deleted\n", report.AccountID, report.RunID)
+	return nil
+}
+
+// accountsResets lists the resets of an account,
+// or shows the resources of a single reset
+func accountsResets(c *cli, args []string) error {
+	fs := newFlagSet("accounts resets")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 2 {
+		report, err := c.client.GetAccountReset(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		return c.printResetResources(report)
+	}
+	if err := requireArgs(fs, args, 1, "<account-id>", "[<run-id>]"); err != nil {
+		return err
+	}
+
+	reports, err := c.client.ListAccountResets(args[0])
+	if err != nil {
+		return err
+	}
+	return c.printResets(reports, reports...)
+}
+
+// printResets prints v, with a table row for each reset report
+func (c *cli) printResets(v interface{}, reports ...*response.ResetReportResponse) error {
+	t := table{header: []string{"RUN ID", "OUTCOME", "DRY RUN", "STARTED ON", "FOUND", "DELETED", "FAILED", "ERROR"}}
+	for _, report := range reports {
+		t.rows = append(t.rows, []string{
+			report.RunID,
+			string(report.Outcome),
+			strconv.FormatBool(report.DryRun),
+			formatTime(report.StartedOn),
+			strconv.Itoa(report.ResourceCounts.Found),
+			strconv.Itoa(report.ResourceCounts.Deleted),
+			strconv.Itoa(report.ResourceCounts.Failed),
+			report.Error,
+		})
+	}
+	return c.print(v, t)
+}
+
+// printResetResources prints the report, with a table row for each
+// resource. Resources which were deleted (or failed to be deleted)
+// are also listed as found, so only their final state is shown.
+func (c *cli) printResetResources(report *response.ResetReportResponse) error {
+	states := map[string]string{}
+	for _, resource := range report.ResourcesFound {
+		states[resource] = "Found"
+	}
+	for _, resource := range report.ResourcesFiltered {
+		states[resource] = "Filtered"
+	}
+	for _, resource := range report.ResourcesDeleted {
+		states[resource] = "Deleted"
+	}
+	for _, resource := range report.ResourcesFailed {
+		states[resource] = "Failed"
+	}
+
+	t := table{header: []string{"RESOURCE", "STATE"}}
+	for _, list := range [][]string{report.ResourcesFound, report.ResourcesFiltered, report.ResourcesDeleted, report.ResourcesFailed} {
+		for _, resource := range list {
+			if state, ok := states[resource]; ok {
+				t.rows = append(t.rows, []string{resource, state})
+				delete(states, resource)
+			}
+		}
+	}
+	return c.print(report, t)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Optum/dce/pkg/client"
+)
+
+// dateLayout is the format of the usage date flags
+const dateLayout = "2006-01-02"
+
+// usageShow shows account usage
+func usageShow(c *cli, args []string) error {
+	now := time.Now().UTC()
+	fs := newFlagSet("usage show")
+	startDate := fs.String("start-date", now.AddDate(0, 0, -7).Format(dateLayout), "Start date, as YYYY-MM-DD")
+	endDate := fs.String("end-date", now.Format(dateLayout), "End date, as YYYY-MM-DD. Ignored with -principal-id.")
+	principalID := fs.String("principal-id", "", "Only show usage for this principal, from the start date")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requireArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	start, err := time.Parse(dateLayout, *startDate)
+	if err != nil {
+		return fmt.Errorf("Invalid -start-date %q: expected YYYY-MM-DD", *startDate)
+	}
+	end, err := time.Parse(dateLayout, *endDate)
+	if err != nil {
+		return fmt.Errorf("Invalid -end-date %q: expected YYYY-MM-DD", *endDate)
+	}
+
+	usage, err := c.client.GetUsage(client.GetUsageInput{
+		StartDate: start,
+		// Include usage for the whole of the end date
+		EndDate:     end.AddDate(0, 0, 1).Add(-time.Second),
+		PrincipalID: *principalID,
+	})
+	if err != nil {
+		return err
+	}
+
+	t := table{header: []string{"PRINCIPAL ID", "ACCOUNT ID", "START DATE", "END DATE", "COST"}}
+	for _, u := range usage {
+		cost := fmt.Sprintf("%.2f %s", u.CostAmount, u.CostCurrency)
+		// Show the cost in the lease's budget currency, if it differs
+		if u.ConvertedCostCurrency != "" && u.ConvertedCostCurrency != u.CostCurrency {
+			cost += fmt.Sprintf(" (%.2f %s)", u.ConvertedCostAmount, u.ConvertedCostCurrency)
+		}
+		t.rows = append(t.rows, []string{
+			u.PrincipalID,
+			u.AccountID,
+			formatTime(u.StartDate),
+			formatTime(u.EndDate),
+			cost,
+		})
+	}
+	return c.print(usage, t)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/policydrift"
+	"github.com/Optum/dce/pkg/rolemanager"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+func main() {
+	lambda.Start(handler)
+}
+
+// handler checks every account for changes to its principal
+// role and policy, on a timer (cloudwatch event)
+func handler(cloudWatchEvent events.CloudWatchEvent) error {
+	baseDbSvc, err := db.NewFromEnv()
+	if err != nil {
+		log.Printf("Unable to setup DB Service: %s", err.Error())
+		return err
+	}
+	historySvc, err := history.NewFromEnv()
+	if err != nil {
+		log.Printf("Unable to setup History Service: %s", err.Error())
+		return err
+	}
+	awsSession := session.Must(session.NewSession())
+
+	checker, err := policydrift.NewCheckerFromEnv(policydrift.Checker{
+		DB:         history.NewRecorder(baseDbSvc, historySvc, "check_policy_drift"),
+		TokenSvc:   common.STS{Client: sts.New(awsSession)},
+		AwsSession: awsSession,
+		Storager: common.S3{
+			Client:  s3.New(awsSession),
+			Manager: s3manager.NewDownloader(awsSession),
+		},
+		SNS:           &common.SNS{Client: sns.New(awsSession)},
+		PolicyManager: &rolemanager.IAMPolicyManager{},
+	})
+	if err != nil {
+		log.Printf("Unable to setup policy drift checker: %s", err.Error())
+		return err
+	}
+
+	return checker.CheckAll()
+}
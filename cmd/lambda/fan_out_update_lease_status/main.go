@@ -7,19 +7,22 @@ import (
 	"github.com/Optum/dce/pkg/common"
 	"github.com/Optum/dce/pkg/db"
 	"github.com/Optum/dce/pkg/errors"
+	"github.com/Optum/dce/pkg/waitlist"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	lambdaSDK "github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"github.com/aws/aws-sdk-go/service/sns"
 )
 
 /*
 This lambda initiates the budget check process. It:
 
 - Runs on a CloudWatch scheduled event (eg. every 6 hours)
-- Grabs all active leases from the DB
+- Times out pending leases which were not fulfilled in time
+- Grabs all active and frozen leases from the DB
 - For each lease, invokes the `check_budget` lambda, with the lease object as JSON payload
 
 In this way, it acts as a _fan out_ process, to parallelize
@@ -43,7 +46,9 @@ func main() {
 		err = lambdaHandler(&lambdaHandlerInput{
 			dbSvc:                         dbSvc,
 			lambdaSvc:                     lambdaSvc,
+			snsSvc:                        &common.SNS{Client: sns.New(awsSession)},
 			updateLeaseStatusFunctionName: common.RequireEnv("UPDATE_LEASE_STATUS_FUNCTION_NAME"),
+			leaseRemovedTopicArn:          common.RequireEnv("LEASE_REMOVED_TOPIC_ARN"),
 		})
 		if err != nil {
 			log.Fatal(err.Error())
@@ -56,10 +61,24 @@ func main() {
 type lambdaHandlerInput struct {
 	dbSvc                         db.DBer
 	lambdaSvc                     lambdaiface.LambdaAPI
+	snsSvc                        common.Notificationer
 	updateLeaseStatusFunctionName string
+	leaseRemovedTopicArn          string
 }
 
 func lambdaHandler(input *lambdaHandlerInput) error {
+	// Time out pending leases, so they do not stay
+	// Pending until the next account is reset
+	expired, err := waitlist.Expire(&waitlist.ExpireInput{
+		DbSvc:                input.dbSvc,
+		SnsSvc:               input.snsSvc,
+		LeaseRemovedTopicArn: input.leaseRemovedTopicArn,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Timed out %d pending leases", len(expired))
+
 	// Grab all Status=Leased accounts from the DB
 	log.Printf("Looking up active leases...")
 	leases, err := input.dbSvc.FindLeasesByStatus(db.Active)
@@ -68,6 +87,14 @@ func lambdaHandler(input *lambdaHandlerInput) error {
 	}
 	log.Printf("Found %d active leases", len(leases))
 
+	// Frozen leases are checked for the end of their grace period
+	frozenLeases, err := input.dbSvc.FindLeasesByStatus(db.Frozen)
+	if err != nil {
+		return err
+	}
+	log.Printf("Found %d frozen leases", len(frozenLeases))
+	leases = append(leases, frozenLeases...)
+
 	// Invoke our `check_bucket` lambda for each lease
 	invokeErrors := []error{}
 	for _, lease := range leases {
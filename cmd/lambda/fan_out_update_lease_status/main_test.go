@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	awsMocks "github.com/Optum/dce/pkg/awsiface/mocks"
+	commonMocks "github.com/Optum/dce/pkg/common/mocks"
 	"github.com/Optum/dce/pkg/db"
 	dbMocks "github.com/Optum/dce/pkg/db/mocks"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -15,15 +18,21 @@ import (
 )
 
 func TestLambdaHandler(t *testing.T) {
-	t.Run("should invoke a lambda for each active lease", func(t *testing.T) {
+	t.Run("should invoke a lambda for each active or frozen lease", func(t *testing.T) {
 		// Mock the DB to return some leases
 		dbSvc := &dbMocks.DBer{}
+		dbSvc.On("FindLeasesByStatus", db.Pending).
+			Return([]*db.Lease{}, nil)
 		dbSvc.On("FindLeasesByStatus", db.Active).
 			Return([]*db.Lease{
 				{AccountID: "1"},
 				{AccountID: "2"},
 				{AccountID: "3"},
 			}, nil)
+		dbSvc.On("FindLeasesByStatus", db.Frozen).
+			Return([]*db.Lease{
+				{AccountID: "4", LeaseStatus: db.Frozen},
+			}, nil)
 
 		// Mock Lambda Invoke method
 		lambdaSvc := &awsMocks.LambdaAPI{}
@@ -50,12 +59,49 @@ func TestLambdaHandler(t *testing.T) {
 		require.Nil(t, err)
 
 		// Check that we invoked a lambda for each lease
-		lambdaSvc.AssertNumberOfCalls(t, "Invoke", 3)
+		lambdaSvc.AssertNumberOfCalls(t, "Invoke", 4)
+	})
+
+	t.Run("should time out expired pending leases", func(t *testing.T) {
+		dbSvc := &dbMocks.DBer{}
+		dbSvc.On("FindLeasesByStatus", db.Pending).
+			Return([]*db.Lease{
+				{ID: "expired", AccountID: db.PendingLeaseAccountID, PrincipalID: "user",
+					LeaseStatus: db.Pending, PendingExpiresOn: time.Now().Add(-time.Hour).Unix()},
+				{ID: "pending", AccountID: db.PendingLeaseAccountID, PrincipalID: "other",
+					LeaseStatus: db.Pending, PendingExpiresOn: time.Now().Add(time.Hour).Unix()},
+			}, nil)
+		dbSvc.On("TransitionLeaseStatus", db.PendingLeaseAccountID, "user",
+			db.Pending, db.Inactive, db.LeasePendingTimeout).
+			Return(&db.Lease{ID: "expired", AccountID: db.PendingLeaseAccountID, PrincipalID: "user",
+				LeaseStatus: db.Inactive, LeaseStatusReason: db.LeasePendingTimeout}, nil)
+		dbSvc.On("FindLeasesByStatus", db.Active).
+			Return([]*db.Lease{}, nil)
+		dbSvc.On("FindLeasesByStatus", db.Frozen).
+			Return([]*db.Lease{}, nil)
+
+		snsSvc := &commonMocks.Notificationer{}
+		snsSvc.On("PublishMessage", aws.String("lease-removed"), mock.Anything, true).
+			Return(aws.String("message-id"), nil)
+
+		err := lambdaHandler(&lambdaHandlerInput{
+			dbSvc:                         dbSvc,
+			lambdaSvc:                     &awsMocks.LambdaAPI{},
+			snsSvc:                        snsSvc,
+			updateLeaseStatusFunctionName: "update_lease_status",
+			leaseRemovedTopicArn:          "lease-removed",
+		})
+		require.Nil(t, err)
+
+		dbSvc.AssertNumberOfCalls(t, "TransitionLeaseStatus", 1)
+		snsSvc.AssertNumberOfCalls(t, "PublishMessage", 1)
 	})
 
 	t.Run("should return DB errors", func(t *testing.T) {
 		// Mock the DB to return an error
 		dbSvc := &dbMocks.DBer{}
+		dbSvc.On("FindLeasesByStatus", db.Pending).
+			Return([]*db.Lease{}, nil)
 		dbSvc.On("FindLeasesByStatus", db.Active).
 			Return([]*db.Lease{}, errors.New("db error"))
 
@@ -71,12 +117,16 @@ func TestLambdaHandler(t *testing.T) {
 	t.Run("should continue to invoke Lambdas, even if one fails", func(t *testing.T) {
 		// Mock the DB to return some leases
 		dbSvc := &dbMocks.DBer{}
+		dbSvc.On("FindLeasesByStatus", db.Pending).
+			Return([]*db.Lease{}, nil)
 		dbSvc.On("FindLeasesByStatus", db.Active).
 			Return([]*db.Lease{
 				{AccountID: "1"},
 				{AccountID: "2"},
 				{AccountID: "3"},
 			}, nil)
+		dbSvc.On("FindLeasesByStatus", db.Frozen).
+			Return([]*db.Lease{}, nil)
 
 		// Mock Lambda Invoke method, to fail the second time
 		lambdaSvc := &awsMocks.LambdaAPI{}
@@ -105,11 +155,15 @@ func TestLambdaHandler(t *testing.T) {
 		lambdaSvc.AssertNumberOfCalls(t, "Invoke", 3)
 	})
 
-	t.Run("should do nothing, if there are no active leases", func(t *testing.T) {
+	t.Run("should do nothing, if there are no active or frozen leases", func(t *testing.T) {
 		// Mock the DB to return no leases
 		dbSvc := &dbMocks.DBer{}
+		dbSvc.On("FindLeasesByStatus", db.Pending).
+			Return([]*db.Lease{}, nil)
 		dbSvc.On("FindLeasesByStatus", db.Active).
 			Return([]*db.Lease{}, nil)
+		dbSvc.On("FindLeasesByStatus", db.Frozen).
+			Return([]*db.Lease{}, nil)
 
 		lambdaSvc := &awsMocks.LambdaAPI{}
 
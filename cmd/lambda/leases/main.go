@@ -6,43 +6,32 @@ import (
 	"log"
 
 	"github.com/Optum/dce/pkg/api"
+	"github.com/Optum/dce/pkg/api/leases"
 	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/currency"
 	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/leaserule"
+	"github.com/Optum/dce/pkg/policyprofile"
 	"github.com/Optum/dce/pkg/usage"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
 	"github.com/aws/aws-sdk-go/service/sns"
 	"github.com/aws/aws-sdk-go/service/sqs"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 )
 
-const (
-	PrincipalIDParam     = "principalId"
-	AccountIDParam       = "accountId"
-	NextPrincipalIDParam = "nextPrincipalId"
-	NextAccountIDParam   = "nextAccountId"
-	StatusParam          = "status"
-	LimitParam           = "limit"
-)
-
-// messageBody is the structured object of the JSON Message to send
-// to an SNS Topic for lease creation/destruction
-type messageBody struct {
-	Default string `json:"default"`
-	Body    string `json:"Body"`
-}
-
-// buildBaseURL returns a base API url from the request properties.
-func buildBaseURL(req *events.APIGatewayProxyRequest) string {
-	return fmt.Sprintf("https://%s/%s", req.Headers["Host"], req.RequestContext.Stage)
-}
-
 func main() {
 
-	// Create the Database Service from the environment
-	dao := newDBer()
+	// Create the Database Service from the environment,
+	// recording changes to leases in the history table
+	historySvc, err := history.NewFromEnv()
+	if err != nil {
+		errorMessage := fmt.Sprintf("Failed to initialize history service: %s", err)
+		log.Fatal(errorMessage)
+	}
+	dao := history.NewRecorder(newDBer(), historySvc, "leases-api")
 
 	// Create the SNS Service
 	awsSession := newAWSSession()
@@ -59,47 +48,49 @@ func main() {
 		log.Fatal(errorMessage)
 	}
 
-	leaseAddedTopicArn := common.RequireEnv("LEASE_ADDED_TOPIC")
-	accountDeletedTopicArn := common.RequireEnv("DECOMMISSION_TOPIC")
-	resetQueueURL := common.RequireEnv("RESET_SQS_URL")
+	policyProfiles, err := policyprofile.NewConfigFromEnv()
+	if err != nil {
+		errorMessage := fmt.Sprintf("Failed to initialize policy profiles: %s", err)
+		log.Fatal(errorMessage)
+	}
+
+	rates, err := currency.NewFromEnv()
+	if err != nil {
+		errorMessage := fmt.Sprintf("Failed to initialize exchange rates: %s", err)
+		log.Fatal(errorMessage)
+	}
 
-	principalBudgetAmount := common.RequireEnvFloat("PRINCIPAL_BUDGET_AMOUNT")
-	principalBudgetPeriod := common.RequireEnv("PRINCIPAL_BUDGET_PERIOD")
-	maxLeaseBudgetAmount := common.RequireEnvFloat("MAX_LEASE_BUDGET_AMOUNT")
-	maxLeasePeriod := common.RequireEnvInt("MAX_LEASE_PERIOD")
+	leaseRules, err := leaserule.NewFromEnv()
+	if err != nil {
+		errorMessage := fmt.Sprintf("Failed to initialize lease rules: %s", err)
+		log.Fatal(errorMessage)
+	}
 
-	router := &api.Router{
-		ResourceName: "/leases",
-		GetController: GetController{
-			Dao: dao,
-		},
-		ListController: ListController{
-			Dao: dao,
-		},
-		DeleteController: DeleteController{
-			Dao:                    dao,
-			SNS:                    snsSvc,
-			AccountDeletedTopicArn: accountDeletedTopicArn,
-			ResetQueueURL:          resetQueueURL,
-			Queue:                  queue,
-		},
-		CreateController: CreateController{
-			Dao:                      dao,
-			SNS:                      snsSvc,
-			LeaseAddedTopicARN:       &leaseAddedTopicArn,
-			UsageSvc:                 usageSvc,
-			PrincipalBudgetAmount:    &principalBudgetAmount,
-			PrincipalBudgetPeriod:    &principalBudgetPeriod,
-			MaxLeaseBudgetAmount:     &maxLeaseBudgetAmount,
-			MaxLeasePeriod:           &maxLeasePeriod,
-			DefaultLeaseLengthInDays: common.GetEnvInt("DEFAULT_LEASE_LENGTH_IN_DAYS", 7),
-		},
+	router := leases.NewRouter(leases.RouterConfig{
+		Dao:                      dao,
+		History:                  historySvc,
+		SNS:                      snsSvc,
+		Queue:                    queue,
+		UsageSvc:                 usageSvc,
+		LeaseAddedTopicARN:       common.RequireEnv("LEASE_ADDED_TOPIC"),
+		LeaseExtendedTopicARN:    common.RequireEnv("LEASE_EXTENDED_TOPIC"),
+		AccountDeletedTopicARN:   common.RequireEnv("DECOMMISSION_TOPIC"),
+		ResetQueueURL:            common.RequireEnv("RESET_SQS_URL"),
+		PrincipalBudgetAmount:    common.RequireEnvFloat("PRINCIPAL_BUDGET_AMOUNT"),
+		PrincipalBudgetPeriod:    common.RequireEnv("PRINCIPAL_BUDGET_PERIOD"),
+		MaxLeaseBudgetAmount:     common.RequireEnvFloat("MAX_LEASE_BUDGET_AMOUNT"),
+		MaxLeasePeriod:           common.RequireEnvInt("MAX_LEASE_PERIOD"),
+		DefaultLeaseLengthInDays: common.GetEnvInt("DEFAULT_LEASE_LENGTH_IN_DAYS", 7),
+		PendingLeaseTimeout:      common.GetEnvInt("PENDING_LEASE_TIMEOUT", 86400),
+		PolicyProfiles:           policyProfiles,
+		Rates:                    rates,
+		LeaseRules:               leaseRules,
 		UserDetails: api.UserDetails{
 			CognitoUserPoolID:        common.RequireEnv("COGNITO_USER_POOL_ID"),
 			RolesAttributesAdminName: common.RequireEnv("COGNITO_ROLES_ATTRIBUTE_ADMIN_NAME"),
 			CognitoClient:            cognitoidentityprovider.New(awsSession),
 		},
-	}
+	})
 
 	lambda.Start(router.Route)
 }
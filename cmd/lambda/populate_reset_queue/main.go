@@ -2,11 +2,14 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/Optum/dce/pkg/common"
 	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/resetstatus"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -20,6 +23,13 @@ func addAccountToQueue(accounts []*db.Account, queueURL *string,
 	// For each Account, send the message to Reset Queue and update
 	// FinanceLock Lease status if necessary
 	for _, acct := range accounts {
+		// Skip accounts whose failed resets are waiting to be retried,
+		// or which failed too many times
+		if !resetstatus.ShouldEnqueue(acct, time.Now()) {
+			log.Printf("%s : Skipped, reset is %s\n", acct.ID, acct.ResetStatus.State)
+			continue
+		}
+
 		// Send Message
 		err := queue.SendMessage(queueURL, &acct.ID)
 		if err != nil {
@@ -35,10 +45,15 @@ func addAccountToQueue(accounts []*db.Account, queueURL *string,
 func rbenqHandler(cloudWatchEvent events.CloudWatchEvent) error {
 
 	// Create Database Service
-	dbSvc, err := db.NewFromEnv()
+	baseDbSvc, err := db.NewFromEnv()
+	if err != nil {
+		return err
+	}
+	historySvc, err := history.NewFromEnv()
 	if err != nil {
 		return err
 	}
+	dbSvc := history.NewRecorder(baseDbSvc, historySvc, "populate_reset_queue")
 
 	// Get NotReady Accounts
 	accounts, err := dbSvc.FindAccountsByStatus(db.NotReady)
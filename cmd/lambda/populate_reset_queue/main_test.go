@@ -2,12 +2,14 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 
 	commock "github.com/Optum/dce/pkg/common/mocks"
 	"github.com/Optum/dce/pkg/db"
 	dbmock "github.com/Optum/dce/pkg/db/mocks"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -60,3 +62,27 @@ func TestAddAccountToQueue(t *testing.T) {
 		}
 	}
 }
+
+// TestAddAccountToQueueResetStatus verifies that accounts are not enqueued
+// while their reset is in Alarm, or waiting to be retried
+func TestAddAccountToQueueResetStatus(t *testing.T) {
+	accounts := []*db.Account{
+		{ID: "1", AccountStatus: db.NotReady},
+		{ID: "2", AccountStatus: db.NotReady, ResetStatus: &db.ResetStatus{State: db.ResetAlarm}},
+		{ID: "3", AccountStatus: db.NotReady, ResetStatus: &db.ResetStatus{
+			State:         db.ResetFailed,
+			NextAttemptOn: time.Now().Add(time.Hour).Unix(),
+		}},
+		{ID: "4", AccountStatus: db.NotReady, ResetStatus: &db.ResetStatus{State: db.ResetRunning}},
+	}
+	queueURL := "url"
+	mockQueue := commock.Queue{}
+	mockQueue.On("SendMessage", mock.Anything, mock.Anything).Return(nil)
+
+	err := addAccountToQueue(accounts, &queueURL, &mockQueue, &dbmock.DBer{})
+	require.Nil(t, err)
+
+	mockQueue.AssertNumberOfCalls(t, "SendMessage", 2)
+	mockQueue.AssertCalled(t, "SendMessage", &queueURL, aws.String("1"))
+	mockQueue.AssertCalled(t, "SendMessage", &queueURL, aws.String("4"))
+}
@@ -8,7 +8,9 @@ import (
 
 	"github.com/Optum/dce/pkg/common"
 	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
 	"github.com/Optum/dce/pkg/processresetqueue"
+	"github.com/Optum/dce/pkg/resetstatus"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -41,7 +43,16 @@ func handler(ctx context.Context) (bool, error) {
 	}
 
 	// Construct the ResetInput
-	dbSvc, err := db.NewFromEnv()
+	baseDbSvc, err := db.NewFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	historySvc, err := history.NewFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dbSvc := history.NewRecorder(baseDbSvc, historySvc, "process_reset_queue")
+	resetStatus, err := resetstatus.NewTrackerFromEnv(dbSvc, queue)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -51,6 +62,13 @@ func handler(ctx context.Context) (bool, error) {
 		ResetBuild:    &build,
 		BuildName:     &buildName,
 		DbSvc:         dbSvc,
+		ResetStatus:   resetStatus,
+		// Move messages which fail repeatedly to the dead-letter queue
+		DeadLetterQueue: &common.DeadLetterQueue{
+			Queue: queue,
+			URL:   common.RequireEnv("RESET_DLQ_URL"),
+		},
+		MaxReceiveCount: common.GetEnvInt("RESET_MAX_RECEIVE_COUNT", processresetqueue.DefaultMaxReceiveCount),
 	}
 
 	// Call the Reset and return its values
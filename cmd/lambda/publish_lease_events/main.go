@@ -10,6 +10,7 @@ import (
 	"github.com/Optum/dce/pkg/common"
 	"github.com/Optum/dce/pkg/db"
 	errors2 "github.com/Optum/dce/pkg/errors"
+	"github.com/Optum/dce/pkg/history"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
@@ -37,10 +38,15 @@ func handler(ctx context.Context, event events.DynamoDBEvent) error {
 	leaseLockedTopicArn := common.RequireEnv("LEASE_LOCKED_TOPIC_ARN")
 	leaseUnlockedTopicArn := common.RequireEnv("LEASE_UNLOCKED_TOPIC_ARN")
 	resetQueueURL := common.RequireEnv("RESET_QUEUE_URL")
-	dbSvc, err := db.NewFromEnv()
+	baseDbSvc, err := db.NewFromEnv()
 	if err != nil {
 		log.Fatalf("Failed to configure DB service %s", err)
 	}
+	historySvc, err := history.NewFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure History service %s", err)
+	}
+	dbSvc := history.NewRecorder(baseDbSvc, historySvc, "publish_lease_events")
 
 	// We get a stream of DynDB records, representing changes to the table
 	for _, record := range event.Records {
@@ -107,14 +113,23 @@ func handleRecord(input *handleRecordInput) error {
 
 		log.Printf("Transitioning from %s to %s", prevLeaseStatus, nextLeaseStatus)
 
-		// Lease is now expired if it transitioned from "Active" --> "Inactive"
-		didBecomeInactive := isActiveStatus(prevLeaseStatus) && !isActiveStatus(nextLeaseStatus)
+		// Pending leases have no account to reset,
+		// and are published by the waitlist once fulfilled
+		if lease.AccountID == db.PendingLeaseAccountID {
+			log.Printf("Ignoring status change for pending lease %s", lease.ID)
+			return nil
+		}
+
+		// Lease is now expired if it transitioned from "Active" or "Frozen" --> "Inactive".
+		// Frozen leases keep their account until the grace period is over,
+		// so the account is only reset once the lease becomes inactive.
+		didBecomeInactive := isLeasedStatus(prevLeaseStatus) && !isLeasedStatus(nextLeaseStatus)
 
 		if didBecomeInactive {
 			// Before adding the account to any queues, make sure the account is
 			// updated to NotReady state.
 			_, err = input.dbSvc.TransitionAccountStatus(lease.AccountID, db.Leased, db.NotReady)
-			if err != nil {
+			if err != nil && !isAccountNotReady(input.dbSvc, lease.AccountID, err) {
 				log.Printf("ERROR: Failed to mark AccountStatus=NotReady for %s, after lease for %s became inactive",
 					lease.AccountID, lease.PrincipalID)
 			}
@@ -143,6 +158,8 @@ func handleRecord(input *handleRecordInput) error {
 		}
 
 		// Route the lease event to the correct ARN, now for backwards compatibility.
+		// Frozen leases are published to the unlocked topic, which updates the
+		// principal policy of the account.
 		if didBecomeInactive {
 			publishInput.topicArn = input.leaseLockedTopicArn
 		} else {
@@ -169,8 +186,27 @@ func leaseFromImage(image map[string]events.DynamoDBAttributeValue) (*db.Lease,
 
 }
 
-func isActiveStatus(status string) bool {
-	return status == string(db.Active)
+// isAccountNotReady checks if a failed transition to NotReady failed because
+// the account is already NotReady, eg. because the lease was ended by a
+// forced reset (`POST /accounts/{id}/reset?force=true`)
+func isAccountNotReady(dbSvc db.DBer, accountID string, err error) bool {
+	if _, ok := err.(*db.StatusTransitionError); !ok {
+		return false
+	}
+	account, err := dbSvc.GetAccount(accountID)
+	if err != nil || account == nil {
+		return false
+	}
+	if account.AccountStatus != db.NotReady {
+		return false
+	}
+	log.Printf("Account %s is already NotReady", accountID)
+	return true
+}
+
+// isLeasedStatus returns true if leases with the status hold their account
+func isLeasedStatus(status string) bool {
+	return status == string(db.Active) || status == string(db.Frozen)
 }
 
 type publishLeaseInput struct {
@@ -201,3 +201,101 @@ func Test_handleRecord(t *testing.T) {
 		})
 	}
 }
+
+func Test_handleRecordFrozenLease(t *testing.T) {
+	image := func(status db.LeaseStatus) map[string]events.DynamoDBAttributeValue {
+		return map[string]events.DynamoDBAttributeValue{
+			"AccountId":   events.NewStringAttribute("123456789012"),
+			"principalId": events.NewStringAttribute("TestPrincipalID"),
+			"LeaseStatus": events.NewStringAttribute(string(status)),
+		}
+	}
+	record := func(prevStatus db.LeaseStatus, nextStatus db.LeaseStatus) events.DynamoDBEventRecord {
+		return events.DynamoDBEventRecord{
+			EventName: "MODIFY",
+			Change: events.DynamoDBStreamRecord{
+				OldImage: image(prevStatus),
+				NewImage: image(nextStatus),
+			},
+		}
+	}
+
+	tests := []struct {
+		name              string
+		record            events.DynamoDBEventRecord
+		shoudEnqueueReset bool
+		expectedSnsTopic  string
+	}{
+		{"should not reset frozen leases", record(db.Active, db.Frozen), false, UnlockedSnsTopic},
+		{"should reset frozen leases which become inactive", record(db.Frozen, db.Inactive), true, LockedSnsTopic},
+		{"should not reset unfrozen leases", record(db.Frozen, db.Active), false, UnlockedSnsTopic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqsSvc := &commonMocks.Queue{}
+			snsSvc := &commonMocks.Notificationer{}
+			dbSvc := &dbMocks.DBer{}
+			if tt.shoudEnqueueReset {
+				dbSvc.On("TransitionAccountStatus", "123456789012", db.Leased, db.NotReady).Return(nil, nil)
+				sqsSvc.On("SendMessage", aws.String("sqs-queue"), aws.String("123456789012")).Return(nil)
+			}
+			snsSvc.On("PublishMessage", &tt.expectedSnsTopic, mock.Anything, true).Return(nil, nil)
+
+			err := handleRecord(&handleRecordInput{
+				record:                tt.record,
+				snsSvc:                snsSvc,
+				sqsSvc:                sqsSvc,
+				dbSvc:                 dbSvc,
+				leaseLockedTopicArn:   LockedSnsTopic,
+				leaseUnlockedTopicArn: UnlockedSnsTopic,
+				resetQueueURL:         "sqs-queue",
+			})
+			assert.Nil(t, err)
+			sqsSvc.AssertExpectations(t)
+			snsSvc.AssertExpectations(t)
+			dbSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_handleRecordAccountAlreadyNotReady(t *testing.T) {
+	// Forced resets move the account to NotReady when ending the lease
+	sqsSvc := &commonMocks.Queue{}
+	snsSvc := &commonMocks.Notificationer{}
+	dbSvc := &dbMocks.DBer{}
+	dbSvc.On("TransitionAccountStatus", "123456789012", db.Leased, db.NotReady).
+		Return(nil, &db.StatusTransitionError{})
+	dbSvc.On("GetAccount", "123456789012").
+		Return(&db.Account{ID: "123456789012", AccountStatus: db.NotReady}, nil)
+	sqsSvc.On("SendMessage", aws.String("sqs-queue"), aws.String("123456789012")).Return(nil)
+	snsSvc.On("PublishMessage", aws.String(LockedSnsTopic), mock.Anything, true).Return(nil, nil)
+
+	err := handleRecord(&handleRecordInput{
+		record: events.DynamoDBEventRecord{
+			EventName: "MODIFY",
+			Change: events.DynamoDBStreamRecord{
+				OldImage: map[string]events.DynamoDBAttributeValue{
+					"AccountId":   events.NewStringAttribute("123456789012"),
+					"principalId": events.NewStringAttribute("TestPrincipalID"),
+					"LeaseStatus": events.NewStringAttribute("Active"),
+				},
+				NewImage: map[string]events.DynamoDBAttributeValue{
+					"AccountId":         events.NewStringAttribute("123456789012"),
+					"principalId":       events.NewStringAttribute("TestPrincipalID"),
+					"LeaseStatus":       events.NewStringAttribute("Inactive"),
+					"LeaseStatusReason": events.NewStringAttribute(string(db.LeaseAccountReset)),
+				},
+			},
+		},
+		snsSvc:                snsSvc,
+		sqsSvc:                sqsSvc,
+		dbSvc:                 dbSvc,
+		leaseLockedTopicArn:   LockedSnsTopic,
+		leaseUnlockedTopicArn: UnlockedSnsTopic,
+		resetQueueURL:         "sqs-queue",
+	})
+	assert.Nil(t, err)
+	sqsSvc.AssertExpectations(t)
+	snsSvc.AssertExpectations(t)
+	dbSvc.AssertExpectations(t)
+}
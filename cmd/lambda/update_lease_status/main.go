@@ -9,9 +9,12 @@ import (
 	"github.com/Optum/dce/pkg/awsiface"
 	"github.com/Optum/dce/pkg/budget"
 	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/currency"
 	"github.com/Optum/dce/pkg/db"
 	"github.com/Optum/dce/pkg/email"
 	multierrors "github.com/Optum/dce/pkg/errors"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/leaserule"
 	"github.com/Optum/dce/pkg/usage"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -22,11 +25,6 @@ import (
 	"github.com/pkg/errors"
 )
 
-type leaseContext struct {
-	expireDate  int64
-	actualSpend float64
-}
-
 func main() {
 	lambda.Start(func(event interface{}) {
 		log.Printf("Initializing budget check")
@@ -38,11 +36,17 @@ func main() {
 		}
 		log.Printf("Checking budget for lease %s @ %s", lease.PrincipalID, lease.AccountID)
 
-		// Configure the DB service
-		dbSvc, err := db.NewFromEnv()
+		// Configure the DB service,
+		// recording changes to leases in the history table
+		baseDbSvc, err := db.NewFromEnv()
 		if err != nil {
 			log.Fatalf("Failed to configure DB service %s", err)
 		}
+		historySvc, err := history.NewFromEnv()
+		if err != nil {
+			log.Fatalf("Failed to configure History service %s", err)
+		}
+		dbSvc := history.NewRecorder(baseDbSvc, historySvc, "update_lease_status")
 
 		// Configure the STS Token service
 		awsSession := session.Must(session.NewSession())
@@ -54,6 +58,16 @@ func main() {
 			log.Fatalf("Failed to configure Usage service %s", err)
 		}
 
+		leaseRules, err := leaserule.NewFromEnv()
+		if err != nil {
+			log.Fatalf("Failed to configure lease rules: %s", err)
+		}
+
+		rates, err := currency.NewFromEnv()
+		if err != nil {
+			log.Fatalf("Failed to configure exchange rates: %s", err)
+		}
+
 		err = lambdaHandler(&lambdaHandlerInput{
 			dbSvc:                                  dbSvc,
 			lease:                                  lease,
@@ -74,6 +88,16 @@ func main() {
 			budgetNotificationThresholdPercentiles: common.RequireEnvFloatSlice("BUDGET_NOTIFICATION_THRESHOLD_PERCENTILES", ","),
 			principalBudgetAmount:                  common.RequireEnvFloat("PRINCIPAL_BUDGET_AMOUNT"),
 			principalBudgetPeriod:                  common.RequireEnv("PRINCIPAL_BUDGET_PERIOD"),
+			leaseRules:                             leaseRules,
+			expiryNotificationHours:                common.RequireEnvFloatSlice("EXPIRY_NOTIFICATION_HOURS", ","),
+			expiryNotificationTemplateHTML:         common.RequireEnv("EXPIRY_NOTIFICATION_TEMPLATE_HTML"),
+			expiryNotificationTemplateText:         common.RequireEnv("EXPIRY_NOTIFICATION_TEMPLATE_TEXT"),
+			expiryNotificationTemplateSubject:      common.RequireEnv("EXPIRY_NOTIFICATION_TEMPLATE_SUBJECT"),
+			leaseRuleWarningTemplateHTML:           common.RequireEnv("LEASE_RULE_WARNING_TEMPLATE_HTML"),
+			leaseRuleWarningTemplateText:           common.RequireEnv("LEASE_RULE_WARNING_TEMPLATE_TEXT"),
+			leaseRuleWarningTemplateSubject:        common.RequireEnv("LEASE_RULE_WARNING_TEMPLATE_SUBJECT"),
+			frozenLeaseGraceHours:                  common.RequireEnvFloat("FROZEN_LEASE_GRACE_HOURS"),
+			rates:                                  rates,
 		})
 		if err != nil {
 			log.Fatalf("Failed check budget: %s", err)
@@ -119,12 +143,27 @@ type lambdaHandlerInput struct {
 	budgetNotificationThresholdPercentiles []float64
 	principalBudgetAmount                  float64
 	principalBudgetPeriod                  string
+	leaseRules                             []*leaserule.Rule
+	expiryNotificationHours                []float64
+	expiryNotificationTemplateHTML         string
+	expiryNotificationTemplateText         string
+	expiryNotificationTemplateSubject      string
+	leaseRuleWarningTemplateHTML           string
+	leaseRuleWarningTemplateText           string
+	leaseRuleWarningTemplateSubject        string
+	frozenLeaseGraceHours                  float64
+	rates                                  currency.RateProvider
 }
 
 func lambdaHandler(input *lambdaHandlerInput) error {
 	leaseLogID := fmt.Sprintf("%s @ %s", input.lease.PrincipalID, input.lease.PrincipalID)
 	prevLeaseStatus := input.lease.LeaseStatus
 
+	// Frozen leases are ended once their grace period is over
+	if prevLeaseStatus == db.Frozen {
+		return handleFrozenLease(input, time.Now())
+	}
+
 	// Lookup the account for this lease,
 	// so we can get the adminRoleArn
 	account, err := input.dbSvc.GetAccount(input.lease.AccountID)
@@ -137,7 +176,7 @@ func lambdaHandler(input *lambdaHandlerInput) error {
 	}
 
 	// Calculate actual spend for the lease
-	actualLeaseSpend, err := calculateLeaseSpend(&calculateSpendInput{
+	actualLeaseSpend, dailyLeaseSpend, err := calculateLeaseSpend(&calculateSpendInput{
 		account:               account,
 		lease:                 input.lease,
 		tokenSvc:              input.tokenSvc,
@@ -145,6 +184,7 @@ func lambdaHandler(input *lambdaHandlerInput) error {
 		usageSvc:              input.usageSvc,
 		awsSession:            input.awsSession,
 		principalBudgetPeriod: input.principalBudgetPeriod,
+		rates:                 input.rates,
 	})
 	if err != nil {
 		return errors.Wrapf(err, "Failed to calculate spend for lease %s", leaseLogID)
@@ -159,6 +199,7 @@ func lambdaHandler(input *lambdaHandlerInput) error {
 		usageSvc:              input.usageSvc,
 		awsSession:            input.awsSession,
 		principalBudgetPeriod: input.principalBudgetPeriod,
+		rates:                 input.rates,
 	})
 	if err != nil {
 		return errors.Wrapf(err, "Failed to calculate spend for principal %s", leaseLogID)
@@ -166,14 +207,28 @@ func lambdaHandler(input *lambdaHandlerInput) error {
 
 	// Defer errors until the end, so we can continue on error
 	deferredErrors := []error{}
-	currentTimeEpoch := time.Now().Unix()
 
-	expired, reason := isLeaseExpired(input.lease, &leaseContext{currentTimeEpoch, actualLeaseSpend}, actualPrincipalSpend, input.principalBudgetAmount)
+	// Check the lease against the configured lease rules
+	result := leaserule.Evaluate(input.leaseRules, input.lease, &leaserule.State{
+		Now:                   time.Now().Unix(),
+		LeaseSpend:            actualLeaseSpend,
+		PrincipalSpend:        actualPrincipalSpend,
+		PrincipalBudgetAmount: input.principalBudgetAmount,
+		DailySpend:            dailyLeaseSpend,
+	})
+	err = recordFiredRules(input, result)
+	if err != nil {
+		deferredErrors = append(deferredErrors, err)
+	}
 
-	if expired {
-		// Update the lease status with the inactive status and current end time.
+	if result.Enforced != nil {
+		reason := result.Enforced.StatusReason()
+		// Freeze the lease, or end it if there is no grace period.
 		input.lease.LeaseStatus = db.Inactive
-		log.Printf("%s.  Updating lease as ready to be reclaimed...", reason)
+		if result.Enforced.Action == leaserule.Freeze && input.frozenLeaseGraceHours > 0 {
+			input.lease.LeaseStatus = db.Frozen
+		}
+		log.Printf("%s (lease rule %s).  Updating lease to %s...", reason, result.Enforced.Name, input.lease.LeaseStatus)
 		err := handleLeaseExpire(input, prevLeaseStatus, reason)
 		if err != nil {
 			deferredErrors = append(deferredErrors, err)
@@ -183,6 +238,7 @@ func lambdaHandler(input *lambdaHandlerInput) error {
 	// Send notification emails, for budget thresholds
 	err = sendBudgetNotificationEmail(&sendBudgetNotificationEmailInput{
 		lease:                                  input.lease,
+		dbSvc:                                  input.dbSvc,
 		emailSvc:                               input.emailSvc,
 		budgetNotificationFromEmail:            input.budgetNotificationFromEmail,
 		budgetNotificationBCCEmails:            input.budgetNotificationBCCEmails,
@@ -192,6 +248,7 @@ func lambdaHandler(input *lambdaHandlerInput) error {
 		budgetNotificationThresholdPercentiles: input.budgetNotificationThresholdPercentiles,
 		actualLeaseSpend:                       actualLeaseSpend,
 		actualPrincipalSpend:                   actualPrincipalSpend,
+		principalBudgetAmount:                  input.principalBudgetAmount,
 	})
 	if err != nil {
 		log.Printf("Failed to send budget notification emails for lease %s @ %s: %s",
@@ -199,6 +256,25 @@ func lambdaHandler(input *lambdaHandlerInput) error {
 		deferredErrors = append(deferredErrors, err)
 	}
 
+	// Send notification emails, for leases which expire soon
+	err = sendExpiryNotificationEmail(&sendExpiryNotificationEmailInput{
+		lease:                             input.lease,
+		dbSvc:                             input.dbSvc,
+		emailSvc:                          input.emailSvc,
+		currentTime:                       time.Now(),
+		budgetNotificationFromEmail:       input.budgetNotificationFromEmail,
+		budgetNotificationBCCEmails:       input.budgetNotificationBCCEmails,
+		expiryNotificationHours:           input.expiryNotificationHours,
+		expiryNotificationTemplateHTML:    input.expiryNotificationTemplateHTML,
+		expiryNotificationTemplateText:    input.expiryNotificationTemplateText,
+		expiryNotificationTemplateSubject: input.expiryNotificationTemplateSubject,
+	})
+	if err != nil {
+		log.Printf("Failed to send expiry notification emails for lease %s @ %s: %s",
+			input.lease.PrincipalID, input.lease.AccountID, err)
+		deferredErrors = append(deferredErrors, err)
+	}
+
 	// Return deferred errors
 	if len(deferredErrors) > 0 {
 		return multierrors.NewMultiError("Budget check failed: ", deferredErrors)
@@ -207,19 +283,99 @@ func lambdaHandler(input *lambdaHandlerInput) error {
 	return nil
 }
 
-// isLeaseExpried contains the logic for determining if a lease has already
-// expired, given the context.
-func isLeaseExpired(lease *db.Lease, context *leaseContext, actualPrincipalSpend float64, principalBudgetAmount float64) (bool, db.LeaseStatusReason) {
+// recordFiredRules adds the names of the rules which fired to the lease,
+// and emails the lease owner about rules with the warn action.
+// Rules which already fired for the lease are not recorded
+// (or warned about) again.
+func recordFiredRules(input *lambdaHandlerInput, result *leaserule.Result) error {
+	firedRules := append([]string{}, input.lease.FiredRules...)
+	warnings := []*leaserule.Rule{}
+	for _, rule := range result.Fired {
+		if containsString(firedRules, rule.Name) {
+			continue
+		}
+		if rule.Action == leaserule.Warn {
+			log.Printf("Lease rule %s fired for lease %s @ %s (%s)",
+				rule.Name, input.lease.PrincipalID, input.lease.AccountID, rule.StatusReason())
+			warnings = append(warnings, rule)
+		}
+		firedRules = append(firedRules, rule.Name)
+	}
+
+	var warnErr error
+	if len(warnings) > 0 {
+		warnErr = sendLeaseRuleWarningEmail(&sendLeaseRuleWarningEmailInput{
+			lease:                           input.lease,
+			emailSvc:                        input.emailSvc,
+			rules:                           warnings,
+			budgetNotificationFromEmail:     input.budgetNotificationFromEmail,
+			budgetNotificationBCCEmails:     input.budgetNotificationBCCEmails,
+			leaseRuleWarningTemplateHTML:    input.leaseRuleWarningTemplateHTML,
+			leaseRuleWarningTemplateText:    input.leaseRuleWarningTemplateText,
+			leaseRuleWarningTemplateSubject: input.leaseRuleWarningTemplateSubject,
+		})
+		if warnErr != nil {
+			log.Printf("Failed to send lease rule warning emails for lease %s @ %s: %s",
+				input.lease.PrincipalID, input.lease.AccountID, warnErr)
+			// Don't record the warnings, so they are sent again
+			firedRules = removeRuleNames(firedRules, warnings)
+		}
+	}
+	if len(firedRules) == len(input.lease.FiredRules) {
+		return warnErr
+	}
+
+	input.lease.FiredRules = firedRules
+	_, err := input.dbSvc.UpdateLease(*input.lease, []string{"FiredRules"})
+	if err != nil {
+		log.Printf("Failed to record lease rules for lease %s @ %s: %s",
+			input.lease.PrincipalID, input.lease.AccountID, err)
+		return err
+	}
+	return warnErr
+}
+
+// removeRuleNames returns the names, without the names of the rules
+func removeRuleNames(names []string, rules []*leaserule.Rule) []string {
+	kept := []string{}
+	for _, name := range names {
+		removed := false
+		for _, rule := range rules {
+			if rule.Name == name {
+				removed = true
+			}
+		}
+		if !removed {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
 
-	if context.expireDate >= lease.ExpiresOn {
-		return true, db.LeaseExpired
-	} else if context.actualSpend > lease.BudgetAmount {
-		return true, db.LeaseOverBudget
-	} else if actualPrincipalSpend > principalBudgetAmount {
-		return true, db.LeaseOverPrincipalBudget
+// handleFrozenLease ends a frozen lease, once the grace period
+// (counted from when the lease was frozen) is over.
+// Ending the lease adds the account to the reset queue.
+func handleFrozenLease(input *lambdaHandlerInput, currentTime time.Time) error {
+	gracePeriodEndsOn := input.lease.LeaseStatusModifiedOn + int64(input.frozenLeaseGraceHours*3600)
+	if currentTime.Unix() < gracePeriodEndsOn {
+		log.Printf("Lease %s @ %s is frozen until %s",
+			input.lease.PrincipalID, input.lease.AccountID, time.Unix(gracePeriodEndsOn, 0).UTC().Format(time.RFC1123))
+		return nil
 	}
 
-	return false, db.LeaseActive
+	log.Printf("Grace period of frozen lease %s @ %s is over.  Updating lease as ready to be reclaimed...",
+		input.lease.PrincipalID, input.lease.AccountID)
+	input.lease.LeaseStatus = db.Inactive
+	return handleLeaseExpire(input, db.Frozen, input.lease.LeaseStatusReason)
 }
 
 // handleOverBudget handles the case where a lease is over budget:
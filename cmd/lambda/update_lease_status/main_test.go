@@ -9,10 +9,12 @@ import (
 	awsMocks "github.com/Optum/dce/pkg/awsiface/mocks"
 	budgetMocks "github.com/Optum/dce/pkg/budget/mocks"
 	commonMocks "github.com/Optum/dce/pkg/common/mocks"
+	"github.com/Optum/dce/pkg/currency"
 	"github.com/Optum/dce/pkg/db"
 	dbMocks "github.com/Optum/dce/pkg/db/mocks"
 	"github.com/Optum/dce/pkg/email"
 	emailMocks "github.com/Optum/dce/pkg/email/mocks"
+	"github.com/Optum/dce/pkg/leaserule"
 	"github.com/Optum/dce/pkg/usage"
 	usageMocks "github.com/Optum/dce/pkg/usage/mocks"
 	"github.com/stretchr/testify/mock"
@@ -67,6 +69,7 @@ has exceeded its budget of $100. Actual spend is $150
 		actualSpend                   float64
 		leaseStatus                   db.LeaseStatus
 		expectedLeaseStatusTransition db.LeaseStatus
+		expectedFiredRules            []string
 		shouldTransitionLeaseStatus   bool
 		transitionLeaseError          error
 		shouldSNS                     bool
@@ -78,6 +81,13 @@ has exceeded its budget of $100. Actual spend is $150
 		expectedEmailBodyHTML         string
 		expectedEmailBodyText         string
 		expectedError                 string
+		leaseRules                    []*leaserule.Rule
+		firedRules                    []string
+		budgetThresholdsSent          []float64
+		expectedThresholdsSent        []float64
+		frozenLeaseGraceHours         float64
+		expectedWarningSubject        string
+		warningEmailError             error
 	}
 
 	checkBudgetTest := func(test *checkBudgetTestInput) {
@@ -97,8 +107,11 @@ has exceeded its budget of $100. Actual spend is $150
 				BudgetAmount:             test.budgetAmount,
 				BudgetCurrency:           "USD",
 				BudgetNotificationEmails: []string{"recipA@example.com", "recipB@example.com"},
+				CreatedOn:                time.Unix(100, 0).Unix(),
 				LeaseStatusModifiedOn:    time.Unix(100, 0).Unix(),
 				ExpiresOn:                time.Now().AddDate(0, 0, +1000).Unix(), //Make sure it expires in the distant future as we aren't testing that
+				FiredRules:               test.firedRules,
+				BudgetThresholdsSent:     test.budgetThresholdsSent,
 			},
 			awsSession:                             &awsMocks.AwsSession{},
 			tokenSvc:                               tokenSvc,
@@ -116,6 +129,14 @@ has exceeded its budget of $100. Actual spend is $150
 			budgetNotificationTemplateSubject:      emailTemplateSubject,
 			budgetNotificationThresholdPercentiles: []float64{75, 100},
 			principalBudgetAmount:                  1000,
+			leaseRules:                             leaserule.DefaultRules(),
+			leaseRuleWarningTemplateHTML:           "<p>{{range .Rules}}{{.Name}}: {{.StatusReason}}{{end}}</p>",
+			leaseRuleWarningTemplateText:           "{{range .Rules}}{{.Name}}: {{.StatusReason}}{{end}}",
+			leaseRuleWarningTemplateSubject:        "Lease warning [{{.Lease.AccountID}}]",
+			frozenLeaseGraceHours:                  test.frozenLeaseGraceHours,
+		}
+		if test.leaseRules != nil {
+			input.leaseRules = test.leaseRules
 		}
 
 		// Should grab the account from the DB, to get it's adminRoleArn
@@ -138,7 +159,7 @@ has exceeded its budget of $100. Actual spend is $150
 		budgetSvc.On("CalculateTotalSpend",
 			startDate,
 			endDate,
-		).Return(test.actualSpend, nil)
+		).Return(test.actualSpend, "USD", nil)
 
 		// Mock Usage service
 		inputUsage := usage.Usage{
@@ -149,13 +170,25 @@ has exceeded its budget of $100. Actual spend is $150
 			CostAmount:   test.actualSpend,
 			CostCurrency: "USD",
 			TimeToLive:   startDate.AddDate(0, 1, 0).Unix(),
+			// Lease is budgeted in USD
+			ConvertedCostAmount:   test.actualSpend,
+			ConvertedCostCurrency: "USD",
 		}
 
-		budgetStartTime := time.Unix(input.lease.LeaseStatusModifiedOn, 0)
+		budgetStartTime := time.Unix(input.lease.CreatedOn, 0)
 		usageSvc.On("PutUsage", inputUsage).Return(nil)
 		usageSvc.On("GetUsageByDateRange", budgetStartTime, usageEndDate.AddDate(0, 0, -1)).Return(nil, nil)
 		usageSvc.On("GetUsageByDateRange", mock.Anything, mock.Anything).Return(nil, nil)
 
+		// Should record the rule which ended the lease
+		if len(test.expectedFiredRules) > 0 {
+			dbSvc.On("UpdateLease", mock.Anything, []string{"FiredRules"}).
+				Run(func(args mock.Arguments) {
+					require.Equal(t, test.expectedFiredRules, args.Get(0).(db.Lease).FiredRules)
+				}).
+				Return(input.lease, nil)
+		}
+
 		// Should transition from "Active" --> "FinanceLock"
 		if test.shouldTransitionLeaseStatus {
 			dbSvc.On("TransitionLeaseStatus",
@@ -179,6 +212,20 @@ has exceeded its budget of $100. Actual spend is $150
 				BodyHTML:     test.expectedEmailBodyHTML,
 				BodyText:     test.expectedEmailBodyText,
 			}).Return(nil)
+
+			// Should record the threshold, so it isn't sent again
+			dbSvc.On("UpdateLease", mock.Anything, []string{"BudgetThresholdsSent"}).
+				Run(func(args mock.Arguments) {
+					require.Equal(t, test.expectedThresholdsSent, args.Get(0).(db.Lease).BudgetThresholdsSent)
+				}).
+				Return(input.lease, nil)
+		}
+
+		// Should warn about rules with the warn action
+		if test.expectedWarningSubject != "" {
+			emailSvc.On("SendEmail", mock.MatchedBy(func(input *email.SendEmailInput) bool {
+				return input.Subject == test.expectedWarningSubject
+			})).Return(test.warningEmailError)
 		}
 
 		// Call Lambda handler
@@ -206,15 +253,74 @@ has exceeded its budget of $100. Actual spend is $150
 			// Should transition from Active --> FinanceLock
 			leaseStatus:                   db.Active,
 			expectedLeaseStatusTransition: db.Inactive,
+			expectedFiredRules:            []string{"over-budget"},
 			// Should do all the finance locking things
 			shouldTransitionLeaseStatus: true,
 			shouldSNS:                   true,
 			shouldSQSReset:              true,
 			// Should send notification email
-			shouldSendEmail:       true,
-			expectedEmailSubject:  expectedOverBudgetText,
-			expectedEmailBodyHTML: expectedOverBudgetEmailHTML,
-			expectedEmailBodyText: expectedOverBudgetEmailText,
+			shouldSendEmail:        true,
+			expectedThresholdsSent: []float64{100},
+			expectedEmailSubject:   expectedOverBudgetText,
+			expectedEmailBodyHTML:  expectedOverBudgetEmailHTML,
+			expectedEmailBodyText:  expectedOverBudgetEmailText,
+		})
+	})
+
+	t.Run("Scenario: Over Budget Lease, with a grace period", func(t *testing.T) {
+		checkBudgetTest(&checkBudgetTestInput{
+			budgetAmount:          100,
+			actualSpend:           150,
+			leaseStatus:           db.Active,
+			frozenLeaseGraceHours: 24,
+			// The default rules end over budget leases,
+			// as freezing does not stop running resources
+			expectedLeaseStatusTransition: db.Inactive,
+			expectedFiredRules:            []string{"over-budget"},
+			shouldTransitionLeaseStatus:   true,
+			shouldSendEmail:               true,
+			expectedThresholdsSent:        []float64{100},
+			expectedEmailSubject:          expectedOverBudgetText,
+			expectedEmailBodyHTML:         expectedOverBudgetEmailHTML,
+			expectedEmailBodyText:         expectedOverBudgetEmailText,
+		})
+	})
+
+	t.Run("Scenario: Over Budget Lease, with a freeze rule and a grace period", func(t *testing.T) {
+		checkBudgetTest(&checkBudgetTestInput{
+			budgetAmount:          100,
+			actualSpend:           150,
+			leaseStatus:           db.Active,
+			frozenLeaseGraceHours: 24,
+			leaseRules: []*leaserule.Rule{
+				{Name: "over-budget", Type: leaserule.Spend, Action: leaserule.Freeze, Budget: leaserule.LeaseBudget},
+			},
+			// Should freeze the lease, instead of ending it
+			expectedLeaseStatusTransition: db.Frozen,
+			expectedFiredRules:            []string{"over-budget"},
+			shouldTransitionLeaseStatus:   true,
+			shouldSendEmail:               true,
+			expectedThresholdsSent:        []float64{100},
+			expectedEmailSubject:          expectedOverBudgetText,
+			expectedEmailBodyHTML:         expectedOverBudgetEmailHTML,
+			expectedEmailBodyText:         expectedOverBudgetEmailText,
+		})
+	})
+
+	t.Run("Scenario: End Lease Rule, with a grace period", func(t *testing.T) {
+		checkBudgetTest(&checkBudgetTestInput{
+			budgetAmount:          100,
+			actualSpend:           50,
+			leaseStatus:           db.Active,
+			frozenLeaseGraceHours: 24,
+			leaseRules: []*leaserule.Rule{
+				{Name: "half-budget", Type: leaserule.Spend, Action: leaserule.End, Budget: leaserule.LeaseBudget, Percent: 40},
+			},
+			// Should end the lease, without freezing it
+			expectedLeaseStatusTransition: db.Inactive,
+			expectedFiredRules:            []string{"half-budget"},
+			shouldTransitionLeaseStatus:   true,
+			shouldSendEmail:               false,
 		})
 	})
 
@@ -229,8 +335,9 @@ has exceeded its budget of $100. Actual spend is $150
 			shouldSNS:                   false,
 			shouldSQSReset:              false,
 			// Should send notification email
-			shouldSendEmail:      true,
-			expectedEmailSubject: "Lease at 75% of budget [1234567890]",
+			shouldSendEmail:        true,
+			expectedThresholdsSent: []float64{75},
+			expectedEmailSubject:   "Lease at 75% of budget [1234567890]",
 			expectedEmailBodyHTML: strings.TrimSpace(`
 <p>
 
@@ -248,6 +355,35 @@ Actual spend is $76
 		})
 	})
 
+	t.Run("Scenario: Over Threshold Lease, already notified", func(t *testing.T) {
+		checkBudgetTest(&checkBudgetTestInput{
+			budgetAmount:         100,
+			actualSpend:          76,
+			leaseStatus:          db.Active,
+			budgetThresholdsSent: []float64{75},
+			// Should not send the notification email again
+			shouldSendEmail: false,
+		})
+	})
+
+	t.Run("Scenario: Over Budget Lease, notified of lower threshold", func(t *testing.T) {
+		checkBudgetTest(&checkBudgetTestInput{
+			budgetAmount:                  100,
+			actualSpend:                   150,
+			leaseStatus:                   db.Active,
+			budgetThresholdsSent:          []float64{75},
+			expectedLeaseStatusTransition: db.Inactive,
+			expectedFiredRules:            []string{"over-budget"},
+			shouldTransitionLeaseStatus:   true,
+			// Should send the notification email for the next threshold
+			shouldSendEmail:        true,
+			expectedThresholdsSent: []float64{75, 100},
+			expectedEmailSubject:   expectedOverBudgetText,
+			expectedEmailBodyHTML:  expectedOverBudgetEmailHTML,
+			expectedEmailBodyText:  expectedOverBudgetEmailText,
+		})
+	})
+
 	t.Run("Scenario: Under Budget Lease", func(t *testing.T) {
 		checkBudgetTest(&checkBudgetTestInput{
 			// <75% of budget
@@ -263,6 +399,58 @@ Actual spend is $76
 		})
 	})
 
+	t.Run("Scenario: Warn Lease Rule", func(t *testing.T) {
+		checkBudgetTest(&checkBudgetTestInput{
+			// >50% of budget
+			budgetAmount: 100,
+			actualSpend:  60,
+			leaseStatus:  db.Active,
+			leaseRules: []*leaserule.Rule{
+				{Name: "half-budget", Type: leaserule.Spend, Action: leaserule.Warn, Budget: leaserule.LeaseBudget, Percent: 50},
+			},
+			// Should record the rule, and warn the lease owner,
+			// without ending the lease
+			expectedFiredRules:          []string{"half-budget"},
+			expectedWarningSubject:      "Lease warning [1234567890]",
+			shouldTransitionLeaseStatus: false,
+			shouldSendEmail:             false,
+		})
+	})
+
+	t.Run("Scenario: Warn Lease Rule, failing to send the warning", func(t *testing.T) {
+		checkBudgetTest(&checkBudgetTestInput{
+			budgetAmount: 100,
+			actualSpend:  60,
+			leaseStatus:  db.Active,
+			leaseRules: []*leaserule.Rule{
+				{Name: "half-budget", Type: leaserule.Spend, Action: leaserule.Warn, Budget: leaserule.LeaseBudget, Percent: 50},
+				{Name: "third-budget", Type: leaserule.Spend, Action: leaserule.Warn, Budget: leaserule.LeaseBudget, Percent: 30},
+			},
+			// Should not record the rules, so the warning is sent again
+			firedRules:                  []string{"third-budget"},
+			expectedWarningSubject:      "Lease warning [1234567890]",
+			warningEmailError:           errors.New("ses error"),
+			expectedError:               "ses error",
+			shouldTransitionLeaseStatus: false,
+			shouldSendEmail:             false,
+		})
+	})
+
+	t.Run("Scenario: Warn Lease Rule already fired", func(t *testing.T) {
+		checkBudgetTest(&checkBudgetTestInput{
+			budgetAmount: 100,
+			actualSpend:  60,
+			leaseStatus:  db.Active,
+			leaseRules: []*leaserule.Rule{
+				{Name: "half-budget", Type: leaserule.Spend, Action: leaserule.Warn, Budget: leaserule.LeaseBudget, Percent: 50},
+			},
+			// Should not record the rule again
+			firedRules:                  []string{"half-budget"},
+			shouldTransitionLeaseStatus: false,
+			shouldSendEmail:             false,
+		})
+	})
+
 	t.Run("should handle errors and continue", func(t *testing.T) {
 		// Continue if DB fails
 		checkBudgetTest(&checkBudgetTestInput{
@@ -273,6 +461,7 @@ Actual spend is $76
 			// DB Transition fails
 			leaseStatus:                   db.Active,
 			expectedLeaseStatusTransition: db.Inactive,
+			expectedFiredRules:            []string{"over-budget"},
 			transitionLeaseError:          errors.New("DB transition failed"),
 
 			// Should continue on error
@@ -280,6 +469,7 @@ Actual spend is $76
 			shouldSNS:                   true,
 			shouldSQSReset:              true,
 			shouldSendEmail:             true,
+			expectedThresholdsSent:      []float64{100},
 			expectedEmailSubject:        expectedOverBudgetText,
 			expectedEmailBodyHTML:       expectedOverBudgetEmailHTML,
 			expectedEmailBodyText:       expectedOverBudgetEmailText,
@@ -290,77 +480,168 @@ Actual spend is $76
 
 	})
 }
-func Test_isLeaseExpired(t *testing.T) {
-	type args struct {
-		lease                *db.Lease
-		context              *leaseContext
-		actualPrincipalSpend float64
+func TestRecordFiredRules(t *testing.T) {
+	t.Run("should warn again after the lease is extended", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		emailSvc := &emailMocks.Service{}
+		_, err := dbSvc.PutLease(db.Lease{
+			AccountID:                "1234567890",
+			PrincipalID:              "test-user",
+			LeaseStatus:              db.Active,
+			BudgetAmount:             100,
+			BudgetNotificationEmails: []string{"recipA@example.com"},
+			ExpiresOn:                1000,
+			FiredRules:               []string{"half-budget"},
+		})
+		require.Nil(t, err)
+
+		lease, err := dbSvc.ExtendLease("1234567890", "test-user", db.Active, 1000, 100, 2000, 100)
+		require.Nil(t, err)
+		require.Empty(t, lease.FiredRules)
+
+		emailSvc.On("SendEmail", mock.MatchedBy(func(input *email.SendEmailInput) bool {
+			return input.Subject == "Lease warning [1234567890]"
+		})).Return(nil)
+		err = recordFiredRules(&lambdaHandlerInput{
+			lease:                           lease,
+			dbSvc:                           dbSvc,
+			emailSvc:                        emailSvc,
+			budgetNotificationFromEmail:     "from@example.com",
+			leaseRuleWarningTemplateHTML:    "<p>{{range .Rules}}{{.Name}}: {{.StatusReason}}{{end}}</p>",
+			leaseRuleWarningTemplateText:    "{{range .Rules}}{{.Name}}: {{.StatusReason}}{{end}}",
+			leaseRuleWarningTemplateSubject: "Lease warning [{{.Lease.AccountID}}]",
+		}, &leaserule.Result{Fired: []*leaserule.Rule{
+			{Name: "half-budget", Type: leaserule.Spend, Action: leaserule.Warn, Budget: leaserule.LeaseBudget, Percent: 50},
+		}})
+		require.Nil(t, err)
+		emailSvc.AssertExpectations(t)
+
+		lease, err = dbSvc.GetLease("1234567890", "test-user")
+		require.Nil(t, err)
+		require.Equal(t, []string{"half-budget"}, lease.FiredRules)
+	})
+}
+
+func TestHandleFrozenLease(t *testing.T) {
+	frozenOn := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	newInput := func() (*lambdaHandlerInput, *dbMocks.DBer) {
+		dbSvc := &dbMocks.DBer{}
+		return &lambdaHandlerInput{
+			dbSvc: dbSvc,
+			lease: &db.Lease{
+				AccountID:             "1234567890",
+				PrincipalID:           "test-user",
+				LeaseStatus:           db.Frozen,
+				LeaseStatusReason:     db.LeaseOverBudget,
+				LeaseStatusModifiedOn: frozenOn.Unix(),
+			},
+			frozenLeaseGraceHours: 24,
+		}, dbSvc
 	}
-	emails := []string{"joe@example.com"}
-	principalBudgetAmount := 7000.00
+
+	t.Run("should keep the lease frozen during the grace period", func(t *testing.T) {
+		input, dbSvc := newInput()
+
+		err := handleFrozenLease(input, frozenOn.Add(23*time.Hour))
+		require.Nil(t, err)
+		dbSvc.AssertNotCalled(t, "TransitionLeaseStatus",
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should end the lease after the grace period", func(t *testing.T) {
+		input, dbSvc := newInput()
+		dbSvc.On("TransitionLeaseStatus",
+			"1234567890", "test-user", db.Frozen, db.Inactive, db.LeaseOverBudget,
+		).Return(input.lease, nil)
+
+		err := handleFrozenLease(input, frozenOn.Add(24*time.Hour))
+		require.Nil(t, err)
+		dbSvc.AssertExpectations(t)
+	})
+}
+
+func TestCalculateSpendInBudgetCurrency(t *testing.T) {
+	rates := &currency.StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.8}}
 	lease := &db.Lease{
-		AccountID:                "12345",
-		PrincipalID:              "98765",
-		LeaseStatus:              db.Inactive,
-		LeaseStatusReason:        db.LeaseExpired,
-		CreatedOn:                time.Now().Unix(),
-		LastModifiedOn:           time.Now().Unix(),
-		BudgetAmount:             3000,
-		BudgetCurrency:           "USD",
-		BudgetNotificationEmails: emails,
-		LeaseStatusModifiedOn:    time.Now().Unix(),
-		ExpiresOn:                time.Now().Unix()}
-
-	nonExpiredLeaseTestArgs := &args{
-		lease,
-		&leaseContext{
-			time.Now().AddDate(0, 0, -1).Unix(),
-			10},
-		10}
-
-	expiredLeaseTestArgs := &args{
-		lease,
-		&leaseContext{
-			time.Now().AddDate(0, 0, +1).Unix(),
-			10},
-		10}
-
-	overBudgetTest := &args{
-		lease,
-		&leaseContext{
-			time.Now().AddDate(0, 0, -1).Unix(),
-			5000},
-		5000}
-
-	overPrincipalBudgetAmountTest := &args{
-		lease,
-		&leaseContext{
-			time.Now().AddDate(0, 0, -1).Unix(),
-			2500},
-		9000}
-
-	tests := []struct {
-		name  string
-		args  args
-		want  bool
-		want1 db.LeaseStatusReason
-	}{
-		{"Non-expired lease test", *nonExpiredLeaseTestArgs, false, db.LeaseActive},
-		{"Expired lease test", *expiredLeaseTestArgs, true, db.LeaseExpired},
-		{"Over budget lease test", *overBudgetTest, true, db.LeaseOverBudget},
-		{"Over principal budget amount test", *overPrincipalBudgetAmountTest, true, db.LeaseOverPrincipalBudget},
+		AccountID:      "1234567890",
+		PrincipalID:    "test-user",
+		BudgetAmount:   500,
+		BudgetCurrency: "EUR",
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, got1 := isLeaseExpired(tt.args.lease, tt.args.context, tt.args.actualPrincipalSpend, principalBudgetAmount)
-			if got != tt.want {
-				t.Errorf("isLeaseExpired() got = %v, want %v", got, tt.want)
-			}
-			if got1 != tt.want1 {
-				t.Errorf("isLeaseExpired() got1 = %v, want %v", got1, tt.want1)
-			}
-		})
+	usageRecords := []*usage.Usage{
+		// Converted to the lease budget currency
+		{PrincipalID: "test-user", AccountID: "1234567890", StartDate: 1, CostAmount: 60, CostCurrency: "USD",
+			ConvertedCostAmount: 40, ConvertedCostCurrency: "EUR"},
+		// Recorded before the lease had a budget currency
+		{PrincipalID: "test-user", AccountID: "1234567890", StartDate: 2, CostAmount: 50, CostCurrency: "USD"},
+		// Another principal
+		{PrincipalID: "other-user", AccountID: "1234567890", StartDate: 3, CostAmount: 1000, CostCurrency: "USD"},
 	}
+
+	t.Run("should convert lease spend to the lease budget currency", func(t *testing.T) {
+		tokenSvc := &commonMocks.TokenService{}
+		tokenSvc.MockNewSession("mock:admin:role:arn")
+		budgetSvc := &budgetMocks.Service{}
+		budgetSvc.On("SetCostExplorer", mock.Anything)
+		budgetSvc.On("CalculateTotalSpend", mock.Anything, mock.Anything).Return(100.0, "USD", nil)
+
+		// Should store the native and converted spend
+		usageSvc := &usageMocks.Service{}
+		usageSvc.On("PutUsage", mock.MatchedBy(func(u usage.Usage) bool {
+			return u.CostAmount == 100 && u.CostCurrency == "USD" &&
+				u.ConvertedCostAmount == 80 && u.ConvertedCostCurrency == "EUR"
+		})).Return(nil)
+		usageSvc.On("GetUsageByDateRange", mock.Anything, mock.Anything).Return(usageRecords, nil)
+
+		spend, dailySpend, err := calculateLeaseSpend(&calculateSpendInput{
+			account:   &db.Account{ID: "1234567890", AdminRoleArn: "mock:admin:role:arn"},
+			lease:     lease,
+			tokenSvc:  tokenSvc,
+			budgetSvc: budgetSvc,
+			usageSvc:  usageSvc,
+			rates:     rates,
+		})
+		require.Nil(t, err)
+		require.InDelta(t, 160, spend, 0.000001)
+		require.Len(t, dailySpend, 3)
+		require.InDelta(t, 80, dailySpend[0].Amount, 0.000001)
+		require.InDelta(t, 40, dailySpend[1].Amount, 0.000001)
+		require.InDelta(t, 40, dailySpend[2].Amount, 0.000001)
+		usageSvc.AssertExpectations(t)
+	})
+
+	t.Run("should fail for a currency without an exchange rate", func(t *testing.T) {
+		tokenSvc := &commonMocks.TokenService{}
+		tokenSvc.MockNewSession("mock:admin:role:arn")
+		budgetSvc := &budgetMocks.Service{}
+		budgetSvc.On("SetCostExplorer", mock.Anything)
+		budgetSvc.On("CalculateTotalSpend", mock.Anything, mock.Anything).Return(100.0, "USD", nil)
+
+		_, _, err := calculateLeaseSpend(&calculateSpendInput{
+			account:   &db.Account{ID: "1234567890", AdminRoleArn: "mock:admin:role:arn"},
+			lease:     &db.Lease{AccountID: "1234567890", PrincipalID: "test-user", BudgetCurrency: "JPY"},
+			tokenSvc:  tokenSvc,
+			budgetSvc: budgetSvc,
+			usageSvc:  &usageMocks.Service{},
+			rates:     rates,
+		})
+		require.EqualError(t, err, "Failed to convert spend for account 1234567890: No exchange rate for JPY")
+	})
+
+	t.Run("should calculate principal spend in USD", func(t *testing.T) {
+		usageSvc := &usageMocks.Service{}
+		usageSvc.On("GetUsageByDateRange", mock.Anything, mock.Anything).Return(usageRecords, nil)
+
+		spend, err := calculatePrincipalSpend(&calculateSpendInput{
+			lease:                 lease,
+			usageSvc:              usageSvc,
+			principalBudgetPeriod: "WEEKLY",
+			rates:                 rates,
+		})
+		require.Nil(t, err)
+		require.InDelta(t, 110, spend, 0.000001)
+	})
 }
 
 func TestGetBeginningOfCurrentBillingPeriod(t *testing.T) {
@@ -377,3 +658,236 @@ func TestGetBeginningOfCurrentBillingPeriod(t *testing.T) {
 	require.NotNil(t, actualOutput)
 	require.Equal(t, expectedOutput, actualOutput)
 }
+
+func TestSendExpiryNotificationEmail(t *testing.T) {
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	type expiryTestInput struct {
+		hoursRemaining  float64
+		leaseStatus     db.LeaseStatus
+		sent            []float64
+		expectedSubject string
+		expectedSent    []float64
+		shouldSendEmail bool
+	}
+
+	expiryTest := func(test *expiryTestInput) {
+		dbSvc := &dbMocks.DBer{}
+		emailSvc := &emailMocks.Service{}
+		leaseStatus := test.leaseStatus
+		if leaseStatus == "" {
+			leaseStatus = db.Active
+		}
+		lease := &db.Lease{
+			AccountID:                "1234567890",
+			PrincipalID:              "test-user",
+			LeaseStatus:              leaseStatus,
+			BudgetNotificationEmails: []string{"recipA@example.com"},
+			ExpiresOn:                now.Add(time.Duration(test.hoursRemaining * float64(time.Hour))).Unix(),
+			ExpiryNotificationsSent:  test.sent,
+		}
+
+		if test.shouldSendEmail {
+			emailSvc.On("SendEmail", &email.SendEmailInput{
+				FromAddress:  "from@example.com",
+				ToAddresses:  []string{"recipA@example.com"},
+				BCCAddresses: []string{"bcc@example.com"},
+				Subject:      test.expectedSubject,
+				BodyHTML:     "<p>Lease for test-user expires on " + time.Unix(lease.ExpiresOn, 0).UTC().Format(time.RFC1123) + "</p>",
+				BodyText:     "Lease for test-user expires",
+			}).Return(nil)
+			dbSvc.On("UpdateLease", mock.Anything, []string{"ExpiryNotificationsSent"}).
+				Run(func(args mock.Arguments) {
+					require.Equal(t, test.expectedSent, args.Get(0).(db.Lease).ExpiryNotificationsSent)
+				}).
+				Return(lease, nil)
+		}
+
+		err := sendExpiryNotificationEmail(&sendExpiryNotificationEmailInput{
+			lease:                             lease,
+			dbSvc:                             dbSvc,
+			emailSvc:                          emailSvc,
+			currentTime:                       now,
+			budgetNotificationFromEmail:       "from@example.com",
+			budgetNotificationBCCEmails:       []string{"bcc@example.com"},
+			expiryNotificationHours:           []float64{72, 24, 1},
+			expiryNotificationTemplateHTML:    "<p>Lease for {{.Lease.PrincipalID}} expires on {{.ExpiresOn}}</p>",
+			expiryNotificationTemplateText:    "Lease for {{.Lease.PrincipalID}} expires",
+			expiryNotificationTemplateSubject: "Lease expires in {{.HoursRemaining}} hours [{{.Lease.AccountID}}]",
+		})
+		require.Nil(t, err)
+
+		dbSvc.AssertExpectations(t)
+		emailSvc.AssertExpectations(t)
+	}
+
+	t.Run("should not warn before the first threshold", func(t *testing.T) {
+		expiryTest(&expiryTestInput{hoursRemaining: 100})
+	})
+
+	t.Run("should warn at the closest threshold", func(t *testing.T) {
+		expiryTest(&expiryTestInput{
+			hoursRemaining:  20,
+			shouldSendEmail: true,
+			expectedSubject: "Lease expires in 20 hours [1234567890]",
+			// The missed 72h warning is recorded, and not sent
+			expectedSent: []float64{72, 24},
+		})
+	})
+
+	t.Run("should only warn once per threshold", func(t *testing.T) {
+		expiryTest(&expiryTestInput{hoursRemaining: 20, sent: []float64{72, 24}})
+	})
+
+	t.Run("should warn at the next threshold", func(t *testing.T) {
+		expiryTest(&expiryTestInput{
+			hoursRemaining:  0.5,
+			sent:            []float64{72, 24},
+			shouldSendEmail: true,
+			expectedSubject: "Lease expires in 1 hours [1234567890]",
+			expectedSent:    []float64{72, 24, 1},
+		})
+	})
+
+	t.Run("should not warn for inactive leases", func(t *testing.T) {
+		expiryTest(&expiryTestInput{hoursRemaining: 20, leaseStatus: db.Inactive})
+	})
+
+	t.Run("should warn again after the lease is extended", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		emailSvc := &emailMocks.Service{}
+		expiresOn := now.Add(20 * time.Hour).Unix()
+		_, err := dbSvc.PutLease(db.Lease{
+			AccountID:                "1234567890",
+			PrincipalID:              "test-user",
+			LeaseStatus:              db.Active,
+			BudgetAmount:             100,
+			BudgetNotificationEmails: []string{"recipA@example.com"},
+			ExpiresOn:                expiresOn,
+			ExpiryNotificationsSent:  []float64{72, 24},
+		})
+		require.Nil(t, err)
+
+		lease, err := dbSvc.ExtendLease("1234567890", "test-user", db.Active, expiresOn, 100,
+			now.Add(48*time.Hour).Unix(), 100)
+		require.Nil(t, err)
+		require.Empty(t, lease.ExpiryNotificationsSent)
+
+		emailSvc.On("SendEmail", mock.MatchedBy(func(input *email.SendEmailInput) bool {
+			return input.Subject == "Lease expires in 48 hours [1234567890]"
+		})).Return(nil)
+		err = sendExpiryNotificationEmail(&sendExpiryNotificationEmailInput{
+			lease:                             lease,
+			dbSvc:                             dbSvc,
+			emailSvc:                          emailSvc,
+			currentTime:                       now,
+			budgetNotificationFromEmail:       "from@example.com",
+			expiryNotificationHours:           []float64{72, 24, 1},
+			expiryNotificationTemplateHTML:    "<p>Lease for {{.Lease.PrincipalID}} expires on {{.ExpiresOn}}</p>",
+			expiryNotificationTemplateText:    "Lease for {{.Lease.PrincipalID}} expires",
+			expiryNotificationTemplateSubject: "Lease expires in {{.HoursRemaining}} hours [{{.Lease.AccountID}}]",
+		})
+		require.Nil(t, err)
+		emailSvc.AssertExpectations(t)
+
+		lease, err = dbSvc.GetLease("1234567890", "test-user")
+		require.Nil(t, err)
+		require.Equal(t, []float64{72}, lease.ExpiryNotificationsSent)
+	})
+}
+
+func TestSendBudgetNotificationEmail(t *testing.T) {
+	t.Run("should measure principal thresholds against the principal budget", func(t *testing.T) {
+		dbSvc := &dbMocks.DBer{}
+		emailSvc := &emailMocks.Service{}
+		lease := &db.Lease{
+			AccountID:                "1234567890",
+			PrincipalID:              "test-user",
+			BudgetAmount:             100,
+			BudgetCurrency:           "EUR",
+			BudgetNotificationEmails: []string{"recipA@example.com"},
+		}
+
+		// Principal spend is compared to, and labelled with, the USD principal budget
+		emailSvc.On("SendEmail", &email.SendEmailInput{
+			FromAddress:  "from@example.com",
+			ToAddresses:  []string{"recipA@example.com"},
+			BCCAddresses: []string{},
+			Subject:      "Principal at 75% of budget",
+			BodyHTML:     "Principal spend is 800 of 1000 USD",
+			BodyText:     "Principal spend is 800 of 1000 USD",
+		}).Return(nil)
+		dbSvc.On("UpdateLease", mock.Anything, []string{"PrincipalThresholdsSent"}).
+			Run(func(args mock.Arguments) {
+				require.Equal(t, []float64{75}, args.Get(0).(db.Lease).PrincipalThresholdsSent)
+			}).
+			Return(lease, nil)
+
+		err := sendBudgetNotificationEmail(&sendBudgetNotificationEmailInput{
+			lease:                                  lease,
+			dbSvc:                                  dbSvc,
+			emailSvc:                               emailSvc,
+			budgetNotificationFromEmail:            "from@example.com",
+			budgetNotificationBCCEmails:            []string{},
+			budgetNotificationTemplateHTML:         "Principal spend is {{.ActualSpend}} of {{.BudgetAmount}} {{.Currency}}",
+			budgetNotificationTemplateText:         "Principal spend is {{.ActualSpend}} of {{.BudgetAmount}} {{.Currency}}",
+			budgetNotificationTemplateSubject:      "Principal {{if .IsOverBudget}}over budget{{else}}at {{.ThresholdPercentile}}% of budget{{end}}",
+			budgetNotificationThresholdPercentiles: []float64{75, 100},
+			actualLeaseSpend:                       10,
+			actualPrincipalSpend:                   800,
+			principalBudgetAmount:                  1000,
+		})
+		require.Nil(t, err)
+		emailSvc.AssertExpectations(t)
+		dbSvc.AssertExpectations(t)
+	})
+
+	t.Run("should notify again after the lease budget is changed", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		emailSvc := &emailMocks.Service{}
+		_, err := dbSvc.PutLease(db.Lease{
+			AccountID:                "1234567890",
+			PrincipalID:              "test-user",
+			LeaseStatus:              db.Active,
+			BudgetAmount:             100,
+			BudgetCurrency:           "USD",
+			BudgetNotificationEmails: []string{"recipA@example.com"},
+			ExpiresOn:                1000,
+			BudgetThresholdsSent:     []float64{75},
+		})
+		require.Nil(t, err)
+
+		// Extending the lease, without changing the budget,
+		// keeps the notified thresholds
+		lease, err := dbSvc.ExtendLease("1234567890", "test-user", db.Active, 1000, 100, 2000, 100)
+		require.Nil(t, err)
+		require.Equal(t, []float64{75}, lease.BudgetThresholdsSent)
+
+		lease, err = dbSvc.ExtendLease("1234567890", "test-user", db.Active, 2000, 100, 2000, 200)
+		require.Nil(t, err)
+		require.Empty(t, lease.BudgetThresholdsSent)
+
+		emailSvc.On("SendEmail", mock.MatchedBy(func(input *email.SendEmailInput) bool {
+			return input.Subject == "Lease at 75% of budget"
+		})).Return(nil)
+		err = sendBudgetNotificationEmail(&sendBudgetNotificationEmailInput{
+			lease:                                  lease,
+			dbSvc:                                  dbSvc,
+			emailSvc:                               emailSvc,
+			budgetNotificationFromEmail:            "from@example.com",
+			budgetNotificationBCCEmails:            []string{},
+			budgetNotificationTemplateHTML:         "Lease spend is {{.ActualSpend}} of {{.BudgetAmount}} {{.Currency}}",
+			budgetNotificationTemplateText:         "Lease spend is {{.ActualSpend}} of {{.BudgetAmount}} {{.Currency}}",
+			budgetNotificationTemplateSubject:      "Lease {{if .IsOverBudget}}over budget{{else}}at {{.ThresholdPercentile}}% of budget{{end}}",
+			budgetNotificationThresholdPercentiles: []float64{75, 100},
+			actualLeaseSpend:                       160,
+			principalBudgetAmount:                  1000,
+		})
+		require.Nil(t, err)
+		emailSvc.AssertExpectations(t)
+
+		lease, err = dbSvc.GetLease("1234567890", "test-user")
+		require.Nil(t, err)
+		require.Equal(t, []float64{75}, lease.BudgetThresholdsSent)
+	})
+}
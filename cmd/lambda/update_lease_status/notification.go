@@ -2,16 +2,21 @@ package main
 
 import (
 	"bytes"
+	"github.com/Optum/dce/pkg/currency"
 	"github.com/Optum/dce/pkg/db"
 	"github.com/Optum/dce/pkg/email"
+	"github.com/Optum/dce/pkg/leaserule"
 	"html/template"
 	"log"
+	"math"
 	"sort"
 	"strings"
+	"time"
 )
 
 type sendBudgetNotificationEmailInput struct {
 	lease                                  *db.Lease
+	dbSvc                                  db.DBer
 	emailSvc                               email.Service
 	budgetNotificationFromEmail            string
 	budgetNotificationBCCEmails            []string
@@ -21,8 +26,12 @@ type sendBudgetNotificationEmailInput struct {
 	budgetNotificationThresholdPercentiles []float64
 	actualLeaseSpend                       float64
 	actualPrincipalSpend                   float64
+	principalBudgetAmount                  float64
 }
 
+// sendBudgetNotificationEmail notifies the lease owner when the lease or principal
+// spend passes a budget threshold. Each threshold is sent once per lease, and is
+// recorded in the lease's BudgetThresholdsSent or PrincipalThresholdsSent.
 func sendBudgetNotificationEmail(input *sendBudgetNotificationEmailInput) error {
 
 	// Determine the highest lease budget threshold passed
@@ -35,10 +44,18 @@ func sendBudgetNotificationEmail(input *sendBudgetNotificationEmailInput) error
 	// Determine the highest principal budget threshold passed
 	thresholdPrincipalPercentile := determineThresholdPercentile(&determineThresholdPercentileInput{
 		thresholdPercentiles: input.budgetNotificationThresholdPercentiles,
-		budgetAmount:         input.lease.BudgetAmount,
+		budgetAmount:         input.principalBudgetAmount,
 		actualSpend:          input.actualPrincipalSpend,
 	})
 
+	// Skip thresholds which were already sent for this lease
+	if containsFloat(input.lease.BudgetThresholdsSent, thresholdLeasePercentile) {
+		thresholdLeasePercentile = 0
+	}
+	if containsFloat(input.lease.PrincipalThresholdsSent, thresholdPrincipalPercentile) {
+		thresholdPrincipalPercentile = 0
+	}
+
 	if thresholdLeasePercentile == 0 && thresholdPrincipalPercentile == 0 {
 		return nil
 	}
@@ -51,21 +68,33 @@ func sendBudgetNotificationEmail(input *sendBudgetNotificationEmailInput) error
 	}
 
 	// if both lease budget threshold and principal budget threshold passed, notify for lease budget threshold only
+	// Lease spend is in the lease's budget currency, and principal spend in USD
 	thresholdPercentile := 0.0
 	actualSpend := 0.0
+	budgetAmount := 0.0
+	spendCurrency := ""
+	sentField := ""
 	if (thresholdLeasePercentile > 0 && thresholdPrincipalPercentile > 0) || thresholdLeasePercentile > 0 {
 		thresholdPercentile = thresholdLeasePercentile
 		actualSpend = input.actualLeaseSpend
+		budgetAmount = input.lease.BudgetAmount
+		spendCurrency = leaseBudgetCurrency(input.lease)
+		sentField = "BudgetThresholdsSent"
+		input.lease.BudgetThresholdsSent = append(input.lease.BudgetThresholdsSent, thresholdPercentile)
 	} else if thresholdPrincipalPercentile > 0 {
 		thresholdPercentile = thresholdPrincipalPercentile
 		actualSpend = input.actualPrincipalSpend
+		budgetAmount = input.principalBudgetAmount
+		spendCurrency = currency.USD
+		sentField = "PrincipalThresholdsSent"
+		input.lease.PrincipalThresholdsSent = append(input.lease.PrincipalThresholdsSent, thresholdPercentile)
 	}
 
 	log.Printf("Budget notification threshold hit at %.0f%%", thresholdPercentile)
 	log.Printf("Sending budget notification emails for lease %s @ %s to %s",
 		input.lease.PrincipalID, input.lease.AccountID, strings.Join(input.lease.BudgetNotificationEmails, ","))
 
-	return sendEmail(&sendEmailInput{
+	err := sendEmail(&sendEmailInput{
 		lease:                             input.lease,
 		emailSvc:                          input.emailSvc,
 		budgetNotificationFromEmail:       input.budgetNotificationFromEmail,
@@ -74,7 +103,16 @@ func sendBudgetNotificationEmail(input *sendBudgetNotificationEmailInput) error
 		budgetNotificationTemplateText:    input.budgetNotificationTemplateText,
 		budgetNotificationTemplateSubject: input.budgetNotificationTemplateSubject,
 		actualSpend:                       actualSpend,
+		budgetAmount:                      budgetAmount,
+		spendCurrency:                     spendCurrency,
 	}, thresholdPercentile)
+	if err != nil {
+		return err
+	}
+
+	// Record the threshold, so it isn't sent again
+	_, err = input.dbSvc.UpdateLease(*input.lease, []string{sentField})
+	return err
 }
 
 func renderTemplate(id string, templateStr string, data interface{}) (string, error) {
@@ -120,6 +158,8 @@ type sendEmailInput struct {
 	budgetNotificationTemplateText    string
 	budgetNotificationTemplateSubject string
 	actualSpend                       float64
+	budgetAmount                      float64
+	spendCurrency                     string
 }
 
 func sendEmail(input *sendEmailInput, thresholdPercentile float64) error {
@@ -128,12 +168,16 @@ func sendEmail(input *sendEmailInput, thresholdPercentile float64) error {
 	templateData := struct {
 		Lease               db.Lease
 		ActualSpend         float64
+		BudgetAmount        float64
+		Currency            string
 		IsOverBudget        bool
 		ThresholdPercentile int
 	}{
 		Lease:               *input.lease,
 		ActualSpend:         input.actualSpend,
-		IsOverBudget:        input.actualSpend >= input.lease.BudgetAmount,
+		BudgetAmount:        input.budgetAmount,
+		Currency:            input.spendCurrency,
+		IsOverBudget:        input.actualSpend >= input.budgetAmount,
 		ThresholdPercentile: int(thresholdPercentile),
 	}
 	bodyHTML, err := renderTemplate("htmlEmail", input.budgetNotificationTemplateHTML, templateData)
@@ -159,3 +203,157 @@ func sendEmail(input *sendEmailInput, thresholdPercentile float64) error {
 		Subject:      subject,
 	})
 }
+
+type sendExpiryNotificationEmailInput struct {
+	lease                             *db.Lease
+	dbSvc                             db.DBer
+	emailSvc                          email.Service
+	currentTime                       time.Time
+	budgetNotificationFromEmail       string
+	budgetNotificationBCCEmails       []string
+	expiryNotificationHours           []float64
+	expiryNotificationTemplateHTML    string
+	expiryNotificationTemplateText    string
+	expiryNotificationTemplateSubject string
+}
+
+// sendExpiryNotificationEmail warns the lease owner that the lease will expire soon.
+// Each configured warning (hours before the lease expires) is sent once per lease,
+// and is recorded in the lease's ExpiryNotificationsSent.
+func sendExpiryNotificationEmail(input *sendExpiryNotificationEmailInput) error {
+	hoursRemaining := time.Unix(input.lease.ExpiresOn, 0).Sub(input.currentTime).Hours()
+	if input.lease.LeaseStatus != db.Active || hoursRemaining <= 0 {
+		return nil
+	}
+
+	// Find the closest warning we've reached, which has not been sent.
+	// Earlier warnings which were missed (eg. for short leases) are skipped.
+	thresholdHours := 0.0
+	sent := append([]float64{}, input.lease.ExpiryNotificationsSent...)
+	for _, hours := range input.expiryNotificationHours {
+		if hoursRemaining > hours || containsFloat(sent, hours) {
+			continue
+		}
+		if thresholdHours == 0 || hours < thresholdHours {
+			thresholdHours = hours
+		}
+		sent = append(sent, hours)
+	}
+	if thresholdHours == 0 {
+		return nil
+	}
+
+	if len(input.lease.BudgetNotificationEmails)+len(input.budgetNotificationBCCEmails) == 0 {
+		log.Printf("Skipping expiry notification emails: "+
+			"no notification emails addressses were provided for lease %s @ %s",
+			input.lease.PrincipalID, input.lease.AccountID)
+		return nil
+	}
+
+	// Render email templates
+	templateData := struct {
+		Lease          db.Lease
+		ExpiresOn      string
+		HoursRemaining int
+		ThresholdHours float64
+	}{
+		Lease:          *input.lease,
+		ExpiresOn:      time.Unix(input.lease.ExpiresOn, 0).UTC().Format(time.RFC1123),
+		HoursRemaining: int(math.Ceil(hoursRemaining)),
+		ThresholdHours: thresholdHours,
+	}
+	bodyHTML, err := renderTemplate("htmlEmail", input.expiryNotificationTemplateHTML, templateData)
+	if err != nil {
+		return err
+	}
+	bodyText, err := renderTemplate("textEmail", input.expiryNotificationTemplateText, templateData)
+	if err != nil {
+		return err
+	}
+	subject, err := renderTemplate("emailSubject", input.expiryNotificationTemplateSubject, templateData)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Sending %.0fh expiry notification emails for lease %s @ %s to %s", thresholdHours,
+		input.lease.PrincipalID, input.lease.AccountID, strings.Join(input.lease.BudgetNotificationEmails, ","))
+	err = input.emailSvc.SendEmail(&email.SendEmailInput{
+		FromAddress:  input.budgetNotificationFromEmail,
+		ToAddresses:  input.lease.BudgetNotificationEmails,
+		BCCAddresses: input.budgetNotificationBCCEmails,
+		BodyHTML:     bodyHTML,
+		BodyText:     bodyText,
+		Subject:      subject,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Record the warnings, so they aren't sent again
+	input.lease.ExpiryNotificationsSent = sent
+	_, err = input.dbSvc.UpdateLease(*input.lease, []string{"ExpiryNotificationsSent"})
+	return err
+}
+
+type sendLeaseRuleWarningEmailInput struct {
+	lease                           *db.Lease
+	emailSvc                        email.Service
+	rules                           []*leaserule.Rule
+	budgetNotificationFromEmail     string
+	budgetNotificationBCCEmails     []string
+	leaseRuleWarningTemplateHTML    string
+	leaseRuleWarningTemplateText    string
+	leaseRuleWarningTemplateSubject string
+}
+
+// sendLeaseRuleWarningEmail notifies the lease owner of
+// lease rules with the warn action, which fired for the lease
+func sendLeaseRuleWarningEmail(input *sendLeaseRuleWarningEmailInput) error {
+	if len(input.lease.BudgetNotificationEmails)+len(input.budgetNotificationBCCEmails) == 0 {
+		log.Printf("Skipping lease rule warning emails: "+
+			"no notification emails addressses were provided for lease %s @ %s",
+			input.lease.PrincipalID, input.lease.AccountID)
+		return nil
+	}
+
+	// Render email templates
+	templateData := struct {
+		Lease db.Lease
+		Rules []*leaserule.Rule
+	}{
+		Lease: *input.lease,
+		Rules: input.rules,
+	}
+	bodyHTML, err := renderTemplate("htmlEmail", input.leaseRuleWarningTemplateHTML, templateData)
+	if err != nil {
+		return err
+	}
+	bodyText, err := renderTemplate("textEmail", input.leaseRuleWarningTemplateText, templateData)
+	if err != nil {
+		return err
+	}
+	subject, err := renderTemplate("emailSubject", input.leaseRuleWarningTemplateSubject, templateData)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Sending lease rule warning emails for lease %s @ %s to %s",
+		input.lease.PrincipalID, input.lease.AccountID, strings.Join(input.lease.BudgetNotificationEmails, ","))
+	return input.emailSvc.SendEmail(&email.SendEmailInput{
+		FromAddress:  input.budgetNotificationFromEmail,
+		ToAddresses:  input.lease.BudgetNotificationEmails,
+		BCCAddresses: input.budgetNotificationBCCEmails,
+		BodyHTML:     bodyHTML,
+		BodyText:     bodyText,
+		Subject:      subject,
+	})
+}
+
+func containsFloat(list []float64, val float64) bool {
+	for _, item := range list {
+		if item == val {
+			return true
+		}
+	}
+	return false
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/Optum/dce/pkg/awsiface"
 	"github.com/Optum/dce/pkg/budget"
 	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/currency"
 	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/leaserule"
 	"github.com/Optum/dce/pkg/usage"
 	"github.com/aws/aws-sdk-go/service/costexplorer"
 	"github.com/pkg/errors"
@@ -21,15 +23,18 @@ type calculateSpendInput struct {
 	usageSvc              usage.Service
 	awsSession            awsiface.AwsSession
 	principalBudgetPeriod string
+	rates                 currency.RateProvider
 }
 
-// calculateLeaseSpend calculates amount spent by User principal for current lease
-func calculateLeaseSpend(input *calculateSpendInput) (float64, error) {
+// calculateLeaseSpend calculates amount spent by User principal for current lease,
+// in the budget currency of the lease.
+// Also returns the spend of the lease on each day.
+func calculateLeaseSpend(input *calculateSpendInput) (float64, []leaserule.DailySpend, error) {
 	adminRoleArn := input.account.AdminRoleArn
 	log.Printf("Assuming role %s for budget check", adminRoleArn)
 	assumedSession, err := input.tokenSvc.NewSession(input.awsSession, adminRoleArn)
 	if err != nil {
-		return 0, errors.Wrapf(err, "Failed to assume role %s", adminRoleArn)
+		return 0, nil, errors.Wrapf(err, "Failed to assume role %s", adminRoleArn)
 	}
 
 	// Configure the CostExplorer SDK for the Service
@@ -43,12 +48,19 @@ func calculateLeaseSpend(input *calculateSpendInput) (float64, error) {
 	usageEndTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), 23, 59, 59, 0, time.UTC)
 
 	log.Printf("usageStart: %d and usageEnd :%d", usageStartTime.Unix(), usageEndTime.Unix())
-	todayCostAmount, err := input.budgetSvc.CalculateTotalSpend(usageStartTime, usageStartTime.AddDate(0, 0, 1))
+	todayCostAmount, todayCostCurrency, err := input.budgetSvc.CalculateTotalSpend(usageStartTime, usageStartTime.AddDate(0, 0, 1))
 	if err != nil {
-		return 0, errors.Wrapf(err, "Failed to calculate spend for account %s", input.lease.AccountID)
+		return 0, nil, errors.Wrapf(err, "Failed to calculate spend for account %s", input.lease.AccountID)
 	}
 
-	log.Printf("usage for today: %f", todayCostAmount)
+	log.Printf("usage for today: %f %s", todayCostAmount, todayCostCurrency)
+
+	// Convert spend to the budget currency of the lease
+	budgetCurrency := leaseBudgetCurrency(input.lease)
+	todayConvertedAmount, err := currency.Convert(input.rates, todayCostAmount, todayCostCurrency, budgetCurrency)
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "Failed to convert spend for account %s", input.lease.AccountID)
+	}
 
 	// Set Timetolive to one month from StartDate
 	usageItem := usage.Usage{
@@ -57,16 +69,19 @@ func calculateLeaseSpend(input *calculateSpendInput) (float64, error) {
 		PrincipalID:  input.lease.PrincipalID,
 		AccountID:    input.account.ID,
 		CostAmount:   todayCostAmount,
-		CostCurrency: "USD",
+		CostCurrency: todayCostCurrency,
 		TimeToLive:   usageStartTime.AddDate(0, 1, 0).Unix(),
+		// Store the converted amount, so that later checks use
+		// the exchange rate of the day the cost was incurred
+		ConvertedCostAmount:   todayConvertedAmount,
+		ConvertedCostCurrency: budgetCurrency,
 	}
 
 	input.usageSvc.PutUsage(usageItem)
 
-	// Budget period starts last time the lease was reset.
-	// We can look at the `leaseStatusModifiedOn` to know
-	// when the lease status changed from `ResetLock` --> `Active`
-	budgetStartTime := time.Unix(input.lease.LeaseStatusModifiedOn, 0)
+	// Budget period starts when the lease was created,
+	// so that freezing and unfreezing the lease does not reset its spend
+	budgetStartTime := time.Unix(input.lease.CreatedOn, 0)
 	// budget's `endTime` is set to yesterday
 	budgetEndTime := usageEndTime.AddDate(0, 0, -1)
 
@@ -78,25 +93,34 @@ func calculateLeaseSpend(input *calculateSpendInput) (float64, error) {
 	// Query Usage cache DB
 	usageRecords, err := input.usageSvc.GetUsageByDateRange(budgetStartTime, budgetEndTime)
 	if err != nil {
-		return 0, errors.Wrapf(err, "Failed to retrieve usage for account %s", input.lease.AccountID)
+		return 0, nil, errors.Wrapf(err, "Failed to retrieve usage for account %s", input.lease.AccountID)
 	}
 
 	// DynDB is eventually consistent. Pull cache DB for SUN-->yesterday, then add the known value for today
-	spend := todayCostAmount
+	spend := todayConvertedAmount
+	dailySpend := []leaserule.DailySpend{
+		{StartDate: usageStartTime.Unix(), Amount: todayConvertedAmount},
+	}
 	for _, usage := range usageRecords {
 		log.Printf("usage records retrieved: %v", usage)
 		if usage.PrincipalID == input.lease.PrincipalID && usage.AccountID == input.lease.AccountID {
-			spend = spend + usage.CostAmount
+			amount, err := usage.AmountIn(budgetCurrency, input.rates)
+			if err != nil {
+				return 0, nil, errors.Wrapf(err, "Failed to convert usage for account %s", input.lease.AccountID)
+			}
+			spend = spend + amount
+			dailySpend = append(dailySpend, leaserule.DailySpend{StartDate: usage.StartDate, Amount: amount})
 		}
 	}
 
-	log.Printf("Lease for %s @ %s has spent $%.2f of their $%.2f budget",
-		input.lease.PrincipalID, input.lease.AccountID, spend, input.lease.BudgetAmount)
+	log.Printf("Lease for %s @ %s has spent %.2f of their %.2f %s budget",
+		input.lease.PrincipalID, input.lease.AccountID, spend, input.lease.BudgetAmount, budgetCurrency)
 
-	return spend, nil
+	return spend, dailySpend, nil
 }
 
-// calculatePrincipalSpend calculates the amount spent by User principal for current billing period
+// calculatePrincipalSpend calculates the amount spent by User principal for current billing period,
+// in USD (the currency of the principal budget amount)
 func calculatePrincipalSpend(input *calculateSpendInput) (float64, error) {
 
 	// Budget period starts based on principal_budget_period variable value
@@ -119,15 +143,28 @@ func calculatePrincipalSpend(input *calculateSpendInput) (float64, error) {
 	for _, usage := range usageRecords {
 		log.Printf("usage records retrieved: %v", usage)
 		if usage.PrincipalID == input.lease.PrincipalID {
-			spend = spend + usage.CostAmount
+			amount, err := usage.AmountIn(currency.USD, input.rates)
+			if err != nil {
+				return 0, errors.Wrapf(err, "Failed to convert usage for principal %s", input.lease.PrincipalID)
+			}
+			spend = spend + amount
 		}
 	}
 
-	log.Printf("Principal %s has spent $%.2f of their current principal budget amount",
-		input.lease.PrincipalID, spend)
+	log.Printf("Principal %s has spent %.2f %s of their current principal budget amount",
+		input.lease.PrincipalID, spend, currency.USD)
 	return spend, nil
 }
 
+// leaseBudgetCurrency returns the budget currency of the lease.
+// Leases created without a currency are budgeted in USD.
+func leaseBudgetCurrency(lease *db.Lease) string {
+	if lease.BudgetCurrency == "" {
+		return currency.USD
+	}
+	return lease.BudgetCurrency
+}
+
 // getBeginningOfCurrentBillingPeriod returns starts of the billing period based on budget period
 func getBeginningOfCurrentBillingPeriod(input string) time.Time {
 	currentTime := time.Now()
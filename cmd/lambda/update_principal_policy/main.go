@@ -11,9 +11,12 @@ import (
 	"github.com/Optum/dce/pkg/awsiface"
 	"github.com/Optum/dce/pkg/common"
 	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/policyprofile"
 	"github.com/Optum/dce/pkg/rolemanager"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/iam"
@@ -27,7 +30,17 @@ func main() {
 }
 
 func handler(ctx context.Context, snsEvent events.SNSEvent) error {
-	dbSvc, err := db.NewFromEnv()
+	baseDbSvc, err := db.NewFromEnv()
+	if err != nil {
+		log.Printf("Unable to setup DB Service: %s", err.Error())
+		return err
+	}
+	historySvc, err := history.NewFromEnv()
+	if err != nil {
+		log.Printf("Unable to setup History Service: %s", err.Error())
+		return err
+	}
+	dbSvc := history.NewRecorder(baseDbSvc, historySvc, "update_principal_policy")
 	awsSession := session.Must(session.NewSession())
 	tokenSvc := common.STS{Client: sts.New(awsSession)}
 	s3Svc := common.S3{
@@ -35,24 +48,26 @@ func handler(ctx context.Context, snsEvent events.SNSEvent) error {
 		Manager: s3manager.NewDownloader(awsSession),
 	}
 	roleManagerSvc := &rolemanager.IAMPolicyManager{}
-
+	policyProfiles, err := policyprofile.NewConfigFromEnv()
 	if err != nil {
-		log.Printf("Unable to setup DB Service: %s", err.Error())
+		log.Printf("Unable to setup policy profiles: %s", err.Error())
 		return err
 	}
+	resetCompleteTopicArn := common.RequireEnv("RESET_COMPLETE_TOPIC_ARN")
+	trustPolicy := rolemanager.NewPrincipalTrustPolicyTemplate(s3Svc, common.RequireEnv("ARTIFACTS_BUCKET"),
+		common.GetEnv("PRINCIPAL_TRUST_POLICY_S3_KEY", ""), common.GetEnv("PRINCIPAL_TRUST_IDP_ARNS", ""))
 
 	for _, record := range snsEvent.Records {
 		snsRecord := record.SNS
 
-		var lease response.LeaseResponse
-		err := json.Unmarshal([]byte(snsRecord.Message), &lease)
+		accountID, err := accountIDFromMessage(snsRecord.TopicArn, snsRecord.Message, resetCompleteTopicArn)
 		if err != nil {
 			log.Printf("Failed to read SNS message %s: %s", snsRecord.Message, err.Error())
 			return err
 		}
 
 		err = processRecord(processRecordInput{
-			AccountID:            lease.AccountID,
+			AccountID:            accountID,
 			DbSvc:                dbSvc,
 			StoragerSvc:          s3Svc,
 			TokenSvc:             tokenSvc,
@@ -64,11 +79,30 @@ func handler(ctx context.Context, snsEvent events.SNSEvent) error {
 			Regions:              strings.Split(common.RequireEnv("ALLOWED_REGIONS"), ","),
 			PolicyBucket:         common.RequireEnv("ARTIFACTS_BUCKET"),
 			PolicyBucketKey:      common.RequireEnv("PRINCIPAL_POLICY_S3_KEY"),
+			PolicyProfiles:       policyProfiles,
+			PermissionsBoundary:  common.GetEnv("PRINCIPAL_PERMISSIONS_BOUNDARY", ""),
+			MasterAccountID:      common.RequireEnv("MASTER_ACCOUNT_ID"),
+			TrustPolicy:          trustPolicy,
 		})
 	}
 	return nil
 }
 
+// accountIDFromMessage returns the ID of the account to update.
+// Messages from the reset-complete topic contain the reset account,
+// so the policy reverts to the default once a lease ends.
+// Messages from the lease topics contain the lease.
+func accountIDFromMessage(topicArn string, message string, resetCompleteTopicArn string) (string, error) {
+	if topicArn == resetCompleteTopicArn {
+		var account response.AccountResponse
+		err := json.Unmarshal([]byte(message), &account)
+		return account.ID, err
+	}
+	var lease response.LeaseResponse
+	err := json.Unmarshal([]byte(message), &lease)
+	return lease.AccountID, err
+}
+
 type processRecordInput struct {
 	AccountID            string
 	DbSvc                db.DBer
@@ -82,6 +116,13 @@ type processRecordInput struct {
 	Regions              []string
 	PolicyBucket         string
 	PolicyBucketKey      string
+	PolicyProfiles       *policyprofile.Config
+	PermissionsBoundary  string
+	MasterAccountID      string
+	TrustPolicy          *rolemanager.PrincipalTrustPolicyTemplate
+	// NewIAM creates an IAM client for the child account session.
+	// Defaults to iam.New.
+	NewIAM func(session awsiface.AwsSession) awsiface.IAM
 }
 
 func processRecord(input processRecordInput) error {
@@ -102,13 +143,33 @@ func processRecord(input processRecordInput) error {
 	}
 
 	policy, policyHash, err := input.StoragerSvc.GetTemplateObject(input.PolicyBucket, input.PolicyBucketKey, getPolicyInput{
-		PrincipalPolicyArn:   principalPolicyArn.String(),
-		PrincipalRoleArn:     fmt.Sprintf("arn:aws:iam::%s:role/%s", input.AccountID, input.PrincipalRoleName),
-		PrincipalIAMDenyTags: input.PrincipalIAMDenyTags,
-		AdminRoleArn:         accountRes.AdminRoleArn,
+		PrincipalPolicyArn:     principalPolicyArn.String(),
+		PrincipalRoleArn:       fmt.Sprintf("arn:aws:iam::%s:role/%s", input.AccountID, input.PrincipalRoleName),
+		PrincipalIAMDenyTags:   input.PrincipalIAMDenyTags,
+		AdminRoleArn:           accountRes.AdminRoleArn,
+		Regions:                input.Regions,
+		PermissionsBoundaryArn: rolemanager.PermissionsBoundaryArn(input.PermissionsBoundary, input.AccountID),
 	})
+	if err != nil {
+		log.Printf("Failed to render principal policy for account %s: %s", input.AccountID, err)
+		return err
+	}
+
+	// Add the statements of the account's lease, if any,
+	// or freeze the account if its lease is frozen
+	policy, policyHash, err = input.PolicyProfiles.AccountPolicy(input.DbSvc, input.AccountID, policy, policyHash)
+	if err != nil {
+		log.Printf("Failed to get policy statements for account %s: %s", input.AccountID, err)
+		return err
+	}
+
+	trustPolicy, trustPolicyHash, err := input.TrustPolicy.Render(input.AccountID, input.MasterAccountID)
+	if err != nil {
+		log.Printf("Failed to render principal trust policy for account %s: %s", input.AccountID, err)
+		return err
+	}
 
-	if policyHash == accountRes.PrincipalPolicyHash {
+	if policyHash == accountRes.PrincipalPolicyHash && trustPolicyHash == accountRes.PrincipalTrustPolicyHash {
 		log.Printf("Policy already matches.  Not updating '%s'", principalPolicyArn.String())
 		return nil
 	}
@@ -118,33 +179,68 @@ func processRecord(input processRecordInput) error {
 		log.Printf("Failed to assume role '%s': %s", accountRes.AdminRoleArn, err.Error())
 		return err
 	}
-	iamSvc := iam.New(accountSession)
+	var iamSvc awsiface.IAM
+	if input.NewIAM != nil {
+		iamSvc = input.NewIAM(accountSession)
+	} else {
+		iamSvc = iam.New(accountSession)
+	}
 
-	// Update the Policy
-	input.RoleManager.SetIAMClient(iamSvc)
-	log.Printf("Update policy '%s' to hash '%s' from '%s'.", principalPolicyArn.String(), accountRes.PrincipalPolicyHash, policyHash)
-	err = input.RoleManager.MergePolicy(&rolemanager.MergePolicyInput{
-		PolicyName:     input.PrincipalPolicyName,
-		PolicyArn:      principalPolicyArn,
-		PolicyDocument: policy,
-	})
-	if err != nil {
-		log.Printf("Failed updating the policy '%s': %s", principalPolicyArn.String(), err)
-		return err
+	if policyHash != accountRes.PrincipalPolicyHash {
+		// Update the Policy
+		input.RoleManager.SetIAMClient(iamSvc)
+		log.Printf("Update policy '%s' to hash '%s' from '%s'.", principalPolicyArn.String(), accountRes.PrincipalPolicyHash, policyHash)
+		err = input.RoleManager.MergePolicy(&rolemanager.MergePolicyInput{
+			PolicyName:     input.PrincipalPolicyName,
+			PolicyArn:      principalPolicyArn,
+			PolicyDocument: policy,
+		})
+		if err != nil {
+			log.Printf("Failed updating the policy '%s': %s", principalPolicyArn.String(), err)
+			return err
+		}
+
+		log.Printf("Update account '%s' resource record.  Policy Hash from '%s' to '%s'", input.AccountID, accountRes.PrincipalPolicyHash, policyHash)
+		_, err = input.DbSvc.UpdateAccountPrincipalPolicyHash(input.AccountID, accountRes.PrincipalPolicyHash, policyHash)
+		if err != nil {
+			log.Printf("Failed to update account '%s' resource record.  Policy Hash from '%s' to '%s': %s",
+				input.AccountID, accountRes.PrincipalPolicyHash, policyHash, err)
+			return err
+		}
 	}
 
-	log.Printf("Update account '%s' resource record.  Policy Hash from '%s' to '%s'", input.AccountID, accountRes.PrincipalPolicyHash, policyHash)
-	_, err = input.DbSvc.UpdateAccountPrincipalPolicyHash(input.AccountID, accountRes.PrincipalPolicyHash, policyHash)
-	if err != nil {
-		log.Printf("Failed to update account '%s' resource record.  Policy Hash from '%s' to '%s': %s",
-			input.AccountID, accountRes.PrincipalPolicyHash, policyHash, err)
+	if trustPolicyHash != accountRes.PrincipalTrustPolicyHash {
+		// Update the trust policy of the role
+		log.Printf("Update trust policy of role '%s' in account '%s' to hash '%s' from '%s'.",
+			input.PrincipalRoleName, input.AccountID, trustPolicyHash, accountRes.PrincipalTrustPolicyHash)
+		_, err = iamSvc.UpdateAssumeRolePolicy(&iam.UpdateAssumeRolePolicyInput{
+			RoleName:       aws.String(input.PrincipalRoleName),
+			PolicyDocument: aws.String(trustPolicy),
+		})
+		if err != nil {
+			log.Printf("Failed updating the trust policy of role '%s' in account '%s': %s",
+				input.PrincipalRoleName, input.AccountID, err)
+			return err
+		}
+
+		_, err = input.DbSvc.UpdateAccount(db.Account{
+			ID:                       input.AccountID,
+			PrincipalTrustPolicyHash: trustPolicyHash,
+		}, []string{"PrincipalTrustPolicyHash"})
+		if err != nil {
+			log.Printf("Failed to update account '%s' resource record.  Trust Policy Hash from '%s' to '%s': %s",
+				input.AccountID, accountRes.PrincipalTrustPolicyHash, trustPolicyHash, err)
+			return err
+		}
 	}
-	return err
+	return nil
 }
 
 type getPolicyInput struct {
-	PrincipalPolicyArn   string
-	PrincipalRoleArn     string
-	PrincipalIAMDenyTags []string
-	AdminRoleArn         string
+	PrincipalPolicyArn     string
+	PrincipalRoleArn       string
+	PrincipalIAMDenyTags   []string
+	AdminRoleArn           string
+	Regions                []string
+	PermissionsBoundaryArn string
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"testing"
 
+	"github.com/Optum/dce/pkg/awsiface"
+	"github.com/Optum/dce/pkg/policyprofile"
 	"github.com/Optum/dce/pkg/rolemanager"
 
 	awsMocks "github.com/Optum/dce/pkg/awsiface/mocks"
@@ -14,6 +17,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
 	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -31,9 +35,16 @@ type testUpdatePrincipalPolicy struct {
 	PrincipalIAMDenyTags       []string
 	StoragerPolicy             string
 	StoragerError              error
+	Leases                     []*db.Lease
+	ExpectedPolicy             string
+	ExpectedPolicyHash         string
+	PermissionsBoundary        string
+	PermissionsBoundaryArn     string
+	UpdateTrustPolicy          bool
 }
 
 func TestUpdatePrincipalPolicy(t *testing.T) {
+	frozenPolicyHash := fmt.Sprintf("%x", sha256.Sum256([]byte(rolemanager.FrozenPrincipalPolicy(rolemanager.FrozenPolicyDenyAll))))
 
 	tests := []testUpdatePrincipalPolicy{
 		// Happy Path Update Principal Policy
@@ -61,26 +72,113 @@ func TestUpdatePrincipalPolicy(t *testing.T) {
 			PrincipalIAMDenyTags: []string{"DoNotTouch"},
 			StoragerPolicy:       "{\"Test\" : \"Policy\"}",
 		},
+		// Add the statements of the lease's policy profile
+		{
+			GetAccountResult: &db.Account{
+				ID:                  "123456789012",
+				AdminRoleArn:        "arn:aws:iam::123456789012:role/AdminRole",
+				PrincipalPolicyHash: "aHash",
+			},
+			PrincipalPolicyName:  "PrincipalPolicy",
+			PrincipalRoleName:    "PrincipalRole",
+			PrincipalPolicyHash:  "aHash",
+			PrincipalIAMDenyTags: []string{"DoNotTouch"},
+			StoragerPolicy:       "{\"Test\" : \"Policy\"}",
+			Leases: []*db.Lease{
+				{ID: "lease-1", LeaseStatus: db.Inactive, PolicyProfile: "unknown"},
+				{ID: "lease-2", LeaseStatus: db.Active, PolicyProfile: "locked"},
+			},
+			ExpectedPolicy:     `{"Statement":[{"Action":"ec2:*","Effect":"Deny","Resource":"*"}],"Test":"Policy"}`,
+			ExpectedPolicyHash: "aHash+7b10baf85daf83db6e12b14a0aa6727c7e0f1dca9b698272f7b4844aa4f1bf4d",
+		},
+		// Freeze the account, when its lease is frozen
+		{
+			GetAccountResult: &db.Account{
+				ID:                  "123456789012",
+				AdminRoleArn:        "arn:aws:iam::123456789012:role/AdminRole",
+				PrincipalPolicyHash: "aHash",
+			},
+			PrincipalPolicyName:  "PrincipalPolicy",
+			PrincipalRoleName:    "PrincipalRole",
+			PrincipalPolicyHash:  "aHash",
+			PrincipalIAMDenyTags: []string{"DoNotTouch"},
+			StoragerPolicy:       "{\"Test\" : \"Policy\"}",
+			Leases: []*db.Lease{
+				{ID: "lease-1", LeaseStatus: db.Frozen, PolicyProfile: "locked"},
+			},
+			ExpectedPolicy:     rolemanager.FrozenPrincipalPolicy(rolemanager.FrozenPolicyDenyAll),
+			ExpectedPolicyHash: frozenPolicyHash,
+		},
+		// Render the policy with the permissions boundary
+		{
+			GetAccountResult: &db.Account{
+				ID:           "123456789012",
+				AdminRoleArn: "arn:aws:iam::123456789012:role/AdminRole",
+			},
+			PrincipalPolicyName:    "PrincipalPolicy",
+			PrincipalRoleName:      "PrincipalRole",
+			PrincipalPolicyHash:    "aHash",
+			PrincipalIAMDenyTags:   []string{"DoNotTouch"},
+			StoragerPolicy:         "{\"Test\" : \"Policy\"}",
+			PermissionsBoundary:    "DCEBoundary",
+			PermissionsBoundaryArn: "arn:aws:iam::123456789012:policy/DCEBoundary",
+		},
+		// Update the trust policy, without updating the policy
+		{
+			GetAccountResult: &db.Account{
+				ID:                  "123456789012",
+				AdminRoleArn:        "arn:aws:iam::123456789012:role/AdminRole",
+				PrincipalPolicyHash: "aHash",
+			},
+			PrincipalPolicyName:  "PrincipalPolicy",
+			PrincipalRoleName:    "PrincipalRole",
+			PrincipalPolicyHash:  "aHash",
+			PrincipalIAMDenyTags: []string{"DoNotTouch"},
+			StoragerPolicy:       "{\"Test\" : \"Policy\"}",
+			UpdateTrustPolicy:    true,
+		},
+	}
+	trustPolicy, trustPolicyHash, err := (&rolemanager.PrincipalTrustPolicyTemplate{}).Render("123456789012", "000000000000")
+	require.Nil(t, err)
+	policyProfiles := &policyprofile.Config{
+		FrozenPolicy: rolemanager.FrozenPolicyDenyAll,
+		Profiles: map[string]*policyprofile.Profile{
+			"locked": {Statements: []db.PolicyStatement{{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}}},
+		},
 	}
 
 	// Iterate through each test in the list
 	for _, test := range tests {
+		if test.ExpectedPolicy == "" {
+			test.ExpectedPolicy = test.StoragerPolicy
+			test.ExpectedPolicyHash = test.PrincipalPolicyHash
+		}
+		if !test.UpdateTrustPolicy {
+			test.GetAccountResult.PrincipalTrustPolicyHash = trustPolicyHash
+		}
+
 		// Setup mocks
 		mockDB := dbmock.DBer{}
 		mockDB.On("GetAccount", mock.Anything).Return(
 			test.GetAccountResult,
 			test.GetAccountError)
+		mockDB.On("FindLeasesByAccount", test.GetAccountResult.ID).Return(test.Leases, nil)
 		mockDB.On("UpdateAccountPrincipalPolicyHash",
 			test.GetAccountResult.ID,
 			test.GetAccountResult.PrincipalPolicyHash,
-			test.PrincipalPolicyHash,
+			test.ExpectedPolicyHash,
 		).Return(nil, nil)
+		mockDB.On("UpdateAccount", db.Account{
+			ID:                       test.GetAccountResult.ID,
+			PrincipalTrustPolicyHash: trustPolicyHash,
+		}, []string{"PrincipalTrustPolicyHash"}).Return(nil, nil)
 		mockS3 := &commonmock.Storager{}
 		mockS3.On("GetTemplateObject", mock.Anything, mock.Anything, getPolicyInput{
-			PrincipalPolicyArn:   fmt.Sprintf("arn:aws:iam::%s:policy/%s", test.GetAccountResult.ID, test.PrincipalPolicyName),
-			PrincipalRoleArn:     fmt.Sprintf("arn:aws:iam::%s:role/%s", test.GetAccountResult.ID, test.PrincipalRoleName),
-			PrincipalIAMDenyTags: test.PrincipalIAMDenyTags,
-			AdminRoleArn:         test.GetAccountResult.AdminRoleArn,
+			PrincipalPolicyArn:     fmt.Sprintf("arn:aws:iam::%s:policy/%s", test.GetAccountResult.ID, test.PrincipalPolicyName),
+			PrincipalRoleArn:       fmt.Sprintf("arn:aws:iam::%s:role/%s", test.GetAccountResult.ID, test.PrincipalRoleName),
+			PrincipalIAMDenyTags:   test.PrincipalIAMDenyTags,
+			AdminRoleArn:           test.GetAccountResult.AdminRoleArn,
+			PermissionsBoundaryArn: test.PermissionsBoundaryArn,
 		}).Return(
 			test.StoragerPolicy,
 			test.PrincipalPolicyHash,
@@ -91,7 +189,8 @@ func TestUpdatePrincipalPolicy(t *testing.T) {
 		mockToken := &commonmock.TokenService{}
 		mockRoleManager := &roleMock.PolicyManager{}
 		mockSession := &awsMocks.AwsSession{}
-		if test.PrincipalPolicyHash != test.GetAccountResult.PrincipalPolicyHash {
+		mockIAM := &awsMocks.IAM{}
+		if test.ExpectedPolicyHash != test.GetAccountResult.PrincipalPolicyHash || test.UpdateTrustPolicy {
 			mockAdminRoleSession.On("ClientConfig", mock.Anything).Return(client.Config{
 				Config: &aws.Config{},
 			})
@@ -104,13 +203,16 @@ func TestUpdatePrincipalPolicy(t *testing.T) {
 			mockRoleManager.On("MergePolicy", &rolemanager.MergePolicyInput{
 				PolicyArn:      policyArn,
 				PolicyName:     test.PrincipalPolicyName,
-				PolicyDocument: test.StoragerPolicy,
+				PolicyDocument: test.ExpectedPolicy,
 			}).Return(nil)
-
+			mockIAM.On("UpdateAssumeRolePolicy", &iam.UpdateAssumeRolePolicyInput{
+				RoleName:       aws.String(test.PrincipalRoleName),
+				PolicyDocument: aws.String(trustPolicy),
+			}).Return(&iam.UpdateAssumeRolePolicyOutput{}, nil)
 		}
 
 		// Call transitionFinanceLock
-		err := processRecord(processRecordInput{
+		err = processRecord(processRecordInput{
 			AccountID:            test.GetAccountResult.ID,
 			DbSvc:                &mockDB,
 			StoragerSvc:          mockS3,
@@ -120,6 +222,12 @@ func TestUpdatePrincipalPolicy(t *testing.T) {
 			PrincipalRoleName:    test.PrincipalRoleName,
 			PrincipalPolicyName:  test.PrincipalPolicyName,
 			PrincipalIAMDenyTags: test.PrincipalIAMDenyTags,
+			PolicyProfiles:       policyProfiles,
+			PermissionsBoundary:  test.PermissionsBoundary,
+			MasterAccountID:      "000000000000",
+			NewIAM: func(session awsiface.AwsSession) awsiface.IAM {
+				return mockIAM
+			},
 		})
 
 		// Assert expectations
@@ -128,5 +236,11 @@ func TestUpdatePrincipalPolicy(t *testing.T) {
 		} else {
 			require.Nil(t, err)
 		}
+		if test.UpdateTrustPolicy {
+			mockIAM.AssertExpectations(t)
+			mockRoleManager.AssertNotCalled(t, "MergePolicy", mock.Anything)
+		} else {
+			mockIAM.AssertNotCalled(t, "UpdateAssumeRolePolicy", mock.Anything)
+		}
 	}
 }
@@ -0,0 +1,135 @@
+/*
+The reset-worker command processes the reset queue from a long-running
+process, instead of the process_reset_queue Lambda.
+
+With `-executor local` (the default), accounts are reset in the
+worker process, running the same reset pipeline as the CodeBuild
+reset project. Up to `-concurrency` accounts are reset at once.
+
+With `-executor codebuild`, the worker starts a CodeBuild build for each
+account, like the process_reset_queue Lambda.
+
+The worker is configured from the same environment variables
+as the process_reset_queue Lambda and the CodeBuild reset project
+(RESET_SQS_URL, RESET_DLQ_URL, ACCOUNT_DB, HISTORY_DB, RESET_NUKE_TEMPLATE_DEFAULT, etc.)
+
+	go run ./cmd/reset-worker -concurrency 4 -interval 30s
+*/
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/processresetqueue"
+	"github.com/Optum/dce/pkg/reset"
+	"github.com/Optum/dce/pkg/resetpipeline"
+	"github.com/Optum/dce/pkg/resetstatus"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/codebuild"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+const (
+	localExecutor     = "local"
+	codebuildExecutor = "codebuild"
+)
+
+func main() {
+	// aws-nuke runs in a child process of the worker,
+	// to capture its output for each account's reset report
+	reset.RunNukeProcess()
+
+	defaultConcurrency, err := strconv.Atoi(common.GetEnv("RESET_MAX_CONCURRENCY", "2"))
+	if err != nil {
+		log.Fatalf("Invalid RESET_MAX_CONCURRENCY: %s", err)
+	}
+
+	executor := flag.String("executor", common.GetEnv("RESET_EXECUTOR", localExecutor), "Where to run resets: local or codebuild")
+	concurrency := flag.Int("concurrency", defaultConcurrency, "Maximum number of accounts to reset at once, with the local executor")
+	interval := flag.Duration("interval", time.Minute, "How often to poll the reset queue")
+	once := flag.Bool("once", false, "Poll the reset queue once, wait for the resets to complete, and exit")
+	flag.Parse()
+
+	awsSession, err := session.NewSession()
+	if err != nil {
+		log.Fatalf("Failed to create AWS session: %s", err)
+	}
+	baseDbSvc, err := db.NewFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	historySvc, err := history.NewFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dbSvc := history.NewRecorder(baseDbSvc, historySvc, "reset-worker")
+	queueURL := common.RequireEnv("RESET_SQS_URL")
+	buildName := common.GetEnv("RESET_BUILD_NAME", "reset")
+
+	var builder common.Builder
+	var localBuilder *resetpipeline.LocalBuilder
+	switch *executor {
+	case localExecutor:
+		config, err := resetpipeline.NewConfigFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		svc, err := resetpipeline.NewServicesFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		localBuilder = resetpipeline.NewLocalBuilder(config, svc, *concurrency)
+		builder = localBuilder
+	case codebuildExecutor:
+		builder = &common.CodeBuild{Client: codebuild.New(awsSession)}
+	default:
+		log.Fatalf("Invalid executor %q: must be %s or %s", *executor, localExecutor, codebuildExecutor)
+	}
+
+	queue := common.SQSQueue{Client: sqs.New(awsSession)}
+	resetStatus, err := resetstatus.NewTrackerFromEnv(dbSvc, queue)
+	if err != nil {
+		log.Fatal(err)
+	}
+	resetInput := &processresetqueue.ResetInput{
+		ResetQueue:    queue,
+		ResetQueueURL: &queueURL,
+		ResetBuild:    builder,
+		BuildName:     &buildName,
+		DbSvc:         dbSvc,
+		ResetStatus:   resetStatus,
+		DeadLetterQueue: &common.DeadLetterQueue{
+			Queue: queue,
+			URL:   common.RequireEnv("RESET_DLQ_URL"),
+		},
+		MaxReceiveCount: common.GetEnvInt("RESET_MAX_RECEIVE_COUNT", processresetqueue.DefaultMaxReceiveCount),
+	}
+
+	log.Printf("Processing reset queue %s with the %s executor", queueURL, *executor)
+	for {
+		resetOutput, err := processresetqueue.Reset(resetInput)
+		if err != nil {
+			log.Printf("Failed to process reset queue: %s", err)
+		} else {
+			log.Printf("Reset results: %+v", *resetOutput)
+		}
+
+		if *once {
+			if localBuilder != nil {
+				localBuilder.Wait()
+			}
+			if err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+		time.Sleep(*interval)
+	}
+}
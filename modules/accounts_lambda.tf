@@ -19,7 +19,11 @@ module "accounts_lambda" {
     ACCOUNT_DB                     = aws_dynamodb_table.accounts.id
     ARTIFACTS_BUCKET               = aws_s3_bucket.artifacts.id
     LEASE_DB                       = aws_dynamodb_table.leases.id
+    HISTORY_DB                     = aws_dynamodb_table.history.id
+    RESET_REPORTS_DB               = aws_dynamodb_table.reset_reports.id
     RESET_SQS_URL                  = aws_sqs_queue.account_reset.id
+    RESET_DLQ_URL                  = aws_sqs_queue.account_reset_dlq.id
+    RESET_BUILD_NAME               = aws_codebuild_project.reset_build.id
     ACCOUNT_CREATED_TOPIC_ARN      = aws_sns_topic.account_created.arn
     ACCOUNT_DELETED_TOPIC_ARN      = aws_sns_topic.account_deleted.arn
     PRINCIPAL_ROLE_NAME            = local.principal_role_name
@@ -27,9 +31,12 @@ module "accounts_lambda" {
     PRINCIPAL_IAM_DENY_TAGS        = join(",", var.principal_iam_deny_tags)
     ALLOWED_REGIONS                = join(",", var.allowed_regions)
     PRINCIPAL_MAX_SESSION_DURATION = 14400
+    PRINCIPAL_PERMISSIONS_BOUNDARY = var.principal_permissions_boundary
     TAG_ENVIRONMENT                = var.namespace == "prod" ? "PROD" : "NON-PROD"
     TAG_APP_NAME                   = lookup(var.global_tags, "AppName")
     PRINCIPAL_POLICY_S3_KEY        = aws_s3_bucket_object.principal_policy.key
+    PRINCIPAL_TRUST_POLICY_S3_KEY  = aws_s3_bucket_object.principal_trust_policy.key
+    PRINCIPAL_TRUST_IDP_ARNS       = join(",", var.principal_trust_idp_arns)
   }
 }
 
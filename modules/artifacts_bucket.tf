@@ -1,6 +1,7 @@
 locals {
-  principal_policy     = var.principal_policy == "" ? "${path.module}/fixtures/policies/principal_policy.tmpl" : var.principal_policy
-  artifact_bucket_name = "${local.account_id}-dce-artifacts-${var.namespace}"
+  principal_policy       = var.principal_policy == "" ? "${path.module}/fixtures/policies/principal_policy.tmpl" : var.principal_policy
+  principal_trust_policy = var.principal_trust_policy == "" ? "${path.module}/fixtures/policies/principal_trust_policy.tmpl" : var.principal_trust_policy
+  artifact_bucket_name   = "${local.account_id}-dce-artifacts-${var.namespace}"
 }
 
 
@@ -86,3 +87,18 @@ resource "aws_s3_bucket_object" "principal_policy" {
   source = local.principal_policy
   etag   = "${filemd5(local.principal_policy)}"
 }
+
+resource "aws_s3_bucket_object" "principal_trust_policy" {
+  bucket = aws_s3_bucket.artifacts.id
+  key    = "fixtures/policies/principal_trust_policy.tmpl"
+  source = local.principal_trust_policy
+  etag   = "${filemd5(local.principal_trust_policy)}"
+}
+
+resource "aws_s3_bucket_object" "exchange_rates" {
+  count  = var.exchange_rates == "" ? 0 : 1
+  bucket = aws_s3_bucket.artifacts.id
+  key    = "fixtures/exchange_rates.json"
+  source = var.exchange_rates
+  etag   = "${filemd5(var.exchange_rates)}"
+}
@@ -157,3 +157,85 @@ resource "aws_dynamodb_table" "usage" {
 
   tags = var.global_tags
 }
+
+# History table
+# Append-only history of changes made to accounts and leases
+resource "aws_dynamodb_table" "history" {
+  name           = "History${local.table_suffix}"
+  read_capacity  = 5
+  write_capacity = 5
+  hash_key       = "ResourceId"
+  range_key      = "Timestamp"
+
+  server_side_encryption {
+    enabled = true
+  }
+
+  # ID of the Account or Lease which was changed
+  attribute {
+    name = "ResourceId"
+    type = "S"
+  }
+
+  # Time of the change, as an epoch timestamp in nanoseconds
+  attribute {
+    name = "Timestamp"
+    type = "N"
+  }
+
+  tags = var.global_tags
+  /*
+  Other attributes:
+    - ResourceType (string: "Account" or "Lease")
+    - Event (string: "Created", "Updated", "StatusChanged", or "Deleted")
+    - Changes (list of {Field, PrevValue, NextValue})
+    - Actor (string, user or system component which made the change)
+    - Reason (string)
+    - AccountId (string)
+    - PrincipalId (string)
+    - LeaseId (string)
+  */
+}
+
+# Reset Reports table
+# Report of each account reset run, with the resources deleted
+resource "aws_dynamodb_table" "reset_reports" {
+  name           = "ResetReports${local.table_suffix}"
+  read_capacity  = 5
+  write_capacity = 5
+  hash_key       = "AccountId"
+  range_key      = "RunId"
+
+  server_side_encryption {
+    enabled = true
+  }
+
+  # ID of the Account which was reset
+  attribute {
+    name = "AccountId"
+    type = "S"
+  }
+
+  # ID of the reset run, sortable by start time
+  attribute {
+    name = "RunId"
+    type = "S"
+  }
+
+  tags = var.global_tags
+  /*
+  Other attributes:
+    - BuildId (string, ID of the CodeBuild build)
+    - StartedOn (Integer, epoch timestamp)
+    - CompletedOn (Integer, epoch timestamp)
+    - DurationSeconds (Integer)
+    - Outcome (string: "Succeeded" or "Failed")
+    - Error (string)
+    - DryRun (bool)
+    - NukeAttempts (Integer)
+    - Steps (list of {Name, Status, ResourceCount, Error, DurationSeconds})
+    - ResourceCounts ({Found, Deleted, Failed, Filtered})
+    - ResourcesFound, ResourcesDeleted, ResourcesFailed, ResourcesFiltered (list of strings)
+    - ResourcesTruncated (bool)
+  */
+}
@@ -8,21 +8,29 @@ module "leases_lambda" {
   alarm_topic_arn = aws_sns_topic.alarms_topic.arn
 
   environment = {
-    DEBUG                              = "false"
-    NAMESPACE                          = var.namespace
-    AWS_CURRENT_REGION                 = var.aws_region
-    RESET_SQS_URL                      = aws_sqs_queue.account_reset.id
-    ACCOUNT_DB                         = aws_dynamodb_table.accounts.id
-    LEASE_DB                           = aws_dynamodb_table.leases.id
-    LEASE_ADDED_TOPIC                  = aws_sns_topic.lease_added.arn
-    DECOMMISSION_TOPIC                 = aws_sns_topic.lease_removed.arn
-    COGNITO_USER_POOL_ID               = module.api_gateway_authorizer.user_pool_id
-    COGNITO_ROLES_ATTRIBUTE_ADMIN_NAME = var.cognito_roles_attribute_admin_name
-    MAX_LEASE_BUDGET_AMOUNT            = var.max_lease_budget_amount
-    MAX_LEASE_PERIOD                   = var.max_lease_period
-    PRINCIPAL_BUDGET_AMOUNT            = var.principal_budget_amount
-    PRINCIPAL_BUDGET_PERIOD            = var.principal_budget_period
-    USAGE_CACHE_DB                     = aws_dynamodb_table.usage.id
+    DEBUG                                = "false"
+    NAMESPACE                            = var.namespace
+    AWS_CURRENT_REGION                   = var.aws_region
+    RESET_SQS_URL                        = aws_sqs_queue.account_reset.id
+    ACCOUNT_DB                           = aws_dynamodb_table.accounts.id
+    LEASE_DB                             = aws_dynamodb_table.leases.id
+    HISTORY_DB                           = aws_dynamodb_table.history.id
+    LEASE_ADDED_TOPIC                    = aws_sns_topic.lease_added.arn
+    LEASE_EXTENDED_TOPIC                 = aws_sns_topic.lease_extended.arn
+    DECOMMISSION_TOPIC                   = aws_sns_topic.lease_removed.arn
+    COGNITO_USER_POOL_ID                 = module.api_gateway_authorizer.user_pool_id
+    COGNITO_ROLES_ATTRIBUTE_ADMIN_NAME   = var.cognito_roles_attribute_admin_name
+    MAX_LEASE_BUDGET_AMOUNT              = var.max_lease_budget_amount
+    MAX_LEASE_PERIOD                     = var.max_lease_period
+    PENDING_LEASE_TIMEOUT                = var.pending_lease_timeout
+    PRINCIPAL_BUDGET_AMOUNT              = var.principal_budget_amount
+    PRINCIPAL_BUDGET_PERIOD              = var.principal_budget_period
+    USAGE_CACHE_DB                       = aws_dynamodb_table.usage.id
+    PRINCIPAL_POLICY_PROFILES            = jsonencode(var.principal_policy_profiles)
+    PRINCIPAL_POLICY_APPROVED_STATEMENTS = jsonencode(var.principal_policy_approved_statements)
+    ARTIFACTS_BUCKET                     = aws_s3_bucket.artifacts.id
+    EXCHANGE_RATES_S3_KEY                = join("", aws_s3_bucket_object.exchange_rates.*.key)
+    LEASE_RULES                          = jsonencode(var.lease_rules)
   }
 }
 
@@ -31,6 +39,11 @@ resource "aws_sns_topic" "lease_added" {
   tags = var.global_tags
 }
 
+resource "aws_sns_topic" "lease_extended" {
+  name = "lease-extended-${var.namespace}"
+  tags = var.global_tags
+}
+
 resource "aws_sns_topic" "lease_removed" {
   name = "lease-removed-${var.namespace}"
   tags = var.global_tags
@@ -22,10 +22,30 @@ output "usage_table_arn" {
   value = aws_dynamodb_table.usage.arn
 }
 
+output "history_table_name" {
+  value = aws_dynamodb_table.history.name
+}
+
+output "history_table_arn" {
+  value = aws_dynamodb_table.history.arn
+}
+
+output "reset_reports_table_name" {
+  value = aws_dynamodb_table.reset_reports.name
+}
+
+output "reset_reports_table_arn" {
+  value = aws_dynamodb_table.reset_reports.arn
+}
+
 output "sqs_reset_queue_url" {
   value = aws_sqs_queue.account_reset.id
 }
 
+output "sqs_reset_dlq_url" {
+  value = aws_sqs_queue.account_reset_dlq.id
+}
+
 output "sqs_reset_queue_arn" {
   value = aws_sqs_queue.account_reset.arn
 }
@@ -54,6 +74,14 @@ output "lease_added_topic_arn" {
   value = aws_sns_topic.lease_added.arn
 }
 
+output "lease_extended_topic_id" {
+  value = aws_sns_topic.lease_extended.id
+}
+
+output "lease_extended_topic_arn" {
+  value = aws_sns_topic.lease_extended.arn
+}
+
 output "lease_removed_topic_id" {
   value = aws_sns_topic.lease_removed.id
 }
@@ -98,6 +126,14 @@ output "account_deleted_topic_arn" {
   value = aws_sns_topic.account_deleted.arn
 }
 
+output "principal_policy_drift_topic_id" {
+  value = aws_sns_topic.principal_policy_drift.id
+}
+
+output "principal_policy_drift_topic_arn" {
+  value = aws_sns_topic.principal_policy_drift.arn
+}
+
 output "api_url" {
   value = aws_api_gateway_stage.api.invoke_url
 }
@@ -0,0 +1,48 @@
+module "check_policy_drift" {
+  source          = "./lambda"
+  name            = "check_policy_drift-${var.namespace}"
+  namespace       = var.namespace
+  description     = "Checks the principal role and policy of each account for changes made outside of DCE"
+  global_tags     = var.global_tags
+  handler         = "check_policy_drift"
+  alarm_topic_arn = aws_sns_topic.alarms_topic.arn
+
+  environment = {
+    DEBUG                                = "false"
+    NAMESPACE                            = var.namespace
+    AWS_CURRENT_REGION                   = var.aws_region
+    ACCOUNT_DB                           = aws_dynamodb_table.accounts.id
+    LEASE_DB                             = aws_dynamodb_table.leases.id
+    HISTORY_DB                           = aws_dynamodb_table.history.id
+    ARTIFACTS_BUCKET                     = aws_s3_bucket.artifacts.id
+    MASTER_ACCOUNT_ID                    = local.account_id
+    PRINCIPAL_ROLE_NAME                  = local.principal_role_name
+    PRINCIPAL_POLICY_NAME                = local.principal_policy_name
+    PRINCIPAL_POLICY_S3_KEY              = aws_s3_bucket_object.principal_policy.key
+    PRINCIPAL_TRUST_POLICY_S3_KEY        = aws_s3_bucket_object.principal_trust_policy.key
+    PRINCIPAL_TRUST_IDP_ARNS             = join(",", var.principal_trust_idp_arns)
+    PRINCIPAL_IAM_DENY_TAGS              = join(",", var.principal_iam_deny_tags)
+    ALLOWED_REGIONS                      = join(",", var.allowed_regions)
+    POLICY_DRIFT_TOPIC_ARN               = aws_sns_topic.principal_policy_drift.arn
+    POLICY_DRIFT_REMEDIATE               = var.policy_drift_remediate
+    PRINCIPAL_POLICY_PROFILES            = jsonencode(var.principal_policy_profiles)
+    PRINCIPAL_POLICY_APPROVED_STATEMENTS = jsonencode(var.principal_policy_approved_statements)
+    PRINCIPAL_PERMISSIONS_BOUNDARY       = var.principal_permissions_boundary
+    FROZEN_LEASE_POLICY                  = var.frozen_lease_policy
+  }
+}
+
+resource "aws_sns_topic" "principal_policy_drift" {
+  name = "principal-policy-drift-${var.namespace}"
+  tags = var.global_tags
+}
+
+// Run the check_policy_drift lambda on a timer (cloudwatch event)
+module "check_policy_drift_schedule" {
+  source              = "./cloudwatch_event"
+  name                = "check_policy_drift-${var.namespace}"
+  lambda_function_arn = module.check_policy_drift.arn
+  schedule_expression = var.policy_drift_schedule_expression
+  description         = "Checks the principal role and policy of each account for changes made outside of DCE"
+  enabled             = var.policy_drift_enabled
+}
@@ -11,6 +11,7 @@ module "publish_lease_events_lambda" {
     AWS_CURRENT_REGION       = var.aws_region
     ACCOUNT_DB               = aws_dynamodb_table.accounts.id
     LEASE_DB                 = aws_dynamodb_table.leases.id
+    HISTORY_DB               = aws_dynamodb_table.history.id
     LEASE_LOCKED_TOPIC_ARN   = aws_sns_topic.lease_locked.arn
     LEASE_UNLOCKED_TOPIC_ARN = aws_sns_topic.lease_unlocked.arn
     RESET_QUEUE_URL          = aws_sqs_queue.account_reset.id
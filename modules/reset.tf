@@ -4,6 +4,14 @@ resource "aws_sqs_queue" "account_reset" {
   tags = var.global_tags
 }
 
+# SQS Queue, for reset requests which could not be processed
+# after RESET_MAX_RECEIVE_COUNT attempts
+resource "aws_sqs_queue" "account_reset_dlq" {
+  name                      = "account-reset-dlq-${var.namespace}"
+  message_retention_seconds = 1209600
+  tags                      = var.global_tags
+}
+
 # Lambda function to add all NotReady accounts to the reset queue
 module "populate_reset_queue" {
   source          = "./lambda"
@@ -21,6 +29,7 @@ module "populate_reset_queue" {
     RESET_SQS_URL      = aws_sqs_queue.account_reset.id
     ACCOUNT_DB         = aws_dynamodb_table.accounts.id
     LEASE_DB           = aws_dynamodb_table.leases.id
+    HISTORY_DB         = aws_dynamodb_table.history.id
     AWS_CURRENT_REGION = var.aws_region
   }
 }
@@ -60,21 +69,27 @@ module "process_reset_queue" {
   alarm_topic_arn = aws_sns_topic.alarms_topic.arn
 
   environment = {
-    DEBUG              = "false"
-    RESET_BUILD_NAME   = aws_codebuild_project.reset_build.id
-    RESET_SQS_URL      = aws_sqs_queue.account_reset.id
-    ACCOUNT_DB         = aws_dynamodb_table.accounts.id
-    LEASE_DB           = aws_dynamodb_table.leases.id
-    AWS_CURRENT_REGION = var.aws_region
+    DEBUG                   = "false"
+    RESET_BUILD_NAME        = aws_codebuild_project.reset_build.id
+    RESET_SQS_URL           = aws_sqs_queue.account_reset.id
+    RESET_DLQ_URL           = aws_sqs_queue.account_reset_dlq.id
+    RESET_MAX_ATTEMPTS      = var.reset_max_attempts
+    RESET_MAX_RECEIVE_COUNT = var.reset_max_receive_count
+    ACCOUNT_DB              = aws_dynamodb_table.accounts.id
+    LEASE_DB                = aws_dynamodb_table.leases.id
+    HISTORY_DB              = aws_dynamodb_table.history.id
+    AWS_CURRENT_REGION      = var.aws_region
   }
 }
 
 # Trigger Execute Reset lambda function every few minutes
 # (to continuously poll SQS reset queue)
+# Disabled when resets are run by an external reset-worker
 resource "aws_cloudwatch_event_rule" "poll_sqs_reset" {
   name                = "process-reset-queue-${var.namespace}"
   description         = "Process records from the reset queue"
   schedule_expression = "rate(3 minutes)"
+  is_enabled          = var.reset_executor == "codebuild"
 }
 
 resource "aws_cloudwatch_event_target" "poll_sqs_reset" {
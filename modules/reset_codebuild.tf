@@ -95,6 +95,18 @@ resource "aws_codebuild_project" "reset_build" {
       type  = "PLAINTEXT"
     }
 
+    environment_variable {
+      name  = "HISTORY_DB"
+      value = aws_dynamodb_table.history.id
+      type  = "PLAINTEXT"
+    }
+
+    environment_variable {
+      name  = "RESET_REPORTS_DB"
+      value = aws_dynamodb_table.reset_reports.id
+      type  = "PLAINTEXT"
+    }
+
     environment_variable {
       name  = "AWS_CURRENT_REGION"
       value = var.aws_region
@@ -107,6 +119,36 @@ resource "aws_codebuild_project" "reset_build" {
       type  = "PLAINTEXT"
     }
 
+    environment_variable {
+      name  = "RESET_STEPS"
+      value = var.reset_steps
+      type  = "PLAINTEXT"
+    }
+
+    environment_variable {
+      name  = "RESET_VERIFY_TOGGLE"
+      value = var.reset_verify_toggle
+      type  = "PLAINTEXT"
+    }
+
+    environment_variable {
+      name  = "RESET_VERIFY_ALLOWLIST"
+      value = join(",", var.reset_verify_allowlist)
+      type  = "PLAINTEXT"
+    }
+
+    environment_variable {
+      name  = "RESET_NUKE_POOL_CONFIG"
+      value = jsonencode(var.reset_nuke_pool_config)
+      type  = "PLAINTEXT"
+    }
+
+    environment_variable {
+      name  = "ALLOWED_REGIONS"
+      value = join(",", var.allowed_regions)
+      type  = "PLAINTEXT"
+    }
+
     environment_variable {
       name  = "RESET_NAMESPACE"
       value = var.namespace
@@ -124,6 +166,24 @@ resource "aws_codebuild_project" "reset_build" {
       value = aws_sns_topic.reset_complete.arn
       type  = "PLAINTEXT"
     }
+
+    environment_variable {
+      name  = "LEASE_ADDED_TOPIC_ARN"
+      value = aws_sns_topic.lease_added.arn
+      type  = "PLAINTEXT"
+    }
+
+    environment_variable {
+      name  = "RESET_SQS_URL"
+      value = aws_sqs_queue.account_reset.id
+      type  = "PLAINTEXT"
+    }
+
+    environment_variable {
+      name  = "RESET_MAX_ATTEMPTS"
+      value = var.reset_max_attempts
+      type  = "PLAINTEXT"
+    }
   }
 
   tags = var.global_tags
@@ -181,9 +241,20 @@ resource "aws_iam_role_policy" "codebuild_reset" {
         "dynamodb:Scan",
         "dynamodb:Query",
         "dynamodb:UpdateItem",
+        "dynamodb:PutItem",
+        "dynamodb:DeleteItem",
         "sns:Publish"
       ]
     },
+    {
+      "Effect": "Allow",
+      "Action": [
+        "sqs:SendMessage"
+      ],
+      "Resource": [
+        "${aws_sqs_queue.account_reset.arn}"
+      ]
+    },
     {
       "Effect": "Allow",
       "Action": [
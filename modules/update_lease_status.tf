@@ -2,7 +2,7 @@ module "fan_out_update_lease_status_lambda" {
   source          = "./lambda"
   name            = "fan_out_update_lease_status-${var.namespace}"
   namespace       = var.namespace
-  description     = "Initiates the budget check lambda. Invokes a check-budget lamdba for each active lease"
+  description     = "Initiates the budget check lambda. Invokes a check-budget lamdba for each active or frozen lease"
   global_tags     = var.global_tags
   handler         = "fan_out_update_lease_status"
   alarm_topic_arn = aws_sns_topic.alarms_topic.arn
@@ -12,6 +12,7 @@ module "fan_out_update_lease_status_lambda" {
     ACCOUNT_DB                        = aws_dynamodb_table.accounts.id
     LEASE_DB                          = aws_dynamodb_table.leases.id
     UPDATE_LEASE_STATUS_FUNCTION_NAME = module.update_lease_status_lambda.name
+    LEASE_REMOVED_TOPIC_ARN           = aws_sns_topic.lease_removed.arn
   }
 }
 
@@ -34,6 +35,7 @@ module "update_lease_status_lambda" {
     AWS_CURRENT_REGION                        = var.aws_region
     ACCOUNT_DB                                = aws_dynamodb_table.accounts.id
     LEASE_DB                                  = aws_dynamodb_table.leases.id
+    HISTORY_DB                                = aws_dynamodb_table.history.id
     USAGE_CACHE_DB                            = aws_dynamodb_table.usage.id
     RESET_QUEUE_URL                           = aws_sqs_queue.account_reset.id
     LEASE_LOCKED_TOPIC_ARN                    = aws_sns_topic.lease_locked.arn
@@ -45,6 +47,17 @@ module "update_lease_status_lambda" {
     BUDGET_NOTIFICATION_THRESHOLD_PERCENTILES = join(",", var.budget_notification_threshold_percentiles)
     PRINCIPAL_BUDGET_AMOUNT                   = var.principal_budget_amount
     PRINCIPAL_BUDGET_PERIOD                   = var.principal_budget_period
+    LEASE_RULES                               = jsonencode(var.lease_rules)
+    EXPIRY_NOTIFICATION_HOURS                 = join(",", var.expiry_notification_hours)
+    EXPIRY_NOTIFICATION_TEMPLATE_HTML         = var.expiry_notification_template_html
+    EXPIRY_NOTIFICATION_TEMPLATE_TEXT         = var.expiry_notification_template_text
+    EXPIRY_NOTIFICATION_TEMPLATE_SUBJECT      = var.expiry_notification_template_subject
+    LEASE_RULE_WARNING_TEMPLATE_HTML          = var.lease_rule_warning_template_html
+    LEASE_RULE_WARNING_TEMPLATE_TEXT          = var.lease_rule_warning_template_text
+    LEASE_RULE_WARNING_TEMPLATE_SUBJECT       = var.lease_rule_warning_template_subject
+    FROZEN_LEASE_GRACE_HOURS                  = var.frozen_lease_grace_hours
+    ARTIFACTS_BUCKET                          = aws_s3_bucket.artifacts.id
+    EXCHANGE_RATES_S3_KEY                     = join("", aws_s3_bucket_object.exchange_rates.*.key)
   }
 }
 
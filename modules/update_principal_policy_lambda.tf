@@ -8,20 +8,29 @@ module "update_principal_policy" {
   alarm_topic_arn = aws_sns_topic.alarms_topic.arn
 
   environment = {
-    DEBUG                          = "false"
-    NAMESPACE                      = var.namespace
-    AWS_CURRENT_REGION             = var.aws_region
-    ACCOUNT_DB                     = aws_dynamodb_table.accounts.id
-    LEASE_DB                       = aws_dynamodb_table.leases.id
-    ARTIFACTS_BUCKET               = aws_s3_bucket.artifacts.id
-    PRINCIPAL_ROLE_NAME            = local.principal_role_name
-    PRINCIPAL_POLICY_NAME          = local.principal_policy_name
-    PRINCIPAL_POLICY_S3_KEY        = aws_s3_bucket_object.principal_policy.key
-    PRINCIPAL_IAM_DENY_TAGS        = join(",", var.principal_iam_deny_tags)
-    ALLOWED_REGIONS                = join(",", var.allowed_regions)
-    PRINCIPAL_MAX_SESSION_DURATION = 14400
-    TAG_ENVIRONMENT                = var.namespace == "prod" ? "PROD" : "NON-PROD"
-    TAG_APP_NAME                   = lookup(var.global_tags, "AppName")
+    DEBUG                                = "false"
+    NAMESPACE                            = var.namespace
+    AWS_CURRENT_REGION                   = var.aws_region
+    ACCOUNT_DB                           = aws_dynamodb_table.accounts.id
+    LEASE_DB                             = aws_dynamodb_table.leases.id
+    HISTORY_DB                           = aws_dynamodb_table.history.id
+    ARTIFACTS_BUCKET                     = aws_s3_bucket.artifacts.id
+    MASTER_ACCOUNT_ID                    = local.account_id
+    PRINCIPAL_ROLE_NAME                  = local.principal_role_name
+    PRINCIPAL_POLICY_NAME                = local.principal_policy_name
+    PRINCIPAL_POLICY_S3_KEY              = aws_s3_bucket_object.principal_policy.key
+    PRINCIPAL_TRUST_POLICY_S3_KEY        = aws_s3_bucket_object.principal_trust_policy.key
+    PRINCIPAL_TRUST_IDP_ARNS             = join(",", var.principal_trust_idp_arns)
+    PRINCIPAL_IAM_DENY_TAGS              = join(",", var.principal_iam_deny_tags)
+    ALLOWED_REGIONS                      = join(",", var.allowed_regions)
+    PRINCIPAL_MAX_SESSION_DURATION       = 14400
+    TAG_ENVIRONMENT                      = var.namespace == "prod" ? "PROD" : "NON-PROD"
+    TAG_APP_NAME                         = lookup(var.global_tags, "AppName")
+    RESET_COMPLETE_TOPIC_ARN             = aws_sns_topic.reset_complete.arn
+    PRINCIPAL_POLICY_PROFILES            = jsonencode(var.principal_policy_profiles)
+    PRINCIPAL_POLICY_APPROVED_STATEMENTS = jsonencode(var.principal_policy_approved_statements)
+    PRINCIPAL_PERMISSIONS_BOUNDARY       = var.principal_permissions_boundary
+    FROZEN_LEASE_POLICY                  = var.frozen_lease_policy
   }
 }
 
@@ -53,6 +62,21 @@ resource "aws_lambda_permission" "update_principal_policy_on_lease_create" {
   source_arn    = aws_sns_topic.lease_added.arn
 }
 
+// Revert to the default principal policy, once the account is reset
+resource "aws_sns_topic_subscription" "update_principal_policy_on_reset_complete" {
+  topic_arn = aws_sns_topic.reset_complete.arn
+  protocol  = "lambda"
+  endpoint  = module.update_principal_policy.arn
+}
+
+resource "aws_lambda_permission" "update_principal_policy_on_reset_complete" {
+  statement_id  = "AllowInvokeFromResetCompleteTopic"
+  action        = "lambda:InvokeFunction"
+  function_name = module.update_principal_policy.name
+  principal     = "sns.amazonaws.com"
+  source_arn    = aws_sns_topic.reset_complete.arn
+}
+
 resource "aws_iam_role_policy" "update_principal_policy" {
   role   = module.update_principal_policy.execution_role_name
   policy = <<POLICY
@@ -66,6 +90,16 @@ resource "aws_iam_role_policy" "update_principal_policy" {
         ],
         "Resource": "${aws_dynamodb_table.accounts.arn}"
     },
+    {
+        "Effect": "Allow",
+        "Action": [
+            "dynamodb:Query"
+        ],
+        "Resource": [
+            "${aws_dynamodb_table.leases.arn}",
+            "${aws_dynamodb_table.leases.arn}/index/*"
+        ]
+    },
     {
         "Effect": "Allow",
         "Action": [
@@ -52,11 +52,69 @@ variable "reset_nuke_toggle" {
   default     = "true"
 }
 
+variable "reset_steps" {
+  description = "Comma-separated reset steps to run in order, each with an optional timeout. Available steps: rds-backups, athena, s3-versioned-buckets, ec2-images, aws-nuke. eg. \"rds-backups:10m,athena,s3-versioned-buckets,aws-nuke:60m\""
+  default     = "rds-backups,athena,aws-nuke"
+}
+
+variable "reset_verify_toggle" {
+  description = "Set to 'false' to skip checking for resources which remain after a reset. Accounts with unexpected leftover resources are Quarantined, instead of Ready."
+  default     = "true"
+}
+
+variable "reset_verify_allowlist" {
+  type        = list(string)
+  description = "Patterns for resources which are expected to remain after a reset, in addition to the DCE roles and policies. eg. \"global - IAMRole - OrganizationAccountAccessRole\". A '*' matches any characters."
+  default     = []
+}
+
+variable "reset_nuke_pool_config" {
+  type        = any
+  description = "aws-nuke config overrides for accounts in each account pool, by pool name. eg. { shared = { filters = { S3Bucket = [{ type = \"glob\", value = \"s3://fixtures-*\" }] }, excludeResourceTypes = [\"Route53HostedZone\"] } }"
+  default     = {}
+}
+
+variable "reset_max_attempts" {
+  type        = number
+  description = "Failed resets are retried, until the reset of an account fails this many times in a row. The account's reset status is then set to Alarm."
+  default     = 3
+}
+
+variable "reset_max_receive_count" {
+  type        = number
+  description = "Reset requests which fail to start this many times are moved to the reset dead-letter queue"
+  default     = 5
+}
+
+variable "reset_executor" {
+  type        = string
+  description = "Where accounts are reset. \"codebuild\" runs resets in CodeBuild, triggered by the process_reset_queue Lambda. With \"external\", the reset queue is not polled, and a reset-worker process must be run to reset accounts."
+  default     = "codebuild"
+}
+
 variable "populate_reset_queue_schedule_expression" {
   description = "The schedule used with CloudWatch to enqueue accounts for reset."
   default     = "rate(6 hours)" // Runs every six hours
 }
 
+variable "principal_policy_profiles" {
+  type        = any
+  description = "Named sets of IAM statements, which leases may add to the principal policy with `policyProfile`. eg. { analytics = { description = \"Athena and Glue\", statements = [{ Effect = \"Allow\", Action = [\"athena:*\", \"glue:*\"], Resource = \"*\" }] } }"
+  default     = {}
+}
+
+variable "principal_policy_approved_statements" {
+  type        = any
+  description = "IAM statements which any user may add to the principal policy of their lease, with `policyStatements`. Admins may add any statements."
+  default     = []
+}
+
+variable "principal_permissions_boundary" {
+  type        = string
+  description = "Name or ARN of an IAM policy, set as the permissions boundary of the principal role. Principals must set the same boundary on IAM roles and users they create. A policy name refers to a customer managed policy in each child account. No permissions boundary is set, if empty."
+  default     = ""
+}
+
 variable "principal_iam_deny_tags" {
   type        = list(string)
   description = "IAM principal roles will be denied access to resources with the `AppName` tag set to this value"
@@ -90,11 +148,11 @@ variable "budget_notification_template_html" {
 <p>
 {{if .IsOverBudget}}
 Lease for principal {{.Lease.PrincipalID}} in AWS Account {{.Lease.AccountID}}
-has exceeded its budget of $${{.Lease.BudgetAmount}}. Actual spend is $${{.ActualSpend}}
+has exceeded its budget of {{.BudgetAmount}} {{.Currency}}. Actual spend is {{.ActualSpend}} {{.Currency}}
 {{else}}
 Lease for principal {{.Lease.PrincipalID}} in AWS Account {{.Lease.AccountID}}
-has exceeded the {{.ThresholdPercentile}}% threshold limit for its budget of $${{.Lease.BudgetAmount}}.
-Actual spend is $${{.ActualSpend}}
+has exceeded the {{.ThresholdPercentile}}% threshold limit for its budget of {{.BudgetAmount}} {{.Currency}}.
+Actual spend is {{.ActualSpend}} {{.Currency}}
 {{end}}
 </p>
 TMPL
@@ -106,11 +164,11 @@ variable "budget_notification_template_text" {
   default     = <<TMPL
 {{if .IsOverBudget}}
 Lease for principal {{.Lease.PrincipalID}} in AWS Account {{.Lease.AccountID}}
-has exceeded its budget of $${{.Lease.BudgetAmount}}. Actual spend is $${{.ActualSpend}}
+has exceeded its budget of {{.BudgetAmount}} {{.Currency}}. Actual spend is {{.ActualSpend}} {{.Currency}}
 {{else}}
 Lease for principal {{.Lease.PrincipalID}} in AWS Account {{.Lease.AccountID}}
-has exceeded the {{.ThresholdPercentile}}% threshold limit for its budget of $${{.Lease.BudgetAmount}}.
-Actual spend is $${{.ActualSpend}}
+has exceeded the {{.ThresholdPercentile}}% threshold limit for its budget of {{.BudgetAmount}} {{.Currency}}.
+Actual spend is {{.ActualSpend}} {{.Currency}}
 {{end}}
 TMPL
 }
@@ -123,6 +181,93 @@ Lease {{if .IsOverBudget}}over budget{{else}}at {{.ThresholdPercentile}}% of bud
 SUBJ
 }
 
+variable "expiry_notification_hours" {
+  type        = list(number)
+  description = "Hours before a lease expires, at which expiry notification emails will be sent to users. Each notification is sent once per lease."
+  default     = [72, 24]
+}
+
+variable "expiry_notification_template_html" {
+  type        = string
+  description = "HTML template for expiry notification emails"
+  default     = <<TMPL
+<p>
+Lease for principal {{.Lease.PrincipalID}} in AWS Account {{.Lease.AccountID}}
+expires in {{.HoursRemaining}} hours, on {{.ExpiresOn}}.
+The account will then be reset, and all resources in the account will be deleted.
+</p>
+TMPL
+}
+
+variable "expiry_notification_template_text" {
+  type        = string
+  description = "Text template for expiry notification emails"
+  default     = <<TMPL
+Lease for principal {{.Lease.PrincipalID}} in AWS Account {{.Lease.AccountID}}
+expires in {{.HoursRemaining}} hours, on {{.ExpiresOn}}.
+The account will then be reset, and all resources in the account will be deleted.
+TMPL
+}
+
+variable "expiry_notification_template_subject" {
+  type        = string
+  description = "Template for expiry notification email subject"
+  default     = <<SUBJ
+Lease expires in {{.HoursRemaining}} hours [{{.Lease.AccountID}}]
+SUBJ
+}
+
+variable "lease_rule_warning_template_html" {
+  type        = string
+  description = "HTML template for emails sent when lease rules with the warn action fire"
+  default     = <<TMPL
+<p>
+Lease for principal {{.Lease.PrincipalID}} in AWS Account {{.Lease.AccountID}}
+triggered the following warnings:
+</p>
+<ul>
+{{range .Rules}}<li>{{.Name}} ({{.StatusReason}})</li>
+{{end}}</ul>
+TMPL
+}
+
+variable "lease_rule_warning_template_text" {
+  type        = string
+  description = "Text template for emails sent when lease rules with the warn action fire"
+  default     = <<TMPL
+Lease for principal {{.Lease.PrincipalID}} in AWS Account {{.Lease.AccountID}}
+triggered the following warnings:
+{{range .Rules}}- {{.Name}} ({{.StatusReason}})
+{{end}}
+TMPL
+}
+
+variable "lease_rule_warning_template_subject" {
+  type        = string
+  description = "Template for the subject of emails sent when lease rules with the warn action fire"
+  default     = <<SUBJ
+Lease warning [{{.Lease.AccountID}}]
+SUBJ
+}
+
+variable "lease_rules" {
+  type        = any
+  description = "Rules which warn, freeze, or end active leases, evaluated in order by the update_lease_status Lambda. eg. [{ name = \"idle\", type = \"idle\", action = \"end\", idleHours = 48 }]. Defaults to freezing leases which are expired, and ending leases which are over budget, or over the principal budget. See docs/howto.md"
+  default     = []
+}
+
+variable "frozen_lease_grace_hours" {
+  type        = number
+  description = "Hours for which frozen leases keep their account, before the lease is ended and the account is reset. Leases are ended immediately, instead of being frozen, if 0."
+  default     = 24
+}
+
+variable "frozen_lease_policy" {
+  type        = string
+  description = "Principal policy of accounts with a frozen lease. One of `denyAll` (deny all actions), or `readOnly` (allow principals to read and export their data)."
+  default     = "denyAll"
+}
+
 variable "budget_notification_threshold_percentiles" {
   type        = list(number)
   description = "Thresholds (percentiles) at which budget notification emails will be sent to users."
@@ -135,6 +280,24 @@ variable "principal_policy" {
   default     = ""
 }
 
+variable "principal_trust_policy" {
+  type        = string
+  description = "Location of file with the trust policy template of the principal IAM role. Defaults to a trust policy for the master account, and the SAML providers in `principal_trust_idp_arns`"
+  default     = ""
+}
+
+variable "exchange_rates" {
+  type        = string
+  description = "Location of a JSON file with the exchange rates used to convert lease budgets and spend, eg. { \"base\": \"USD\", \"rates\": { \"EUR\": 0.92 } }. Leases may only be budgeted in USD, if empty."
+  default     = ""
+}
+
+variable "principal_trust_idp_arns" {
+  type        = list(string)
+  description = "ARNs of IAM identity providers (SAML or OIDC), which are passed to the principal role trust policy template"
+  default     = []
+}
+
 variable "fan_out_update_lease_status_schedule_expression" {
   type        = string
   description = "Update lease status schedule"
@@ -174,7 +337,7 @@ variable "cognito_roles_attribute_admin_name" {
 
 variable "max_lease_budget_amount" {
   type        = number
-  description = "Lease budget amount for given lease budget period"
+  description = "Maximum lease budget amount, in USD. Budgets in other currencies are converted to USD"
   default     = 1000
 }
 
@@ -184,9 +347,15 @@ variable "max_lease_period" {
   default     = 604800
 }
 
+variable "pending_lease_timeout" {
+  type        = number
+  description = "Time in seconds that a lease may wait in the waitlist for an account to become available"
+  default     = 86400
+}
+
 variable "principal_budget_amount" {
   type        = number
-  description = "User Principal's budget amount for given principal budget period"
+  description = "User Principal's budget amount for given principal budget period, in USD"
   default     = 1000
 }
 
@@ -220,3 +389,21 @@ variable "allowed_regions" {
     "sa-east-1"
   ]
 }
+
+variable "policy_drift_schedule_expression" {
+  type        = string
+  description = "How often to check accounts for changes to the principal role and policy"
+  default     = "rate(1 day)"
+}
+
+variable "policy_drift_enabled" {
+  type        = bool
+  description = "Check accounts for changes to the principal role and policy"
+  default     = true
+}
+
+variable "policy_drift_remediate" {
+  type        = bool
+  description = "Revert changes to the principal role and policy, when they are detected"
+  default     = false
+}
@@ -1,4 +1,4 @@
-package main
+package accounts
 
 import (
 	"encoding/json"
@@ -16,6 +16,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
 )
 
 // CreateAccount - Function to validate the account request to add into the pool and
@@ -38,6 +39,11 @@ func CreateAccount(w http.ResponseWriter, r *http.Request) {
 		request.Metadata = map[string]interface{}{}
 	}
 
+	// Set default pool
+	if request.Pool == "" {
+		request.Pool = db.DefaultAccountPool
+	}
+
 	// Validate the request body
 	isValid, validationRes := request.Validate()
 	if !isValid {
@@ -82,11 +88,16 @@ func CreateAccount(w http.ResponseWriter, r *http.Request) {
 		CreatedOn:      now,
 		AdminRoleArn:   request.AdminRoleArn,
 		Metadata:       request.Metadata,
+		Pool:           request.Pool,
+		Labels:         request.Labels,
+		NukeConfig:     request.NukeConfig,
 	}
 
-	// Create an IAM Role for the principal (end-user) to login to
-	masterAccountID := *CurrentAccountID
-	createRolRes, policyHash, err := createPrincipalRole(account, masterAccountID)
+	// Create an IAM Role for the principal (end-user) to login to.
+	// The master account is the AWS Account this is running in.
+	reqContext, _ := core.GetAPIGatewayContextFromContext(r.Context())
+	masterAccountID := reqContext.AccountID
+	createRolRes, policyHash, trustPolicyHash, err := createPrincipalRole(account, masterAccountID)
 	if err != nil {
 		log.Printf("failed to create principal role for %s: %s", request.ID, err)
 		WriteServerErrorWithResponse(w, "Internal server error")
@@ -94,9 +105,10 @@ func CreateAccount(w http.ResponseWriter, r *http.Request) {
 	}
 	account.PrincipalRoleArn = createRolRes.RoleArn
 	account.PrincipalPolicyHash = policyHash
+	account.PrincipalTrustPolicyHash = trustPolicyHash
 
 	// Write the Account to the DB
-	err = Dao.PutAccount(account)
+	err = requestDao(r).PutAccount(account)
 	if err != nil {
 		log.Printf("Failed to add account %s to pool: %s",
 			request.ID, err.Error())
@@ -148,6 +160,9 @@ type CreateRequest struct {
 	ID           string                 `json:"id"`
 	AdminRoleArn string                 `json:"adminRoleArn"`
 	Metadata     map[string]interface{} `json:"metadata"`
+	Pool         string                 `json:"pool"`
+	Labels       map[string]string      `json:"labels"`
+	NukeConfig   *db.NukeConfig         `json:"nukeConfig"`
 }
 
 // Validate - Checks if the Account Request has the provided id and adminRoleArn
@@ -163,6 +178,13 @@ func (req *CreateRequest) Validate() (bool, *string) {
 		isValid = false
 		validationErrors = append(validationErrors, errors.New("missing required field \"adminRoleArn\""))
 	}
+	if req.NukeConfig != nil {
+		err := req.NukeConfig.Validate()
+		if err != nil {
+			isValid = false
+			validationErrors = append(validationErrors, fmt.Errorf("invalid field \"nukeConfig\": %s", err))
+		}
+	}
 
 	if !isValid {
 		errMsgs := []string{}
@@ -176,47 +198,38 @@ func (req *CreateRequest) Validate() (bool, *string) {
 	return true, nil
 }
 
-func createPrincipalRole(childAccount db.Account, masterAccountID string) (*rolemanager.CreateRoleWithPolicyOutput, string, error) {
-	// Create an assume role policy,
-	// to let principals from the same account assume the role.
-	//
-	// Consumers of open source DCE may modify and customize
-	// this as need (eg. to integrate with SSO/SAML)
-	// by responding to the "account-created" SNS topic
-	assumeRolePolicy := strings.TrimSpace(fmt.Sprintf(`
-		{
-			"Version": "2012-10-17",
-			"Statement": [
-				{
-					"Effect": "Allow",
-					"Principal": {
-						"AWS": "arn:aws:iam::%s:root"
-					},
-					"Action": "sts:AssumeRole",
-					"Condition": {}
-				}
-			]
-		}
-	`, masterAccountID))
+// createPrincipalRole creates the principal role and policy in the account.
+// Returns the hashes of the principal policy and of the role's trust policy.
+func createPrincipalRole(childAccount db.Account, masterAccountID string) (*rolemanager.CreateRoleWithPolicyOutput, string, string, error) {
+	// Render the assume role policy, to let principals from the
+	// master account (or the configured identity providers) assume the role.
+	trustPolicyTemplate := rolemanager.NewPrincipalTrustPolicyTemplate(StorageSvc, artifactsBucket,
+		principalTrustPolicyS3Key, principalTrustIdpArns)
+	assumeRolePolicy, trustPolicyHash, err := trustPolicyTemplate.Render(childAccount.ID, masterAccountID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	permissionsBoundaryArn := rolemanager.PermissionsBoundaryArn(principalPermissionsBoundary, childAccount.ID)
 
 	// Render the default policy for the principal
 
 	policy, policyHash, err := StorageSvc.GetTemplateObject(artifactsBucket, principalPolicyS3Key,
 		principalPolicyInput{
-			PrincipalPolicyArn:   fmt.Sprintf("arn:aws:iam::%s:policy/%s", childAccount.ID, policyName),
-			PrincipalRoleArn:     fmt.Sprintf("arn:aws:iam::%s:role/%s", childAccount.ID, principalRoleName),
-			PrincipalIAMDenyTags: principalIAMDenyTags,
-			AdminRoleArn:         childAccount.AdminRoleArn,
-			Regions:              allowedRegions,
+			PrincipalPolicyArn:     fmt.Sprintf("arn:aws:iam::%s:policy/%s", childAccount.ID, policyName),
+			PrincipalRoleArn:       fmt.Sprintf("arn:aws:iam::%s:role/%s", childAccount.ID, principalRoleName),
+			PrincipalIAMDenyTags:   principalIAMDenyTags,
+			AdminRoleArn:           childAccount.AdminRoleArn,
+			Regions:                allowedRegions,
+			PermissionsBoundaryArn: permissionsBoundaryArn,
 		})
 	if err != nil {
-		return nil, "", err
+		return nil, "", "", err
 	}
 
 	// Assume role into the new account
 	accountSession, err := TokenSvc.NewSession(AWSSession, childAccount.AdminRoleArn)
 	if err != nil {
-		return nil, "", err
+		return nil, "", "", err
 	}
 	iamClient := iam.New(accountSession)
 
@@ -234,9 +247,10 @@ func createPrincipalRole(childAccount db.Account, masterAccountID string) (*role
 		Tags: append(tags,
 			&iam.Tag{Key: aws.String("Name"), Value: aws.String("DCEPrincipal")},
 		),
+		PermissionsBoundary:       permissionsBoundaryArn,
 		IgnoreAlreadyExistsErrors: true,
 	})
-	return createRoleOutput, policyHash, err
+	return createRoleOutput, policyHash, trustPolicyHash, err
 }
 
 type principalPolicyInput struct {
@@ -245,4 +259,7 @@ type principalPolicyInput struct {
 	PrincipalIAMDenyTags []string
 	AdminRoleArn         string
 	Regions              []string
+	// PermissionsBoundaryArn is the permissions boundary of the principal role,
+	// which principals must set on roles they create. May be empty.
+	PermissionsBoundaryArn string
 }
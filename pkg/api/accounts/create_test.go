@@ -1,9 +1,11 @@
-package main
+package accounts
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"testing"
@@ -177,6 +179,7 @@ func TestCreate(t *testing.T) {
 				assert.Equal(t, "1234567890", account.ID)
 				assert.Equal(t, "arn:mock", account.AdminRoleArn)
 				assert.Equal(t, "arn:aws:iam::1234567890:role/DCEPrincipal", account.PrincipalRoleArn)
+				assert.Equal(t, db.DefaultAccountPool, account.Pool)
 				return true
 			}),
 		).Return(nil)
@@ -509,6 +512,57 @@ func TestCreate(t *testing.T) {
 		tokenServiceMock.AssertExpectations(t)
 	})
 
+	t.Run("should render the principal trust policy template", func(t *testing.T) {
+		stubAllServices()
+		principalTrustPolicyS3Key = "principal_trust_policy.tmpl"
+		principalTrustIdpArns = "arn:aws:iam::1234567890:saml-provider/Okta"
+		defer func() {
+			principalTrustPolicyS3Key = ""
+			principalTrustIdpArns = ""
+		}()
+
+		// Mock the trust policy template
+		mockStorageSvc := &commonMocks.Storager{}
+		StorageSvc = mockStorageSvc
+		mockStorageSvc.On("GetTemplateObject", mock.Anything, "principal_trust_policy.tmpl", rolemanager.PrincipalTrustPolicyInput{
+			AccountID:        "1234567890",
+			MasterAccountID:  "0987654321",
+			IdpArns:          []string{"arn:aws:iam::1234567890:saml-provider/Okta"},
+			SAMLProviderArns: []string{"arn:aws:iam::1234567890:saml-provider/Okta"},
+			OIDCProviderArns: []string{},
+		}).Return("Trust Policy", "TrustPolicyETag", nil)
+		mockStorageSvc.On("GetTemplateObject", mock.Anything, mock.Anything, mock.Anything).
+			Return("Policy", "PolicyHash", nil)
+
+		roleManager := roleManagerMocks.RoleManager{}
+		RoleManager = &roleManager
+		roleManager.On("SetIAMClient", mock.Anything)
+		roleManager.On("CreateRoleWithPolicy", mock.MatchedBy(func(input *rolemanager.CreateRoleWithPolicyInput) bool {
+			return input.AssumeRolePolicyDocument == "Trust Policy"
+		})).Return(&rolemanager.CreateRoleWithPolicyOutput{}, nil)
+
+		// The account has the hash of the rendered trust policy
+		mockDb := &dbMocks.DBer{}
+		Dao = mockDb
+		mockDb.On("GetAccount", "1234567890").Return(nil, nil)
+		mockDb.On("PutAccount", mock.MatchedBy(func(account db.Account) bool {
+			return account.PrincipalPolicyHash == "PolicyHash" &&
+				account.PrincipalTrustPolicyHash == fmt.Sprintf("%x", sha256.Sum256([]byte("Trust Policy")))
+		})).Return(nil)
+
+		res, err := Handler(
+			context.TODO(),
+			createAccountAPIRequest(t, CreateRequest{
+				ID:           "1234567890",
+				AdminRoleArn: "arn:mock",
+			}, "0987654321"),
+		)
+		require.Nil(t, err)
+		require.Equal(t, 201, res.StatusCode)
+		roleManager.AssertExpectations(t)
+		mockDb.AssertExpectations(t)
+	})
+
 	t.Run("should return a 500 if creating the principal IAM role fails", func(t *testing.T) {
 		// Create the controller
 		// Mock the RoleManager, to return an error on IAM Role Creation
@@ -0,0 +1,68 @@
+package accounts
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/common"
+)
+
+// resetDeadLetters returns the dead-letter queue for reset requests
+func resetDeadLetters() *common.DeadLetterQueue {
+	return &common.DeadLetterQueue{Queue: Queue, URL: resetDLQURL}
+}
+
+// GetResetDeadLetters - Returns the reset requests which failed
+// too many times, and were moved to the reset dead-letter queue
+func GetResetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := resetDeadLetters().List()
+	if err != nil {
+		log.Printf("Failed to list reset dead letters: %s", err)
+		WriteServerErrorWithResponse(w, "Failed to list reset dead letters")
+		return
+	}
+
+	json.NewEncoder(w).Encode(response.CreateResetDeadLettersResponse(letters))
+}
+
+// GetResetDeadLetter - Returns a single dead-lettered reset request
+func GetResetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["deadLetterId"]
+
+	letter, err := resetDeadLetters().Get(id)
+	if err != nil {
+		log.Printf("Failed to get reset dead letter %s: %s", id, err)
+		WriteServerErrorWithResponse(w, "Failed to get reset dead letter "+id)
+		return
+	}
+	if letter == nil {
+		WriteNotFoundError(w)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response.CreateResetDeadLetterResponse(letter))
+}
+
+// ReplayResetDeadLetter - Sends a dead-lettered reset request back to
+// the reset queue, and removes it from the dead-letter queue
+func ReplayResetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["deadLetterId"]
+
+	letter, err := resetDeadLetters().Replay(id, resetQueueURL)
+	if err != nil {
+		log.Printf("Failed to replay reset dead letter %s: %s", id, err)
+		WriteServerErrorWithResponse(w, "Failed to replay reset dead letter "+id)
+		return
+	}
+	if letter == nil {
+		WriteNotFoundError(w)
+		return
+	}
+	log.Printf("Replayed reset of account %s from dead letter %s", letter.Body, id)
+
+	json.NewEncoder(w).Encode(response.CreateResetDeadLetterResponse(letter))
+}
@@ -0,0 +1,82 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/common"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetDeadLetters(t *testing.T) {
+	queue := common.NewMemoryQueue()
+	Queue = queue
+	dlq := &common.DeadLetterQueue{Queue: queue, URL: resetDLQURL}
+	require.Nil(t, dlq.Send(&common.DeadLetter{
+		Body:           "123456789012",
+		Error:          "Account 123456789012 doesn't exist",
+		ReceiveCount:   5,
+		DeadLetteredOn: 1572912000,
+	}))
+	letters, err := dlq.List()
+	require.Nil(t, err)
+	id := letters[0].ID
+
+	t.Run("should list dead-lettered reset requests", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/accounts/resets/deadletters"}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, actualResponse.StatusCode)
+
+		var res []*response.ResetDeadLetterResponse
+		require.Nil(t, json.Unmarshal([]byte(actualResponse.Body), &res))
+		require.Equal(t, []*response.ResetDeadLetterResponse{{
+			ID:             id,
+			AccountID:      "123456789012",
+			Error:          "Account 123456789012 doesn't exist",
+			ReceiveCount:   5,
+			DeadLetteredOn: 1572912000,
+		}}, res)
+	})
+
+	t.Run("should get a dead-lettered reset request", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/accounts/resets/deadletters/" + id}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, actualResponse.StatusCode)
+
+		var res response.ResetDeadLetterResponse
+		require.Nil(t, json.Unmarshal([]byte(actualResponse.Body), &res))
+		require.Equal(t, "123456789012", res.AccountID)
+	})
+
+	t.Run("should return 404 for unknown dead letters", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/accounts/resets/deadletters/missing"}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 404, actualResponse.StatusCode)
+
+		mockRequest = events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/accounts/resets/deadletters/missing/replay"}
+		actualResponse, err = Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 404, actualResponse.StatusCode)
+	})
+
+	t.Run("should replay a dead-lettered reset request", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/accounts/resets/deadletters/" + id + "/replay"}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, actualResponse.StatusCode)
+
+		require.Equal(t, []string{"123456789012"}, queue.Messages(resetQueueURL))
+		require.Empty(t, queue.Messages(resetDLQURL))
+	})
+}
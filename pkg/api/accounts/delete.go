@@ -1,4 +1,4 @@
-package main
+package accounts
 
 import (
 	"fmt"
@@ -18,7 +18,7 @@ import (
 func DeleteAccount(w http.ResponseWriter, r *http.Request) {
 
 	accountID := mux.Vars(r)["accountId"]
-	deletedAccount, err := Dao.DeleteAccount(accountID)
+	deletedAccount, err := requestDao(r).DeleteAccount(accountID)
 
 	// Handle DB errors
 	if err != nil {
@@ -0,0 +1,43 @@
+package accounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Optum/dce/pkg/api/response"
+)
+
+// GetAccountHistory - Returns the history of changes made to an account
+func GetAccountHistory(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	records, err := History.GetHistory(accountID)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Failed to get history for account %s", accountID)
+		log.Printf("%s: %s", errorMessage, err)
+		WriteServerErrorWithResponse(w, errorMessage)
+		return
+	}
+
+	// Accounts which never existed have no history.
+	// Deleted accounts keep their history.
+	if len(records) == 0 {
+		account, err := Dao.GetAccount(accountID)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Failed to get account %s", accountID)
+			log.Printf("%s: %s", errorMessage, err)
+			WriteServerErrorWithResponse(w, errorMessage)
+			return
+		}
+		if account == nil {
+			WriteNotFoundError(w)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(response.CreateHistoryResponse(records))
+}
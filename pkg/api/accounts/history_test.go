@@ -0,0 +1,49 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountHistory(t *testing.T) {
+	History = history.NewMemory()
+	recorder := history.NewRecorder(db.NewMemoryDB(7), History, "test")
+	Dao = recorder
+	require.Nil(t, recorder.PutAccount(db.Account{ID: "123", AccountStatus: db.NotReady}))
+	_, err := recorder.TransitionAccountStatus("123", db.NotReady, db.Ready)
+	require.Nil(t, err)
+
+	t.Run("should return the account history", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/accounts/123/history"}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, actualResponse.StatusCode)
+
+		var records []*response.HistoryRecordResponse
+		require.Nil(t, json.Unmarshal([]byte(actualResponse.Body), &records))
+		require.Len(t, records, 2)
+		require.Equal(t, history.Created, records[0].Event)
+		require.Equal(t, history.StatusChanged, records[1].Event)
+		require.Equal(t, "test", records[1].Actor)
+		require.Equal(t, []*response.HistoryChangeResponse{
+			{Field: "AccountStatus", PrevValue: "NotReady", NextValue: "Ready"},
+		}, records[1].Changes)
+	})
+
+	t.Run("should return 404 for unknown accounts", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/accounts/456/history"}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 404, actualResponse.StatusCode)
+	})
+}
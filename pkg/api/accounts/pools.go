@@ -0,0 +1,95 @@
+package accounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/db"
+)
+
+// GetAccountsByPool - Returns the accounts in a pool,
+// optionally filtered by status
+func GetAccountsByPool(w http.ResponseWriter, r *http.Request) {
+	pool := r.FormValue("pool")
+
+	var status db.AccountStatus
+	if accountStatus := r.FormValue("accountStatus"); accountStatus != "" {
+		var err error
+		status, err = db.ParseAccountStatus(accountStatus)
+		if err != nil {
+			WriteRequestValidationError(w, fmt.Sprintf("Invalid accountStatus: %s", err))
+			return
+		}
+	}
+
+	accounts, err := Dao.GetAccounts()
+	if err != nil {
+		errorMessage := fmt.Sprintf("Failed to query database: %s", err)
+		log.Print(errorMessage)
+		WriteServerErrorWithResponse(w, errorMessage)
+		return
+	}
+
+	// Serialize them for the JSON response.
+	selector := db.AccountSelector{Pool: pool}
+	accountResponses := []*response.AccountResponse{}
+	for _, a := range accounts {
+		if !selector.Matches(a) || (status != "" && a.AccountStatus != status) {
+			continue
+		}
+		acctRes := response.AccountResponse(*a)
+		accountResponses = append(accountResponses, &acctRes)
+	}
+
+	json.NewEncoder(w).Encode(accountResponses)
+}
+
+// GetAccountPools - Returns the capacity of each account pool
+func GetAccountPools(w http.ResponseWriter, r *http.Request) {
+	accounts, err := Dao.GetAccounts()
+	if err != nil {
+		errorMessage := fmt.Sprintf("Failed to query database: %s", err)
+		log.Print(errorMessage)
+		WriteServerErrorWithResponse(w, errorMessage)
+		return
+	}
+
+	// Count accounts in each pool, by status
+	poolsByName := map[string]*response.AccountPoolResponse{}
+	for _, a := range accounts {
+		poolName := a.PoolName()
+		pool, ok := poolsByName[poolName]
+		if !ok {
+			pool = &response.AccountPoolResponse{Pool: poolName}
+			poolsByName[poolName] = pool
+		}
+
+		switch a.AccountStatus {
+		case db.Ready:
+			pool.Ready++
+		case db.NotReady:
+			pool.NotReady++
+		case db.Leased:
+			pool.Leased++
+		case db.Orphaned:
+			pool.Orphaned++
+		case db.Quarantined:
+			pool.Quarantined++
+		}
+		pool.Total++
+	}
+
+	poolResponses := []*response.AccountPoolResponse{}
+	for _, pool := range poolsByName {
+		poolResponses = append(poolResponses, pool)
+	}
+	sort.Slice(poolResponses, func(i, j int) bool {
+		return poolResponses[i].Pool < poolResponses[j].Pool
+	})
+
+	json.NewEncoder(w).Encode(poolResponses)
+}
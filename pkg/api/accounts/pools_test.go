@@ -0,0 +1,89 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountPools(t *testing.T) {
+	dbSvc := db.NewMemoryDB(7)
+	for _, account := range []db.Account{
+		{ID: "1", AccountStatus: db.Ready},
+		{ID: "2", AccountStatus: db.Leased, Pool: "default"},
+		{ID: "3", AccountStatus: db.Ready, Pool: "sandbox"},
+		{ID: "4", AccountStatus: db.NotReady, Pool: "sandbox"},
+		{ID: "5", AccountStatus: db.Orphaned, Pool: "sandbox"},
+	} {
+		require.Nil(t, dbSvc.PutAccount(account))
+	}
+	Dao = dbSvc
+
+	t.Run("should return the capacity of each pool", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/accounts/pools"}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, actualResponse.StatusCode)
+
+		var pools []*response.AccountPoolResponse
+		require.Nil(t, json.Unmarshal([]byte(actualResponse.Body), &pools))
+		require.Equal(t, []*response.AccountPoolResponse{
+			{Pool: "default", Ready: 1, Leased: 1, Total: 2},
+			{Pool: "sandbox", Ready: 1, NotReady: 1, Orphaned: 1, Total: 3},
+		}, pools)
+	})
+
+	t.Run("should list the accounts in a pool", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{
+			HTTPMethod:            http.MethodGet,
+			Path:                  "/accounts",
+			QueryStringParameters: map[string]string{"pool": "default"},
+		}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, actualResponse.StatusCode)
+
+		var accounts []*response.AccountResponse
+		require.Nil(t, json.Unmarshal([]byte(actualResponse.Body), &accounts))
+		require.Len(t, accounts, 2)
+		require.Equal(t, "1", accounts[0].ID)
+		require.Equal(t, "2", accounts[1].ID)
+	})
+
+	t.Run("should list the accounts in a pool, by status", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{
+			HTTPMethod:            http.MethodGet,
+			Path:                  "/accounts",
+			QueryStringParameters: map[string]string{"pool": "sandbox", "accountStatus": "NotReady"},
+		}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, actualResponse.StatusCode)
+
+		var accounts []*response.AccountResponse
+		require.Nil(t, json.Unmarshal([]byte(actualResponse.Body), &accounts))
+		require.Len(t, accounts, 1)
+		require.Equal(t, "4", accounts[0].ID)
+	})
+
+	t.Run("should reject an invalid status", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{
+			HTTPMethod:            http.MethodGet,
+			Path:                  "/accounts",
+			QueryStringParameters: map[string]string{"pool": "sandbox", "accountStatus": "Unknown"},
+		}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 400, actualResponse.StatusCode)
+	})
+}
@@ -0,0 +1,212 @@
+package accounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/processresetqueue"
+	"github.com/Optum/dce/pkg/resetreport"
+)
+
+// ResetAccount - Resets an account.
+// Ready and Quarantined accounts are moved to NotReady, and added to the
+// reset queue. Leased accounts are only reset with force=true, which ends
+// the lease. The account is then added to the reset queue by the
+// publish_lease_events Lambda, as for any lease which becomes inactive.
+//
+// With dryRun=true, starts a preview of the reset instead. See previewReset.
+func ResetAccount(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	dryRun, err := parseBoolQuery(r, "dryRun")
+	if err != nil {
+		WriteRequestValidationError(w, "dryRun must be true or false")
+		return
+	}
+	force, err := parseBoolQuery(r, "force")
+	if err != nil {
+		WriteRequestValidationError(w, "force must be true or false")
+		return
+	}
+
+	account, err := Dao.GetAccount(accountID)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Failed to get account %s", accountID)
+		log.Printf("%s: %s", errorMessage, err)
+		WriteServerErrorWithResponse(w, errorMessage)
+		return
+	}
+	if account == nil {
+		WriteNotFoundError(w)
+		return
+	}
+
+	if dryRun {
+		previewReset(w, account)
+		return
+	}
+
+	dao := requestDao(r)
+	switch account.AccountStatus {
+	case db.Ready, db.Quarantined:
+		_, err = dao.TransitionAccountStatus(accountID, account.AccountStatus, db.NotReady)
+	case db.NotReady:
+		// The account is already waiting to be reset
+	case db.Leased:
+		if !force {
+			WriteAPIErrorResponse(w, http.StatusConflict, "Conflict",
+				fmt.Sprintf("Account %s is leased. Use force=true to end the lease, and reset the account", accountID))
+			return
+		}
+		err = endLeaseForReset(dao, accountID)
+	default:
+		WriteAPIErrorResponse(w, http.StatusConflict, "Conflict",
+			fmt.Sprintf("Account %s cannot be reset with status %s", accountID, account.AccountStatus))
+		return
+	}
+	if err != nil {
+		if _, ok := err.(*db.StatusTransitionError); ok {
+			WriteAPIErrorResponse(w, http.StatusConflict, "Conflict",
+				fmt.Sprintf("Account %s changed status while starting the reset. Please try again", accountID))
+			return
+		}
+		log.Printf("Failed to reset account %s: %s", accountID, err)
+		WriteServerErrorWithResponse(w, fmt.Sprintf("Failed to reset account %s", accountID))
+		return
+	}
+
+	if account.AccountStatus != db.Leased {
+		err = Queue.SendMessage(&resetQueueURL, &accountID)
+		// The account is NotReady, so the populate_reset_queue Lambda
+		// still adds it to the reset queue if this fails
+		if err != nil {
+			log.Printf("Failed to add account %s to reset queue: %s", accountID, err)
+		}
+	}
+	log.Printf("Reset of account %s requested, with status %s", accountID, account.AccountStatus)
+
+	account, err = Dao.GetAccount(accountID)
+	if err != nil || account == nil {
+		log.Printf("Failed to get account %s after reset: %s", accountID, err)
+		WriteServerErrorWithResponse(w, "Internal server error")
+		return
+	}
+	accountJSON, err := json.Marshal(response.AccountResponse(*account))
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal account response for %s: %s", accountID, err)
+		WriteServerErrorWithResponse(w, "Internal server error")
+		return
+	}
+
+	WriteAPIResponse(w, http.StatusAccepted, string(accountJSON))
+}
+
+// endLeaseForReset ends the active or frozen lease of an account, and moves
+// the account to NotReady
+func endLeaseForReset(dao db.DBer, accountID string) error {
+	leases, err := dao.FindLeasesByAccount(accountID)
+	if err != nil {
+		return err
+	}
+	for _, lease := range leases {
+		if lease.LeaseStatus != db.Active && lease.LeaseStatus != db.Frozen {
+			continue
+		}
+		log.Printf("Ending lease %s of account %s, to reset the account", lease.ID, accountID)
+		_, err = dao.TransitionLeaseStatus(accountID, lease.PrincipalID, lease.LeaseStatus, db.Inactive, db.LeaseAccountReset)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = dao.TransitionAccountStatus(accountID, db.Leased, db.NotReady)
+	if err != nil && isAccountNotReady(dao, accountID, err) {
+		return nil
+	}
+	return err
+}
+
+// isAccountNotReady checks if a failed transition to NotReady failed because
+// the account is already NotReady, eg. because publish_lease_events moved
+// the account to NotReady once the lease was ended
+func isAccountNotReady(dao db.DBer, accountID string, err error) bool {
+	if _, ok := err.(*db.StatusTransitionError); !ok {
+		return false
+	}
+	account, err := dao.GetAccount(accountID)
+	if err != nil || account == nil {
+		return false
+	}
+	if account.AccountStatus != db.NotReady {
+		return false
+	}
+	log.Printf("Account %s is already NotReady", accountID)
+	return true
+}
+
+// parseBoolQuery parses an optional boolean query parameter
+func parseBoolQuery(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// previewReset starts a preview of the reset of an account:
+// aws-nuke lists the resources it would delete, but nothing is deleted,
+// and the account status is not changed. The report of the preview is
+// returned with the Running outcome, and can be fetched from
+// GetAccountReset once the preview completes.
+func previewReset(w http.ResponseWriter, account *db.Account) {
+	accountID := account.ID
+
+	env, err := processresetqueue.BuildEnvironment(account)
+	if err != nil {
+		log.Printf("Failed to preview reset of account %s: %s", accountID, err)
+		WriteServerErrorWithResponse(w, fmt.Sprintf("Failed to preview reset of account %s", accountID))
+		return
+	}
+
+	// Save the report before starting the build, so the build
+	// can't complete before the report exists
+	report := resetreport.NewReport(accountID, "", true)
+	report.Outcome = resetreport.Running
+	err = ResetReports.PutReport(report)
+	if err != nil {
+		log.Printf("Failed to save reset report for account %s: %s", accountID, err)
+		WriteServerErrorWithResponse(w, fmt.Sprintf("Failed to preview reset of account %s", accountID))
+		return
+	}
+
+	env["RESET_PREVIEW"] = "true"
+	env["RESET_RUN_ID"] = report.RunID
+	buildID, err := ResetBuild.StartBuild(&resetBuildName, env)
+	if err != nil {
+		log.Printf("Failed to start reset preview build for account %s: %s", accountID, err)
+		report.Complete(err)
+		if err := ResetReports.PutReport(report); err != nil {
+			log.Printf("Failed to save reset report for account %s: %s", accountID, err)
+		}
+		WriteServerErrorWithResponse(w, fmt.Sprintf("Failed to preview reset of account %s", accountID))
+		return
+	}
+	log.Printf("Started reset preview %s for account %s in build %s", report.RunID, accountID, buildID)
+
+	// The build saves the report with its build ID once it completes
+	report.BuildID = buildID
+	reportJSON, err := json.Marshal(response.CreateResetReportResponse(report))
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal reset report for %s: %s", accountID, err)
+		WriteServerErrorWithResponse(w, "Internal server error")
+		return
+	}
+
+	WriteAPIResponse(w, http.StatusAccepted, string(reportJSON))
+}
@@ -0,0 +1,264 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/common"
+	commonMocks "github.com/Optum/dce/pkg/common/mocks"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/resetreport"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetAccount(t *testing.T) {
+	setup := func() *common.MemoryBuilder {
+		ResetReports = resetreport.NewMemory()
+		Dao = db.NewMemoryDB(7)
+		require.Nil(t, Dao.PutAccount(db.Account{
+			ID:                  "123456789012",
+			AccountStatus:       db.Leased,
+			AdminRoleArn:        "arn:aws:iam::123456789012:role/AdminRole",
+			PrincipalRoleArn:    "arn:aws:iam::123456789012:role/PrincipalRole",
+			PrincipalPolicyHash: "hash",
+		}))
+		builder := common.NewMemoryBuilder()
+		ResetBuild = builder
+		resetBuildName = "reset"
+		return builder
+	}
+
+	t.Run("should start a preview build, without changing the account", func(t *testing.T) {
+		builder := setup()
+		mockRequest := events.APIGatewayProxyRequest{
+			HTTPMethod:            http.MethodPost,
+			Path:                  "/accounts/123456789012/reset",
+			QueryStringParameters: map[string]string{"dryRun": "true"},
+		}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 202, actualResponse.StatusCode)
+
+		var report response.ResetReportResponse
+		require.Nil(t, json.Unmarshal([]byte(actualResponse.Body), &report))
+		require.Equal(t, "123456789012", report.AccountID)
+		require.Equal(t, resetreport.Running, report.Outcome)
+		require.True(t, report.DryRun)
+		require.Equal(t, "reset:1", report.BuildID)
+
+		builds := builder.Builds()
+		require.Len(t, builds, 1)
+		require.Equal(t, "reset", builds[0].ProjectName)
+		require.Equal(t, map[string]string{
+			"RESET_ACCOUNT":                     "123456789012",
+			"RESET_ACCOUNT_ADMIN_ROLE_NAME":     "AdminRole",
+			"RESET_ACCOUNT_PRINCIPAL_ROLE_NAME": "PrincipalRole",
+			"RESET_PREVIEW":                     "true",
+			"RESET_RUN_ID":                      report.RunID,
+		}, builds[0].EnvironmentVariables)
+
+		// The report is saved before the build starts
+		saved, err := ResetReports.GetReport("123456789012", report.RunID)
+		require.Nil(t, err)
+		require.Equal(t, resetreport.Running, saved.Outcome)
+
+		account, err := Dao.GetAccount("123456789012")
+		require.Nil(t, err)
+		require.Equal(t, db.Leased, account.AccountStatus)
+	})
+
+	t.Run("should fail the report if the build does not start", func(t *testing.T) {
+		setup()
+		mockBuilder := &commonMocks.Builder{}
+		mockBuilder.On("StartBuild", mock.Anything, mock.Anything).Return("", errors.New("build failed"))
+		ResetBuild = mockBuilder
+		mockRequest := events.APIGatewayProxyRequest{
+			HTTPMethod:            http.MethodPost,
+			Path:                  "/accounts/123456789012/reset",
+			QueryStringParameters: map[string]string{"dryRun": "true"},
+		}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 500, actualResponse.StatusCode)
+
+		reports, err := ResetReports.GetReports("123456789012")
+		require.Nil(t, err)
+		require.Len(t, reports, 1)
+		require.Equal(t, resetreport.Failed, reports[0].Outcome)
+		require.Equal(t, "build failed", reports[0].Error)
+	})
+
+	t.Run("should return 404 for unknown accounts", func(t *testing.T) {
+		builder := setup()
+		mockRequest := events.APIGatewayProxyRequest{
+			HTTPMethod:            http.MethodPost,
+			Path:                  "/accounts/999999999999/reset",
+			QueryStringParameters: map[string]string{"dryRun": "true"},
+		}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 404, actualResponse.StatusCode)
+		require.Empty(t, builder.Builds())
+	})
+
+	t.Run("should reject invalid query parameters", func(t *testing.T) {
+		builder := setup()
+		for _, query := range []map[string]string{{"dryRun": "maybe"}, {"force": "maybe"}} {
+			mockRequest := events.APIGatewayProxyRequest{
+				HTTPMethod:            http.MethodPost,
+				Path:                  "/accounts/123456789012/reset",
+				QueryStringParameters: query,
+			}
+
+			actualResponse, err := Handler(context.TODO(), mockRequest)
+			require.Nil(t, err)
+			require.Equal(t, 400, actualResponse.StatusCode, query)
+		}
+		require.Empty(t, builder.Builds())
+	})
+
+	t.Run("should reset accounts", func(t *testing.T) {
+		resetQueueURL = "reset-queue"
+		receiveResets := func(queue *common.MemoryQueue) []string {
+			messages, err := queue.ReceiveMessage(&sqs.ReceiveMessageInput{
+				QueueUrl:            aws.String(resetQueueURL),
+				MaxNumberOfMessages: aws.Int64(10),
+			})
+			require.Nil(t, err)
+			accountIDs := []string{}
+			for _, message := range messages.Messages {
+				accountIDs = append(accountIDs, *message.Body)
+			}
+			return accountIDs
+		}
+		resetRequest := func(accountID string, query map[string]string) events.APIGatewayProxyResponse {
+			actualResponse, err := Handler(context.TODO(), events.APIGatewayProxyRequest{
+				HTTPMethod:            http.MethodPost,
+				Path:                  "/accounts/" + accountID + "/reset",
+				QueryStringParameters: query,
+			})
+			require.Nil(t, err)
+			return actualResponse
+		}
+
+		t.Run("should move Ready accounts to NotReady, and queue the reset", func(t *testing.T) {
+			setup()
+			queue := common.NewMemoryQueue()
+			Queue = queue
+			require.Nil(t, Dao.PutAccount(db.Account{ID: "111111111111", AccountStatus: db.Ready}))
+
+			actualResponse := resetRequest("111111111111", map[string]string{"dryRun": "false"})
+			require.Equal(t, 202, actualResponse.StatusCode)
+
+			var account response.AccountResponse
+			require.Nil(t, json.Unmarshal([]byte(actualResponse.Body), &account))
+			require.Equal(t, db.NotReady, account.AccountStatus)
+			require.Equal(t, []string{"111111111111"}, receiveResets(queue))
+		})
+
+		t.Run("should queue the reset of NotReady and Quarantined accounts", func(t *testing.T) {
+			setup()
+			queue := common.NewMemoryQueue()
+			Queue = queue
+			require.Nil(t, Dao.PutAccount(db.Account{ID: "111111111111", AccountStatus: db.NotReady}))
+			require.Nil(t, Dao.PutAccount(db.Account{ID: "222222222222", AccountStatus: db.Quarantined}))
+
+			require.Equal(t, 202, resetRequest("111111111111", nil).StatusCode)
+			require.Equal(t, 202, resetRequest("222222222222", nil).StatusCode)
+
+			account, err := Dao.GetAccount("222222222222")
+			require.Nil(t, err)
+			require.Equal(t, db.NotReady, account.AccountStatus)
+			require.Equal(t, []string{"111111111111", "222222222222"}, receiveResets(queue))
+		})
+
+		t.Run("should refuse to reset Leased accounts, unless forced", func(t *testing.T) {
+			setup()
+			queue := common.NewMemoryQueue()
+			Queue = queue
+			_, err := Dao.PutLease(db.Lease{
+				ID:          "lease-1",
+				AccountID:   "123456789012",
+				PrincipalID: "jdoe",
+				LeaseStatus: db.Active,
+			})
+			require.Nil(t, err)
+
+			actualResponse := resetRequest("123456789012", nil)
+			require.Equal(t, 409, actualResponse.StatusCode)
+			require.Contains(t, actualResponse.Body, "force=true")
+
+			actualResponse = resetRequest("123456789012", map[string]string{"force": "true"})
+			require.Equal(t, 202, actualResponse.StatusCode)
+
+			lease, err := Dao.GetLeaseByID("lease-1")
+			require.Nil(t, err)
+			require.Equal(t, db.Inactive, lease.LeaseStatus)
+			require.Equal(t, db.LeaseAccountReset, lease.LeaseStatusReason)
+			account, err := Dao.GetAccount("123456789012")
+			require.Nil(t, err)
+			require.Equal(t, db.NotReady, account.AccountStatus)
+
+			// The reset is queued once the lease becomes inactive,
+			// by publish_lease_events
+			require.Empty(t, receiveResets(queue))
+		})
+
+		t.Run("should accept forced resets, if the account is moved to NotReady by publish_lease_events", func(t *testing.T) {
+			setup()
+			Queue = common.NewMemoryQueue()
+			_, err := Dao.PutLease(db.Lease{
+				ID:          "lease-1",
+				AccountID:   "123456789012",
+				PrincipalID: "jdoe",
+				LeaseStatus: db.Active,
+			})
+			require.Nil(t, err)
+
+			err = endLeaseForReset(leaseEventsDao{Dao}, "123456789012")
+			require.Nil(t, err)
+
+			account, err := Dao.GetAccount("123456789012")
+			require.Nil(t, err)
+			require.Equal(t, db.NotReady, account.AccountStatus)
+		})
+
+		t.Run("should refuse to reset Orphaned accounts", func(t *testing.T) {
+			setup()
+			queue := common.NewMemoryQueue()
+			Queue = queue
+			require.Nil(t, Dao.PutAccount(db.Account{ID: "111111111111", AccountStatus: db.Orphaned}))
+
+			require.Equal(t, 409, resetRequest("111111111111", map[string]string{"force": "true"}).StatusCode)
+			require.Empty(t, receiveResets(queue))
+		})
+	})
+}
+
+// leaseEventsDao moves the account of a lease to NotReady as soon as the
+// lease becomes Inactive, as publish_lease_events does
+type leaseEventsDao struct {
+	db.DBer
+}
+
+func (dao leaseEventsDao) TransitionLeaseStatus(accountID string, principalID string, prevStatus db.LeaseStatus, nextStatus db.LeaseStatus, leaseStatusReason db.LeaseStatusReason) (*db.Lease, error) {
+	lease, err := dao.DBer.TransitionLeaseStatus(accountID, principalID, prevStatus, nextStatus, leaseStatusReason)
+	if err != nil {
+		return nil, err
+	}
+	if nextStatus == db.Inactive {
+		_, err = dao.DBer.TransitionAccountStatus(accountID, db.Leased, db.NotReady)
+	}
+	return lease, err
+}
@@ -0,0 +1,69 @@
+package accounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/resetreport"
+)
+
+// GetAccountResets - Returns a summary of each reset run for an account,
+// newest first. Resource lists are only included in GetAccountReset.
+func GetAccountResets(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	reports, err := ResetReports.GetReports(accountID)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Failed to get resets for account %s", accountID)
+		log.Printf("%s: %s", errorMessage, err)
+		WriteServerErrorWithResponse(w, errorMessage)
+		return
+	}
+
+	// Accounts which never existed have no resets.
+	// Deleted accounts keep their reset reports.
+	if len(reports) == 0 {
+		account, err := Dao.GetAccount(accountID)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Failed to get account %s", accountID)
+			log.Printf("%s: %s", errorMessage, err)
+			WriteServerErrorWithResponse(w, errorMessage)
+			return
+		}
+		if account == nil {
+			WriteNotFoundError(w)
+			return
+		}
+	}
+
+	summaries := []*resetreport.Report{}
+	for _, report := range reports {
+		summaries = append(summaries, report.Summary())
+	}
+	json.NewEncoder(w).Encode(response.CreateResetReportsResponse(summaries))
+}
+
+// GetAccountReset - Returns the report of a single reset run for an account
+func GetAccountReset(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+	runID := mux.Vars(r)["runId"]
+
+	report, err := ResetReports.GetReport(accountID, runID)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Failed to get reset %s for account %s", runID, accountID)
+		log.Printf("%s: %s", errorMessage, err)
+		WriteServerErrorWithResponse(w, errorMessage)
+		return
+	}
+	if report == nil {
+		WriteNotFoundError(w)
+		return
+	}
+
+	json.NewEncoder(w).Encode(response.CreateResetReportResponse(report))
+}
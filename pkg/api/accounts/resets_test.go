@@ -0,0 +1,96 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/resetreport"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAccountResets(t *testing.T) {
+	ResetReports = resetreport.NewMemory()
+	Dao = db.NewMemoryDB(7)
+	require.Nil(t, Dao.PutAccount(db.Account{ID: "123", AccountStatus: db.Ready}))
+	require.Nil(t, Dao.PutAccount(db.Account{ID: "789", AccountStatus: db.Ready}))
+	require.Nil(t, ResetReports.PutReport(&resetreport.Report{
+		AccountID: "123",
+		RunID:     "20191101T000000Z-aaaaaaaa",
+		Outcome:   resetreport.Failed,
+		Error:     "nuke failed",
+		Steps: []resetreport.StepResult{
+			{Name: "aws-nuke", Status: "Failed", ResourceCount: 1, Error: "nuke failed"},
+		},
+		ResourceCounts:  resetreport.ResourceCounts{Found: 2, Deleted: 1, Failed: 1},
+		ResourcesFound:  []string{"us-east-1 - EC2Instance - i-1", "us-east-1 - EC2Instance - i-2"},
+		ResourcesFailed: []string{"us-east-1 - EC2Instance - i-2"},
+	}))
+	require.Nil(t, ResetReports.PutReport(&resetreport.Report{
+		AccountID: "123",
+		RunID:     "20191102T000000Z-bbbbbbbb",
+		Outcome:   resetreport.Succeeded,
+	}))
+
+	t.Run("should list resets newest first, without resources", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/accounts/123/resets"}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, actualResponse.StatusCode)
+
+		var reports []*response.ResetReportResponse
+		require.Nil(t, json.Unmarshal([]byte(actualResponse.Body), &reports))
+		require.Len(t, reports, 2)
+		require.Equal(t, "20191102T000000Z-bbbbbbbb", reports[0].RunID)
+		require.Equal(t, "20191101T000000Z-aaaaaaaa", reports[1].RunID)
+		require.Equal(t, resetreport.Failed, reports[1].Outcome)
+		require.Equal(t, 2, reports[1].ResourceCounts.Found)
+		require.Empty(t, reports[1].ResourcesFound)
+	})
+
+	t.Run("should return an empty list for accounts without resets", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/accounts/789/resets"}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, actualResponse.StatusCode)
+		require.JSONEq(t, "[]", actualResponse.Body)
+	})
+
+	t.Run("should return 404 for unknown accounts", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/accounts/456/resets"}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 404, actualResponse.StatusCode)
+	})
+
+	t.Run("should return a single reset, with resources", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/accounts/123/resets/20191101T000000Z-aaaaaaaa"}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, actualResponse.StatusCode)
+
+		var report response.ResetReportResponse
+		require.Nil(t, json.Unmarshal([]byte(actualResponse.Body), &report))
+		require.Equal(t, "nuke failed", report.Error)
+		require.Equal(t, []string{"us-east-1 - EC2Instance - i-2"}, report.ResourcesFailed)
+		require.Equal(t, []*response.ResetStepResponse{
+			{Name: "aws-nuke", Status: "Failed", ResourceCount: 1, Error: "nuke failed"},
+		}, report.Steps)
+	})
+
+	t.Run("should return 404 for unknown resets", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/accounts/123/resets/missing"}
+
+		actualResponse, err := Handler(context.TODO(), mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 404, actualResponse.StatusCode)
+	})
+}
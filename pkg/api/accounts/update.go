@@ -1,4 +1,4 @@
-package main
+package accounts
 
 import (
 	"encoding/json"
@@ -26,6 +26,9 @@ type updateAccountRequest struct {
 	PrincipalRoleArn    *string                 `json:"principalRoleArn"`    // Assumed by principal users
 	PrincipalPolicyHash *string                 `json:"principalPolicyHash"` // The policy used by the PrincipalRoleArn
 	Metadata            *map[string]interface{} `json:"metadata"`
+	Pool                *string                 `json:"pool"`
+	Labels              *map[string]string      `json:"labels"`
+	NukeConfig          *db.NukeConfig          `json:"nukeConfig"`
 }
 
 func UpdateAccountByID(w http.ResponseWriter, r *http.Request) {
@@ -77,6 +80,31 @@ func UpdateAccountByID(w http.ResponseWriter, r *http.Request) {
 		fieldsToUpdate = append(fieldsToUpdate, "Metadata")
 		accountPartial.Metadata = *request.Metadata
 	}
+	if request.Pool != nil {
+		fieldsToUpdate = append(fieldsToUpdate, "Pool")
+		accountPartial.Pool = *request.Pool
+		if accountPartial.Pool == "" {
+			accountPartial.Pool = db.DefaultAccountPool
+		}
+	}
+	if request.Labels != nil {
+		fieldsToUpdate = append(fieldsToUpdate, "Labels")
+		accountPartial.Labels = *request.Labels
+	}
+	if request.NukeConfig != nil {
+		err = request.NukeConfig.Validate()
+		if err != nil {
+			WriteRequestValidationError(
+				w,
+				fmt.Sprintf("Unable to update account %s: "+
+					"invalid nukeConfig: %s",
+					accountID, err),
+			)
+			return
+		}
+		fieldsToUpdate = append(fieldsToUpdate, "NukeConfig")
+		accountPartial.NukeConfig = request.NukeConfig
+	}
 	if len(fieldsToUpdate) == 0 {
 		WriteRequestValidationError(
 			w,
@@ -88,7 +116,7 @@ func UpdateAccountByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update the DB record
-	acct, err := Dao.UpdateAccount(accountPartial, fieldsToUpdate)
+	acct, err := requestDao(r).UpdateAccount(accountPartial, fieldsToUpdate)
 	if err != nil {
 		// If the account doesn't exist, return a 404
 		if _, ok := err.(*db.NotFoundError); ok {
@@ -1,4 +1,4 @@
-package main
+package accounts
 
 import (
 	"context"
@@ -128,6 +128,98 @@ func TestUpdateAccountByID(t *testing.T) {
 		dbMock.AssertNumberOfCalls(t, "UpdateAccount", 1)
 	})
 
+	t.Run("should update pool and labels", func(t *testing.T) {
+		stubAllServices()
+		dbMock := dbStub()
+		Dao = dbMock
+
+		// Should update the pool and labels
+		util.ReplaceMock(&dbMock.Mock,
+			"UpdateAccount",
+			db.Account{
+				ID:     "123456789012",
+				Pool:   "sandbox",
+				Labels: map[string]string{"gpu": "true"},
+			},
+			[]string{"Pool", "Labels"},
+		).Return(&db.Account{}, nil)
+
+		// Call the controller
+		res, err := Handler(context.TODO(),
+			newUpdateRequest(t, "123456789012", map[string]interface{}{
+				"pool":   "sandbox",
+				"labels": map[string]string{"gpu": "true"},
+			}),
+		)
+		require.Nil(t, err)
+		require.Equal(t, 200, res.StatusCode)
+
+		// Check the dbmock was called
+		dbMock.AssertNumberOfCalls(t, "UpdateAccount", 1)
+	})
+
+	t.Run("should update nuke config", func(t *testing.T) {
+		stubAllServices()
+		dbMock := dbStub()
+		Dao = dbMock
+
+		nukeConfig := &db.NukeConfig{
+			Filters: map[string][]db.NukeFilter{
+				"S3Bucket": {{Type: "glob", Value: "s3://fixtures-*"}},
+			},
+			ExcludeResourceTypes: []string{"Route53HostedZone"},
+		}
+		util.ReplaceMock(&dbMock.Mock,
+			"UpdateAccount",
+			db.Account{
+				ID:         "123456789012",
+				NukeConfig: nukeConfig,
+			},
+			[]string{"NukeConfig"},
+		).Return(&db.Account{NukeConfig: nukeConfig}, nil)
+
+		// Call the controller
+		res, err := Handler(context.TODO(),
+			newUpdateRequest(t, "123456789012", map[string]interface{}{
+				"nukeConfig": map[string]interface{}{
+					"filters": map[string]interface{}{
+						"S3Bucket": []map[string]string{{"type": "glob", "value": "s3://fixtures-*"}},
+					},
+					"excludeResourceTypes": []string{"Route53HostedZone"},
+				},
+			}),
+		)
+		require.Nil(t, err)
+		require.Equal(t, 200, res.StatusCode)
+		require.Contains(t, res.Body, `"nukeConfig":{"filters":{"S3Bucket":[{"type":"glob","value":"s3://fixtures-*"}]}`)
+
+		// Check the dbmock was called
+		dbMock.AssertNumberOfCalls(t, "UpdateAccount", 1)
+	})
+
+	t.Run("should reject invalid nuke config", func(t *testing.T) {
+		stubAllServices()
+		dbMock := dbStub()
+		Dao = dbMock
+
+		// Call the controller
+		res, err := Handler(context.TODO(),
+			newUpdateRequest(t, "123456789012", map[string]interface{}{
+				"nukeConfig": map[string]interface{}{
+					"filters": map[string]interface{}{
+						"S3Bucket": []map[string]string{{"type": "prefix", "value": "s3://"}},
+					},
+				},
+			}),
+		)
+		require.Nil(t, err)
+		require.Equal(t, 400, res.StatusCode)
+		require.Contains(t, res.Body, "invalid nukeConfig")
+
+		// Should not update the account
+		dbMock.AssertNumberOfCalls(t, "UpdateAccount", 0)
+	})
+
 	t.Run("should allow you to pass in a full account object, without updating non-updatable fields", func(t *testing.T) {
 		stubAllServices()
 		dbMock := dbStub()
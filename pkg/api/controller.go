@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"path"
 	"strings"
 
 	"github.com/Optum/dce/pkg/api/response"
@@ -26,7 +27,13 @@ type Router struct {
 	DeleteController Controller
 	GetController    Controller
 	CreateController Controller
-	UserDetails      UserDetails
+	// ActionControllers handle `POST {ResourceName}/{id}/{action}` requests,
+	// keyed by action name
+	ActionControllers map[string]Controller
+	// SubresourceControllers handle `GET {ResourceName}/{id}/{subresource}` requests,
+	// keyed by subresource name
+	SubresourceControllers map[string]Controller
+	UserDetails            UserDetails
 }
 
 // Route - provides a router for the given resource
@@ -42,6 +49,9 @@ func (router *Router) Route(ctx context.Context, req *events.APIGatewayProxyRequ
 	switch {
 	case req.HTTPMethod == http.MethodGet && strings.HasSuffix(req.Path, router.ResourceName):
 		res, err = router.ListController.Call(ctxWithUser, req)
+	case req.HTTPMethod == http.MethodGet && path.Dir(path.Dir(req.Path)) == router.ResourceName &&
+		router.SubresourceControllers[path.Base(req.Path)] != nil:
+		res, err = router.SubresourceControllers[path.Base(req.Path)].Call(ctxWithUser, req)
 	case req.HTTPMethod == http.MethodGet && strings.Compare(string(req.Path[0:strLen+1]), fmt.Sprintf("%s/", router.ResourceName)) == 0:
 		res, err = router.GetController.Call(ctxWithUser, req)
 	case req.HTTPMethod == http.MethodDelete &&
@@ -49,6 +59,9 @@ func (router *Router) Route(ctx context.Context, req *events.APIGatewayProxyRequ
 		res, err = router.DeleteController.Call(ctxWithUser, req)
 	case req.HTTPMethod == http.MethodPost && strings.HasSuffix(req.Path, router.ResourceName):
 		res, err = router.CreateController.Call(ctxWithUser, req)
+	case req.HTTPMethod == http.MethodPost && strings.HasPrefix(req.Path, fmt.Sprintf("%s/", router.ResourceName)) &&
+		router.ActionControllers[path.Base(req.Path)] != nil:
+		res, err = router.ActionControllers[path.Base(req.Path)].Call(ctxWithUser, req)
 	default:
 		errMsg := fmt.Sprintf("Resource %s not found for method %s", req.Path, req.HTTPMethod)
 		log.Printf(errMsg)
@@ -14,6 +14,8 @@ func TestRouter_Route(t *testing.T) {
 	mockGetController := &mockController.Controller{}
 	mockDeleteController := &mockController.Controller{}
 	mockCreateController := &mockController.Controller{}
+	mockExtendController := &mockController.Controller{}
+	mockHistoryController := &mockController.Controller{}
 
 	ctx := context.Background()
 
@@ -32,6 +34,16 @@ func TestRouter_Route(t *testing.T) {
 		HTTPMethod: "POST",
 	}
 
+	extendLeaseRequest := &events.APIGatewayProxyRequest{
+		Path:       "/leases/34232342/extend",
+		HTTPMethod: "POST",
+	}
+
+	leaseHistoryRequest := &events.APIGatewayProxyRequest{
+		Path:       "/leases/34232342/history",
+		HTTPMethod: "GET",
+	}
+
 	deleteLeaseRequest := &events.APIGatewayProxyRequest{
 		Path:       "/leases/",
 		HTTPMethod: "DELETE",
@@ -43,6 +55,12 @@ func TestRouter_Route(t *testing.T) {
 		ListController:   mockListController,
 		GetController:    mockGetController,
 		DeleteController: mockDeleteController,
+		ActionControllers: map[string]api.Controller{
+			"extend": mockExtendController,
+		},
+		SubresourceControllers: map[string]api.Controller{
+			"history": mockHistoryController,
+		},
 	}
 
 	tests := []struct {
@@ -89,6 +107,24 @@ func TestRouter_Route(t *testing.T) {
 				Role: api.AdminGroupName,
 			},
 		},
+		{
+			name:               "POST (extend) HTTP...",
+			request:            *extendLeaseRequest,
+			ctx:                ctx,
+			expectedController: mockExtendController,
+			user: api.User{
+				Role: api.AdminGroupName,
+			},
+		},
+		{
+			name:               "GET (history) HTTP...",
+			request:            *leaseHistoryRequest,
+			ctx:                ctx,
+			expectedController: mockHistoryController,
+			user: api.User{
+				Role: api.AdminGroupName,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1,4 +1,4 @@
-package main
+package leaseauth
 
 import (
 	"context"
@@ -46,8 +46,10 @@ func (controller CreateController) Call(ctx context.Context, req *events.APIGate
 		log.Printf("Error Getting Lease (%s) by Id: %s", leaseID, err)
 		return response.NotFoundError(), nil
 	}
-	// Don't return any lease information if the lease isn't active
-	if lease.LeaseStatus != db.Active {
+	// Don't return any lease information if the lease isn't active.
+	// Principals may still login to accounts with a frozen lease,
+	// which are restricted by the frozen principal policy.
+	if lease.LeaseStatus != db.Active && lease.LeaseStatus != db.Frozen {
 		log.Printf("Lease (%s) isn't in an active state", leaseID)
 		return response.UnauthorizedError(), nil
 	}
@@ -1,4 +1,4 @@
-package main
+package leaseauth
 
 import (
 	"context"
@@ -133,6 +133,27 @@ func TestGetLeaseAuth(t *testing.T) {
 				userRole:         api.AdminGroupName,
 				principalRoleArn: "arn:aws:iam::Account123:role/Principal",
 			},
+			{
+				// Frozen leases may login, so the account is looked up
+				name:            "LeaseFrozen",
+				leaseID:         "Lease123",
+				getLeaseByIDErr: nil,
+				getAccountErr:   nil,
+				expectedResponse: &events.APIGatewayProxyResponse{
+					StatusCode: 500,
+					Headers: map[string]string{
+						"Content-Type":                "application/json",
+						"Access-Control-Allow-Origin": "*",
+					},
+					Body: `{"error":{"code":"ServerError","message":"Account  could not be found"}}`,
+				},
+				assumeRoleErr:    nil,
+				leaseStatus:      db.Frozen,
+				expectedErr:      nil,
+				userName:         "TestUser",
+				userRole:         api.AdminGroupName,
+				principalRoleArn: "arn:aws:iam::Account123:role/Principal",
+			},
 			{
 				name:            "GetAccountError",
 				leaseID:         "Lease987",
@@ -0,0 +1,27 @@
+// Package leaseauth handles API requests for the `/leases/{id}/auth` endpoint
+package leaseauth
+
+import (
+	"github.com/Optum/dce/pkg/api"
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/db"
+)
+
+const (
+	consoleURL    = "https://console.aws.amazon.com/"
+	federationURL = "https://signin.aws.amazon.com/federation"
+)
+
+// NewRouter creates a router for the `/leases/{id}/auth` endpoint
+func NewRouter(dao db.DBer, tokenSvc common.TokenService, userDetailer api.UserDetailer) *api.Router {
+	return &api.Router{
+		ResourceName: "/auth",
+		CreateController: CreateController{
+			Dao:           dao,
+			TokenService:  tokenSvc,
+			FederationURL: federationURL,
+			ConsoleURL:    consoleURL,
+			UserDetailer:  userDetailer,
+		},
+	}
+}
@@ -0,0 +1,71 @@
+package leases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+
+	"github.com/Optum/dce/pkg/api"
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// CancelController is responsible for handling API events for
+// cancelling pending leases.
+type CancelController struct {
+	Dao db.DBer
+}
+
+// Call - Function to remove a Pending lease from the waitlist.
+//
+// Handles requests for `POST /leases/{id}/cancel`
+func (c CancelController) Call(ctx context.Context, req *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Record changes to the lease under the user making the request
+	c.Dao = history.WithActor(c.Dao, api.RequestActor(ctx, req.RequestContext))
+
+	leaseID := path.Base(path.Dir(req.Path))
+
+	// Lookup the lease
+	lease, err := c.Dao.GetLeaseByID(leaseID)
+	if err != nil {
+		log.Printf("Error Getting Lease for Id %s: %s", leaseID, err)
+		return response.CreateAPIErrorResponse(http.StatusInternalServerError,
+			response.CreateErrorResponse("ServerError",
+				fmt.Sprintf("Failed Get on Lease %s",
+					leaseID))), nil
+	}
+	if lease == nil {
+		return response.NotFoundError(), nil
+	}
+
+	// Users may only cancel their own leases
+	user, ok := ctx.Value(api.DceCtxKey).(api.User)
+	if ok && user.Role != api.AdminGroupName && lease.PrincipalID != user.Username {
+		log.Printf("User (%s) doesn't have access to lease %s", user.Username, leaseID)
+		return response.NotFoundError(), nil
+	}
+
+	if lease.LeaseStatus != db.Pending {
+		return response.RequestValidationError(
+			fmt.Sprintf("Unable to cancel lease %s: lease is not pending", leaseID)), nil
+	}
+
+	log.Printf("Cancelling pending lease %s for %s", leaseID, lease.PrincipalID)
+	cancelledLease, err := c.Dao.TransitionLeaseStatus(lease.AccountID, lease.PrincipalID,
+		db.Pending, db.Inactive, db.LeaseCancelled)
+	if err != nil {
+		if _, ok := err.(*db.StatusTransitionError); ok {
+			return response.ConflictError(
+				fmt.Sprintf("Unable to cancel lease %s: lease is no longer pending", leaseID)), nil
+		}
+		log.Printf("Failed to cancel lease %s: %s", leaseID, err)
+		return response.ServerError(), nil
+	}
+
+	leaseResponse := response.LeaseResponse(*cancelledLease)
+	return response.CreateJSONResponse(http.StatusOK, leaseResponse), nil
+}
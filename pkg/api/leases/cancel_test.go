@@ -0,0 +1,56 @@
+package leases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelController_Call(t *testing.T) {
+
+	newDB := func(t *testing.T, status db.LeaseStatus) *db.MemoryDB {
+		dbSvc := db.NewMemoryDB(7)
+		_, err := dbSvc.UpsertLease(db.Lease{
+			ID:               "lease-1",
+			AccountID:        db.PendingLeaseAccountID,
+			PrincipalID:      "jdoe123",
+			LeaseStatus:      status,
+			ExpiresOn:        time.Now().Add(time.Hour).Unix(),
+			PendingExpiresOn: time.Now().Add(time.Hour).Unix(),
+		})
+		require.Nil(t, err)
+		return dbSvc
+	}
+	cancelRequest := &events.APIGatewayProxyRequest{
+		Path:       "/leases/lease-1/cancel",
+		HTTPMethod: "POST",
+	}
+
+	t.Run("should cancel a pending lease", func(t *testing.T) {
+		dbSvc := newDB(t, db.Pending)
+		controller := CancelController{Dao: dbSvc}
+
+		res, err := controller.Call(context.TODO(), cancelRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, res.StatusCode)
+
+		lease, err := dbSvc.GetLeaseByID("lease-1")
+		require.Nil(t, err)
+		require.Equal(t, db.Inactive, lease.LeaseStatus)
+		require.Equal(t, db.LeaseCancelled, lease.LeaseStatusReason)
+	})
+
+	t.Run("should not cancel leases which are not pending", func(t *testing.T) {
+		dbSvc := newDB(t, db.Active)
+		controller := CancelController{Dao: dbSvc}
+
+		res, err := controller.Call(context.TODO(), cancelRequest)
+		require.Nil(t, err)
+		require.Equal(t, response.RequestValidationError("Unable to cancel lease lease-1: lease is not pending"), res)
+	})
+}
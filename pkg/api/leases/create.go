@@ -1,4 +1,4 @@
-package main
+package leases
 
 import (
 	"context"
@@ -10,10 +10,15 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Optum/dce/pkg/api"
 	"github.com/Optum/dce/pkg/api/response"
 	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/currency"
 	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/policyprofile"
 	"github.com/Optum/dce/pkg/usage"
+	"github.com/Optum/dce/pkg/waitlist"
 	"github.com/aws/aws-lambda-go/events"
 )
 
@@ -28,6 +33,13 @@ type CreateController struct {
 	MaxLeaseBudgetAmount     *float64
 	MaxLeasePeriod           *int
 	DefaultLeaseLengthInDays int
+	// Time, in seconds, that a Pending lease
+	// may wait for an account to become available
+	PendingLeaseTimeout int
+	// Policy profiles, and approved statements, which may be
+	// added to the principal policy of the lease
+	PolicyProfiles *policyprofile.Config
+	Rates          currency.RateProvider
 }
 
 type createLeaseRequest struct {
@@ -37,6 +49,27 @@ type createLeaseRequest struct {
 	BudgetNotificationEmails []string               `json:"budgetNotificationEmails"`
 	ExpiresOn                int64                  `json:"expiresOn"`
 	Metadata                 map[string]interface{} `json:"metadata"`
+	// If no accounts are available, add the lease to the waitlist
+	// rather than failing
+	Waitlist bool `json:"waitlist"`
+	// Only lease accounts from this pool
+	Pool string `json:"pool"`
+	// Only lease accounts which have all of these labels
+	Labels map[string]string `json:"labels"`
+	// Add the statements of this policy profile to the principal policy
+	PolicyProfile string `json:"policyProfile"`
+	// Add these statements to the principal policy.
+	// Statements must be approved by an admin, unless an admin creates the lease.
+	PolicyStatements []db.PolicyStatement `json:"policyStatements"`
+}
+
+// accountSelector returns the selector for the accounts
+// which may be leased for this request
+func (r *createLeaseRequest) accountSelector() db.AccountSelector {
+	return db.AccountSelector{
+		Pool:   r.Pool,
+		Labels: r.Labels,
+	}
 }
 
 // Call - Function to validate the account request to add into the pool and
@@ -46,6 +79,8 @@ type createLeaseRequest struct {
 // returned is nil. It's only not nil if we get an error that we don't know
 // what to do with, in which case the calling router will handle it.
 func (c CreateController) Call(ctx context.Context, req *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Record changes to the lease under the user making the request
+	c.Dao = history.WithActor(c.Dao, api.RequestActor(ctx, req.RequestContext))
 
 	// Extract the Body from the Request
 	requestBody, isValid, validationErrorMessage, err := validateLeaseRequest(c, req)
@@ -58,10 +93,18 @@ func (c CreateController) Call(ctx context.Context, req *events.APIGatewayProxyR
 		return response.RequestValidationError(validationErrorMessage), nil
 	}
 
+	// Only admins may add statements which are not approved
+	user, ok := ctx.Value(api.DceCtxKey).(api.User)
+	isAdmin := !ok || user.Role == api.AdminGroupName
+	err = c.policyProfiles().ValidateLease(requestBody.PolicyProfile, requestBody.PolicyStatements, isAdmin)
+	if err != nil {
+		return response.RequestValidationError(err.Error()), nil
+	}
+
 	principalID := requestBody.PrincipalID
 	log.Printf("Creating lease for Principal %s", principalID)
 
-	// Fail if the Principal already has an active lease
+	// Fail if the Principal already has an active or pending lease
 	principalLeases, err := c.Dao.FindLeasesByPrincipal(requestBody.PrincipalID)
 	if err != nil {
 		log.Printf("Failed to list leases for principal %s: %s", requestBody.PrincipalID, err)
@@ -69,22 +112,31 @@ func (c CreateController) Call(ctx context.Context, req *events.APIGatewayProxyR
 	}
 	if principalLeases != nil {
 		for _, lease := range principalLeases {
-			if lease.LeaseStatus == db.Active {
+			if lease.LeaseStatus == db.Active || lease.LeaseStatus == db.Frozen {
 				msg := fmt.Sprintf("Principal already has an active lease for account %s", lease.AccountID)
 				return response.ConflictError(msg), nil
 			}
+			if lease.LeaseStatus == db.Pending && lease.PendingExpiresOn > time.Now().Unix() {
+				msg := fmt.Sprintf("Principal already has a pending lease %s", lease.ID)
+				return response.ConflictError(msg), nil
+			}
 		}
 	}
 
-	// Get the First Ready Account
+	// Get the First Ready Account matching the requested pool and labels
 	// Exit if there's an error or no ready accounts
-	account, err := c.Dao.GetReadyAccount()
+	account, err := c.findReadyAccount(requestBody.accountSelector())
 	if err != nil {
 		log.Printf("Failed to Check Ready Accounts: %s", err)
 		return response.ServerErrorWithResponse(
 			fmt.Sprintf("Failed to find a Ready Account: %s", err)), nil
+	} else if account == nil && requestBody.Waitlist {
+		return c.addToWaitlist(requestBody)
 	} else if account == nil {
 		errStr := "No Available accounts at this moment"
+		if !requestBody.accountSelector().IsEmpty() {
+			errStr = "No Available accounts matching the requested pool and labels at this moment"
+		}
 		log.Printf(errStr)
 		return response.ServiceUnavailableError(errStr), nil
 	}
@@ -107,6 +159,10 @@ func (c CreateController) Call(ctx context.Context, req *events.APIGatewayProxyR
 		LeaseStatusModifiedOn:    now.Unix(),
 		ExpiresOn:                requestBody.ExpiresOn,
 		Metadata:                 requestBody.Metadata,
+		AccountPool:              requestBody.Pool,
+		AccountLabels:            requestBody.Labels,
+		PolicyProfile:            requestBody.PolicyProfile,
+		PolicyStatements:         requestBody.PolicyStatements,
 	})
 	if err != nil {
 		log.Printf("Failed to create lease DB record for %s @ %s: %s",
@@ -159,6 +215,64 @@ func (c CreateController) Call(ctx context.Context, req *events.APIGatewayProxyR
 	}, nil
 }
 
+// policyProfiles returns the configured policy profiles,
+// or an empty config if none are configured
+func (c CreateController) policyProfiles() *policyprofile.Config {
+	if c.PolicyProfiles == nil {
+		return &policyprofile.Config{}
+	}
+	return c.PolicyProfiles
+}
+
+// findReadyAccount returns the first Ready account which matches the selector,
+// or nil if there are no matching Ready accounts
+func (c CreateController) findReadyAccount(selector db.AccountSelector) (*db.Account, error) {
+	if selector.IsEmpty() {
+		return c.Dao.GetReadyAccount()
+	}
+
+	accounts, err := c.Dao.FindAccountsByStatus(db.Ready)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		if selector.Matches(account) {
+			return account, nil
+		}
+	}
+	return nil, nil
+}
+
+// addToWaitlist creates a Pending lease, to be fulfilled
+// once an account becomes available
+func (c CreateController) addToWaitlist(requestBody *createLeaseRequest) (events.APIGatewayProxyResponse, error) {
+	log.Printf("No accounts available. Adding lease for principal %s to the waitlist", requestBody.PrincipalID)
+
+	now := time.Now()
+	lease, err := waitlist.NewPendingLease(c.Dao, db.Lease{
+		PrincipalID:              requestBody.PrincipalID,
+		ID:                       uuid.New().String(),
+		BudgetAmount:             requestBody.BudgetAmount,
+		BudgetCurrency:           requestBody.BudgetCurrency,
+		BudgetNotificationEmails: requestBody.BudgetNotificationEmails,
+		CreatedOn:                now.Unix(),
+		LastModifiedOn:           now.Unix(),
+		LeaseStatusModifiedOn:    now.Unix(),
+		ExpiresOn:                requestBody.ExpiresOn,
+		Metadata:                 requestBody.Metadata,
+		AccountPool:              requestBody.Pool,
+		AccountLabels:            requestBody.Labels,
+		PolicyProfile:            requestBody.PolicyProfile,
+		PolicyStatements:         requestBody.PolicyStatements,
+	}, time.Duration(c.PendingLeaseTimeout)*time.Second)
+	if err != nil {
+		log.Print(err.Error())
+		return response.ServerError(), nil
+	}
+
+	return response.CreateJSONResponse(http.StatusAccepted, response.CreateLeaseResponse(lease)), nil
+}
+
 // publishLease is a helper function to create and publish an lease
 // structured message to an SNS Topic
 func publishLease(snsSvc common.Notificationer,
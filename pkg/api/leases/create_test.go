@@ -1,4 +1,4 @@
-package main
+package leases
 
 import (
 	"context"
@@ -17,11 +17,14 @@ import (
 
 	"github.com/stretchr/testify/mock"
 
+	"github.com/Optum/dce/pkg/api"
 	"github.com/Optum/dce/pkg/api/response"
 	"github.com/Optum/dce/pkg/common"
 	commonMock "github.com/Optum/dce/pkg/common/mocks"
+	"github.com/Optum/dce/pkg/currency"
 	"github.com/Optum/dce/pkg/db"
 	mockDB "github.com/Optum/dce/pkg/db/mocks"
+	"github.com/Optum/dce/pkg/policyprofile"
 	mockUsage "github.com/Optum/dce/pkg/usage/mocks"
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -213,6 +216,166 @@ func TestCreateController_Call(t *testing.T) {
 		)
 	})
 
+	t.Run("should fail if the principal already has a pending lease", func(t *testing.T) {
+		dbMock := stubDb()
+		util.ReplaceMock(&dbMock.Mock, "FindLeasesByPrincipal", "jdoe123").
+			Return([]*db.Lease{{
+				ID:               "pending-lease",
+				AccountID:        db.PendingLeaseAccountID,
+				LeaseStatus:      db.Pending,
+				PendingExpiresOn: time.Now().Add(time.Hour).Unix(),
+			}}, nil)
+
+		controller := stubCreateController()
+		controller.Dao = dbMock
+
+		res, err := controller.Call(context.TODO(), apiGatewayRequest(t, map[string]interface{}{
+			"principalId":    "jdoe123",
+			"budgetAmount":   100,
+			"budgetCurrency": "USD",
+			"waitlist":       true,
+		}))
+		require.Nil(t, err)
+		require.Equal(t,
+			response.ConflictError("Principal already has a pending lease pending-lease"),
+			res,
+		)
+	})
+
+	t.Run("should add the lease to the waitlist, if no accounts are available", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		controller := stubCreateController()
+		controller.Dao = dbSvc
+		controller.PendingLeaseTimeout = 3600
+
+		// Another principal is already waiting
+		_, err := dbSvc.UpsertLease(db.Lease{
+			ID:               "other-lease",
+			AccountID:        db.PendingLeaseAccountID,
+			PrincipalID:      "other",
+			LeaseStatus:      db.Pending,
+			CreatedOn:        time.Now().Add(-time.Minute).Unix(),
+			ExpiresOn:        time.Now().Add(time.Hour).Unix(),
+			PendingExpiresOn: time.Now().Add(time.Hour).Unix(),
+		})
+		require.Nil(t, err)
+
+		res, err := controller.Call(context.TODO(), apiGatewayRequest(t, map[string]interface{}{
+			"principalId":    "jdoe123",
+			"budgetAmount":   100,
+			"budgetCurrency": "USD",
+			"waitlist":       true,
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 202, res.StatusCode)
+
+		resJSON := unmarshal(t, res.Body)
+		require.Equal(t, "Pending", resJSON["leaseStatus"])
+		require.Equal(t, float64(2), resJSON["queuePosition"])
+		require.InDelta(t, time.Now().Add(time.Hour).Unix(), resJSON["pendingExpiresOn"], 2)
+
+		leases, err := dbSvc.FindLeasesByStatus(db.Pending)
+		require.Nil(t, err)
+		require.Len(t, leases, 2)
+	})
+
+	t.Run("should return a 503 if no accounts are available, and waitlist is not requested", func(t *testing.T) {
+		dbMock := stubDb()
+		util.ReplaceMock(&dbMock.Mock, "GetReadyAccount").Return(nil, nil)
+		controller := stubCreateController()
+		controller.Dao = dbMock
+
+		res, err := controller.Call(context.TODO(), apiGatewayRequest(t, map[string]interface{}{
+			"principalId":    "jdoe123",
+			"budgetAmount":   100,
+			"budgetCurrency": "USD",
+		}))
+		require.Nil(t, err)
+		require.Equal(t, response.ServiceUnavailableError("No Available accounts at this moment"), res)
+		dbMock.AssertNotCalled(t, "UpsertLease", mock.Anything)
+	})
+
+	t.Run("should only lease accounts matching the requested pool and labels", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		controller := stubCreateController()
+		controller.Dao = dbSvc
+		for _, account := range []db.Account{
+			{ID: "1", AccountStatus: db.Ready, Pool: "default", Labels: map[string]string{"gpu": "true"}},
+			{ID: "2", AccountStatus: db.Ready, Pool: "sandbox", Labels: map[string]string{"gpu": "false"}},
+			{ID: "3", AccountStatus: db.Ready, Pool: "sandbox", Labels: map[string]string{"gpu": "true"}},
+		} {
+			require.Nil(t, dbSvc.PutAccount(account))
+		}
+
+		res, err := controller.Call(context.TODO(), apiGatewayRequest(t, map[string]interface{}{
+			"principalId":    "jdoe123",
+			"budgetAmount":   100,
+			"budgetCurrency": "USD",
+			"pool":           "sandbox",
+			"labels":         map[string]string{"gpu": "true"},
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 201, res.StatusCode)
+
+		resJSON := unmarshal(t, res.Body)
+		require.Equal(t, "3", resJSON["accountId"])
+		require.Equal(t, "sandbox", resJSON["accountPool"])
+
+		account, err := dbSvc.GetAccount("3")
+		require.Nil(t, err)
+		require.Equal(t, db.Leased, account.AccountStatus)
+
+		// No other sandbox GPU accounts are available
+		res, err = controller.Call(context.TODO(), apiGatewayRequest(t, map[string]interface{}{
+			"principalId":    "other",
+			"budgetAmount":   100,
+			"budgetCurrency": "USD",
+			"pool":           "sandbox",
+			"labels":         map[string]string{"gpu": "true"},
+		}))
+		require.Nil(t, err)
+		require.Equal(t, response.ServiceUnavailableError(
+			"No Available accounts matching the requested pool and labels at this moment"), res)
+	})
+
+	t.Run("should add policy profiles and approved statements to leases", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		controller := stubCreateController()
+		controller.Dao = dbSvc
+		controller.PolicyProfiles = &policyprofile.Config{
+			Profiles: map[string]*policyprofile.Profile{
+				"analytics": {Statements: []db.PolicyStatement{{"Effect": "Allow", "Action": "athena:*", "Resource": "*"}}},
+			},
+			ApprovedStatements: []db.PolicyStatement{{"Effect": "Allow", "Action": "glue:*", "Resource": "*"}},
+		}
+		require.Nil(t, dbSvc.PutAccount(db.Account{ID: "1", AccountStatus: db.Ready}))
+		userCtx := context.WithValue(context.TODO(), api.DceCtxKey, api.User{Username: "jdoe123", Role: api.UserGroupName})
+
+		for _, body := range []map[string]interface{}{
+			{"policyProfile": "unknown"},
+			{"policyStatements": []map[string]interface{}{{"Effect": "Allow", "Action": "organizations:*", "Resource": "*"}}},
+		} {
+			body["principalId"] = "jdoe123"
+			res, err := controller.Call(userCtx, apiGatewayRequest(t, body))
+			require.Nil(t, err)
+			require.Equal(t, 400, res.StatusCode, body)
+		}
+
+		res, err := controller.Call(userCtx, apiGatewayRequest(t, map[string]interface{}{
+			"principalId":      "jdoe123",
+			"policyProfile":    "analytics",
+			"policyStatements": []map[string]interface{}{{"Effect": "Allow", "Action": "glue:*", "Resource": "*"}},
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 201, res.StatusCode)
+
+		leases, err := dbSvc.FindLeasesByAccount("1")
+		require.Nil(t, err)
+		require.Len(t, leases, 1)
+		require.Equal(t, "analytics", leases[0].PolicyProfile)
+		require.Equal(t, []db.PolicyStatement{{"Effect": "Allow", "Action": "glue:*", "Resource": "*"}}, leases[0].PolicyStatements)
+	})
+
 	t.Run("should mark the account.Status=Leased", func(t *testing.T) {
 		// Setup the controller
 		dbMock := stubDb()
@@ -443,6 +606,75 @@ func TestCreateController_Call(t *testing.T) {
 		}
 	})
 
+	t.Run("should default the budget currency to USD", func(t *testing.T) {
+		dbMock := stubDb()
+		controller := stubCreateController()
+		controller.Dao = dbMock
+
+		util.ReplaceMock(&dbMock.Mock, "UpsertLease",
+			mock.MatchedBy(func(lease db.Lease) bool {
+				return lease.BudgetCurrency == "USD"
+			}),
+		).Return(func(lease db.Lease) *db.Lease {
+			return &lease
+		}, nil)
+
+		res, err := controller.Call(context.TODO(), apiGatewayRequest(t, map[string]interface{}{
+			"principalId":  "pid",
+			"budgetAmount": 100,
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 201, res.StatusCode)
+		dbMock.AssertExpectations(t)
+	})
+
+	t.Run("should check budgets in other currencies against the max lease budget, in USD", func(t *testing.T) {
+		controller := stubCreateController()
+		controller.Rates = &currency.StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.8}}
+
+		// 800 EUR is 1000 USD
+		res, err := controller.Call(context.TODO(), apiGatewayRequest(t, map[string]interface{}{
+			"principalId":    "pid",
+			"budgetAmount":   800,
+			"budgetCurrency": "EUR",
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 201, res.StatusCode)
+		require.Equal(t, "EUR", unmarshal(t, res.Body)["budgetCurrency"])
+
+		// 900 EUR is 1125 USD
+		res, err = controller.Call(context.TODO(), apiGatewayRequest(t, map[string]interface{}{
+			"principalId":    "pid",
+			"budgetAmount":   900,
+			"budgetCurrency": "EUR",
+		}))
+		require.Nil(t, err)
+		require.Equal(t, response.RequestValidationError(
+			"Requested lease has a budget amount of 900.000000 EUR (1125.000000 USD), which is greater than max lease budget amount of 1000.000000",
+		), res)
+	})
+
+	t.Run("should reject unsupported budget currencies", func(t *testing.T) {
+		controller := stubCreateController()
+		controller.Rates = &currency.StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.8}}
+
+		res, err := controller.Call(context.TODO(), apiGatewayRequest(t, map[string]interface{}{
+			"principalId":    "pid",
+			"budgetAmount":   100,
+			"budgetCurrency": "JPY",
+		}))
+		require.Nil(t, err)
+		require.Equal(t, response.RequestValidationError("Unsupported currency JPY: No exchange rate for JPY"), res)
+
+		res, err = controller.Call(context.TODO(), apiGatewayRequest(t, map[string]interface{}{
+			"principalId":    "pid",
+			"budgetAmount":   100,
+			"budgetCurrency": "eur",
+		}))
+		require.Nil(t, err)
+		require.Equal(t, response.RequestValidationError(`Invalid currency "eur": must be a three letter ISO 4217 code, eg. USD`), res)
+	})
+
 	t.Run("should deactivate the lease if the account status update fails", func(t *testing.T) {
 		// Setup the controller
 		dbMock := stubDb()
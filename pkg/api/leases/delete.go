@@ -1,4 +1,4 @@
-package main
+package leases
 
 import (
 	"context"
@@ -7,11 +7,13 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/Optum/dce/pkg/api"
 	"github.com/Optum/dce/pkg/api/response"
 	"github.com/aws/aws-lambda-go/events"
 
 	"github.com/Optum/dce/pkg/common"
 	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
 )
 
 // requestBody is the structured object of the Request Called to the Router
@@ -29,6 +31,8 @@ type DeleteController struct {
 }
 
 func (c DeleteController) Call(ctx context.Context, req *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Record changes to the lease under the user making the request
+	c.Dao = history.WithActor(c.Dao, api.RequestActor(ctx, req.RequestContext))
 
 	requestBody := &deleteLeaseRequest{}
 
@@ -64,7 +68,7 @@ func (c DeleteController) Call(ctx context.Context, req *events.APIGatewayProxyR
 	}
 	if acct == nil {
 		return response.ClientBadRequestError(fmt.Sprintf("No active leases found for %s", principalID)), nil
-	} else if acct.LeaseStatus != db.Active {
+	} else if acct.LeaseStatus != db.Active && acct.LeaseStatus != db.Frozen {
 		errStr := fmt.Sprintf("Lease is not active for %s - %s",
 			principalID, accountID)
 		return response.ClientBadRequestError(errStr), nil
@@ -72,7 +76,7 @@ func (c DeleteController) Call(ctx context.Context, req *events.APIGatewayProxyR
 
 	// Transition the Lease Status
 	updatedLease, err := c.Dao.TransitionLeaseStatus(acct.AccountID, principalID,
-		db.Active, db.Inactive, db.LeaseDestroyed)
+		acct.LeaseStatus, db.Inactive, db.LeaseDestroyed)
 	if err != nil {
 		log.Printf("Error transitioning lease status: %s", err)
 		return response.ServerErrorWithResponse(fmt.Sprintf("Failed to destroy lease %s - %s", principalID, accountID)), nil
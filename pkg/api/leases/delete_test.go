@@ -1,4 +1,4 @@
-package main
+package leases
 
 import (
 	"context"
@@ -75,6 +75,13 @@ func TestDeleteController_Call(t *testing.T) {
 	mockSNS.On("PublishMessage", &leaseTopicARN, mock.Anything, true).Return(&messageID, nil)
 	successResponse := createSuccessDeleteResponse()
 
+	// Successful delete of a frozen lease
+	frozenDeleteArgs := &args{ctx: context.Background(), req: createDeleteRequest("34567", "123456789")}
+	mockDB.On("FindLeasesByPrincipal", "34567").Return([]*db.Lease{
+		{AccountID: "123456789", PrincipalID: "34567", LeaseStatus: db.Frozen},
+	}, nil)
+	mockDB.On("TransitionLeaseStatus", "123456789", "34567", db.Frozen, db.Inactive, db.LeaseDestroyed).Return(lease, nil)
+
 	testFields := &fields{
 		Dao: mockDB,
 		SNS: mockSNS,
@@ -91,6 +98,7 @@ func TestDeleteController_Call(t *testing.T) {
 		{name: "No matching accounts.", fields: *testFields, args: *noAccountsForLeaseArgs, want: noAccountsForLeaseResponse, wantErr: false},
 		{name: "No matching leases.", fields: *testFields, args: *noActiveAccountForLeaseArgs, want: noActiveAccountForLeaseResponse, wantErr: false},
 		{name: "Successful delete.", fields: *testFields, args: *successfulDeleteArgs, want: successResponse, wantErr: false},
+		{name: "Successful delete of a frozen lease.", fields: *testFields, args: *frozenDeleteArgs, want: successResponse, wantErr: false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -148,6 +156,17 @@ func createSuccessfulDeleteRequest() *events.APIGatewayProxyRequest {
 	}
 }
 
+func createDeleteRequest(principalID string, accountID string) *events.APIGatewayProxyRequest {
+	deleteLeaseRequest := &deleteLeaseRequest{
+		PrincipalID: principalID,
+		AccountID:   accountID,
+	}
+	requestBodyBytes, _ := json.Marshal(deleteLeaseRequest)
+	return &events.APIGatewayProxyRequest{
+		Body: string(requestBodyBytes),
+	}
+}
+
 func createNoAccountsForLeaseDBResponse() []*db.Lease {
 	leases := []*db.Lease{}
 	return leases
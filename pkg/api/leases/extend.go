@@ -0,0 +1,229 @@
+package leases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/Optum/dce/pkg/api"
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/currency"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/leaserule"
+	"github.com/Optum/dce/pkg/usage"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pkg/errors"
+)
+
+// ExtendController is responsible for handling API events for extending leases.
+type ExtendController struct {
+	Dao                   db.DBer
+	SNS                   common.Notificationer
+	LeaseExtendedTopicARN *string
+	UsageSvc              usage.Service
+	PrincipalBudgetAmount *float64
+	PrincipalBudgetPeriod *string
+	MaxLeaseBudgetAmount  *float64
+	MaxLeasePeriod        *int
+	Rates                 currency.RateProvider
+	// LeaseRules are checked against frozen leases before they are unfrozen.
+	// Defaults to leaserule.DefaultRules, if nil.
+	LeaseRules []*leaserule.Rule
+}
+
+type extendLeaseRequest struct {
+	ExpiresOn    int64   `json:"expiresOn"`
+	BudgetAmount float64 `json:"budgetAmount"`
+}
+
+// Call - Function to extend the expiry date and/or increase the budget
+// of an active lease, and publish the change to the lease-extended topic.
+// Frozen leases are unfrozen (made active) when extended,
+// if the lease rules would leave the extended lease active.
+//
+// Handles requests for `POST /leases/{id}/extend`
+func (c ExtendController) Call(ctx context.Context, req *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Record changes to the lease under the user making the request
+	c.Dao = history.WithActor(c.Dao, api.RequestActor(ctx, req.RequestContext))
+
+	leaseID := path.Base(path.Dir(req.Path))
+
+	// Parse the request body
+	requestBody := &extendLeaseRequest{}
+	err := json.Unmarshal([]byte(req.Body), requestBody)
+	if err != nil || (requestBody.ExpiresOn == 0 && requestBody.BudgetAmount == 0) {
+		return response.RequestValidationError("invalid request parameters"), nil
+	}
+
+	// Lookup the lease
+	lease, err := c.Dao.GetLeaseByID(leaseID)
+	if err != nil {
+		log.Printf("Error Getting Lease for Id %s: %s", leaseID, err)
+		return response.CreateAPIErrorResponse(http.StatusInternalServerError,
+			response.CreateErrorResponse("ServerError",
+				fmt.Sprintf("Failed Get on Lease %s",
+					leaseID))), nil
+	}
+	if lease == nil {
+		return response.NotFoundError(), nil
+	}
+
+	// Users may only extend their own leases
+	user, ok := ctx.Value(api.DceCtxKey).(api.User)
+	if ok && user.Role != api.AdminGroupName && lease.PrincipalID != user.Username {
+		log.Printf("User (%s) doesn't have access to lease %s", user.Username, leaseID)
+		return response.NotFoundError(), nil
+	}
+
+	if lease.LeaseStatus != db.Active && lease.LeaseStatus != db.Frozen {
+		return response.RequestValidationError(
+			fmt.Sprintf("Unable to extend lease %s: lease is not active", leaseID)), nil
+	}
+
+	// Leases may only be extended, not shortened
+	nextExpiresOn := lease.ExpiresOn
+	if requestBody.ExpiresOn != 0 {
+		if requestBody.ExpiresOn < lease.ExpiresOn || requestBody.ExpiresOn <= time.Now().Unix() {
+			validationErrStr := fmt.Sprintf("Requested lease has a desired expiry date less than the current expiry date: %d", requestBody.ExpiresOn)
+			return response.RequestValidationError(validationErrStr), nil
+		}
+		nextExpiresOn = requestBody.ExpiresOn
+	}
+	// Frozen leases may have expired, and must be extended to be unfrozen
+	if lease.LeaseStatus == db.Frozen && nextExpiresOn <= time.Now().Unix() {
+		return response.RequestValidationError(
+			fmt.Sprintf("Unable to extend lease %s: frozen lease has expired, and requires a later expiry date", leaseID)), nil
+	}
+	nextBudgetAmount := lease.BudgetAmount
+	if requestBody.BudgetAmount != 0 {
+		if requestBody.BudgetAmount < lease.BudgetAmount {
+			validationErrStr := fmt.Sprintf("Requested lease has a budget amount of %f, which is less than the current budget amount of %f", requestBody.BudgetAmount, lease.BudgetAmount)
+			return response.RequestValidationError(validationErrStr), nil
+		}
+		nextBudgetAmount = requestBody.BudgetAmount
+	}
+
+	// Re-run the lease budget and period validations against the extended lease.
+	// The max lease period is measured from when the lease was created,
+	// so that leases may not be extended indefinitely.
+	isValid, validationErrStr, err := validateLeaseLimits(leaseLimits{
+		UsageSvc:              c.UsageSvc,
+		PrincipalBudgetAmount: c.PrincipalBudgetAmount,
+		PrincipalBudgetPeriod: c.PrincipalBudgetPeriod,
+		MaxLeaseBudgetAmount:  c.MaxLeaseBudgetAmount,
+		MaxLeasePeriod:        c.MaxLeasePeriod,
+		Rates:                 c.Rates,
+	}, "extend", lease.PrincipalID, time.Unix(lease.CreatedOn, 0), nextExpiresOn, nextBudgetAmount, lease.BudgetCurrency)
+	if err != nil {
+		return response.ServerErrorWithResponse(err.Error()), nil
+	}
+	if !isValid {
+		return response.RequestValidationError(validationErrStr), nil
+	}
+
+	// Frozen leases are only unfrozen if the extension is enough
+	// for the lease rules to leave the lease active
+	if lease.LeaseStatus == db.Frozen {
+		rule, err := c.enforcedRule(lease, nextExpiresOn, nextBudgetAmount)
+		if err != nil {
+			log.Printf("Failed to check lease rules for lease %s: %s", leaseID, err)
+			return response.ServerError(), nil
+		}
+		if rule != nil {
+			return response.RequestValidationError(
+				fmt.Sprintf("Unable to extend lease %s: lease rule %s would %s the extended lease", leaseID, rule.Name, rule.Action)), nil
+		}
+	}
+
+	// Update (and unfreeze) the lease, failing if it was modified since we retrieved it
+	log.Printf("Extending %s lease %s for %s @ %s: expiresOn %d -> %d, budgetAmount %f -> %f",
+		lease.LeaseStatus, leaseID, lease.PrincipalID, lease.AccountID,
+		lease.ExpiresOn, nextExpiresOn, lease.BudgetAmount, nextBudgetAmount)
+	extendedLease, err := c.Dao.ExtendLease(lease.AccountID, lease.PrincipalID, lease.LeaseStatus,
+		lease.ExpiresOn, lease.BudgetAmount, nextExpiresOn, nextBudgetAmount)
+	if err != nil {
+		if _, ok := err.(*db.StatusTransitionError); ok {
+			return response.ConflictError(
+				fmt.Sprintf("Unable to extend lease %s: lease was modified by another request", leaseID)), nil
+		}
+		log.Printf("Failed to extend lease %s: %s", leaseID, err)
+		return response.ServerError(), nil
+	}
+
+	// Publish the extended lease to the topic
+	message, err := publishLease(c.SNS, extendedLease, c.LeaseExtendedTopicARN)
+	if err != nil {
+		log.Printf("Failed to publish lease-extended event for lease %s: %s", leaseID, err)
+		return response.ServerError(), nil
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Body:       *message,
+	}, nil
+}
+
+// enforcedRule evaluates the lease rules against the lease, as it would be once
+// extended and unfrozen. Returns the rule which would freeze or end the lease,
+// or nil if the lease would remain active.
+func (c ExtendController) enforcedRule(lease *db.Lease, nextExpiresOn int64, nextBudgetAmount float64) (*leaserule.Rule, error) {
+	currentTime := time.Now()
+	extendedLease := *lease
+	extendedLease.ExpiresOn = nextExpiresOn
+	extendedLease.BudgetAmount = nextBudgetAmount
+	extendedLease.LeaseStatus = db.Active
+	extendedLease.LeaseStatusModifiedOn = currentTime.Unix()
+
+	budgetCurrency := lease.BudgetCurrency
+	if budgetCurrency == "" {
+		budgetCurrency = currency.USD
+	}
+	state := &leaserule.State{
+		Now:                   currentTime.Unix(),
+		PrincipalBudgetAmount: *c.PrincipalBudgetAmount,
+		DailySpend:            []leaserule.DailySpend{},
+	}
+
+	// Lease spend is measured since the lease was created, in the budget currency of the lease
+	usageRecords, err := c.UsageSvc.GetUsageByDateRange(time.Unix(lease.CreatedOn, 0), currentTime)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to retrieve usage for lease %s", lease.ID)
+	}
+	for _, usageItem := range usageRecords {
+		if usageItem.PrincipalID == lease.PrincipalID && usageItem.AccountID == lease.AccountID {
+			amount, err := usageItem.AmountIn(budgetCurrency, c.Rates)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to convert usage")
+			}
+			state.LeaseSpend = state.LeaseSpend + amount
+			state.DailySpend = append(state.DailySpend, leaserule.DailySpend{StartDate: usageItem.StartDate, Amount: amount})
+		}
+	}
+
+	// Principal spend is measured for the current billing period, in USD
+	usageRecords, err = c.UsageSvc.GetUsageByDateRange(getBeginningOfCurrentBillingPeriod(*c.PrincipalBudgetPeriod), currentTime)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to retrieve usage for principal %s", lease.PrincipalID)
+	}
+	for _, usageItem := range usageRecords {
+		if usageItem.PrincipalID == lease.PrincipalID {
+			amount, err := usageItem.AmountIn(currency.USD, c.Rates)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to convert usage")
+			}
+			state.PrincipalSpend = state.PrincipalSpend + amount
+		}
+	}
+
+	rules := c.LeaseRules
+	if rules == nil {
+		rules = leaserule.DefaultRules()
+	}
+	return leaserule.Evaluate(rules, &extendedLease, state).Enforced, nil
+}
@@ -0,0 +1,394 @@
+package leases
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Optum/dce/pkg/api"
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/currency"
+	"github.com/Optum/dce/pkg/db"
+	mockDB "github.com/Optum/dce/pkg/db/mocks"
+	"github.com/Optum/dce/pkg/leaserule"
+	"github.com/Optum/dce/pkg/usage"
+	mockUsage "github.com/Optum/dce/pkg/usage/mocks"
+	util "github.com/Optum/dce/tests/testutils"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtendController_Call(t *testing.T) {
+	now := time.Now()
+	expiresOn := now.AddDate(0, 0, 2).Unix()
+	nextExpiresOn := now.AddDate(0, 0, 5).Unix()
+
+	t.Run("should extend the lease", func(t *testing.T) {
+		dbMock := stubExtendDb(expiresOn)
+		snsMock := snsStub()
+		controller := stubExtendController()
+		controller.Dao = dbMock
+		controller.SNS = snsMock
+
+		dbMock.On("ExtendLease", "123456789012", "jdoe123", db.Active, expiresOn, float64(100), nextExpiresOn, float64(200)).
+			Return(&db.Lease{
+				ID:           "lease-1",
+				AccountID:    "123456789012",
+				PrincipalID:  "jdoe123",
+				LeaseStatus:  db.Active,
+				BudgetAmount: 200,
+				ExpiresOn:    nextExpiresOn,
+			}, nil)
+
+		res, err := controller.Call(context.TODO(), extendRequest(t, "lease-1", map[string]interface{}{
+			"expiresOn":    nextExpiresOn,
+			"budgetAmount": 200,
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 200, res.StatusCode)
+
+		resLease := &response.LeaseResponse{}
+		require.Nil(t, json.Unmarshal([]byte(res.Body), resLease))
+		assert.Equal(t, nextExpiresOn, resLease.ExpiresOn)
+		assert.Equal(t, float64(200), resLease.BudgetAmount)
+
+		dbMock.AssertExpectations(t)
+		snsMock.AssertCalled(t, "PublishMessage", aws.String("lease-extended-topic"), mock.Anything, true)
+	})
+
+	t.Run("should keep the current values for omitted fields", func(t *testing.T) {
+		dbMock := stubExtendDb(expiresOn)
+		controller := stubExtendController()
+		controller.Dao = dbMock
+
+		dbMock.On("ExtendLease", "123456789012", "jdoe123", db.Active, expiresOn, float64(100), expiresOn, float64(300)).
+			Return(&db.Lease{}, nil)
+
+		res, err := controller.Call(context.TODO(), extendRequest(t, "lease-1", map[string]interface{}{
+			"budgetAmount": 300,
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 200, res.StatusCode)
+		dbMock.AssertExpectations(t)
+	})
+
+	t.Run("should validate the extended lease", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			reqBody        map[string]interface{}
+			leaseStatus    db.LeaseStatus
+			expiresOn      int64
+			budgetCurrency string
+			usage          []*usage.Usage
+			expectedRes    events.APIGatewayProxyResponse
+		}{
+			{
+				name:        "empty request",
+				reqBody:     map[string]interface{}{},
+				leaseStatus: db.Active,
+				expectedRes: response.RequestValidationError("invalid request parameters"),
+			},
+			{
+				name:        "inactive lease",
+				reqBody:     map[string]interface{}{"budgetAmount": 200},
+				leaseStatus: db.Inactive,
+				expectedRes: response.RequestValidationError("Unable to extend lease lease-1: lease is not active"),
+			},
+			{
+				name:        "expired frozen lease",
+				reqBody:     map[string]interface{}{"budgetAmount": 200},
+				leaseStatus: db.Frozen,
+				expiresOn:   now.Add(-time.Hour).Unix(),
+				expectedRes: response.RequestValidationError("Unable to extend lease lease-1: frozen lease has expired, and requires a later expiry date"),
+			},
+			{
+				name:        "shorter lease",
+				reqBody:     map[string]interface{}{"expiresOn": expiresOn - 1},
+				leaseStatus: db.Active,
+				expectedRes: response.RequestValidationError(fmt.Sprintf(
+					"Requested lease has a desired expiry date less than the current expiry date: %d", expiresOn-1)),
+			},
+			{
+				name:        "smaller budget",
+				reqBody:     map[string]interface{}{"budgetAmount": 50},
+				leaseStatus: db.Active,
+				expectedRes: response.RequestValidationError("Requested lease has a budget amount of 50.000000, which is less than the current budget amount of 100.000000"),
+			},
+			{
+				name:        "over max lease budget amount",
+				reqBody:     map[string]interface{}{"budgetAmount": 5000},
+				leaseStatus: db.Active,
+				expectedRes: response.RequestValidationError("Requested lease has a budget amount of 5000.000000, which is greater than max lease budget amount of 1000.000000"),
+			},
+			{
+				name:           "over max lease budget amount, in another currency",
+				reqBody:        map[string]interface{}{"budgetAmount": 900},
+				leaseStatus:    db.Active,
+				budgetCurrency: "EUR",
+				expectedRes:    response.RequestValidationError("Requested lease has a budget amount of 900.000000 EUR (1125.000000 USD), which is greater than max lease budget amount of 1000.000000"),
+			},
+			{
+				name:        "over principal budget",
+				reqBody:     map[string]interface{}{"budgetAmount": 200},
+				leaseStatus: db.Active,
+				usage: []*usage.Usage{
+					{PrincipalID: "jdoe123", CostAmount: 1500},
+				},
+				expectedRes: response.RequestValidationError("Unable to extend lease: User principal jdoe123 has already spent 1000.000000 of their principal budget"),
+			},
+			{
+				name:        "frozen lease still over budget",
+				reqBody:     map[string]interface{}{"expiresOn": nextExpiresOn},
+				leaseStatus: db.Frozen,
+				usage: []*usage.Usage{
+					{PrincipalID: "jdoe123", AccountID: "123456789012", CostAmount: 150},
+				},
+				expectedRes: response.RequestValidationError("Unable to extend lease lease-1: lease rule over-budget would end the extended lease"),
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if tt.expiresOn == 0 {
+					tt.expiresOn = expiresOn
+				}
+				dbMock := stubExtendDb(expiresOn)
+				util.ReplaceMock(&dbMock.Mock, "GetLeaseByID", "lease-1").
+					Return(&db.Lease{
+						ID:             "lease-1",
+						AccountID:      "123456789012",
+						PrincipalID:    "jdoe123",
+						LeaseStatus:    tt.leaseStatus,
+						BudgetAmount:   100,
+						BudgetCurrency: tt.budgetCurrency,
+						ExpiresOn:      tt.expiresOn,
+						CreatedOn:      now.Unix(),
+					}, nil)
+				usageMock := stubUsageService()
+				if tt.usage != nil {
+					util.ReplaceMock(&usageMock.Mock, "GetUsageByDateRange", mock.Anything, mock.Anything).
+						Return(tt.usage, nil)
+				}
+				controller := stubExtendController()
+				controller.Dao = dbMock
+				controller.UsageSvc = usageMock
+				controller.Rates = &currency.StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.8}}
+
+				res, err := controller.Call(context.TODO(), extendRequest(t, "lease-1", tt.reqBody))
+				require.Nil(t, err)
+				require.Equal(t, tt.expectedRes, res)
+				dbMock.AssertNotCalled(t, "ExtendLease",
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("should unfreeze frozen leases", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		_, err := dbSvc.UpsertLease(db.Lease{
+			ID:                "lease-1",
+			AccountID:         "123456789012",
+			PrincipalID:       "jdoe123",
+			LeaseStatus:       db.Frozen,
+			LeaseStatusReason: db.LeaseExpired,
+			BudgetAmount:      100,
+			ExpiresOn:         now.Add(-time.Hour).Unix(),
+			CreatedOn:         now.AddDate(0, 0, -1).Unix(),
+		})
+		require.Nil(t, err)
+		controller := stubExtendController()
+		controller.Dao = dbSvc
+
+		res, err := controller.Call(context.TODO(), extendRequest(t, "lease-1", map[string]interface{}{
+			"expiresOn": nextExpiresOn,
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 200, res.StatusCode)
+
+		lease, err := dbSvc.GetLeaseByID("lease-1")
+		require.Nil(t, err)
+		assert.Equal(t, db.Active, lease.LeaseStatus)
+		assert.Equal(t, db.LeaseActive, lease.LeaseStatusReason)
+		assert.Equal(t, nextExpiresOn, lease.ExpiresOn)
+	})
+
+	t.Run("should unfreeze over budget leases, once their budget is raised", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		_, err := dbSvc.UpsertLease(db.Lease{
+			ID:                "lease-1",
+			AccountID:         "123456789012",
+			PrincipalID:       "jdoe123",
+			LeaseStatus:       db.Frozen,
+			LeaseStatusReason: db.LeaseOverBudget,
+			BudgetAmount:      100,
+			ExpiresOn:         expiresOn,
+			CreatedOn:         now.AddDate(0, 0, -1).Unix(),
+		})
+		require.Nil(t, err)
+		usageMock := &mockUsage.Service{}
+		usageMock.On("GetUsageByDateRange", mock.Anything, mock.Anything).
+			Return([]*usage.Usage{
+				{PrincipalID: "jdoe123", AccountID: "123456789012", CostAmount: 150},
+			}, nil)
+		controller := stubExtendController()
+		controller.Dao = dbSvc
+		controller.UsageSvc = usageMock
+		controller.LeaseRules = []*leaserule.Rule{
+			{Name: "over-budget", Type: leaserule.Spend, Action: leaserule.Freeze, Budget: leaserule.LeaseBudget},
+			{Name: "over-budget-warning", Type: leaserule.Spend, Action: leaserule.Warn, Budget: leaserule.LeaseBudget, Percent: 50},
+		}
+
+		res, err := controller.Call(context.TODO(), extendRequest(t, "lease-1", map[string]interface{}{
+			"budgetAmount": 200,
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 200, res.StatusCode)
+
+		lease, err := dbSvc.GetLeaseByID("lease-1")
+		require.Nil(t, err)
+		assert.Equal(t, db.Active, lease.LeaseStatus)
+		assert.Equal(t, float64(200), lease.BudgetAmount)
+	})
+
+	t.Run("should not allow users to extend other principals' leases", func(t *testing.T) {
+		dbMock := stubExtendDb(expiresOn)
+		controller := stubExtendController()
+		controller.Dao = dbMock
+
+		ctx := context.WithValue(context.TODO(), api.DceCtxKey, api.User{
+			Username: "someone-else",
+			Role:     api.UserGroupName,
+		})
+		res, err := controller.Call(ctx, extendRequest(t, "lease-1", map[string]interface{}{
+			"budgetAmount": 200,
+		}))
+		require.Nil(t, err)
+		require.Equal(t, response.NotFoundError(), res)
+	})
+
+	t.Run("should return a conflict if the lease was modified", func(t *testing.T) {
+		dbMock := stubExtendDb(expiresOn)
+		controller := stubExtendController()
+		controller.Dao = dbMock
+
+		dbMock.On("ExtendLease", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, &db.StatusTransitionError{})
+
+		res, err := controller.Call(context.TODO(), extendRequest(t, "lease-1", map[string]interface{}{
+			"budgetAmount": 200,
+		}))
+		require.Nil(t, err)
+		require.Equal(t, response.ConflictError("Unable to extend lease lease-1: lease was modified by another request"), res)
+	})
+
+	t.Run("should not extend leases past the max lease period", func(t *testing.T) {
+		// Lease was created 6 days ago, with a max lease period of 7 days
+		createdOn := now.Add(-6 * 24 * time.Hour)
+		dbSvc := db.NewMemoryDB(7)
+		_, err := dbSvc.UpsertLease(db.Lease{
+			ID:           "lease-1",
+			AccountID:    "123456789012",
+			PrincipalID:  "jdoe123",
+			LeaseStatus:  db.Active,
+			BudgetAmount: 100,
+			ExpiresOn:    now.Add(time.Hour).Unix(),
+			CreatedOn:    createdOn.Unix(),
+		})
+		require.Nil(t, err)
+		controller := stubExtendController()
+		controller.Dao = dbSvc
+		controller.MaxLeasePeriod = aws.Int(7 * 24 * 60 * 60)
+
+		// Extending within the max lease period succeeds
+		res, err := controller.Call(context.TODO(), extendRequest(t, "lease-1", map[string]interface{}{
+			"expiresOn": createdOn.Add(7 * 24 * time.Hour).Unix(),
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 200, res.StatusCode)
+
+		// Extending again, past the max lease period, fails
+		nextExpiresOn := createdOn.Add(8 * 24 * time.Hour).Unix()
+		res, err = controller.Call(context.TODO(), extendRequest(t, "lease-1", map[string]interface{}{
+			"expiresOn": nextExpiresOn,
+		}))
+		require.Nil(t, err)
+		require.Equal(t, response.RequestValidationError(fmt.Sprintf(
+			"Requested lease has a budget expires on of %d, which is greater than max lease period of %d",
+			nextExpiresOn, createdOn.Add(7*24*time.Hour).Unix())), res)
+
+		lease, err := dbSvc.GetLeaseByID("lease-1")
+		require.Nil(t, err)
+		assert.Equal(t, createdOn.Add(7*24*time.Hour).Unix(), lease.ExpiresOn)
+	})
+
+	t.Run("should persist the extension", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		_, err := dbSvc.UpsertLease(db.Lease{
+			ID:           "lease-1",
+			AccountID:    "123456789012",
+			PrincipalID:  "jdoe123",
+			LeaseStatus:  db.Active,
+			BudgetAmount: 100,
+			ExpiresOn:    expiresOn,
+			CreatedOn:    now.Unix(),
+		})
+		require.Nil(t, err)
+		controller := stubExtendController()
+		controller.Dao = dbSvc
+
+		res, err := controller.Call(context.TODO(), extendRequest(t, "lease-1", map[string]interface{}{
+			"expiresOn": nextExpiresOn,
+		}))
+		require.Nil(t, err)
+		require.Equal(t, 200, res.StatusCode)
+
+		lease, err := dbSvc.GetLeaseByID("lease-1")
+		require.Nil(t, err)
+		assert.Equal(t, nextExpiresOn, lease.ExpiresOn)
+		assert.Equal(t, float64(100), lease.BudgetAmount)
+	})
+}
+
+func extendRequest(t *testing.T, leaseID string, jsonObj map[string]interface{}) *events.APIGatewayProxyRequest {
+	req := apiGatewayRequest(t, jsonObj)
+	req.Path = "/leases/" + leaseID + "/extend"
+	return req
+}
+
+// stubExtendController creates an ExtendController instance
+// with stubbed out / default values for a fields
+func stubExtendController() ExtendController {
+	return ExtendController{
+		Dao:                   stubExtendDb(time.Now().AddDate(0, 0, 2).Unix()),
+		SNS:                   snsStub(),
+		LeaseExtendedTopicARN: aws.String("lease-extended-topic"),
+		UsageSvc:              stubUsageService(),
+		PrincipalBudgetAmount: aws.Float64(1000),
+		PrincipalBudgetPeriod: aws.String(Weekly),
+		MaxLeaseBudgetAmount:  aws.Float64(1000),
+		MaxLeasePeriod:        aws.Int(704800),
+	}
+}
+
+// stubExtendDb creates a mock DBer,
+// which returns an active lease with the given expiresOn
+func stubExtendDb(expiresOn int64) *mockDB.DBer {
+	dbMock := &mockDB.DBer{}
+	dbMock.On("GetLeaseByID", "lease-1").
+		Return(&db.Lease{
+			ID:           "lease-1",
+			AccountID:    "123456789012",
+			PrincipalID:  "jdoe123",
+			LeaseStatus:  db.Active,
+			BudgetAmount: 100,
+			ExpiresOn:    expiresOn,
+			CreatedOn:    time.Now().Unix(),
+		}, nil)
+
+	return dbMock
+}
@@ -1,4 +1,4 @@
-package main
+package leases
 
 import (
 	"context"
@@ -8,6 +8,7 @@ import (
 	"path"
 
 	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/waitlist"
 
 	"github.com/Optum/dce/pkg/api/response"
 	"github.com/aws/aws-lambda-go/events"
@@ -34,6 +35,13 @@ func (controller GetController) Call(ctx context.Context, req *events.APIGateway
 		return response.NotFoundError(), nil
 	}
 
+	// Set the waitlist position of a pending lease
+	err = waitlist.SetQueuePositions(controller.Dao, lease)
+	if err != nil {
+		log.Printf("Error Getting waitlist position for Lease %s: %s", leaseID, err)
+		return response.ServerError(), nil
+	}
+
 	leaseResponse := response.LeaseResponse(*lease)
 	return response.CreateJSONResponse(http.StatusOK, leaseResponse), nil
 }
@@ -0,0 +1,57 @@
+package leases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+
+	"github.com/Optum/dce/pkg/api"
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// HistoryController is responsible for handling API events for
+// viewing the history of a lease.
+type HistoryController struct {
+	Dao     db.DBer
+	History history.Service
+}
+
+// Call - Function to return the history of changes made to a lease
+//
+// Handles requests for `GET /leases/{id}/history`
+func (c HistoryController) Call(ctx context.Context, req *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	leaseID := path.Base(path.Dir(req.Path))
+
+	// Lookup the lease
+	lease, err := c.Dao.GetLeaseByID(leaseID)
+	if err != nil {
+		log.Printf("Error Getting Lease for Id %s: %s", leaseID, err)
+		return response.CreateAPIErrorResponse(http.StatusInternalServerError,
+			response.CreateErrorResponse("ServerError",
+				fmt.Sprintf("Failed Get on Lease %s",
+					leaseID))), nil
+	}
+	if lease == nil {
+		return response.NotFoundError(), nil
+	}
+
+	// Users may only view the history of their own leases
+	user, ok := ctx.Value(api.DceCtxKey).(api.User)
+	if ok && user.Role != api.AdminGroupName && lease.PrincipalID != user.Username {
+		log.Printf("User (%s) doesn't have access to lease %s", user.Username, leaseID)
+		return response.NotFoundError(), nil
+	}
+
+	records, err := c.History.GetHistory(leaseID)
+	if err != nil {
+		log.Printf("Failed to get history for lease %s: %s", leaseID, err)
+		return response.ServerError(), nil
+	}
+
+	return response.CreateJSONResponse(http.StatusOK, response.CreateHistoryResponse(records)), nil
+}
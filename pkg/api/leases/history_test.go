@@ -0,0 +1,63 @@
+package leases
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Optum/dce/pkg/api"
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryController_Call(t *testing.T) {
+	historySvc := history.NewMemory()
+	recorder := history.NewRecorder(db.NewMemoryDB(7), historySvc, "test")
+	_, err := recorder.UpsertLease(db.Lease{
+		ID:          "lease-1",
+		AccountID:   "123456789012",
+		PrincipalID: "jdoe123",
+		LeaseStatus: db.Active,
+		ExpiresOn:   1000,
+	})
+	require.Nil(t, err)
+	_, err = recorder.WithActor("admin").TransitionLeaseStatus("123456789012", "jdoe123", db.Active, db.Inactive, db.LeaseDestroyed)
+	require.Nil(t, err)
+
+	controller := HistoryController{
+		Dao:     recorder,
+		History: historySvc,
+	}
+
+	t.Run("should return the lease history", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/leases/lease-1/history"}
+
+		res, err := controller.Call(context.TODO(), &mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, 200, res.StatusCode)
+
+		var records []*response.HistoryRecordResponse
+		require.Nil(t, json.Unmarshal([]byte(res.Body), &records))
+		require.Len(t, records, 2)
+		require.Equal(t, history.StatusChanged, records[1].Event)
+		require.Equal(t, "admin", records[1].Actor)
+		require.Equal(t, string(db.LeaseDestroyed), records[1].Reason)
+		require.Equal(t, "lease-1", records[1].LeaseID)
+	})
+
+	t.Run("should not allow users to view other principals' leases", func(t *testing.T) {
+		mockRequest := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/leases/lease-1/history"}
+		ctx := context.WithValue(context.TODO(), api.DceCtxKey, api.User{
+			Username: "someone-else",
+			Role:     api.UserGroupName,
+		})
+
+		res, err := controller.Call(ctx, &mockRequest)
+		require.Nil(t, err)
+		require.Equal(t, response.NotFoundError(), res)
+	})
+}
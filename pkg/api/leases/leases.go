@@ -0,0 +1,121 @@
+// Package leases handles API requests for the `/leases` endpoints
+package leases
+
+import (
+	"fmt"
+
+	"github.com/Optum/dce/pkg/api"
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/currency"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/leaserule"
+	"github.com/Optum/dce/pkg/policyprofile"
+	"github.com/Optum/dce/pkg/usage"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	PrincipalIDParam     = "principalId"
+	AccountIDParam       = "accountId"
+	NextPrincipalIDParam = "nextPrincipalId"
+	NextAccountIDParam   = "nextAccountId"
+	StatusParam          = "status"
+	LimitParam           = "limit"
+)
+
+// messageBody is the structured object of the JSON Message to send
+// to an SNS Topic for lease creation/destruction
+type messageBody struct {
+	Default string `json:"default"`
+	Body    string `json:"Body"`
+}
+
+// buildBaseURL returns a base API url from the request properties.
+func buildBaseURL(req *events.APIGatewayProxyRequest) string {
+	return fmt.Sprintf("https://%s/%s", req.Headers["Host"], req.RequestContext.Stage)
+}
+
+// RouterConfig contains the services and settings
+// used by the `/leases` controllers
+type RouterConfig struct {
+	Dao                      db.DBer
+	History                  history.Service
+	SNS                      common.Notificationer
+	Queue                    common.Queue
+	UsageSvc                 usage.Service
+	UserDetails              api.UserDetails
+	LeaseAddedTopicARN       string
+	LeaseExtendedTopicARN    string
+	AccountDeletedTopicARN   string
+	ResetQueueURL            string
+	PrincipalBudgetAmount    float64
+	PrincipalBudgetPeriod    string
+	MaxLeaseBudgetAmount     float64
+	MaxLeasePeriod           int
+	DefaultLeaseLengthInDays int
+	PendingLeaseTimeout      int
+	PolicyProfiles           *policyprofile.Config
+	// Exchange rates, to convert lease budgets and usage between currencies
+	Rates currency.RateProvider
+	// LeaseRules are checked before unfreezing leases
+	LeaseRules []*leaserule.Rule
+}
+
+// NewRouter creates a router for the `/leases` endpoints
+func NewRouter(config RouterConfig) *api.Router {
+	return &api.Router{
+		ResourceName: "/leases",
+		GetController: GetController{
+			Dao: config.Dao,
+		},
+		ListController: ListController{
+			Dao: config.Dao,
+		},
+		DeleteController: DeleteController{
+			Dao:                    config.Dao,
+			SNS:                    config.SNS,
+			AccountDeletedTopicArn: config.AccountDeletedTopicARN,
+			ResetQueueURL:          config.ResetQueueURL,
+			Queue:                  config.Queue,
+		},
+		CreateController: CreateController{
+			Dao:                      config.Dao,
+			SNS:                      config.SNS,
+			LeaseAddedTopicARN:       &config.LeaseAddedTopicARN,
+			UsageSvc:                 config.UsageSvc,
+			PrincipalBudgetAmount:    &config.PrincipalBudgetAmount,
+			PrincipalBudgetPeriod:    &config.PrincipalBudgetPeriod,
+			MaxLeaseBudgetAmount:     &config.MaxLeaseBudgetAmount,
+			MaxLeasePeriod:           &config.MaxLeasePeriod,
+			DefaultLeaseLengthInDays: config.DefaultLeaseLengthInDays,
+			PendingLeaseTimeout:      config.PendingLeaseTimeout,
+			PolicyProfiles:           config.PolicyProfiles,
+			Rates:                    config.Rates,
+		},
+		ActionControllers: map[string]api.Controller{
+			"extend": ExtendController{
+				Dao:                   config.Dao,
+				SNS:                   config.SNS,
+				LeaseExtendedTopicARN: &config.LeaseExtendedTopicARN,
+				UsageSvc:              config.UsageSvc,
+				PrincipalBudgetAmount: &config.PrincipalBudgetAmount,
+				PrincipalBudgetPeriod: &config.PrincipalBudgetPeriod,
+				MaxLeaseBudgetAmount:  &config.MaxLeaseBudgetAmount,
+				MaxLeasePeriod:        &config.MaxLeasePeriod,
+				Rates:                 config.Rates,
+				LeaseRules:            config.LeaseRules,
+			},
+			"cancel": CancelController{
+				Dao: config.Dao,
+			},
+		},
+		SubresourceControllers: map[string]api.Controller{
+			"history": HistoryController{
+				Dao:     config.Dao,
+				History: config.History,
+			},
+		},
+		UserDetails: config.UserDetails,
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package leases
 
 import (
 	"context"
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/waitlist"
 
 	"github.com/Optum/dce/pkg/api/response"
 	"github.com/aws/aws-lambda-go/events"
@@ -23,7 +24,7 @@ func (c ListController) Call(ctx context.Context, req *events.APIGatewayProxyReq
 	getLeasesInput, err := parseGetLeasesInput(req.QueryStringParameters)
 
 	if err != nil {
-		return response.RequestValidationError(fmt.Sprintf("Error parsing query params")), nil
+		return response.RequestValidationError(fmt.Sprintf("Error parsing query params: %s", err)), nil
 	}
 
 	result, err := c.Dao.GetLeases(getLeasesInput)
@@ -32,9 +33,20 @@ func (c ListController) Call(ctx context.Context, req *events.APIGatewayProxyReq
 		return response.ServerErrorWithResponse(fmt.Sprintf("Error querying leases: %s", err)), nil
 	}
 
+	// Set the waitlist position of any pending leases
+	err = waitlist.SetQueuePositions(c.Dao, result.Results...)
+	if err != nil {
+		return response.ServerErrorWithResponse(fmt.Sprintf("Error querying leases: %s", err)), nil
+	}
+
 	// Convert DB Lease model to API Response model
 	leaseResponseItems := []response.LeaseResponse{}
 	for _, lease := range result.Results {
+		// Pending leases which timed out are no longer pending,
+		// even before they are marked as Inactive
+		if getLeasesInput.Status == db.Pending && lease.LeaseStatus != db.Pending {
+			continue
+		}
 		leaseResponseItems = append(leaseResponseItems, response.LeaseResponse(*lease))
 	}
 
@@ -74,8 +86,12 @@ func parseGetLeasesInput(queryParams map[string]string) (db.GetLeasesInput, erro
 			query.Status = db.Active
 		case "inactive":
 			query.Status = db.Inactive
+		case "frozen":
+			query.Status = db.Frozen
+		case "pending":
+			query.Status = db.Pending
 		default:
-			query.Status = db.Active
+			return query, fmt.Errorf("invalid status %q: must be active, inactive, frozen or pending", status)
 		}
 	}
 
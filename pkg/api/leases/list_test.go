@@ -1,4 +1,4 @@
-package main
+package leases
 
 import (
 	"context"
@@ -11,6 +11,7 @@ import (
 	"github.com/Optum/dce/pkg/db"
 	"github.com/Optum/dce/pkg/db/mocks"
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -74,6 +75,44 @@ func TestListLeases(t *testing.T) {
 		require.Equal(t, actualResponse.StatusCode, 200, "Returns a 200.")
 	})
 
+	t.Run("should list pending leases", func(t *testing.T) {
+		mockDb := mocks.DBer{}
+		mockDb.On("GetLeases", db.GetLeasesInput{
+			Status:    db.Pending,
+			StartKeys: map[string]string{},
+		}).Return(*createEmptyLeasesOutput(), nil)
+
+		controller := ListController{
+			Dao: &mockDb,
+		}
+
+		actualResponse, err := controller.Call(context.Background(), &events.APIGatewayProxyRequest{
+			HTTPMethod:            http.MethodGet,
+			QueryStringParameters: map[string]string{StatusParam: "Pending"},
+			Path:                  "/leases",
+		})
+		require.Nil(t, err)
+		require.Equal(t, 200, actualResponse.StatusCode)
+		mockDb.AssertExpectations(t)
+	})
+
+	t.Run("should reject unknown statuses", func(t *testing.T) {
+		mockDb := mocks.DBer{}
+		controller := ListController{
+			Dao: &mockDb,
+		}
+
+		actualResponse, err := controller.Call(context.Background(), &events.APIGatewayProxyRequest{
+			HTTPMethod:            http.MethodGet,
+			QueryStringParameters: map[string]string{StatusParam: "expired"},
+			Path:                  "/leases",
+		})
+		require.Nil(t, err)
+		require.Equal(t, response.RequestValidationError(
+			`Error parsing query params: invalid status "expired": must be active, inactive, frozen or pending`), actualResponse)
+		mockDb.AssertNotCalled(t, "GetLeases", mock.Anything)
+	})
+
 	t.Run("When the query fails", func(t *testing.T) {
 		expectedError := errors.New("Error")
 		mockLeaseInput := createGetLeasesInput()
@@ -0,0 +1,158 @@
+package leases
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Optum/dce/pkg/currency"
+	"github.com/Optum/dce/pkg/usage"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pkg/errors"
+	"math"
+	"time"
+)
+
+const Weekly = "WEEKLY"
+const Monthly = "MONTHLY"
+
+// validateLeaseRequest validates lease budget amount and period
+func validateLeaseRequest(controller CreateController, req *events.APIGatewayProxyRequest) (*createLeaseRequest, bool, string, error) {
+
+	// Validate body from the Request
+	requestBody := &createLeaseRequest{}
+	var err error
+
+	err = json.Unmarshal([]byte(req.Body), requestBody)
+	if err != nil || requestBody.PrincipalID == "" {
+		validationErrStr := "invalid request parameters"
+		return requestBody, false, validationErrStr, nil
+	}
+
+	// Set default expiresOn
+	if requestBody.ExpiresOn == 0 {
+		requestBody.ExpiresOn = time.Now().AddDate(0, 0, controller.DefaultLeaseLengthInDays).Unix()
+	}
+
+	// Set default metadata (empty object)
+	if requestBody.Metadata == nil {
+		requestBody.Metadata = map[string]interface{}{}
+	}
+
+	// Set default budget currency
+	if requestBody.BudgetCurrency == "" {
+		requestBody.BudgetCurrency = currency.USD
+	}
+
+	// Validate the budget currency may be converted to USD,
+	// to check the budget against the configured limits
+	err = currency.Validate(controller.Rates, requestBody.BudgetCurrency)
+	if err != nil {
+		return requestBody, false, err.Error(), nil
+	}
+
+	// Validate requested lease end date is greater than today
+	if requestBody.ExpiresOn <= time.Now().Unix() {
+		validationErrStr := fmt.Sprintf("Requested lease has a desired expiry date less than today: %d", requestBody.ExpiresOn)
+		return requestBody, false, validationErrStr, nil
+	}
+
+	isValid, validationErrStr, err := validateLeaseLimits(leaseLimits{
+		UsageSvc:              controller.UsageSvc,
+		PrincipalBudgetAmount: controller.PrincipalBudgetAmount,
+		PrincipalBudgetPeriod: controller.PrincipalBudgetPeriod,
+		MaxLeaseBudgetAmount:  controller.MaxLeaseBudgetAmount,
+		MaxLeasePeriod:        controller.MaxLeasePeriod,
+		Rates:                 controller.Rates,
+	}, "create", requestBody.PrincipalID, time.Now(), requestBody.ExpiresOn, requestBody.BudgetAmount, requestBody.BudgetCurrency)
+
+	return requestBody, isValid, validationErrStr, err
+}
+
+// leaseLimits are the configured limits on a lease's
+// budget amount and period. Budget limits are in USD.
+type leaseLimits struct {
+	UsageSvc              usage.Service
+	PrincipalBudgetAmount *float64
+	PrincipalBudgetPeriod *string
+	MaxLeaseBudgetAmount  *float64
+	MaxLeasePeriod        *int
+	Rates                 currency.RateProvider
+}
+
+// validateLeaseLimits validates a lease's budget amount and expiry against
+// the MAX_LEASE_BUDGET_AMOUNT, MAX_LEASE_PERIOD and PRINCIPAL_BUDGET_AMOUNT limits.
+// `action` describes the operation being validated (eg. "create"), for use in error messages.
+// The lease period is measured from `startsOn`: now, for new leases,
+// or when the lease was created, for extended leases.
+// Leases without a budget currency are budgeted in USD.
+func validateLeaseLimits(limits leaseLimits, action string, principalID string, startsOn time.Time, expiresOn int64, budgetAmount float64, budgetCurrency string) (bool, string, error) {
+	if budgetCurrency == "" {
+		budgetCurrency = currency.USD
+	}
+
+	// Validate requested lease budget amount is less than MAX_LEASE_BUDGET_AMOUNT
+	budgetAmountUSD, err := currency.Convert(limits.Rates, budgetAmount, budgetCurrency, currency.USD)
+	if err != nil {
+		validationErrStr := fmt.Sprintf("Unable to %s lease: %s", action, err)
+		return false, validationErrStr, nil
+	}
+	if budgetAmountUSD > *limits.MaxLeaseBudgetAmount {
+		requestedBudget := fmt.Sprintf("%f", math.Round(budgetAmount))
+		if budgetCurrency != currency.USD {
+			requestedBudget = fmt.Sprintf("%f %s (%f %s)", math.Round(budgetAmount), budgetCurrency, math.Round(budgetAmountUSD), currency.USD)
+		}
+		validationErrStr := fmt.Sprintf("Requested lease has a budget amount of %s, which is greater than max lease budget amount of %f", requestedBudget, math.Round(*limits.MaxLeaseBudgetAmount))
+		return false, validationErrStr, nil
+	}
+
+	// Validate requested lease budget period is less than MAX_LEASE_BUDGET_PERIOD
+	currentTime := time.Now()
+	maxLeaseExpiresOn := startsOn.Add(time.Second * time.Duration(*limits.MaxLeasePeriod))
+	if expiresOn > maxLeaseExpiresOn.Unix() {
+		validationErrStr := fmt.Sprintf("Requested lease has a budget expires on of %d, which is greater than max lease period of %d", expiresOn, maxLeaseExpiresOn.Unix())
+		return false, validationErrStr, nil
+	}
+
+	// Validate requested lease budget amount is less than PRINCIPAL_BUDGET_AMOUNT for current principal billing period
+	usageStartTime := getBeginningOfCurrentBillingPeriod(*limits.PrincipalBudgetPeriod)
+	usageEndTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), 23, 59, 59, 0, time.UTC)
+
+	usageRecords, err := limits.UsageSvc.GetUsageByDateRange(usageStartTime, usageEndTime)
+	if err != nil {
+		errStr := fmt.Sprintf("Failed to retrieve usage: %s", err)
+		return true, "", errors.New(errStr)
+	}
+
+	// Group by PrincipalID to get sum of total spent for current billing period
+	spent := 0.0
+	for _, usageItem := range usageRecords {
+		if usageItem.PrincipalID == principalID {
+			amount, err := usageItem.AmountIn(currency.USD, limits.Rates)
+			if err != nil {
+				return true, "", errors.Wrapf(err, "Failed to convert usage")
+			}
+			spent = spent + amount
+		}
+	}
+
+	if spent > *limits.PrincipalBudgetAmount {
+		validationErrStr := fmt.Sprintf("Unable to %s lease: User principal %s has already spent %f of their principal budget", action, principalID, math.Round(*limits.PrincipalBudgetAmount))
+		return false, validationErrStr, nil
+	}
+
+	return true, "", nil
+}
+
+// getBeginningOfCurrentBillingPeriod returns starts of the billing period based on budget period
+func getBeginningOfCurrentBillingPeriod(input string) time.Time {
+	currentTime := time.Now()
+	if input == Weekly {
+
+		for currentTime.Weekday() != time.Sunday { // iterate back to Sunday
+			currentTime = currentTime.AddDate(0, 0, -1)
+		}
+
+		return time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	return time.Date(currentTime.Year(), currentTime.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ProxyHandler handles an API Gateway proxy request,
+// in the same way as an API Lambda function
+type ProxyHandler func(ctx context.Context, req *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// HTTPHandler adapts a ProxyHandler to a net/http Handler,
+// so that API controllers may be served without API Gateway
+func HTTPHandler(handler ProxyHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := NewProxyRequest(r)
+		if err != nil {
+			log.Printf("Failed to read request %s %s: %s", r.Method, r.URL.Path, err)
+			WriteProxyResponse(w, response.BadRequestError("Failed to read request body"))
+			return
+		}
+
+		res, err := handler(r.Context(), req)
+		if err != nil {
+			log.Printf("Handler error for %s %s: %s", r.Method, r.URL.Path, err)
+			res = response.ServerError()
+		}
+
+		WriteProxyResponse(w, res)
+	})
+}
+
+// NewProxyRequest converts an HTTP request into
+// the API Gateway proxy request expected by API controllers
+func NewProxyRequest(r *http.Request) (*events.APIGatewayProxyRequest, error) {
+	body := []byte{}
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	headers := map[string]string{}
+	multiValueHeaders := map[string][]string{}
+	for name, values := range r.Header {
+		headers[name] = strings.Join(values, ",")
+		multiValueHeaders[name] = values
+	}
+	// net/http removes the Host header from the request headers
+	headers["Host"] = r.Host
+	multiValueHeaders["Host"] = []string{r.Host}
+
+	query := map[string]string{}
+	multiValueQuery := map[string][]string{}
+	for name, values := range r.URL.Query() {
+		query[name] = values[len(values)-1]
+		multiValueQuery[name] = values
+	}
+
+	sourceIP := r.RemoteAddr
+	if i := strings.LastIndex(sourceIP, ":"); i > 0 {
+		sourceIP = sourceIP[:i]
+	}
+
+	return &events.APIGatewayProxyRequest{
+		Path:                            r.URL.Path,
+		HTTPMethod:                      r.Method,
+		Headers:                         headers,
+		MultiValueHeaders:               multiValueHeaders,
+		QueryStringParameters:           query,
+		MultiValueQueryStringParameters: multiValueQuery,
+		PathParameters:                  map[string]string{},
+		Body:                            string(body),
+		RequestContext: events.APIGatewayProxyRequestContext{
+			HTTPMethod: r.Method,
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  sourceIP,
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}, nil
+}
+
+// WriteProxyResponse writes an API Gateway proxy response
+// to an HTTP response
+func WriteProxyResponse(w http.ResponseWriter, res events.APIGatewayProxyResponse) {
+	for name, value := range res.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range res.MultiValueHeaders {
+		w.Header().Del(name)
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	body := []byte(res.Body)
+	if res.IsBase64Encoded {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(res.Body)
+		if err != nil {
+			log.Printf("Failed to decode response body: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	statusCode := res.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
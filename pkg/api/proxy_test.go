@@ -0,0 +1,55 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Optum/dce/pkg/api"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHandler(t *testing.T) {
+
+	t.Run("should adapt HTTP requests and responses", func(t *testing.T) {
+		var proxyReq *events.APIGatewayProxyRequest
+		handler := api.HTTPHandler(func(ctx context.Context, req *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			proxyReq = req
+			return events.APIGatewayProxyResponse{
+				StatusCode: http.StatusCreated,
+				Headers:    map[string]string{"Content-Type": "application/json"},
+				Body:       `{"id":"lease-1"}`,
+			}, nil
+		})
+
+		httpReq := httptest.NewRequest(http.MethodPost, "http://localhost:8080/leases?principalId=jdoe&limit=5", strings.NewReader(`{"foo":"bar"}`))
+		httpReq.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httpReq)
+
+		require.Equal(t, http.MethodPost, proxyReq.HTTPMethod)
+		require.Equal(t, "/leases", proxyReq.Path)
+		require.Equal(t, `{"foo":"bar"}`, proxyReq.Body)
+		require.Equal(t, map[string]string{"principalId": "jdoe", "limit": "5"}, proxyReq.QueryStringParameters)
+		require.Equal(t, "application/json", proxyReq.Headers["Content-Type"])
+		require.Equal(t, "localhost:8080", proxyReq.Headers["Host"])
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		require.Equal(t, `{"id":"lease-1"}`, w.Body.String())
+	})
+
+	t.Run("should return a server error if the handler fails", func(t *testing.T) {
+		handler := api.HTTPHandler(func(ctx context.Context, req *events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+			return events.APIGatewayProxyResponse{}, context.DeadlineExceeded
+		})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/leases", nil))
+
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
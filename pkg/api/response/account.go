@@ -18,12 +18,19 @@ import (
 //	dbAccount := db.Account{...}
 //	accountRes := response.AccountResponse(dbAccount)
 type AccountResponse struct {
-	ID                  string                 `json:"id"`
-	AccountStatus       db.AccountStatus       `json:"accountStatus"`
-	LastModifiedOn      int64                  `json:"lastModifiedOn"`
-	CreatedOn           int64                  `json:"createdOn"`
-	AdminRoleArn        string                 `json:"adminRoleArn"`        // Assumed by the master account, to manage this user account
-	PrincipalRoleArn    string                 `json:"principalRoleArn"`    // Assumed by principal users
-	PrincipalPolicyHash string                 `json:"principalPolicyHash"` // The policy used by the PrincipalRoleArn
-	Metadata            map[string]interface{} `json:"metadata"`
+	ID                       string                 `json:"id"`
+	AccountStatus            db.AccountStatus       `json:"accountStatus"`
+	LastModifiedOn           int64                  `json:"lastModifiedOn"`
+	CreatedOn                int64                  `json:"createdOn"`
+	AdminRoleArn             string                 `json:"adminRoleArn"`                       // Assumed by the master account, to manage this user account
+	PrincipalRoleArn         string                 `json:"principalRoleArn"`                   // Assumed by principal users
+	PrincipalPolicyHash      string                 `json:"principalPolicyHash"`                // The policy used by the PrincipalRoleArn
+	PrincipalTrustPolicyHash string                 `json:"principalTrustPolicyHash,omitempty"` // The trust policy of the PrincipalRoleArn
+	Metadata                 map[string]interface{} `json:"metadata"`
+	Pool                     string                 `json:"pool,omitempty"`
+	Labels                   map[string]string      `json:"labels,omitempty"`
+	LeftoverResources        []string               `json:"leftoverResources,omitempty"` // Resources which remained after the last reset
+	ResetStatus              *db.ResetStatus        `json:"resetStatus,omitempty"`       // Status of the latest reset
+	NukeConfig               *db.NukeConfig         `json:"nukeConfig,omitempty"`        // Overrides of the aws-nuke config used to reset the account
+	PolicyDrift              *db.PolicyDrift        `json:"policyDrift,omitempty"`       // Result of the last check for changes to the principal IAM role and policy
 }
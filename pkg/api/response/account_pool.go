@@ -0,0 +1,22 @@
+package response
+
+// AccountPoolResponse is the serialized JSON Response for
+// the capacity of an account pool
+// {
+// 	"pool": "default",
+// 	"ready": 3,
+// 	"notReady": 1,
+// 	"leased": 5,
+// 	"orphaned": 0,
+// 	"quarantined": 0,
+// 	"total": 9
+// }
+type AccountPoolResponse struct {
+	Pool        string `json:"pool"`
+	Ready       int    `json:"ready"`
+	NotReady    int    `json:"notReady"`
+	Leased      int    `json:"leased"`
+	Orphaned    int    `json:"orphaned"`
+	Quarantined int    `json:"quarantined"`
+	Total       int    `json:"total"`
+}
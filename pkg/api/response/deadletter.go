@@ -0,0 +1,45 @@
+package response
+
+import (
+	"github.com/Optum/dce/pkg/common"
+)
+
+// ResetDeadLetterResponse is the serialized JSON Response for
+// a reset request which failed too many times,
+// and was moved to the reset dead-letter queue
+// {
+// 	"id": "a1b2c3d4-5678-90ab-cdef-EXAMPLE11111",
+// 	"accountId": "123456789012",
+// 	"error": "Account 123456789012 doesn't exist",
+// 	"receiveCount": 5,
+// 	"deadLetteredOn": 1572912000
+// }
+type ResetDeadLetterResponse struct {
+	ID             string `json:"id"`
+	AccountID      string `json:"accountId"`
+	Error          string `json:"error"`
+	ReceiveCount   int    `json:"receiveCount"`
+	DeadLetteredOn int64  `json:"deadLetteredOn"`
+}
+
+// CreateResetDeadLetterResponse creates a response from a dead-lettered
+// reset request. The body of a reset request is the account ID.
+func CreateResetDeadLetterResponse(letter *common.DeadLetter) *ResetDeadLetterResponse {
+	return &ResetDeadLetterResponse{
+		ID:             letter.ID,
+		AccountID:      letter.Body,
+		Error:          letter.Error,
+		ReceiveCount:   letter.ReceiveCount,
+		DeadLetteredOn: letter.DeadLetteredOn,
+	}
+}
+
+// CreateResetDeadLettersResponse creates a response for a list
+// of dead-lettered reset requests
+func CreateResetDeadLettersResponse(letters []*common.DeadLetter) []*ResetDeadLetterResponse {
+	res := []*ResetDeadLetterResponse{}
+	for _, letter := range letters {
+		res = append(res, CreateResetDeadLetterResponse(letter))
+	}
+	return res
+}
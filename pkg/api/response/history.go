@@ -0,0 +1,68 @@
+package response
+
+import (
+	"time"
+
+	"github.com/Optum/dce/pkg/history"
+)
+
+// HistoryRecordResponse is the serialized JSON Response for
+// a change made to an account or lease
+// {
+// 	"resourceId": "123456789012",
+// 	"resourceType": "Account",
+// 	"event": "StatusChanged",
+// 	"timestamp": 1573050000,
+// 	"changes": [{"field": "AccountStatus", "prevValue": "Ready", "nextValue": "Leased"}],
+// 	"actor": "jdoe123",
+// 	"reason": ""
+// }
+type HistoryRecordResponse struct {
+	ResourceID   string                   `json:"resourceId"`
+	ResourceType history.ResourceType     `json:"resourceType"`
+	Event        history.Event            `json:"event"`
+	Timestamp    int64                    `json:"timestamp"`
+	Changes      []*HistoryChangeResponse `json:"changes"`
+	Actor        string                   `json:"actor"`
+	Reason       string                   `json:"reason"`
+	AccountID    string                   `json:"accountId"`
+	PrincipalID  string                   `json:"principalId,omitempty"`
+	LeaseID      string                   `json:"leaseId,omitempty"`
+}
+
+// HistoryChangeResponse is the serialized JSON Response for
+// a change to a single field of an account or lease
+type HistoryChangeResponse struct {
+	Field     string `json:"field"`
+	PrevValue string `json:"prevValue"`
+	NextValue string `json:"nextValue"`
+}
+
+// CreateHistoryResponse creates a list of History Responses
+// based on the provided history records
+func CreateHistoryResponse(records []*history.Record) []*HistoryRecordResponse {
+	res := []*HistoryRecordResponse{}
+	for _, record := range records {
+		changes := []*HistoryChangeResponse{}
+		for _, change := range record.Changes {
+			changes = append(changes, &HistoryChangeResponse{
+				Field:     change.Field,
+				PrevValue: change.PrevValue,
+				NextValue: change.NextValue,
+			})
+		}
+		res = append(res, &HistoryRecordResponse{
+			ResourceID:   record.ResourceID,
+			ResourceType: record.ResourceType,
+			Event:        record.Event,
+			Timestamp:    time.Unix(0, record.Timestamp).Unix(),
+			Changes:      changes,
+			Actor:        record.Actor,
+			Reason:       record.Reason,
+			AccountID:    record.AccountID,
+			PrincipalID:  record.PrincipalID,
+			LeaseID:      record.LeaseID,
+		})
+	}
+	return res
+}
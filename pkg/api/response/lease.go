@@ -37,4 +37,14 @@ type LeaseResponse struct {
 	LeaseStatusModifiedOn    int64                  `json:"leaseStatusModifiedOn"`
 	ExpiresOn                int64                  `json:"expiresOn"`
 	Metadata                 map[string]interface{} `json:"metadata"`
+	PendingExpiresOn         int64                  `json:"pendingExpiresOn,omitempty"`
+	QueuePosition            int                    `json:"queuePosition,omitempty"`
+	AccountPool              string                 `json:"accountPool,omitempty"`
+	AccountLabels            map[string]string      `json:"accountLabels,omitempty"`
+	PolicyProfile            string                 `json:"policyProfile,omitempty"`
+	PolicyStatements         []db.PolicyStatement   `json:"policyStatements,omitempty"`
+	FiredRules               []string               `json:"firedRules,omitempty"`
+	ExpiryNotificationsSent  []float64              `json:"expiryNotificationsSent,omitempty"`
+	BudgetThresholdsSent     []float64              `json:"budgetThresholdsSent,omitempty"`
+	PrincipalThresholdsSent  []float64              `json:"principalThresholdsSent,omitempty"`
 }
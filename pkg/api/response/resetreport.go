@@ -0,0 +1,103 @@
+package response
+
+import (
+	"github.com/Optum/dce/pkg/resetreport"
+)
+
+// ResetReportResponse is the serialized JSON Response for
+// the report of a single account reset run
+// {
+// 	"accountId": "123456789012",
+// 	"runId": "20191106T143000Z-1a2b3c4d",
+// 	"outcome": "Succeeded",
+// 	"resourceCounts": {"found": 2, "deleted": 2, "failed": 0, "filtered": 1},
+// 	"resourcesDeleted": ["us-east-1 - EC2Instance - i-0123"],
+// 	...
+// }
+type ResetReportResponse struct {
+	AccountID          string                      `json:"accountId"`
+	RunID              string                      `json:"runId"`
+	BuildID            string                      `json:"buildId"`
+	StartedOn          int64                       `json:"startedOn"`
+	CompletedOn        int64                       `json:"completedOn"`
+	DurationSeconds    int64                       `json:"durationSeconds"`
+	Outcome            resetreport.Outcome         `json:"outcome"`
+	Error              string                      `json:"error,omitempty"`
+	DryRun             bool                        `json:"dryRun"`
+	NukeAttempts       int                         `json:"nukeAttempts"`
+	Steps              []*ResetStepResponse        `json:"steps"`
+	ResourceCounts     ResetResourceCountsResponse `json:"resourceCounts"`
+	ResourcesFound     []string                    `json:"resourcesFound,omitempty"`
+	ResourcesDeleted   []string                    `json:"resourcesDeleted,omitempty"`
+	ResourcesFailed    []string                    `json:"resourcesFailed,omitempty"`
+	ResourcesFiltered  []string                    `json:"resourcesFiltered,omitempty"`
+	ResourcesTruncated bool                        `json:"resourcesTruncated"`
+}
+
+// ResetStepResponse is the serialized JSON Response for
+// the result of a single reset step
+type ResetStepResponse struct {
+	Name            string `json:"name"`
+	Status          string `json:"status"`
+	ResourceCount   int    `json:"resourceCount"`
+	Error           string `json:"error,omitempty"`
+	DurationSeconds int64  `json:"durationSeconds"`
+}
+
+// ResetResourceCountsResponse is the serialized JSON Response for
+// the number of resources in each state
+type ResetResourceCountsResponse struct {
+	Found    int `json:"found"`
+	Deleted  int `json:"deleted"`
+	Failed   int `json:"failed"`
+	Filtered int `json:"filtered"`
+}
+
+// CreateResetReportResponse creates a Reset Report Response
+// based on the provided reset report
+func CreateResetReportResponse(report *resetreport.Report) *ResetReportResponse {
+	steps := []*ResetStepResponse{}
+	for _, step := range report.Steps {
+		steps = append(steps, &ResetStepResponse{
+			Name:            step.Name,
+			Status:          step.Status,
+			ResourceCount:   step.ResourceCount,
+			Error:           step.Error,
+			DurationSeconds: step.DurationSeconds,
+		})
+	}
+	return &ResetReportResponse{
+		AccountID:       report.AccountID,
+		RunID:           report.RunID,
+		BuildID:         report.BuildID,
+		StartedOn:       report.StartedOn,
+		CompletedOn:     report.CompletedOn,
+		DurationSeconds: report.DurationSeconds,
+		Outcome:         report.Outcome,
+		Error:           report.Error,
+		DryRun:          report.DryRun,
+		NukeAttempts:    report.NukeAttempts,
+		Steps:           steps,
+		ResourceCounts: ResetResourceCountsResponse{
+			Found:    report.ResourceCounts.Found,
+			Deleted:  report.ResourceCounts.Deleted,
+			Failed:   report.ResourceCounts.Failed,
+			Filtered: report.ResourceCounts.Filtered,
+		},
+		ResourcesFound:     report.ResourcesFound,
+		ResourcesDeleted:   report.ResourcesDeleted,
+		ResourcesFailed:    report.ResourcesFailed,
+		ResourcesFiltered:  report.ResourcesFiltered,
+		ResourcesTruncated: report.ResourcesTruncated,
+	}
+}
+
+// CreateResetReportsResponse creates a list of Reset Report Responses
+// based on the provided reset reports
+func CreateResetReportsResponse(reports []*resetreport.Report) []*ResetReportResponse {
+	res := []*ResetReportResponse{}
+	for _, report := range reports {
+		res = append(res, CreateResetReportResponse(report))
+	}
+	return res
+}
@@ -3,11 +3,13 @@ package response
 // UsageResponse is the serialized JSON Response for an account usage
 // to be returned by usage API
 type UsageResponse struct {
-	PrincipalID  string  `json:"principalId"`  // User Principal ID
-	AccountID    string  `json:"accountId"`    // AWS Account ID
-	StartDate    int64   `json:"startDate"`    // Usage start date Epoch Timestamp
-	EndDate      int64   `json:"endDate"`      // Usage ends date Epoch Timestamp
-	CostAmount   float64 `json:"costAmount"`   // Cost Amount for given period
-	CostCurrency string  `json:"costCurrency"` // Cost currency
-	TimeToLive   int64   `json:"timeToLive"`   // ttl attribute
+	PrincipalID           string  `json:"principalId"`                     // User Principal ID
+	AccountID             string  `json:"accountId"`                       // AWS Account ID
+	StartDate             int64   `json:"startDate"`                       // Usage start date Epoch Timestamp
+	EndDate               int64   `json:"endDate"`                         // Usage ends date Epoch Timestamp
+	CostAmount            float64 `json:"costAmount"`                      // Cost Amount for given period, as billed by AWS
+	CostCurrency          string  `json:"costCurrency"`                    // Cost currency, as billed by AWS
+	TimeToLive            int64   `json:"timeToLive"`                      // ttl attribute
+	ConvertedCostAmount   float64 `json:"convertedCostAmount,omitempty"`   // Cost Amount, converted to the lease budget currency
+	ConvertedCostCurrency string  `json:"convertedCostCurrency,omitempty"` // Lease budget currency
 }
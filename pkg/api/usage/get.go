@@ -1,4 +1,4 @@
-package main
+package usage
 
 import (
 	"encoding/json"
@@ -135,6 +135,13 @@ func SumCostAmountByPrincipalID(input []*response.UsageResponse) []*response.Usa
 					log.Printf("item: %v", item)
 					log.Printf("val: %v", val)
 					u[i].CostAmount = u[i].CostAmount + val.CostAmount
+					// Converted amounts may only be summed in the same currency
+					if u[i].ConvertedCostCurrency == val.ConvertedCostCurrency {
+						u[i].ConvertedCostAmount = u[i].ConvertedCostAmount + val.ConvertedCostAmount
+					} else {
+						u[i].ConvertedCostAmount = 0
+						u[i].ConvertedCostCurrency = ""
+					}
 					break
 				}
 
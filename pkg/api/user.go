@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -120,3 +121,18 @@ func (u *UserDetails) isUserInAdminFromList(groups string) bool {
 	}
 	return false
 }
+
+// RequestActor returns the name of the user making an API request,
+// to be recorded in the history of accounts and leases.
+func RequestActor(ctx context.Context, reqContext events.APIGatewayProxyRequestContext) string {
+	if user, ok := ctx.Value(DceCtxKey).(User); ok && user.Username != "" {
+		return user.Username
+	}
+	if reqContext.Identity.UserArn != "" {
+		return reqContext.Identity.UserArn
+	}
+	if reqContext.Identity.User != "" {
+		return reqContext.Identity.User
+	}
+	return "api"
+}
@@ -1,6 +1,7 @@
 package api_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -388,3 +389,36 @@ func TestUser(t *testing.T) {
 		require.Equal(t, user.Role, api.UserGroupName)
 	})
 }
+
+func TestRequestActor(t *testing.T) {
+
+	t.Run("should use the Cognito username", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), api.DceCtxKey, api.User{
+			Username: "testuser",
+			Role:     api.UserGroupName,
+		})
+		actor := api.RequestActor(ctx, events.APIGatewayProxyRequestContext{
+			Identity: events.APIGatewayRequestIdentity{
+				UserArn: "arn:aws:sts::123456789012:assumed-role/Cognito_Auth/CognitoIdentityCredentials",
+			},
+		})
+		require.Equal(t, "testuser", actor)
+	})
+
+	t.Run("should use the IAM user ARN", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), api.DceCtxKey, api.User{
+			Role: api.AdminGroupName,
+		})
+		actor := api.RequestActor(ctx, events.APIGatewayProxyRequestContext{
+			Identity: events.APIGatewayRequestIdentity{
+				UserArn: "arn:aws:iam::123456789012:user/admin",
+			},
+		})
+		require.Equal(t, "arn:aws:iam::123456789012:user/admin", actor)
+	})
+
+	t.Run("should default to api", func(t *testing.T) {
+		actor := api.RequestActor(context.Background(), events.APIGatewayProxyRequestContext{})
+		require.Equal(t, "api", actor)
+	})
+}
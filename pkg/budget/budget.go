@@ -1,7 +1,9 @@
 package budget
 
 import (
+	"fmt"
 	"github.com/Optum/dce/pkg/awsiface"
+	"github.com/Optum/dce/pkg/currency"
 	"strconv"
 	"time"
 
@@ -13,7 +15,9 @@ import (
 // (eg, if I'm testing a Lambda controller that uses this Service)
 //go:generate mockery -name Service
 type Service interface {
-	CalculateTotalSpend(startDate time.Time, endDate time.Time) (float64, error)
+	// CalculateTotalSpend returns the total spend for the date range,
+	// and the currency of the spend
+	CalculateTotalSpend(startDate time.Time, endDate time.Time) (float64, string, error)
 	SetCostExplorer(costExplorer awsiface.CostExplorerAPI)
 }
 
@@ -26,8 +30,10 @@ func (budgetSvc *AWSBudgetService) SetCostExplorer(costExplorer awsiface.CostExp
 	budgetSvc.CostExplorer = costExplorer
 }
 
-// Implement the CalculateTotalSpend method of the Service interface
-func (budgetSvc *AWSBudgetService) CalculateTotalSpend(startDate time.Time, endDate time.Time) (float64, error) {
+// Implement the CalculateTotalSpend method of the Service interface.
+// Fails if CostExplorer reports costs in more than one currency,
+// as they cannot be summed.
+func (budgetSvc *AWSBudgetService) CalculateTotalSpend(startDate time.Time, endDate time.Time) (float64, string, error) {
 
 	// CostExplorer uses strings for dates, in the format
 	// of "2017-01-01"
@@ -50,19 +56,32 @@ func (budgetSvc *AWSBudgetService) CalculateTotalSpend(startDate time.Time, endD
 
 	output, err := budgetSvc.CostExplorer.GetCostAndUsage(&getCostAndUsageInput)
 	if err != nil {
-		return 0, err
+		return 0, "", err
 	}
 
 	var totalCost float64
+	costCurrency := ""
 
 	for _, result := range output.ResultsByTime {
 		cost, err := strconv.ParseFloat(*result.Total["UnblendedCost"].Amount, 64)
 		if err != nil {
-			return 0, err
+			return 0, "", err
 		}
 
+		unit := aws.StringValue(result.Total["UnblendedCost"].Unit)
+		if costCurrency != "" && unit != costCurrency {
+			return 0, "", fmt.Errorf("Unable to sum costs in different currencies: %s and %s", costCurrency, unit)
+		}
+		costCurrency = unit
+
 		totalCost = totalCost + cost
 
 	}
-	return totalCost, nil
+
+	// CostExplorer reports costs in USD, unless the
+	// account is billed in another currency
+	if costCurrency == "" {
+		costCurrency = currency.USD
+	}
+	return totalCost, costCurrency, nil
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/costexplorer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestCalculateTotalSpend(t *testing.T) {
@@ -44,10 +45,45 @@ func TestCalculateTotalSpend(t *testing.T) {
 	budgetSvc := AWSBudgetService{
 		CostExplorer: costExplorer,
 	}
-	cost, err := budgetSvc.CalculateTotalSpend(
+	cost, costCurrency, err := budgetSvc.CalculateTotalSpend(
 		time.Unix(0, 0),
 		time.Unix(0, 0).Add(time.Hour*24),
 	)
 	assert.Nil(t, err, "There should be no errors")
 	assert.Equal(t, cost, float64(150))
+	assert.Equal(t, "USD", costCurrency)
+}
+
+func TestCalculateTotalSpendInDifferentCurrencies(t *testing.T) {
+	// Mock the CostExplorer SDK, to return costs in two currencies
+	costExplorer := &mocks.CostExplorerAPI{}
+	costExplorer.On("GetCostAndUsage", mock.Anything).Return(&costexplorer.GetCostAndUsageOutput{
+		ResultsByTime: []*costexplorer.ResultByTime{
+			{
+				Total: map[string]*costexplorer.MetricValue{
+					"UnblendedCost": {
+						Amount: aws.String("100"),
+						Unit:   aws.String("USD"),
+					},
+				},
+			},
+			{
+				Total: map[string]*costexplorer.MetricValue{
+					"UnblendedCost": {
+						Amount: aws.String("50"),
+						Unit:   aws.String("EUR"),
+					},
+				},
+			},
+		},
+	}, nil)
+
+	budgetSvc := AWSBudgetService{
+		CostExplorer: costExplorer,
+	}
+	_, _, err := budgetSvc.CalculateTotalSpend(
+		time.Unix(0, 0),
+		time.Unix(0, 0).Add(time.Hour*24),
+	)
+	assert.EqualError(t, err, "Unable to sum costs in different currencies: USD and EUR")
 }
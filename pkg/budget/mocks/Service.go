@@ -13,7 +13,7 @@ type Service struct {
 }
 
 // CalculateTotalSpend provides a mock function with given fields: startDate, endDate
-func (_m *Service) CalculateTotalSpend(startDate time.Time, endDate time.Time) (float64, error) {
+func (_m *Service) CalculateTotalSpend(startDate time.Time, endDate time.Time) (float64, string, error) {
 	ret := _m.Called(startDate, endDate)
 
 	var r0 float64
@@ -23,14 +23,21 @@ func (_m *Service) CalculateTotalSpend(startDate time.Time, endDate time.Time) (
 		r0 = ret.Get(0).(float64)
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(time.Time, time.Time) error); ok {
+	var r1 string
+	if rf, ok := ret.Get(1).(func(time.Time, time.Time) string); ok {
 		r1 = rf(startDate, endDate)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(string)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(time.Time, time.Time) error); ok {
+		r2 = rf(startDate, endDate)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // SetCostExplorer provides a mock function with given fields: costExplorer
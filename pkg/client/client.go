@@ -0,0 +1,417 @@
+// Package client is a typed client for the DCE API.
+//
+// Responses are returned as the same `pkg/api/response` types
+// which are served by the API.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// Client sends requests to the DCE API
+type Client struct {
+	// BaseURL is the URL of the DCE API,
+	// eg. https://abc123.execute-api.us-east-1.amazonaws.com/api
+	BaseURL    string
+	HTTPClient *http.Client
+	// Credentials are used to sign requests with AWS Signature Version 4,
+	// as required by API Gateway. Requests are not signed if nil.
+	Credentials *credentials.Credentials
+	// Region is the AWS region of the API Gateway
+	Region string
+}
+
+// New creates a client for the DCE API at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// APIError is returned for API responses with an error status code
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("DCE API request failed with status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("DCE API request failed with status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// CreateAccountInput is the request body for `POST /accounts`
+type CreateAccountInput struct {
+	ID           string                 `json:"id"`
+	AdminRoleArn string                 `json:"adminRoleArn"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	Pool         string                 `json:"pool,omitempty"`
+	Labels       map[string]string      `json:"labels,omitempty"`
+	NukeConfig   *db.NukeConfig         `json:"nukeConfig,omitempty"`
+}
+
+// ListAccountsInput filters the accounts returned by `GET /accounts`
+type ListAccountsInput struct {
+	AccountStatus string
+	Pool          string
+}
+
+// CreateLeaseInput is the request body for `POST /leases`
+type CreateLeaseInput struct {
+	PrincipalID              string                 `json:"principalId"`
+	BudgetAmount             float64                `json:"budgetAmount"`
+	BudgetCurrency           string                 `json:"budgetCurrency"`
+	BudgetNotificationEmails []string               `json:"budgetNotificationEmails"`
+	ExpiresOn                int64                  `json:"expiresOn,omitempty"`
+	Metadata                 map[string]interface{} `json:"metadata,omitempty"`
+	Waitlist                 bool                   `json:"waitlist,omitempty"`
+	Pool                     string                 `json:"pool,omitempty"`
+	Labels                   map[string]string      `json:"labels,omitempty"`
+	PolicyProfile            string                 `json:"policyProfile,omitempty"`
+	PolicyStatements         []db.PolicyStatement   `json:"policyStatements,omitempty"`
+}
+
+// ListLeasesInput filters the leases returned by `GET /leases`
+type ListLeasesInput struct {
+	PrincipalID string
+	AccountID   string
+	Status      string
+	// Limit is the page size. All pages are returned.
+	Limit int64
+}
+
+// ResetAccountInput is the query for `POST /accounts/{id}/reset`
+type ResetAccountInput struct {
+	// Force ends the lease of a Leased account, to reset it
+	Force bool
+}
+
+// GetUsageInput filters the usage returned by `GET /usage`
+type GetUsageInput struct {
+	StartDate   time.Time
+	EndDate     time.Time
+	PrincipalID string
+}
+
+// ListAccounts returns all accounts matching the input
+func (c *Client) ListAccounts(input ListAccountsInput) ([]*response.AccountResponse, error) {
+	query := url.Values{}
+	if input.AccountStatus != "" {
+		query.Set("accountStatus", input.AccountStatus)
+	}
+	if input.Pool != "" {
+		query.Set("pool", input.Pool)
+	}
+
+	accounts := []*response.AccountResponse{}
+	_, err := c.do(http.MethodGet, "/accounts", query, nil, &accounts)
+	return accounts, err
+}
+
+// GetAccount returns the account with the given ID
+func (c *Client) GetAccount(id string) (*response.AccountResponse, error) {
+	account := &response.AccountResponse{}
+	_, err := c.do(http.MethodGet, "/accounts/"+url.PathEscape(id), nil, nil, account)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// CreateAccount adds an account to the account pool
+func (c *Client) CreateAccount(input CreateAccountInput) (*response.AccountResponse, error) {
+	account := &response.AccountResponse{}
+	_, err := c.do(http.MethodPost, "/accounts", nil, input, account)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// DeleteAccount removes an account from the account pool
+func (c *Client) DeleteAccount(id string) error {
+	_, err := c.do(http.MethodDelete, "/accounts/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+// ResetAccount adds the account to the reset queue.
+// Leased accounts are only reset with Force, which ends the lease.
+func (c *Client) ResetAccount(id string, input ResetAccountInput) (*response.AccountResponse, error) {
+	query := url.Values{}
+	if input.Force {
+		query.Set("force", "true")
+	}
+	account := &response.AccountResponse{}
+	_, err := c.do(http.MethodPost, "/accounts/"+url.PathEscape(id)+"/reset", query, nil, account)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// PreviewAccountReset starts a dry run reset of the account.
+// The report is returned with the `Running` outcome, and lists the
+// resources which a reset would delete once the preview completes.
+// See GetAccountReset.
+func (c *Client) PreviewAccountReset(id string) (*response.ResetReportResponse, error) {
+	query := url.Values{}
+	query.Set("dryRun", "true")
+	report := &response.ResetReportResponse{}
+	_, err := c.do(http.MethodPost, "/accounts/"+url.PathEscape(id)+"/reset", query, nil, report)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ListAccountResets returns a summary of each reset of the account,
+// newest first
+func (c *Client) ListAccountResets(id string) ([]*response.ResetReportResponse, error) {
+	reports := []*response.ResetReportResponse{}
+	_, err := c.do(http.MethodGet, "/accounts/"+url.PathEscape(id)+"/resets", nil, nil, &reports)
+	return reports, err
+}
+
+// GetAccountReset returns the report of a reset of the account,
+// including resources
+func (c *Client) GetAccountReset(id string, runID string) (*response.ResetReportResponse, error) {
+	report := &response.ResetReportResponse{}
+	_, err := c.do(http.MethodGet, "/accounts/"+url.PathEscape(id)+"/resets/"+url.PathEscape(runID), nil, nil, report)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ListResetDeadLetters returns the reset requests which failed
+// too many times, and were moved to the reset dead-letter queue
+func (c *Client) ListResetDeadLetters() ([]*response.ResetDeadLetterResponse, error) {
+	letters := []*response.ResetDeadLetterResponse{}
+	_, err := c.do(http.MethodGet, "/accounts/resets/deadletters", nil, nil, &letters)
+	return letters, err
+}
+
+// GetResetDeadLetter returns a dead-lettered reset request
+func (c *Client) GetResetDeadLetter(id string) (*response.ResetDeadLetterResponse, error) {
+	letter := &response.ResetDeadLetterResponse{}
+	_, err := c.do(http.MethodGet, "/accounts/resets/deadletters/"+url.PathEscape(id), nil, nil, letter)
+	if err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// ReplayResetDeadLetter sends a dead-lettered reset request
+// back to the reset queue
+func (c *Client) ReplayResetDeadLetter(id string) (*response.ResetDeadLetterResponse, error) {
+	letter := &response.ResetDeadLetterResponse{}
+	_, err := c.do(http.MethodPost, "/accounts/resets/deadletters/"+url.PathEscape(id)+"/replay", nil, nil, letter)
+	if err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+// GetLease returns the lease with the given ID
+func (c *Client) GetLease(id string) (*response.LeaseResponse, error) {
+	lease := &response.LeaseResponse{}
+	_, err := c.do(http.MethodGet, "/leases/"+url.PathEscape(id), nil, nil, lease)
+	if err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// nextLinkRegex matches the URL of the next page in a `Link` header
+var nextLinkRegex = regexp.MustCompile(`<([^>]*)>;\s*rel="next"`)
+
+// ListLeases returns all leases matching the input,
+// following the `Link` header of each page
+func (c *Client) ListLeases(input ListLeasesInput) ([]*response.LeaseResponse, error) {
+	query := url.Values{}
+	if input.PrincipalID != "" {
+		query.Set("principalId", input.PrincipalID)
+	}
+	if input.AccountID != "" {
+		query.Set("accountId", input.AccountID)
+	}
+	if input.Status != "" {
+		query.Set("status", input.Status)
+	}
+	if input.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(input.Limit, 10))
+	}
+
+	leases := []*response.LeaseResponse{}
+	for {
+		page := []*response.LeaseResponse{}
+		res, err := c.do(http.MethodGet, "/leases", query, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		leases = append(leases, page...)
+
+		match := nextLinkRegex.FindStringSubmatch(res.Header.Get("Link"))
+		if match == nil {
+			return leases, nil
+		}
+		// The next page is requested from the client's base URL,
+		// as the API builds the link from the API Gateway host
+		nextURL, err := url.Parse(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid next page link %q: %s", match[1], err)
+		}
+		query = nextURL.Query()
+	}
+}
+
+// CreateLease leases an account to a principal.
+// Waitlisted leases are returned with a `Pending` status.
+func (c *Client) CreateLease(input CreateLeaseInput) (*response.LeaseResponse, error) {
+	lease := &response.LeaseResponse{}
+	_, err := c.do(http.MethodPost, "/leases", nil, input, lease)
+	if err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// DestroyLease ends the principal's lease of an account
+func (c *Client) DestroyLease(principalID string, accountID string) (*response.LeaseResponse, error) {
+	body := map[string]string{
+		"principalId": principalID,
+		"accountId":   accountID,
+	}
+	lease := &response.LeaseResponse{}
+	_, err := c.do(http.MethodDelete, "/leases", nil, body, lease)
+	if err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// GetLeaseAuth returns credentials and a console URL
+// for the account of an active lease
+func (c *Client) GetLeaseAuth(leaseID string) (*response.LeaseAuthResponse, error) {
+	auth := &response.LeaseAuthResponse{}
+	_, err := c.do(http.MethodPost, "/leases/"+url.PathEscape(leaseID)+"/auth", nil, nil, auth)
+	if err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+// GetUsage returns usage records between the start and end dates,
+// or from the start date for a principal
+func (c *Client) GetUsage(input GetUsageInput) ([]*response.UsageResponse, error) {
+	query := url.Values{}
+	query.Set("startDate", strconv.FormatInt(input.StartDate.Unix(), 10))
+	if input.PrincipalID != "" {
+		query.Set("principalId", input.PrincipalID)
+	} else {
+		query.Set("endDate", strconv.FormatInt(input.EndDate.Unix(), 10))
+	}
+
+	usage := []*response.UsageResponse{}
+	_, err := c.do(http.MethodGet, "/usage", query, nil, &usage)
+	return usage, err
+}
+
+// do sends a request to the API, and decodes the JSON response into out.
+// An *APIError is returned for error responses.
+func (c *Client) do(method string, path string, query url.Values, in interface{}, out interface{}) (*http.Response, error) {
+	reqURL := c.BaseURL + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to serialize request: %s", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	if c.Credentials != nil {
+		signer := v4.NewSigner(c.Credentials)
+		_, err = signer.Sign(req, bytes.NewReader(body), "execute-api", c.Region, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("Failed to sign request: %s", err)
+		}
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read response: %s", err)
+	}
+
+	if res.StatusCode >= 300 {
+		return nil, newAPIError(res.StatusCode, resBody)
+	}
+
+	if out != nil && len(bytes.TrimSpace(resBody)) > 0 {
+		err = json.NewDecoder(bytes.NewReader(resBody)).Decode(out)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("Failed to parse response: %s", err)
+		}
+	}
+
+	return res, nil
+}
+
+// newAPIError creates an APIError from an error response body
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+
+	errRes := response.ErrorResponse{}
+	if json.Unmarshal(body, &errRes) == nil && errRes.Error.Message != "" {
+		apiErr.Code = errRes.Error.Code
+		apiErr.Message = errRes.Error.Message
+		return apiErr
+	}
+
+	apiErr.Message = strings.TrimSpace(string(body))
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(statusCode)
+	}
+	return apiErr
+}
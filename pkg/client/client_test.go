@@ -0,0 +1,170 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+
+	t.Run("ListLeases", func(t *testing.T) {
+
+		t.Run("should follow next page links", func(t *testing.T) {
+			var queries []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/api/leases", r.URL.Path)
+				queries = append(queries, r.URL.RawQuery)
+
+				if r.URL.Query().Get("nextPrincipalId") == "" {
+					// The API builds links from the API Gateway host
+					w.Header().Set("Link", `<https://example.com/api/leases?principalId=jdoe&nextAccountId=222&nextPrincipalId=jdoe>; rel="next"`)
+					json.NewEncoder(w).Encode([]response.LeaseResponse{{ID: "lease-1"}})
+					return
+				}
+				json.NewEncoder(w).Encode([]response.LeaseResponse{{ID: "lease-2"}})
+			}))
+			defer server.Close()
+
+			leases, err := New(server.URL + "/api/").ListLeases(ListLeasesInput{PrincipalID: "jdoe"})
+			require.Nil(t, err)
+			require.Len(t, leases, 2)
+			require.Equal(t, "lease-1", leases[0].ID)
+			require.Equal(t, "lease-2", leases[1].ID)
+			require.Equal(t, []string{
+				"principalId=jdoe",
+				"nextAccountId=222&nextPrincipalId=jdoe&principalId=jdoe",
+			}, queries)
+		})
+
+	})
+
+	t.Run("CreateLease", func(t *testing.T) {
+
+		t.Run("should send the lease request", func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "/leases", r.URL.Path)
+				require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+				body, err := ioutil.ReadAll(r.Body)
+				require.Nil(t, err)
+				require.JSONEq(t, `{
+					"principalId": "jdoe",
+					"budgetAmount": 100,
+					"budgetCurrency": "USD",
+					"budgetNotificationEmails": ["jdoe@example.com"],
+					"waitlist": true
+				}`, string(body))
+
+				w.WriteHeader(http.StatusAccepted)
+				json.NewEncoder(w).Encode(response.LeaseResponse{
+					ID:          "lease-1",
+					PrincipalID: "jdoe",
+					LeaseStatus: db.Pending,
+				})
+			}))
+			defer server.Close()
+
+			lease, err := New(server.URL).CreateLease(CreateLeaseInput{
+				PrincipalID:              "jdoe",
+				BudgetAmount:             100,
+				BudgetCurrency:           "USD",
+				BudgetNotificationEmails: []string{"jdoe@example.com"},
+				Waitlist:                 true,
+			})
+			require.Nil(t, err)
+			require.Equal(t, "lease-1", lease.ID)
+			require.Equal(t, db.Pending, lease.LeaseStatus)
+		})
+
+		t.Run("should return API errors", func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(response.CreateErrorResponse("ClientError", "Invalid request"))
+			}))
+			defer server.Close()
+
+			_, err := New(server.URL).CreateLease(CreateLeaseInput{PrincipalID: "jdoe"})
+			require.Equal(t, &APIError{
+				StatusCode: http.StatusBadRequest,
+				Code:       "ClientError",
+				Message:    "Invalid request",
+			}, err)
+		})
+
+	})
+
+	t.Run("DeleteAccount", func(t *testing.T) {
+
+		t.Run("should accept empty responses", func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodDelete, r.Method)
+				require.Equal(t, "/accounts/123456789012", r.URL.Path)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			require.Nil(t, New(server.URL).DeleteAccount("123456789012"))
+		})
+
+		t.Run("should return non-JSON errors", func(t *testing.T) {
+			server := httptest.NewServer(http.NotFoundHandler())
+			defer server.Close()
+
+			err := New(server.URL).DeleteAccount("123456789012")
+			require.Equal(t, &APIError{
+				StatusCode: http.StatusNotFound,
+				Message:    "404 page not found",
+			}, err)
+		})
+
+	})
+
+	t.Run("GetUsage", func(t *testing.T) {
+
+		t.Run("should query by date range", func(t *testing.T) {
+			startDate := time.Unix(1570000000, 0)
+			endDate := time.Unix(1570086400, 0)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/usage", r.URL.Path)
+				require.Equal(t, fmt.Sprint(startDate.Unix()), r.URL.Query().Get("startDate"))
+				require.Equal(t, fmt.Sprint(endDate.Unix()), r.URL.Query().Get("endDate"))
+				json.NewEncoder(w).Encode([]response.UsageResponse{{PrincipalID: "jdoe", CostAmount: 12}})
+			}))
+			defer server.Close()
+
+			usage, err := New(server.URL).GetUsage(GetUsageInput{StartDate: startDate, EndDate: endDate})
+			require.Nil(t, err)
+			require.Len(t, usage, 1)
+			require.Equal(t, float64(12), usage[0].CostAmount)
+		})
+
+	})
+
+	t.Run("should sign requests when credentials are set", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Contains(t, r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/")
+			require.Contains(t, r.Header.Get("Authorization"), "/us-east-1/execute-api/aws4_request")
+			require.Equal(t, "token", r.Header.Get("X-Amz-Security-Token"))
+			json.NewEncoder(w).Encode(response.LeaseAuthResponse{AccessKeyID: "lease-key"})
+		}))
+		defer server.Close()
+
+		c := New(server.URL)
+		c.Credentials = credentials.NewStaticCredentials("AKID", "SECRET", "token")
+		c.Region = "us-east-1"
+		auth, err := c.GetLeaseAuth("lease-1")
+		require.Nil(t, err)
+		require.Equal(t, "lease-key", auth.AccessKeyID)
+	})
+}
@@ -0,0 +1,190 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// DeadLetter is a message which could not be processed,
+// and was moved to a dead-letter queue
+type DeadLetter struct {
+	ID             string `json:"-"`              // ID of the message in the dead-letter queue
+	Body           string `json:"Body"`           // Body of the original message
+	Error          string `json:"Error"`          // Error from the last attempt to process the message
+	ReceiveCount   int    `json:"ReceiveCount"`   // Number of times the original message was received
+	DeadLetteredOn int64  `json:"DeadLetteredOn"` // Epoch timestamp
+}
+
+// DeadLetterQueue stores messages which could not be processed
+// in a Queue, so that they may be inspected and replayed.
+type DeadLetterQueue struct {
+	Queue Queue
+	URL   string
+}
+
+// maxDeadLetterReceives is the most ReceiveMessage requests
+// made to list the dead-letter queue
+const maxDeadLetterReceives = 10
+
+// replayVisibilityTimeout is the number of seconds for which messages
+// received by Replay are hidden, while the message is replayed
+const replayVisibilityTimeout = 30
+
+// Send adds a message to the dead-letter queue
+func (q *DeadLetterQueue) Send(letter *DeadLetter) error {
+	body, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	return q.Queue.SendMessage(&q.URL, aws.String(string(body)))
+}
+
+// List returns the messages in the dead-letter queue, oldest first.
+//
+// Messages are received without hiding them from other consumers,
+// and SQS may not return every message: very large dead-letter queues
+// may return only the first 100 or so messages.
+func (q *DeadLetterQueue) List() ([]*DeadLetter, error) {
+	letters := []*DeadLetter{}
+	seen := map[string]bool{}
+	for i := 0; i < maxDeadLetterReceives; i++ {
+		res, err := q.Queue.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            &q.URL,
+			MaxNumberOfMessages: aws.Int64(10),
+			VisibilityTimeout:   aws.Int64(0),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		found := 0
+		for _, message := range res.Messages {
+			id := aws.StringValue(message.MessageId)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			found++
+			letters = append(letters, newDeadLetter(message))
+		}
+		if found == 0 {
+			break
+		}
+	}
+
+	sort.SliceStable(letters, func(i, j int) bool {
+		return letters[i].DeadLetteredOn < letters[j].DeadLetteredOn
+	})
+	return letters, nil
+}
+
+// Get returns a message from the dead-letter queue,
+// or nil if the message is not found
+func (q *DeadLetterQueue) Get(id string) (*DeadLetter, error) {
+	letters, err := q.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, letter := range letters {
+		if letter.ID == id {
+			return letter, nil
+		}
+	}
+	return nil, nil
+}
+
+// Replay sends the body of a dead-lettered message back to the
+// queue at queueURL, and removes it from the dead-letter queue.
+// Returns nil if the message is not found.
+//
+// Replay receives the message itself, and hides it while it is replayed,
+// so that it is deleted with the receipt handle of the latest receive.
+func (q *DeadLetterQueue) Replay(id string, queueURL string) (*DeadLetter, error) {
+	message, err := q.receive(id)
+	if err != nil || message == nil {
+		return nil, err
+	}
+	letter := newDeadLetter(message)
+
+	err = q.Queue.SendMessage(&queueURL, &letter.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to replay dead letter %s: %s", id, err)
+	}
+	_, err = q.Queue.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      &q.URL,
+		ReceiptHandle: message.ReceiptHandle,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Replayed dead letter %s, but failed to remove it from the dead-letter queue: %s", id, err)
+	}
+	return letter, nil
+}
+
+// receive receives messages from the dead-letter queue until it finds
+// the message with the given ID, and returns it, or nil if it is not found.
+// Received messages are hidden, so each receive returns new messages.
+// Other messages are made visible again, once the message is found.
+func (q *DeadLetterQueue) receive(id string) (*sqs.Message, error) {
+	others := []*sqs.Message{}
+	defer func() { q.release(others) }()
+
+	for i := 0; i < maxDeadLetterReceives; i++ {
+		res, err := q.Queue.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            &q.URL,
+			MaxNumberOfMessages: aws.Int64(10),
+			VisibilityTimeout:   aws.Int64(replayVisibilityTimeout),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(res.Messages) == 0 {
+			break
+		}
+
+		var found *sqs.Message
+		for _, message := range res.Messages {
+			if aws.StringValue(message.MessageId) == id {
+				found = message
+			} else {
+				others = append(others, message)
+			}
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+	return nil, nil
+}
+
+// release makes received messages visible again
+func (q *DeadLetterQueue) release(messages []*sqs.Message) {
+	for _, message := range messages {
+		_, err := q.Queue.ChangeMessageVisibility(&sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          &q.URL,
+			ReceiptHandle:     message.ReceiptHandle,
+			VisibilityTimeout: aws.Int64(0),
+		})
+		if err != nil {
+			log.Printf("Failed to release dead letter %s: %s", aws.StringValue(message.MessageId), err)
+		}
+	}
+}
+
+// newDeadLetter parses a message from the dead-letter queue
+func newDeadLetter(message *sqs.Message) *DeadLetter {
+	id := aws.StringValue(message.MessageId)
+	letter := &DeadLetter{}
+	err := json.Unmarshal([]byte(aws.StringValue(message.Body)), letter)
+	if err != nil {
+		// Keep messages which were not sent by DeadLetterQueue.Send
+		log.Printf("Dead letter %s is not valid JSON: %s", id, err)
+		letter = &DeadLetter{Body: aws.StringValue(message.Body)}
+	}
+	letter.ID = id
+	return letter
+}
@@ -0,0 +1,71 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterQueue(t *testing.T) {
+	queue := NewMemoryQueue()
+	dlq := &DeadLetterQueue{Queue: queue, URL: "dlq"}
+
+	require.Nil(t, dlq.Send(&DeadLetter{Body: "222", Error: "bad role", ReceiveCount: 5, DeadLetteredOn: 200}))
+	require.Nil(t, dlq.Send(&DeadLetter{Body: "111", Error: "not found", ReceiveCount: 5, DeadLetteredOn: 100}))
+	require.Nil(t, queue.SendMessage(aws.String("dlq"), aws.String("333")))
+
+	t.Run("List should return all messages, oldest first", func(t *testing.T) {
+		letters, err := dlq.List()
+		require.Nil(t, err)
+		require.Len(t, letters, 3)
+
+		require.Equal(t, "333", letters[0].Body)
+		require.Equal(t, "111", letters[1].Body)
+		require.Equal(t, "not found", letters[1].Error)
+		require.Equal(t, 5, letters[1].ReceiveCount)
+		require.Equal(t, "222", letters[2].Body)
+		require.NotEmpty(t, letters[2].ID)
+	})
+
+	t.Run("Get should return nil for missing messages", func(t *testing.T) {
+		letter, err := dlq.Get("missing")
+		require.Nil(t, err)
+		require.Nil(t, letter)
+	})
+
+	t.Run("Replay should send the message back to the queue", func(t *testing.T) {
+		letters, err := dlq.List()
+		require.Nil(t, err)
+
+		letter, err := dlq.Replay(letters[2].ID, "reset-queue")
+		require.Nil(t, err)
+		require.Equal(t, "222", letter.Body)
+
+		require.Equal(t, []string{"222"}, queue.Messages("reset-queue"))
+		letters, err = dlq.List()
+		require.Nil(t, err)
+		require.Len(t, letters, 2)
+
+		letter, err = dlq.Replay("missing", "reset-queue")
+		require.Nil(t, err)
+		require.Nil(t, letter)
+	})
+
+	t.Run("Replay should delete messages which were received more than once", func(t *testing.T) {
+		queue := NewMemoryQueue()
+		dlq := &DeadLetterQueue{Queue: queue, URL: "dlq"}
+		require.Nil(t, dlq.Send(&DeadLetter{Body: "111", DeadLetteredOn: 100}))
+
+		// Each List receives the message again, with a new receipt handle
+		letters, err := dlq.List()
+		require.Nil(t, err)
+		_, err = dlq.List()
+		require.Nil(t, err)
+
+		letter, err := dlq.Replay(letters[0].ID, "reset-queue")
+		require.Nil(t, err)
+		require.Equal(t, "111", letter.Body)
+		require.Empty(t, queue.Messages("dlq"))
+	})
+}
@@ -0,0 +1,231 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// MemoryQueue is an in-memory implementation of the Queue interface,
+// for local runs and tests.
+//
+// Messages are kept per queue URL, and are removed from the queue
+// once they are deleted. Received messages are only hidden if they are
+// received with a VisibilityTimeout. As in SQS, each receive returns
+// a new receipt handle, and only the latest one deletes the message.
+type MemoryQueue struct {
+	mu       sync.Mutex
+	messages map[string][]*sqs.Message
+	// visibleOn is the time delayed or hidden messages
+	// may be received, by message ID
+	visibleOn     map[string]time.Time
+	receiveCounts map[string]int
+	nextID        int
+}
+
+// NewMemoryQueue creates an empty MemoryQueue
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		messages:      map[string][]*sqs.Message{},
+		visibleOn:     map[string]time.Time{},
+		receiveCounts: map[string]int{},
+	}
+}
+
+// SendMessage adds a message to the queue
+func (queue *MemoryQueue) SendMessage(queueURL *string, message *string) error {
+	return queue.SendMessageWithDelay(queueURL, message, 0)
+}
+
+// SendMessageWithDelay adds a message to the queue,
+// which may not be received for delaySeconds
+func (queue *MemoryQueue) SendMessageWithDelay(queueURL *string, message *string, delaySeconds int64) error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	queue.nextID++
+	id := fmt.Sprintf("%d", queue.nextID)
+	queue.messages[*queueURL] = append(queue.messages[*queueURL], &sqs.Message{
+		MessageId:     aws.String(id),
+		ReceiptHandle: aws.String(id),
+		Body:          aws.String(*message),
+	})
+	if delaySeconds > 0 {
+		queue.visibleOn[id] = time.Now().Add(time.Duration(delaySeconds) * time.Second)
+	}
+	return nil
+}
+
+// ReceiveMessage returns up to MaxNumberOfMessages messages from the queue.
+// Messages remain in the queue until they are deleted, and are hidden
+// for the VisibilityTimeout, if it is set.
+func (queue *MemoryQueue) ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	max := int(aws.Int64Value(input.MaxNumberOfMessages))
+	if max <= 0 {
+		max = 1
+	}
+
+	output := &sqs.ReceiveMessageOutput{Messages: []*sqs.Message{}}
+	for _, message := range queue.messages[aws.StringValue(input.QueueUrl)] {
+		if len(output.Messages) == max {
+			break
+		}
+		// Skip delayed and hidden messages
+		if visibleOn, ok := queue.visibleOn[*message.MessageId]; ok && time.Now().Before(visibleOn) {
+			continue
+		}
+		// Count receives, like SQS's ApproximateReceiveCount
+		queue.receiveCounts[*message.MessageId]++
+		message.ReceiptHandle = aws.String(fmt.Sprintf("%s-%d",
+			*message.MessageId, queue.receiveCounts[*message.MessageId]))
+		if timeout := aws.Int64Value(input.VisibilityTimeout); timeout > 0 {
+			queue.visibleOn[*message.MessageId] = time.Now().Add(time.Duration(timeout) * time.Second)
+		}
+		msg := *message
+		msg.Attributes = map[string]*string{
+			sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String(
+				strconv.Itoa(queue.receiveCounts[*message.MessageId])),
+		}
+		output.Messages = append(output.Messages, &msg)
+	}
+	return output, nil
+}
+
+// DeleteMessage removes a message from the queue
+func (queue *MemoryQueue) DeleteMessage(input *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	queueURL := aws.StringValue(input.QueueUrl)
+	messages := queue.messages[queueURL]
+	for i, message := range messages {
+		if *message.ReceiptHandle == aws.StringValue(input.ReceiptHandle) {
+			queue.messages[queueURL] = append(messages[:i:i], messages[i+1:]...)
+			delete(queue.visibleOn, *message.MessageId)
+			delete(queue.receiveCounts, *message.MessageId)
+			break
+		}
+	}
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// ChangeMessageVisibility hides a received message for the VisibilityTimeout,
+// or makes it visible again, if the timeout is zero
+func (queue *MemoryQueue) ChangeMessageVisibility(input *sqs.ChangeMessageVisibilityInput) (*sqs.ChangeMessageVisibilityOutput, error) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	for _, message := range queue.messages[aws.StringValue(input.QueueUrl)] {
+		if *message.ReceiptHandle != aws.StringValue(input.ReceiptHandle) {
+			continue
+		}
+		timeout := aws.Int64Value(input.VisibilityTimeout)
+		if timeout > 0 {
+			queue.visibleOn[*message.MessageId] = time.Now().Add(time.Duration(timeout) * time.Second)
+		} else {
+			delete(queue.visibleOn, *message.MessageId)
+		}
+		return &sqs.ChangeMessageVisibilityOutput{}, nil
+	}
+	return nil, fmt.Errorf("Receipt handle %s is not valid", aws.StringValue(input.ReceiptHandle))
+}
+
+// NewFromEnv is a no-op, as the MemoryQueue has no configuration
+func (queue *MemoryQueue) NewFromEnv() error {
+	return nil
+}
+
+// Messages returns the bodies of all messages in the queue
+func (queue *MemoryQueue) Messages(queueURL string) []string {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	bodies := []string{}
+	for _, message := range queue.messages[queueURL] {
+		bodies = append(bodies, *message.Body)
+	}
+	return bodies
+}
+
+// MemoryNotifier is an in-memory implementation of the Notificationer interface,
+// for local runs and tests. Published messages are kept per topic.
+type MemoryNotifier struct {
+	mu       sync.Mutex
+	messages map[string][]string
+}
+
+// NewMemoryNotifier creates an empty MemoryNotifier
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{
+		messages: map[string][]string{},
+	}
+}
+
+// PublishMessage records a message published to the topic
+func (notifier *MemoryNotifier) PublishMessage(topicArn *string, message *string, isJSON bool) (*string, error) {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	notifier.messages[*topicArn] = append(notifier.messages[*topicArn], *message)
+	messageID := fmt.Sprintf("%d", len(notifier.messages[*topicArn]))
+	return &messageID, nil
+}
+
+// Messages returns all messages published to the topic
+func (notifier *MemoryNotifier) Messages(topicArn string) []string {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	return append([]string{}, notifier.messages[topicArn]...)
+}
+
+// MemoryBuilder is an in-memory implementation of the Builder interface,
+// for local runs and tests. Builds are recorded, but not run.
+type MemoryBuilder struct {
+	mu     sync.Mutex
+	builds []MemoryBuild
+}
+
+// MemoryBuild is a build started by a MemoryBuilder
+type MemoryBuild struct {
+	ID                   string
+	ProjectName          string
+	EnvironmentVariables map[string]string
+}
+
+// NewMemoryBuilder creates a MemoryBuilder with no builds
+func NewMemoryBuilder() *MemoryBuilder {
+	return &MemoryBuilder{}
+}
+
+// StartBuild records the build, and returns its ID
+func (builder *MemoryBuilder) StartBuild(projectName *string, environmentVariables map[string]string) (string, error) {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+
+	build := MemoryBuild{
+		ID:                   fmt.Sprintf("%s:%d", aws.StringValue(projectName), len(builder.builds)+1),
+		ProjectName:          aws.StringValue(projectName),
+		EnvironmentVariables: map[string]string{},
+	}
+	for key, val := range environmentVariables {
+		build.EnvironmentVariables[key] = val
+	}
+	builder.builds = append(builder.builds, build)
+	return build.ID, nil
+}
+
+// Builds returns all started builds, in the order they were started
+func (builder *MemoryBuilder) Builds() []MemoryBuild {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+
+	return append([]MemoryBuild{}, builder.builds...)
+}
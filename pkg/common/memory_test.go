@@ -0,0 +1,98 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryQueue(t *testing.T) {
+	queue := NewMemoryQueue()
+	require.Nil(t, queue.SendMessage(aws.String("queue-1"), aws.String("msg-1")))
+	require.Nil(t, queue.SendMessage(aws.String("queue-1"), aws.String("msg-2")))
+	require.Nil(t, queue.SendMessage(aws.String("queue-2"), aws.String("msg-3")))
+
+	res, err := queue.ReceiveMessage(&sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String("queue-1"),
+		MaxNumberOfMessages: aws.Int64(10),
+	})
+	require.Nil(t, err)
+	require.Len(t, res.Messages, 2)
+	require.Equal(t, "msg-1", *res.Messages[0].Body)
+	require.Equal(t, "1", *res.Messages[0].Attributes["ApproximateReceiveCount"])
+
+	// Messages remain in the queue until deleted
+	_, err = queue.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      aws.String("queue-1"),
+		ReceiptHandle: res.Messages[0].ReceiptHandle,
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"msg-2"}, queue.Messages("queue-1"))
+	require.Equal(t, []string{"msg-3"}, queue.Messages("queue-2"))
+
+	// Delayed messages are not received until the delay has passed
+	require.Nil(t, queue.SendMessageWithDelay(aws.String("queue-3"), aws.String("msg-4"), 60))
+	res, err = queue.ReceiveMessage(&sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String("queue-3"),
+		MaxNumberOfMessages: aws.Int64(10),
+	})
+	require.Nil(t, err)
+	require.Len(t, res.Messages, 0)
+	require.Equal(t, []string{"msg-4"}, queue.Messages("queue-3"))
+
+	// Each receive returns a new receipt handle,
+	// and only the latest one deletes the message
+	first, err := queue.ReceiveMessage(&sqs.ReceiveMessageInput{QueueUrl: aws.String("queue-2")})
+	require.Nil(t, err)
+	latest, err := queue.ReceiveMessage(&sqs.ReceiveMessageInput{QueueUrl: aws.String("queue-2")})
+	require.Nil(t, err)
+	require.NotEqual(t, *first.Messages[0].ReceiptHandle, *latest.Messages[0].ReceiptHandle)
+	_, err = queue.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      aws.String("queue-2"),
+		ReceiptHandle: first.Messages[0].ReceiptHandle,
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"msg-3"}, queue.Messages("queue-2"))
+	_, err = queue.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      aws.String("queue-2"),
+		ReceiptHandle: latest.Messages[0].ReceiptHandle,
+	})
+	require.Nil(t, err)
+	require.Empty(t, queue.Messages("queue-2"))
+
+	// Messages received with a VisibilityTimeout are hidden
+	res, err = queue.ReceiveMessage(&sqs.ReceiveMessageInput{
+		QueueUrl:          aws.String("queue-1"),
+		VisibilityTimeout: aws.Int64(60),
+	})
+	require.Nil(t, err)
+	require.Len(t, res.Messages, 1)
+	res, err = queue.ReceiveMessage(&sqs.ReceiveMessageInput{QueueUrl: aws.String("queue-1")})
+	require.Nil(t, err)
+	require.Len(t, res.Messages, 0)
+	require.Equal(t, []string{"msg-2"}, queue.Messages("queue-1"))
+}
+
+func TestMemoryNotifier(t *testing.T) {
+	notifier := NewMemoryNotifier()
+	_, err := notifier.PublishMessage(aws.String("topic-1"), aws.String("msg-1"), true)
+	require.Nil(t, err)
+
+	require.Equal(t, []string{"msg-1"}, notifier.Messages("topic-1"))
+	require.Equal(t, []string{}, notifier.Messages("topic-2"))
+}
+
+func TestMemoryBuilder(t *testing.T) {
+	builder := NewMemoryBuilder()
+	buildID, err := builder.StartBuild(aws.String("reset"), map[string]string{"RESET_ACCOUNT": "123456789012"})
+	require.Nil(t, err)
+	require.Equal(t, "reset:1", buildID)
+
+	require.Equal(t, []MemoryBuild{{
+		ID:                   "reset:1",
+		ProjectName:          "reset",
+		EnvironmentVariables: map[string]string{"RESET_ACCOUNT": "123456789012"},
+	}}, builder.Builds())
+}
@@ -12,6 +12,29 @@ type Queue struct {
 	mock.Mock
 }
 
+// ChangeMessageVisibility provides a mock function with given fields: _a0
+func (_m *Queue) ChangeMessageVisibility(_a0 *sqs.ChangeMessageVisibilityInput) (*sqs.ChangeMessageVisibilityOutput, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *sqs.ChangeMessageVisibilityOutput
+	if rf, ok := ret.Get(0).(func(*sqs.ChangeMessageVisibilityInput) *sqs.ChangeMessageVisibilityOutput); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*sqs.ChangeMessageVisibilityOutput)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*sqs.ChangeMessageVisibilityInput) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteMessage provides a mock function with given fields: _a0
 func (_m *Queue) DeleteMessage(_a0 *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
 	ret := _m.Called(_a0)
@@ -35,6 +58,20 @@ func (_m *Queue) DeleteMessage(_a0 *sqs.DeleteMessageInput) (*sqs.DeleteMessageO
 	return r0, r1
 }
 
+// SendMessageWithDelay provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Queue) SendMessageWithDelay(_a0 *string, _a1 *string, _a2 int64) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*string, *string, int64) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewFromEnv provides a mock function with given fields:
 func (_m *Queue) NewFromEnv() error {
 	ret := _m.Called()
@@ -10,8 +10,10 @@ import (
 // the provided SQS Message Input
 type Queue interface {
 	SendMessage(*string, *string) error
+	SendMessageWithDelay(*string, *string, int64) error
 	ReceiveMessage(*sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error)
 	DeleteMessage(*sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error)
+	ChangeMessageVisibility(*sqs.ChangeMessageVisibilityInput) (*sqs.ChangeMessageVisibilityOutput, error)
 	NewFromEnv() error
 }
 
@@ -34,6 +36,18 @@ func (queue SQSQueue) SendMessage(queueURL *string, message *string) error {
 	return err
 }
 
+// SendMessageWithDelay sends the provided message to the queue, and
+// hides the message from consumers for delaySeconds (up to 900 seconds)
+func (queue SQSQueue) SendMessageWithDelay(queueURL *string, message *string, delaySeconds int64) error {
+	input := sqs.SendMessageInput{
+		QueueUrl:     queueURL,
+		MessageBody:  message,
+		DelaySeconds: aws.Int64(delaySeconds),
+	}
+	_, err := queue.Client.SendMessage(&input)
+	return err
+}
+
 // ReceiveMessage method returns an AWS SQS Message Output based on the provided
 // Message Input through the AWS SQS Client.
 func (queue SQSQueue) ReceiveMessage(input *sqs.ReceiveMessageInput) (
@@ -48,6 +62,13 @@ func (queue SQSQueue) DeleteMessage(input *sqs.DeleteMessageInput) (
 	return queue.Client.DeleteMessage(input)
 }
 
+// ChangeMessageVisibility changes how long a received message is hidden
+// from other consumers, through the SQS Client
+func (queue SQSQueue) ChangeMessageVisibility(input *sqs.ChangeMessageVisibilityInput) (
+	*sqs.ChangeMessageVisibilityOutput, error) {
+	return queue.Client.ChangeMessageVisibility(input)
+}
+
 // NewFromEnv creates an SQS instance configured from environment variables.
 // Requires env vars for:
 // - AWS_CURRENT_REGION
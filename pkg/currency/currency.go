@@ -0,0 +1,149 @@
+// Package currency converts amounts between currencies.
+//
+// Exchange rates are looked up from a RateProvider. StaticRates provides
+// fixed rates, loaded from a JSON file in the artifacts bucket.
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/Optum/dce/pkg/common"
+)
+
+// USD is the currency of AWS billing data,
+// and of the principal and max lease budget amounts
+const USD = "USD"
+
+var codeRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// RateProvider provides exchange rates between currencies
+type RateProvider interface {
+	// Rate returns the amount of the `to` currency,
+	// equal to one unit of the `from` currency
+	Rate(from string, to string) (float64, error)
+}
+
+// Convert converts an amount from one currency to another.
+// Amounts in the same currency are returned as-is, without looking up a rate.
+func Convert(rates RateProvider, amount float64, from string, to string) (float64, error) {
+	if from == to {
+		return amount, nil
+	}
+	if rates == nil {
+		return 0, fmt.Errorf("No exchange rates are configured, to convert %s to %s", from, to)
+	}
+	rate, err := rates.Rate(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// Validate checks that a currency code is an ISO 4217 code (eg. "EUR"),
+// and that amounts in that currency may be converted to USD
+func Validate(rates RateProvider, code string) error {
+	if !codeRegex.MatchString(code) {
+		return fmt.Errorf("Invalid currency %q: must be a three letter ISO 4217 code, eg. USD", code)
+	}
+	_, err := Convert(rates, 1, code, USD)
+	if err != nil {
+		return fmt.Errorf("Unsupported currency %s: %s", code, err)
+	}
+	return nil
+}
+
+// StaticRates is a RateProvider with fixed exchange rates,
+// relative to a base currency. eg.
+//
+//	{ "base": "USD", "rates": { "EUR": 0.92, "GBP": 0.79 } }
+type StaticRates struct {
+	// Base is the currency which the rates are relative to
+	Base string `json:"base"`
+	// Rates is the amount of each currency, equal to one unit of the Base currency
+	Rates map[string]float64 `json:"rates"`
+}
+
+// ParseStaticRates parses and validates StaticRates from JSON
+func ParseStaticRates(data string) (*StaticRates, error) {
+	rates := &StaticRates{}
+	err := json.Unmarshal([]byte(data), rates)
+	if err != nil {
+		return nil, err
+	}
+	if !codeRegex.MatchString(rates.Base) {
+		return nil, fmt.Errorf("Invalid base currency %q", rates.Base)
+	}
+	for code, rate := range rates.Rates {
+		if !codeRegex.MatchString(code) {
+			return nil, fmt.Errorf("Invalid currency %q", code)
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("Invalid rate for %s: %f must be greater than 0", code, rate)
+		}
+	}
+	return rates, nil
+}
+
+// Rate implements RateProvider, converting through the base currency
+func (r *StaticRates) Rate(from string, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	fromRate, err := r.baseRate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := r.baseRate(to)
+	if err != nil {
+		return 0, err
+	}
+	return toRate / fromRate, nil
+}
+
+// baseRate returns the amount of a currency, equal to one unit of the base currency
+func (r *StaticRates) baseRate(code string) (float64, error) {
+	if code == r.Base {
+		return 1, nil
+	}
+	rate, ok := r.Rates[code]
+	if !ok {
+		return 0, fmt.Errorf("No exchange rate for %s", code)
+	}
+	return rate, nil
+}
+
+/*
+NewFromEnv creates a RateProvider from environment variables
+
+Optional env vars:
+
+- EXCHANGE_RATES_S3_KEY (JSON file of StaticRates, in the ARTIFACTS_BUCKET. Only USD is supported, if not set)
+- ARTIFACTS_BUCKET (required, if EXCHANGE_RATES_S3_KEY is set)
+*/
+func NewFromEnv() (RateProvider, error) {
+	key := os.Getenv("EXCHANGE_RATES_S3_KEY")
+	if key == "" {
+		return &StaticRates{Base: USD, Rates: map[string]float64{}}, nil
+	}
+
+	awsSession, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	storager := common.S3{Client: s3.New(awsSession)}
+	data, err := storager.GetObject(common.RequireEnv("ARTIFACTS_BUCKET"), key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve exchange rates from %s: %s", key, err)
+	}
+	rates, err := ParseStaticRates(data)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid exchange rates in %s: %s", key, err)
+	}
+	return rates, nil
+}
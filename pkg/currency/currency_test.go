@@ -0,0 +1,97 @@
+package currency
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticRates(t *testing.T) {
+	rates, err := ParseStaticRates(`{"base": "USD", "rates": {"EUR": 0.8, "GBP": 0.5}}`)
+	require.Nil(t, err)
+
+	tests := []struct {
+		from     string
+		to       string
+		expected float64
+	}{
+		{"USD", "USD", 1},
+		{"EUR", "EUR", 1},
+		{"USD", "EUR", 0.8},
+		{"EUR", "USD", 1.25},
+		{"GBP", "EUR", 1.6},
+	}
+	for _, test := range tests {
+		t.Run(test.from+" to "+test.to, func(t *testing.T) {
+			rate, err := rates.Rate(test.from, test.to)
+			require.Nil(t, err)
+			require.InDelta(t, test.expected, rate, 0.000001)
+		})
+	}
+
+	t.Run("should fail for an unknown currency", func(t *testing.T) {
+		_, err := rates.Rate("USD", "JPY")
+		require.EqualError(t, err, "No exchange rate for JPY")
+	})
+}
+
+func TestParseStaticRates(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		expectedErr string
+	}{
+		{"invalid JSON", `{`, "unexpected end of JSON input"},
+		{"missing base", `{"rates": {"EUR": 0.8}}`, `Invalid base currency ""`},
+		{"invalid currency", `{"base": "USD", "rates": {"euro": 0.8}}`, `Invalid currency "euro"`},
+		{"invalid rate", `{"base": "USD", "rates": {"EUR": 0}}`, "Invalid rate for EUR: 0.000000 must be greater than 0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseStaticRates(test.data)
+			require.EqualError(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	rates := &StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.8}}
+
+	t.Run("should convert between currencies", func(t *testing.T) {
+		amount, err := Convert(rates, 100, "USD", "EUR")
+		require.Nil(t, err)
+		require.InDelta(t, 80, amount, 0.000001)
+	})
+
+	t.Run("should not require rates for the same currency", func(t *testing.T) {
+		amount, err := Convert(nil, 100, "USD", "USD")
+		require.Nil(t, err)
+		require.Equal(t, 100.0, amount)
+	})
+
+	t.Run("should fail without rates", func(t *testing.T) {
+		_, err := Convert(nil, 100, "USD", "EUR")
+		require.EqualError(t, err, "No exchange rates are configured, to convert USD to EUR")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	rates := &StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.8}}
+
+	require.Nil(t, Validate(rates, "USD"))
+	require.Nil(t, Validate(rates, "EUR"))
+	require.EqualError(t, Validate(rates, "usd"), `Invalid currency "usd": must be a three letter ISO 4217 code, eg. USD`)
+	require.EqualError(t, Validate(rates, "JPY"), "Unsupported currency JPY: No exchange rate for JPY")
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Run("should support only USD, without an exchange rates file", func(t *testing.T) {
+		os.Unsetenv("EXCHANGE_RATES_S3_KEY")
+
+		rates, err := NewFromEnv()
+		require.Nil(t, err)
+		require.Nil(t, Validate(rates, "USD"))
+		require.NotNil(t, Validate(rates, "EUR"))
+	})
+}
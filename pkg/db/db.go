@@ -54,10 +54,13 @@ type DBer interface {
 	PutAccount(account Account) error
 	UpdateAccount(account Account, fieldsToUpdate []string) (*Account, error)
 	DeleteAccount(accountID string) (*Account, error)
+	DeleteLease(accountID string, principalID string) (*Lease, error)
 	PutLease(lease Lease) (*Lease, error)
 	UpsertLease(lease Lease) (*Lease, error)
+	UpdateLease(lease Lease, fieldsToUpdate []string) (*Lease, error)
 	TransitionAccountStatus(accountID string, prevStatus AccountStatus, nextStatus AccountStatus) (*Account, error)
 	TransitionLeaseStatus(accountID string, principalID string, prevStatus LeaseStatus, nextStatus LeaseStatus, leaseStatusReason LeaseStatusReason) (*Lease, error)
+	ExtendLease(accountID string, principalID string, prevStatus LeaseStatus, prevExpiresOn int64, prevBudgetAmount float64, nextExpiresOn int64, nextBudgetAmount float64) (*Lease, error)
 	FindLeasesByAccount(accountID string) ([]*Lease, error)
 	FindLeasesByPrincipal(principalID string) ([]*Lease, error)
 	FindLeasesByStatus(status LeaseStatus) ([]*Lease, error)
@@ -492,6 +495,54 @@ func (db *DB) UpsertLease(lease Lease) (*Lease, error) {
 	return updatedLease, nil
 }
 
+// UpdateLease updates the requested fields of an existing lease.
+// Fails if the lease does not exist
+func (db *DB) UpdateLease(lease Lease, fieldsToUpdate []string) (*Lease, error) {
+	// Update timestamps
+	lease.LastModifiedOn = time.Now().Unix()
+	fieldsToUpdate = append(fieldsToUpdate, "LastModifiedOn")
+
+	// Create an update expression for the lease object
+	expr, err := buildUpdateExpression(&buildUpdateExpressInput{
+		obj:           lease,
+		includeFields: fieldsToUpdate,
+	})
+	if err != nil {
+		return nil, errors2.Wrapf(err, "Failed to update lease %s/%s",
+			lease.PrincipalID, lease.AccountID)
+	}
+
+	// Update the Lease record
+	res, err := db.Client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName: &db.LeaseTableName,
+		Key: map[string]*dynamodb.AttributeValue{
+			"AccountId":   {S: &lease.AccountID},
+			"PrincipalId": {S: &lease.PrincipalID},
+		},
+		// Make sure the record we're updating already exists
+		ConditionExpression:       aws.String("attribute_exists(AccountId)"),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		UpdateExpression:          expr.Update(),
+		ReturnValues:              aws.String("ALL_NEW"),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			if aerr.Code() == "ConditionalCheckFailedException" {
+				return nil, &NotFoundError{
+					fmt.Sprintf(
+						"Unable to update lease %s/%s: lease does not exist",
+						lease.PrincipalID, lease.AccountID,
+					),
+				}
+			}
+		}
+		return nil, err
+	}
+
+	return unmarshalLease(res.Attributes)
+}
+
 // TransitionLeaseStatus updates a lease's status from prevStatus to nextStatus.
 // Will fail if the Lease was not previously set to `prevStatus`
 //
@@ -562,6 +613,106 @@ func (db *DB) TransitionLeaseStatus(accountID string, principalID string, prevSt
 	return unmarshalLease(result.Attributes)
 }
 
+// ExtendLease updates the ExpiresOn and BudgetAmount of a lease,
+// and makes the lease Active (eg. to unfreeze a Frozen lease).
+// The lease's ExpiryNotificationsSent and FiredRules are cleared, and its
+// BudgetThresholdsSent are cleared if the budget changes.
+// Will fail if the lease's LeaseStatus, ExpiresOn and BudgetAmount
+// were not previously set to `prevStatus`, `prevExpiresOn` and `prevBudgetAmount`
+// (eg. if the lease was modified by another request)
+func (db *DB) ExtendLease(accountID string, principalID string, prevStatus LeaseStatus, prevExpiresOn int64, prevBudgetAmount float64, nextExpiresOn int64, nextBudgetAmount float64) (*Lease, error) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	updateExpression := "set ExpiresOn=:nextExpiresOn, " +
+		"BudgetAmount=:nextBudgetAmount, " +
+		"LastModifiedOn=:lastModifiedOn"
+	expressionAttributeValues := map[string]*dynamodb.AttributeValue{
+		":prevStatus": {
+			S: aws.String(string(prevStatus)),
+		},
+		":prevExpiresOn": {
+			N: aws.String(strconv.FormatInt(prevExpiresOn, 10)),
+		},
+		":prevBudgetAmount": {
+			N: aws.String(strconv.FormatFloat(prevBudgetAmount, 'f', -1, 64)),
+		},
+		":nextExpiresOn": {
+			N: aws.String(strconv.FormatInt(nextExpiresOn, 10)),
+		},
+		":nextBudgetAmount": {
+			N: aws.String(strconv.FormatFloat(nextBudgetAmount, 'f', -1, 64)),
+		},
+		":lastModifiedOn": {
+			N: aws.String(now),
+		},
+	}
+	// Activate the lease in the same update, so that it is never
+	// left Active without its extension (or extended while Frozen)
+	if prevStatus != Active {
+		updateExpression += ", LeaseStatus=:nextStatus, " +
+			"LeaseStatusReason=:nextStatusReason, " +
+			"LeaseStatusModifiedOn=:leaseStatusModifiedOn"
+		expressionAttributeValues[":nextStatus"] = &dynamodb.AttributeValue{
+			S: aws.String(string(Active)),
+		}
+		expressionAttributeValues[":nextStatusReason"] = &dynamodb.AttributeValue{
+			S: aws.String(string(LeaseActive)),
+		}
+		expressionAttributeValues[":leaseStatusModifiedOn"] = &dynamodb.AttributeValue{
+			N: aws.String(now),
+		}
+	}
+	// Expiry notifications are sent again, before the new expiry,
+	// and lease rules fire again, for the new expiry and budget
+	removeAttributes := []string{"ExpiryNotificationsSent", "FiredRules"}
+	// Budget notifications are sent again, for the new budget
+	if nextBudgetAmount != prevBudgetAmount {
+		removeAttributes = append(removeAttributes, "BudgetThresholdsSent")
+	}
+	updateExpression += " remove " + strings.Join(removeAttributes, ", ")
+
+	result, err := db.Client.UpdateItem(
+		&dynamodb.UpdateItemInput{
+			// Query in Lease Table
+			TableName: aws.String(db.LeaseTableName),
+			// Find Lease for the requested accountId
+			Key: map[string]*dynamodb.AttributeValue{
+				"AccountId": {
+					S: aws.String(accountID),
+				},
+				"PrincipalId": {
+					S: aws.String(principalID),
+				},
+			},
+			UpdateExpression:          aws.String(updateExpression),
+			ExpressionAttributeValues: expressionAttributeValues,
+			// Only update leases which have not been modified
+			ConditionExpression: aws.String("LeaseStatus = :prevStatus and ExpiresOn = :prevExpiresOn " +
+				"and BudgetAmount = :prevBudgetAmount"),
+			// Return the updated record
+			ReturnValues: aws.String("ALL_NEW"),
+		},
+	)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok {
+			if aerr.Code() == "ConditionalCheckFailedException" {
+				return nil, &StatusTransitionError{
+					fmt.Sprintf(
+						"unable to extend lease for %v/%v: no lease exists with Status=\"%v\", ExpiresOn=%v and BudgetAmount=%v",
+						accountID,
+						principalID,
+						prevStatus,
+						prevExpiresOn,
+						prevBudgetAmount,
+					),
+				}
+			}
+		}
+		return nil, err
+	}
+
+	return unmarshalLease(result.Attributes)
+}
+
 // TransitionAccountStatus updates account status for a given accountID and
 // returns the updated record on success
 func (db *DB) TransitionAccountStatus(accountID string, prevStatus AccountStatus, nextStatus AccountStatus) (*Account, error) {
@@ -717,6 +868,33 @@ func (db *DB) DeleteAccount(accountID string) (*Account, error) {
 	return account, err
 }
 
+// DeleteLease deletes the lease for the given accountID and principalID.
+// Returns the deleted lease, or nil if no lease existed.
+func (db *DB) DeleteLease(accountID string, principalID string) (*Lease, error) {
+	result, err := db.Client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(db.LeaseTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"AccountId": {
+				S: aws.String(accountID),
+			},
+			"PrincipalId": {
+				S: aws.String(principalID),
+			},
+		},
+		// Return the deleted record
+		ReturnValues: aws.String("ALL_OLD"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Attributes) == 0 {
+		return nil, nil
+	}
+
+	return unmarshalLease(result.Attributes)
+}
+
 // GetLeasesInput contains the filtering criteria for the GetLeases scan.
 type GetLeasesInput struct {
 	StartKeys   map[string]string
@@ -842,7 +1020,7 @@ func (db *DB) UpdateMetadata(accountID string, metadata map[string]interface{})
 	return nil
 }
 
-// OrphanAccount puts account in Oprhaned status and inactivates any active leases
+// OrphanAccount puts account in Oprhaned status and inactivates any active or frozen leases
 func (db *DB) OrphanAccount(accountID string) (*Account, error) {
 	account, err := db.GetAccount(accountID)
 	if err != nil {
@@ -854,21 +1032,23 @@ func (db *DB) OrphanAccount(accountID string) (*Account, error) {
 		fmt.Printf("Issue transitioning account '%s' status to orphaned: %s", accountID, err)
 		return nil, err
 	}
-	leases, err := db.GetLeases(GetLeasesInput{
-		AccountID: accountID,
-		Status:    Active,
-	})
-	if err != nil {
-		fmt.Printf("Issue getting leases with account id '%s': %s", accountID, err)
-		return resAccount, err
-	}
-	for _, lease := range leases.Results {
-		_, err = db.TransitionLeaseStatus(
-			accountID, lease.PrincipalID, Active, Inactive, AccountOrphaned)
+	for _, status := range []LeaseStatus{Active, Frozen} {
+		leases, err := db.GetLeases(GetLeasesInput{
+			AccountID: accountID,
+			Status:    status,
+		})
 		if err != nil {
-			fmt.Printf("Issue transition lease '%s' to Inactive: %s", lease.ID, err)
+			fmt.Printf("Issue getting leases with account id '%s': %s", accountID, err)
 			return resAccount, err
 		}
+		for _, lease := range leases.Results {
+			_, err = db.TransitionLeaseStatus(
+				accountID, lease.PrincipalID, status, Inactive, AccountOrphaned)
+			if err != nil {
+				fmt.Printf("Issue transition lease '%s' to Inactive: %s", lease.ID, err)
+				return resAccount, err
+			}
+		}
 	}
 
 	return resAccount, nil
@@ -204,6 +204,22 @@ func TestDb(t *testing.T) {
 			}).Return(
 				test.ScanLeasesOutput, test.ScanLeasesError,
 			)
+			mockDynamo.On("Scan", &dynamodb.ScanInput{
+				ConsistentRead: aws.Bool(false),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":status": {
+						S: aws.String("Frozen"),
+					},
+					":accountId": {
+						S: aws.String(test.AccountID),
+					},
+				},
+				FilterExpression: aws.String("LeaseStatus = :status and AccountId = :accountId"),
+				Limit:            aws.Int64(25),
+				TableName:        aws.String("lease"),
+			}).Return(
+				&dynamodb.ScanOutput{Items: []map[string]*dynamodb.AttributeValue{}}, nil,
+			)
 
 			mockDynamo.On("UpdateItem", mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
 				return *input.TableName == "lease"
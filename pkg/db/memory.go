@@ -0,0 +1,711 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	guuid "github.com/google/uuid"
+	errors2 "github.com/pkg/errors"
+	"gopkg.in/oleiade/reflections.v1"
+)
+
+/*
+The `MemoryDB` service implements the DBer interface
+against in-memory maps, rather than DynamoDB tables.
+
+It follows the same semantics as the `DB` service
+(conditional status transitions, pagination, default lease expiry, etc.)
+so that whole lease and account lifecycles may be run
+in unit tests, or on a local machine.
+*/
+
+// MemoryDB is an in-memory implementation of the DBer interface
+type MemoryDB struct {
+	// Default expiry time, in days, of the lease
+	DefaultLeaseLengthInDays int
+
+	mu       sync.RWMutex
+	accounts map[string]Account
+	// Leases are keyed by AccountId and PrincipalId,
+	// the same as the Lease table's hash and range keys
+	leases map[leaseKey]Lease
+}
+
+type leaseKey struct {
+	AccountID   string
+	PrincipalID string
+}
+
+// NewMemoryDB creates a new, empty MemoryDB
+func NewMemoryDB(defaultLeaseLengthInDays int) *MemoryDB {
+	return &MemoryDB{
+		DefaultLeaseLengthInDays: defaultLeaseLengthInDays,
+		accounts:                 map[string]Account{},
+		leases:                   map[leaseKey]Lease{},
+	}
+}
+
+// GetAccount returns an account record corresponding to an accountID
+// string. Returns nil if the account does not exist.
+func (m *MemoryDB) GetAccount(accountID string) (*Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	account, ok := m.accounts[accountID]
+	if !ok {
+		return nil, nil
+	}
+	return copyAccount(account), nil
+}
+
+// GetAccounts returns a list of all accounts, ordered by ID
+func (m *MemoryDB) GetAccounts() ([]*Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.filterAccounts(func(Account) bool { return true }), nil
+}
+
+// GetReadyAccount returns an available account record with a
+// corresponding status of 'Ready'
+func (m *MemoryDB) GetReadyAccount() (*Account, error) {
+	accounts, err := m.FindAccountsByStatus(Ready)
+	if len(accounts) < 1 {
+		return nil, err
+	}
+	return accounts[0], err
+}
+
+// FindAccountsByStatus returns all accounts with the given status
+func (m *MemoryDB) FindAccountsByStatus(status AccountStatus) ([]*Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.filterAccounts(func(a Account) bool {
+		return a.AccountStatus == status
+	}), nil
+}
+
+// FindAccountsByPrincipalID returns all accounts which have
+// been leased to the given principal
+func (m *MemoryDB) FindAccountsByPrincipalID(principalID string) ([]*Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	accountIDs := map[string]bool{}
+	for key := range m.leases {
+		if key.PrincipalID == principalID {
+			accountIDs[key.AccountID] = true
+		}
+	}
+
+	return m.filterAccounts(func(a Account) bool {
+		return accountIDs[a.ID]
+	}), nil
+}
+
+// GetLeaseByID gets a lease by ID
+func (m *MemoryDB) GetLeaseByID(leaseID string) (*Lease, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	leases := m.filterLeases(func(l Lease) bool {
+		return l.ID == leaseID
+	})
+
+	if len(leases) < 1 {
+		return nil, fmt.Errorf("No Lease found with id: %s", leaseID)
+	}
+	if len(leases) > 1 {
+		return nil, fmt.Errorf("Found more than one Lease with id: %s", leaseID)
+	}
+
+	return leases[0], nil
+}
+
+// GetLease retrieves a Lease for the
+// given accountID and principalID
+func (m *MemoryDB) GetLease(accountID string, principalID string) (*Lease, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	lease, ok := m.leases[leaseKey{accountID, principalID}]
+	if !ok {
+		return nil, nil
+	}
+	return copyLease(lease), nil
+}
+
+// FindLeasesByAccount finds lease values for a given accountID
+func (m *MemoryDB) FindLeasesByAccount(accountID string) ([]*Lease, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.filterLeases(func(l Lease) bool {
+		return l.AccountID == accountID
+	}), nil
+}
+
+// FindLeasesByPrincipal finds leased accounts for a given principalID
+func (m *MemoryDB) FindLeasesByPrincipal(principalID string) ([]*Lease, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	leases := m.filterLeases(func(l Lease) bool {
+		return l.PrincipalID == principalID
+	})
+	if len(leases) == 0 {
+		return nil, nil
+	}
+	return leases, nil
+}
+
+// FindLeasesByStatus finds all leases with the given status
+func (m *MemoryDB) FindLeasesByStatus(status LeaseStatus) ([]*Lease, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.filterLeases(func(l Lease) bool {
+		return l.LeaseStatus == status
+	}), nil
+}
+
+// PutAccount stores an account, overwriting any existing record
+func (m *MemoryDB) PutAccount(account Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.accounts[account.ID] = *copyAccount(account)
+	return nil
+}
+
+// UpdateAccount updates an existing account record.
+// fails if the account does not exist
+func (m *MemoryDB) UpdateAccount(account Account, fieldsToUpdate []string) (*Account, error) {
+	// Verify the account has an ID
+	if account.ID == "" {
+		return nil, errors.New("unable to update account: account has no ID")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.accounts[account.ID]
+	if !ok {
+		return nil, &NotFoundError{
+			fmt.Sprintf(
+				"Unable to update account %s: account does not exist", account.ID,
+			),
+		}
+	}
+
+	// Update timestamps
+	account.LastModifiedOn = time.Now().Unix()
+	fieldsToUpdate = append(fieldsToUpdate, "LastModifiedOn")
+
+	// Copy each of the requested fields onto the existing record
+	for _, fieldName := range fieldsToUpdate {
+		val, err := reflections.GetField(account, fieldName)
+		if err != nil {
+			return nil, errors2.Wrapf(err, "Failed to update account %s", account.ID)
+		}
+		err = reflections.SetField(&existing, fieldName, val)
+		if err != nil {
+			return nil, errors2.Wrapf(err, "Failed to update account %s", account.ID)
+		}
+	}
+	m.accounts[account.ID] = *copyAccount(existing)
+
+	return copyAccount(existing), nil
+}
+
+// DeleteAccount finds a given account and deletes it if it is not of status `Leased`. Returns the account.
+func (m *MemoryDB) DeleteAccount(accountID string) (*Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	account, ok := m.accounts[accountID]
+	if !ok {
+		errorMessage := fmt.Sprintf("No account found with ID \"%s\".", accountID)
+		log.Print(errorMessage)
+		return nil, &AccountNotFoundError{err: errorMessage}
+	}
+
+	if account.AccountStatus == Leased {
+		errorMessage := fmt.Sprintf("Unable to delete account \"%s\": account is leased.", accountID)
+		log.Print(errorMessage)
+		return copyAccount(account), &AccountLeasedError{err: errorMessage}
+	}
+
+	delete(m.accounts, accountID)
+	return copyAccount(account), nil
+}
+
+// DeleteLease deletes the lease for the given accountID and principalID.
+// Returns the deleted lease, or nil if no lease existed.
+func (m *MemoryDB) DeleteLease(accountID string, principalID string) (*Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := leaseKey{accountID, principalID}
+	lease, ok := m.leases[key]
+	if !ok {
+		return nil, nil
+	}
+	delete(m.leases, key)
+	return copyLease(lease), nil
+}
+
+// PutLease writes a Lease, overwriting any existing record.
+// Returns the previous Lease if there is one - does not return
+// the lease that was added
+func (m *MemoryDB) PutLease(lease Lease) (*Lease, error) {
+	// apply some reasonable DEFAULTS to the lease before saving it.
+	if len(lease.ID) == 0 {
+		lease.ID = guuid.New().String()
+	}
+
+	if lease.ExpiresOn == 0 {
+		lease.ExpiresOn = time.Now().AddDate(0, 0, m.DefaultLeaseLengthInDays).Unix()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := leaseKey{lease.AccountID, lease.PrincipalID}
+	prevLease, ok := m.leases[key]
+	m.leases[key] = *copyLease(lease)
+	if !ok {
+		return &Lease{}, nil
+	}
+	return copyLease(prevLease), nil
+}
+
+// UpsertLease creates or updates the lease record
+func (m *MemoryDB) UpsertLease(lease Lease) (*Lease, error) {
+	// Some basic validation of the lease
+	if len(lease.ID) == 0 {
+		return nil, fmt.Errorf(
+			"failed to create lease for %s/%s: missing ID", lease.PrincipalID, lease.AccountID,
+		)
+	}
+	if lease.ExpiresOn == 0 {
+		return nil, fmt.Errorf(
+			"failed to create lease for %s/%s: missing ExpiresOn", lease.PrincipalID, lease.AccountID,
+		)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.leases[leaseKey{lease.AccountID, lease.PrincipalID}] = *copyLease(lease)
+	return copyLease(lease), nil
+}
+
+// UpdateLease updates the requested fields of an existing lease.
+// fails if the lease does not exist
+func (m *MemoryDB) UpdateLease(lease Lease, fieldsToUpdate []string) (*Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := leaseKey{lease.AccountID, lease.PrincipalID}
+	existing, ok := m.leases[key]
+	if !ok {
+		return nil, &NotFoundError{
+			fmt.Sprintf(
+				"Unable to update lease %s/%s: lease does not exist",
+				lease.PrincipalID, lease.AccountID,
+			),
+		}
+	}
+
+	// Update timestamps
+	lease.LastModifiedOn = time.Now().Unix()
+	fieldsToUpdate = append(fieldsToUpdate, "LastModifiedOn")
+
+	// Copy each of the requested fields onto the existing record
+	for _, fieldName := range fieldsToUpdate {
+		val, err := reflections.GetField(lease, fieldName)
+		if err != nil {
+			return nil, errors2.Wrapf(err, "Failed to update lease %s/%s", lease.PrincipalID, lease.AccountID)
+		}
+		err = reflections.SetField(&existing, fieldName, val)
+		if err != nil {
+			return nil, errors2.Wrapf(err, "Failed to update lease %s/%s", lease.PrincipalID, lease.AccountID)
+		}
+	}
+	m.leases[key] = *copyLease(existing)
+
+	return copyLease(existing), nil
+}
+
+// TransitionLeaseStatus updates a lease's status from prevStatus to nextStatus.
+// Will fail if the Lease was not previously set to `prevStatus`
+func (m *MemoryDB) TransitionLeaseStatus(accountID string, principalID string, prevStatus LeaseStatus, nextStatus LeaseStatus, leaseStatusReason LeaseStatusReason) (*Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := leaseKey{accountID, principalID}
+	lease, ok := m.leases[key]
+	if !ok || lease.LeaseStatus != prevStatus {
+		return nil, &StatusTransitionError{
+			fmt.Sprintf(
+				"unable to update lease status from \"%v\" to \"%v\" for %v/%v: no lease exists with Status=\"%v\"",
+				prevStatus,
+				nextStatus,
+				accountID,
+				principalID,
+				prevStatus,
+			),
+		}
+	}
+
+	now := time.Now().Unix()
+	lease.LeaseStatus = nextStatus
+	lease.LeaseStatusReason = leaseStatusReason
+	lease.LastModifiedOn = now
+	lease.LeaseStatusModifiedOn = now
+	m.leases[key] = lease
+
+	return copyLease(lease), nil
+}
+
+// ExtendLease updates the ExpiresOn and BudgetAmount of a lease,
+// and makes the lease Active (eg. to unfreeze a Frozen lease).
+// The lease's ExpiryNotificationsSent and FiredRules are cleared, and its
+// BudgetThresholdsSent are cleared if the budget changes.
+// Will fail if the lease's LeaseStatus, ExpiresOn and BudgetAmount
+// were not previously set to `prevStatus`, `prevExpiresOn` and `prevBudgetAmount`
+func (m *MemoryDB) ExtendLease(accountID string, principalID string, prevStatus LeaseStatus, prevExpiresOn int64, prevBudgetAmount float64, nextExpiresOn int64, nextBudgetAmount float64) (*Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := leaseKey{accountID, principalID}
+	lease, ok := m.leases[key]
+	if !ok || lease.LeaseStatus != prevStatus || lease.ExpiresOn != prevExpiresOn || lease.BudgetAmount != prevBudgetAmount {
+		return nil, &StatusTransitionError{
+			fmt.Sprintf(
+				"unable to extend lease for %v/%v: no lease exists with Status=\"%v\", ExpiresOn=%v and BudgetAmount=%v",
+				accountID,
+				principalID,
+				prevStatus,
+				prevExpiresOn,
+				prevBudgetAmount,
+			),
+		}
+	}
+
+	now := time.Now().Unix()
+	lease.ExpiresOn = nextExpiresOn
+	lease.BudgetAmount = nextBudgetAmount
+	lease.LastModifiedOn = now
+	// Expiry notifications are sent again, before the new expiry,
+	// and lease rules fire again, for the new expiry and budget
+	lease.ExpiryNotificationsSent = nil
+	lease.FiredRules = nil
+	// Budget notifications are sent again, for the new budget
+	if nextBudgetAmount != prevBudgetAmount {
+		lease.BudgetThresholdsSent = nil
+	}
+	if prevStatus != Active {
+		lease.LeaseStatus = Active
+		lease.LeaseStatusReason = LeaseActive
+		lease.LeaseStatusModifiedOn = now
+	}
+	m.leases[key] = lease
+
+	return copyLease(lease), nil
+}
+
+// TransitionAccountStatus updates account status for a given accountID and
+// returns the updated record on success
+func (m *MemoryDB) TransitionAccountStatus(accountID string, prevStatus AccountStatus, nextStatus AccountStatus) (*Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	account, ok := m.accounts[accountID]
+	if !ok || account.AccountStatus != prevStatus {
+		return nil, &StatusTransitionError{
+			fmt.Sprintf(
+				"unable to update account status from \"%v\" to \"%v\" "+
+					"for account %v: no account exists with Status=\"%v\"",
+				prevStatus,
+				nextStatus,
+				accountID,
+				prevStatus,
+			),
+		}
+	}
+
+	account.AccountStatus = nextStatus
+	account.LastModifiedOn = time.Now().Unix()
+	m.accounts[accountID] = account
+
+	return copyAccount(account), nil
+}
+
+// UpdateAccountPrincipalPolicyHash updates hash representing the
+// current version of the Principal IAM Policy applied to the account
+func (m *MemoryDB) UpdateAccountPrincipalPolicyHash(accountID string, prevHash string, nextHash string) (*Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	account, ok := m.accounts[accountID]
+	if !ok || account.PrincipalPolicyHash != prevHash {
+		return nil, &StatusTransitionError{
+			fmt.Sprintf(
+				"unable to update Principal Policy hash from \"%v\" to \"%v\" "+
+					"for account %v: no account exists with PrincipalPolicyHash=\"%v\"",
+				prevHash,
+				nextHash,
+				accountID,
+				prevHash,
+			),
+		}
+	}
+
+	account.PrincipalPolicyHash = nextHash
+	account.LastModifiedOn = time.Now().Unix()
+	m.accounts[accountID] = account
+
+	return copyAccount(account), nil
+}
+
+// GetLeases takes a set of filtering criteria and scans the leases for the matching records.
+//
+// As with a DynamoDB scan, the `Limit` applies to the number of records evaluated
+// (not the number of records matched), and `NextKeys` are returned
+// whenever there are more records to evaluate.
+func (m *MemoryDB) GetLeases(input GetLeasesInput) (GetLeasesOutput, error) {
+	limit := int64(25)
+	if input.Limit > 0 {
+		limit = input.Limit
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Order all leases by their keys, so that we can page through them
+	all := m.filterLeases(func(Lease) bool { return true })
+
+	// Skip past the start keys
+	start := 0
+	if len(input.StartKeys) > 0 {
+		startKey := leaseKey{input.StartKeys["AccountId"], input.StartKeys["PrincipalId"]}
+		for start < len(all) && !leaseKeyLess(startKey, leaseKey{all[start].AccountID, all[start].PrincipalID}) {
+			start++
+		}
+	}
+
+	results := make([]*Lease, 0)
+	nextKey := make(map[string]string)
+	evaluated := int64(0)
+	for i := start; i < len(all); i++ {
+		lease := all[i]
+		evaluated++
+
+		matches := (input.Status == "" || lease.LeaseStatus == input.Status) &&
+			(input.PrincipalID == "" || lease.PrincipalID == input.PrincipalID) &&
+			(input.AccountID == "" || lease.AccountID == input.AccountID)
+		if matches {
+			results = append(results, lease)
+		}
+
+		if evaluated >= limit {
+			if i < len(all)-1 {
+				nextKey["AccountId"] = lease.AccountID
+				nextKey["PrincipalId"] = lease.PrincipalID
+			}
+			break
+		}
+	}
+
+	return GetLeasesOutput{
+		Results:  results,
+		NextKeys: nextKey,
+	}, nil
+}
+
+// UpdateMetadata updates the metadata field of an account, overwriting the old value completely with a new one
+func (m *MemoryDB) UpdateMetadata(accountID string, metadata map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// As with a DynamoDB UpdateItem, this will create
+	// the record if it does not already exist
+	account, ok := m.accounts[accountID]
+	if !ok {
+		account = Account{ID: accountID}
+	}
+	account.Metadata = copyMap(metadata)
+	account.LastModifiedOn = time.Now().Unix()
+	m.accounts[accountID] = account
+
+	return nil
+}
+
+// OrphanAccount puts account in Oprhaned status and inactivates any active leases
+func (m *MemoryDB) OrphanAccount(accountID string) (*Account, error) {
+	account, err := m.GetAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, &AccountNotFoundError{err: fmt.Sprintf("No account found with ID \"%s\".", accountID)}
+	}
+	resAccount, err := m.TransitionAccountStatus(accountID, account.AccountStatus, Orphaned)
+	if err != nil {
+		return nil, err
+	}
+
+	leases, err := m.FindLeasesByAccount(accountID)
+	if err != nil {
+		return resAccount, err
+	}
+	for _, lease := range leases {
+		if lease.LeaseStatus != Active && lease.LeaseStatus != Frozen {
+			continue
+		}
+		_, err = m.TransitionLeaseStatus(
+			accountID, lease.PrincipalID, lease.LeaseStatus, Inactive, AccountOrphaned)
+		if err != nil {
+			return resAccount, err
+		}
+	}
+
+	return resAccount, nil
+}
+
+// filterAccounts returns copies of all accounts matching the filter,
+// ordered by account ID.
+// Callers must hold the lock.
+func (m *MemoryDB) filterAccounts(filter func(Account) bool) []*Account {
+	accounts := []*Account{}
+	for _, account := range m.accounts {
+		if filter(account) {
+			accounts = append(accounts, copyAccount(account))
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].ID < accounts[j].ID
+	})
+	return accounts
+}
+
+// filterLeases returns copies of all leases matching the filter,
+// ordered by AccountId, then PrincipalId.
+// Callers must hold the lock.
+func (m *MemoryDB) filterLeases(filter func(Lease) bool) []*Lease {
+	leases := []*Lease{}
+	for _, lease := range m.leases {
+		if filter(lease) {
+			leases = append(leases, copyLease(lease))
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		return leaseKeyLess(
+			leaseKey{leases[i].AccountID, leases[i].PrincipalID},
+			leaseKey{leases[j].AccountID, leases[j].PrincipalID},
+		)
+	})
+	return leases
+}
+
+func leaseKeyLess(a leaseKey, b leaseKey) bool {
+	if a.AccountID != b.AccountID {
+		return a.AccountID < b.AccountID
+	}
+	return a.PrincipalID < b.PrincipalID
+}
+
+// copyAccount copies an account, so that callers
+// may not modify the stored record
+func copyAccount(account Account) *Account {
+	account.Metadata = copyMap(account.Metadata)
+	account.Labels = copyStringMap(account.Labels)
+	if account.LeftoverResources != nil {
+		account.LeftoverResources = append([]string{}, account.LeftoverResources...)
+	}
+	if account.ResetStatus != nil {
+		resetStatus := *account.ResetStatus
+		account.ResetStatus = &resetStatus
+	}
+	if account.NukeConfig != nil {
+		account.NukeConfig = copyNukeConfig(account.NukeConfig)
+	}
+	if account.PolicyDrift != nil {
+		policyDrift := *account.PolicyDrift
+		account.PolicyDrift = &policyDrift
+	}
+	return &account
+}
+
+// copyLease copies a lease, so that callers
+// may not modify the stored record
+func copyLease(lease Lease) *Lease {
+	lease.Metadata = copyMap(lease.Metadata)
+	lease.AccountLabels = copyStringMap(lease.AccountLabels)
+	if lease.BudgetNotificationEmails != nil {
+		lease.BudgetNotificationEmails = append([]string{}, lease.BudgetNotificationEmails...)
+	}
+	if lease.FiredRules != nil {
+		lease.FiredRules = append([]string{}, lease.FiredRules...)
+	}
+	if lease.ExpiryNotificationsSent != nil {
+		lease.ExpiryNotificationsSent = append([]float64{}, lease.ExpiryNotificationsSent...)
+	}
+	if lease.BudgetThresholdsSent != nil {
+		lease.BudgetThresholdsSent = append([]float64{}, lease.BudgetThresholdsSent...)
+	}
+	if lease.PrincipalThresholdsSent != nil {
+		lease.PrincipalThresholdsSent = append([]float64{}, lease.PrincipalThresholdsSent...)
+	}
+	if lease.PolicyStatements != nil {
+		statements := make([]PolicyStatement, len(lease.PolicyStatements))
+		for i, statement := range lease.PolicyStatements {
+			statements[i] = PolicyStatement(copyMap(statement))
+		}
+		lease.PolicyStatements = statements
+	}
+	return &lease
+}
+
+func copyNukeConfig(config *NukeConfig) *NukeConfig {
+	c := &NukeConfig{}
+	if config.Filters != nil {
+		c.Filters = make(map[string][]NukeFilter, len(config.Filters))
+		for resourceType, filters := range config.Filters {
+			c.Filters[resourceType] = append([]NukeFilter{}, filters...)
+		}
+	}
+	if config.ExcludeResourceTypes != nil {
+		c.ExcludeResourceTypes = append([]string{}, config.ExcludeResourceTypes...)
+	}
+	return c
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,253 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Verify that MemoryDB satisfies the DBer interface
+var _ DBer = &MemoryDB{}
+
+func TestMemoryDB(t *testing.T) {
+
+	t.Run("Account and Lease lifecycle", func(t *testing.T) {
+		dbSvc := NewMemoryDB(7)
+
+		require.Nil(t, dbSvc.PutAccount(Account{
+			ID:            "123",
+			AccountStatus: Ready,
+			Metadata:      map[string]interface{}{"foo": "bar"},
+		}))
+
+		// Get a ready account
+		account, err := dbSvc.GetReadyAccount()
+		require.Nil(t, err)
+		require.NotNil(t, account)
+		assert.Equal(t, "123", account.ID)
+
+		// Modifying the returned account should not modify the stored record
+		account.Metadata["foo"] = "baz"
+		account, err = dbSvc.GetAccount("123")
+		require.Nil(t, err)
+		assert.Equal(t, "bar", account.Metadata["foo"])
+
+		// Lease the account
+		_, err = dbSvc.UpsertLease(Lease{
+			ID:          "lease-1",
+			AccountID:   "123",
+			PrincipalID: "user",
+			LeaseStatus: Active,
+			ExpiresOn:   time.Now().Add(time.Hour).Unix(),
+		})
+		require.Nil(t, err)
+		account, err = dbSvc.TransitionAccountStatus("123", Ready, Leased)
+		require.Nil(t, err)
+		assert.Equal(t, Leased, account.AccountStatus)
+
+		// No more accounts are ready
+		account, err = dbSvc.GetReadyAccount()
+		require.Nil(t, err)
+		assert.Nil(t, account)
+
+		// Leased accounts may not be deleted
+		account, err = dbSvc.DeleteAccount("123")
+		assert.IsType(t, &AccountLeasedError{}, err)
+		assert.Equal(t, "123", account.ID)
+
+		// Lookup the lease
+		lease, err := dbSvc.GetLeaseByID("lease-1")
+		require.Nil(t, err)
+		assert.Equal(t, "user", lease.PrincipalID)
+		accounts, err := dbSvc.FindAccountsByPrincipalID("user")
+		require.Nil(t, err)
+		require.Len(t, accounts, 1)
+		assert.Equal(t, "123", accounts[0].ID)
+
+		// End the lease
+		lease, err = dbSvc.TransitionLeaseStatus("123", "user", Active, Inactive, LeaseDestroyed)
+		require.Nil(t, err)
+		assert.Equal(t, Inactive, lease.LeaseStatus)
+		assert.Equal(t, LeaseDestroyed, lease.LeaseStatusReason)
+		_, err = dbSvc.TransitionAccountStatus("123", Leased, NotReady)
+		require.Nil(t, err)
+
+		// Delete the account
+		_, err = dbSvc.DeleteAccount("123")
+		require.Nil(t, err)
+		account, err = dbSvc.GetAccount("123")
+		require.Nil(t, err)
+		assert.Nil(t, account)
+		_, err = dbSvc.DeleteAccount("123")
+		assert.IsType(t, &AccountNotFoundError{}, err)
+	})
+
+	t.Run("TransitionAccountStatus should fail if the previous status does not match", func(t *testing.T) {
+		dbSvc := NewMemoryDB(7)
+		require.Nil(t, dbSvc.PutAccount(Account{ID: "123", AccountStatus: NotReady}))
+
+		_, err := dbSvc.TransitionAccountStatus("123", Ready, Leased)
+		assert.IsType(t, &StatusTransitionError{}, err)
+		_, err = dbSvc.TransitionAccountStatus("456", Ready, Leased)
+		assert.IsType(t, &StatusTransitionError{}, err)
+	})
+
+	t.Run("TransitionLeaseStatus should fail if the previous status does not match", func(t *testing.T) {
+		dbSvc := NewMemoryDB(7)
+		_, err := dbSvc.PutLease(Lease{AccountID: "123", PrincipalID: "user", LeaseStatus: Inactive})
+		require.Nil(t, err)
+
+		_, err = dbSvc.TransitionLeaseStatus("123", "user", Active, Inactive, LeaseExpired)
+		assert.IsType(t, &StatusTransitionError{}, err)
+	})
+
+	t.Run("ExtendLease should fail if the lease was extended by another request", func(t *testing.T) {
+		dbSvc := NewMemoryDB(7)
+		_, err := dbSvc.PutLease(Lease{AccountID: "123", PrincipalID: "user", LeaseStatus: Active, ExpiresOn: 1000, BudgetAmount: 100})
+		require.Nil(t, err)
+
+		// Another request raised the budget
+		_, err = dbSvc.ExtendLease("123", "user", Active, 1000, 100, 1000, 200)
+		require.Nil(t, err)
+
+		_, err = dbSvc.ExtendLease("123", "user", Active, 1000, 100, 1000, 300)
+		assert.IsType(t, &StatusTransitionError{}, err)
+	})
+
+	t.Run("ExtendLease should unfreeze frozen leases", func(t *testing.T) {
+		dbSvc := NewMemoryDB(7)
+		_, err := dbSvc.PutLease(Lease{AccountID: "123", PrincipalID: "user", LeaseStatus: Frozen, LeaseStatusReason: LeaseOverBudget, ExpiresOn: 1000, BudgetAmount: 100})
+		require.Nil(t, err)
+
+		_, err = dbSvc.ExtendLease("123", "user", Active, 1000, 100, 1000, 200)
+		assert.IsType(t, &StatusTransitionError{}, err)
+
+		lease, err := dbSvc.ExtendLease("123", "user", Frozen, 1000, 100, 1000, 200)
+		require.Nil(t, err)
+		assert.Equal(t, Active, lease.LeaseStatus)
+		assert.Equal(t, LeaseActive, lease.LeaseStatusReason)
+		assert.Equal(t, float64(200), lease.BudgetAmount)
+	})
+
+	t.Run("ExtendLease should clear the fired lease rules", func(t *testing.T) {
+		dbSvc := NewMemoryDB(7)
+		_, err := dbSvc.PutLease(Lease{AccountID: "123", PrincipalID: "user", LeaseStatus: Active, ExpiresOn: 1000, BudgetAmount: 100, FiredRules: []string{"expiry-warning"}})
+		require.Nil(t, err)
+
+		lease, err := dbSvc.ExtendLease("123", "user", Active, 1000, 100, 2000, 100)
+		require.Nil(t, err)
+		assert.Empty(t, lease.FiredRules)
+	})
+
+	t.Run("UpdateAccount should only update the requested fields", func(t *testing.T) {
+		dbSvc := NewMemoryDB(7)
+		require.Nil(t, dbSvc.PutAccount(Account{
+			ID:            "123",
+			AccountStatus: Ready,
+			AdminRoleArn:  "arn:aws:iam::123:role/OldRole",
+		}))
+
+		account, err := dbSvc.UpdateAccount(Account{
+			ID:            "123",
+			AccountStatus: Leased,
+			AdminRoleArn:  "arn:aws:iam::123:role/NewRole",
+		}, []string{"AdminRoleArn"})
+		require.Nil(t, err)
+		assert.Equal(t, "arn:aws:iam::123:role/NewRole", account.AdminRoleArn)
+		assert.Equal(t, Ready, account.AccountStatus)
+
+		_, err = dbSvc.UpdateAccount(Account{ID: "456"}, []string{"AdminRoleArn"})
+		assert.IsType(t, &NotFoundError{}, err)
+	})
+
+	t.Run("UpdateLease should only update the requested fields", func(t *testing.T) {
+		dbSvc := NewMemoryDB(7)
+		_, err := dbSvc.PutLease(Lease{
+			AccountID:    "123",
+			PrincipalID:  "user",
+			LeaseStatus:  Active,
+			BudgetAmount: 100,
+		})
+		require.Nil(t, err)
+
+		lease, err := dbSvc.UpdateLease(Lease{
+			AccountID:    "123",
+			PrincipalID:  "user",
+			LeaseStatus:  Inactive,
+			BudgetAmount: 200,
+			FiredRules:   []string{"idle"},
+		}, []string{"FiredRules"})
+		require.Nil(t, err)
+		assert.Equal(t, []string{"idle"}, lease.FiredRules)
+		assert.Equal(t, Active, lease.LeaseStatus)
+		assert.Equal(t, 100.0, lease.BudgetAmount)
+
+		_, err = dbSvc.UpdateLease(Lease{AccountID: "456", PrincipalID: "user"}, []string{"FiredRules"})
+		assert.IsType(t, &NotFoundError{}, err)
+	})
+
+	t.Run("PutLease should apply defaults", func(t *testing.T) {
+		dbSvc := NewMemoryDB(7)
+		_, err := dbSvc.PutLease(Lease{AccountID: "123", PrincipalID: "user"})
+		require.Nil(t, err)
+
+		lease, err := dbSvc.GetLease("123", "user")
+		require.Nil(t, err)
+		assert.NotEmpty(t, lease.ID)
+		assert.InDelta(t, time.Now().AddDate(0, 0, 7).Unix(), lease.ExpiresOn, 60)
+	})
+
+	t.Run("GetLeases should filter and paginate", func(t *testing.T) {
+		dbSvc := NewMemoryDB(7)
+		for _, acctID := range []string{"1", "2", "3", "4", "5"} {
+			_, err := dbSvc.PutLease(Lease{
+				AccountID:   acctID,
+				PrincipalID: "user",
+				LeaseStatus: Active,
+			})
+			require.Nil(t, err)
+		}
+		_, err := dbSvc.TransitionLeaseStatus("2", "user", Active, Inactive, LeaseExpired)
+		require.Nil(t, err)
+
+		// First page
+		output, err := dbSvc.GetLeases(GetLeasesInput{Status: Active, Limit: 3})
+		require.Nil(t, err)
+		require.Len(t, output.Results, 2)
+		assert.Equal(t, "1", output.Results[0].AccountID)
+		assert.Equal(t, "3", output.Results[1].AccountID)
+		assert.Equal(t, map[string]string{"AccountId": "3", "PrincipalId": "user"}, output.NextKeys)
+
+		// Last page
+		output, err = dbSvc.GetLeases(GetLeasesInput{Status: Active, Limit: 3, StartKeys: output.NextKeys})
+		require.Nil(t, err)
+		require.Len(t, output.Results, 2)
+		assert.Equal(t, "4", output.Results[0].AccountID)
+		assert.Equal(t, "5", output.Results[1].AccountID)
+		assert.Empty(t, output.NextKeys)
+	})
+
+	t.Run("OrphanAccount should deactivate active and frozen leases", func(t *testing.T) {
+		dbSvc := NewMemoryDB(7)
+		require.Nil(t, dbSvc.PutAccount(Account{ID: "123", AccountStatus: Leased}))
+		_, err := dbSvc.PutLease(Lease{AccountID: "123", PrincipalID: "user", LeaseStatus: Active})
+		require.Nil(t, err)
+		_, err = dbSvc.PutLease(Lease{AccountID: "123", PrincipalID: "other", LeaseStatus: Frozen})
+		require.Nil(t, err)
+
+		account, err := dbSvc.OrphanAccount("123")
+		require.Nil(t, err)
+		assert.Equal(t, Orphaned, account.AccountStatus)
+
+		lease, err := dbSvc.GetLease("123", "user")
+		require.Nil(t, err)
+		assert.Equal(t, Inactive, lease.LeaseStatus)
+		assert.Equal(t, AccountOrphaned, lease.LeaseStatusReason)
+
+		lease, err = dbSvc.GetLease("123", "other")
+		require.Nil(t, err)
+		assert.Equal(t, Inactive, lease.LeaseStatus)
+	})
+}
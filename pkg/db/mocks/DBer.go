@@ -33,6 +33,52 @@ func (_m *DBer) DeleteAccount(accountID string) (*db.Account, error) {
 	return r0, r1
 }
 
+// DeleteLease provides a mock function with given fields: accountID, principalID
+func (_m *DBer) DeleteLease(accountID string, principalID string) (*db.Lease, error) {
+	ret := _m.Called(accountID, principalID)
+
+	var r0 *db.Lease
+	if rf, ok := ret.Get(0).(func(string, string) *db.Lease); ok {
+		r0 = rf(accountID, principalID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*db.Lease)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(accountID, principalID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExtendLease provides a mock function with given fields: accountID, principalID, prevStatus, prevExpiresOn, prevBudgetAmount, nextExpiresOn, nextBudgetAmount
+func (_m *DBer) ExtendLease(accountID string, principalID string, prevStatus db.LeaseStatus, prevExpiresOn int64, prevBudgetAmount float64, nextExpiresOn int64, nextBudgetAmount float64) (*db.Lease, error) {
+	ret := _m.Called(accountID, principalID, prevStatus, prevExpiresOn, prevBudgetAmount, nextExpiresOn, nextBudgetAmount)
+
+	var r0 *db.Lease
+	if rf, ok := ret.Get(0).(func(string, string, db.LeaseStatus, int64, float64, int64, float64) *db.Lease); ok {
+		r0 = rf(accountID, principalID, prevStatus, prevExpiresOn, prevBudgetAmount, nextExpiresOn, nextBudgetAmount)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*db.Lease)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, db.LeaseStatus, int64, float64, int64, float64) error); ok {
+		r1 = rf(accountID, principalID, prevStatus, prevExpiresOn, prevBudgetAmount, nextExpiresOn, nextBudgetAmount)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindAccountsByPrincipalID provides a mock function with given fields: principalID
 func (_m *DBer) FindAccountsByPrincipalID(principalID string) ([]*db.Account, error) {
 	ret := _m.Called(principalID)
@@ -413,6 +459,29 @@ func (_m *DBer) UpdateAccountPrincipalPolicyHash(accountID string, prevHash stri
 	return r0, r1
 }
 
+// UpdateLease provides a mock function with given fields: lease, fieldsToUpdate
+func (_m *DBer) UpdateLease(lease db.Lease, fieldsToUpdate []string) (*db.Lease, error) {
+	ret := _m.Called(lease, fieldsToUpdate)
+
+	var r0 *db.Lease
+	if rf, ok := ret.Get(0).(func(db.Lease, []string) *db.Lease); ok {
+		r0 = rf(lease, fieldsToUpdate)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*db.Lease)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(db.Lease, []string) error); ok {
+		r1 = rf(lease, fieldsToUpdate)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateMetadata provides a mock function with given fields: accountID, metadata
 func (_m *DBer) UpdateMetadata(accountID string, metadata map[string]interface{}) error {
 	ret := _m.Called(accountID, metadata)
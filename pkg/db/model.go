@@ -1,20 +1,185 @@
 package db
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 )
 
 // Account is a type corresponding to a Account table record
 type Account struct {
-	ID                  string                 `json:"Id"`             // AWS Account ID
-	AccountStatus       AccountStatus          `json:"AccountStatus"`  // Status of the AWS Account
-	LastModifiedOn      int64                  `json:"LastModifiedOn"` // Last Modified Epoch Timestamp
-	CreatedOn           int64                  `json:"CreatedOn"`
-	AdminRoleArn        string                 `json:"AdminRoleArn"`        // Assumed by the master account, to manage this user account
-	PrincipalRoleArn    string                 `json:"PrincipalRoleArn"`    // Assumed by principal users
-	PrincipalPolicyHash string                 `json:"PrincipalPolicyHash"` // The the hash of the policy version deployed
-	Metadata            map[string]interface{} `json:"Metadata"`            // Any org specific metadata pertaining to the account
+	ID                       string                 `json:"Id"`             // AWS Account ID
+	AccountStatus            AccountStatus          `json:"AccountStatus"`  // Status of the AWS Account
+	LastModifiedOn           int64                  `json:"LastModifiedOn"` // Last Modified Epoch Timestamp
+	CreatedOn                int64                  `json:"CreatedOn"`
+	AdminRoleArn             string                 `json:"AdminRoleArn"`             // Assumed by the master account, to manage this user account
+	PrincipalRoleArn         string                 `json:"PrincipalRoleArn"`         // Assumed by principal users
+	PrincipalPolicyHash      string                 `json:"PrincipalPolicyHash"`      // The the hash of the policy version deployed
+	PrincipalTrustPolicyHash string                 `json:"PrincipalTrustPolicyHash"` // The hash of the principal role's trust policy deployed
+	Metadata                 map[string]interface{} `json:"Metadata"`                 // Any org specific metadata pertaining to the account
+	Pool                     string                 `json:"Pool"`                     // Name of the account pool this account belongs to
+	Labels                   map[string]string      `json:"Labels"`                   // Labels used to select accounts for a lease
+	LeftoverResources        []string               `json:"LeftoverResources"`        // Resources which remained after the last reset, if the account is Quarantined
+	ResetStatus              *ResetStatus           `json:"ResetStatus"`              // Status of the latest reset of the account
+	NukeConfig               *NukeConfig            `json:"NukeConfig"`               // Overrides of the aws-nuke config used to reset the account
+	PolicyDrift              *PolicyDrift           `json:"PolicyDrift"`              // Result of the last check for changes to the principal IAM role and policy
+}
+
+// ResetStatus is the status of the latest reset of an account.
+//
+// ResetStatus is returned as-is by the accounts API, so it uses camelCase
+// JSON field names. It is stored in DynamoDB with the `dynamodbav` field names.
+type ResetStatus struct {
+	State          ResetState `json:"state" dynamodbav:"State"`
+	BuildID        string     `json:"buildId" dynamodbav:"BuildId"`               // ID of the build which runs the reset
+	Attempts       int        `json:"attempts" dynamodbav:"Attempts"`             // Number of consecutive failed resets
+	LastError      string     `json:"lastError" dynamodbav:"LastError"`           // Error from the last failed reset
+	NextAttemptOn  int64      `json:"nextAttemptOn" dynamodbav:"NextAttemptOn"`   // Epoch timestamp, when a failed reset is retried
+	LastModifiedOn int64      `json:"lastModifiedOn" dynamodbav:"LastModifiedOn"` // Epoch timestamp
+}
+
+// ResetState is the state of an account reset
+type ResetState string
+
+const (
+	// ResetQueued means a reset build was started, and is waiting to run
+	ResetQueued ResetState = "Queued"
+	// ResetRunning means the reset is running
+	ResetRunning ResetState = "Running"
+	// ResetSucceeded means the last reset completed
+	ResetSucceeded ResetState = "Succeeded"
+	// ResetFailed means the last reset failed.
+	// The reset is retried at NextAttemptOn, if set.
+	ResetFailed ResetState = "Failed"
+	// ResetAlarm means the reset failed too many times in a row,
+	// and will not be retried automatically
+	ResetAlarm ResetState = "Alarm"
+)
+
+// PolicyDrift is the result of the last check for changes made to the
+// principal IAM role and policy of an account, outside of DCE.
+type PolicyDrift struct {
+	PolicyDrifted      bool   `json:"policyDrifted" dynamodbav:"PolicyDrifted"`           // The principal policy differs from the rendered policy
+	TrustPolicyDrifted bool   `json:"trustPolicyDrifted" dynamodbav:"TrustPolicyDrifted"` // The principal role's trust policy differs from the rendered policy
+	BoundaryDrifted    bool   `json:"boundaryDrifted" dynamodbav:"BoundaryDrifted"`       // The principal role's permissions boundary differs from the configured boundary
+	DetectedOn         int64  `json:"detectedOn" dynamodbav:"DetectedOn"`                 // Epoch timestamp, when the current drift was first detected
+	CheckedOn          int64  `json:"checkedOn" dynamodbav:"CheckedOn"`                   // Epoch timestamp, of the last check
+	RemediatedOn       int64  `json:"remediatedOn" dynamodbav:"RemediatedOn"`             // Epoch timestamp, when drift was last reverted by DCE
+	LastError          string `json:"lastError" dynamodbav:"LastError"`                   // Error from the last check, if it failed
+}
+
+// Drifted returns true if the principal role or policy has drifted
+func (d *PolicyDrift) Drifted() bool {
+	return d != nil && (d.PolicyDrifted || d.TrustPolicyDrifted || d.BoundaryDrifted)
+}
+
+// NukeConfig overrides the aws-nuke config used to reset an account,
+// to keep resources which must survive resets.
+type NukeConfig struct {
+	// Filters are added to the aws-nuke filters of the account, by resource type,
+	// eg. "S3Bucket". Resources matching a filter are not deleted.
+	Filters map[string][]NukeFilter `json:"filters,omitempty" dynamodbav:"Filters,omitempty"`
+	// ExcludeResourceTypes are added to the resource types
+	// which are not deleted by aws-nuke
+	ExcludeResourceTypes []string `json:"excludeResourceTypes,omitempty" dynamodbav:"ExcludeResourceTypes,omitempty"`
+}
+
+// NukeFilter is an aws-nuke filter.
+// See https://github.com/rebuy-de/aws-nuke#filtering-resources
+type NukeFilter struct {
+	Type     string `json:"type,omitempty" dynamodbav:"Type,omitempty" yaml:"type,omitempty"`             // exact (default), contains, glob, regex or dateOlderThan
+	Property string `json:"property,omitempty" dynamodbav:"Property,omitempty" yaml:"property,omitempty"` // Property of the resource to match, instead of its name
+	Value    string `json:"value" dynamodbav:"Value" yaml:"value"`
+}
+
+// nukeFilterTypes are the filter types supported by aws-nuke
+var nukeFilterTypes = map[string]bool{
+	"":              true,
+	"exact":         true,
+	"contains":      true,
+	"glob":          true,
+	"regex":         true,
+	"dateOlderThan": true,
+}
+
+// Validate returns an error if the config has
+// empty resource types, or invalid filters
+func (c *NukeConfig) Validate() error {
+	for resourceType, filters := range c.Filters {
+		if strings.TrimSpace(resourceType) == "" {
+			return errors.New("nuke filters must have a resource type")
+		}
+		for _, filter := range filters {
+			err := filter.Validate()
+			if err != nil {
+				return fmt.Errorf("invalid nuke filter for %s: %s", resourceType, err)
+			}
+		}
+	}
+	for _, resourceType := range c.ExcludeResourceTypes {
+		if strings.TrimSpace(resourceType) == "" {
+			return errors.New("nuke excluded resource types must not be empty")
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if the filter type is not supported by
+// aws-nuke, the value is empty, or a regex filter does not compile
+func (f NukeFilter) Validate() error {
+	if !nukeFilterTypes[f.Type] {
+		return fmt.Errorf("unknown filter type %q", f.Type)
+	}
+	if f.Value == "" {
+		return errors.New("filter value must not be empty")
+	}
+	if f.Type == "regex" {
+		_, err := regexp.Compile(f.Value)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %s", f.Value, err)
+		}
+	}
+	return nil
+}
+
+// PoolName returns the name of the pool the account belongs to.
+// Accounts without a pool belong to the DefaultAccountPool.
+func (a *Account) PoolName() string {
+	if a.Pool == "" {
+		return DefaultAccountPool
+	}
+	return a.Pool
+}
+
+// DefaultAccountPool is the pool for accounts
+// which were not assigned a pool
+const DefaultAccountPool = "default"
+
+// AccountSelector selects accounts by pool and/or labels
+type AccountSelector struct {
+	Pool   string
+	Labels map[string]string
+}
+
+// IsEmpty returns true if the selector matches any account
+func (s AccountSelector) IsEmpty() bool {
+	return s.Pool == "" && len(s.Labels) == 0
+}
+
+// Matches returns true if the account belongs to the selected pool,
+// and has all of the selected labels
+func (s AccountSelector) Matches(account *Account) bool {
+	if s.Pool != "" && s.Pool != account.PoolName() {
+		return false
+	}
+	for key, val := range s.Labels {
+		accountVal, ok := account.Labels[key]
+		if !ok || accountVal != val {
+			return false
+		}
+	}
+	return true
 }
 
 // Lease is a type corresponding to a Lease
@@ -33,8 +198,35 @@ type Lease struct {
 	LeaseStatusModifiedOn    int64                  `json:"LeaseStatusModifiedOn"`    // Last Modified Epoch Timestamp
 	ExpiresOn                int64                  `json:"ExpiresOn"`                // Lease expiration time as Epoch
 	Metadata                 map[string]interface{} `json:"Metadata"`                 // Arbitrary key-value metadata to store with lease object
+	PendingExpiresOn         int64                  `json:"PendingExpiresOn"`         // Time as Epoch, after which a Pending lease will no longer be fulfilled
+	QueuePosition            int                    `json:"-"`                        // Position of a Pending lease in the waitlist. Calculated on read, not persisted.
+	AccountPool              string                 `json:"AccountPool"`              // Pool from which the account was selected
+	AccountLabels            map[string]string      `json:"AccountLabels"`            // Labels used to select the account
+	PolicyProfile            string                 `json:"PolicyProfile"`            // Name of the policy profile added to the principal policy
+	PolicyStatements         []PolicyStatement      `json:"PolicyStatements"`         // Additional statements added to the principal policy
+	FiredRules               []string               `json:"FiredRules"`               // Names of the lease rules which fired for the lease
+	ExpiryNotificationsSent  []float64              `json:"ExpiryNotificationsSent"`  // Expiry notifications sent for the lease, as hours before expiry
+	BudgetThresholdsSent     []float64              `json:"BudgetThresholdsSent"`     // Lease budget notification thresholds sent for the lease, as percentiles
+	PrincipalThresholdsSent  []float64              `json:"PrincipalThresholdsSent"`  // Principal budget notification thresholds sent for the lease, as percentiles
 }
 
+// PolicyStatement is a statement of an IAM policy document,
+// eg. {"Effect": "Allow", "Action": "athena:*", "Resource": "*"}
+type PolicyStatement map[string]interface{}
+
+// AccountSelector returns the selector used to
+// choose an account for the lease
+func (l *Lease) AccountSelector() AccountSelector {
+	return AccountSelector{
+		Pool:   l.AccountPool,
+		Labels: l.AccountLabels,
+	}
+}
+
+// PendingLeaseAccountID is the AccountId used for Pending leases,
+// which have not yet been assigned an account
+const PendingLeaseAccountID = "Pending"
+
 // Timestamp is a timestamp type for epoch format
 type Timestamp int64
 
@@ -58,6 +250,9 @@ const (
 	Leased AccountStatus = "Leased"
 	// Orphaned status
 	Orphaned AccountStatus = "Orphaned"
+	// Quarantined status means resources remained in the account
+	// after a reset, so the account may not be leased
+	Quarantined AccountStatus = "Quarantined"
 )
 
 // ParseAccountStatus - parses the string into an account status.
@@ -69,6 +264,8 @@ func ParseAccountStatus(status string) (AccountStatus, error) {
 		return NotReady, nil
 	case "leased":
 		return Leased, nil
+	case "quarantined":
+		return Quarantined, nil
 	}
 	return None, fmt.Errorf("Cannot parse value %s", status)
 }
@@ -81,6 +278,12 @@ const (
 	Active LeaseStatus = "Active"
 	// Inactive status
 	Inactive LeaseStatus = "Inactive"
+	// Pending status, for leases waiting for an account to become available
+	Pending LeaseStatus = "Pending"
+	// Frozen status, for leases which were stopped by a lease rule.
+	// The principal may no longer modify the account, and the account
+	// is reset once the grace period ends.
+	Frozen LeaseStatus = "Frozen"
 )
 
 // LeaseStatusReason provides consistent verbiage for lease status change reasons.
@@ -103,4 +306,18 @@ const (
 	// AccountOrphaned means that the health of the account was compromised.  The account has been orphaned
 	// which means the leases are also made Inactive
 	AccountOrphaned LeaseStatusReason = "AccountOrphaned"
+	// LeasePending means the lease is waiting for an account to become available.
+	LeasePending LeaseStatusReason = "Pending"
+	// LeasePendingTimeout means no account became available before the pending lease timed out.
+	LeasePendingTimeout LeaseStatusReason = "PendingTimeout"
+	// LeaseFulfilled means the pending lease was assigned an account, and replaced by an active lease.
+	LeaseFulfilled LeaseStatusReason = "Fulfilled"
+	// LeaseCancelled means the pending lease was cancelled via an API call or other user action.
+	LeaseCancelled LeaseStatusReason = "Cancelled"
+	// LeaseAccountReset means the lease was ended, because an admin forced a reset of the leased account.
+	LeaseAccountReset LeaseStatusReason = "AccountReset"
+	// LeaseIdle means the leased account had no spend for longer than the configured idle time.
+	LeaseIdle LeaseStatusReason = "Idle"
+	// LeaseOverSpendVelocity means the daily spend of the lease exceeded the configured rate.
+	LeaseOverSpendVelocity LeaseStatusReason = "OverSpendVelocity"
 )
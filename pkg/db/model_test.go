@@ -0,0 +1,47 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNukeConfigValidate(t *testing.T) {
+	valid := &NukeConfig{
+		Filters: map[string][]NukeFilter{
+			"S3Bucket": {
+				{Value: "s3://shared-fixtures"},
+				{Type: "glob", Value: "s3://fixtures-*"},
+			},
+			"IAMRole": {
+				{Type: "regex", Property: "Name", Value: "^fixture-.+$"},
+			},
+		},
+		ExcludeResourceTypes: []string{"Route53HostedZone"},
+	}
+	require.Nil(t, valid.Validate())
+	require.Nil(t, (&NukeConfig{}).Validate())
+
+	tests := map[string]*NukeConfig{
+		"unknown filter type": {
+			Filters: map[string][]NukeFilter{"S3Bucket": {{Type: "prefix", Value: "s3://"}}},
+		},
+		"empty filter value": {
+			Filters: map[string][]NukeFilter{"S3Bucket": {{Type: "glob"}}},
+		},
+		"invalid regex": {
+			Filters: map[string][]NukeFilter{"S3Bucket": {{Type: "regex", Value: "fixture-("}}},
+		},
+		"empty resource type": {
+			Filters: map[string][]NukeFilter{"": {{Value: "fixture"}}},
+		},
+		"empty excluded resource type": {
+			ExcludeResourceTypes: []string{" "},
+		},
+	}
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.NotNil(t, config.Validate())
+		})
+	}
+}
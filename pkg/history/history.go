@@ -0,0 +1,172 @@
+// Package history records an append-only history of changes
+// made to accounts and leases.
+//
+// Records are stored in the History table, keyed by the ID
+// of the account or lease which was changed.
+package history
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Optum/dce/pkg/common"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Record is a single change made to an account or lease
+type Record struct {
+	ResourceID   string       `json:"ResourceId"`   // ID of the Account or Lease which was changed
+	Timestamp    int64        `json:"Timestamp"`    // Epoch timestamp of the change, in nanoseconds
+	ResourceType ResourceType `json:"ResourceType"` // Type of the resource which was changed
+	Event        Event        `json:"Event"`        // Type of change
+	Changes      []Change     `json:"Changes"`      // Fields which were changed
+	Actor        string       `json:"Actor"`        // User or system component which made the change
+	Reason       string       `json:"Reason"`       // Reason for the change
+	AccountID    string       `json:"AccountId"`    // AWS Account ID
+	PrincipalID  string       `json:"PrincipalId"`  // Principal ID of the lease (for lease records)
+	LeaseID      string       `json:"LeaseId"`      // Lease ID (for lease records)
+}
+
+// Change is a change to a single field of an account or lease
+type Change struct {
+	Field     string `json:"Field"`
+	PrevValue string `json:"PrevValue"`
+	NextValue string `json:"NextValue"`
+}
+
+// ResourceType is the type of resource a history record refers to
+type ResourceType string
+
+const (
+	// AccountResource is used for changes to accounts
+	AccountResource ResourceType = "Account"
+	// LeaseResource is used for changes to leases
+	LeaseResource ResourceType = "Lease"
+)
+
+// Event is the type of change made to a resource
+type Event string
+
+const (
+	// Created means the resource was created
+	Created Event = "Created"
+	// Updated means one or more fields of the resource were modified
+	Updated Event = "Updated"
+	// StatusChanged means the status of the resource was transitioned
+	StatusChanged Event = "StatusChanged"
+	// Deleted means the resource was deleted
+	Deleted Event = "Deleted"
+)
+
+// The Service interface includes all methods used by the DB struct to interact with
+// History DynamoDB. This is useful if we want to mock the DB service.
+type Service interface {
+	PutRecord(record Record) error
+	GetHistory(resourceID string) ([]*Record, error)
+}
+
+// DB contains DynamoDB client and table names
+type DB struct {
+	// DynamoDB Client
+	Client *dynamodb.DynamoDB
+	// Name of the History table
+	HistoryTableName string
+	// Use Consistent Reads when querying
+	ConsistentRead bool
+}
+
+// PutRecord adds a record to the History DB
+func (db *DB) PutRecord(record Record) error {
+	item, err := dynamodbattribute.MarshalMap(record)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal history record for %s: %s", record.ResourceID, err)
+	}
+
+	_, err = db.Client.PutItem(
+		&dynamodb.PutItemInput{
+			TableName: aws.String(db.HistoryTableName),
+			Item:      item,
+			// Records are append-only
+			ConditionExpression: aws.String("attribute_not_exists(ResourceId)"),
+		},
+	)
+	return err
+}
+
+// GetHistory returns all history records for an account or lease,
+// oldest first
+func (db *DB) GetHistory(resourceID string) ([]*Record, error) {
+	records := []*Record{}
+	var startKey map[string]*dynamodb.AttributeValue
+
+	for {
+		res, err := db.Client.Query(&dynamodb.QueryInput{
+			TableName: aws.String(db.HistoryTableName),
+			KeyConditions: map[string]*dynamodb.Condition{
+				"ResourceId": {
+					ComparisonOperator: aws.String("EQ"),
+					AttributeValueList: []*dynamodb.AttributeValue{
+						{S: aws.String(resourceID)},
+					},
+				},
+			},
+			ExclusiveStartKey: startKey,
+			ScanIndexForward:  aws.Bool(true),
+			ConsistentRead:    aws.Bool(db.ConsistentRead),
+		})
+		if err != nil {
+			log.Printf("Failed to query history for %s: %s", resourceID, err)
+			return nil, err
+		}
+
+		for _, item := range res.Items {
+			record := Record{}
+			err := dynamodbattribute.UnmarshalMap(item, &record)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to unmarshal history record for %s: %s", resourceID, err)
+			}
+			records = append(records, &record)
+		}
+
+		if len(res.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = res.LastEvaluatedKey
+	}
+
+	return records, nil
+}
+
+// New creates a new history DB Service struct,
+// with all the necessary fields configured.
+func New(client *dynamodb.DynamoDB, historyTableName string) *DB {
+	return &DB{
+		Client:           client,
+		HistoryTableName: historyTableName,
+		ConsistentRead:   false,
+	}
+}
+
+/*
+NewFromEnv creates a DB instance configured from environment variables.
+Requires env vars for:
+
+- AWS_CURRENT_REGION
+- HISTORY_DB
+*/
+func NewFromEnv() (*DB, error) {
+	awsSession, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return New(
+		dynamodb.New(
+			awsSession,
+			aws.NewConfig().WithRegion(common.RequireEnv("AWS_CURRENT_REGION")),
+		),
+		common.RequireEnv("HISTORY_DB"),
+	), nil
+}
@@ -0,0 +1,48 @@
+package history
+
+import (
+	"sort"
+	"sync"
+)
+
+// Memory is an in-memory implementation of the history Service,
+// for local runs and tests.
+type Memory struct {
+	mu      sync.RWMutex
+	records map[string][]Record
+}
+
+// NewMemory creates an empty in-memory history store
+func NewMemory() *Memory {
+	return &Memory{
+		records: map[string][]Record{},
+	}
+}
+
+// PutRecord adds a record to the history
+func (m *Memory) PutRecord(record Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record.Changes = append([]Change{}, record.Changes...)
+	m.records[record.ResourceID] = append(m.records[record.ResourceID], record)
+	return nil
+}
+
+// GetHistory returns all history records for an account or lease,
+// oldest first
+func (m *Memory) GetHistory(resourceID string) ([]*Record, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	records := []*Record{}
+	for _, record := range m.records[resourceID] {
+		r := record
+		r.Changes = append([]Change{}, record.Changes...)
+		records = append(records, &r)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp < records[j].Timestamp
+	})
+	return records, nil
+}
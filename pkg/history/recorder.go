@@ -0,0 +1,462 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/Optum/dce/pkg/db"
+	"gopkg.in/oleiade/reflections.v1"
+)
+
+// Recorder wraps a db.DBer, and records a history record
+// for each change made to an account or lease.
+//
+// Failures to write history records are logged, but do
+// not fail the change to the account or lease.
+type Recorder struct {
+	db.DBer
+	History Service
+	// Actor is the user or system component making changes
+	Actor string
+}
+
+// NewRecorder creates a Recorder, which records changes made
+// through the DBer under the given actor.
+func NewRecorder(dbSvc db.DBer, historySvc Service, actor string) *Recorder {
+	return &Recorder{
+		DBer:    dbSvc,
+		History: historySvc,
+		Actor:   actor,
+	}
+}
+
+// WithActor returns a copy of the Recorder,
+// which records changes under the given actor
+func (r *Recorder) WithActor(actor string) *Recorder {
+	return NewRecorder(r.DBer, r.History, actor)
+}
+
+// WithActor returns a DBer which records changes under the given actor.
+// DBers which do not record history are returned as-is.
+func WithActor(dbSvc db.DBer, actor string) db.DBer {
+	if recorder, ok := dbSvc.(*Recorder); ok {
+		return recorder.WithActor(actor)
+	}
+	return dbSvc
+}
+
+// PutAccount records the creation of an account
+func (r *Recorder) PutAccount(account db.Account) error {
+	err := r.DBer.PutAccount(account)
+	if err != nil {
+		return err
+	}
+
+	r.record(Record{
+		ResourceID:   account.ID,
+		ResourceType: AccountResource,
+		Event:        Created,
+		AccountID:    account.ID,
+		Changes: []Change{
+			{Field: "AccountStatus", NextValue: string(account.AccountStatus)},
+		},
+	})
+	return nil
+}
+
+// UpdateAccount records each updated field of an account
+func (r *Recorder) UpdateAccount(account db.Account, fieldsToUpdate []string) (*db.Account, error) {
+	prevAccount := r.getAccount(account.ID)
+
+	nextAccount, err := r.DBer.UpdateAccount(account, fieldsToUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	for _, field := range fieldsToUpdate {
+		change := Change{Field: field}
+		if prevAccount != nil {
+			prevVal, _ := reflections.GetField(prevAccount, field)
+			change.PrevValue = formatValue(prevVal)
+		}
+		nextVal, _ := reflections.GetField(nextAccount, field)
+		change.NextValue = formatValue(nextVal)
+		if change.PrevValue != change.NextValue {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) > 0 {
+		r.record(Record{
+			ResourceID:   account.ID,
+			ResourceType: AccountResource,
+			Event:        Updated,
+			AccountID:    account.ID,
+			Changes:      changes,
+		})
+	}
+
+	return nextAccount, nil
+}
+
+// DeleteAccount records the deletion of an account
+func (r *Recorder) DeleteAccount(accountID string) (*db.Account, error) {
+	account, err := r.DBer.DeleteAccount(accountID)
+	if err != nil {
+		return account, err
+	}
+
+	r.record(Record{
+		ResourceID:   accountID,
+		ResourceType: AccountResource,
+		Event:        Deleted,
+		AccountID:    accountID,
+	})
+	return account, nil
+}
+
+// UpsertLease records the creation of a lease
+func (r *Recorder) UpsertLease(lease db.Lease) (*db.Lease, error) {
+	nextLease, err := r.DBer.UpsertLease(lease)
+	if err != nil {
+		return nil, err
+	}
+
+	r.recordLeaseCreated(nextLease)
+	return nextLease, nil
+}
+
+// PutLease records the creation of a lease
+func (r *Recorder) PutLease(lease db.Lease) (*db.Lease, error) {
+	prevLease, err := r.DBer.PutLease(lease)
+	if err != nil {
+		return nil, err
+	}
+
+	// PutLease returns the previous lease, so lookup the written lease,
+	// which is assigned an ID if the lease did not have one
+	if nextLease := r.getLease(lease.AccountID, lease.PrincipalID); nextLease != nil {
+		r.recordLeaseCreated(nextLease)
+	}
+	return prevLease, nil
+}
+
+// DeleteLease records the deletion of a lease
+func (r *Recorder) DeleteLease(accountID string, principalID string) (*db.Lease, error) {
+	lease, err := r.DBer.DeleteLease(accountID, principalID)
+	if err != nil || lease == nil {
+		return lease, err
+	}
+
+	r.recordLease(Record{
+		ResourceID:   lease.ID,
+		ResourceType: LeaseResource,
+		Event:        Deleted,
+	}, lease)
+	return lease, nil
+}
+
+// UpdateLease records each updated field of a lease
+func (r *Recorder) UpdateLease(lease db.Lease, fieldsToUpdate []string) (*db.Lease, error) {
+	prevLease := r.getLease(lease.AccountID, lease.PrincipalID)
+
+	nextLease, err := r.DBer.UpdateLease(lease, fieldsToUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	for _, field := range fieldsToUpdate {
+		change := Change{Field: field}
+		if prevLease != nil {
+			prevVal, _ := reflections.GetField(prevLease, field)
+			change.PrevValue = formatValue(prevVal)
+		}
+		nextVal, _ := reflections.GetField(nextLease, field)
+		change.NextValue = formatValue(nextVal)
+		if change.PrevValue != change.NextValue {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) > 0 {
+		r.recordLease(Record{
+			ResourceID:   nextLease.ID,
+			ResourceType: LeaseResource,
+			Event:        Updated,
+			Changes:      changes,
+		}, nextLease)
+	}
+
+	return nextLease, nil
+}
+
+// TransitionAccountStatus records the account status change
+func (r *Recorder) TransitionAccountStatus(accountID string, prevStatus db.AccountStatus, nextStatus db.AccountStatus) (*db.Account, error) {
+	account, err := r.DBer.TransitionAccountStatus(accountID, prevStatus, nextStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	r.record(Record{
+		ResourceID:   accountID,
+		ResourceType: AccountResource,
+		Event:        StatusChanged,
+		AccountID:    accountID,
+		Changes: []Change{
+			{Field: "AccountStatus", PrevValue: string(prevStatus), NextValue: string(nextStatus)},
+		},
+	})
+	return account, nil
+}
+
+// TransitionLeaseStatus records the lease status change,
+// with the lease status reason
+func (r *Recorder) TransitionLeaseStatus(accountID string, principalID string, prevStatus db.LeaseStatus, nextStatus db.LeaseStatus, leaseStatusReason db.LeaseStatusReason) (*db.Lease, error) {
+	lease, err := r.DBer.TransitionLeaseStatus(accountID, principalID, prevStatus, nextStatus, leaseStatusReason)
+	if err != nil {
+		return nil, err
+	}
+
+	r.recordLease(Record{
+		ResourceID:   lease.ID,
+		ResourceType: LeaseResource,
+		Event:        StatusChanged,
+		Reason:       string(leaseStatusReason),
+		Changes: []Change{
+			{Field: "LeaseStatus", PrevValue: string(prevStatus), NextValue: string(nextStatus)},
+		},
+	}, lease)
+	return lease, nil
+}
+
+// ExtendLease records the updated expiry date and budget of the lease,
+// and the lease status change, if the lease was unfrozen
+func (r *Recorder) ExtendLease(accountID string, principalID string, prevStatus db.LeaseStatus, prevExpiresOn int64, prevBudgetAmount float64, nextExpiresOn int64, nextBudgetAmount float64) (*db.Lease, error) {
+	lease, err := r.DBer.ExtendLease(accountID, principalID, prevStatus, prevExpiresOn, prevBudgetAmount, nextExpiresOn, nextBudgetAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	if prevStatus != lease.LeaseStatus {
+		changes = append(changes, Change{
+			Field:     "LeaseStatus",
+			PrevValue: string(prevStatus),
+			NextValue: string(lease.LeaseStatus),
+		})
+	}
+	if prevExpiresOn != nextExpiresOn {
+		changes = append(changes, Change{
+			Field:     "ExpiresOn",
+			PrevValue: formatValue(prevExpiresOn),
+			NextValue: formatValue(nextExpiresOn),
+		})
+	}
+	if prevBudgetAmount != nextBudgetAmount {
+		changes = append(changes, Change{
+			Field:     "BudgetAmount",
+			PrevValue: formatValue(prevBudgetAmount),
+			NextValue: formatValue(nextBudgetAmount),
+		})
+	}
+	r.recordLease(Record{
+		ResourceID:   lease.ID,
+		ResourceType: LeaseResource,
+		Event:        Updated,
+		Reason:       "Extended",
+		Changes:      changes,
+	}, lease)
+	return lease, nil
+}
+
+// UpdateMetadata records the updated account metadata
+func (r *Recorder) UpdateMetadata(accountID string, metadata map[string]interface{}) error {
+	prevMetadata := ""
+	if prevAccount := r.getAccount(accountID); prevAccount != nil {
+		prevMetadata = formatValue(prevAccount.Metadata)
+	}
+
+	err := r.DBer.UpdateMetadata(accountID, metadata)
+	if err != nil {
+		return err
+	}
+
+	r.record(Record{
+		ResourceID:   accountID,
+		ResourceType: AccountResource,
+		Event:        Updated,
+		AccountID:    accountID,
+		Changes: []Change{
+			{Field: "Metadata", PrevValue: prevMetadata, NextValue: formatValue(metadata)},
+		},
+	})
+	return nil
+}
+
+// UpdateAccountPrincipalPolicyHash records the updated principal policy hash
+func (r *Recorder) UpdateAccountPrincipalPolicyHash(accountID string, prevHash string, nextHash string) (*db.Account, error) {
+	account, err := r.DBer.UpdateAccountPrincipalPolicyHash(accountID, prevHash, nextHash)
+	if err != nil {
+		return nil, err
+	}
+
+	r.record(Record{
+		ResourceID:   accountID,
+		ResourceType: AccountResource,
+		Event:        Updated,
+		AccountID:    accountID,
+		Changes: []Change{
+			{Field: "PrincipalPolicyHash", PrevValue: prevHash, NextValue: nextHash},
+		},
+	})
+	return account, nil
+}
+
+// OrphanAccount records the account status change,
+// and the deactivation of any active or frozen leases on the account
+func (r *Recorder) OrphanAccount(accountID string) (*db.Account, error) {
+	prevAccount := r.getAccount(accountID)
+	leases, err := r.DBer.FindLeasesByAccount(accountID)
+	if err != nil {
+		log.Printf("Failed to lookup leases for account %s for history: %s", accountID, err)
+	}
+
+	account, err := r.DBer.OrphanAccount(accountID)
+	if account == nil {
+		return nil, err
+	}
+
+	prevStatus := ""
+	if prevAccount != nil {
+		prevStatus = string(prevAccount.AccountStatus)
+	}
+	r.record(Record{
+		ResourceID:   accountID,
+		ResourceType: AccountResource,
+		Event:        StatusChanged,
+		Reason:       string(db.AccountOrphaned),
+		AccountID:    accountID,
+		Changes: []Change{
+			{Field: "AccountStatus", PrevValue: prevStatus, NextValue: string(db.Orphaned)},
+		},
+	})
+
+	// Leases are only deactivated if the account was orphaned
+	if err == nil {
+		for _, lease := range leases {
+			if lease.LeaseStatus != db.Active && lease.LeaseStatus != db.Frozen {
+				continue
+			}
+			r.recordLease(Record{
+				ResourceID:   lease.ID,
+				ResourceType: LeaseResource,
+				Event:        StatusChanged,
+				Reason:       string(db.AccountOrphaned),
+				Changes: []Change{
+					{Field: "LeaseStatus", PrevValue: string(lease.LeaseStatus), NextValue: string(db.Inactive)},
+				},
+			}, lease)
+		}
+	}
+
+	return account, err
+}
+
+func (r *Recorder) recordLeaseCreated(lease *db.Lease) {
+	r.recordLease(Record{
+		ResourceID:   lease.ID,
+		ResourceType: LeaseResource,
+		Event:        Created,
+		Reason:       string(lease.LeaseStatusReason),
+		Changes: []Change{
+			{Field: "LeaseStatus", NextValue: string(lease.LeaseStatus)},
+		},
+	}, lease)
+}
+
+// recordLease records a change to a lease in the lease's history,
+// and in the history of the leased account.
+func (r *Recorder) recordLease(record Record, lease *db.Lease) {
+	record.AccountID = lease.AccountID
+	record.PrincipalID = lease.PrincipalID
+	record.LeaseID = lease.ID
+	r.record(record)
+
+	// Pending leases are not yet assigned an account
+	if lease.AccountID != db.PendingLeaseAccountID {
+		record.ResourceID = lease.AccountID
+		r.record(record)
+	}
+}
+
+func (r *Recorder) record(record Record) {
+	record.Timestamp = time.Now().UnixNano()
+	record.Actor = r.Actor
+
+	err := r.History.PutRecord(record)
+	if err != nil {
+		log.Printf("Failed to write %s %s history record for %s: %s",
+			record.ResourceType, record.Event, record.ResourceID, err)
+	}
+}
+
+// getAccount returns the current account record,
+// or nil if the account could not be found.
+func (r *Recorder) getAccount(accountID string) *db.Account {
+	account, err := r.DBer.GetAccount(accountID)
+	if err != nil {
+		log.Printf("Failed to lookup account %s for history: %s", accountID, err)
+		return nil
+	}
+	return account
+}
+
+// getLease returns the current lease record,
+// or nil if the lease could not be found.
+func (r *Recorder) getLease(accountID string, principalID string) *db.Lease {
+	leases, err := r.DBer.FindLeasesByAccount(accountID)
+	if err != nil {
+		log.Printf("Failed to lookup lease %s/%s for history: %s", accountID, principalID, err)
+		return nil
+	}
+	for _, lease := range leases {
+		if lease.PrincipalID == principalID {
+			return lease
+		}
+	}
+	return nil
+}
+
+// formatValue formats a field value as a string,
+// for a history record
+func formatValue(val interface{}) string {
+	if val == nil {
+		return ""
+	}
+	switch v := val.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return ""
+		}
+		fallthrough
+	case reflect.Struct:
+		valJSON, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+		return string(valJSON)
+	}
+	return fmt.Sprintf("%v", val)
+}
@@ -0,0 +1,193 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/Optum/dce/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ db.DBer = &Recorder{}
+var _ Service = &Memory{}
+
+func TestRecorder(t *testing.T) {
+
+	newRecorder := func(t *testing.T) (*Recorder, *Memory) {
+		historySvc := NewMemory()
+		recorder := NewRecorder(db.NewMemoryDB(7), historySvc, "test")
+		require.Nil(t, recorder.PutAccount(db.Account{
+			ID:            "123",
+			AccountStatus: db.Ready,
+			Metadata:      map[string]interface{}{"foo": "bar"},
+		}))
+		return recorder, historySvc
+	}
+
+	t.Run("should record account status transitions", func(t *testing.T) {
+		recorder, historySvc := newRecorder(t)
+
+		_, err := recorder.WithActor("jdoe").TransitionAccountStatus("123", db.Ready, db.NotReady)
+		require.Nil(t, err)
+
+		records, err := historySvc.GetHistory("123")
+		require.Nil(t, err)
+		require.Len(t, records, 2)
+
+		assert.Equal(t, Created, records[0].Event)
+		assert.Equal(t, "test", records[0].Actor)
+
+		assert.Equal(t, AccountResource, records[1].ResourceType)
+		assert.Equal(t, StatusChanged, records[1].Event)
+		assert.Equal(t, "jdoe", records[1].Actor)
+		assert.Equal(t, []Change{
+			{Field: "AccountStatus", PrevValue: "Ready", NextValue: "NotReady"},
+		}, records[1].Changes)
+	})
+
+	t.Run("should not record failed transitions", func(t *testing.T) {
+		recorder, historySvc := newRecorder(t)
+
+		_, err := recorder.TransitionAccountStatus("123", db.Leased, db.NotReady)
+		require.IsType(t, &db.StatusTransitionError{}, err)
+
+		records, err := historySvc.GetHistory("123")
+		require.Nil(t, err)
+		require.Len(t, records, 1)
+	})
+
+	t.Run("should record changed account fields", func(t *testing.T) {
+		recorder, historySvc := newRecorder(t)
+
+		_, err := recorder.UpdateAccount(db.Account{
+			ID:           "123",
+			AdminRoleArn: "arn:admin",
+			Metadata:     map[string]interface{}{"foo": "bar"},
+		}, []string{"AdminRoleArn", "Metadata"})
+		require.Nil(t, err)
+		_, err = recorder.UpdateAccountPrincipalPolicyHash("123", "", "hash")
+		require.Nil(t, err)
+
+		records, err := historySvc.GetHistory("123")
+		require.Nil(t, err)
+		require.Len(t, records, 3)
+		// Unchanged metadata is not recorded
+		assert.Equal(t, Updated, records[1].Event)
+		assert.Equal(t, []Change{
+			{Field: "AdminRoleArn", PrevValue: "", NextValue: "arn:admin"},
+		}, records[1].Changes)
+		assert.Equal(t, []Change{
+			{Field: "PrincipalPolicyHash", PrevValue: "", NextValue: "hash"},
+		}, records[2].Changes)
+	})
+
+	t.Run("should record lease changes on the lease and account", func(t *testing.T) {
+		recorder, historySvc := newRecorder(t)
+
+		_, err := recorder.UpsertLease(db.Lease{
+			ID:                "lease-1",
+			AccountID:         "123",
+			PrincipalID:       "jdoe",
+			LeaseStatus:       db.Active,
+			LeaseStatusReason: db.LeaseActive,
+			BudgetAmount:      100,
+			ExpiresOn:         1000,
+		})
+		require.Nil(t, err)
+		_, err = recorder.ExtendLease("123", "jdoe", db.Active, 1000, 100, 2000, 150)
+		require.Nil(t, err)
+		_, err = recorder.TransitionLeaseStatus("123", "jdoe", db.Active, db.Inactive, db.LeaseExpired)
+		require.Nil(t, err)
+
+		records, err := historySvc.GetHistory("lease-1")
+		require.Nil(t, err)
+		require.Len(t, records, 3)
+		for _, record := range records {
+			assert.Equal(t, LeaseResource, record.ResourceType)
+			assert.Equal(t, "lease-1", record.LeaseID)
+			assert.Equal(t, "jdoe", record.PrincipalID)
+			assert.Equal(t, "123", record.AccountID)
+		}
+		assert.Equal(t, Created, records[0].Event)
+		assert.Equal(t, Updated, records[1].Event)
+		assert.Equal(t, []Change{
+			{Field: "ExpiresOn", PrevValue: "1000", NextValue: "2000"},
+			{Field: "BudgetAmount", PrevValue: "100", NextValue: "150"},
+		}, records[1].Changes)
+		assert.Equal(t, StatusChanged, records[2].Event)
+		assert.Equal(t, string(db.LeaseExpired), records[2].Reason)
+
+		// Lease changes are also recorded in the account history
+		accountRecords, err := historySvc.GetHistory("123")
+		require.Nil(t, err)
+		require.Len(t, accountRecords, 4)
+		assert.Equal(t, "lease-1", accountRecords[3].LeaseID)
+		assert.Equal(t, string(db.LeaseExpired), accountRecords[3].Reason)
+	})
+
+	t.Run("should record created and deleted leases", func(t *testing.T) {
+		recorder, historySvc := newRecorder(t)
+
+		_, err := recorder.PutLease(db.Lease{
+			ID:                "lease-1",
+			AccountID:         "123",
+			PrincipalID:       "jdoe",
+			LeaseStatus:       db.Active,
+			LeaseStatusReason: db.LeaseActive,
+		})
+		require.Nil(t, err)
+		_, err = recorder.DeleteLease("123", "jdoe")
+		require.Nil(t, err)
+		// Deleting a missing lease is not recorded
+		_, err = recorder.DeleteLease("123", "jdoe")
+		require.Nil(t, err)
+
+		records, err := historySvc.GetHistory("lease-1")
+		require.Nil(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, Created, records[0].Event)
+		assert.Equal(t, []Change{
+			{Field: "LeaseStatus", NextValue: "Active"},
+		}, records[0].Changes)
+		assert.Equal(t, Deleted, records[1].Event)
+		assert.Equal(t, "123", records[1].AccountID)
+	})
+
+	t.Run("should record orphaned accounts and leases", func(t *testing.T) {
+		recorder, historySvc := newRecorder(t)
+
+		_, err := recorder.UpsertLease(db.Lease{
+			ID:          "lease-1",
+			AccountID:   "123",
+			PrincipalID: "jdoe",
+			LeaseStatus: db.Active,
+			ExpiresOn:   1000,
+		})
+		require.Nil(t, err)
+		_, err = recorder.OrphanAccount("123")
+		require.Nil(t, err)
+
+		records, err := historySvc.GetHistory("lease-1")
+		require.Nil(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, StatusChanged, records[1].Event)
+		assert.Equal(t, string(db.AccountOrphaned), records[1].Reason)
+
+		accountRecords, err := historySvc.GetHistory("123")
+		require.Nil(t, err)
+		require.Len(t, accountRecords, 4)
+		assert.Equal(t, []Change{
+			{Field: "AccountStatus", PrevValue: "Ready", NextValue: "Orphaned"},
+		}, accountRecords[2].Changes)
+	})
+}
+
+func TestWithActor(t *testing.T) {
+	dbSvc := db.NewMemoryDB(7)
+	assert.Equal(t, dbSvc, WithActor(dbSvc, "jdoe"))
+
+	recorder := NewRecorder(dbSvc, NewMemory(), "test")
+	withActor := WithActor(recorder, "jdoe").(*Recorder)
+	assert.Equal(t, "jdoe", withActor.Actor)
+	assert.Equal(t, "test", recorder.Actor)
+}
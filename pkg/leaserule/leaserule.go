@@ -0,0 +1,345 @@
+// Package leaserule decides when active leases should end.
+//
+// Admins define lease rules, eg. to end leases which are idle for a day, or
+// which spend more than $50 a day. Each rule has an action (warn, freeze,
+// or end) and a lease status reason. Rules are evaluated in order by the
+// update_lease_status Lambda, which records the rules which fired on the lease.
+package leaserule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Optum/dce/pkg/db"
+)
+
+// Type is the type of condition checked by a rule
+type Type string
+
+const (
+	// Expiry rules fire when the lease passes its expiresOn date,
+	// or `beforeHours` before it.
+	Expiry Type = "expiry"
+	// Idle rules fire when the leased account has no spend
+	// for `idleHours`
+	Idle Type = "idle"
+	// Spend rules fire when the lease (or principal) spend
+	// exceeds a percentage of the budget, or a fixed amount
+	Spend Type = "spend"
+	// SpendVelocity rules fire when the average daily spend of the lease
+	// over the last `days` exceeds `maxDailySpend`
+	SpendVelocity Type = "spendVelocity"
+	// Metadata rules fire when the lease metadata has
+	// the `metadataKey`, with the `metadataValue`
+	Metadata Type = "metadata"
+)
+
+// Action is what happens to the lease when a rule fires
+type Action string
+
+const (
+	// Warn records the rule on the lease, and emails the lease owner,
+	// without changing the lease status
+	Warn Action = "warn"
+	// Freeze stops the lease, without resetting the account.
+	// The lease is ended, and the account reset, after a grace period.
+	Freeze Action = "freeze"
+	// End ends the lease, and resets the account
+	End Action = "end"
+)
+
+// MaxUsageDays is the number of days of usage which rules may check.
+// Usage records expire a month after they are recorded, so idle and
+// spend velocity rules cannot look back further than the shortest month.
+const MaxUsageDays = 28
+
+// Budgets checked by spend rules
+const (
+	LeaseBudget     = "lease"
+	PrincipalBudget = "principal"
+)
+
+// Rule is a condition on an active lease
+type Rule struct {
+	// Name identifies the rule, and is recorded on the lease when the rule fires
+	Name   string `json:"name"`
+	Type   Type   `json:"type"`
+	Action Action `json:"action"`
+	// Reason is the lease status reason, when the rule freezes or ends the lease.
+	// Defaults to a reason for the rule type.
+	Reason db.LeaseStatusReason `json:"reason,omitempty"`
+
+	// BeforeHours fires expiry rules before the lease expires
+	BeforeHours float64 `json:"beforeHours,omitempty"`
+	// IdleHours is the time without spend, after which idle rules fire.
+	// Days with spend of IdleSpendAmount or less count as idle.
+	IdleHours       float64 `json:"idleHours,omitempty"`
+	IdleSpendAmount float64 `json:"idleSpendAmount,omitempty"`
+	// Budget is the budget checked by spend rules ("lease" or "principal").
+	// Spend rules fire when spend exceeds Amount, or if Amount is not set,
+	// Percent (default 100) of the budget.
+	Budget  string  `json:"budget,omitempty"`
+	Percent float64 `json:"percent,omitempty"`
+	Amount  float64 `json:"amount,omitempty"`
+	// MaxDailySpend and Days configure spend velocity rules
+	MaxDailySpend float64 `json:"maxDailySpend,omitempty"`
+	Days          int     `json:"days,omitempty"`
+	// MetadataKey and MetadataValue configure metadata rules.
+	// If MetadataValue is empty, the rule fires for any value.
+	MetadataKey   string `json:"metadataKey,omitempty"`
+	MetadataValue string `json:"metadataValue,omitempty"`
+}
+
+// DailySpend is the spend of a lease on a single day
+type DailySpend struct {
+	// StartDate is the beginning of the day, as Epoch
+	StartDate int64
+	Amount    float64
+}
+
+// State is the current state of a lease,
+// against which rules are evaluated
+type State struct {
+	// Now is the current time, as Epoch
+	Now                   int64
+	LeaseSpend            float64
+	PrincipalSpend        float64
+	PrincipalBudgetAmount float64
+	// DailySpend is the spend of the lease on each day
+	// since the lease was activated
+	DailySpend []DailySpend
+}
+
+// Result is the outcome of evaluating rules against a lease
+type Result struct {
+	// Fired are the rules which fired, in order
+	Fired []*Rule
+	// Enforced is the first rule which fired with the freeze or end action,
+	// or nil if the lease should remain active
+	Enforced *Rule
+}
+
+// DefaultRules freeze leases which are expired, and end leases which are
+// over budget, or over the principal budget, in that order.
+// Over budget leases are ended right away, as freezing a lease
+// does not stop the spend of resources which are already running.
+func DefaultRules() []*Rule {
+	return []*Rule{
+		{Name: "expired", Type: Expiry, Action: Freeze},
+		{Name: "over-budget", Type: Spend, Action: End, Budget: LeaseBudget},
+		{Name: "over-principal-budget", Type: Spend, Action: End, Budget: PrincipalBudget},
+	}
+}
+
+/*
+NewFromEnv creates lease rules from environment variables
+
+Optional env vars:
+
+- LEASE_RULES (JSON list of rules. Defaults to DefaultRules, if empty)
+*/
+func NewFromEnv() ([]*Rule, error) {
+	value := os.Getenv("LEASE_RULES")
+	if strings.TrimSpace(value) == "" {
+		return DefaultRules(), nil
+	}
+
+	rules := []*Rule{}
+	err := json.Unmarshal([]byte(value), &rules)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid LEASE_RULES: %s", err)
+	}
+	if len(rules) == 0 {
+		return DefaultRules(), nil
+	}
+	err = Validate(rules)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid LEASE_RULES: %s", err)
+	}
+	return rules, nil
+}
+
+// Validate checks that each rule has a unique name,
+// a known type and action, and the fields required by its type
+func Validate(rules []*Rule) error {
+	names := map[string]bool{}
+	for i, rule := range rules {
+		if rule == nil || rule.Name == "" {
+			return fmt.Errorf("rule %d must have a name", i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rule %s is defined more than once", rule.Name)
+		}
+		names[rule.Name] = true
+
+		err := rule.validate()
+		if err != nil {
+			return fmt.Errorf("rule %s %s", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) validate() error {
+	switch r.Action {
+	case Warn, Freeze, End:
+	default:
+		return fmt.Errorf("must have an action of warn, freeze or end")
+	}
+
+	switch r.Type {
+	case Expiry:
+		if r.BeforeHours < 0 {
+			return fmt.Errorf("must not have a negative beforeHours")
+		}
+	case Idle:
+		if r.IdleHours <= 0 {
+			return fmt.Errorf("must have an idleHours greater than 0")
+		}
+		if r.IdleHours > MaxUsageDays*24 {
+			return fmt.Errorf("must have an idleHours of at most %d", MaxUsageDays*24)
+		}
+	case Spend:
+		if r.Budget != LeaseBudget && r.Budget != PrincipalBudget {
+			return fmt.Errorf("must have a budget of lease or principal")
+		}
+		if r.Percent < 0 || r.Amount < 0 {
+			return fmt.Errorf("must not have a negative percent or amount")
+		}
+	case SpendVelocity:
+		if r.MaxDailySpend <= 0 {
+			return fmt.Errorf("must have a maxDailySpend greater than 0")
+		}
+		if r.Days < 0 {
+			return fmt.Errorf("must not have a negative days")
+		}
+		if r.Days > MaxUsageDays {
+			return fmt.Errorf("must have a days of at most %d", MaxUsageDays)
+		}
+	case Metadata:
+		if r.MetadataKey == "" {
+			return fmt.Errorf("must have a metadataKey")
+		}
+		if r.Reason == "" {
+			return fmt.Errorf("must have a reason")
+		}
+	default:
+		return fmt.Errorf("has an unknown type \"%s\"", r.Type)
+	}
+	return nil
+}
+
+// Evaluate checks each rule against the lease, in order
+func Evaluate(rules []*Rule, lease *db.Lease, state *State) *Result {
+	result := &Result{Fired: []*Rule{}}
+	for _, rule := range rules {
+		if !rule.Fires(lease, state) {
+			continue
+		}
+		result.Fired = append(result.Fired, rule)
+		if result.Enforced == nil && rule.Action != Warn {
+			result.Enforced = rule
+		}
+	}
+	return result
+}
+
+// Fires returns true if the rule's condition is met by the lease
+func (r *Rule) Fires(lease *db.Lease, state *State) bool {
+	switch r.Type {
+	case Expiry:
+		return state.Now >= lease.ExpiresOn-int64(r.BeforeHours*3600)
+	case Idle:
+		return state.Now-lastActiveOn(lease, state, r.IdleSpendAmount) >= int64(r.IdleHours*3600)
+	case Spend:
+		spend, budgetAmount := state.LeaseSpend, lease.BudgetAmount
+		if r.Budget == PrincipalBudget {
+			spend, budgetAmount = state.PrincipalSpend, state.PrincipalBudgetAmount
+		}
+		if r.Amount > 0 {
+			return spend > r.Amount
+		}
+		percent := r.Percent
+		if percent == 0 {
+			percent = 100
+		}
+		return spend > budgetAmount*percent/100
+	case SpendVelocity:
+		return dailySpendRate(lease, state, r.Days) > r.MaxDailySpend
+	case Metadata:
+		value, ok := lease.Metadata[r.MetadataKey]
+		if !ok {
+			return false
+		}
+		return r.MetadataValue == "" || fmt.Sprint(value) == r.MetadataValue
+	}
+	return false
+}
+
+// StatusReason returns the lease status reason of the rule
+func (r *Rule) StatusReason() db.LeaseStatusReason {
+	if r.Reason != "" {
+		return r.Reason
+	}
+	switch r.Type {
+	case Expiry:
+		return db.LeaseExpired
+	case Idle:
+		return db.LeaseIdle
+	case Spend:
+		if r.Budget == PrincipalBudget {
+			return db.LeaseOverPrincipalBudget
+		}
+		return db.LeaseOverBudget
+	case SpendVelocity:
+		return db.LeaseOverSpendVelocity
+	}
+	return db.LeaseExpired
+}
+
+// lastActiveOn returns the end of the last day on which the lease spent
+// more than idleSpendAmount, or when the lease was activated.
+func lastActiveOn(lease *db.Lease, state *State, idleSpendAmount float64) int64 {
+	activeOn := lease.LeaseStatusModifiedOn
+	for _, spend := range state.DailySpend {
+		endOfDay := spend.StartDate + int64((24 * time.Hour).Seconds())
+		if spend.Amount > idleSpendAmount && endOfDay > activeOn {
+			activeOn = endOfDay
+		}
+	}
+	return activeOn
+}
+
+// dailySpendRate returns the average daily spend of the lease
+// over the last number of days (including today),
+// or since the lease was activated, if more recent.
+func dailySpendRate(lease *db.Lease, state *State, days int) float64 {
+	if days < 1 {
+		days = 1
+	}
+	now := time.Unix(state.Now, 0).UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	windowStart := today.AddDate(0, 0, -(days - 1))
+
+	// Don't average over days before the lease was activated
+	activated := time.Unix(lease.LeaseStatusModifiedOn, 0).UTC()
+	activatedDay := time.Date(activated.Year(), activated.Month(), activated.Day(), 0, 0, 0, 0, time.UTC)
+	if activatedDay.After(windowStart) {
+		windowStart = activatedDay
+	}
+	numDays := int(today.Sub(windowStart).Hours()/24) + 1
+	if numDays < 1 {
+		numDays = 1
+	}
+
+	total := 0.0
+	for _, spend := range state.DailySpend {
+		if spend.StartDate >= windowStart.Unix() {
+			total += spend.Amount
+		}
+	}
+	return total / float64(numDays)
+}
@@ -0,0 +1,187 @@
+package leaserule
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Optum/dce/pkg/db"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	t.Run("should parse rules", func(t *testing.T) {
+		os.Setenv("LEASE_RULES", `[{"name": "idle-day", "type": "idle", "action": "end", "idleHours": 24}]`)
+		defer os.Unsetenv("LEASE_RULES")
+
+		rules, err := NewFromEnv()
+		require.Nil(t, err)
+		require.Len(t, rules, 1)
+		require.Equal(t, "idle-day", rules[0].Name)
+		require.Equal(t, Idle, rules[0].Type)
+		require.Equal(t, 24.0, rules[0].IdleHours)
+	})
+
+	t.Run("should default to the default rules", func(t *testing.T) {
+		rules, err := NewFromEnv()
+		require.Nil(t, err)
+		require.Equal(t, DefaultRules(), rules)
+
+		os.Setenv("LEASE_RULES", `[]`)
+		defer os.Unsetenv("LEASE_RULES")
+		rules, err = NewFromEnv()
+		require.Nil(t, err)
+		require.Equal(t, DefaultRules(), rules)
+	})
+
+	t.Run("should reject invalid rules", func(t *testing.T) {
+		os.Setenv("LEASE_RULES", `[{"name": "idle-day", "type": "idle", "action": "end"}]`)
+		defer os.Unsetenv("LEASE_RULES")
+
+		_, err := NewFromEnv()
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "rule idle-day must have an idleHours")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []*Rule
+		err   string
+	}{
+		{"valid rules", DefaultRules(), ""},
+		{"missing name", []*Rule{{Type: Expiry, Action: End}}, "rule 0 must have a name"},
+		{"duplicate name", []*Rule{
+			{Name: "a", Type: Expiry, Action: End},
+			{Name: "a", Type: Expiry, Action: Warn},
+		}, "rule a is defined more than once"},
+		{"unknown action", []*Rule{{Name: "a", Type: Expiry, Action: "stop"}}, "rule a must have an action"},
+		{"unknown type", []*Rule{{Name: "a", Type: "weather", Action: End}}, "rule a has an unknown type"},
+		{"spend without budget", []*Rule{{Name: "a", Type: Spend, Action: End}}, "rule a must have a budget"},
+		{"idle longer than the usage kept", []*Rule{{Name: "a", Type: Idle, Action: End, IdleHours: 30 * 24}}, "rule a must have an idleHours of at most 672"},
+		{"velocity without max", []*Rule{{Name: "a", Type: SpendVelocity, Action: End}}, "rule a must have a maxDailySpend"},
+		{"velocity longer than the usage kept", []*Rule{{Name: "a", Type: SpendVelocity, Action: End, MaxDailySpend: 10, Days: 30}}, "rule a must have a days of at most 28"},
+		{"metadata without reason", []*Rule{{Name: "a", Type: Metadata, Action: End, MetadataKey: "stop"}}, "rule a must have a reason"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.rules)
+			if tt.err == "" {
+				require.Nil(t, err)
+			} else {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tt.err)
+			}
+		})
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	now := time.Now().Unix()
+	lease := &db.Lease{
+		BudgetAmount: 3000,
+		ExpiresOn:    now,
+	}
+	principalBudgetAmount := 7000.00
+
+	tests := []struct {
+		name   string
+		state  *State
+		reason db.LeaseStatusReason
+	}{
+		{"non-expired lease", &State{Now: now - 86400, LeaseSpend: 10, PrincipalSpend: 10}, ""},
+		{"expired lease", &State{Now: now + 86400, LeaseSpend: 10, PrincipalSpend: 10}, db.LeaseExpired},
+		{"over budget lease", &State{Now: now - 86400, LeaseSpend: 5000, PrincipalSpend: 5000}, db.LeaseOverBudget},
+		{"over principal budget lease", &State{Now: now - 86400, LeaseSpend: 2500, PrincipalSpend: 9000}, db.LeaseOverPrincipalBudget},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.state.PrincipalBudgetAmount = principalBudgetAmount
+			result := Evaluate(DefaultRules(), lease, tt.state)
+			if tt.reason == "" {
+				require.Nil(t, result.Enforced)
+				require.Empty(t, result.Fired)
+			} else {
+				require.NotNil(t, result.Enforced)
+				require.Equal(t, tt.reason, result.Enforced.StatusReason())
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	day := int64(86400)
+	now := time.Date(2020, 3, 10, 12, 0, 0, 0, time.UTC).Unix()
+	today := time.Date(2020, 3, 10, 0, 0, 0, 0, time.UTC).Unix()
+	lease := &db.Lease{
+		BudgetAmount:          100,
+		ExpiresOn:             now + 2*day,
+		LeaseStatusModifiedOn: today - 5*day,
+		Metadata:              map[string]interface{}{"project": "sandbox", "ticket": 123},
+	}
+
+	tests := []struct {
+		name  string
+		rule  *Rule
+		state *State
+		fires bool
+	}{
+		{"expiry before expiresOn",
+			&Rule{Type: Expiry, BeforeHours: 24}, &State{Now: now}, false},
+		{"expiry within beforeHours",
+			&Rule{Type: Expiry, BeforeHours: 72}, &State{Now: now}, true},
+		{"idle with recent spend",
+			&Rule{Type: Idle, IdleHours: 48},
+			&State{Now: now, DailySpend: []DailySpend{{today - day, 5}}}, false},
+		{"idle without recent spend",
+			&Rule{Type: Idle, IdleHours: 48},
+			&State{Now: now, DailySpend: []DailySpend{{today - 4*day, 5}, {today, 0}}}, true},
+		{"idle with spend below the idle amount",
+			&Rule{Type: Idle, IdleHours: 48, IdleSpendAmount: 1},
+			&State{Now: now, DailySpend: []DailySpend{{today - day, 0.5}}}, true},
+		{"idle since activation",
+			&Rule{Type: Idle, IdleHours: 200}, &State{Now: now}, false},
+		{"spend under percent",
+			&Rule{Type: Spend, Budget: LeaseBudget, Percent: 50}, &State{Now: now, LeaseSpend: 40}, false},
+		{"spend over percent",
+			&Rule{Type: Spend, Budget: LeaseBudget, Percent: 50}, &State{Now: now, LeaseSpend: 60}, true},
+		{"spend over amount",
+			&Rule{Type: Spend, Budget: PrincipalBudget, Amount: 20}, &State{Now: now, PrincipalSpend: 25}, true},
+		{"spend velocity under max",
+			&Rule{Type: SpendVelocity, MaxDailySpend: 20, Days: 3},
+			&State{Now: now, DailySpend: []DailySpend{{today, 30}, {today - day, 10}, {today - 5*day, 100}}}, false},
+		{"spend velocity over max",
+			&Rule{Type: SpendVelocity, MaxDailySpend: 20},
+			&State{Now: now, DailySpend: []DailySpend{{today, 30}, {today - day, 10}}}, true},
+		{"metadata key",
+			&Rule{Type: Metadata, MetadataKey: "project"}, &State{Now: now}, true},
+		{"metadata value",
+			&Rule{Type: Metadata, MetadataKey: "ticket", MetadataValue: "123"}, &State{Now: now}, true},
+		{"metadata other value",
+			&Rule{Type: Metadata, MetadataKey: "project", MetadataValue: "prod"}, &State{Now: now}, false},
+		{"metadata missing key",
+			&Rule{Type: Metadata, MetadataKey: "owner"}, &State{Now: now}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.fires, tt.rule.Fires(lease, tt.state))
+		})
+	}
+
+	t.Run("should enforce the first freeze or end rule", func(t *testing.T) {
+		rules := []*Rule{
+			{Name: "half-budget", Type: Spend, Action: Warn, Budget: LeaseBudget, Percent: 50},
+			{Name: "flagged", Type: Metadata, Action: Freeze, MetadataKey: "project", Reason: db.LeaseDestroyed},
+			{Name: "over-budget", Type: Spend, Action: End, Budget: LeaseBudget},
+			{Name: "expired", Type: Expiry, Action: End},
+		}
+		result := Evaluate(rules, lease, &State{Now: now, LeaseSpend: 150})
+
+		require.Len(t, result.Fired, 3)
+		require.Equal(t, "flagged", result.Enforced.Name)
+		require.Equal(t, db.LeaseDestroyed, result.Enforced.StatusReason())
+		require.Equal(t, "half-budget", result.Fired[0].Name)
+	})
+}
@@ -0,0 +1,433 @@
+// Package policydrift detects changes made to the principal IAM role
+// and policy of child accounts outside of DCE, eg. by editing the
+// policy in the child account. Drift is recorded on the account,
+// published to an SNS topic, and may be reverted.
+package policydrift
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/pkg/errors"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/awsiface"
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/policyprofile"
+	"github.com/Optum/dce/pkg/rolemanager"
+)
+
+// Checker compares the principal role and policy of each account
+// with the role and policy rendered by DCE
+type Checker struct {
+	DB         db.DBer
+	Storager   common.Storager
+	TokenSvc   common.TokenService
+	AwsSession awsiface.AwsSession
+	// SNS and DriftTopicARN receive the account,
+	// when drift is first detected
+	SNS           common.Notificationer
+	DriftTopicARN string
+	// Remediate reverts drift, with the PolicyManager
+	Remediate     bool
+	PolicyManager rolemanager.PolicyManager
+	// MasterAccountID is trusted by the principal role
+	MasterAccountID      string
+	PrincipalRoleName    string
+	PrincipalPolicyName  string
+	PrincipalIAMDenyTags []string
+	Regions              []string
+	PolicyBucket         string
+	PolicyBucketKey      string
+	// PermissionsBoundary is the name or ARN of the permissions
+	// boundary of the principal role. The boundary is not checked if empty.
+	PermissionsBoundary string
+	// TrustPolicy renders the trust policy of the principal role
+	TrustPolicy *rolemanager.PrincipalTrustPolicyTemplate
+	// PolicyProfiles add statements to the principal
+	// policy of leased accounts, and freeze accounts with a frozen lease
+	PolicyProfiles *policyprofile.Config
+	// NewIAM creates an IAM client for a child account session.
+	// Defaults to iam.New.
+	NewIAM func(session awsiface.AwsSession) awsiface.IAM
+}
+
+/*
+NewCheckerFromEnv creates a Checker from environment variables
+
+Requires env vars for:
+
+- MASTER_ACCOUNT_ID
+- PRINCIPAL_ROLE_NAME
+- PRINCIPAL_POLICY_NAME
+- PRINCIPAL_IAM_DENY_TAGS
+- ALLOWED_REGIONS
+- ARTIFACTS_BUCKET
+- PRINCIPAL_POLICY_S3_KEY
+- POLICY_DRIFT_TOPIC_ARN
+
+Optional env vars:
+
+- POLICY_DRIFT_REMEDIATE (default false)
+- PRINCIPAL_POLICY_PROFILES
+- PRINCIPAL_POLICY_APPROVED_STATEMENTS
+- PRINCIPAL_PERMISSIONS_BOUNDARY
+- PRINCIPAL_TRUST_POLICY_S3_KEY
+- PRINCIPAL_TRUST_IDP_ARNS
+*/
+func NewCheckerFromEnv(checker Checker) (*Checker, error) {
+	remediate, err := strconv.ParseBool(common.GetEnv("POLICY_DRIFT_REMEDIATE", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid POLICY_DRIFT_REMEDIATE: %s", err)
+	}
+	checker.Remediate = remediate
+	checker.PolicyProfiles, err = policyprofile.NewConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	checker.MasterAccountID = common.RequireEnv("MASTER_ACCOUNT_ID")
+	checker.PrincipalRoleName = common.RequireEnv("PRINCIPAL_ROLE_NAME")
+	checker.PrincipalPolicyName = common.RequireEnv("PRINCIPAL_POLICY_NAME")
+	checker.PrincipalIAMDenyTags = strings.Split(common.RequireEnv("PRINCIPAL_IAM_DENY_TAGS"), ",")
+	checker.Regions = strings.Split(common.RequireEnv("ALLOWED_REGIONS"), ",")
+	checker.PolicyBucket = common.RequireEnv("ARTIFACTS_BUCKET")
+	checker.PolicyBucketKey = common.RequireEnv("PRINCIPAL_POLICY_S3_KEY")
+	checker.DriftTopicARN = common.RequireEnv("POLICY_DRIFT_TOPIC_ARN")
+	checker.PermissionsBoundary = common.GetEnv("PRINCIPAL_PERMISSIONS_BOUNDARY", "")
+	checker.TrustPolicy = rolemanager.NewPrincipalTrustPolicyTemplate(checker.Storager, checker.PolicyBucket,
+		common.GetEnv("PRINCIPAL_TRUST_POLICY_S3_KEY", ""), common.GetEnv("PRINCIPAL_TRUST_IDP_ARNS", ""))
+	return &checker, nil
+}
+
+// CheckAll checks every account, except Orphaned accounts.
+// Accounts which fail to be checked are logged, and do not
+// stop other accounts from being checked.
+func (c *Checker) CheckAll() error {
+	accounts, err := c.DB.GetAccounts()
+	if err != nil {
+		return errors.Wrap(err, "Failed to list accounts")
+	}
+
+	failed := 0
+	for _, account := range accounts {
+		if account.AccountStatus == db.Orphaned || account.PrincipalRoleArn == "" {
+			continue
+		}
+		_, err := c.Check(account)
+		if err != nil {
+			log.Printf("Failed to check policy drift for account %s: %s", account.ID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("Failed to check policy drift for %d of %d accounts", failed, len(accounts))
+	}
+	return nil
+}
+
+// principalPolicyInput is the input for the principal policy template
+type principalPolicyInput struct {
+	PrincipalPolicyArn     string
+	PrincipalRoleArn       string
+	PrincipalIAMDenyTags   []string
+	AdminRoleArn           string
+	Regions                []string
+	PermissionsBoundaryArn string
+}
+
+// renderedPolicies are the principal policy and
+// role trust policy rendered for an account
+type renderedPolicies struct {
+	Policy          string
+	PolicyHash      string
+	TrustPolicy     string
+	TrustPolicyHash string
+}
+
+// Check compares the principal role and policy of the account with
+// the rendered role and policy, and records the result on the account.
+// If the check fails, the error is also recorded on the account.
+func (c *Checker) Check(account *db.Account) (*db.PolicyDrift, error) {
+	prev := account.PolicyDrift
+	drift, rendered, checkErr := c.check(account)
+	if checkErr != nil {
+		// Keep the result of the last successful check
+		drift = &db.PolicyDrift{}
+		if prev != nil {
+			*drift = *prev
+		}
+		drift.LastError = checkErr.Error()
+	}
+	drift.CheckedOn = time.Now().Unix()
+
+	// Drift is new if it was not found by the last check,
+	// or if the last check reverted it
+	isNew := drift.Drifted() && (!prev.Drifted() || prev.RemediatedOn >= prev.CheckedOn)
+	if checkErr == nil && drift.Drifted() {
+		if isNew {
+			drift.DetectedOn = drift.CheckedOn
+		} else {
+			drift.DetectedOn = prev.DetectedOn
+		}
+		log.Printf("Principal role or policy of account %s has drifted (policy: %t, trust policy: %t, permissions boundary: %t)",
+			account.ID, drift.PolicyDrifted, drift.TrustPolicyDrifted, drift.BoundaryDrifted)
+	}
+
+	if checkErr == nil && drift.Drifted() && c.Remediate {
+		err := c.remediate(account, drift, rendered)
+		if err != nil {
+			checkErr = errors.Wrap(err, "Failed to revert drift")
+			drift.LastError = checkErr.Error()
+		} else {
+			drift.RemediatedOn = time.Now().Unix()
+		}
+	}
+
+	updated, err := c.DB.UpdateAccount(db.Account{
+		ID:          account.ID,
+		PolicyDrift: drift,
+	}, []string{"PolicyDrift"})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to save policy drift for account %s", account.ID)
+	}
+
+	if checkErr == nil && isNew {
+		err = c.publish(updated)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return drift, checkErr
+}
+
+// check returns the drift of the principal role and policy,
+// and the rendered policies
+func (c *Checker) check(account *db.Account) (*db.PolicyDrift, *renderedPolicies, error) {
+	policy, policyHash, err := c.Storager.GetTemplateObject(c.PolicyBucket, c.PolicyBucketKey,
+		principalPolicyInput{
+			PrincipalPolicyArn:     c.policyArn(account.ID),
+			PrincipalRoleArn:       fmt.Sprintf("arn:aws:iam::%s:role/%s", account.ID, c.PrincipalRoleName),
+			PrincipalIAMDenyTags:   c.PrincipalIAMDenyTags,
+			AdminRoleArn:           account.AdminRoleArn,
+			Regions:                c.Regions,
+			PermissionsBoundaryArn: c.permissionsBoundaryArn(account.ID),
+		})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to render principal policy")
+	}
+	trustPolicy, trustPolicyHash, err := c.TrustPolicy.Render(account.ID, c.MasterAccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Leased accounts have the statements of their lease,
+	// and accounts with a frozen lease have the frozen policy
+	policy, policyHash, err = c.PolicyProfiles.AccountPolicy(c.DB, account.ID, policy, policyHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	iamSvc, err := c.iam(account)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	drift := &db.PolicyDrift{}
+	livePolicy, err := c.getPolicyDocument(iamSvc, c.policyArn(account.ID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if livePolicy == "" {
+		drift.PolicyDrifted = true
+	} else {
+		equal, err := rolemanager.PolicyDocumentsEqual(policy, livePolicy)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Failed to compare principal policy")
+		}
+		drift.PolicyDrifted = !equal
+	}
+
+	role, err := c.getRole(iamSvc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if role == nil {
+		drift.TrustPolicyDrifted = true
+	} else {
+		equal, err := rolemanager.PolicyDocumentsEqual(trustPolicy, aws.StringValue(role.AssumeRolePolicyDocument))
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Failed to compare principal role trust policy")
+		}
+		drift.TrustPolicyDrifted = !equal
+	}
+
+	// Roles are only expected to have a permissions boundary,
+	// if one is configured
+	boundaryArn := c.permissionsBoundaryArn(account.ID)
+	if boundaryArn != "" {
+		liveBoundaryArn := ""
+		if role != nil && role.PermissionsBoundary != nil {
+			liveBoundaryArn = aws.StringValue(role.PermissionsBoundary.PermissionsBoundaryArn)
+		}
+		drift.BoundaryDrifted = liveBoundaryArn != boundaryArn
+	}
+
+	return drift, &renderedPolicies{
+		Policy:          policy,
+		PolicyHash:      policyHash,
+		TrustPolicy:     trustPolicy,
+		TrustPolicyHash: trustPolicyHash,
+	}, nil
+}
+
+// remediate reverts the principal role and policy to the rendered
+// role and policy
+func (c *Checker) remediate(account *db.Account, drift *db.PolicyDrift, rendered *renderedPolicies) error {
+	iamSvc, err := c.iam(account)
+	if err != nil {
+		return err
+	}
+
+	if drift.PolicyDrifted {
+		policyArn, err := arn.Parse(c.policyArn(account.ID))
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Reverting principal policy %s", policyArn.String())
+		c.PolicyManager.SetIAMClient(iamSvc)
+		err = c.PolicyManager.MergePolicy(&rolemanager.MergePolicyInput{
+			PolicyName:     c.PrincipalPolicyName,
+			PolicyArn:      policyArn,
+			PolicyDocument: rendered.Policy,
+		})
+		if err != nil {
+			return err
+		}
+
+		if account.PrincipalPolicyHash != rendered.PolicyHash {
+			_, err = c.DB.UpdateAccountPrincipalPolicyHash(account.ID, account.PrincipalPolicyHash, rendered.PolicyHash)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if drift.TrustPolicyDrifted {
+		log.Printf("Reverting trust policy of role %s in account %s", c.PrincipalRoleName, account.ID)
+		_, err = iamSvc.UpdateAssumeRolePolicy(&iam.UpdateAssumeRolePolicyInput{
+			RoleName:       aws.String(c.PrincipalRoleName),
+			PolicyDocument: aws.String(rendered.TrustPolicy),
+		})
+		if err != nil {
+			return err
+		}
+
+		if account.PrincipalTrustPolicyHash != rendered.TrustPolicyHash {
+			_, err = c.DB.UpdateAccount(db.Account{
+				ID:                       account.ID,
+				PrincipalTrustPolicyHash: rendered.TrustPolicyHash,
+			}, []string{"PrincipalTrustPolicyHash"})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if drift.BoundaryDrifted {
+		log.Printf("Reverting permissions boundary of role %s in account %s", c.PrincipalRoleName, account.ID)
+		_, err = iamSvc.PutRolePermissionsBoundary(&iam.PutRolePermissionsBoundaryInput{
+			RoleName:            aws.String(c.PrincipalRoleName),
+			PermissionsBoundary: aws.String(c.permissionsBoundaryArn(account.ID)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish sends the account, with its policy drift, to the drift topic
+func (c *Checker) publish(account *db.Account) error {
+	message, err := common.PrepareSNSMessageJSON(response.AccountResponse(*account))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to prepare policy drift message for account %s", account.ID)
+	}
+	_, err = c.SNS.PublishMessage(&c.DriftTopicARN, &message, true)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to publish policy drift for account %s", account.ID)
+	}
+	return nil
+}
+
+// iam returns an IAM client for the account, using its admin role
+func (c *Checker) iam(account *db.Account) (awsiface.IAM, error) {
+	accountSession, err := c.TokenSvc.NewSession(c.AwsSession, account.AdminRoleArn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to assume role %s", account.AdminRoleArn)
+	}
+	if c.NewIAM != nil {
+		return c.NewIAM(accountSession), nil
+	}
+	return iam.New(accountSession), nil
+}
+
+func (c *Checker) policyArn(accountID string) string {
+	return fmt.Sprintf("arn:aws:iam::%s:policy/%s", accountID, c.PrincipalPolicyName)
+}
+
+func (c *Checker) permissionsBoundaryArn(accountID string) string {
+	return rolemanager.PermissionsBoundaryArn(c.PermissionsBoundary, accountID)
+}
+
+// getPolicyDocument returns the document of the default version
+// of the policy, or an empty string if the policy does not exist
+func (c *Checker) getPolicyDocument(iamSvc awsiface.IAM, policyArn string) (string, error) {
+	policy, err := iamSvc.GetPolicy(&iam.GetPolicyInput{
+		PolicyArn: aws.String(policyArn),
+	})
+	if isNoSuchEntityError(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to get policy %s", policyArn)
+	}
+
+	version, err := iamSvc.GetPolicyVersion(&iam.GetPolicyVersionInput{
+		PolicyArn: aws.String(policyArn),
+		VersionId: policy.Policy.DefaultVersionId,
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to get policy %s version %s",
+			policyArn, aws.StringValue(policy.Policy.DefaultVersionId))
+	}
+	return aws.StringValue(version.PolicyVersion.Document), nil
+}
+
+// getRole returns the principal role,
+// or nil if the role does not exist
+func (c *Checker) getRole(iamSvc awsiface.IAM) (*iam.Role, error) {
+	role, err := iamSvc.GetRole(&iam.GetRoleInput{
+		RoleName: aws.String(c.PrincipalRoleName),
+	})
+	if isNoSuchEntityError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get role %s", c.PrincipalRoleName)
+	}
+	return role.Role, nil
+}
+
+func isNoSuchEntityError(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == iam.ErrCodeNoSuchEntityException
+}
@@ -0,0 +1,335 @@
+package policydrift
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/awsiface"
+	awsMocks "github.com/Optum/dce/pkg/awsiface/mocks"
+	"github.com/Optum/dce/pkg/common"
+	commonMocks "github.com/Optum/dce/pkg/common/mocks"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/policyprofile"
+	"github.com/Optum/dce/pkg/rolemanager"
+	roleMocks "github.com/Optum/dce/pkg/rolemanager/mocks"
+)
+
+const renderedPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"*","Resource":"*"}]}`
+
+type testChecker struct {
+	checker       *Checker
+	iam           *awsMocks.IAM
+	role          *iam.Role
+	notifier      *common.MemoryNotifier
+	policyManager *roleMocks.PolicyManager
+}
+
+// setupChecker creates a Checker for account 123456789012,
+// where the live policy and trust policy are livePolicy and liveTrustPolicy
+func setupChecker(t *testing.T, livePolicy string, liveTrustPolicy string) *testChecker {
+	dao := db.NewMemoryDB(7)
+	require.Nil(t, dao.PutAccount(db.Account{
+		ID:                  "123456789012",
+		AccountStatus:       db.Ready,
+		AdminRoleArn:        "arn:aws:iam::123456789012:role/AdminRole",
+		PrincipalRoleArn:    "arn:aws:iam::123456789012:role/PrincipalRole",
+		PrincipalPolicyHash: "hash",
+	}))
+
+	storager := &commonMocks.Storager{}
+	storager.On("GetTemplateObject", "artifacts", "principal_policy.tmpl", principalPolicyInput{
+		PrincipalPolicyArn:   "arn:aws:iam::123456789012:policy/PrincipalPolicy",
+		PrincipalRoleArn:     "arn:aws:iam::123456789012:role/PrincipalRole",
+		PrincipalIAMDenyTags: []string{"DoNotTouch"},
+		AdminRoleArn:         "arn:aws:iam::123456789012:role/AdminRole",
+		Regions:              []string{"us-east-1"},
+	}).Return(renderedPolicy, "newHash", nil)
+
+	tokenSvc := &commonMocks.TokenService{}
+	tokenSvc.On("NewSession", mock.Anything, "arn:aws:iam::123456789012:role/AdminRole").
+		Return(&awsMocks.AwsSession{}, nil)
+
+	iamSvc := &awsMocks.IAM{}
+	if livePolicy == "" {
+		iamSvc.On("GetPolicy", mock.Anything).
+			Return(nil, awserr.New(iam.ErrCodeNoSuchEntityException, "not found", nil))
+	} else {
+		iamSvc.On("GetPolicy", &iam.GetPolicyInput{
+			PolicyArn: aws.String("arn:aws:iam::123456789012:policy/PrincipalPolicy"),
+		}).Return(&iam.GetPolicyOutput{
+			Policy: &iam.Policy{DefaultVersionId: aws.String("v2")},
+		}, nil)
+		iamSvc.On("GetPolicyVersion", &iam.GetPolicyVersionInput{
+			PolicyArn: aws.String("arn:aws:iam::123456789012:policy/PrincipalPolicy"),
+			VersionId: aws.String("v2"),
+		}).Return(&iam.GetPolicyVersionOutput{
+			PolicyVersion: &iam.PolicyVersion{Document: aws.String(url.PathEscape(livePolicy))},
+		}, nil)
+	}
+	role := &iam.Role{AssumeRolePolicyDocument: aws.String(url.PathEscape(liveTrustPolicy))}
+	iamSvc.On("GetRole", &iam.GetRoleInput{
+		RoleName: aws.String("PrincipalRole"),
+	}).Return(&iam.GetRoleOutput{Role: role}, nil)
+
+	notifier := common.NewMemoryNotifier()
+	policyManager := &roleMocks.PolicyManager{}
+	return &testChecker{
+		checker: &Checker{
+			DB:                   dao,
+			Storager:             storager,
+			TokenSvc:             tokenSvc,
+			AwsSession:           &awsMocks.AwsSession{},
+			SNS:                  notifier,
+			DriftTopicARN:        "drift-topic",
+			PolicyManager:        policyManager,
+			MasterAccountID:      "000000000000",
+			PrincipalRoleName:    "PrincipalRole",
+			PrincipalPolicyName:  "PrincipalPolicy",
+			PrincipalIAMDenyTags: []string{"DoNotTouch"},
+			Regions:              []string{"us-east-1"},
+			PolicyBucket:         "artifacts",
+			PolicyBucketKey:      "principal_policy.tmpl",
+			PolicyProfiles:       &policyprofile.Config{},
+			NewIAM: func(session awsiface.AwsSession) awsiface.IAM {
+				return iamSvc
+			},
+		},
+		iam:           iamSvc,
+		role:          role,
+		notifier:      notifier,
+		policyManager: policyManager,
+	}
+}
+
+func TestCheck(t *testing.T) {
+	trustPolicy := rolemanager.PrincipalTrustPolicy("000000000000")
+	editedPolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"*","Resource":"*"}]}`
+
+	t.Run("should record accounts without drift", func(t *testing.T) {
+		test := setupChecker(t, renderedPolicy, trustPolicy)
+
+		require.Nil(t, test.checker.CheckAll())
+
+		account, err := test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+		require.NotNil(t, account.PolicyDrift)
+		require.False(t, account.PolicyDrift.Drifted())
+		require.NotZero(t, account.PolicyDrift.CheckedOn)
+		require.Zero(t, account.PolicyDrift.DetectedOn)
+		require.Empty(t, test.notifier.Messages("drift-topic"))
+	})
+
+	t.Run("should record and publish drift, once", func(t *testing.T) {
+		test := setupChecker(t, editedPolicy, trustPolicy)
+		account, err := test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+
+		drift, err := test.checker.Check(account)
+		require.Nil(t, err)
+		require.True(t, drift.PolicyDrifted)
+		require.False(t, drift.TrustPolicyDrifted)
+		require.Equal(t, drift.CheckedOn, drift.DetectedOn)
+		require.Zero(t, drift.RemediatedOn)
+
+		messages := test.notifier.Messages("drift-topic")
+		require.Len(t, messages, 1)
+		var message map[string]string
+		require.Nil(t, json.Unmarshal([]byte(messages[0]), &message))
+		var published response.AccountResponse
+		require.Nil(t, json.Unmarshal([]byte(message["default"]), &published))
+		require.Equal(t, "123456789012", published.ID)
+		require.True(t, published.PolicyDrift.PolicyDrifted)
+
+		// Drift which was already detected is not published again
+		account, err = test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+		drift, err = test.checker.Check(account)
+		require.Nil(t, err)
+		require.True(t, drift.PolicyDrifted)
+		require.Equal(t, account.PolicyDrift.DetectedOn, drift.DetectedOn)
+		require.Len(t, test.notifier.Messages("drift-topic"), 1)
+
+		test.policyManager.AssertNotCalled(t, "MergePolicy", mock.Anything)
+	})
+
+	t.Run("should add the statements of the account's lease", func(t *testing.T) {
+		statement := db.PolicyStatement{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}
+		leasedPolicy, err := policyprofile.Apply(renderedPolicy, []db.PolicyStatement{statement})
+		require.Nil(t, err)
+		test := setupChecker(t, leasedPolicy, trustPolicy)
+		_, err = test.checker.DB.PutLease(db.Lease{
+			ID:               "lease-1",
+			AccountID:        "123456789012",
+			PrincipalID:      "jdoe",
+			LeaseStatus:      db.Active,
+			PolicyStatements: []db.PolicyStatement{statement},
+		})
+		require.Nil(t, err)
+		account, err := test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+
+		drift, err := test.checker.Check(account)
+		require.Nil(t, err)
+		require.False(t, drift.Drifted())
+	})
+
+	t.Run("should record a missing policy as drift", func(t *testing.T) {
+		test := setupChecker(t, "", trustPolicy)
+		account, err := test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+
+		drift, err := test.checker.Check(account)
+		require.Nil(t, err)
+		require.True(t, drift.PolicyDrifted)
+	})
+
+	t.Run("should revert drift", func(t *testing.T) {
+		editedTrustPolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":"*"},"Action":"sts:AssumeRole"}]}`
+		test := setupChecker(t, editedPolicy, editedTrustPolicy)
+		test.checker.Remediate = true
+		test.policyManager.On("SetIAMClient", test.iam).Return()
+		test.policyManager.On("MergePolicy", mock.MatchedBy(func(input *rolemanager.MergePolicyInput) bool {
+			return input.PolicyName == "PrincipalPolicy" &&
+				input.PolicyArn.String() == "arn:aws:iam::123456789012:policy/PrincipalPolicy" &&
+				input.PolicyDocument == renderedPolicy
+		})).Return(nil)
+		test.iam.On("UpdateAssumeRolePolicy", &iam.UpdateAssumeRolePolicyInput{
+			RoleName:       aws.String("PrincipalRole"),
+			PolicyDocument: aws.String(trustPolicy),
+		}).Return(&iam.UpdateAssumeRolePolicyOutput{}, nil)
+		account, err := test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+
+		drift, err := test.checker.Check(account)
+		require.Nil(t, err)
+		require.True(t, drift.PolicyDrifted)
+		require.True(t, drift.TrustPolicyDrifted)
+		require.NotZero(t, drift.RemediatedOn)
+		test.policyManager.AssertExpectations(t)
+		test.iam.AssertExpectations(t)
+
+		account, err = test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+		require.Equal(t, "newHash", account.PrincipalPolicyHash)
+		require.Len(t, test.notifier.Messages("drift-topic"), 1)
+	})
+
+	t.Run("should revert the trust policy to the trust policy template", func(t *testing.T) {
+		federatedTrustPolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Federated":"arn:aws:iam::123456789012:saml-provider/Okta"},"Action":"sts:AssumeRoleWithSAML"}]}`
+		test := setupChecker(t, renderedPolicy, trustPolicy)
+		test.checker.Remediate = true
+		storager := test.checker.Storager.(*commonMocks.Storager)
+		storager.On("GetTemplateObject", "artifacts", "principal_trust_policy.tmpl", rolemanager.PrincipalTrustPolicyInput{
+			AccountID:        "123456789012",
+			MasterAccountID:  "000000000000",
+			IdpArns:          []string{"arn:aws:iam::123456789012:saml-provider/Okta"},
+			SAMLProviderArns: []string{"arn:aws:iam::123456789012:saml-provider/Okta"},
+			OIDCProviderArns: []string{},
+		}).Return(federatedTrustPolicy, "etag", nil)
+		test.checker.TrustPolicy = rolemanager.NewPrincipalTrustPolicyTemplate(storager, "artifacts",
+			"principal_trust_policy.tmpl", "arn:aws:iam::123456789012:saml-provider/Okta")
+		test.iam.On("UpdateAssumeRolePolicy", &iam.UpdateAssumeRolePolicyInput{
+			RoleName:       aws.String("PrincipalRole"),
+			PolicyDocument: aws.String(federatedTrustPolicy),
+		}).Return(&iam.UpdateAssumeRolePolicyOutput{}, nil)
+		account, err := test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+
+		// The role trusts the master account, instead of the identity provider
+		drift, err := test.checker.Check(account)
+		require.Nil(t, err)
+		require.False(t, drift.PolicyDrifted)
+		require.True(t, drift.TrustPolicyDrifted)
+		require.NotZero(t, drift.RemediatedOn)
+		test.iam.AssertExpectations(t)
+
+		_, trustPolicyHash, err := test.checker.TrustPolicy.Render("123456789012", "000000000000")
+		require.Nil(t, err)
+		account, err = test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+		require.Equal(t, trustPolicyHash, account.PrincipalTrustPolicyHash)
+	})
+
+	t.Run("should record and revert permissions boundary drift", func(t *testing.T) {
+		test := setupChecker(t, renderedPolicy, trustPolicy)
+		test.checker.PermissionsBoundary = "DCEBoundary"
+		test.checker.Remediate = true
+		test.checker.Storager.(*commonMocks.Storager).On("GetTemplateObject", "artifacts", "principal_policy.tmpl", principalPolicyInput{
+			PrincipalPolicyArn:     "arn:aws:iam::123456789012:policy/PrincipalPolicy",
+			PrincipalRoleArn:       "arn:aws:iam::123456789012:role/PrincipalRole",
+			PrincipalIAMDenyTags:   []string{"DoNotTouch"},
+			AdminRoleArn:           "arn:aws:iam::123456789012:role/AdminRole",
+			Regions:                []string{"us-east-1"},
+			PermissionsBoundaryArn: "arn:aws:iam::123456789012:policy/DCEBoundary",
+		}).Return(renderedPolicy, "newHash", nil)
+		test.iam.On("PutRolePermissionsBoundary", &iam.PutRolePermissionsBoundaryInput{
+			RoleName:            aws.String("PrincipalRole"),
+			PermissionsBoundary: aws.String("arn:aws:iam::123456789012:policy/DCEBoundary"),
+		}).Return(&iam.PutRolePermissionsBoundaryOutput{}, nil)
+		account, err := test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+
+		// The role has no permissions boundary
+		drift, err := test.checker.Check(account)
+		require.Nil(t, err)
+		require.False(t, drift.PolicyDrifted)
+		require.False(t, drift.TrustPolicyDrifted)
+		require.True(t, drift.BoundaryDrifted)
+		require.NotZero(t, drift.RemediatedOn)
+		test.iam.AssertExpectations(t)
+		test.policyManager.AssertNotCalled(t, "MergePolicy", mock.Anything)
+
+		// The role has the permissions boundary
+		test.role.PermissionsBoundary = &iam.AttachedPermissionsBoundary{
+			PermissionsBoundaryArn: aws.String("arn:aws:iam::123456789012:policy/DCEBoundary"),
+		}
+		account, err = test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+		drift, err = test.checker.Check(account)
+		require.Nil(t, err)
+		require.False(t, drift.Drifted())
+	})
+
+	t.Run("should record errors, and keep checking other accounts", func(t *testing.T) {
+		test := setupChecker(t, renderedPolicy, trustPolicy)
+		require.Nil(t, test.checker.DB.PutAccount(db.Account{
+			ID:               "111111111111",
+			AccountStatus:    db.Ready,
+			AdminRoleArn:     "arn:aws:iam::111111111111:role/AdminRole",
+			PrincipalRoleArn: "arn:aws:iam::111111111111:role/PrincipalRole",
+		}))
+		// Orphaned accounts are not checked
+		require.Nil(t, test.checker.DB.PutAccount(db.Account{
+			ID:               "222222222222",
+			AccountStatus:    db.Orphaned,
+			PrincipalRoleArn: "arn:aws:iam::222222222222:role/PrincipalRole",
+		}))
+		test.checker.Storager.(*commonMocks.Storager).On("GetTemplateObject", mock.Anything, mock.Anything, mock.Anything).
+			Return("", "", awserr.New("AccessDenied", "access denied", nil))
+
+		err := test.checker.CheckAll()
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "1 of 3 accounts")
+
+		account, err := test.checker.DB.GetAccount("111111111111")
+		require.Nil(t, err)
+		require.Contains(t, account.PolicyDrift.LastError, "access denied")
+		require.False(t, account.PolicyDrift.Drifted())
+
+		account, err = test.checker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+		require.Empty(t, account.PolicyDrift.LastError)
+
+		account, err = test.checker.DB.GetAccount("222222222222")
+		require.Nil(t, err)
+		require.Nil(t, account.PolicyDrift)
+	})
+}
@@ -0,0 +1,298 @@
+// Package policyprofile customizes the principal policy of leased accounts.
+//
+// Admins define named policy profiles, each with IAM statements which are
+// added to the principal policy, eg. to allow additional services, or to deny
+// services which are otherwise allowed. Leases may reference a profile, and
+// may add statements approved by an admin. The statements apply to the
+// principal policy for the duration of the lease.
+package policyprofile
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/rolemanager"
+)
+
+// Profile is a named set of statements,
+// added to the principal policy
+type Profile struct {
+	Description string               `json:"description"`
+	Statements  []db.PolicyStatement `json:"statements"`
+}
+
+// Config holds the policy profiles, and the statements
+// which may be added to leases by any user
+type Config struct {
+	Profiles           map[string]*Profile
+	ApprovedStatements []db.PolicyStatement
+	// FrozenPolicy is the principal policy of accounts with a Frozen lease
+	// (rolemanager.FrozenPolicyDenyAll or rolemanager.FrozenPolicyReadOnly)
+	FrozenPolicy string
+}
+
+/*
+NewConfigFromEnv creates a Config from environment variables
+
+Optional env vars:
+
+- PRINCIPAL_POLICY_PROFILES (JSON object of profiles, by profile name)
+- PRINCIPAL_POLICY_APPROVED_STATEMENTS (JSON list of statements)
+- FROZEN_LEASE_POLICY (denyAll or readOnly. Defaults to denyAll)
+*/
+func NewConfigFromEnv() (*Config, error) {
+	profiles := map[string]*Profile{}
+	err := parseJSON(os.Getenv("PRINCIPAL_POLICY_PROFILES"), &profiles)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid PRINCIPAL_POLICY_PROFILES: %s", err)
+	}
+	approved := []db.PolicyStatement{}
+	err = parseJSON(os.Getenv("PRINCIPAL_POLICY_APPROVED_STATEMENTS"), &approved)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid PRINCIPAL_POLICY_APPROVED_STATEMENTS: %s", err)
+	}
+
+	config := &Config{
+		Profiles:           profiles,
+		ApprovedStatements: approved,
+		FrozenPolicy:       os.Getenv("FROZEN_LEASE_POLICY"),
+	}
+	if config.FrozenPolicy == "" {
+		config.FrozenPolicy = rolemanager.FrozenPolicyDenyAll
+	}
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func parseJSON(value string, v interface{}) error {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(value), v)
+}
+
+// Validate checks the statements of each profile,
+// the approved statements, and the frozen policy
+func (c *Config) Validate() error {
+	switch c.FrozenPolicy {
+	case "", rolemanager.FrozenPolicyDenyAll, rolemanager.FrozenPolicyReadOnly:
+	default:
+		return fmt.Errorf("frozen lease policy must be %s or %s",
+			rolemanager.FrozenPolicyDenyAll, rolemanager.FrozenPolicyReadOnly)
+	}
+	for name, profile := range c.Profiles {
+		if profile == nil || len(profile.Statements) == 0 {
+			return fmt.Errorf("policy profile %s has no statements", name)
+		}
+		err := validateStatements(profile.Statements)
+		if err != nil {
+			return fmt.Errorf("policy profile %s: %s", name, err)
+		}
+	}
+	err := validateStatements(c.ApprovedStatements)
+	if err != nil {
+		return fmt.Errorf("approved statements: %s", err)
+	}
+	return nil
+}
+
+// validateStatements checks that each statement
+// has an Effect, and an Action or NotAction
+func validateStatements(statements []db.PolicyStatement) error {
+	for i, statement := range statements {
+		effect, _ := statement["Effect"].(string)
+		if effect != "Allow" && effect != "Deny" {
+			return fmt.Errorf("statement %d must have an Effect of Allow or Deny", i)
+		}
+		if statement["Action"] == nil && statement["NotAction"] == nil {
+			return fmt.Errorf("statement %d must have an Action or NotAction", i)
+		}
+	}
+	return nil
+}
+
+// ProfileNames returns the names of the profiles, in order
+func (c *Config) ProfileNames() []string {
+	names := []string{}
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateLease checks that the lease's profile exists,
+// and that its statements are valid.
+//
+// Unless isAdmin is set, each of the lease's statements must
+// match one of the approved statements.
+func (c *Config) ValidateLease(profile string, statements []db.PolicyStatement, isAdmin bool) error {
+	if profile != "" && c.Profiles[profile] == nil {
+		return fmt.Errorf("Unknown policy profile %s. Available profiles: %s",
+			profile, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	err := validateStatements(statements)
+	if err != nil {
+		return fmt.Errorf("Invalid policy statements: %s", err)
+	}
+	if isAdmin {
+		return nil
+	}
+	for i, statement := range statements {
+		approved, err := c.isApproved(statement)
+		if err != nil {
+			return fmt.Errorf("Invalid policy statements: %s", err)
+		}
+		if !approved {
+			return fmt.Errorf("Policy statement %d has not been approved by an admin", i)
+		}
+	}
+	return nil
+}
+
+func (c *Config) isApproved(statement db.PolicyStatement) (bool, error) {
+	statementJSON, err := json.Marshal(statement)
+	if err != nil {
+		return false, err
+	}
+	for _, approved := range c.ApprovedStatements {
+		approvedJSON, err := json.Marshal(approved)
+		if err != nil {
+			return false, err
+		}
+		equal, err := rolemanager.PolicyDocumentsEqual(string(statementJSON), string(approvedJSON))
+		if err != nil {
+			return false, err
+		}
+		if equal {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Statements returns the statements added to the principal policy
+// for the lease: the statements of its profile, then its own statements.
+// Returns no statements for a nil lease.
+func (c *Config) Statements(lease *db.Lease) ([]db.PolicyStatement, error) {
+	statements := []db.PolicyStatement{}
+	if lease == nil {
+		return statements, nil
+	}
+	if lease.PolicyProfile != "" {
+		profile := c.Profiles[lease.PolicyProfile]
+		if profile == nil {
+			return nil, fmt.Errorf("Unknown policy profile %s, for lease %s", lease.PolicyProfile, lease.ID)
+		}
+		statements = append(statements, profile.Statements...)
+	}
+	return append(statements, lease.PolicyStatements...), nil
+}
+
+// StatementsForAccount returns the statements added to the principal policy
+// of the account, for its Active lease. Accounts without an Active lease
+// use the default principal policy, with no additional statements.
+func (c *Config) StatementsForAccount(dbSvc db.DBer, accountID string) ([]db.PolicyStatement, error) {
+	leases, err := dbSvc.FindLeasesByAccount(accountID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list leases for account %s", accountID)
+	}
+	return c.statementsForLeases(leases)
+}
+
+func (c *Config) statementsForLeases(leases []*db.Lease) ([]db.PolicyStatement, error) {
+	for _, lease := range leases {
+		if lease.LeaseStatus == db.Active {
+			return c.Statements(lease)
+		}
+	}
+	return c.Statements(nil)
+}
+
+// AccountPolicy returns the principal policy of the account, and its hash.
+// policy and policyHash are the rendered default principal policy, to which
+// the statements of the account's Active lease are added.
+//
+// Accounts with a Frozen lease use the frozen principal policy instead,
+// so principals may no longer modify the account.
+func (c *Config) AccountPolicy(dbSvc db.DBer, accountID string, policy string, policyHash string) (string, string, error) {
+	leases, err := dbSvc.FindLeasesByAccount(accountID)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "Failed to list leases for account %s", accountID)
+	}
+	for _, lease := range leases {
+		if lease.LeaseStatus == db.Frozen {
+			frozenPolicy := rolemanager.FrozenPrincipalPolicy(c.FrozenPolicy)
+			return frozenPolicy, fmt.Sprintf("%x", sha256.Sum256([]byte(frozenPolicy))), nil
+		}
+	}
+
+	statements, err := c.statementsForLeases(leases)
+	if err != nil {
+		return "", "", err
+	}
+	policy, err = Apply(policy, statements)
+	if err != nil {
+		return "", "", err
+	}
+	policyHash, err = Hash(policyHash, statements)
+	if err != nil {
+		return "", "", err
+	}
+	return policy, policyHash, nil
+}
+
+// Apply adds statements to the end of a rendered policy document
+func Apply(policy string, statements []db.PolicyStatement) (string, error) {
+	if len(statements) == 0 {
+		return policy, nil
+	}
+
+	var document map[string]interface{}
+	err := json.Unmarshal([]byte(policy), &document)
+	if err != nil {
+		return "", errors.Wrap(err, "Invalid principal policy")
+	}
+	var policyStatements []interface{}
+	switch existing := document["Statement"].(type) {
+	case []interface{}:
+		policyStatements = existing
+	case map[string]interface{}:
+		policyStatements = []interface{}{existing}
+	}
+	for _, statement := range statements {
+		policyStatements = append(policyStatements, statement)
+	}
+	document["Statement"] = policyStatements
+
+	documentJSON, err := json.Marshal(document)
+	if err != nil {
+		return "", err
+	}
+	return string(documentJSON), nil
+}
+
+// Hash returns the hash of a principal policy, with statements added.
+// policyHash is the hash of the rendered policy, which is returned as-is
+// when there are no statements.
+func Hash(policyHash string, statements []db.PolicyStatement) (string, error) {
+	if len(statements) == 0 {
+		return policyHash, nil
+	}
+	statementsJSON, err := json.Marshal(statements)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s+%x", policyHash, sha256.Sum256(statementsJSON)), nil
+}
@@ -0,0 +1,190 @@
+package policyprofile
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/rolemanager"
+)
+
+func TestNewConfigFromEnv(t *testing.T) {
+	t.Run("should parse profiles and approved statements", func(t *testing.T) {
+		os.Setenv("PRINCIPAL_POLICY_PROFILES", `{"analytics": {"description": "Athena", "statements": [{"Effect": "Allow", "Action": "athena:*", "Resource": "*"}]}}`)
+		os.Setenv("PRINCIPAL_POLICY_APPROVED_STATEMENTS", `[{"Effect": "Allow", "Action": "glue:*", "Resource": "*"}]`)
+		defer os.Unsetenv("PRINCIPAL_POLICY_PROFILES")
+		defer os.Unsetenv("PRINCIPAL_POLICY_APPROVED_STATEMENTS")
+
+		config, err := NewConfigFromEnv()
+		require.Nil(t, err)
+		require.Equal(t, []string{"analytics"}, config.ProfileNames())
+		require.Equal(t, "Athena", config.Profiles["analytics"].Description)
+		require.Len(t, config.ApprovedStatements, 1)
+	})
+
+	t.Run("should default to no profiles", func(t *testing.T) {
+		config, err := NewConfigFromEnv()
+		require.Nil(t, err)
+		require.Empty(t, config.Profiles)
+		require.Empty(t, config.ApprovedStatements)
+		require.Equal(t, rolemanager.FrozenPolicyDenyAll, config.FrozenPolicy)
+	})
+
+	t.Run("should reject unknown frozen policies", func(t *testing.T) {
+		os.Setenv("FROZEN_LEASE_POLICY", "writeOnly")
+		defer os.Unsetenv("FROZEN_LEASE_POLICY")
+
+		_, err := NewConfigFromEnv()
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "frozen lease policy must be denyAll or readOnly")
+	})
+
+	t.Run("should reject invalid statements", func(t *testing.T) {
+		os.Setenv("PRINCIPAL_POLICY_PROFILES", `{"analytics": {"statements": [{"Effect": "Maybe", "Action": "athena:*"}]}}`)
+		defer os.Unsetenv("PRINCIPAL_POLICY_PROFILES")
+
+		_, err := NewConfigFromEnv()
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "policy profile analytics")
+	})
+}
+
+func TestValidateLease(t *testing.T) {
+	config := &Config{
+		Profiles: map[string]*Profile{
+			"analytics": {Statements: []db.PolicyStatement{{"Effect": "Allow", "Action": "athena:*", "Resource": "*"}}},
+		},
+		ApprovedStatements: []db.PolicyStatement{
+			{"Effect": "Allow", "Action": []interface{}{"glue:*"}, "Resource": "*"},
+		},
+	}
+
+	require.Nil(t, config.ValidateLease("", nil, false))
+	require.Nil(t, config.ValidateLease("analytics", nil, false))
+
+	err := config.ValidateLease("unknown", nil, false)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "Available profiles: analytics")
+
+	// Approved statements match regardless of formatting
+	require.Nil(t, config.ValidateLease("", []db.PolicyStatement{
+		{"Resource": "*", "Action": "glue:*", "Effect": "Allow"},
+	}, false))
+
+	// Only admins may add statements which were not approved
+	unapproved := []db.PolicyStatement{{"Effect": "Allow", "Action": "organizations:*", "Resource": "*"}}
+	err = config.ValidateLease("", unapproved, false)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "not been approved")
+	require.Nil(t, config.ValidateLease("", unapproved, true))
+
+	require.NotNil(t, config.ValidateLease("", []db.PolicyStatement{{"Effect": "Allow"}}, true))
+}
+
+func TestStatementsForAccount(t *testing.T) {
+	config := &Config{
+		Profiles: map[string]*Profile{
+			"locked": {Statements: []db.PolicyStatement{{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}}},
+		},
+	}
+	dbSvc := db.NewMemoryDB(7)
+	_, err := dbSvc.PutLease(db.Lease{
+		ID:               "lease-1",
+		AccountID:        "123456789012",
+		PrincipalID:      "jdoe",
+		LeaseStatus:      db.Inactive,
+		PolicyStatements: []db.PolicyStatement{{"Effect": "Allow", "Action": "glue:*", "Resource": "*"}},
+	})
+	require.Nil(t, err)
+
+	// Accounts without an Active lease use the default policy
+	statements, err := config.StatementsForAccount(dbSvc, "123456789012")
+	require.Nil(t, err)
+	require.Empty(t, statements)
+
+	_, err = dbSvc.PutLease(db.Lease{
+		ID:               "lease-2",
+		AccountID:        "123456789012",
+		PrincipalID:      "jane",
+		LeaseStatus:      db.Active,
+		PolicyProfile:    "locked",
+		PolicyStatements: []db.PolicyStatement{{"Effect": "Allow", "Action": "athena:*", "Resource": "*"}},
+	})
+	require.Nil(t, err)
+
+	statements, err = config.StatementsForAccount(dbSvc, "123456789012")
+	require.Nil(t, err)
+	require.Equal(t, []db.PolicyStatement{
+		{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"},
+		{"Effect": "Allow", "Action": "athena:*", "Resource": "*"},
+	}, statements)
+}
+
+func TestAccountPolicy(t *testing.T) {
+	config := &Config{FrozenPolicy: rolemanager.FrozenPolicyReadOnly}
+	policy := `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "*", "Resource": "*"}]}`
+	dbSvc := db.NewMemoryDB(7)
+	_, err := dbSvc.PutLease(db.Lease{
+		ID:               "lease-1",
+		AccountID:        "123456789012",
+		PrincipalID:      "jdoe",
+		LeaseStatus:      db.Active,
+		PolicyStatements: []db.PolicyStatement{{"Effect": "Allow", "Action": "glue:*", "Resource": "*"}},
+	})
+	require.Nil(t, err)
+
+	// Active leases add their statements
+	accountPolicy, accountPolicyHash, err := config.AccountPolicy(dbSvc, "123456789012", policy, `"etag"`)
+	require.Nil(t, err)
+	require.Contains(t, accountPolicy, "glue:*")
+	require.NotEqual(t, `"etag"`, accountPolicyHash)
+
+	// Frozen leases use the frozen policy
+	_, err = dbSvc.TransitionLeaseStatus("123456789012", "jdoe", db.Active, db.Frozen, db.LeaseExpired)
+	require.Nil(t, err)
+	frozenPolicy, frozenPolicyHash, err := config.AccountPolicy(dbSvc, "123456789012", policy, `"etag"`)
+	require.Nil(t, err)
+	require.Equal(t, rolemanager.FrozenPrincipalPolicy(rolemanager.FrozenPolicyReadOnly), frozenPolicy)
+	require.NotEqual(t, accountPolicyHash, frozenPolicyHash)
+}
+
+func TestApply(t *testing.T) {
+	policy := `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:*", "Resource": "*"}]}`
+
+	applied, err := Apply(policy, nil)
+	require.Nil(t, err)
+	require.Equal(t, policy, applied)
+
+	statements := []db.PolicyStatement{{"Effect": "Deny", "Action": "s3:DeleteBucket", "Resource": "*"}}
+	applied, err = Apply(policy, statements)
+	require.Nil(t, err)
+	var document struct {
+		Version   string
+		Statement []map[string]interface{}
+	}
+	require.Nil(t, json.Unmarshal([]byte(applied), &document))
+	require.Equal(t, "2012-10-17", document.Version)
+	require.Len(t, document.Statement, 2)
+	require.Equal(t, "Deny", document.Statement[1]["Effect"])
+
+	_, err = Apply("not a policy", statements)
+	require.NotNil(t, err)
+}
+
+func TestHash(t *testing.T) {
+	hash, err := Hash(`"etag"`, nil)
+	require.Nil(t, err)
+	require.Equal(t, `"etag"`, hash)
+
+	statements := []db.PolicyStatement{{"Effect": "Deny", "Action": "ec2:*", "Resource": "*"}}
+	hash, err = Hash(`"etag"`, statements)
+	require.Nil(t, err)
+	require.NotEqual(t, `"etag"`, hash)
+
+	other, err := Hash(`"etag"`, []db.PolicyStatement{{"Effect": "Deny", "Action": "s3:*", "Resource": "*"}})
+	require.Nil(t, err)
+	require.NotEqual(t, hash, other)
+}
@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/Optum/dce/pkg/db"
 
 	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/resetstatus"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sqs"
 )
 
@@ -20,14 +24,28 @@ type ResetInput struct {
 	ResetBuild    common.Builder
 	BuildName     *string
 	DbSvc         db.DBer
+	// ResetStatus, if set, records that the reset
+	// of each account is Queued, once its build starts
+	ResetStatus *resetstatus.Tracker
+	// DeadLetterQueue, if set, receives messages which failed
+	// MaxReceiveCount times, so they are not retried forever
+	DeadLetterQueue *common.DeadLetterQueue
+	MaxReceiveCount int
 }
 
+// DefaultMaxReceiveCount is the number of times a reset request is
+// received and fails, before it is moved to the dead-letter queue
+const DefaultMaxReceiveCount = 5
+
 // ResetResult is the individual results of a Reset trigger for an AWS
 // Account, including if the CodeBuild build has started executing and
 // if the SQS Message was deleted from the Queue.
 type ResetResult struct {
 	BuildTrigger    bool
 	MessageDeletion bool
+	// DeadLettered is true if the message failed too many times,
+	// and was moved to the dead-letter queue
+	DeadLettered bool
 }
 
 // ResetOutput is the overall results of the Reset function containing the
@@ -53,6 +71,10 @@ func Reset(input *ResetInput) (*ResetOutput, error) {
 	messageInput := &sqs.ReceiveMessageInput{
 		QueueUrl:            input.ResetQueueURL,
 		MaxNumberOfMessages: &maxMessages,
+		// Used to detect messages which fail repeatedly
+		AttributeNames: []*string{
+			aws.String(sqs.MessageSystemAttributeNameApproximateReceiveCount),
+		},
 	}
 	messages, err := input.ResetQueue.ReceiveMessage(messageInput)
 	if err != nil {
@@ -77,76 +99,60 @@ func Reset(input *ResetInput) (*ResetOutput, error) {
 			// Get the Account from the Database
 			account, err := input.DbSvc.GetAccount(accountID)
 			if err != nil {
-				failTriggerResetOnAccount(&output, result, accountID,
+				failMessage(input, &output, message, result,
 					err.Error())
 				continue
 			}
 			if account == nil {
-				failTriggerResetOnAccount(&output, result, accountID,
+				failMessage(input, &output, message, result,
 					fmt.Sprintf("Account %s doesn't exist", accountID))
 				continue
 			}
 
-			// Lookup the Account's AdminRoleArn
-			accountAdminRoleName, err := extractRoleNameFromARN(account.AdminRoleArn)
-			if err != nil {
-				failTriggerResetOnAccount(&output, result, accountID,
-					fmt.Sprintf("Cannot extract Admin Role Name from %s",
-						account.AdminRoleArn))
+			// Skip accounts which no longer need a reset, eg. when a
+			// retried reset already returned the account to the pool
+			if !resetstatus.NeedsReset(account) {
+				log.Printf("Skipping reset of account %s with status %s\n",
+					accountID, account.AccountStatus)
+				deleteMessage(input, &output, message, result)
 				continue
 			}
 
-			// Lookup the account's PrincipalRoleArn
-			accountPrincipalRoleName, err := extractRoleNameFromARN(
-				account.PrincipalRoleArn)
+			// Set Reset Build Env Vars
+			resetBuildEnvironment, err := BuildEnvironment(account)
 			if err != nil {
-				failTriggerResetOnAccount(&output, result, accountID,
-					fmt.Sprintf("Cannot extract Principal Role Name from %s",
-						account.PrincipalRoleArn))
+				failMessage(input, &output, message, result,
+					err.Error())
 				continue
 			}
 			log.Printf("Start Account: %s\nMessage ID: %s\n", accountID,
 				*message.MessageId)
 
-			// Set Reset Build Env Vars
-			resetBuildEnvironment := map[string]string{
-				"RESET_ACCOUNT":                     account.ID,
-				"RESET_ACCOUNT_ADMIN_ROLE_NAME":     accountAdminRoleName,
-				"RESET_ACCOUNT_PRINCIPAL_ROLE_NAME": accountPrincipalRoleName,
-			}
-
 			// Trigger Code Pipeline
 			log.Printf("Triggering Reset Build %s for Account %s\n",
 				*input.BuildName, accountID)
 			buildID, err := input.ResetBuild.StartBuild(input.BuildName,
 				resetBuildEnvironment)
 			if err != nil {
-				failTriggerResetOnAccount(&output, result, accountID,
+				failMessage(input, &output, message, result,
 					err.Error())
 				continue
 			}
 			result.BuildTrigger = true
 			log.Printf("Triggered Build ID: %s\n", buildID)
 
-			// Construct the message to be deleted
-			deleteMessageInput := sqs.DeleteMessageInput{
-				QueueUrl:      input.ResetQueueURL,
-				ReceiptHandle: message.ReceiptHandle,
+			// Record the build on the account.
+			// The build is running, so the reset continues if this fails.
+			if input.ResetStatus != nil {
+				_, err = input.ResetStatus.Queued(accountID, buildID)
+				if err != nil {
+					log.Printf("Failed to update reset status for account %s: %s\n", accountID, err)
+				}
 			}
 
 			// Delete the Message
-			_, err = input.ResetQueue.DeleteMessage(&deleteMessageInput)
-			if err != nil {
-				failTriggerResetOnAccount(&output, result, accountID,
-					err.Error())
-				continue
-			}
-			result.MessageDeletion = true
-			log.Printf("Deleted Message: %s\n", *message.MessageId)
+			deleteMessage(input, &output, message, result)
 			log.Printf("End Account: %s\n", accountID)
-
-			// Add the account to the output
-			output.Accounts[accountID] = result
 		}
 
 		// Retrieve at most 10 messages from the Queue
@@ -167,6 +173,31 @@ func Reset(input *ResetInput) (*ResetOutput, error) {
 	return &output, nil
 }
 
+// BuildEnvironment returns the environment variables
+// of a reset build for the account
+func BuildEnvironment(account *db.Account) (map[string]string, error) {
+	// Lookup the Account's AdminRoleArn
+	accountAdminRoleName, err := extractRoleNameFromARN(account.AdminRoleArn)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot extract Admin Role Name from %s",
+			account.AdminRoleArn)
+	}
+
+	// Lookup the account's PrincipalRoleArn
+	accountPrincipalRoleName, err := extractRoleNameFromARN(
+		account.PrincipalRoleArn)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot extract Principal Role Name from %s",
+			account.PrincipalRoleArn)
+	}
+
+	return map[string]string{
+		"RESET_ACCOUNT":                     account.ID,
+		"RESET_ACCOUNT_ADMIN_ROLE_NAME":     accountAdminRoleName,
+		"RESET_ACCOUNT_PRINCIPAL_ROLE_NAME": accountPrincipalRoleName,
+	}, nil
+}
+
 // extractRoleNameFromARN returns the name of the role from its arn
 func extractRoleNameFromARN(arn string) (string, error) {
 	reg := regexp.MustCompile("arn:aws:iam::\\d{12}:role/(.+)")
@@ -177,6 +208,72 @@ func extractRoleNameFromARN(arn string) (string, error) {
 	return result[1], nil
 }
 
+// deleteMessage deletes the Message from the Queue, and adds the
+// account to the ResetOutput
+func deleteMessage(input *ResetInput, output *ResetOutput, message *sqs.Message,
+	result ResetResult) {
+	accountID := aws.StringValue(message.Body)
+	_, err := input.ResetQueue.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      input.ResetQueueURL,
+		ReceiptHandle: message.ReceiptHandle,
+	})
+	if err != nil {
+		failTriggerResetOnAccount(output, result, accountID, err.Error())
+		return
+	}
+	result.MessageDeletion = true
+	log.Printf("Deleted Message: %s\n", aws.StringValue(message.MessageId))
+	output.Accounts[accountID] = result
+}
+
+// failMessage will update the ResetOutput with the failed results of the
+// trigger of an account. Messages which failed MaxReceiveCount times are
+// moved to the dead-letter queue, if there is one.
+// Otherwise, the message is left on the queue to be retried.
+func failMessage(input *ResetInput, output *ResetOutput, message *sqs.Message,
+	result ResetResult, errMessage string) {
+	accountID := aws.StringValue(message.Body)
+	failTriggerResetOnAccount(output, result, accountID, errMessage)
+	if input.DeadLetterQueue == nil {
+		return
+	}
+
+	receiveCount, _ := strconv.Atoi(aws.StringValue(
+		message.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]))
+	maxReceiveCount := input.MaxReceiveCount
+	if maxReceiveCount < 1 {
+		maxReceiveCount = DefaultMaxReceiveCount
+	}
+	if receiveCount < maxReceiveCount {
+		return
+	}
+
+	log.Printf("Moving reset request for account %s to the dead-letter queue, after %d attempts\n",
+		accountID, receiveCount)
+	err := input.DeadLetterQueue.Send(&common.DeadLetter{
+		Body:           accountID,
+		Error:          errMessage,
+		ReceiveCount:   receiveCount,
+		DeadLetteredOn: time.Now().Unix(),
+	})
+	if err != nil {
+		log.Printf("Error: Failed to send message %s to the dead-letter queue: %s",
+			aws.StringValue(message.MessageId), err)
+		return
+	}
+	_, err = input.ResetQueue.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      input.ResetQueueURL,
+		ReceiptHandle: message.ReceiptHandle,
+	})
+	if err != nil {
+		log.Printf("Error: Failed to delete dead-lettered message %s: %s",
+			aws.StringValue(message.MessageId), err)
+		return
+	}
+	result.DeadLettered = true
+	output.Accounts[accountID] = result
+}
+
 // failTriggerResetOnAccount will update the ResetOutput with the failed results
 // of the trigger of an account
 func failTriggerResetOnAccount(output *ResetOutput, result ResetResult,
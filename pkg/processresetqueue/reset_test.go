@@ -13,6 +13,7 @@ import (
 	comMocks "github.com/Optum/dce/pkg/common/mocks"
 	"github.com/Optum/dce/pkg/db"
 	dbMocks "github.com/Optum/dce/pkg/db/mocks"
+	"github.com/Optum/dce/pkg/resetstatus"
 	"github.com/stretchr/testify/require"
 
 	"github.com/Optum/dce/pkg/common"
@@ -34,6 +35,11 @@ func (queue *mockQueue) SendMessage(queueURL *string, message *string) error {
 	return nil
 }
 
+// SendMessageWithDelay mocks the interface function, not used
+func (queue *mockQueue) SendMessageWithDelay(queueURL *string, message *string, delaySeconds int64) error {
+	return nil
+}
+
 func (queue *mockQueue) NewFromEnv() error {
 	return nil
 }
@@ -85,6 +91,12 @@ func (queue *mockQueue) DeleteMessage(input *sqs.DeleteMessageInput) (
 	return &sqs.DeleteMessageOutput{}, nil
 }
 
+// ChangeMessageVisibility mocks the interface function, not used
+func (queue *mockQueue) ChangeMessageVisibility(input *sqs.ChangeMessageVisibilityInput) (
+	*sqs.ChangeMessageVisibilityOutput, error) {
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
 // resetTest is the testing structure used for table driven testing on the
 // Reset Function
 type resetTest struct {
@@ -111,6 +123,7 @@ func TestReset(t *testing.T) {
 			ResetQueue:    createMockQueue(0),
 			ResetQueueURL: "https://mytesturl.com/123456789012/reset_queue",
 			GetAccount: &db.Account{
+				AccountStatus:    db.NotReady,
 				ID:               "1234567890",
 				AdminRoleArn:     "arn:aws:iam::123456789012:role/AdminRole",
 				PrincipalRoleArn: "arn:aws:iam::123456789012:role/PrincipalRole",
@@ -132,6 +145,7 @@ func TestReset(t *testing.T) {
 			ResetQueue:    createMockQueue(1),
 			ResetQueueURL: "https://mytesturl.com/123456789012/reset_queue",
 			GetAccount: &db.Account{
+				AccountStatus:    db.NotReady,
 				ID:               "1234567890",
 				AdminRoleArn:     "arn:aws:iam::123456789012:role/AdminRole",
 				PrincipalRoleArn: "arn:aws:iam::123456789012:role/PrincipalRole",
@@ -158,6 +172,7 @@ func TestReset(t *testing.T) {
 			ResetQueue:    createMockQueue(5),
 			ResetQueueURL: "https://mytesturl.com/123456789012/reset_queue",
 			GetAccount: &db.Account{
+				AccountStatus:    db.NotReady,
 				ID:               "1234567890",
 				AdminRoleArn:     "arn:aws:iam::123456789012:role/AdminRole",
 				PrincipalRoleArn: "arn:aws:iam::123456789012:role/PrincipalRole",
@@ -217,7 +232,8 @@ func TestReset(t *testing.T) {
 			ResetQueue:    createMockQueue(1),
 			ResetQueueURL: "https://mytesturl.com/123456789012/reset_queue",
 			GetAccount: &db.Account{
-				AdminRoleArn: "MyArn",
+				AccountStatus: db.NotReady,
+				AdminRoleArn:  "MyArn",
 			},
 			ExpectedBuildCount: 0,
 			ExpectedOutput: &ResetOutput{
@@ -236,7 +252,8 @@ func TestReset(t *testing.T) {
 			ResetQueue:    createMockQueue(1),
 			ResetQueueURL: "https://mytesturl.com/123456789012/fail_receive",
 			GetAccount: &db.Account{
-				AdminRoleArn: "arn:aws:iam::123456789012:role/AdminRole",
+				AccountStatus: db.NotReady,
+				AdminRoleArn:  "arn:aws:iam::123456789012:role/AdminRole",
 			},
 			ExpectedBuildCount: 0,
 			ExpectedOutput: &ResetOutput{
@@ -250,6 +267,7 @@ func TestReset(t *testing.T) {
 			ResetQueue:    createMockQueue(1),
 			ResetQueueURL: "https://mytesturl.com/123456789012/fail_delete",
 			GetAccount: &db.Account{
+				AccountStatus:    db.NotReady,
 				ID:               "1234567890",
 				AdminRoleArn:     "arn:aws:iam::123456789012:role/AdminRole",
 				PrincipalRoleArn: "arn:aws:iam::123456789012:role/PrincipalRole",
@@ -278,6 +296,7 @@ func TestReset(t *testing.T) {
 			ResetQueueURL: "https://mytesturl.com/123456789012/reset_queue",
 			BuildError:    errors.New("Fail Triggering Build"),
 			GetAccount: &db.Account{
+				AccountStatus:    db.NotReady,
 				ID:               "1234567890",
 				AdminRoleArn:     "arn:aws:iam::123456789012:role/AdminRole",
 				PrincipalRoleArn: "arn:aws:iam::123456789012:role/PrincipalRole",
@@ -438,6 +457,7 @@ func TestReset(t *testing.T) {
 		mockDb.
 			On("GetAccount", "accountId-1").
 			Return(&db.Account{
+				AccountStatus:    db.NotReady,
 				ID:               "123456789012",
 				AdminRoleArn:     "arn:aws:iam::123456789012:role/AdminRole",
 				PrincipalRoleArn: "arn:aws:iam::123456789012:role/PrincipalRole",
@@ -468,10 +488,95 @@ func TestReset(t *testing.T) {
 		// Make sure we called builder.StartBuild, with the expected params
 		mockBuilder.AssertExpectations(t)
 	})
+
+	t.Run("Should set the account's reset status to Queued", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		account := mockAccount()
+		account.ID = "accountId-1"
+		require.Nil(t, dbSvc.PutAccount(*account))
+
+		mockBuilder := &comMocks.Builder{}
+		mockBuilder.On("StartBuild", mock.Anything, mock.Anything).
+			Return("mock-build-id", nil)
+
+		_, err := Reset(&ResetInput{
+			ResetQueue:    createMockQueue(1),
+			ResetQueueURL: aws.String("https://mytesturl.com/123456789012/reset_queue"),
+			ResetBuild:    mockBuilder,
+			BuildName:     aws.String("mock-build-name"),
+			DbSvc:         dbSvc,
+			ResetStatus:   &resetstatus.Tracker{DB: dbSvc},
+		})
+		require.Nil(t, err)
+
+		account, err = dbSvc.GetAccount("accountId-1")
+		require.Nil(t, err)
+		require.Equal(t, db.ResetQueued, account.ResetStatus.State)
+		require.Equal(t, "mock-build-id", account.ResetStatus.BuildID)
+	})
+
+	t.Run("Should skip accounts which no longer need a reset, and delete their messages", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		account := mockAccount()
+		account.ID = "ready-account"
+		account.AccountStatus = db.Ready
+		require.Nil(t, dbSvc.PutAccount(*account))
+		queue := common.NewMemoryQueue()
+		queueURL := "reset-queue"
+		require.Nil(t, queue.SendMessage(&queueURL, aws.String("ready-account")))
+
+		mockBuilder := &comMocks.Builder{}
+		output, err := Reset(&ResetInput{
+			ResetQueue:    queue,
+			ResetQueueURL: &queueURL,
+			ResetBuild:    mockBuilder,
+			BuildName:     aws.String("mock-build-name"),
+			DbSvc:         dbSvc,
+		})
+		require.Nil(t, err)
+		require.Equal(t, ResetResult{MessageDeletion: true}, output.Accounts["ready-account"])
+		require.Empty(t, queue.Messages(queueURL))
+		mockBuilder.AssertNotCalled(t, "StartBuild")
+	})
+
+	t.Run("Should move messages which fail MaxReceiveCount times to the dead-letter queue", func(t *testing.T) {
+		queue := common.NewMemoryQueue()
+		queueURL := "reset-queue"
+		require.Nil(t, queue.SendMessage(&queueURL, aws.String("missing-account")))
+		dlq := &common.DeadLetterQueue{Queue: queue, URL: "reset-dlq"}
+
+		mockBuilder := &comMocks.Builder{}
+		resetInput := &ResetInput{
+			ResetQueue:      queue,
+			ResetQueueURL:   &queueURL,
+			ResetBuild:      mockBuilder,
+			BuildName:       aws.String("mock-build-name"),
+			DbSvc:           db.NewMemoryDB(7),
+			DeadLetterQueue: dlq,
+			MaxReceiveCount: 2,
+		}
+
+		// The in-memory queue does not hide received messages, so the
+		// message is received again in the same call, and is
+		// dead-lettered after its second failure
+		output, err := Reset(resetInput)
+		require.NotNil(t, err)
+		require.True(t, output.Accounts["missing-account"].DeadLettered)
+		require.Empty(t, queue.Messages(queueURL))
+		mockBuilder.AssertNotCalled(t, "StartBuild")
+
+		letters, err := dlq.List()
+		require.Nil(t, err)
+		require.Len(t, letters, 1)
+		require.Equal(t, "missing-account", letters[0].Body)
+		require.Equal(t, "Account missing-account doesn't exist", letters[0].Error)
+		require.Equal(t, 2, letters[0].ReceiveCount)
+	})
 }
 
 func mockAccount() *db.Account {
 	return &db.Account{
+		AccountStatus:    db.NotReady,
 		ID:               "123456789012",
 		AdminRoleArn:     "arn:aws:iam::123456789012:role/AdminRole",
 		PrincipalRoleArn: "arn:aws:iam::123456789012:role/PrincipalRole",
@@ -1,8 +1,10 @@
 package reset
 
 import (
+	"context"
 	"log"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/athena"
 	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
 )
@@ -18,39 +20,72 @@ type AthenaService interface {
 // AthenaReset defines a concrete implementation of the above Service interface
 type AthenaReset struct {
 	Client athenaiface.AthenaAPI
+	// Context cancels the Athena requests, if set
+	Context aws.Context
+}
+
+// context returns the context for Athena requests
+func (athenaReset AthenaReset) context() aws.Context {
+	if athenaReset.Context == nil {
+		return aws.BackgroundContext()
+	}
+	return athenaReset.Context
 }
 
 // ListWorkGroups implemenation
 func (athenaReset AthenaReset) ListWorkGroups(input *athena.ListWorkGroupsInput) (*athena.ListWorkGroupsOutput, error) {
-	return athenaReset.Client.ListWorkGroups(input)
+	return athenaReset.Client.ListWorkGroupsWithContext(athenaReset.context(), input)
 }
 
 // ListNamedQueries implemenation
 func (athenaReset AthenaReset) ListNamedQueries(input *athena.ListNamedQueriesInput) (*athena.ListNamedQueriesOutput, error) {
-	return athenaReset.Client.ListNamedQueries(input)
+	return athenaReset.Client.ListNamedQueriesWithContext(athenaReset.context(), input)
 }
 
 // DeleteWorkGroup implemenation
 func (athenaReset AthenaReset) DeleteWorkGroup(input *athena.DeleteWorkGroupInput) (*athena.DeleteWorkGroupOutput, error) {
-	return athenaReset.Client.DeleteWorkGroup(input)
+	return athenaReset.Client.DeleteWorkGroupWithContext(athenaReset.context(), input)
 }
 
 // DeleteNamedQuery implemenation
 func (athenaReset AthenaReset) DeleteNamedQuery(input *athena.DeleteNamedQueryInput) (*athena.DeleteNamedQueryOutput, error) {
-	return athenaReset.Client.DeleteNamedQuery(input)
+	return athenaReset.Client.DeleteNamedQueryWithContext(athenaReset.context(), input)
 }
 
 // DeleteAthenaResources deletes all aethna resources in the current aws session
 func DeleteAthenaResources(athenaSvc AthenaService) error {
+	_, err := deleteAthenaResources(athenaSvc, false)
+	return err
+}
+
+// AthenaStep is a reset Step which deletes Athena workgroups and named queries
+type AthenaStep struct {
+	Client athenaiface.AthenaAPI
+}
+
+// Name of the step
+func (step AthenaStep) Name() string {
+	return "athena"
+}
+
+// Run deletes Athena workgroups and named queries
+func (step AthenaStep) Run(ctx context.Context, input *StepInput) ([]string, error) {
+	return deleteAthenaResources(AthenaReset{Client: step.Client, Context: ctx}, input.DryRun)
+}
+
+// deleteAthenaResources deletes all athena resources in the current aws session,
+// and returns the deleted resources. With dryRun, nothing is deleted.
+func deleteAthenaResources(athenaSvc AthenaService, dryRun bool) ([]string, error) {
 
 	var maxResult int64 = 50
+	deleted := []string{}
 	// Delete all workgroups
 	listWorkGroupsInput := &athena.ListWorkGroupsInput{
 		MaxResults: &maxResult,
 	}
 	listWorkGroupsOutput, err := athenaSvc.ListWorkGroups(listWorkGroupsInput)
 	if err != nil {
-		return err
+		return deleted, err
 	}
 
 	for _, workGroup := range listWorkGroupsOutput.WorkGroups {
@@ -59,6 +94,10 @@ func DeleteAthenaResources(athenaSvc AthenaService) error {
 		if *workGroup.Name == "primary" {
 			continue
 		}
+		if dryRun {
+			deleted = append(deleted, "workgroup/"+*workGroup.Name)
+			continue
+		}
 		deleteWorkGroupInput := &athena.DeleteWorkGroupInput{
 			RecursiveDeleteOption: &isDelete,
 			WorkGroup:             workGroup.Name,
@@ -67,27 +106,33 @@ func DeleteAthenaResources(athenaSvc AthenaService) error {
 		_, err := athenaSvc.DeleteWorkGroup(deleteWorkGroupInput)
 		if err != nil {
 			log.Printf("Athena workgroup delete error: %v", err)
-			return err
+			return deleted, err
 		}
+		deleted = append(deleted, "workgroup/"+*workGroup.Name)
 	}
 
 	// Delete all namedqueries
 	listNamedQueriesInput := &athena.ListNamedQueriesInput{}
 	listNamedQueriesOutput, err := athenaSvc.ListNamedQueries(listNamedQueriesInput)
 	if err != nil {
-		return err
+		return deleted, err
 	}
 
 	for _, namedQuery := range listNamedQueriesOutput.NamedQueryIds {
+		if dryRun {
+			deleted = append(deleted, "namedquery/"+*namedQuery)
+			continue
+		}
 		log.Printf("Starting Athena namedquery delete %v", *namedQuery)
 		deleteNamedQueryInput := &athena.DeleteNamedQueryInput{
 			NamedQueryId: namedQuery,
 		}
 		_, err := athenaSvc.DeleteNamedQuery(deleteNamedQueryInput)
 		if err != nil {
-			return err
+			return deleted, err
 		}
+		deleted = append(deleted, "namedquery/"+*namedQuery)
 	}
 
-	return nil
+	return deleted, nil
 }
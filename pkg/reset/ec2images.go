@@ -0,0 +1,138 @@
+package reset
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+// EC2ImagesStep is a reset Step which deregisters AMIs
+// and deletes EBS snapshots owned by the account, in each reset region.
+// All enabled regions are reset if none are configured.
+//
+// Images and snapshots kept by the account's nuke config overrides
+// are not deleted, nor are the snapshots of kept images.
+type EC2ImagesStep struct {
+	// NewClient creates an EC2 client for a region
+	NewClient func(region string) ec2iface.EC2API
+}
+
+// Name of the step
+func (step EC2ImagesStep) Name() string {
+	return "ec2-images"
+}
+
+// Run deregisters AMIs and deletes snapshots, and returns them
+// as "<region>/<id>"
+func (step EC2ImagesStep) Run(ctx context.Context, input *StepInput) ([]string, error) {
+	deleted := []string{}
+	regions, err := step.regions(ctx, input)
+	if err != nil {
+		return deleted, err
+	}
+	for _, region := range regions {
+		client := step.NewClient(region)
+
+		// Deregister images first, as snapshots
+		// cannot be deleted while they back an image
+		images, err := client.DescribeImagesWithContext(ctx, &ec2.DescribeImagesInput{
+			Owners: []*string{aws.String("self")},
+		})
+		if err != nil {
+			return deleted, err
+		}
+		keptSnapshots := map[string]bool{}
+		for _, image := range images.Images {
+			properties := ec2Properties(image.Tags)
+			properties["Name"] = aws.StringValue(image.Name)
+			properties["CreationDate"] = aws.StringValue(image.CreationDate)
+			if input.keeps("EC2Image", *image.ImageId, properties) {
+				log.Printf("Keeping image %s in %s, which is filtered by the nuke config", *image.ImageId, region)
+				// Snapshots cannot be deleted while they back an image
+				for _, mapping := range image.BlockDeviceMappings {
+					if mapping.Ebs != nil && mapping.Ebs.SnapshotId != nil {
+						keptSnapshots[*mapping.Ebs.SnapshotId] = true
+					}
+				}
+				continue
+			}
+			if !input.DryRun {
+				log.Printf("Deregistering image %s in %s", *image.ImageId, region)
+				_, err := client.DeregisterImageWithContext(ctx, &ec2.DeregisterImageInput{
+					ImageId: image.ImageId,
+				})
+				if err != nil {
+					return deleted, err
+				}
+			}
+			deleted = append(deleted, region+"/"+*image.ImageId)
+		}
+
+		snapshotIDs := []*string{}
+		err = client.DescribeSnapshotsPagesWithContext(ctx, &ec2.DescribeSnapshotsInput{
+			OwnerIds: []*string{aws.String("self")},
+		}, func(page *ec2.DescribeSnapshotsOutput, lastPage bool) bool {
+			for _, snapshot := range page.Snapshots {
+				properties := ec2Properties(snapshot.Tags)
+				if snapshot.StartTime != nil {
+					properties["StartTime"] = snapshot.StartTime.Format(time.RFC3339)
+				}
+				if keptSnapshots[*snapshot.SnapshotId] ||
+					input.keeps("EC2Snapshot", *snapshot.SnapshotId, properties) {
+					log.Printf("Keeping snapshot %s in %s, which is filtered by the nuke config", *snapshot.SnapshotId, region)
+					continue
+				}
+				snapshotIDs = append(snapshotIDs, snapshot.SnapshotId)
+			}
+			return true
+		})
+		if err != nil {
+			return deleted, err
+		}
+		for _, snapshotID := range snapshotIDs {
+			if !input.DryRun {
+				log.Printf("Deleting snapshot %s in %s", *snapshotID, region)
+				_, err := client.DeleteSnapshotWithContext(ctx, &ec2.DeleteSnapshotInput{
+					SnapshotId: snapshotID,
+				})
+				if err != nil {
+					return deleted, err
+				}
+			}
+			deleted = append(deleted, region+"/"+*snapshotID)
+		}
+	}
+	return deleted, nil
+}
+
+// ec2Properties returns the tags of an EC2 resource
+// as aws-nuke properties, eg. "tag:Name"
+func ec2Properties(tags []*ec2.Tag) map[string]string {
+	properties := map[string]string{}
+	for _, tag := range tags {
+		properties["tag:"+aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return properties
+}
+
+// regions returns the reset regions,
+// or all regions enabled for the account if none are configured
+func (step EC2ImagesStep) regions(ctx context.Context, input *StepInput) ([]string, error) {
+	if len(input.Regions) > 0 {
+		return input.Regions, nil
+	}
+
+	res, err := step.NewClient("us-east-1").DescribeRegionsWithContext(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, err
+	}
+	regions := []string{}
+	for _, region := range res.Regions {
+		regions = append(regions, aws.StringValue(region.RegionName))
+	}
+	return regions, nil
+}
@@ -0,0 +1,118 @@
+package reset
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Optum/dce/pkg/db"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/stretchr/testify/require"
+)
+
+type mockEC2Images struct {
+	ec2iface.EC2API
+	calls *[]string
+}
+
+func (m mockEC2Images) DescribeRegionsWithContext(ctx aws.Context, input *ec2.DescribeRegionsInput, opts ...request.Option) (*ec2.DescribeRegionsOutput, error) {
+	return &ec2.DescribeRegionsOutput{
+		Regions: []*ec2.Region{{RegionName: aws.String("eu-west-1")}},
+	}, nil
+}
+
+func (m mockEC2Images) DescribeImagesWithContext(ctx aws.Context, input *ec2.DescribeImagesInput, opts ...request.Option) (*ec2.DescribeImagesOutput, error) {
+	return &ec2.DescribeImagesOutput{
+		Images: []*ec2.Image{{
+			ImageId: aws.String("ami-1"),
+			Tags:    []*ec2.Tag{{Key: aws.String("keep"), Value: aws.String("true")}},
+			BlockDeviceMappings: []*ec2.BlockDeviceMapping{{
+				Ebs: &ec2.EbsBlockDevice{SnapshotId: aws.String("snap-1")},
+			}},
+		}},
+	}, nil
+}
+
+func (m mockEC2Images) DeregisterImageWithContext(ctx aws.Context, input *ec2.DeregisterImageInput, opts ...request.Option) (*ec2.DeregisterImageOutput, error) {
+	*m.calls = append(*m.calls, "deregister "+*input.ImageId)
+	return &ec2.DeregisterImageOutput{}, nil
+}
+
+func (m mockEC2Images) DescribeSnapshotsPagesWithContext(ctx aws.Context, input *ec2.DescribeSnapshotsInput, fn func(*ec2.DescribeSnapshotsOutput, bool) bool, opts ...request.Option) error {
+	fn(&ec2.DescribeSnapshotsOutput{
+		Snapshots: []*ec2.Snapshot{{SnapshotId: aws.String("snap-1")}, {SnapshotId: aws.String("snap-2")}},
+	}, true)
+	return nil
+}
+
+func (m mockEC2Images) DeleteSnapshotWithContext(ctx aws.Context, input *ec2.DeleteSnapshotInput, opts ...request.Option) (*ec2.DeleteSnapshotOutput, error) {
+	*m.calls = append(*m.calls, "delete "+*input.SnapshotId)
+	return &ec2.DeleteSnapshotOutput{}, nil
+}
+
+func TestEC2ImagesStep(t *testing.T) {
+	calls := []string{}
+	regions := []string{}
+	step := EC2ImagesStep{
+		NewClient: func(region string) ec2iface.EC2API {
+			regions = append(regions, region)
+			return mockEC2Images{calls: &calls}
+		},
+	}
+
+	t.Run("should deregister images before deleting snapshots", func(t *testing.T) {
+		deleted, err := step.Run(context.Background(), &StepInput{Regions: []string{"us-east-1", "us-west-2"}})
+		require.Nil(t, err)
+		require.Equal(t, []string{"us-east-1", "us-west-2"}, regions)
+		require.Equal(t, []string{
+			"us-east-1/ami-1", "us-east-1/snap-1", "us-east-1/snap-2",
+			"us-west-2/ami-1", "us-west-2/snap-1", "us-west-2/snap-2",
+		}, deleted)
+		require.Equal(t, []string{
+			"deregister ami-1", "delete snap-1", "delete snap-2",
+			"deregister ami-1", "delete snap-1", "delete snap-2",
+		}, calls)
+	})
+
+	t.Run("should not delete in dry run mode", func(t *testing.T) {
+		calls = []string{}
+		deleted, err := step.Run(context.Background(), &StepInput{Regions: []string{"us-east-1"}, DryRun: true})
+		require.Nil(t, err)
+		require.Equal(t, []string{"us-east-1/ami-1", "us-east-1/snap-1", "us-east-1/snap-2"}, deleted)
+		require.Empty(t, calls)
+	})
+
+	t.Run("should keep images filtered by the nuke config, and their snapshots", func(t *testing.T) {
+		calls = []string{}
+		deleted, err := step.Run(context.Background(), &StepInput{
+			Regions: []string{"us-east-1"},
+			NukeConfig: &db.NukeConfig{
+				Filters: map[string][]db.NukeFilter{
+					"EC2Image": {{Property: "tag:keep", Value: "true"}},
+				},
+			},
+		})
+		require.Nil(t, err)
+		require.Equal(t, []string{"us-east-1/snap-2"}, deleted)
+		require.Equal(t, []string{"delete snap-2"}, calls)
+	})
+
+	t.Run("should keep snapshots of excluded resource types", func(t *testing.T) {
+		calls = []string{}
+		deleted, err := step.Run(context.Background(), &StepInput{
+			Regions:    []string{"us-east-1"},
+			NukeConfig: &db.NukeConfig{ExcludeResourceTypes: []string{"EC2Snapshot"}},
+		})
+		require.Nil(t, err)
+		require.Equal(t, []string{"us-east-1/ami-1"}, deleted)
+		require.Equal(t, []string{"deregister ami-1"}, calls)
+	})
+
+	t.Run("should reset all regions, if none are configured", func(t *testing.T) {
+		deleted, err := step.Run(context.Background(), &StepInput{DryRun: true})
+		require.Nil(t, err)
+		require.Equal(t, []string{"eu-west-1/ami-1", "eu-west-1/snap-1", "eu-west-1/snap-2"}, deleted)
+	})
+}
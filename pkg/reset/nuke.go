@@ -1,15 +1,20 @@
 package reset
 
 import (
-	"github.com/pkg/errors"
+	"context"
 	"time"
 
+	"github.com/pkg/errors"
+
 	"github.com/Optum/dce/pkg/common"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/rebuy-de/aws-nuke/cmd"
 	"github.com/rebuy-de/aws-nuke/pkg/awsutil"
 )
 
+// nukeTimeout is the maximum time aws-nuke may run for
+const nukeTimeout = time.Minute * 60
+
 // NukeAccountInput is the container used for the TokenService and the
 // NukeService to execute a Nuke for an AWS Account
 type NukeAccountInput struct {
@@ -23,8 +28,9 @@ type NukeAccountInput struct {
 
 // NukeAccount directly triggers aws-nuke to be called on the
 // configuration file provided, bypassing any manual prompts.
+// The nuke is stopped once ctx is done.
 // Returns an error if there's any, else nil.
-func NukeAccount(input *NukeAccountInput) error {
+func NukeAccount(ctx context.Context, input *NukeAccountInput) error {
 
 	// Create a NukeParameter based on the configuration file
 	// path and force to bypass prompts.
@@ -76,8 +82,10 @@ func NukeAccount(input *NukeAccountInput) error {
 	if err != nil {
 		return errors.Wrapf(err, "Failed to load nuke config at %s", nuke.Parameters.ConfigPath)
 	}
+	ctx, cancel := context.WithTimeout(ctx, nukeTimeout)
+	defer cancel()
 	c := make(chan error, 1)
-	go func() { c <- input.Nuke.Run(nuke) }()
+	go func() { c <- input.Nuke.Run(ctx, nuke) }()
 	select {
 	case err := <-c:
 		if err != nil {
@@ -85,7 +93,10 @@ func NukeAccount(input *NukeAccountInput) error {
 				input.AccountID, roleArn)
 		}
 		return nil
-	case <-time.After(time.Minute * 60):
-		return errors.New("Nuke Timed Out after 60 minutes")
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return errors.New("Nuke Timed Out after 60 minutes")
+		}
+		return errors.Wrapf(ctx.Err(), "Nuke of account %s was stopped", input.AccountID)
 	}
 }
@@ -1,6 +1,7 @@
 package reset
 
 import (
+	"context"
 	"errors"
 	"github.com/Optum/dce/pkg/common"
 	"github.com/stretchr/testify/require"
@@ -76,11 +77,16 @@ func (nuke mockNukeService) Load(configPath string) (*config.Nuke, error) {
 }
 
 // Run mocks an execution of a Nuke process
-func (nuke mockNukeService) Run(cmd *cmd.Nuke) error {
+func (nuke mockNukeService) Run(ctx context.Context, cmd *cmd.Nuke) error {
 	// Failure case
 	if cmd.Account.Credentials.SessionToken == "DCENukeTestRunError" {
 		return errors.New("Error: Failed to Run")
 	}
+	// Nukes which run until they are stopped
+	if cmd.Account.Credentials.SessionToken == "DCENukeTestRunStopped" {
+		<-ctx.Done()
+		return ctx.Err()
+	}
 
 	return nil
 }
@@ -160,7 +166,7 @@ func TestNukeAccount(t *testing.T) {
 	// Iterate through each test in the list
 	for _, test := range tests {
 		// Call the NukeAccount function and get the respective error
-		err := NukeAccount(test.Input)
+		err := NukeAccount(context.Background(), test.Input)
 
 		// Assert that error is expected correctly
 		if test.ExpectedError == "" {
@@ -171,3 +177,20 @@ func TestNukeAccount(t *testing.T) {
 		}
 	}
 }
+
+// TestNukeAccountStopped verifies that the Nuke stops once its context is done
+func TestNukeAccountStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NukeAccount(ctx, &NukeAccountInput{
+		AccountID:  "TestRunStopped",
+		RoleName:   "TestRunStopped",
+		ConfigPath: "TestRunStopped",
+		NoDryRun:   true,
+		Token:      mockTokenService{},
+		Nuke:       mockNukeService{},
+	})
+	require.NotNil(t, err)
+	require.Regexp(t, "context canceled", err.Error())
+}
@@ -0,0 +1,61 @@
+package reset
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Optum/dce/pkg/db"
+)
+
+// keeps returns true if the nuke config overrides of the account
+// keep a resource, so the resource must not be deleted by a step.
+//
+// As with aws-nuke, filters without a property match the resource ID
+// (eg. "s3://my-bucket" for S3Bucket), and filters with a property
+// match the property of the resource. Filters which cannot be
+// evaluated by the step (eg. properties which the step does not know,
+// or dateOlderThan filters) keep the resource, so that resources are
+// never deleted against the account's overrides.
+func (input *StepInput) keeps(resourceType string, id string, properties map[string]string) bool {
+	config := input.NukeConfig
+	if config == nil {
+		return false
+	}
+	for _, excluded := range config.ExcludeResourceTypes {
+		if excluded == resourceType {
+			return true
+		}
+	}
+	for _, filter := range config.Filters[resourceType] {
+		value := id
+		if filter.Property != "" {
+			var ok bool
+			value, ok = properties[filter.Property]
+			if !ok {
+				return true
+			}
+		}
+		if matchesNukeFilter(filter, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNukeFilter checks a value against an aws-nuke filter.
+// dateOlderThan filters always match, as steps do not evaluate them.
+func matchesNukeFilter(filter db.NukeFilter, value string) bool {
+	switch filter.Type {
+	case "", "exact":
+		return value == filter.Value
+	case "contains":
+		return strings.Contains(value, filter.Value)
+	case "glob":
+		return patternRegex(filter.Value).MatchString(value)
+	case "regex":
+		re, err := regexp.Compile(filter.Value)
+		return err != nil || re.MatchString(value)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,51 @@
+package reset
+
+import (
+	"testing"
+
+	"github.com/Optum/dce/pkg/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepInputKeeps(t *testing.T) {
+	input := &StepInput{NukeConfig: &db.NukeConfig{
+		Filters: map[string][]db.NukeFilter{
+			"S3Bucket": {
+				{Value: "s3://exact"},
+				{Type: "contains", Value: "keep"},
+				{Type: "glob", Value: "s3://logs-*"},
+				{Type: "regex", Value: "^s3://backup-[0-9]+$"},
+			},
+			"EC2Volume": {
+				{Type: "exact", Property: "tag:team", Value: "platform"},
+			},
+			"EC2Image": {
+				{Type: "dateOlderThan", Property: "CreationDate", Value: "24h"},
+			},
+		},
+		ExcludeResourceTypes: []string{"EC2Snapshot"},
+	}}
+
+	for id, expected := range map[string]bool{
+		"s3://exact":      true,
+		"s3://exact-not":  false,
+		"s3://to-keep":    true,
+		"s3://logs-2020":  true,
+		"s3://backup-123": true,
+		"s3://backup-abc": false,
+		"s3://other":      false,
+	} {
+		require.Equal(t, expected, input.keeps("S3Bucket", id, map[string]string{}), id)
+	}
+	require.True(t, input.keeps("EC2Volume", "vol-1", map[string]string{"tag:team": "platform"}))
+	require.False(t, input.keeps("EC2Volume", "vol-1", map[string]string{"tag:team": "other"}))
+
+	// Excluded types are always kept
+	require.True(t, input.keeps("EC2Snapshot", "snap-1", map[string]string{}))
+	// Filters which are not evaluated keep the resource
+	require.True(t, input.keeps("EC2Image", "ami-1", map[string]string{"CreationDate": "2020-01-01T00:00:00Z"}))
+	require.True(t, input.keeps("EC2Volume", "vol-1", map[string]string{}))
+	// Other types are not kept
+	require.False(t, input.keeps("EC2Instance", "i-1", map[string]string{}))
+	require.False(t, (&StepInput{}).keeps("S3Bucket", "s3://exact", map[string]string{}))
+}
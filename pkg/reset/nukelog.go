@@ -0,0 +1,126 @@
+package reset
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// NukeLog tracks the state of each resource from aws-nuke output.
+// It is an io.Writer, so aws-nuke output may be copied to it as nuke runs.
+//
+// aws-nuke logs a line for each resource as its state changes, eg.
+//
+//	us-east-1 - EC2Instance - i-0123 - [Name: "test"] - would remove
+//	us-east-1 - EC2Instance - i-0123 - [Name: "test"] - removed
+type NukeLog struct {
+	mu      sync.Mutex
+	partial []byte
+	// Last state of each resource, by resource
+	states map[string]string
+	// Resources in the order they were first logged
+	order []string
+	// Resources which aws-nuke would remove
+	found map[string]bool
+}
+
+// NukeSummary is the final state of resources logged by aws-nuke
+type NukeSummary struct {
+	// Resources which aws-nuke would remove
+	Found []string
+	// Resources which were removed
+	Deleted []string
+	// Resources which could not be removed
+	Failed []string
+	// Resources which were not removed, because of filters
+	Filtered []string
+}
+
+// aws-nuke resource states
+const (
+	nukeWouldRemove     = "would remove"
+	nukeRemoved         = "removed"
+	nukeFailed          = "failed"
+	nukeTriggeredRemove = "triggered remove"
+	nukeWaiting         = "waiting"
+)
+
+// ansiRegex matches terminal color codes
+var ansiRegex = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// NewNukeLog creates an empty NukeLog
+func NewNukeLog() *NukeLog {
+	return &NukeLog{
+		states: map[string]string{},
+		found:  map[string]bool{},
+	}
+}
+
+// Write parses complete lines of aws-nuke output
+func (l *NukeLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		l.parseLine(string(l.partial[:i]))
+		l.partial = l.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// parseLine records the state of the resource in a line of output.
+// Lines which do not describe a resource are ignored.
+func (l *NukeLog) parseLine(line string) {
+	line = strings.TrimSpace(ansiRegex.ReplaceAllString(line, ""))
+	i := strings.LastIndex(line, " - ")
+	// Resource lines have at least a region, type, and state
+	if i < 0 || strings.Count(line, " - ") < 2 {
+		return
+	}
+	resource, state := line[:i], line[i+len(" - "):]
+
+	if _, ok := l.states[resource]; !ok {
+		l.order = append(l.order, resource)
+	}
+	l.states[resource] = state
+	if state == nukeWouldRemove {
+		l.found[resource] = true
+	}
+}
+
+// Summary returns the final state of each resource
+func (l *NukeLog) Summary() NukeSummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	summary := NukeSummary{
+		Found:    []string{},
+		Deleted:  []string{},
+		Failed:   []string{},
+		Filtered: []string{},
+	}
+	for _, resource := range l.order {
+		if l.found[resource] {
+			summary.Found = append(summary.Found, resource)
+		}
+		switch l.states[resource] {
+		case nukeRemoved:
+			summary.Deleted = append(summary.Deleted, resource)
+		case nukeFailed:
+			summary.Failed = append(summary.Failed, resource)
+		case nukeWouldRemove, nukeTriggeredRemove, nukeWaiting:
+		default:
+			// Filtered resources are logged with the reason they were filtered
+			if !l.found[resource] {
+				summary.Filtered = append(summary.Filtered, resource)
+			}
+		}
+	}
+	return summary
+}
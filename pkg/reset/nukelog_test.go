@@ -0,0 +1,56 @@
+package reset
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNukeLog(t *testing.T) {
+
+	t.Run("should track the final state of each resource", func(t *testing.T) {
+		nukeLog := NewNukeLog()
+		// Output is written in chunks, which may split lines
+		output := "" +
+			"aws-nuke version v2.11.0\n" +
+			"us-east-1 - EC2Instance - i-1 - [Name: \"a\"] - would remove\n" +
+			"\x1b[1mus-east-1\x1b[0m - EC2Instance - i-2 - [Name: \"b\"] - \x1b[34mwould remove\x1b[0m\n" +
+			"us-east-1 - EC2VPC - vpc-1 - [IsDefault: \"true\"] - filtered by config\n" +
+			"Scan complete: 3 total, 2 nukeable, 1 filtered.\n" +
+			"us-east-1 - EC2Instance - i-1 - [Name: \"a\"] - triggered remove\n" +
+			"us-east-1 - EC2Instance - i-2 - [Name: \"b\"] - failed\n" +
+			"Removal requested: 1 waiting, 1 failed, 1 skipped, 0 finished\n" +
+			"us-east-1 - EC2Instance - i-1 - [Name: \"a\"] - removed\n"
+		for i := 0; i < len(output); i += 10 {
+			end := i + 10
+			if end > len(output) {
+				end = len(output)
+			}
+			n, err := fmt.Fprint(nukeLog, output[i:end])
+			require.Nil(t, err)
+			require.Equal(t, end-i, n)
+		}
+
+		require.Equal(t, NukeSummary{
+			Found: []string{
+				"us-east-1 - EC2Instance - i-1 - [Name: \"a\"]",
+				"us-east-1 - EC2Instance - i-2 - [Name: \"b\"]",
+			},
+			Deleted:  []string{"us-east-1 - EC2Instance - i-1 - [Name: \"a\"]"},
+			Failed:   []string{"us-east-1 - EC2Instance - i-2 - [Name: \"b\"]"},
+			Filtered: []string{"us-east-1 - EC2VPC - vpc-1 - [IsDefault: \"true\"]"},
+		}, nukeLog.Summary())
+	})
+
+	t.Run("should only find resources in dry run mode", func(t *testing.T) {
+		nukeLog := NewNukeLog()
+		fmt.Fprint(nukeLog, "global - IAMRole - DCEPrincipal - filtered by config\n"+
+			"us-east-1 - S3Bucket - s3://test - would remove\n")
+
+		summary := nukeLog.Summary()
+		require.Equal(t, []string{"us-east-1 - S3Bucket - s3://test"}, summary.Found)
+		require.Empty(t, summary.Deleted)
+		require.Equal(t, []string{"global - IAMRole - DCEPrincipal"}, summary.Filtered)
+	})
+}
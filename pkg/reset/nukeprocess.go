@@ -0,0 +1,106 @@
+package reset
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+	"github.com/rebuy-de/aws-nuke/cmd"
+	"github.com/rebuy-de/aws-nuke/pkg/awsutil"
+)
+
+// nukeProcessEnv is set for child processes started to run aws-nuke
+const nukeProcessEnv = "DCE_NUKE_PROCESS"
+
+// nukeProcessInput is passed to the aws-nuke child process on stdin,
+// so that the account credentials are not visible in its args or env
+type nukeProcessInput struct {
+	Parameters      cmd.NukeParameters
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// startNukeProcess runs the nuke in a child process of the current
+// executable, and copies its stdout to output.
+// The child process is killed once ctx is done,
+// or if it runs for longer than the nuke timeout.
+func startNukeProcess(ctx context.Context, nuke *cmd.Nuke, output io.Writer) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "Failed to find the executable to run aws-nuke")
+	}
+	input, err := json.Marshal(nukeProcessInput{
+		Parameters:      nuke.Parameters,
+		AccessKeyID:     nuke.Account.Credentials.AccessKeyID,
+		SecretAccessKey: nuke.Account.Credentials.SecretAccessKey,
+		SessionToken:    nuke.Account.Credentials.SessionToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, nukeTimeout)
+	defer cancel()
+	process := exec.CommandContext(ctx, executable)
+	process.Env = append(os.Environ(), nukeProcessEnv+"=true")
+	process.Stdin = bytes.NewReader(input)
+	process.Stdout = io.MultiWriter(os.Stdout, output)
+	process.Stderr = os.Stderr
+	err = process.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.Wrap(ctx.Err(), "aws-nuke timed out")
+	}
+	if ctx.Err() != nil {
+		return errors.Wrap(ctx.Err(), "aws-nuke was stopped")
+	}
+	return err
+}
+
+// RunNukeProcess runs aws-nuke and exits, if this process was started
+// by Nuke.Run to capture the aws-nuke output.
+// Commands which reset accounts with a Nuke call
+// RunNukeProcess at the start of main.
+func RunNukeProcess() {
+	if os.Getenv(nukeProcessEnv) == "" {
+		return
+	}
+
+	err := runNukeProcess(os.Stdin)
+	if err != nil {
+		log.Printf("Failed to run aws-nuke: %s", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// runNukeProcess runs aws-nuke in-process,
+// with the nukeProcessInput read from r
+func runNukeProcess(r io.Reader) error {
+	input := nukeProcessInput{}
+	err := json.NewDecoder(r).Decode(&input)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read aws-nuke input")
+	}
+
+	nukeSvc := Nuke{}
+	account, err := nukeSvc.NewAccount(awsutil.Credentials{
+		AccessKeyID:     input.AccessKeyID,
+		SecretAccessKey: input.SecretAccessKey,
+		SessionToken:    input.SessionToken,
+	})
+	if err != nil {
+		return err
+	}
+	nuke := cmd.NewNuke(input.Parameters, *account)
+	nuke.Config, err = nukeSvc.Load(input.Parameters.ConfigPath)
+	if err != nil {
+		return err
+	}
+	return nukeSvc.Run(context.Background(), nuke)
+}
@@ -1,6 +1,9 @@
 package reset
 
 import (
+	"context"
+	"io"
+
 	"github.com/rebuy-de/aws-nuke/cmd"
 	"github.com/rebuy-de/aws-nuke/pkg/awsutil"
 	"github.com/rebuy-de/aws-nuke/pkg/config"
@@ -11,12 +14,17 @@ import (
 type Nuker interface {
 	NewAccount(awsutil.Credentials) (*awsutil.Account, error)
 	Load(string) (*config.Nuke, error)
-	Run(*cmd.Nuke) error
+	Run(context.Context, *cmd.Nuke) error
 }
 
 // Nuke implements the NukeService interface using rebuy-de/aws-nuke
 // https://github.com/rebuy-de/aws-nuke
 type Nuke struct {
+	// Output receives a copy of the aws-nuke output, if set.
+	// aws-nuke writes to the process's stdout, so to capture the output
+	// of each run, aws-nuke is run in a child process.
+	// See RunNukeProcess.
+	Output io.Writer
 }
 
 // NewAccount returns an aws-nuke Account that is created from the provided
@@ -35,6 +43,11 @@ func (nuke Nuke) Load(configPath string) (*config.Nuke, error) {
 }
 
 // Run executes and returns the result of the aws-nuke nuke.
-func (nuke Nuke) Run(cmd *cmd.Nuke) error {
+// The aws-nuke child process is killed once ctx is done.
+// Without an Output, aws-nuke runs in-process, and ignores ctx.
+func (nuke Nuke) Run(ctx context.Context, cmd *cmd.Nuke) error {
+	if nuke.Output != nil {
+		return startNukeProcess(ctx, cmd, nuke.Output)
+	}
 	return cmd.Run()
 }
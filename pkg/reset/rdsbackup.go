@@ -1,8 +1,10 @@
 package reset
 
 import (
+	"context"
 	"log"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/rds"
 	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
 )
@@ -18,52 +20,87 @@ type RdsService interface {
 // RdsReset struct implements above interface
 type RdsReset struct {
 	Client rdsiface.RDSAPI
+	// Context cancels the RDS requests, if set
+	Context aws.Context
+}
+
+// context returns the context for RDS requests
+func (r RdsReset) context() aws.Context {
+	if r.Context == nil {
+		return aws.BackgroundContext()
+	}
+	return r.Context
 }
 
 // DescribeDBInstances implementation
 func (r RdsReset) DescribeDBInstances(input *rds.DescribeDBInstancesInput) (*rds.DescribeDBInstancesOutput, error) {
-	return r.Client.DescribeDBInstances(input)
+	return r.Client.DescribeDBInstancesWithContext(r.context(), input)
 }
 
 // ModifyDBInstance implementation
 func (r RdsReset) ModifyDBInstance(input *rds.ModifyDBInstanceInput) (*rds.ModifyDBInstanceOutput, error) {
-	return r.Client.ModifyDBInstance(input)
+	return r.Client.ModifyDBInstanceWithContext(r.context(), input)
 }
 
 // DescribeDBInstanceAutomatedBackups implementation
 func (r RdsReset) DescribeDBInstanceAutomatedBackups(input *rds.DescribeDBInstanceAutomatedBackupsInput) (*rds.DescribeDBInstanceAutomatedBackupsOutput, error) {
-	return r.Client.DescribeDBInstanceAutomatedBackups(input)
+	return r.Client.DescribeDBInstanceAutomatedBackupsWithContext(r.context(), input)
 }
 
 // DeleteDBInstanceAutomatedBackup implementation
 func (r RdsReset) DeleteDBInstanceAutomatedBackup(input *rds.DeleteDBInstanceAutomatedBackupInput) (*rds.DeleteDBInstanceAutomatedBackupOutput, error) {
-	return r.Client.DeleteDBInstanceAutomatedBackup(input)
+	return r.Client.DeleteDBInstanceAutomatedBackupWithContext(r.context(), input)
 }
 
 // DeleteRdsBackups deletes RDS backups
 func DeleteRdsBackups(rdsService RdsService) error {
+	_, err := deleteRdsBackups(rdsService, false)
+	return err
+}
+
+// RdsBackupsStep is a reset Step which deletes RDS automated backups
+type RdsBackupsStep struct {
+	Client rdsiface.RDSAPI
+}
+
+// Name of the step
+func (step RdsBackupsStep) Name() string {
+	return "rds-backups"
+}
+
+// Run deletes RDS automated backups
+func (step RdsBackupsStep) Run(ctx context.Context, input *StepInput) ([]string, error) {
+	return deleteRdsBackups(RdsReset{Client: step.Client, Context: ctx}, input.DryRun)
+}
+
+// deleteRdsBackups deletes RDS automated backups, and returns the
+// IDs of the deleted backups. With dryRun, nothing is modified or deleted.
+func deleteRdsBackups(rdsService RdsService, dryRun bool) ([]string, error) {
 
 	var retentionPeriod int64
 	var applyNow = true
+	deleted := []string{}
 
 	describeDBInstancesInput := &rds.DescribeDBInstancesInput{}
 
 	dbInstances, err := rdsService.DescribeDBInstances(describeDBInstancesInput)
 	if err != nil {
-		return err
+		return deleted, err
 	}
 
 	for _, dbInstance := range dbInstances.DBInstances {
-		log.Printf("Modify dbInstance retention period to 0 for %s \n", *dbInstance.DBInstanceArn)
+		if !dryRun {
+			log.Printf("Modify dbInstance retention period to 0 for %s \n", *dbInstance.DBInstanceArn)
 
-		modifyDBInstanceInput := &rds.ModifyDBInstanceInput{
-			DBInstanceIdentifier:  dbInstance.DBInstanceIdentifier,
-			BackupRetentionPeriod: &retentionPeriod,
-			ApplyImmediately:      &applyNow,
-		}
-		_, err := rdsService.ModifyDBInstance(modifyDBInstanceInput)
-		if err != nil {
-			return err
+			modifyDBInstanceInput := &rds.ModifyDBInstanceInput{
+				DBInstanceIdentifier:  dbInstance.DBInstanceIdentifier,
+				BackupRetentionPeriod: &retentionPeriod,
+				ApplyImmediately:      &applyNow,
+			}
+			_, err := rdsService.ModifyDBInstance(modifyDBInstanceInput)
+			if err != nil {
+				return deleted, err
+			}
 		}
 
 		describeDBInstanceAutomatedBackupInput := &rds.DescribeDBInstanceAutomatedBackupsInput{
@@ -72,9 +109,14 @@ func DeleteRdsBackups(rdsService RdsService) error {
 
 		dbInstanceBackups, err := rdsService.DescribeDBInstanceAutomatedBackups(describeDBInstanceAutomatedBackupInput)
 		if err != nil {
-			return err
+			return deleted, err
 		}
 		for _, dbInstanceBackup := range dbInstanceBackups.DBInstanceAutomatedBackups {
+			deleted = append(deleted, *dbInstanceBackup.DbiResourceId)
+			if dryRun {
+				log.Printf("dbInstanceBackup would be deleted : %s \n", *dbInstanceBackup.DbiResourceId)
+				continue
+			}
 			log.Printf("dbInstanceBackup to be deleted : %s \n", *dbInstanceBackup.DbiResourceId)
 			deleteDBInstanceAutomatedBackupInput := &rds.DeleteDBInstanceAutomatedBackupInput{
 				DbiResourceId: dbInstanceBackup.DbiResourceId,
@@ -85,5 +127,5 @@ func DeleteRdsBackups(rdsService RdsService) error {
 		}
 
 	}
-	return nil
+	return deleted, nil
 }
@@ -0,0 +1,154 @@
+package reset
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// S3VersionedBucketsStep is a reset Step which empties versioned S3 buckets,
+// by deleting every object version and delete marker.
+// aws-nuke does not remove old object versions, so versioned buckets
+// would otherwise fail to be deleted.
+//
+// Buckets and objects kept by the account's nuke config overrides
+// are not emptied.
+type S3VersionedBucketsStep struct {
+	// NewClient creates an S3 client for a region
+	NewClient func(region string) s3iface.S3API
+}
+
+// Name of the step
+func (step S3VersionedBucketsStep) Name() string {
+	return "s3-versioned-buckets"
+}
+
+// Run empties all versioned buckets in the reset regions,
+// and returns the emptied buckets
+func (step S3VersionedBucketsStep) Run(ctx context.Context, input *StepInput) ([]string, error) {
+	emptied := []string{}
+
+	client := step.NewClient("us-east-1")
+	buckets, err := client.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return emptied, err
+	}
+
+	for _, bucket := range buckets.Buckets {
+		location, err := client.GetBucketLocationWithContext(ctx, &s3.GetBucketLocationInput{
+			Bucket: bucket.Name,
+		})
+		if err != nil {
+			return emptied, err
+		}
+		region := s3.NormalizeBucketLocation(aws.StringValue(location.LocationConstraint))
+		if !containsRegion(input.Regions, region) {
+			continue
+		}
+		if input.keeps("S3Bucket", "s3://"+*bucket.Name, map[string]string{"Name": *bucket.Name}) {
+			log.Printf("Keeping bucket %s, which is filtered by the nuke config", *bucket.Name)
+			continue
+		}
+
+		regionClient := step.NewClient(region)
+		versioning, err := regionClient.GetBucketVersioningWithContext(ctx, &s3.GetBucketVersioningInput{
+			Bucket: bucket.Name,
+		})
+		if err != nil {
+			return emptied, err
+		}
+		// Buckets which were never versioned have no status,
+		// and are emptied by aws-nuke
+		if versioning.Status == nil {
+			continue
+		}
+
+		count, err := deleteObjectVersions(ctx, regionClient, *bucket.Name, input)
+		if err != nil {
+			return emptied, err
+		}
+		log.Printf("Deleted %d object versions from bucket %s (dry run: %t)", count, *bucket.Name, input.DryRun)
+		emptied = append(emptied, "s3://"+*bucket.Name)
+	}
+
+	return emptied, nil
+}
+
+// deleteObjectVersions deletes all object versions and delete markers
+// from a bucket, other than objects kept by the nuke config overrides,
+// and returns the number deleted
+func deleteObjectVersions(ctx context.Context, client s3iface.S3API, bucket string, input *StepInput) (int, error) {
+	count := 0
+	var deleteErr error
+	addObject := func(objects []*s3.ObjectIdentifier, key *string, versionID *string) []*s3.ObjectIdentifier {
+		properties := map[string]string{
+			"Bucket":    bucket,
+			"Key":       aws.StringValue(key),
+			"VersionID": aws.StringValue(versionID),
+		}
+		if input.keeps("S3Object", fmt.Sprintf("s3://%s/%s", bucket, aws.StringValue(key)), properties) {
+			return objects
+		}
+		return append(objects, &s3.ObjectIdentifier{Key: key, VersionId: versionID})
+	}
+	err := client.ListObjectVersionsPagesWithContext(ctx, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+	}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		objects := []*s3.ObjectIdentifier{}
+		for _, version := range page.Versions {
+			objects = addObject(objects, version.Key, version.VersionId)
+		}
+		for _, marker := range page.DeleteMarkers {
+			objects = addObject(objects, marker.Key, marker.VersionId)
+		}
+		if len(objects) == 0 {
+			return true
+		}
+		if input.DryRun {
+			count += len(objects)
+			return true
+		}
+
+		// Pages have at most 1000 versions, the limit for DeleteObjects
+		out, err := client.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			deleteErr = err
+			return false
+		}
+		if len(out.Errors) > 0 {
+			deleteErr = fmt.Errorf("failed to delete %d object versions from bucket %s: %s",
+				len(out.Errors), bucket, aws.StringValue(out.Errors[0].Message))
+			return false
+		}
+		count += len(objects)
+		return true
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, deleteErr
+}
+
+// containsRegion checks if a region is one of the reset regions.
+// All regions are reset if none are configured.
+func containsRegion(regions []string, region string) bool {
+	if len(regions) == 0 {
+		return true
+	}
+	for _, r := range regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
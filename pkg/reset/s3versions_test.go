@@ -0,0 +1,124 @@
+package reset
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Optum/dce/pkg/db"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/require"
+)
+
+type mockS3Versions struct {
+	s3iface.S3API
+	region  string
+	deleted *[]string
+}
+
+func (m mockS3Versions) ListBucketsWithContext(ctx aws.Context, input *s3.ListBucketsInput, opts ...request.Option) (*s3.ListBucketsOutput, error) {
+	return &s3.ListBucketsOutput{
+		Buckets: []*s3.Bucket{
+			{Name: aws.String("versioned")},
+			{Name: aws.String("unversioned")},
+			{Name: aws.String("other-region")},
+		},
+	}, nil
+}
+
+func (m mockS3Versions) GetBucketLocationWithContext(ctx aws.Context, input *s3.GetBucketLocationInput, opts ...request.Option) (*s3.GetBucketLocationOutput, error) {
+	if *input.Bucket == "other-region" {
+		return &s3.GetBucketLocationOutput{LocationConstraint: aws.String("eu-west-1")}, nil
+	}
+	// us-east-1 buckets have no location constraint
+	return &s3.GetBucketLocationOutput{}, nil
+}
+
+func (m mockS3Versions) GetBucketVersioningWithContext(ctx aws.Context, input *s3.GetBucketVersioningInput, opts ...request.Option) (*s3.GetBucketVersioningOutput, error) {
+	if *input.Bucket == "versioned" {
+		return &s3.GetBucketVersioningOutput{Status: aws.String("Suspended")}, nil
+	}
+	return &s3.GetBucketVersioningOutput{}, nil
+}
+
+func (m mockS3Versions) ListObjectVersionsPagesWithContext(ctx aws.Context, input *s3.ListObjectVersionsInput, fn func(*s3.ListObjectVersionsOutput, bool) bool, opts ...request.Option) error {
+	fn(&s3.ListObjectVersionsOutput{
+		Versions:      []*s3.ObjectVersion{{Key: aws.String("a"), VersionId: aws.String("1")}},
+		DeleteMarkers: []*s3.DeleteMarkerEntry{{Key: aws.String("a"), VersionId: aws.String("2")}},
+	}, true)
+	return nil
+}
+
+func (m mockS3Versions) DeleteObjectsWithContext(ctx aws.Context, input *s3.DeleteObjectsInput, opts ...request.Option) (*s3.DeleteObjectsOutput, error) {
+	for _, obj := range input.Delete.Objects {
+		*m.deleted = append(*m.deleted, *input.Bucket+"/"+*obj.Key+"@"+*obj.VersionId)
+	}
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+func TestS3VersionedBucketsStep(t *testing.T) {
+	deleted := []string{}
+	step := S3VersionedBucketsStep{
+		NewClient: func(region string) s3iface.S3API {
+			return mockS3Versions{region: region, deleted: &deleted}
+		},
+	}
+
+	t.Run("should empty versioned buckets in the reset regions", func(t *testing.T) {
+		deleted = []string{}
+		emptied, err := step.Run(context.Background(), &StepInput{Regions: []string{"us-east-1"}})
+		require.Nil(t, err)
+		require.Equal(t, []string{"s3://versioned"}, emptied)
+		require.Equal(t, []string{"versioned/a@1", "versioned/a@2"}, deleted)
+	})
+
+	t.Run("should not delete in dry run mode", func(t *testing.T) {
+		deleted = []string{}
+		emptied, err := step.Run(context.Background(), &StepInput{Regions: []string{"us-east-1"}, DryRun: true})
+		require.Nil(t, err)
+		require.Equal(t, []string{"s3://versioned"}, emptied)
+		require.Empty(t, deleted)
+	})
+
+	t.Run("should keep buckets filtered by the nuke config", func(t *testing.T) {
+		deleted = []string{}
+		emptied, err := step.Run(context.Background(), &StepInput{
+			Regions: []string{"us-east-1"},
+			NukeConfig: &db.NukeConfig{
+				Filters: map[string][]db.NukeFilter{
+					"S3Bucket": {{Property: "Name", Value: "versioned"}},
+				},
+			},
+		})
+		require.Nil(t, err)
+		require.Empty(t, emptied)
+		require.Empty(t, deleted)
+	})
+
+	t.Run("should keep objects filtered by the nuke config", func(t *testing.T) {
+		deleted = []string{}
+		_, err := step.Run(context.Background(), &StepInput{
+			Regions: []string{"us-east-1"},
+			NukeConfig: &db.NukeConfig{
+				Filters: map[string][]db.NukeFilter{
+					"S3Object": {{Type: "glob", Value: "s3://versioned/*"}},
+				},
+			},
+		})
+		require.Nil(t, err)
+		require.Empty(t, deleted)
+	})
+
+	t.Run("should keep buckets of excluded resource types", func(t *testing.T) {
+		deleted = []string{}
+		emptied, err := step.Run(context.Background(), &StepInput{
+			Regions:    []string{"us-east-1"},
+			NukeConfig: &db.NukeConfig{ExcludeResourceTypes: []string{"S3Bucket"}},
+		})
+		require.Nil(t, err)
+		require.Empty(t, emptied)
+		require.Empty(t, deleted)
+	})
+}
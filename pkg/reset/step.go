@@ -0,0 +1,240 @@
+package reset
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Optum/dce/pkg/db"
+)
+
+// Step cleans up a type of resource in a child account,
+// as one stage of an account reset
+type Step interface {
+	// Name identifies the step in configuration and results,
+	// eg. "rds-backups"
+	Name() string
+	// Run deletes the step's resources from the account,
+	// and returns the IDs of the deleted resources.
+	// In dry run mode, Run returns the resources which would be deleted,
+	// without deleting them.
+	Run(ctx context.Context, input *StepInput) ([]string, error)
+}
+
+// StepInput is the account to be reset, passed to each Step
+type StepInput struct {
+	AccountID string
+	// Regions are the regions to reset.
+	// Global resources are reset regardless of region.
+	Regions []string
+	DryRun  bool
+	// NukeConfig are the nuke config overrides of the account and its pool.
+	// Steps do not delete resources which the overrides keep.
+	NukeConfig *db.NukeConfig
+}
+
+// StepConfig enables a step, with an optional timeout
+type StepConfig struct {
+	Name string
+	// Timeout is the maximum time to run the step.
+	// Zero means no timeout.
+	Timeout time.Duration
+}
+
+// StepStatus is the outcome of a reset step
+type StepStatus string
+
+const (
+	// StepSucceeded means the step completed without error
+	StepSucceeded StepStatus = "Succeeded"
+	// StepFailed means the step returned an error
+	StepFailed StepStatus = "Failed"
+	// StepTimedOut means the step did not complete within its timeout
+	StepTimedOut StepStatus = "TimedOut"
+	// StepSkipped means the step did not run, because an earlier step failed
+	StepSkipped StepStatus = "Skipped"
+)
+
+// StepResult is the result of running a reset step
+type StepResult struct {
+	Name      string        `json:"name"`
+	Status    StepStatus    `json:"status"`
+	DryRun    bool          `json:"dryRun"`
+	Resources []string      `json:"resources"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Registry holds the reset steps which may be enabled by configuration
+type Registry struct {
+	steps map[string]Step
+}
+
+// NewRegistry creates a Registry with the given steps
+func NewRegistry(steps ...Step) (*Registry, error) {
+	registry := &Registry{steps: map[string]Step{}}
+	for _, step := range steps {
+		err := registry.Register(step)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Register adds a step to the registry
+func (r *Registry) Register(step Step) error {
+	if _, ok := r.steps[step.Name()]; ok {
+		return fmt.Errorf("reset step %q is already registered", step.Name())
+	}
+	r.steps[step.Name()] = step
+	return nil
+}
+
+// Names returns the names of all registered steps, in alphabetical order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.steps))
+	for name := range r.steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PlannedStep is an enabled step, with its configuration
+type PlannedStep struct {
+	Step    Step
+	Timeout time.Duration
+}
+
+// Plan returns the configured steps, in the configured order
+func (r *Registry) Plan(configs []StepConfig) ([]PlannedStep, error) {
+	plan := make([]PlannedStep, 0, len(configs))
+	for _, config := range configs {
+		step, ok := r.steps[config.Name]
+		if !ok {
+			return nil, fmt.Errorf("unknown reset step %q: must be one of %s",
+				config.Name, strings.Join(r.Names(), ", "))
+		}
+		plan = append(plan, PlannedStep{Step: step, Timeout: config.Timeout})
+	}
+	return plan, nil
+}
+
+// ParseStepConfig parses a comma-separated list of step names,
+// each with an optional timeout, eg. "rds-backups:10m,athena,aws-nuke:60m"
+func ParseStepConfig(config string) ([]StepConfig, error) {
+	configs := []StepConfig{}
+	for _, item := range strings.Split(config, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.SplitN(item, ":", 2)
+		stepConfig := StepConfig{Name: parts[0]}
+		if len(parts) == 2 {
+			timeout, err := time.ParseDuration(parts[1])
+			if err != nil || timeout < 0 {
+				return nil, fmt.Errorf("invalid timeout for reset step %q: %q", parts[0], parts[1])
+			}
+			stepConfig.Timeout = timeout
+		}
+		configs = append(configs, stepConfig)
+	}
+	return configs, nil
+}
+
+// RunSteps runs each step in order, and returns a result for every step.
+// Once a step fails or times out, the remaining steps are skipped,
+// and the error of the failed step is returned.
+func RunSteps(ctx context.Context, plan []PlannedStep, input *StepInput) ([]*StepResult, error) {
+	results := make([]*StepResult, 0, len(plan))
+	var stepErr error
+	for _, planned := range plan {
+		result := &StepResult{
+			Name:      planned.Step.Name(),
+			DryRun:    input.DryRun,
+			Resources: []string{},
+		}
+		results = append(results, result)
+
+		if stepErr != nil {
+			result.Status = StepSkipped
+			continue
+		}
+
+		log.Printf("Starting reset step %s for account %s (dry run: %t)",
+			result.Name, input.AccountID, input.DryRun)
+		stepCtx, cancel := ctx, context.CancelFunc(func() {})
+		if planned.Timeout > 0 {
+			stepCtx, cancel = context.WithTimeout(ctx, planned.Timeout)
+		}
+		start := time.Now()
+		resources, err := runStep(stepCtx, planned, input)
+		result.Duration = time.Since(start)
+		cancel()
+		if resources != nil {
+			result.Resources = resources
+		}
+
+		// Steps may wrap or replace the context error (eg. the AWS SDK's
+		// RequestCanceled error), so check the step's context for timeouts
+		switch {
+		case err != nil && stepCtx.Err() == context.DeadlineExceeded:
+			result.Status = StepTimedOut
+			result.Error = fmt.Sprintf("timed out after %s", planned.Timeout)
+		case err != nil:
+			result.Status = StepFailed
+			result.Error = err.Error()
+		default:
+			result.Status = StepSucceeded
+		}
+		log.Printf("Reset step %s for account %s: %s in %s, %d resources",
+			result.Name, input.AccountID, result.Status, result.Duration, len(result.Resources))
+
+		if result.Status != StepSucceeded {
+			stepErr = fmt.Errorf("reset step %s failed for account %s: %s",
+				result.Name, input.AccountID, result.Error)
+		}
+	}
+	return results, stepErr
+}
+
+// stepStopTimeout is how long a step may take to return,
+// once its context is done
+const stepStopTimeout = 30 * time.Second
+
+// runStep runs a single step, until the context is done
+func runStep(ctx context.Context, planned PlannedStep, input *StepInput) ([]string, error) {
+	type stepOutput struct {
+		resources []string
+		err       error
+	}
+	c := make(chan stepOutput, 1)
+	go func() {
+		resources, err := planned.Step.Run(ctx, input)
+		c <- stepOutput{resources, err}
+	}()
+
+	select {
+	case out := <-c:
+		return out.resources, out.err
+	case <-ctx.Done():
+	}
+
+	// Wait for the step to stop, so that it does not run
+	// alongside later steps. Steps which do not check
+	// the context are abandoned.
+	select {
+	case out := <-c:
+		return out.resources, out.err
+	case <-time.After(stepStopTimeout):
+		log.Printf("Reset step %s for account %s did not stop, and was abandoned",
+			planned.Step.Name(), input.AccountID)
+		return nil, ctx.Err()
+	}
+}
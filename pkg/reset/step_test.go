@@ -0,0 +1,137 @@
+package reset
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testStep is a Step which records its runs
+type testStep struct {
+	name      string
+	resources []string
+	err       error
+	delay     time.Duration
+	// ctxErr is returned instead of the context error, once the context is done
+	ctxErr error
+	runs   *[]string
+}
+
+func (step testStep) Name() string {
+	return step.name
+}
+
+func (step testStep) Run(ctx context.Context, input *StepInput) ([]string, error) {
+	*step.runs = append(*step.runs, step.name)
+	select {
+	case <-time.After(step.delay):
+	case <-ctx.Done():
+		if step.ctxErr != nil {
+			return nil, step.ctxErr
+		}
+		return nil, ctx.Err()
+	}
+	return step.resources, step.err
+}
+
+func TestParseStepConfig(t *testing.T) {
+	t.Run("should parse names and timeouts", func(t *testing.T) {
+		configs, err := ParseStepConfig("rds-backups:10m, athena,,aws-nuke:1h")
+		require.Nil(t, err)
+		require.Equal(t, []StepConfig{
+			{Name: "rds-backups", Timeout: 10 * time.Minute},
+			{Name: "athena"},
+			{Name: "aws-nuke", Timeout: time.Hour},
+		}, configs)
+	})
+
+	t.Run("should fail for invalid timeouts", func(t *testing.T) {
+		_, err := ParseStepConfig("athena:soon")
+		require.EqualError(t, err, "invalid timeout for reset step \"athena\": \"soon\"")
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	runs := []string{}
+	registry, err := NewRegistry(
+		testStep{name: "b", runs: &runs},
+		testStep{name: "a", runs: &runs},
+	)
+	require.Nil(t, err)
+
+	t.Run("should reject duplicate steps", func(t *testing.T) {
+		err := registry.Register(testStep{name: "a", runs: &runs})
+		require.EqualError(t, err, "reset step \"a\" is already registered")
+	})
+
+	t.Run("should plan steps in the configured order", func(t *testing.T) {
+		plan, err := registry.Plan([]StepConfig{{Name: "b", Timeout: time.Second}, {Name: "a"}})
+		require.Nil(t, err)
+		require.Len(t, plan, 2)
+		require.Equal(t, "b", plan[0].Step.Name())
+		require.Equal(t, time.Second, plan[0].Timeout)
+		require.Equal(t, "a", plan[1].Step.Name())
+	})
+
+	t.Run("should fail for unknown steps", func(t *testing.T) {
+		_, err := registry.Plan([]StepConfig{{Name: "c"}})
+		require.EqualError(t, err, "unknown reset step \"c\": must be one of a, b")
+	})
+}
+
+func TestRunSteps(t *testing.T) {
+	input := &StepInput{AccountID: "123456789012", DryRun: true}
+
+	t.Run("should run steps in order", func(t *testing.T) {
+		runs := []string{}
+		results, err := RunSteps(context.Background(), []PlannedStep{
+			{Step: testStep{name: "first", resources: []string{"r1"}, runs: &runs}},
+			{Step: testStep{name: "second", runs: &runs}},
+		}, input)
+		require.Nil(t, err)
+		require.Equal(t, []string{"first", "second"}, runs)
+
+		require.Len(t, results, 2)
+		require.Equal(t, "first", results[0].Name)
+		require.Equal(t, StepSucceeded, results[0].Status)
+		require.Equal(t, []string{"r1"}, results[0].Resources)
+		require.True(t, results[0].DryRun)
+		require.Equal(t, StepSucceeded, results[1].Status)
+		require.Equal(t, []string{}, results[1].Resources)
+	})
+
+	t.Run("should skip steps after a failure", func(t *testing.T) {
+		runs := []string{}
+		results, err := RunSteps(context.Background(), []PlannedStep{
+			{Step: testStep{name: "first", err: errors.New("boom"), runs: &runs}},
+			{Step: testStep{name: "second", runs: &runs}},
+		}, input)
+		require.EqualError(t, err, "reset step first failed for account 123456789012: boom")
+		require.Equal(t, []string{"first"}, runs)
+
+		require.Equal(t, StepFailed, results[0].Status)
+		require.Equal(t, "boom", results[0].Error)
+		require.Equal(t, StepSkipped, results[1].Status)
+	})
+
+	t.Run("should time out steps", func(t *testing.T) {
+		runs := []string{}
+		results, err := RunSteps(context.Background(), []PlannedStep{
+			{Step: testStep{name: "slow", delay: time.Minute, runs: &runs}, Timeout: 10 * time.Millisecond},
+		}, input)
+		require.EqualError(t, err, "reset step slow failed for account 123456789012: timed out after 10ms")
+		require.Equal(t, StepTimedOut, results[0].Status)
+	})
+
+	t.Run("should time out steps which return their own error", func(t *testing.T) {
+		runs := []string{}
+		results, err := RunSteps(context.Background(), []PlannedStep{
+			{Step: testStep{name: "slow", delay: time.Minute, ctxErr: errors.New("request canceled"), runs: &runs}, Timeout: 10 * time.Millisecond},
+		}, input)
+		require.EqualError(t, err, "reset step slow failed for account 123456789012: timed out after 10ms")
+		require.Equal(t, StepTimedOut, results[0].Status)
+	})
+}
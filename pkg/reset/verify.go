@@ -0,0 +1,318 @@
+package reset
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// globalRegion is the region used in resource IDs
+// for resources which are not in a region, eg. IAM roles
+const globalRegion = "global"
+
+// taggedResourceType is the type of resources listed by TaggedResources
+const taggedResourceType = "TaggedResource"
+
+// ResourceLister lists the resources in an account,
+// for verifying that the account was reset.
+//
+// Resources are identified in the same format as aws-nuke output:
+//
+//	us-east-1 - EC2Instance - i-0123
+//	global - IAMRole - DCEPrincipal
+type ResourceLister interface {
+	ListResources(ctx context.Context, regions []string) ([]string, error)
+}
+
+// Verifier checks that no resources remain in an account after a reset,
+// other than those matching the Allowlist
+type Verifier struct {
+	Listers []ResourceLister
+	// Allowlist is a list of patterns for resources which are expected to
+	// remain after a reset, eg. "global - IAMRole - DCEPrincipal".
+	// A `*` in a pattern matches any characters.
+	Allowlist []string
+}
+
+// Verify lists all resources in the account, and returns the
+// resources which do not match the allowlist
+func (v *Verifier) Verify(ctx context.Context, regions []string) ([]string, error) {
+	allowlist := make([]*regexp.Regexp, 0, len(v.Allowlist))
+	for _, pattern := range v.Allowlist {
+		allowlist = append(allowlist, patternRegex(pattern))
+	}
+
+	leftovers := []string{}
+	seen := map[string]bool{}
+	for _, lister := range v.Listers {
+		resources, err := lister.ListResources(ctx, regions)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			if seen[resource] || matchesAny(allowlist, resource) {
+				continue
+			}
+			seen[resource] = true
+			leftovers = append(leftovers, resource)
+		}
+	}
+	sort.Strings(leftovers)
+	return leftovers, nil
+}
+
+// NukeAllowlist returns allowlist patterns for the resources which
+// aws-nuke was configured to keep: the filtered resources from the
+// aws-nuke output, and all resources of the excluded resource types.
+//
+// Tagged resources are listed by ARN, so they are matched by the
+// resource ID at the end of the ARN, or for excluded resource types,
+// by the ARN format of the resource type, where it is known.
+func NukeAllowlist(filtered []string, excludedTypes []string) []string {
+	allowlist := []string{}
+	for _, resource := range filtered {
+		region, resourceType, id, ok := parseNukeResource(resource)
+		if !ok {
+			allowlist = append(allowlist, resource)
+			continue
+		}
+		allowlist = append(allowlist, resourceID(region, resourceType, id))
+
+		// Global resources are listed as tagged resources in each region
+		taggedRegion := region
+		if region == globalRegion {
+			taggedRegion = "*"
+		}
+		if strings.HasPrefix(id, "arn:") {
+			allowlist = append(allowlist, resourceID(taggedRegion, taggedResourceType, id))
+		} else {
+			allowlist = append(allowlist,
+				resourceID(taggedRegion, taggedResourceType, "arn:*/"+id),
+				resourceID(taggedRegion, taggedResourceType, "arn:*:"+id),
+			)
+		}
+	}
+
+	for _, resourceType := range excludedTypes {
+		allowlist = append(allowlist, resourceID("*", resourceType, "*"))
+		if arnPattern, ok := nukeTypeARNs[resourceType]; ok {
+			allowlist = append(allowlist, resourceID("*", taggedResourceType, arnPattern))
+		}
+	}
+	return allowlist
+}
+
+// parseNukeResource splits a resource from the aws-nuke output
+// into its region, type and ID, in the format used by the
+// ResourceListers. Properties of the resource are removed, eg.
+//
+//	us-east-1 - EC2Instance - i-0123 - [Name: "test"]
+//
+// is returned as "us-east-1", "EC2Instance", "i-0123".
+// Returns false if the resource has no ID.
+func parseNukeResource(resource string) (string, string, string, bool) {
+	parts := strings.SplitN(resource, " - ", 3)
+	if len(parts) < 3 || strings.HasPrefix(parts[2], "[") {
+		return "", "", "", false
+	}
+	region, resourceType, id := parts[0], parts[1], parts[2]
+	if i := strings.Index(id, " - ["); i >= 0 && strings.HasSuffix(id, "]") {
+		id = id[:i]
+	}
+
+	// aws-nuke lists buckets in their region, as s3://<name>
+	if resourceType == "S3Bucket" {
+		region = globalRegion
+		id = strings.TrimPrefix(id, "s3://")
+	}
+	return region, resourceType, id, true
+}
+
+// nukeTypeARNs are patterns for the ARNs of aws-nuke resource types,
+// for matching the tagged resources of excluded resource types
+var nukeTypeARNs = map[string]string{
+	"CloudFormationStack": "arn:*:cloudformation:*:stack/*",
+	"DynamoDBTable":       "arn:*:dynamodb:*:table/*",
+	"EC2Instance":         "arn:*:ec2:*:instance/*",
+	"EC2SecurityGroup":    "arn:*:ec2:*:security-group/*",
+	"EC2Subnet":           "arn:*:ec2:*:subnet/*",
+	"EC2Volume":           "arn:*:ec2:*:volume/*",
+	"EC2VPC":              "arn:*:ec2:*:vpc/*",
+	"IAMPolicy":           "arn:*:iam::*:policy/*",
+	"IAMRole":             "arn:*:iam::*:role/*",
+	"IAMUser":             "arn:*:iam::*:user/*",
+	"KMSKey":              "arn:*:kms:*:key/*",
+	"LambdaFunction":      "arn:*:lambda:*:function:*",
+	"RDSInstance":         "arn:*:rds:*:db:*",
+	"Route53HostedZone":   "arn:*:route53:::hostedzone/*",
+	"S3Bucket":            "arn:*:s3:::*",
+	"SNSTopic":            "arn:*:sns:*",
+	"SQSQueue":            "arn:*:sqs:*",
+}
+
+// patternRegex converts an allowlist pattern to a regex,
+// where `*` matches any characters
+func patternRegex(pattern string) *regexp.Regexp {
+	parts := strings.Split(strings.TrimSpace(pattern), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+func matchesAny(patterns []*regexp.Regexp, resource string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(resource) {
+			return true
+		}
+	}
+	return false
+}
+
+func resourceID(region string, resourceType string, id string) string {
+	return fmt.Sprintf("%s - %s - %s", region, resourceType, id)
+}
+
+// IAMResources lists IAM roles, users, and customer managed policies.
+// Service-linked roles are not listed, as they are managed by AWS.
+type IAMResources struct {
+	Client iamiface.IAMAPI
+}
+
+// ListResources lists IAM resources in the account
+func (l IAMResources) ListResources(ctx context.Context, regions []string) ([]string, error) {
+	resources := []string{}
+
+	err := l.Client.ListRolesPagesWithContext(ctx, &iam.ListRolesInput{},
+		func(out *iam.ListRolesOutput, lastPage bool) bool {
+			for _, role := range out.Roles {
+				if strings.HasPrefix(aws.StringValue(role.Path), "/aws-service-role/") ||
+					strings.HasPrefix(aws.StringValue(role.Path), "/aws-reserved/") {
+					continue
+				}
+				resources = append(resources, resourceID(globalRegion, "IAMRole", aws.StringValue(role.RoleName)))
+			}
+			return true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IAM roles: %s", err)
+	}
+
+	err = l.Client.ListUsersPagesWithContext(ctx, &iam.ListUsersInput{},
+		func(out *iam.ListUsersOutput, lastPage bool) bool {
+			for _, user := range out.Users {
+				resources = append(resources, resourceID(globalRegion, "IAMUser", aws.StringValue(user.UserName)))
+			}
+			return true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IAM users: %s", err)
+	}
+
+	err = l.Client.ListPoliciesPagesWithContext(ctx, &iam.ListPoliciesInput{Scope: aws.String(iam.PolicyScopeTypeLocal)},
+		func(out *iam.ListPoliciesOutput, lastPage bool) bool {
+			for _, policy := range out.Policies {
+				resources = append(resources, resourceID(globalRegion, "IAMPolicy", aws.StringValue(policy.Arn)))
+			}
+			return true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IAM policies: %s", err)
+	}
+
+	return resources, nil
+}
+
+// S3Buckets lists S3 buckets.
+// Buckets are listed once, for all regions.
+type S3Buckets struct {
+	Client s3iface.S3API
+}
+
+// ListResources lists S3 buckets in the account
+func (l S3Buckets) ListResources(ctx context.Context, regions []string) ([]string, error) {
+	out, err := l.Client.ListBucketsWithContext(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list S3 buckets: %s", err)
+	}
+	resources := []string{}
+	for _, bucket := range out.Buckets {
+		resources = append(resources, resourceID(globalRegion, "S3Bucket", aws.StringValue(bucket.Name)))
+	}
+	return resources, nil
+}
+
+// EC2Instances lists EC2 instances which are not terminated,
+// in each region
+type EC2Instances struct {
+	NewClient func(region string) ec2iface.EC2API
+}
+
+// ListResources lists EC2 instances in each region
+func (l EC2Instances) ListResources(ctx context.Context, regions []string) ([]string, error) {
+	resources := []string{}
+	for _, region := range regions {
+		err := l.NewClient(region).DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{{
+				Name: aws.String("instance-state-name"),
+				Values: aws.StringSlice([]string{
+					ec2.InstanceStateNamePending, ec2.InstanceStateNameRunning,
+					ec2.InstanceStateNameStopping, ec2.InstanceStateNameStopped,
+				}),
+			}},
+		}, func(out *ec2.DescribeInstancesOutput, lastPage bool) bool {
+			for _, reservation := range out.Reservations {
+				for _, instance := range reservation.Instances {
+					resources = append(resources, resourceID(region, "EC2Instance", aws.StringValue(instance.InstanceId)))
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list EC2 instances in %s: %s", region, err)
+		}
+	}
+	return resources, nil
+}
+
+// TaggedResources lists all resources which have tags, in each region,
+// using the Resource Groups Tagging API.
+// This covers most resource types which support tags.
+type TaggedResources struct {
+	NewClient func(region string) resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI
+}
+
+// ListResources lists tagged resources in each region, by ARN
+func (l TaggedResources) ListResources(ctx context.Context, regions []string) ([]string, error) {
+	resources := []string{}
+	for _, region := range regions {
+		err := l.NewClient(region).GetResourcesPagesWithContext(ctx, &resourcegroupstaggingapi.GetResourcesInput{},
+			func(out *resourcegroupstaggingapi.GetResourcesOutput, lastPage bool) bool {
+				for _, mapping := range out.ResourceTagMappingList {
+					// Resources are sometimes listed with no tags
+					// for a short time after they are deleted
+					if len(mapping.Tags) == 0 {
+						continue
+					}
+					resources = append(resources, resourceID(region, taggedResourceType, aws.StringValue(mapping.ResourceARN)))
+				}
+				return true
+			})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tagged resources in %s: %s", region, err)
+		}
+	}
+	return resources, nil
+}
@@ -0,0 +1,164 @@
+package reset
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/stretchr/testify/require"
+)
+
+type staticLister struct {
+	resources []string
+	err       error
+}
+
+func (l staticLister) ListResources(ctx context.Context, regions []string) ([]string, error) {
+	return l.resources, l.err
+}
+
+func TestVerifier(t *testing.T) {
+
+	t.Run("should return resources not in the allowlist", func(t *testing.T) {
+		verifier := Verifier{
+			Listers: []ResourceLister{
+				staticLister{resources: []string{
+					"global - IAMRole - DCEPrincipal",
+					"global - IAMRole - AdminRole",
+					"global - IAMPolicy - arn:aws:iam::123:policy/DCEPrincipalDefaultPolicy",
+					"us-east-1 - EC2Instance - i-1",
+				}},
+				staticLister{resources: []string{
+					"us-east-1 - EC2Instance - i-1",
+					"global - S3Bucket - leftover",
+				}},
+			},
+			Allowlist: []string{
+				"global - IAMRole - DCEPrincipal",
+				"global - IAMRole - AdminRole",
+				"global - IAMPolicy - *DCEPrincipalDefaultPolicy*",
+			},
+		}
+
+		leftovers, err := verifier.Verify(context.Background(), []string{"us-east-1"})
+		require.Nil(t, err)
+		require.Equal(t, []string{
+			"global - S3Bucket - leftover",
+			"us-east-1 - EC2Instance - i-1",
+		}, leftovers)
+	})
+
+	t.Run("should return an empty list for clean accounts", func(t *testing.T) {
+		verifier := Verifier{
+			Listers:   []ResourceLister{staticLister{resources: []string{"global - IAMRole - DCEPrincipal"}}},
+			Allowlist: []string{"global - IAMRole - DCE*"},
+		}
+
+		leftovers, err := verifier.Verify(context.Background(), []string{"us-east-1"})
+		require.Nil(t, err)
+		require.Empty(t, leftovers)
+	})
+
+	t.Run("should return lister errors", func(t *testing.T) {
+		verifier := Verifier{
+			Listers: []ResourceLister{staticLister{err: errors.New("access denied")}},
+		}
+
+		_, err := verifier.Verify(context.Background(), []string{"us-east-1"})
+		require.EqualError(t, err, "access denied")
+	})
+
+	t.Run("patterns should only match whole resource IDs", func(t *testing.T) {
+		require.True(t, patternRegex("global - IAMRole - DCE*").MatchString("global - IAMRole - DCEPrincipal"))
+		require.False(t, patternRegex("global - IAMRole - DCE").MatchString("global - IAMRole - DCEPrincipal"))
+		require.False(t, patternRegex("global - IAMRole - DCE.rincipal").MatchString("global - IAMRole - DCEPrincipal"))
+	})
+}
+
+func TestNukeAllowlist(t *testing.T) {
+
+	t.Run("should keep resources filtered by aws-nuke", func(t *testing.T) {
+		nukeLog := NewNukeLog()
+		_, err := nukeLog.Write([]byte("" +
+			"us-east-1 - EC2Instance - i-0123 - [Name: \"fixture\", tag:Team: \"qa\"] - filtered by config\n" +
+			"us-west-2 - S3Bucket - s3://shared-fixtures-1 - [Name: \"shared-fixtures-1\"] - filtered by config\n" +
+			"global - IAMRole - fixture-role - filtered by config\n" +
+			"us-east-1 - EC2Instance - i-0456 - [Name: \"test\"] - would remove\n" +
+			"us-east-1 - EC2Instance - i-0456 - [Name: \"test\"] - removed\n",
+		))
+		require.Nil(t, err)
+
+		verifier := Verifier{
+			Listers: []ResourceLister{staticLister{resources: []string{
+				"us-east-1 - EC2Instance - i-0123",
+				"global - S3Bucket - shared-fixtures-1",
+				"global - S3Bucket - shared-fixtures-2",
+				"global - IAMRole - fixture-role",
+				"us-east-1 - TaggedResource - arn:aws:ec2:us-east-1:123456789012:instance/i-0123",
+				"us-west-2 - TaggedResource - arn:aws:s3:::shared-fixtures-1",
+				"us-east-1 - TaggedResource - arn:aws:iam::123456789012:role/fixture-role",
+				"us-east-1 - TaggedResource - arn:aws:ec2:us-east-1:123456789012:instance/i-0789",
+			}}},
+			Allowlist: NukeAllowlist(nukeLog.Summary().Filtered, nil),
+		}
+
+		leftovers, err := verifier.Verify(context.Background(), []string{"us-east-1", "us-west-2"})
+		require.Nil(t, err)
+		require.Equal(t, []string{
+			"global - S3Bucket - shared-fixtures-2",
+			"us-east-1 - TaggedResource - arn:aws:ec2:us-east-1:123456789012:instance/i-0789",
+		}, leftovers)
+	})
+
+	t.Run("should keep resources of excluded types", func(t *testing.T) {
+		verifier := Verifier{
+			Listers: []ResourceLister{staticLister{resources: []string{
+				"us-east-1 - EC2Instance - i-0123",
+				"global - S3Bucket - fixtures",
+				"us-east-1 - TaggedResource - arn:aws:ec2:us-east-1:123456789012:instance/i-0123",
+				"us-east-1 - TaggedResource - arn:aws:dynamodb:us-east-1:123456789012:table/fixtures",
+			}}},
+			Allowlist: NukeAllowlist(nil, []string{"EC2Instance", "DynamoDBTable"}),
+		}
+
+		leftovers, err := verifier.Verify(context.Background(), []string{"us-east-1"})
+		require.Nil(t, err)
+		require.Equal(t, []string{"global - S3Bucket - fixtures"}, leftovers)
+	})
+}
+
+type mockIAMResources struct {
+	iamiface.IAMAPI
+}
+
+func (m mockIAMResources) ListRolesPagesWithContext(ctx aws.Context, input *iam.ListRolesInput, fn func(*iam.ListRolesOutput, bool) bool, opts ...request.Option) error {
+	fn(&iam.ListRolesOutput{Roles: []*iam.Role{
+		{RoleName: aws.String("DCEPrincipal"), Path: aws.String("/")},
+		{RoleName: aws.String("AWSServiceRoleForSupport"), Path: aws.String("/aws-service-role/support.amazonaws.com/")},
+	}}, true)
+	return nil
+}
+
+func (m mockIAMResources) ListUsersPagesWithContext(ctx aws.Context, input *iam.ListUsersInput, fn func(*iam.ListUsersOutput, bool) bool, opts ...request.Option) error {
+	fn(&iam.ListUsersOutput{Users: []*iam.User{{UserName: aws.String("jdoe")}}}, true)
+	return nil
+}
+
+func (m mockIAMResources) ListPoliciesPagesWithContext(ctx aws.Context, input *iam.ListPoliciesInput, fn func(*iam.ListPoliciesOutput, bool) bool, opts ...request.Option) error {
+	fn(&iam.ListPoliciesOutput{Policies: []*iam.Policy{{Arn: aws.String("arn:aws:iam::123:policy/custom")}}}, true)
+	return nil
+}
+
+func TestIAMResources(t *testing.T) {
+	resources, err := IAMResources{Client: mockIAMResources{}}.ListResources(context.Background(), []string{"us-east-1"})
+	require.Nil(t, err)
+	require.Equal(t, []string{
+		"global - IAMRole - DCEPrincipal",
+		"global - IAMUser - jdoe",
+		"global - IAMPolicy - arn:aws:iam::123:policy/custom",
+	}, resources)
+}
@@ -0,0 +1,241 @@
+package resetpipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/history"
+	"github.com/Optum/dce/pkg/reset"
+	"github.com/Optum/dce/pkg/resetreport"
+	"github.com/Optum/dce/pkg/resetstatus"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// Config configures the reset of an account
+type Config struct {
+	AccountID           string
+	AdminRoleName       string
+	PrincipalRoleName   string
+	PrincipalPolicyName string
+	// AllowedRegions are the regions to reset
+	AllowedRegions []string
+
+	// NukeEnabled is false to run the reset in dry run mode
+	NukeEnabled         bool
+	NukeTemplateDefault string
+	NukeTemplateBucket  string
+	NukeTemplateKey     string
+	// NukePoolConfig overrides the aws-nuke config for accounts in each
+	// account pool. Accounts may also have their own overrides.
+	NukePoolConfig map[string]*db.NukeConfig
+
+	// Steps are the reset steps to run, in order
+	Steps []reset.StepConfig
+
+	// VerifyEnabled checks for resources remaining after the reset
+	VerifyEnabled bool
+	// VerifyAllowlist are patterns for resources which are expected to
+	// remain after the reset, in addition to the DCE roles and policies
+	VerifyAllowlist []string
+
+	// Preview lists the resources which the reset would delete,
+	// without deleting them, or changing the status of the account
+	Preview bool
+	// RunID is the ID of the reset report, if the report was
+	// created before the reset started. Otherwise, a new ID is used.
+	RunID string
+}
+
+// DefaultResetSteps are the reset steps run when RESET_STEPS is not set
+const DefaultResetSteps = "rds-backups,athena,aws-nuke"
+
+/*
+NewConfigFromEnv creates a Config from environment variables.
+The returned Config applies to all accounts: use ForAccount
+to configure the account to reset.
+
+Requires env vars for:
+
+- RESET_ACCOUNT_PRINCIPAL_POLICY_NAME
+- RESET_NUKE_TEMPLATE_DEFAULT
+- RESET_NUKE_TEMPLATE_BUCKET
+- RESET_NUKE_TEMPLATE_KEY
+
+Optional env vars:
+
+- RESET_NUKE_POOL_CONFIG (JSON object of aws-nuke config overrides, by pool name)
+*/
+func NewConfigFromEnv() (*Config, error) {
+	steps, err := reset.ParseStepConfig(common.GetEnv("RESET_STEPS", DefaultResetSteps))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid RESET_STEPS: %s", err)
+	}
+	poolConfig, err := parseNukePoolConfig(os.Getenv("RESET_NUKE_POOL_CONFIG"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid RESET_NUKE_POOL_CONFIG: %s", err)
+	}
+	return &Config{
+		PrincipalPolicyName: common.RequireEnv("RESET_ACCOUNT_PRINCIPAL_POLICY_NAME"),
+		AllowedRegions:      strings.Split(common.GetEnv("ALLOWED_REGIONS", common.GetEnv("AWS_CURRENT_REGION", "us-east-1")), ","),
+
+		NukeEnabled:         os.Getenv("RESET_NUKE_TOGGLE") != "false",
+		NukeTemplateDefault: common.RequireEnv("RESET_NUKE_TEMPLATE_DEFAULT"),
+		NukeTemplateBucket:  common.RequireEnv("RESET_NUKE_TEMPLATE_BUCKET"),
+		NukeTemplateKey:     common.RequireEnv("RESET_NUKE_TEMPLATE_KEY"),
+		NukePoolConfig:      poolConfig,
+
+		Steps: steps,
+
+		VerifyEnabled:   os.Getenv("RESET_VERIFY_TOGGLE") != "false",
+		VerifyAllowlist: splitList(os.Getenv("RESET_VERIFY_ALLOWLIST")),
+	}, nil
+}
+
+// ForAccount returns a copy of the config, for resetting an account
+func (c Config) ForAccount(accountID string, adminRoleName string, principalRoleName string) *Config {
+	c.AccountID = accountID
+	c.AdminRoleName = adminRoleName
+	c.PrincipalRoleName = principalRoleName
+	return &c
+}
+
+// ForPreview returns a copy of the config, to preview the reset
+// of the account. The report is saved with the given run ID.
+func (c Config) ForPreview(runID string) *Config {
+	c.Preview = true
+	c.NukeEnabled = false
+	c.RunID = runID
+	return &c
+}
+
+// AdminRoleARN is the ARN of the role used to reset the account
+func (c *Config) AdminRoleARN() string {
+	return "arn:aws:iam::" + c.AccountID + ":role/" + c.AdminRoleName
+}
+
+// verifyAllowlist returns patterns for the DCE roles and policies,
+// which are expected to remain after the reset,
+// and any additional patterns in VerifyAllowlist
+func (c *Config) verifyAllowlist() []string {
+	allowlist := []string{
+		"global - IAMRole - " + c.AdminRoleName,
+		"global - IAMRole - " + c.PrincipalRoleName,
+		"global - IAMPolicy - */" + c.PrincipalPolicyName,
+	}
+	return append(allowlist, c.VerifyAllowlist...)
+}
+
+// parseNukePoolConfig parses a JSON object
+// of aws-nuke config overrides by pool name
+func parseNukePoolConfig(poolConfigJSON string) (map[string]*db.NukeConfig, error) {
+	poolConfig := map[string]*db.NukeConfig{}
+	if strings.TrimSpace(poolConfigJSON) == "" {
+		return poolConfig, nil
+	}
+	err := json.Unmarshal([]byte(poolConfigJSON), &poolConfig)
+	if err != nil {
+		return nil, err
+	}
+	for pool, config := range poolConfig {
+		if config == nil {
+			return nil, fmt.Errorf("missing config for pool %s", pool)
+		}
+		err := config.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("pool %s: %s", pool, err)
+		}
+	}
+	return poolConfig, nil
+}
+
+// splitList splits a comma-separated list, ignoring empty values
+func splitList(list string) []string {
+	values := []string{}
+	for _, val := range strings.Split(list, ",") {
+		val = strings.TrimSpace(val)
+		if val != "" {
+			values = append(values, val)
+		}
+	}
+	return values
+}
+
+// Services are the clients used to reset an account
+type Services struct {
+	AWSSession   *session.Session
+	TokenService common.TokenService
+	// Storage is used to download the nuke template
+	Storage      common.Storager
+	DB           db.DBer
+	SNS          common.Notificationer
+	ResetReports resetreport.Service
+	// ResetCompleteTopicARN is notified when an account is reset
+	ResetCompleteTopicARN string
+	// LeaseAddedTopicARN is notified when a reset account
+	// is leased to a Pending lease
+	LeaseAddedTopicARN string
+	// ResetStatus records the status of the reset on the account,
+	// and re-enqueues failed resets
+	ResetStatus *resetstatus.Tracker
+}
+
+/*
+NewServicesFromEnv creates the Services from environment variables.
+Changes to accounts and leases are recorded in the history table.
+
+Requires env vars for:
+
+- ACCOUNT_DB, LEASE_DB, HISTORY_DB, RESET_REPORTS_DB
+- RESET_COMPLETE_TOPIC_ARN
+- LEASE_ADDED_TOPIC_ARN
+- RESET_SQS_URL
+*/
+func NewServicesFromEnv() (*Services, error) {
+	awsSession, err := session.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create AWS session: %s", err)
+	}
+	dbSvc, err := db.NewFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to initialize DB Service: %s", err)
+	}
+	historySvc, err := history.NewFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to initialize History Service: %s", err)
+	}
+	resetReports, err := resetreport.NewFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to initialize Reset Reports Service: %s", err)
+	}
+	// Record changes to the account and leases
+	// in the history table
+	recorder := history.NewRecorder(dbSvc, historySvc, "reset")
+	resetStatus, err := resetstatus.NewTrackerFromEnv(recorder, common.SQSQueue{Client: sqs.New(awsSession)})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Services{
+		AWSSession:   awsSession,
+		TokenService: &common.STS{Client: sts.New(awsSession)},
+		Storage: &common.S3{
+			Client:  s3.New(awsSession),
+			Manager: s3manager.NewDownloader(awsSession),
+		},
+		DB:                    recorder,
+		SNS:                   &common.SNS{Client: sns.New(awsSession)},
+		ResetReports:          resetReports,
+		ResetCompleteTopicARN: common.RequireEnv("RESET_COMPLETE_TOPIC_ARN"),
+		LeaseAddedTopicARN:    common.RequireEnv("LEASE_ADDED_TOPIC_ARN"),
+		ResetStatus:           resetStatus,
+	}, nil
+}
@@ -0,0 +1,138 @@
+package resetpipeline
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/reset"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+
+	// Stub required env vars
+	envVars := []string{
+		"RESET_ACCOUNT_PRINCIPAL_POLICY_NAME",
+		"RESET_NUKE_TOGGLE",
+		"RESET_NUKE_TEMPLATE_DEFAULT",
+		"RESET_NUKE_TEMPLATE_BUCKET",
+		"RESET_NUKE_TEMPLATE_KEY",
+	}
+	for _, envKey := range envVars {
+		err := os.Setenv(envKey, envKey+"_VAL")
+		require.Nil(t, err)
+	}
+
+	// Set toggle env vars
+	err := os.Setenv("RESET_NUKE_TOGGLE", "true")
+	require.Nil(t, err)
+
+	t.Run("NewConfigFromEnv", func(t *testing.T) {
+
+		t.Run("should configure from env vars", func(t *testing.T) {
+			config, err := NewConfigFromEnv()
+			require.Nil(t, err)
+			config = config.ForAccount("123456789012", "AdminRole", "PrincipalRole")
+
+			// Check configs from env vars
+			require.Equal(t, "123456789012", config.AccountID)
+			require.Equal(t, "AdminRole", config.AdminRoleName)
+			require.Equal(t, "PrincipalRole", config.PrincipalRoleName)
+			require.Equal(t, "RESET_ACCOUNT_PRINCIPAL_POLICY_NAME_VAL", config.PrincipalPolicyName)
+			require.Equal(t, "RESET_NUKE_TEMPLATE_DEFAULT_VAL", config.NukeTemplateDefault)
+			require.Equal(t, "RESET_NUKE_TEMPLATE_BUCKET_VAL", config.NukeTemplateBucket)
+			require.Equal(t, "RESET_NUKE_TEMPLATE_KEY_VAL", config.NukeTemplateKey)
+
+			// Check computed config vals
+			require.Equal(t, "arn:aws:iam::123456789012:role/AdminRole", config.AdminRoleARN())
+
+			// Check toggle env vars
+			require.Equal(t, true, config.NukeEnabled)
+
+			// Check default reset steps
+			require.Equal(t, []reset.StepConfig{
+				{Name: "rds-backups"},
+				{Name: "athena"},
+				{Name: "aws-nuke"},
+			}, config.Steps)
+
+			// Check the default verify allowlist
+			require.True(t, config.VerifyEnabled)
+			require.Equal(t, []string{
+				"global - IAMRole - AdminRole",
+				"global - IAMRole - PrincipalRole",
+				"global - IAMPolicy - */RESET_ACCOUNT_PRINCIPAL_POLICY_NAME_VAL",
+			}, config.verifyAllowlist())
+		})
+
+		t.Run("should add patterns to the verify allowlist", func(t *testing.T) {
+			os.Setenv("RESET_VERIFY_ALLOWLIST", "global - IAMRole - OrganizationAccountAccessRole, us-east-1 - TaggedResource - *,")
+			defer os.Unsetenv("RESET_VERIFY_ALLOWLIST")
+
+			config, err := NewConfigFromEnv()
+			require.Nil(t, err)
+			config = config.ForAccount("123456789012", "Admin", "Principal")
+
+			require.Equal(t, []string{
+				"global - IAMRole - Admin",
+				"global - IAMRole - Principal",
+				"global - IAMPolicy - */RESET_ACCOUNT_PRINCIPAL_POLICY_NAME_VAL",
+				"global - IAMRole - OrganizationAccountAccessRole",
+				"us-east-1 - TaggedResource - *",
+			}, config.verifyAllowlist())
+		})
+
+		t.Run("should configure nuke overrides by pool", func(t *testing.T) {
+			os.Setenv("RESET_NUKE_POOL_CONFIG", `{"shared": {"filters": {"S3Bucket": [{"type": "glob", "value": "s3://fixtures-*"}]}}}`)
+			defer os.Unsetenv("RESET_NUKE_POOL_CONFIG")
+
+			config, err := NewConfigFromEnv()
+			require.Nil(t, err)
+			require.Equal(t, map[string]*db.NukeConfig{
+				"shared": {
+					Filters: map[string][]db.NukeFilter{
+						"S3Bucket": {{Type: "glob", Value: "s3://fixtures-*"}},
+					},
+				},
+			}, config.NukePoolConfig)
+		})
+
+		t.Run("should return an error for invalid nuke overrides", func(t *testing.T) {
+			os.Setenv("RESET_NUKE_POOL_CONFIG", `{"shared": {"filters": {"S3Bucket": [{"type": "prefix", "value": "s3://"}]}}}`)
+			defer os.Unsetenv("RESET_NUKE_POOL_CONFIG")
+
+			_, err := NewConfigFromEnv()
+			require.NotNil(t, err)
+		})
+
+		t.Run("should return an error for invalid steps", func(t *testing.T) {
+			os.Setenv("RESET_STEPS", "aws-nuke:never")
+			defer os.Unsetenv("RESET_STEPS")
+
+			_, err := NewConfigFromEnv()
+			require.NotNil(t, err)
+		})
+
+	})
+
+	t.Run("ForAccount should not modify the shared config", func(t *testing.T) {
+		shared := &Config{PrincipalPolicyName: "Policy"}
+		config := shared.ForAccount("123456789012", "AdminRole", "PrincipalRole")
+
+		require.Equal(t, "123456789012", config.AccountID)
+		require.Equal(t, "Policy", config.PrincipalPolicyName)
+		require.Equal(t, "", shared.AccountID)
+	})
+
+	t.Run("ForPreview should run the reset in dry run mode", func(t *testing.T) {
+		shared := &Config{NukeEnabled: true}
+		config := shared.ForAccount("123456789012", "AdminRole", "PrincipalRole").ForPreview("run-1")
+
+		require.True(t, config.Preview)
+		require.False(t, config.NukeEnabled)
+		require.Equal(t, "run-1", config.RunID)
+		require.Equal(t, "123456789012", config.AccountID)
+		require.True(t, shared.NukeEnabled)
+	})
+}
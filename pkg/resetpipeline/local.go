@@ -0,0 +1,116 @@
+package resetpipeline
+
+import (
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/Optum/dce/pkg/resetreport"
+	"github.com/google/uuid"
+)
+
+// LocalBuilder implements the common.Builder interface, by running
+// resets in the current process instead of in CodeBuild.
+//
+// StartBuild queues the reset and returns immediately.
+// Up to the maxConcurrency passed to NewLocalBuilder resets run at once.
+// The outcome of each build is kept in the ResetStatus of its account,
+// and in its reset report.
+type LocalBuilder struct {
+	// Config applies to all accounts. The account to reset is
+	// configured by the environment variables passed to StartBuild.
+	Config   *Config
+	Services *Services
+
+	slots    chan struct{}
+	mu       sync.Mutex
+	active   map[string]string // queued or running build ID, by account ID
+	wg       sync.WaitGroup
+	runReset func(config *Config, svc *Services, buildID string) (*resetreport.Report, error)
+}
+
+// NewLocalBuilder creates a LocalBuilder, which runs up to
+// maxConcurrency resets at once
+func NewLocalBuilder(config *Config, svc *Services, maxConcurrency int) *LocalBuilder {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	return &LocalBuilder{
+		Config:   config,
+		Services: svc,
+		slots:    make(chan struct{}, maxConcurrency),
+		active:   map[string]string{},
+		runReset: Run,
+	}
+}
+
+// StartBuild queues a reset of the account in the RESET_ACCOUNT
+// environment variable, and returns the ID of the build.
+// If the account is already queued or resetting, the ID of
+// the existing build is returned.
+//
+// With RESET_PREVIEW=true, the build previews the reset, and the report
+// is saved with the RESET_RUN_ID. Previews run alongside other builds.
+func (b *LocalBuilder) StartBuild(projectName *string, environmentVariables map[string]string) (string, error) {
+	accountID := environmentVariables["RESET_ACCOUNT"]
+	if accountID == "" {
+		return "", errors.New("missing RESET_ACCOUNT environment variable")
+	}
+	config := b.Config.ForAccount(
+		accountID,
+		environmentVariables["RESET_ACCOUNT_ADMIN_ROLE_NAME"],
+		environmentVariables["RESET_ACCOUNT_PRINCIPAL_ROLE_NAME"],
+	)
+	if environmentVariables["RESET_PREVIEW"] == "true" {
+		config = config.ForPreview(environmentVariables["RESET_RUN_ID"])
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if buildID, ok := b.active[accountID]; ok && !config.Preview {
+		log.Printf("Reset of account %s is already queued or running as build %s", accountID, buildID)
+		return buildID, nil
+	}
+
+	name := "local-reset"
+	if projectName != nil {
+		name = *projectName
+	}
+	buildID := name + ":" + uuid.New().String()
+	if !config.Preview {
+		b.active[accountID] = buildID
+	}
+
+	b.wg.Add(1)
+	go b.run(buildID, config)
+
+	return buildID, nil
+}
+
+// run waits for a free slot, and then resets the account
+func (b *LocalBuilder) run(buildID string, config *Config) {
+	defer b.wg.Done()
+	b.slots <- struct{}{}
+	defer func() { <-b.slots }()
+
+	log.Printf("Starting local reset build %s for account %s", buildID, config.AccountID)
+
+	_, err := b.runReset(config, b.Services, buildID)
+	if err != nil {
+		log.Printf("Local reset build %s failed for account %s: %s", buildID, config.AccountID, err)
+	} else {
+		log.Printf("Local reset build %s succeeded for account %s", buildID, config.AccountID)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.active[config.AccountID] == buildID {
+		delete(b.active, config.AccountID)
+	}
+}
+
+// Wait blocks until all started builds have completed
+func (b *LocalBuilder) Wait() {
+	b.wg.Wait()
+}
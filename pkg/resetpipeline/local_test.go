@@ -0,0 +1,136 @@
+package resetpipeline
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/resetreport"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/require"
+)
+
+var _ common.Builder = &LocalBuilder{}
+
+func TestLocalBuilder(t *testing.T) {
+
+	t.Run("should reset accounts with bounded concurrency", func(t *testing.T) {
+		builder := NewLocalBuilder(&Config{PrincipalPolicyName: "Policy"}, &Services{}, 2)
+
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+		release := make(chan struct{})
+		configs := map[string]*Config{}
+		builder.runReset = func(config *Config, svc *Services, buildID string) (*resetreport.Report, error) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			configs[config.AccountID] = config
+			mu.Unlock()
+
+			<-release
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			if config.AccountID == "333" {
+				return &resetreport.Report{RunID: "run-333"}, errors.New("nuke failed")
+			}
+			return &resetreport.Report{RunID: "run-" + config.AccountID}, nil
+		}
+
+		buildIDs := []string{}
+		for _, accountID := range []string{"111", "222", "333"} {
+			buildID, err := builder.StartBuild(aws.String("reset"), map[string]string{
+				"RESET_ACCOUNT":                     accountID,
+				"RESET_ACCOUNT_ADMIN_ROLE_NAME":     "AdminRole",
+				"RESET_ACCOUNT_PRINCIPAL_ROLE_NAME": "PrincipalRole",
+			})
+			require.Nil(t, err)
+			require.Regexp(t, "^reset:", buildID)
+			buildIDs = append(buildIDs, buildID)
+		}
+
+		// Starting a build for an account which is already resetting
+		// returns the existing build
+		buildID, err := builder.StartBuild(aws.String("reset"), map[string]string{"RESET_ACCOUNT": "111"})
+		require.Nil(t, err)
+		require.Equal(t, buildIDs[0], buildID)
+
+		close(release)
+		builder.Wait()
+
+		require.True(t, maxRunning <= 2, "ran %d resets at once", maxRunning)
+		require.Equal(t, "AdminRole", configs["111"].AdminRoleName)
+		require.Equal(t, "PrincipalRole", configs["111"].PrincipalRoleName)
+		require.Equal(t, "Policy", configs["111"].PrincipalPolicyName)
+
+		// Finished builds are no longer active
+		require.Empty(t, builder.active)
+	})
+
+	t.Run("should run previews alongside resets", func(t *testing.T) {
+		builder := NewLocalBuilder(&Config{NukeEnabled: true}, &Services{}, 2)
+
+		var mu sync.Mutex
+		release := make(chan struct{})
+		configs := map[string]*Config{}
+		builder.runReset = func(config *Config, svc *Services, buildID string) (*resetreport.Report, error) {
+			mu.Lock()
+			configs[buildID] = config
+			mu.Unlock()
+			<-release
+			return &resetreport.Report{RunID: config.RunID}, nil
+		}
+
+		resetID, err := builder.StartBuild(aws.String("reset"), map[string]string{"RESET_ACCOUNT": "111"})
+		require.Nil(t, err)
+		previewID, err := builder.StartBuild(aws.String("reset"), map[string]string{
+			"RESET_ACCOUNT": "111",
+			"RESET_PREVIEW": "true",
+			"RESET_RUN_ID":  "run-1",
+		})
+		require.Nil(t, err)
+		require.NotEqual(t, resetID, previewID)
+
+		// The reset is still active
+		buildID, err := builder.StartBuild(aws.String("reset"), map[string]string{"RESET_ACCOUNT": "111"})
+		require.Nil(t, err)
+		require.Equal(t, resetID, buildID)
+
+		close(release)
+		builder.Wait()
+
+		require.False(t, configs[resetID].Preview)
+		require.True(t, configs[resetID].NukeEnabled)
+		require.True(t, configs[previewID].Preview)
+		require.False(t, configs[previewID].NukeEnabled)
+		require.Equal(t, "run-1", configs[previewID].RunID)
+	})
+
+	t.Run("should start a new build once the previous build finishes", func(t *testing.T) {
+		builder := NewLocalBuilder(&Config{}, &Services{}, 1)
+		builder.runReset = func(config *Config, svc *Services, buildID string) (*resetreport.Report, error) {
+			return nil, errors.New("reset failed")
+		}
+
+		firstID, err := builder.StartBuild(aws.String("reset"), map[string]string{"RESET_ACCOUNT": "111"})
+		require.Nil(t, err)
+		builder.Wait()
+
+		secondID, err := builder.StartBuild(aws.String("reset"), map[string]string{"RESET_ACCOUNT": "111"})
+		require.Nil(t, err)
+		require.NotEqual(t, firstID, secondID)
+		builder.Wait()
+		require.Empty(t, builder.active)
+	})
+
+	t.Run("should require an account ID", func(t *testing.T) {
+		builder := NewLocalBuilder(&Config{}, &Services{}, 1)
+		_, err := builder.StartBuild(aws.String("reset"), map[string]string{})
+		require.NotNil(t, err)
+	})
+}
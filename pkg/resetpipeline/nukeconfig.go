@@ -0,0 +1,251 @@
+package resetpipeline
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/Optum/dce/pkg/db"
+)
+
+// nukeConfigFile is an aws-nuke config file.
+// Keys which are not used by DCE are kept as-is.
+type nukeConfigFile struct {
+	Regions          []string                      `yaml:"regions"`
+	AccountBlacklist []string                      `yaml:"account-blacklist"`
+	ResourceTypes    nukeResourceTypes             `yaml:"resource-types,omitempty"`
+	Accounts         map[string]*nukeAccountConfig `yaml:"accounts"`
+	Extra            map[string]interface{}        `yaml:",inline"`
+}
+
+type nukeResourceTypes struct {
+	Targets  []string               `yaml:"targets,omitempty"`
+	Excludes []string               `yaml:"excludes,omitempty"`
+	Extra    map[string]interface{} `yaml:",inline"`
+}
+
+type nukeAccountConfig struct {
+	// Filters are by resource type. Each filter is either
+	// a string (an exact match) or a filter object.
+	Filters       map[string][]interface{} `yaml:"filters,omitempty"`
+	ResourceTypes nukeResourceTypes        `yaml:"resource-types,omitempty"`
+	Extra         map[string]interface{}   `yaml:",inline"`
+}
+
+// writeNukeConfig renders the nuke config for the account to a file,
+// and returns the path of the file, and the excluded resource types.
+// See renderNukeConfig.
+func writeNukeConfig(config *Config, svc *Services) (string, []string, error) {
+	account, err := svc.DB.GetAccount(config.AccountID)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "Failed to get account %s", config.AccountID)
+	}
+	if account == nil {
+		return "", nil, fmt.Errorf("Account %s does not exist", config.AccountID)
+	}
+
+	nukeConfig, excludedTypes, err := renderNukeConfig(config, svc, account)
+	if err != nil {
+		return "", nil, err
+	}
+
+	configFile := fmt.Sprintf("/tmp/nuke-config-%s.yml", config.AccountID)
+	err = ioutil.WriteFile(configFile, nukeConfig, 0644)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "Failed to write file %s", configFile)
+	}
+	return configFile, excludedTypes, nil
+}
+
+// renderNukeConfig renders the nuke template for the account, and adds
+// the overrides for the account's pool, and for the account itself.
+// The resource types which aws-nuke will not delete are returned
+// with the config.
+// Returns an error if the resulting config is not valid.
+func renderNukeConfig(config *Config, svc *Services, account *db.Account) ([]byte, []string, error) {
+	var rendered bytes.Buffer
+	err := generateNukeConfig(config, svc, &rendered)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file := &nukeConfigFile{}
+	err = yaml.Unmarshal(rendered.Bytes(), file)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Failed to parse nuke config for account %s", config.AccountID)
+	}
+
+	overrides := nukeOverrides(config, account)
+	file.merge(config.AccountID, overrides...)
+
+	err = file.validate(config.AccountID)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Invalid nuke config for account %s", config.AccountID)
+	}
+	excludedTypes := file.excludedResourceTypes(config.AccountID)
+
+	// Keep the rendered template as-is, if there are no overrides
+	if len(overrides) == 0 {
+		return rendered.Bytes(), excludedTypes, nil
+	}
+	merged, err := yaml.Marshal(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return merged, excludedTypes, nil
+}
+
+// nukeOverrides returns the nuke config overrides
+// for the account's pool, and for the account itself
+func nukeOverrides(config *Config, account *db.Account) []*db.NukeConfig {
+	overrides := []*db.NukeConfig{}
+	if poolConfig, ok := config.NukePoolConfig[account.PoolName()]; ok {
+		log.Printf("Using nuke config overrides for pool %s", account.PoolName())
+		overrides = append(overrides, poolConfig)
+	}
+	if account.NukeConfig != nil {
+		log.Printf("Using nuke config overrides for account %s", account.ID)
+		overrides = append(overrides, account.NukeConfig)
+	}
+	return overrides
+}
+
+// mergeNukeOverrides combines the filters and excluded resource types
+// of the overrides, or returns nil if there are no overrides
+func mergeNukeOverrides(overrides ...*db.NukeConfig) *db.NukeConfig {
+	if len(overrides) == 0 {
+		return nil
+	}
+	merged := &db.NukeConfig{Filters: map[string][]db.NukeFilter{}}
+	for _, override := range overrides {
+		for resourceType, filters := range override.Filters {
+			merged.Filters[resourceType] = append(merged.Filters[resourceType], filters...)
+		}
+		merged.ExcludeResourceTypes = appendUnique(merged.ExcludeResourceTypes, override.ExcludeResourceTypes...)
+	}
+	return merged
+}
+
+// merge adds the filters and excluded resource types of each override
+// to the config for the account
+func (f *nukeConfigFile) merge(accountID string, overrides ...*db.NukeConfig) {
+	if len(overrides) == 0 {
+		return
+	}
+	if f.Accounts == nil {
+		f.Accounts = map[string]*nukeAccountConfig{}
+	}
+	account, ok := f.Accounts[accountID]
+	if !ok || account == nil {
+		account = &nukeAccountConfig{}
+		f.Accounts[accountID] = account
+	}
+	if account.Filters == nil {
+		account.Filters = map[string][]interface{}{}
+	}
+
+	for _, override := range overrides {
+		for resourceType, filters := range override.Filters {
+			for _, filter := range filters {
+				account.Filters[resourceType] = append(account.Filters[resourceType], filter)
+			}
+		}
+		f.ResourceTypes.Excludes = appendUnique(f.ResourceTypes.Excludes, override.ExcludeResourceTypes...)
+	}
+}
+
+// excludedResourceTypes returns the resource types which aws-nuke
+// does not delete from the account
+func (f *nukeConfigFile) excludedResourceTypes(accountID string) []string {
+	excludedTypes := appendUnique([]string{}, f.ResourceTypes.Excludes...)
+	if account := f.Accounts[accountID]; account != nil {
+		excludedTypes = appendUnique(excludedTypes, account.ResourceTypes.Excludes...)
+	}
+	return excludedTypes
+}
+
+// validate returns an error if aws-nuke would refuse the config,
+// or the config has invalid filters for the account
+func (f *nukeConfigFile) validate(accountID string) error {
+	if len(f.Regions) == 0 {
+		return errors.New("no regions are configured")
+	}
+	if len(f.AccountBlacklist) == 0 {
+		return errors.New("account-blacklist must not be empty")
+	}
+	for _, blacklisted := range f.AccountBlacklist {
+		if blacklisted == accountID {
+			return fmt.Errorf("account %s is in the account-blacklist", accountID)
+		}
+	}
+	account, ok := f.Accounts[accountID]
+	if !ok {
+		return fmt.Errorf("account %s is not configured", accountID)
+	}
+	if account == nil {
+		return nil
+	}
+
+	for resourceType, filters := range account.Filters {
+		for _, filter := range filters {
+			err := validateNukeFilter(filter)
+			if err != nil {
+				return fmt.Errorf("invalid filter for %s: %s", resourceType, err)
+			}
+		}
+	}
+	for _, resourceType := range append(f.ResourceTypes.Excludes, account.ResourceTypes.Excludes...) {
+		if resourceType == "" {
+			return errors.New("excluded resource types must not be empty")
+		}
+	}
+	return nil
+}
+
+// validateNukeFilter checks a filter from a nuke config file,
+// which is either a string or a filter object
+func validateNukeFilter(filter interface{}) error {
+	if value, ok := filter.(string); ok {
+		if value == "" {
+			return errors.New("filter value must not be empty")
+		}
+		return nil
+	}
+	if nukeFilter, ok := filter.(db.NukeFilter); ok {
+		return nukeFilter.Validate()
+	}
+
+	// Filters parsed from the template are maps,
+	// so convert them to a NukeFilter
+	filterYAML, err := yaml.Marshal(filter)
+	if err != nil {
+		return err
+	}
+	nukeFilter := db.NukeFilter{}
+	err = yaml.Unmarshal(filterYAML, &nukeFilter)
+	if err != nil {
+		return err
+	}
+	return nukeFilter.Validate()
+}
+
+// appendUnique appends values which are not already in the list
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
@@ -0,0 +1,131 @@
+package resetpipeline
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Optum/dce/pkg/db"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestNukeConfig(t *testing.T) {
+	newConfig := func(accountID string) *Config {
+		return &Config{
+			AccountID:           accountID,
+			AdminRoleName:       "AdminRole",
+			AllowedRegions:      []string{"us-east-1"},
+			PrincipalRoleName:   "PrincipalRole",
+			PrincipalPolicyName: "PrincipalPolicy",
+			NukeTemplateDefault: "../../cmd/codebuild/reset/default-nuke-config-template.yml",
+			NukeTemplateBucket:  "STUB",
+			NukeTemplateKey:     "STUB",
+			NukePoolConfig: map[string]*db.NukeConfig{
+				"shared": {
+					Filters: map[string][]db.NukeFilter{
+						"S3Bucket": {{Type: "glob", Value: "s3://shared-fixtures-*"}},
+					},
+					ExcludeResourceTypes: []string{"Route53HostedZone"},
+				},
+			},
+		}
+	}
+
+	t.Run("should use the rendered template, if there are no overrides", func(t *testing.T) {
+		config := newConfig("123456789012")
+
+		got, excludedTypes, err := renderNukeConfig(config, &Services{}, &db.Account{ID: "123456789012"})
+		require.Nil(t, err)
+		require.Equal(t, []string{"S3Object"}, excludedTypes)
+
+		var want bytes.Buffer
+		require.Nil(t, generateNukeConfig(config, &Services{}, &want))
+		require.Equal(t, want.String(), string(got))
+	})
+
+	t.Run("should merge pool and account overrides", func(t *testing.T) {
+		config := newConfig("123456789012")
+		account := &db.Account{
+			ID:   "123456789012",
+			Pool: "shared",
+			NukeConfig: &db.NukeConfig{
+				Filters: map[string][]db.NukeFilter{
+					"IAMRole": {{Value: "fixture-role"}},
+				},
+				ExcludeResourceTypes: []string{"Route53HostedZone", "DynamoDBTable"},
+			},
+		}
+
+		got, excludedTypes, err := renderNukeConfig(config, &Services{}, account)
+		require.Nil(t, err)
+		require.Equal(t, []string{"S3Object", "Route53HostedZone", "DynamoDBTable"}, excludedTypes)
+
+		file := &nukeConfigFile{}
+		require.Nil(t, yaml.Unmarshal(got, file))
+		require.Equal(t, []string{"global", "us-east-1"}, file.Regions)
+		require.Equal(t, []string{"S3Object", "Route53HostedZone", "DynamoDBTable"}, file.ResourceTypes.Excludes)
+
+		filters := file.Accounts["123456789012"].Filters
+		require.Equal(t, []interface{}{"AdminRole", "PrincipalRole", map[interface{}]interface{}{"value": "fixture-role"}}, filters["IAMRole"])
+		require.Equal(t, []interface{}{map[interface{}]interface{}{"type": "glob", "value": "s3://shared-fixtures-*"}}, filters["S3Bucket"])
+		require.Len(t, filters["IAMRolePolicy"], 3)
+	})
+
+	t.Run("should merge overrides for the reset steps", func(t *testing.T) {
+		config := newConfig("123456789012")
+		account := &db.Account{
+			ID:   "123456789012",
+			Pool: "shared",
+			NukeConfig: &db.NukeConfig{
+				Filters: map[string][]db.NukeFilter{
+					"S3Bucket": {{Value: "s3://fixture"}},
+				},
+				ExcludeResourceTypes: []string{"Route53HostedZone", "EC2Image"},
+			},
+		}
+
+		merged := mergeNukeOverrides(nukeOverrides(config, account)...)
+		require.Equal(t, &db.NukeConfig{
+			Filters: map[string][]db.NukeFilter{
+				"S3Bucket": {
+					{Type: "glob", Value: "s3://shared-fixtures-*"},
+					{Value: "s3://fixture"},
+				},
+			},
+			ExcludeResourceTypes: []string{"Route53HostedZone", "EC2Image"},
+		}, merged)
+
+		require.Nil(t, mergeNukeOverrides(nukeOverrides(config, &db.Account{ID: "123456789012"})...))
+	})
+
+	t.Run("should return an error for invalid overrides", func(t *testing.T) {
+		config := newConfig("123456789012")
+		account := &db.Account{
+			ID: "123456789012",
+			NukeConfig: &db.NukeConfig{
+				Filters: map[string][]db.NukeFilter{
+					"IAMRole": {{Type: "regex", Value: "fixture-("}},
+				},
+			},
+		}
+
+		_, _, err := renderNukeConfig(config, &Services{}, account)
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "invalid filter for IAMRole")
+	})
+
+	t.Run("should return an error for blacklisted accounts", func(t *testing.T) {
+		config := newConfig("999999999999")
+
+		_, _, err := renderNukeConfig(config, &Services{}, &db.Account{ID: "999999999999"})
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "account-blacklist")
+	})
+
+	t.Run("writeNukeConfig should return an error for missing accounts", func(t *testing.T) {
+		config := newConfig("123456789012")
+
+		_, _, err := writeNukeConfig(config, &Services{DB: db.NewMemoryDB(7)})
+		require.NotNil(t, err)
+	})
+}
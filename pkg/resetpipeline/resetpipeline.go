@@ -0,0 +1,571 @@
+// Package resetpipeline resets a DCE child account. It runs each of the
+// configured reset steps (including aws-nuke), checks that no unexpected
+// resources remain in the account, and returns the account to the account pool.
+//
+// Resets run in CodeBuild (see cmd/codebuild/reset),
+// or in-process with a LocalBuilder.
+package resetpipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/reset"
+	"github.com/Optum/dce/pkg/resetreport"
+	"github.com/Optum/dce/pkg/resetstatus"
+	"github.com/Optum/dce/pkg/waitlist"
+	"github.com/avast/retry-go"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Run resets an account, and saves a report of the reset run.
+// buildID is the ID of the build which ran the reset, eg. the CodeBuild build ID.
+//
+// If config.Preview is set, the reset steps run in dry run mode,
+// and the account is not changed. The report lists the resources
+// which the reset would delete.
+func Run(config *Config, svc *Services, buildID string) (*resetreport.Report, error) {
+	if config.Preview {
+		return preview(config, svc, buildID)
+	}
+	if !config.NukeEnabled {
+		log.Println("INFO: Nuke is set in Dry Run mode and will not remove " +
+			"any resources and cannot set back the state of the DCE child account " +
+			"Please set 'RESET_NUKE_TOGGLE' to not 'false' to exit Dry Run " +
+			"mode.")
+	}
+
+	_, statusErr := svc.ResetStatus.Running(config.AccountID, buildID)
+	if statusErr != nil {
+		log.Printf("Failed to update reset status for account %s: %s\n", config.AccountID, statusErr)
+	}
+
+	// Record the result of the reset, whether or not it succeeds
+	report := newReport(config, buildID)
+	err := resetAccount(config, svc, report)
+	report.Complete(err)
+	putErr := svc.ResetReports.PutReport(report)
+	if putErr != nil {
+		log.Printf("Failed to save reset report %s for account %s: %s\n", report.RunID, config.AccountID, putErr)
+	}
+
+	if err != nil {
+		// Failed resets are retried, unless the account was
+		// quarantined, as quarantined accounts are not reset automatically.
+		// Resets which fail after the account was returned to the
+		// account pool, or leased, are not retried either.
+		_, quarantined := errors.Cause(err).(*leftoverResourcesError)
+		retry := !quarantined && needsReset(svc.DB, config.AccountID)
+		_, statusErr = svc.ResetStatus.Failed(config.AccountID, buildID, err, retry)
+		if statusErr != nil {
+			log.Printf("Failed to update reset status for account %s: %s\n", config.AccountID, statusErr)
+		}
+		return report, err
+	}
+
+	_, statusErr = svc.ResetStatus.Succeeded(config.AccountID, buildID)
+	if statusErr != nil {
+		log.Printf("Failed to update reset status for account %s: %s\n", config.AccountID, statusErr)
+	}
+	log.Printf("%s  :  Reset Success\n", config.AccountID)
+	return report, nil
+}
+
+// preview runs the reset steps in dry run mode,
+// and saves a report of the resources which would be deleted
+func preview(config *Config, svc *Services, buildID string) (*resetreport.Report, error) {
+	log.Printf("%s  :  Previewing reset. No resources will be deleted.\n", config.AccountID)
+
+	report := newReport(config, buildID)
+	_, err := runSteps(config, svc, report)
+	report.Complete(err)
+	putErr := svc.ResetReports.PutReport(report)
+	if putErr != nil {
+		log.Printf("Failed to save reset report %s for account %s: %s\n", report.RunID, config.AccountID, putErr)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	log.Printf("%s  :  Preview Success. %d resources would be deleted.\n",
+		config.AccountID, report.ResourceCounts.Found)
+	return report, nil
+}
+
+// newReport creates the report of the reset run, using
+// the configured RunID, if set
+func newReport(config *Config, buildID string) *resetreport.Report {
+	report := resetreport.NewReport(config.AccountID, buildID, !config.NukeEnabled)
+	if config.RunID != "" {
+		report.RunID = config.RunID
+	}
+	return report
+}
+
+// resetAccount runs the reset steps, and then returns the account
+// to the account pool. The results of each step are added to the report.
+func resetAccount(config *Config, svc *Services, report *resetreport.Report) error {
+	// Skip accounts which no longer need a reset, eg. when a
+	// retried reset already returned the account to the pool
+	account, err := svc.DB.GetAccount(config.AccountID)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get account %s", config.AccountID)
+	}
+	if account == nil {
+		return fmt.Errorf("Account %s does not exist", config.AccountID)
+	}
+	if !resetstatus.NeedsReset(account) {
+		log.Printf("%s  :  Skipping reset of account with status %s\n",
+			config.AccountID, account.AccountStatus)
+		return nil
+	}
+
+	nuke, err := runSteps(config, svc, report)
+	if err != nil {
+		return err
+	}
+	log.Printf("%s  :  Nuke Success\n", config.AccountID)
+
+	// Check that no unexpected resources remain, before
+	// returning the account to the account pool.
+	// In dry run mode, resources are expected to remain.
+	verified := false
+	if config.VerifyEnabled && config.NukeEnabled {
+		start := time.Now()
+		// Resources kept by aws-nuke filters, and resources of excluded
+		// types, are expected to remain
+		kept := reset.NukeAllowlist(nuke.log.Summary().Filtered, nuke.excludedTypes)
+		leftovers, err := verifyAccount(config, svc, kept)
+		result := resetreport.StepResult{
+			Name:            verifyStepName,
+			Status:          string(reset.StepSucceeded),
+			ResourceCount:   len(leftovers),
+			DurationSeconds: int64(time.Since(start).Seconds()),
+		}
+		if err == nil && len(leftovers) > 0 {
+			err = &leftoverResourcesError{count: len(leftovers)}
+		}
+		if err != nil {
+			result.Status = string(reset.StepFailed)
+			result.Error = err.Error()
+		}
+		report.Steps = append(report.Steps, result)
+
+		if len(leftovers) > 0 {
+			log.Printf("%s  :  %d resources remain after reset:\n%s\n",
+				config.AccountID, len(leftovers), strings.Join(leftovers, "\n"))
+			qErr := quarantineAccount(svc.DB, config.AccountID, leftovers)
+			if qErr != nil {
+				return errors.Wrapf(qErr, "Failed to quarantine account %s", config.AccountID)
+			}
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Failed to verify reset of account %s", config.AccountID)
+		}
+		log.Printf("%s  :  Verify Success\n", config.AccountID)
+		verified = true
+	}
+
+	// Update the DB with Account/Lease statuses
+	err = updateDBPostReset(svc.DB, svc.SNS, config.AccountID, verified,
+		svc.ResetCompleteTopicARN, svc.LeaseAddedTopicARN)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update the DB post-reset")
+	}
+	return nil
+}
+
+// needsReset returns true if the account is still waiting to be reset.
+// If the account cannot be loaded, the reset is assumed to be needed.
+func needsReset(dbSvc db.DBer, accountID string) bool {
+	account, err := dbSvc.GetAccount(accountID)
+	if err != nil {
+		log.Printf("Failed to get account %s: %s\n", accountID, err)
+		return true
+	}
+	return account != nil && resetstatus.NeedsReset(account)
+}
+
+// runSteps runs the reset steps, to delete all resources from the account,
+// and adds the results of each step to the report.
+// Steps only list resources, if NukeEnabled is off.
+// The aws-nuke step is returned, with the resources handled by aws-nuke.
+func runSteps(config *Config, svc *Services, report *resetreport.Report) (*nukeStep, error) {
+	nuke := &nukeStep{config: config, svc: svc, log: reset.NewNukeLog()}
+	registry, err := newStepRegistry(config, svc, nuke)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to configure reset steps")
+	}
+	plan, err := registry.Plan(config.Steps)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to configure reset steps")
+	}
+
+	// Generate and validate the aws-nuke config,
+	// before any resources are deleted
+	for _, step := range plan {
+		if step.Step.Name() == nuke.Name() {
+			nuke.configPath, nuke.excludedTypes, err = writeNukeConfig(config, svc)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Other steps keep the same resources as aws-nuke
+	// for the account's overrides
+	account, err := svc.DB.GetAccount(config.AccountID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get account %s", config.AccountID)
+	}
+	var nukeConfig *db.NukeConfig
+	if account != nil {
+		nukeConfig = mergeNukeOverrides(nukeOverrides(config, account)...)
+	}
+
+	results, err := reset.RunSteps(context.Background(), plan, &reset.StepInput{
+		AccountID:  config.AccountID,
+		Regions:    config.AllowedRegions,
+		DryRun:     config.NukeEnabled == false,
+		NukeConfig: nukeConfig,
+	})
+	addStepResults(report, results, nuke)
+	return nuke, err
+}
+
+// addStepResults adds the results of the reset steps to the report.
+// Resources handled by aws-nuke are taken from the aws-nuke output.
+func addStepResults(report *resetreport.Report, results []*reset.StepResult, nuke *nukeStep) {
+	for _, result := range results {
+		log.Printf("%s  :  %s %s (%d resources) %s\n", report.AccountID,
+			result.Name, result.Status, len(result.Resources), result.Error)
+
+		report.Steps = append(report.Steps, resetreport.StepResult{
+			Name:            result.Name,
+			Status:          string(result.Status),
+			ResourceCount:   len(result.Resources),
+			Error:           result.Error,
+			DurationSeconds: int64(result.Duration.Seconds()),
+		})
+
+		if result.Name == nuke.Name() {
+			summary := nuke.log.Summary()
+			report.AddResources(summary.Found, summary.Deleted, summary.Failed, summary.Filtered)
+			report.NukeAttempts = nuke.attempts
+		} else if result.DryRun {
+			report.AddResources(result.Resources, nil, nil, nil)
+		} else {
+			report.AddResources(result.Resources, result.Resources, nil, nil)
+		}
+	}
+}
+
+// newStepRegistry creates the reset steps which may be enabled
+// with Config.Steps. Steps use the admin role of the child account.
+func newStepRegistry(config *Config, svc *Services, nuke *nukeStep) (*reset.Registry, error) {
+	childSession, err := svc.TokenService.NewSession(svc.AWSSession, config.AdminRoleARN())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create session for account %s", config.AccountID)
+	}
+
+	return reset.NewRegistry(
+		reset.RdsBackupsStep{Client: rds.New(childSession)},
+		reset.AthenaStep{Client: athena.New(childSession)},
+		reset.S3VersionedBucketsStep{
+			NewClient: func(region string) s3iface.S3API {
+				return s3.New(childSession, aws.NewConfig().WithRegion(region))
+			},
+		},
+		reset.EC2ImagesStep{
+			NewClient: func(region string) ec2iface.EC2API {
+				return ec2.New(childSession, aws.NewConfig().WithRegion(region))
+			},
+		},
+		nuke,
+	)
+}
+
+// verifyStepName is the name of the post-reset verification,
+// in reset reports
+const verifyStepName = "verify"
+
+// verifyAccount lists the resources remaining in the account,
+// and returns any which are not in the verify allowlist,
+// or matched by the kept patterns
+func verifyAccount(config *Config, svc *Services, kept []string) ([]string, error) {
+	childSession, err := svc.TokenService.NewSession(svc.AWSSession, config.AdminRoleARN())
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create session for account %s", config.AccountID)
+	}
+
+	verifier := &reset.Verifier{
+		Listers: []reset.ResourceLister{
+			reset.IAMResources{Client: iam.New(childSession)},
+			reset.S3Buckets{Client: s3.New(childSession)},
+			reset.EC2Instances{
+				NewClient: func(region string) ec2iface.EC2API {
+					return ec2.New(childSession, aws.NewConfig().WithRegion(region))
+				},
+			},
+			reset.TaggedResources{
+				NewClient: func(region string) resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI {
+					return resourcegroupstaggingapi.New(childSession, aws.NewConfig().WithRegion(region))
+				},
+			},
+		},
+		Allowlist: append(config.verifyAllowlist(), kept...),
+	}
+	return verifier.Verify(context.Background(), config.AllowedRegions)
+}
+
+// leftoverResourcesError means that resources remained in the
+// account after the reset, and the account was quarantined
+type leftoverResourcesError struct {
+	count int
+}
+
+func (e *leftoverResourcesError) Error() string {
+	return fmt.Sprintf("%d resources remain after reset", e.count)
+}
+
+// quarantineAccount changes the account from "Status=NotReady" to
+// "Status=Quarantined", so it is not leased, and attaches the leftover
+// resources to the account.
+// Accounts which are not NotReady (eg. Leased) keep their status.
+func quarantineAccount(dbSvc db.DBer, accountID string, leftovers []string) error {
+	log.Printf("Setting Account Status from NotReady to Quarantined: %s", accountID)
+	_, err := dbSvc.TransitionAccountStatus(accountID, db.NotReady, db.Quarantined)
+	if err != nil {
+		if _, ok := err.(*db.StatusTransitionError); !ok {
+			return err
+		}
+		log.Printf("Account %s is not NotReady, and will not be quarantined: %s", accountID, err)
+	}
+
+	_, err = dbSvc.UpdateAccount(db.Account{
+		ID:                accountID,
+		LeftoverResources: leftovers,
+	}, []string{"LeftoverResources"})
+	return err
+}
+
+// nukeStep is a reset Step which runs aws-nuke,
+// with the nuke config generated for the account
+type nukeStep struct {
+	config *Config
+	svc    *Services
+	// configPath is the path of the generated nuke config
+	configPath string
+	// excludedTypes are the resource types excluded by the nuke config
+	excludedTypes []string
+	// log tracks resources from the aws-nuke output
+	log *reset.NukeLog
+	// attempts is the number of times aws-nuke ran
+	attempts int
+}
+
+// Name of the step
+func (step *nukeStep) Name() string {
+	return "aws-nuke"
+}
+
+// Run executes aws-nuke, and returns the removed resources
+// (or in dry run mode, the resources which would be removed)
+func (step *nukeStep) Run(ctx context.Context, input *reset.StepInput) ([]string, error) {
+	err := nukeAccount(ctx, step.config, step.svc, step.configPath, input.DryRun, step.log, &step.attempts)
+	summary := step.log.Summary()
+	if input.DryRun {
+		return summary.Found, err
+	}
+	return summary.Deleted, err
+}
+
+// updateDBPostReset changes any leases for the Account
+// from "Status=ResetLock" to "Status=Active"
+// Also, if the account was set as "Status=NotReady", or "Status=Quarantined"
+// and the reset was `verified` to leave no resources behind,
+// will update to "Status=Ready", and lease the account
+// to the oldest Pending lease in the waitlist
+func updateDBPostReset(dbSvc db.DBer, snsSvc common.Notificationer, accountID string, verified bool, snsTopicArn string, leaseAddedTopicArn string) error {
+
+	// If the Account.Status=NotReady, change it back to Status=Ready
+	log.Printf("Setting Account Status from NotReady to Ready: %s", accountID)
+	account, err := dbSvc.TransitionAccountStatus(
+		accountID,
+		db.NotReady, db.Ready)
+
+	// Ignore StatusTransitionErrors
+	// (just means the status was NOT previously NotReady")
+	if err != nil {
+		if _, ok := err.(*db.StatusTransitionError); !ok {
+			return err
+		}
+		account, err = dbSvc.GetAccount(accountID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Quarantined accounts are released once a reset deletes the account's
+	// resources, and verifies that no unexpected resources remain.
+	// Dry run resets, and resets without verification, leave them Quarantined.
+	if account.AccountStatus == db.Quarantined {
+		if !verified {
+			log.Printf("Reset of account %s was not verified, and the account will remain Quarantined", accountID)
+		} else {
+			log.Printf("Setting Account Status from Quarantined to Ready: %s", accountID)
+			account, err = dbSvc.TransitionAccountStatus(accountID, db.Quarantined, db.Ready)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if verified && len(account.LeftoverResources) > 0 {
+		account, err = dbSvc.UpdateAccount(db.Account{
+			ID:                accountID,
+			LeftoverResources: []string{},
+		}, []string{"LeftoverResources"})
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Notifying Reset Topic that the account is complete for: %s", accountID)
+	snsMessage, err := common.PrepareSNSMessageJSON(account)
+	if err != nil {
+		log.Printf("Failed to create SNS account-created message for %s: %s", accountID, err)
+		return err
+	}
+	log.Print(snsMessage)
+	_, err = snsSvc.PublishMessage(aws.String(snsTopicArn), aws.String(snsMessage), true)
+	if err != nil {
+		log.Print("Issue in publishing message: %s" + err.Error())
+		return err
+	}
+
+	// Lease the account to the next principal in the waitlist
+	if account.AccountStatus == db.Ready {
+		_, err = waitlist.Fulfill(&waitlist.FulfillInput{
+			DbSvc:              dbSvc,
+			SnsSvc:             snsSvc,
+			AccountID:          accountID,
+			LeaseAddedTopicArn: leaseAddedTopicArn,
+		})
+		if err != nil {
+			log.Printf("Failed to fulfill pending lease for account %s: %s", accountID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// nukeAccount runs aws-nuke against the account, with the nuke config
+// at configFile, with up to 3 attempts, until ctx is done.
+// The aws-nuke output is copied to output, and the number of
+// attempts is counted in attempts.
+func nukeAccount(ctx context.Context, config *Config, svc *Services, configFile string, isDryRun bool, output io.Writer, attempts *int) error {
+	// Construct Nuke, which copies the aws-nuke output to output
+	nuke := reset.Nuke{Output: output}
+
+	// Configure the NukeAccountInput
+	nukeAccountInput := reset.NukeAccountInput{
+		AccountID:  config.AccountID,
+		RoleName:   config.AdminRoleName,
+		ConfigPath: configFile,
+		NoDryRun:   !isDryRun,
+		Token:      svc.TokenService,
+		Nuke:       nuke,
+	}
+
+	// Nukes based on the configuration file that is generated
+	// Attempt Nuke 3 times in the case not all resources get deleted
+	err := retry.Do(
+		func() error {
+			*attempts++
+			return reset.NukeAccount(ctx, &nukeAccountInput)
+		},
+		retry.Attempts(3),         // Retry 3 times
+		retry.LastErrorOnly(true), // Only return the last error
+		// Stop retrying once the step is cancelled, or times out
+		retry.RetryIf(func(err error) bool { return ctx.Err() == nil }),
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func generateNukeConfig(config *Config, svc *Services, f io.Writer) error {
+	// Verify the nuke template configuration to download file from s3 or to
+	// use the default
+	var templateFile string
+	if config.NukeTemplateBucket != "STUB" && config.NukeTemplateKey != "STUB" {
+		log.Printf("Using Nuke Configuration from S3: %s/%s",
+			config.NukeTemplateBucket, config.NukeTemplateKey)
+
+		// Download the file from S3
+		templateFile = fmt.Sprintf("nuke-config-template-%s.yml", config.AccountID)
+		err := svc.Storage.Download(config.NukeTemplateBucket,
+			config.NukeTemplateKey, templateFile)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to download nuke template at s3://%s/%s to %s",
+				config.NukeTemplateBucket, config.NukeTemplateKey, templateFile)
+		}
+	} else {
+		log.Printf("Using Default Nuke Configuration: %s",
+			config.NukeTemplateDefault)
+
+		// Use default template
+		templateFile = config.NukeTemplateDefault
+	}
+
+	// Templates are named by their base file name,
+	// so the template may be in any directory
+	nukeTemplate, err := template.ParseFiles(templateFile)
+	if err != nil {
+		log.Printf("Failed to generate nuke config for acount %s using template %s: %s",
+			config.AccountID, templateFile, err)
+		return err
+	}
+
+	type templateParams struct {
+		ID              string
+		AdminRole       string
+		PrincipalRole   string
+		PrincipalPolicy string
+		Regions         []string
+	}
+
+	err = nukeTemplate.Execute(f, &templateParams{
+		ID:              config.AccountID,
+		AdminRole:       config.AdminRoleName,
+		PrincipalRole:   config.PrincipalRoleName,
+		PrincipalPolicy: config.PrincipalPolicyName,
+		Regions:         config.AllowedRegions,
+	})
+	if err != nil {
+		log.Printf("Failed to generate nuke config for acount %s using template %s: %s",
+			config.AccountID, templateFile, err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,366 @@
+package resetpipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Optum/dce/pkg/common"
+	commonMocks "github.com/Optum/dce/pkg/common/mocks"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/Optum/dce/pkg/db/mocks"
+	"github.com/Optum/dce/pkg/reset"
+	"github.com/Optum/dce/pkg/resetreport"
+	"github.com/Optum/dce/pkg/resetstatus"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetPipeline(t *testing.T) {
+	t.Run("updateDBPostReset", func(t *testing.T) {
+
+		t.Run("Should change account status from NotReady to Ready", func(t *testing.T) {
+			dbSvc := &mocks.DBer{}
+			snsSvc := &commonMocks.Notificationer{}
+			defer dbSvc.AssertExpectations(t)
+
+			// Should change the Account Status
+			dbSvc.
+				On("TransitionAccountStatus", "111", db.NotReady, db.Ready).
+				Return(&db.Account{}, nil)
+
+			snsSvc.On("PublishMessage",
+				mock.MatchedBy(func(arn *string) bool {
+					return *arn == "Topic"
+				}),
+				mock.MatchedBy(func(message *string) bool {
+					// Parse the message JSON
+					messageObj := unmarshal(t, *message)
+					// `default` and `body` and JSON embedded within the message JSON
+					msgDefault := unmarshal(t, messageObj["default"].(string))
+					msgBody := unmarshal(t, messageObj["Body"].(string))
+
+					assert.Equal(t, msgDefault, msgBody, "SNS default/Body should  match")
+
+					// Check that we're sending the account object
+					assert.Equal(t, "", msgBody["Id"])
+
+					return true
+				}), true,
+			).Return(aws.String("mock message"), nil)
+			defer snsSvc.AssertExpectations(t)
+
+			err := updateDBPostReset(dbSvc, snsSvc, "111", true, "Topic", "LeaseAddedTopic")
+			dbSvc.AssertNumberOfCalls(t, "TransitionLeaseStatus", 0)
+			dbSvc.AssertNumberOfCalls(t, "TransitionAccountStatus", 1)
+			require.Nil(t, err)
+		})
+
+		t.Run("Should not change account status of Leased accounts", func(t *testing.T) {
+			dbSvc := &mocks.DBer{}
+			snsSvc := &commonMocks.Notificationer{}
+			defer dbSvc.AssertExpectations(t)
+
+			// Mock Account status change, so it returns an error
+			// saying that the Account was not in "NotReady" state.
+			// We'll just ignore this error, because it means
+			// we don't want to change status at all.
+			dbSvc.
+				On("TransitionAccountStatus", "111", db.NotReady, db.Ready).
+				Return(nil, &db.StatusTransitionError{})
+
+			dbSvc.
+				On("GetAccount", "111").
+				Return(&db.Account{}, nil)
+
+			snsSvc.On("PublishMessage",
+				mock.MatchedBy(func(arn *string) bool {
+					return *arn == "Topic"
+				}),
+				mock.MatchedBy(func(message *string) bool {
+					// Parse the message JSON
+					messageObj := unmarshal(t, *message)
+					// `default` and `body` and JSON embedded within the message JSON
+					msgDefault := unmarshal(t, messageObj["default"].(string))
+					msgBody := unmarshal(t, messageObj["Body"].(string))
+
+					assert.Equal(t, msgDefault, msgBody, "SNS default/Body should  match")
+
+					// Check that we're sending the account object
+					assert.Equal(t, "", msgBody["Id"])
+
+					return true
+				}), true,
+			).Return(aws.String("mock message"), nil)
+			defer snsSvc.AssertExpectations(t)
+
+			err := updateDBPostReset(dbSvc, snsSvc, "111", true, "Topic", "LeaseAddedTopic")
+			dbSvc.AssertNumberOfCalls(t, "TransitionLeaseStatus", 0)
+			dbSvc.AssertNumberOfCalls(t, "TransitionAccountStatus", 1)
+			require.Nil(t, err)
+		})
+
+		t.Run("Should lease the account to the oldest pending lease", func(t *testing.T) {
+			dbSvc := db.NewMemoryDB(7)
+			snsSvc := &commonMocks.Notificationer{}
+			defer snsSvc.AssertExpectations(t)
+
+			require.Nil(t, dbSvc.PutAccount(db.Account{ID: "111", AccountStatus: db.NotReady}))
+			for i, principalID := range []string{"jdoe", "jsmith"} {
+				_, err := dbSvc.UpsertLease(db.Lease{
+					ID:               principalID + "-lease",
+					AccountID:        db.PendingLeaseAccountID,
+					PrincipalID:      principalID,
+					LeaseStatus:      db.Pending,
+					CreatedOn:        int64(1000 + i),
+					ExpiresOn:        int64(2000 + i),
+					PendingExpiresOn: time.Now().Add(time.Hour).Unix(),
+				})
+				require.Nil(t, err)
+			}
+
+			snsSvc.On("PublishMessage", aws.String("Topic"), mock.Anything, true).
+				Return(aws.String("mock message"), nil)
+			snsSvc.On("PublishMessage", aws.String("LeaseAddedTopic"), mock.Anything, true).
+				Return(aws.String("mock message"), nil)
+
+			err := updateDBPostReset(dbSvc, snsSvc, "111", true, "Topic", "LeaseAddedTopic")
+			require.Nil(t, err)
+
+			account, err := dbSvc.GetAccount("111")
+			require.Nil(t, err)
+			require.Equal(t, db.Leased, account.AccountStatus)
+
+			lease, err := dbSvc.GetLeaseByID("jdoe-lease")
+			require.Nil(t, err)
+			require.Equal(t, "111", lease.AccountID)
+			require.Equal(t, db.Active, lease.LeaseStatus)
+
+			lease, err = dbSvc.GetLeaseByID("jsmith-lease")
+			require.Nil(t, err)
+			require.Equal(t, db.Pending, lease.LeaseStatus)
+		})
+
+		t.Run("Should handle DB errors (TransitionAccountStatus)", func(t *testing.T) {
+			snsSvc := &commonMocks.Notificationer{}
+			dbSvc := &mocks.DBer{}
+			defer dbSvc.AssertExpectations(t)
+
+			// Mock Account status change, so it returns an error
+			// saying that the Account was not in "NotReady" state.
+			// We'll just ignore this error, because it means
+			// we don't want to change status at all.
+			dbSvc.
+				On("TransitionAccountStatus", "111", db.NotReady, db.Ready).
+				Return(nil, errors.New("test error"))
+
+			err := updateDBPostReset(dbSvc, snsSvc, "111", true, "Topic", "LeaseAddedTopic")
+			dbSvc.AssertNumberOfCalls(t, "TransitionLeaseStatus", 0)
+			dbSvc.AssertNumberOfCalls(t, "TransitionAccountStatus", 1)
+			require.Equal(t, errors.New("test error"), err)
+		})
+	})
+
+	t.Run("Run", func(t *testing.T) {
+
+		newServices := func(dbSvc db.DBer, snsSvc common.Notificationer, queue common.Queue) *Services {
+			tokenSvc := &commonMocks.TokenService{}
+			tokenSvc.On("NewSession", mock.Anything, mock.Anything).
+				Return(session.Must(session.NewSession()), nil)
+			return &Services{
+				TokenService:          tokenSvc,
+				DB:                    dbSvc,
+				SNS:                   snsSvc,
+				ResetReports:          resetreport.NewMemory(),
+				ResetCompleteTopicARN: "Topic",
+				LeaseAddedTopicARN:    "LeaseAddedTopic",
+				ResetStatus: &resetstatus.Tracker{
+					DB:          dbSvc,
+					Queue:       queue,
+					QueueURL:    "reset-queue",
+					MaxAttempts: 3,
+				},
+			}
+		}
+
+		t.Run("should not retry resets which fail after the account is Ready", func(t *testing.T) {
+			dbSvc := db.NewMemoryDB(7)
+			require.Nil(t, dbSvc.PutAccount(db.Account{ID: "111", AccountStatus: db.NotReady}))
+			queue := common.NewMemoryQueue()
+
+			snsSvc := &commonMocks.Notificationer{}
+			defer snsSvc.AssertExpectations(t)
+			snsSvc.On("PublishMessage", aws.String("Topic"), mock.Anything, true).
+				Return(nil, errors.New("sns error"))
+
+			_, err := Run(&Config{AccountID: "111", NukeEnabled: true}, newServices(dbSvc, snsSvc, queue), "build-1")
+			require.NotNil(t, err)
+
+			account, err := dbSvc.GetAccount("111")
+			require.Nil(t, err)
+			require.Equal(t, db.Ready, account.AccountStatus)
+			require.Equal(t, db.ResetFailed, account.ResetStatus.State)
+			require.Zero(t, account.ResetStatus.NextAttemptOn)
+			require.Empty(t, queue.Messages("reset-queue"))
+		})
+
+		t.Run("should retry resets which fail while the account is NotReady", func(t *testing.T) {
+			dbSvc := db.NewMemoryDB(7)
+			require.Nil(t, dbSvc.PutAccount(db.Account{ID: "111", AccountStatus: db.NotReady}))
+			queue := common.NewMemoryQueue()
+
+			config := &Config{AccountID: "111", NukeEnabled: true, Steps: []reset.StepConfig{{Name: "unknown"}}}
+			_, err := Run(config, newServices(dbSvc, &commonMocks.Notificationer{}, queue), "build-1")
+			require.NotNil(t, err)
+
+			account, err := dbSvc.GetAccount("111")
+			require.Nil(t, err)
+			require.Equal(t, db.NotReady, account.AccountStatus)
+			require.Equal(t, db.ResetFailed, account.ResetStatus.State)
+			require.Equal(t, []string{"111"}, queue.Messages("reset-queue"))
+		})
+
+		t.Run("should skip accounts which no longer need a reset", func(t *testing.T) {
+			dbSvc := db.NewMemoryDB(7)
+			require.Nil(t, dbSvc.PutAccount(db.Account{ID: "111", AccountStatus: db.Leased}))
+			queue := common.NewMemoryQueue()
+
+			// Neither the reset steps, nor the reset notification, run
+			svc := newServices(dbSvc, &commonMocks.Notificationer{}, queue)
+			_, err := Run(&Config{AccountID: "111", NukeEnabled: true}, svc, "build-1")
+			require.Nil(t, err)
+			svc.TokenService.(*commonMocks.TokenService).AssertNotCalled(t, "NewSession", mock.Anything, mock.Anything)
+
+			account, err := dbSvc.GetAccount("111")
+			require.Nil(t, err)
+			require.Equal(t, db.Leased, account.AccountStatus)
+			require.Empty(t, queue.Messages("reset-queue"))
+		})
+	})
+
+	t.Run("testNukeConfigGeneration", func(t *testing.T) {
+
+		var b bytes.Buffer
+		config := &Config{
+			AccountID:           "ABC123",
+			AdminRoleName:       "AdminRole",
+			AllowedRegions:      []string{"us-east-1", "us-west-1"},
+			PrincipalRoleName:   "PrincipalRole",
+			PrincipalPolicyName: "PrincipalPolicy",
+			NukeTemplateDefault: "../../cmd/codebuild/reset/default-nuke-config-template.yml",
+			NukeTemplateBucket:  "STUB",
+			NukeTemplateKey:     "STUB",
+		}
+
+		err := generateNukeConfig(config, &Services{}, &b)
+		assert.NoError(t, err)
+
+		got := b.String()
+		want := "regions:\n  - \"global\"\n  # DCE Principals roles are currently locked down\n  # to only access these two regions\n  # This significantly reduces the run time of nuke.\n  - \"us-east-1\"\n  - \"us-west-1\"\n\naccount-blacklist:\n  - \"999999999999\" # Arbitrary production account id\n\nresource-types:\n  excludes:\n    - S3Object # Let the S3Bucket delete all Objects instead of individual objects (optimization)\n\naccounts:\n  \"ABC123\": # Child Account\n    filters:\n      IAMPolicy:\n        - type: \"contains\"\n          value: \"PrincipalPolicy\"\n      IAMRole:\n        - \"AdminRole\"\n        - \"PrincipalRole\"\n      IAMRolePolicy:\n        - type: \"contains\"\n          value: \"AdminRole\"\n        - type: \"contains\"\n          value: \"PrincipalRole\"\n        - type: \"contains\"\n          value: \"PrincipalPolicy\"\n      IAMRolePolicyAttachment:\n        # Do not remove the policy from the principal user role\n        - \"PrincipalRole -> PrincipalPolicy\"\n"
+		assert.Equal(t, got, want, "Template subsitition works")
+	})
+
+	t.Run("quarantineAccount", func(t *testing.T) {
+
+		t.Run("should quarantine NotReady accounts, with leftover resources", func(t *testing.T) {
+			dbSvc := db.NewMemoryDB(7)
+			require.Nil(t, dbSvc.PutAccount(db.Account{ID: "111", AccountStatus: db.NotReady}))
+
+			err := quarantineAccount(dbSvc, "111", []string{"us-east-1 - EC2Instance - i-1"})
+			require.Nil(t, err)
+
+			account, err := dbSvc.GetAccount("111")
+			require.Nil(t, err)
+			require.Equal(t, db.Quarantined, account.AccountStatus)
+			require.Equal(t, []string{"us-east-1 - EC2Instance - i-1"}, account.LeftoverResources)
+		})
+
+		t.Run("should not change the status of Leased accounts", func(t *testing.T) {
+			dbSvc := db.NewMemoryDB(7)
+			require.Nil(t, dbSvc.PutAccount(db.Account{ID: "111", AccountStatus: db.Leased}))
+
+			err := quarantineAccount(dbSvc, "111", []string{"us-east-1 - EC2Instance - i-1"})
+			require.Nil(t, err)
+
+			account, err := dbSvc.GetAccount("111")
+			require.Nil(t, err)
+			require.Equal(t, db.Leased, account.AccountStatus)
+			require.Equal(t, []string{"us-east-1 - EC2Instance - i-1"}, account.LeftoverResources)
+		})
+
+		t.Run("should release Quarantined accounts after a clean reset", func(t *testing.T) {
+			dbSvc := db.NewMemoryDB(7)
+			require.Nil(t, dbSvc.PutAccount(db.Account{
+				ID:                "111",
+				AccountStatus:     db.Quarantined,
+				LeftoverResources: []string{"us-east-1 - EC2Instance - i-1"},
+			}))
+
+			err := updateDBPostReset(dbSvc, common.NewMemoryNotifier(), "111", true, "Topic", "LeaseAddedTopic")
+			require.Nil(t, err)
+
+			account, err := dbSvc.GetAccount("111")
+			require.Nil(t, err)
+			require.Equal(t, db.Ready, account.AccountStatus)
+			require.Empty(t, account.LeftoverResources)
+		})
+
+		t.Run("should not release Quarantined accounts after an unverified reset", func(t *testing.T) {
+			dbSvc := db.NewMemoryDB(7)
+			require.Nil(t, dbSvc.PutAccount(db.Account{
+				ID:                "111",
+				AccountStatus:     db.Quarantined,
+				LeftoverResources: []string{"us-east-1 - EC2Instance - i-1"},
+			}))
+
+			// eg. a dry run reset, or a reset with verification disabled
+			err := updateDBPostReset(dbSvc, common.NewMemoryNotifier(), "111", false, "Topic", "LeaseAddedTopic")
+			require.Nil(t, err)
+
+			account, err := dbSvc.GetAccount("111")
+			require.Nil(t, err)
+			require.Equal(t, db.Quarantined, account.AccountStatus)
+			require.Equal(t, []string{"us-east-1 - EC2Instance - i-1"}, account.LeftoverResources)
+		})
+	})
+
+	t.Run("addStepResults", func(t *testing.T) {
+		nuke := &nukeStep{log: reset.NewNukeLog(), attempts: 2}
+		_, _ = nuke.log.Write([]byte(
+			"us-east-1 - EC2Instance - i-1 - would remove\n" +
+				"us-east-1 - EC2Instance - i-1 - removed\n" +
+				"us-east-1 - IAMRole - OrganizationAccountAccessRole - filtered by config\n",
+		))
+		report := resetreport.NewReport("123", "", false)
+
+		addStepResults(report, []*reset.StepResult{
+			{Name: "rds-backups", Status: reset.StepSucceeded, Resources: []string{"snapshot-1"}, Duration: 2 * time.Second},
+			{Name: "aws-nuke", Status: reset.StepFailed, Resources: []string{"us-east-1 - EC2Instance - i-1"}, Error: "nuke failed"},
+		}, nuke)
+
+		require.Equal(t, []resetreport.StepResult{
+			{Name: "rds-backups", Status: "Succeeded", ResourceCount: 1, DurationSeconds: 2},
+			{Name: "aws-nuke", Status: "Failed", ResourceCount: 1, Error: "nuke failed"},
+		}, report.Steps)
+		require.Equal(t, []string{"snapshot-1", "us-east-1 - EC2Instance - i-1"}, report.ResourcesFound)
+		require.Equal(t, []string{"snapshot-1", "us-east-1 - EC2Instance - i-1"}, report.ResourcesDeleted)
+		require.Equal(t, []string{"us-east-1 - IAMRole - OrganizationAccountAccessRole"}, report.ResourcesFiltered)
+		require.Equal(t, 2, report.NukeAttempts)
+	})
+}
+
+func unmarshal(t *testing.T, jsonStr string) map[string]interface{} {
+	var data map[string]interface{}
+	err := json.Unmarshal([]byte(jsonStr), &data)
+	require.Nil(t, err,
+		fmt.Sprintf("Failed to unmarshal JSON: %s; %s", jsonStr, err),
+	)
+
+	return data
+}
@@ -0,0 +1,73 @@
+package resetreport
+
+import (
+	"sort"
+	"sync"
+)
+
+// Memory is an in-memory implementation of the reset report Service,
+// for local runs and tests.
+type Memory struct {
+	mu      sync.RWMutex
+	reports map[string]map[string]Report
+}
+
+// NewMemory creates an empty in-memory reset report store
+func NewMemory() *Memory {
+	return &Memory{
+		reports: map[string]map[string]Report{},
+	}
+}
+
+// PutReport saves a report
+func (m *Memory) PutReport(report *Report) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reports[report.AccountID] == nil {
+		m.reports[report.AccountID] = map[string]Report{}
+	}
+	m.reports[report.AccountID][report.RunID] = copyReport(report)
+	return nil
+}
+
+// GetReports returns all reset reports for an account, newest first
+func (m *Memory) GetReports(accountID string) ([]*Report, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reports := []*Report{}
+	for _, report := range m.reports[accountID] {
+		r := copyReport(&report)
+		reports = append(reports, &r)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].RunID > reports[j].RunID
+	})
+	return reports, nil
+}
+
+// GetReport returns a single reset report, or nil if it does not exist
+func (m *Memory) GetReport(accountID string, runID string) (*Report, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	report, ok := m.reports[accountID][runID]
+	if !ok {
+		return nil, nil
+	}
+	r := copyReport(&report)
+	return &r, nil
+}
+
+// copyReport copies a report, so that stored reports
+// are not modified by callers
+func copyReport(report *Report) Report {
+	r := *report
+	r.Steps = append([]StepResult{}, report.Steps...)
+	r.ResourcesFound = append([]string{}, report.ResourcesFound...)
+	r.ResourcesDeleted = append([]string{}, report.ResourcesDeleted...)
+	r.ResourcesFailed = append([]string{}, report.ResourcesFailed...)
+	r.ResourcesFiltered = append([]string{}, report.ResourcesFiltered...)
+	return r
+}
@@ -0,0 +1,288 @@
+// Package resetreport stores a report of each account reset run,
+// including the resources found and deleted, and the outcome.
+//
+// Reports are stored in the ResetReports table,
+// keyed by account ID and run ID.
+package resetreport
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Optum/dce/pkg/common"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// MaxResources is the maximum number of resources kept in each
+// resource list of a report, to stay within DynamoDB item size limits.
+// Resource counts include all resources.
+const MaxResources = 500
+
+// Report is the result of a single reset run for an account
+type Report struct {
+	AccountID       string  `json:"AccountId"`       // AWS Account ID
+	RunID           string  `json:"RunId"`           // ID of the reset run, sortable by start time
+	BuildID         string  `json:"BuildId"`         // ID of the CodeBuild build which ran the reset
+	StartedOn       int64   `json:"StartedOn"`       // Epoch timestamp
+	CompletedOn     int64   `json:"CompletedOn"`     // Epoch timestamp
+	DurationSeconds int64   `json:"DurationSeconds"` // Duration of the reset run
+	Outcome         Outcome `json:"Outcome"`         // Whether the reset succeeded
+	Error           string  `json:"Error"`           // Error which failed the reset
+	DryRun          bool    `json:"DryRun"`          // Resources were listed, but not deleted
+	// Number of times aws-nuke ran, including retries
+	NukeAttempts int `json:"NukeAttempts"`
+	// Results of each reset step, in order
+	Steps []StepResult `json:"Steps"`
+	// Number of resources in each state, including truncated resources
+	ResourceCounts ResourceCounts `json:"ResourceCounts"`
+	// Resources to be deleted
+	ResourcesFound []string `json:"ResourcesFound"`
+	// Resources which were deleted
+	ResourcesDeleted []string `json:"ResourcesDeleted"`
+	// Resources which could not be deleted
+	ResourcesFailed []string `json:"ResourcesFailed"`
+	// Resources which were kept, because of aws-nuke filters
+	ResourcesFiltered []string `json:"ResourcesFiltered"`
+	// True if any resource list was truncated to MaxResources
+	ResourcesTruncated bool `json:"ResourcesTruncated"`
+}
+
+// StepResult is the result of a reset step
+type StepResult struct {
+	Name            string `json:"Name"`
+	Status          string `json:"Status"`
+	ResourceCount   int    `json:"ResourceCount"`
+	Error           string `json:"Error"`
+	DurationSeconds int64  `json:"DurationSeconds"`
+}
+
+// ResourceCounts is the number of resources in each state
+type ResourceCounts struct {
+	Found    int `json:"Found"`
+	Deleted  int `json:"Deleted"`
+	Failed   int `json:"Failed"`
+	Filtered int `json:"Filtered"`
+}
+
+// Outcome is the final result of a reset run
+type Outcome string
+
+const (
+	// Succeeded means all reset steps completed,
+	// and the account was returned to the account pool
+	Succeeded Outcome = "Succeeded"
+	// Failed means a reset step failed, and the account stays NotReady
+	Failed Outcome = "Failed"
+	// Running means the reset run has started, and has not completed
+	Running Outcome = "Running"
+)
+
+// NewReport creates a report for a reset run, starting now
+func NewReport(accountID string, buildID string, dryRun bool) *Report {
+	now := time.Now()
+	return &Report{
+		AccountID:         accountID,
+		RunID:             NewRunID(now),
+		BuildID:           buildID,
+		StartedOn:         now.Unix(),
+		DryRun:            dryRun,
+		Steps:             []StepResult{},
+		ResourcesFound:    []string{},
+		ResourcesDeleted:  []string{},
+		ResourcesFailed:   []string{},
+		ResourcesFiltered: []string{},
+	}
+}
+
+// NewRunID creates a unique run ID, which sorts by start time
+func NewRunID(start time.Time) string {
+	return start.UTC().Format("20060102T150405Z") + "-" + strings.Split(uuid.New().String(), "-")[0]
+}
+
+// AddResources adds resources in each state to the report
+func (r *Report) AddResources(found []string, deleted []string, failed []string, filtered []string) {
+	r.ResourceCounts.Found += len(found)
+	r.ResourceCounts.Deleted += len(deleted)
+	r.ResourceCounts.Failed += len(failed)
+	r.ResourceCounts.Filtered += len(filtered)
+
+	r.ResourcesFound = r.appendResources(r.ResourcesFound, found)
+	r.ResourcesDeleted = r.appendResources(r.ResourcesDeleted, deleted)
+	r.ResourcesFailed = r.appendResources(r.ResourcesFailed, failed)
+	r.ResourcesFiltered = r.appendResources(r.ResourcesFiltered, filtered)
+}
+
+// appendResources appends resources to a list, up to MaxResources
+func (r *Report) appendResources(list []string, resources []string) []string {
+	for _, resource := range resources {
+		if len(list) >= MaxResources {
+			r.ResourcesTruncated = true
+			break
+		}
+		list = append(list, resource)
+	}
+	return list
+}
+
+// Complete sets the outcome of the reset run, from the error
+// which failed the reset (if any)
+func (r *Report) Complete(err error) {
+	now := time.Now()
+	r.CompletedOn = now.Unix()
+	r.DurationSeconds = r.CompletedOn - r.StartedOn
+	if err != nil {
+		r.Outcome = Failed
+		r.Error = err.Error()
+	} else {
+		r.Outcome = Succeeded
+	}
+}
+
+// Summary returns a copy of the report without resource lists
+func (r *Report) Summary() *Report {
+	summary := *r
+	summary.ResourcesFound = []string{}
+	summary.ResourcesDeleted = []string{}
+	summary.ResourcesFailed = []string{}
+	summary.ResourcesFiltered = []string{}
+	return &summary
+}
+
+// The Service interface includes all methods used by the DB struct to interact with
+// ResetReports DynamoDB. This is useful if we want to mock the DB service.
+type Service interface {
+	PutReport(report *Report) error
+	GetReports(accountID string) ([]*Report, error)
+	GetReport(accountID string, runID string) (*Report, error)
+}
+
+// DB contains DynamoDB client and table names
+type DB struct {
+	// DynamoDB Client
+	Client *dynamodb.DynamoDB
+	// Name of the ResetReports table
+	ResetReportsTableName string
+	// Use Consistent Reads when querying
+	ConsistentRead bool
+}
+
+// PutReport saves a report to the ResetReports DB
+func (db *DB) PutReport(report *Report) error {
+	item, err := dynamodbattribute.MarshalMap(report)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal reset report %s for %s: %s", report.RunID, report.AccountID, err)
+	}
+
+	_, err = db.Client.PutItem(
+		&dynamodb.PutItemInput{
+			TableName: aws.String(db.ResetReportsTableName),
+			Item:      item,
+		},
+	)
+	return err
+}
+
+// GetReports returns all reset reports for an account, newest first
+func (db *DB) GetReports(accountID string) ([]*Report, error) {
+	reports := []*Report{}
+	var startKey map[string]*dynamodb.AttributeValue
+
+	for {
+		res, err := db.Client.Query(&dynamodb.QueryInput{
+			TableName: aws.String(db.ResetReportsTableName),
+			KeyConditions: map[string]*dynamodb.Condition{
+				"AccountId": {
+					ComparisonOperator: aws.String("EQ"),
+					AttributeValueList: []*dynamodb.AttributeValue{
+						{S: aws.String(accountID)},
+					},
+				},
+			},
+			ExclusiveStartKey: startKey,
+			ScanIndexForward:  aws.Bool(false),
+			ConsistentRead:    aws.Bool(db.ConsistentRead),
+		})
+		if err != nil {
+			log.Printf("Failed to query reset reports for %s: %s", accountID, err)
+			return nil, err
+		}
+
+		for _, item := range res.Items {
+			report := Report{}
+			err := dynamodbattribute.UnmarshalMap(item, &report)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to unmarshal reset report for %s: %s", accountID, err)
+			}
+			reports = append(reports, &report)
+		}
+
+		if len(res.LastEvaluatedKey) == 0 {
+			break
+		}
+		startKey = res.LastEvaluatedKey
+	}
+
+	return reports, nil
+}
+
+// GetReport returns a single reset report, or nil if it does not exist
+func (db *DB) GetReport(accountID string, runID string) (*Report, error) {
+	res, err := db.Client.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(db.ResetReportsTableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"AccountId": {S: aws.String(accountID)},
+			"RunId":     {S: aws.String(runID)},
+		},
+		ConsistentRead: aws.Bool(db.ConsistentRead),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Item) == 0 {
+		return nil, nil
+	}
+
+	report := &Report{}
+	err = dynamodbattribute.UnmarshalMap(res.Item, report)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal reset report %s for %s: %s", runID, accountID, err)
+	}
+	return report, nil
+}
+
+// New creates a new reset report DB Service struct,
+// with all the necessary fields configured.
+func New(client *dynamodb.DynamoDB, resetReportsTableName string) *DB {
+	return &DB{
+		Client:                client,
+		ResetReportsTableName: resetReportsTableName,
+		ConsistentRead:        false,
+	}
+}
+
+/*
+NewFromEnv creates a DB instance configured from environment variables.
+Requires env vars for:
+
+- AWS_CURRENT_REGION
+- RESET_REPORTS_DB
+*/
+func NewFromEnv() (*DB, error) {
+	awsSession, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return New(
+		dynamodb.New(
+			awsSession,
+			aws.NewConfig().WithRegion(common.RequireEnv("AWS_CURRENT_REGION")),
+		),
+		common.RequireEnv("RESET_REPORTS_DB"),
+	), nil
+}
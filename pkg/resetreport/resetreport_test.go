@@ -0,0 +1,99 @@
+package resetreport
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var _ Service = &DB{}
+var _ Service = &Memory{}
+
+func TestReport(t *testing.T) {
+
+	t.Run("NewReport", func(t *testing.T) {
+		report := NewReport("123456789012", "reset-build:abc", true)
+		require.Regexp(t, regexp.MustCompile(`^\d{8}T\d{6}Z-[0-9a-f]{8}$`), report.RunID)
+		require.Equal(t, "123456789012", report.AccountID)
+		require.True(t, report.DryRun)
+		require.NotZero(t, report.StartedOn)
+	})
+
+	t.Run("AddResources", func(t *testing.T) {
+
+		t.Run("should add resources and counts", func(t *testing.T) {
+			report := NewReport("123456789012", "", false)
+			report.AddResources([]string{"a", "b"}, []string{"a"}, []string{"b"}, nil)
+			report.AddResources([]string{"c"}, []string{"c"}, nil, []string{"d"})
+
+			require.Equal(t, []string{"a", "b", "c"}, report.ResourcesFound)
+			require.Equal(t, []string{"a", "c"}, report.ResourcesDeleted)
+			require.Equal(t, []string{"b"}, report.ResourcesFailed)
+			require.Equal(t, []string{"d"}, report.ResourcesFiltered)
+			require.Equal(t, ResourceCounts{Found: 3, Deleted: 2, Failed: 1, Filtered: 1}, report.ResourceCounts)
+			require.False(t, report.ResourcesTruncated)
+		})
+
+		t.Run("should truncate resource lists", func(t *testing.T) {
+			report := NewReport("123456789012", "", false)
+			found := []string{}
+			for i := 0; i < MaxResources+10; i++ {
+				found = append(found, fmt.Sprintf("resource-%d", i))
+			}
+			report.AddResources(found, nil, nil, nil)
+
+			require.Len(t, report.ResourcesFound, MaxResources)
+			require.Equal(t, MaxResources+10, report.ResourceCounts.Found)
+			require.True(t, report.ResourcesTruncated)
+		})
+
+	})
+
+	t.Run("Complete", func(t *testing.T) {
+		report := NewReport("123456789012", "", false)
+		report.Complete(nil)
+		require.Equal(t, Succeeded, report.Outcome)
+
+		report.Complete(errors.New("nuke failed"))
+		require.Equal(t, Failed, report.Outcome)
+		require.Equal(t, "nuke failed", report.Error)
+	})
+}
+
+func TestMemory(t *testing.T) {
+	store := NewMemory()
+	older := &Report{AccountID: "123456789012", RunID: "20191101T000000Z-aaaaaaaa"}
+	newer := &Report{AccountID: "123456789012", RunID: "20191102T000000Z-bbbbbbbb", ResourcesFound: []string{"a"}}
+	require.Nil(t, store.PutReport(older))
+	require.Nil(t, store.PutReport(newer))
+	require.Nil(t, store.PutReport(&Report{AccountID: "000000000000", RunID: "20191103T000000Z-cccccccc"}))
+
+	t.Run("should list reports newest first", func(t *testing.T) {
+		reports, err := store.GetReports("123456789012")
+		require.Nil(t, err)
+		require.Len(t, reports, 2)
+		require.Equal(t, newer.RunID, reports[0].RunID)
+		require.Equal(t, older.RunID, reports[1].RunID)
+	})
+
+	t.Run("should get a report", func(t *testing.T) {
+		report, err := store.GetReport("123456789012", newer.RunID)
+		require.Nil(t, err)
+		require.Equal(t, []string{"a"}, report.ResourcesFound)
+
+		// Stored reports should not be modified by callers
+		report.ResourcesFound[0] = "b"
+		report, err = store.GetReport("123456789012", newer.RunID)
+		require.Nil(t, err)
+		require.Equal(t, []string{"a"}, report.ResourcesFound)
+	})
+
+	t.Run("should return nil for missing reports", func(t *testing.T) {
+		report, err := store.GetReport("123456789012", "missing")
+		require.Nil(t, err)
+		require.Nil(t, report)
+	})
+}
@@ -0,0 +1,214 @@
+// Package resetstatus tracks the status of account resets
+// on the account record, and retries failed resets.
+package resetstatus
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/db"
+)
+
+const (
+	// DefaultMaxAttempts is the number of consecutive failed resets,
+	// after which an account's reset status is set to Alarm
+	DefaultMaxAttempts = 3
+	// retryDelay is the delay before the first retry of a failed reset.
+	// The delay doubles with each failed attempt.
+	retryDelay = 5 * time.Minute
+	// maxRetryDelay is the longest delay allowed for an SQS message
+	maxRetryDelay = 15 * time.Minute
+)
+
+// Tracker updates the reset status of accounts
+type Tracker struct {
+	DB db.DBer
+	// Queue and QueueURL are the reset queue,
+	// where failed resets are re-enqueued
+	Queue    common.Queue
+	QueueURL string
+	// MaxAttempts is the number of consecutive failed resets,
+	// after which the reset is not retried
+	MaxAttempts int
+}
+
+/*
+NewTrackerFromEnv creates a Tracker from environment variables
+
+Requires env vars for:
+
+- RESET_SQS_URL
+
+Optional env vars:
+
+- RESET_MAX_ATTEMPTS (default 3)
+*/
+func NewTrackerFromEnv(dbSvc db.DBer, queue common.Queue) (*Tracker, error) {
+	maxAttempts, err := strconv.Atoi(common.GetEnv("RESET_MAX_ATTEMPTS", strconv.Itoa(DefaultMaxAttempts)))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid RESET_MAX_ATTEMPTS: %s", err)
+	}
+	return &Tracker{
+		DB:          dbSvc,
+		Queue:       queue,
+		QueueURL:    common.RequireEnv("RESET_SQS_URL"),
+		MaxAttempts: maxAttempts,
+	}, nil
+}
+
+// Queued sets the account's reset status to Queued,
+// once a reset build has been started.
+// If the status is already tracking the build (eg. the build is running,
+// or has already finished), the status is not changed.
+func (t *Tracker) Queued(accountID string, buildID string) (*db.ResetStatus, error) {
+	prev, err := t.get(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if prev.BuildID != "" && prev.BuildID == buildID {
+		return prev, nil
+	}
+	return t.put(accountID, db.ResetStatus{
+		State:    db.ResetQueued,
+		BuildID:  buildID,
+		Attempts: prev.Attempts,
+	})
+}
+
+// Running sets the account's reset status to Running
+func (t *Tracker) Running(accountID string, buildID string) (*db.ResetStatus, error) {
+	prev, err := t.get(accountID)
+	if err != nil {
+		return nil, err
+	}
+	return t.put(accountID, db.ResetStatus{
+		State:    db.ResetRunning,
+		BuildID:  buildID,
+		Attempts: prev.Attempts,
+	})
+}
+
+// Succeeded sets the account's reset status to Succeeded,
+// and resets the count of failed attempts
+func (t *Tracker) Succeeded(accountID string, buildID string) (*db.ResetStatus, error) {
+	return t.put(accountID, db.ResetStatus{
+		State:   db.ResetSucceeded,
+		BuildID: buildID,
+	})
+}
+
+// Failed records a failed reset.
+//
+// If retry is true, the account is sent back to the reset queue,
+// with a delay which doubles after each consecutive failure.
+// After MaxAttempts consecutive failures, the status is set to Alarm,
+// and the reset is not retried.
+func (t *Tracker) Failed(accountID string, buildID string, resetErr error, retry bool) (*db.ResetStatus, error) {
+	prev, err := t.get(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := db.ResetStatus{
+		State:     db.ResetFailed,
+		BuildID:   buildID,
+		Attempts:  prev.Attempts + 1,
+		LastError: resetErr.Error(),
+	}
+	if !retry {
+		return t.put(accountID, status)
+	}
+	if status.Attempts >= t.MaxAttempts {
+		log.Printf("ALARM: Reset of account %s failed %d times in a row, and will not be retried: %s",
+			accountID, status.Attempts, resetErr)
+		status.State = db.ResetAlarm
+		return t.put(accountID, status)
+	}
+
+	delay := RetryDelay(status.Attempts)
+	status.NextAttemptOn = time.Now().Add(delay).Unix()
+	log.Printf("Retrying reset of account %s in %s (attempt %d of %d)",
+		accountID, delay, status.Attempts+1, t.MaxAttempts)
+	err = t.Queue.SendMessageWithDelay(&t.QueueURL, &accountID, int64(delay.Seconds()))
+	if err != nil {
+		// Save the failure, so the account does not appear to be
+		// still resetting
+		err = fmt.Errorf("Failed to re-enqueue account %s for reset: %s", accountID, err)
+		status.LastError = fmt.Sprintf("%s; %s", status.LastError, err)
+		_, putErr := t.put(accountID, status)
+		if putErr != nil {
+			log.Printf("Failed to update reset status for account %s: %s", accountID, putErr)
+		}
+		return nil, err
+	}
+	return t.put(accountID, status)
+}
+
+// RetryDelay is the delay before retrying a reset
+// which failed the given number of times in a row
+func RetryDelay(attempts int) time.Duration {
+	delay := retryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// ShouldEnqueue returns false if the account's reset should not be
+// enqueued: either the reset is in Alarm, or a failed reset is
+// already waiting to be retried
+func ShouldEnqueue(account *db.Account, now time.Time) bool {
+	status := account.ResetStatus
+	if status == nil {
+		return true
+	}
+	switch status.State {
+	case db.ResetAlarm:
+		return false
+	case db.ResetFailed:
+		return status.NextAttemptOn <= now.Unix()
+	}
+	return true
+}
+
+// NeedsReset returns true if the account is waiting to be reset.
+// Only NotReady and Quarantined accounts are reset: accounts with any
+// other status were already reset, or are leased.
+func NeedsReset(account *db.Account) bool {
+	return account.AccountStatus == db.NotReady ||
+		account.AccountStatus == db.Quarantined
+}
+
+// get returns the account's current reset status
+func (t *Tracker) get(accountID string) (*db.ResetStatus, error) {
+	account, err := t.DB.GetAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, fmt.Errorf("Account %s does not exist", accountID)
+	}
+	if account.ResetStatus == nil {
+		return &db.ResetStatus{}, nil
+	}
+	return account.ResetStatus, nil
+}
+
+// put saves the account's reset status
+func (t *Tracker) put(accountID string, status db.ResetStatus) (*db.ResetStatus, error) {
+	status.LastModifiedOn = time.Now().Unix()
+	account, err := t.DB.UpdateAccount(db.Account{
+		ID:          accountID,
+		ResetStatus: &status,
+	}, []string{"ResetStatus"})
+	if err != nil {
+		return nil, err
+	}
+	return account.ResetStatus, nil
+}
@@ -0,0 +1,181 @@
+package resetstatus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Optum/dce/pkg/common"
+	commonMocks "github.com/Optum/dce/pkg/common/mocks"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/require"
+)
+
+func newTracker(t *testing.T) (*Tracker, *common.MemoryQueue) {
+	dbSvc := db.NewMemoryDB(7)
+	require.Nil(t, dbSvc.PutAccount(db.Account{ID: "123456789012", AccountStatus: db.NotReady}))
+	queue := common.NewMemoryQueue()
+	return &Tracker{
+		DB:          dbSvc,
+		Queue:       queue,
+		QueueURL:    "reset-queue",
+		MaxAttempts: 3,
+	}, queue
+}
+
+func TestTracker(t *testing.T) {
+
+	t.Run("should track a successful reset", func(t *testing.T) {
+		tracker, _ := newTracker(t)
+
+		status, err := tracker.Queued("123456789012", "build-1")
+		require.Nil(t, err)
+		require.Equal(t, db.ResetQueued, status.State)
+		require.Equal(t, "build-1", status.BuildID)
+
+		status, err = tracker.Running("123456789012", "build-1")
+		require.Nil(t, err)
+		require.Equal(t, db.ResetRunning, status.State)
+
+		// Queueing a running build should not change the status
+		status, err = tracker.Queued("123456789012", "build-1")
+		require.Nil(t, err)
+		require.Equal(t, db.ResetRunning, status.State)
+
+		_, err = tracker.Succeeded("123456789012", "build-1")
+		require.Nil(t, err)
+		account, err := tracker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+		require.Equal(t, db.ResetSucceeded, account.ResetStatus.State)
+		require.Equal(t, "build-1", account.ResetStatus.BuildID)
+		require.NotZero(t, account.ResetStatus.LastModifiedOn)
+	})
+
+	t.Run("should retry failed resets, and alarm after MaxAttempts", func(t *testing.T) {
+		tracker, queue := newTracker(t)
+
+		status, err := tracker.Failed("123456789012", "build-1", errors.New("nuke failed"), true)
+		require.Nil(t, err)
+		require.Equal(t, db.ResetFailed, status.State)
+		require.Equal(t, 1, status.Attempts)
+		require.Equal(t, "nuke failed", status.LastError)
+		require.True(t, status.NextAttemptOn > time.Now().Unix())
+
+		// The account is re-enqueued with a delay
+		require.Equal(t, []string{"123456789012"}, queue.Messages("reset-queue"))
+		res, err := queue.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String("reset-queue"),
+			MaxNumberOfMessages: aws.Int64(10),
+		})
+		require.Nil(t, err)
+		require.Len(t, res.Messages, 0)
+
+		// Attempts are kept while the retry runs
+		status, err = tracker.Running("123456789012", "build-2")
+		require.Nil(t, err)
+		require.Equal(t, 1, status.Attempts)
+
+		status, err = tracker.Failed("123456789012", "build-2", errors.New("nuke failed"), true)
+		require.Nil(t, err)
+		require.Equal(t, db.ResetFailed, status.State)
+		require.Equal(t, 2, status.Attempts)
+		require.Len(t, queue.Messages("reset-queue"), 2)
+
+		status, err = tracker.Failed("123456789012", "build-3", errors.New("nuke failed again"), true)
+		require.Nil(t, err)
+		require.Equal(t, db.ResetAlarm, status.State)
+		require.Equal(t, 3, status.Attempts)
+		require.Equal(t, "nuke failed again", status.LastError)
+		require.Len(t, queue.Messages("reset-queue"), 2)
+
+		// A successful reset clears the alarm
+		status, err = tracker.Succeeded("123456789012", "build-4")
+		require.Nil(t, err)
+		require.Equal(t, db.ResetSucceeded, status.State)
+		require.Equal(t, 0, status.Attempts)
+		require.Equal(t, "", status.LastError)
+	})
+
+	t.Run("should not re-queue a build which already failed", func(t *testing.T) {
+		tracker, _ := newTracker(t)
+
+		_, err := tracker.Failed("123456789012", "build-1", errors.New("nuke failed"), true)
+		require.Nil(t, err)
+
+		// eg. a local build which fails before StartBuild returns
+		status, err := tracker.Queued("123456789012", "build-1")
+		require.Nil(t, err)
+		require.Equal(t, db.ResetFailed, status.State)
+		require.Equal(t, 1, status.Attempts)
+		require.Equal(t, "nuke failed", status.LastError)
+
+		account, err := tracker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+		require.Equal(t, db.ResetFailed, account.ResetStatus.State)
+		require.NotZero(t, account.ResetStatus.NextAttemptOn)
+	})
+
+	t.Run("should not retry, if retry is false", func(t *testing.T) {
+		tracker, queue := newTracker(t)
+
+		status, err := tracker.Failed("123456789012", "build-1", errors.New("resources remain"), false)
+		require.Nil(t, err)
+		require.Equal(t, db.ResetFailed, status.State)
+		require.Equal(t, int64(0), status.NextAttemptOn)
+		require.Empty(t, queue.Messages("reset-queue"))
+	})
+
+	t.Run("should save the failure, if the reset cannot be re-enqueued", func(t *testing.T) {
+		tracker, _ := newTracker(t)
+		queue := &commonMocks.Queue{}
+		queue.On("SendMessageWithDelay", aws.String("reset-queue"), aws.String("123456789012"), int64(300)).
+			Return(errors.New("sqs unavailable"))
+		tracker.Queue = queue
+
+		_, err := tracker.Running("123456789012", "build-1")
+		require.Nil(t, err)
+		_, err = tracker.Failed("123456789012", "build-1", errors.New("nuke failed"), true)
+		require.EqualError(t, err, "Failed to re-enqueue account 123456789012 for reset: sqs unavailable")
+
+		account, err := tracker.DB.GetAccount("123456789012")
+		require.Nil(t, err)
+		require.Equal(t, db.ResetFailed, account.ResetStatus.State)
+		require.Equal(t, 1, account.ResetStatus.Attempts)
+		require.Equal(t, "nuke failed; Failed to re-enqueue account 123456789012 for reset: sqs unavailable",
+			account.ResetStatus.LastError)
+		queue.AssertExpectations(t)
+	})
+
+	t.Run("should return an error for missing accounts", func(t *testing.T) {
+		tracker, _ := newTracker(t)
+
+		_, err := tracker.Running("000000000000", "build-1")
+		require.NotNil(t, err)
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	require.Equal(t, 5*time.Minute, RetryDelay(1))
+	require.Equal(t, 10*time.Minute, RetryDelay(2))
+	require.Equal(t, 15*time.Minute, RetryDelay(3))
+	require.Equal(t, 15*time.Minute, RetryDelay(10))
+}
+
+func TestShouldEnqueue(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	require.True(t, ShouldEnqueue(&db.Account{}, now))
+	require.True(t, ShouldEnqueue(&db.Account{ResetStatus: &db.ResetStatus{State: db.ResetRunning}}, now))
+	require.False(t, ShouldEnqueue(&db.Account{ResetStatus: &db.ResetStatus{State: db.ResetAlarm}}, now))
+	require.False(t, ShouldEnqueue(&db.Account{ResetStatus: &db.ResetStatus{State: db.ResetFailed, NextAttemptOn: 1001}}, now))
+	require.True(t, ShouldEnqueue(&db.Account{ResetStatus: &db.ResetStatus{State: db.ResetFailed, NextAttemptOn: 999}}, now))
+}
+
+func TestNeedsReset(t *testing.T) {
+	require.True(t, NeedsReset(&db.Account{AccountStatus: db.NotReady}))
+	require.True(t, NeedsReset(&db.Account{AccountStatus: db.Quarantined}))
+	require.False(t, NeedsReset(&db.Account{AccountStatus: db.Ready}))
+	require.False(t, NeedsReset(&db.Account{AccountStatus: db.Leased}))
+}
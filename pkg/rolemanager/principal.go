@@ -0,0 +1,259 @@
+package rolemanager
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/Optum/dce/pkg/common"
+)
+
+// PrincipalTrustPolicy returns the default trust policy of the principal role,
+// which lets principals from the master account assume the role.
+//
+// The default is used when no trust policy template is configured.
+// See PrincipalTrustPolicyTemplate.
+func PrincipalTrustPolicy(masterAccountID string) string {
+	return strings.TrimSpace(fmt.Sprintf(`
+		{
+			"Version": "2012-10-17",
+			"Statement": [
+				{
+					"Effect": "Allow",
+					"Principal": {
+						"AWS": "arn:aws:iam::%s:root"
+					},
+					"Action": "sts:AssumeRole",
+					"Condition": {}
+				}
+			]
+		}
+	`, masterAccountID))
+}
+
+// Frozen principal policies, which replace the principal policy
+// of accounts with a frozen lease
+const (
+	// FrozenPolicyDenyAll denies all actions
+	FrozenPolicyDenyAll = "denyAll"
+	// FrozenPolicyReadOnly denies all actions, except for reading
+	// resources and data, so principals may export their data
+	FrozenPolicyReadOnly = "readOnly"
+)
+
+// frozenReadOnlyActions are the actions allowed by FrozenPolicyReadOnly
+const frozenReadOnlyActions = `[
+	"cloudformation:Describe*",
+	"cloudformation:Get*",
+	"cloudformation:List*",
+	"cloudwatch:Describe*",
+	"cloudwatch:Get*",
+	"cloudwatch:List*",
+	"dynamodb:BatchGetItem",
+	"dynamodb:Describe*",
+	"dynamodb:GetItem",
+	"dynamodb:List*",
+	"dynamodb:Query",
+	"dynamodb:Scan",
+	"ec2:Describe*",
+	"lambda:Get*",
+	"lambda:List*",
+	"logs:Describe*",
+	"logs:FilterLogEvents",
+	"logs:Get*",
+	"rds:Describe*",
+	"s3:Get*",
+	"s3:List*",
+	"tag:Get*"
+]`
+
+// FrozenPrincipalPolicy returns the principal policy of accounts
+// with a frozen lease, for the FrozenPolicyDenyAll or FrozenPolicyReadOnly
+// policy. Defaults to FrozenPolicyDenyAll.
+func FrozenPrincipalPolicy(frozenPolicy string) string {
+	if frozenPolicy == FrozenPolicyReadOnly {
+		return strings.TrimSpace(fmt.Sprintf(`
+		{
+			"Version": "2012-10-17",
+			"Statement": [
+				{
+					"Sid": "AllowFrozenLeaseReadOnly",
+					"Effect": "Allow",
+					"Action": %s,
+					"Resource": "*"
+				},
+				{
+					"Sid": "DenyFrozenLease",
+					"Effect": "Deny",
+					"NotAction": %s,
+					"Resource": "*"
+				}
+			]
+		}
+	`, frozenReadOnlyActions, frozenReadOnlyActions))
+	}
+	return strings.TrimSpace(`
+		{
+			"Version": "2012-10-17",
+			"Statement": [
+				{
+					"Sid": "DenyFrozenLease",
+					"Effect": "Deny",
+					"Action": "*",
+					"Resource": "*"
+				}
+			]
+		}
+	`)
+}
+
+// PrincipalTrustPolicyInput is the input for the principal trust policy template
+type PrincipalTrustPolicyInput struct {
+	// AccountID is the ID of the child account
+	AccountID       string
+	MasterAccountID string
+	// IdpArns are the configured IAM identity provider ARNs.
+	// SAMLProviderArns and OIDCProviderArns are the SAML and
+	// OIDC providers, from IdpArns.
+	IdpArns          []string
+	SAMLProviderArns []string
+	OIDCProviderArns []string
+}
+
+// PrincipalTrustPolicyTemplate renders the trust policy of the principal role,
+// from a template stored in S3 (eg. to federate with a SAML or OIDC identity provider)
+type PrincipalTrustPolicyTemplate struct {
+	Storager common.Storager
+	Bucket   string
+	// Key is the S3 key of the template.
+	// If empty, PrincipalTrustPolicy is used.
+	Key     string
+	IdpArns []string
+}
+
+// NewPrincipalTrustPolicyTemplate creates a PrincipalTrustPolicyTemplate.
+// idpArns is a comma-separated list of IAM identity provider ARNs.
+func NewPrincipalTrustPolicyTemplate(storager common.Storager, bucket string, key string, idpArns string) *PrincipalTrustPolicyTemplate {
+	arns := []string{}
+	for _, idpArn := range strings.Split(idpArns, ",") {
+		idpArn = strings.TrimSpace(idpArn)
+		if idpArn != "" {
+			arns = append(arns, idpArn)
+		}
+	}
+	return &PrincipalTrustPolicyTemplate{
+		Storager: storager,
+		Bucket:   bucket,
+		Key:      key,
+		IdpArns:  arns,
+	}
+}
+
+// Render returns the trust policy of the principal role in the account,
+// and the hash of the rendered policy.
+//
+// The hash changes with the rendered policy (unlike the ETag of the
+// template), so it may be used to check whether the trust policy
+// of the role is up to date. A nil template renders PrincipalTrustPolicy.
+func (t *PrincipalTrustPolicyTemplate) Render(accountID string, masterAccountID string) (string, string, error) {
+	policy := PrincipalTrustPolicy(masterAccountID)
+	if t != nil && t.Key != "" {
+		input := PrincipalTrustPolicyInput{
+			AccountID:        accountID,
+			MasterAccountID:  masterAccountID,
+			IdpArns:          t.IdpArns,
+			SAMLProviderArns: []string{},
+			OIDCProviderArns: []string{},
+		}
+		for _, idpArn := range t.IdpArns {
+			if strings.Contains(idpArn, ":saml-provider/") {
+				input.SAMLProviderArns = append(input.SAMLProviderArns, idpArn)
+			} else if strings.Contains(idpArn, ":oidc-provider/") {
+				input.OIDCProviderArns = append(input.OIDCProviderArns, idpArn)
+			}
+		}
+
+		var err error
+		policy, _, err = t.Storager.GetTemplateObject(t.Bucket, t.Key, input)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to render principal trust policy: %s", err)
+		}
+	}
+	return policy, fmt.Sprintf("%x", sha256.Sum256([]byte(policy))), nil
+}
+
+// PermissionsBoundaryArn returns the ARN of the permissions boundary
+// of the principal role, in the account.
+// boundary may be a policy ARN, which is returned as-is, or the name of
+// a customer managed policy in the account. Returns an empty string if
+// boundary is empty.
+func PermissionsBoundaryArn(boundary string, accountID string) string {
+	if boundary == "" || strings.HasPrefix(boundary, "arn:") {
+		return boundary
+	}
+	return fmt.Sprintf("arn:aws:iam::%s:policy/%s", accountID, boundary)
+}
+
+// PolicyDocumentsEqual returns true if two IAM policy documents grant
+// the same permissions.
+//
+// Documents returned by IAM are URL-encoded, and IAM may reformat the
+// document, so documents are compared after decoding and normalizing:
+// lists with a single item are equal to the item, and empty
+// objects (eg. `"Condition": {}`) are ignored.
+func PolicyDocumentsEqual(a string, b string) (bool, error) {
+	normalA, err := normalizePolicyDocument(a)
+	if err != nil {
+		return false, err
+	}
+	normalB, err := normalizePolicyDocument(b)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(normalA, normalB), nil
+}
+
+func normalizePolicyDocument(document string) (interface{}, error) {
+	document = strings.TrimSpace(document)
+	if !strings.HasPrefix(document, "{") {
+		decoded, err := url.PathUnescape(document)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy document: %s", err)
+		}
+		document = decoded
+	}
+	var policy interface{}
+	err := json.Unmarshal([]byte(document), &policy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy document: %s", err)
+	}
+	return normalizePolicyValue(policy), nil
+}
+
+func normalizePolicyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		normal := map[string]interface{}{}
+		for key, item := range v {
+			item = normalizePolicyValue(item)
+			if obj, ok := item.(map[string]interface{}); ok && len(obj) == 0 {
+				continue
+			}
+			normal[key] = item
+		}
+		return normal
+	case []interface{}:
+		if len(v) == 1 {
+			return normalizePolicyValue(v[0])
+		}
+		normal := make([]interface{}, len(v))
+		for i, item := range v {
+			normal[i] = normalizePolicyValue(item)
+		}
+		return normal
+	}
+	return value
+}
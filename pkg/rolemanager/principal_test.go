@@ -0,0 +1,105 @@
+package rolemanager
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	commonMocks "github.com/Optum/dce/pkg/common/mocks"
+)
+
+func TestPolicyDocumentsEqual(t *testing.T) {
+	trustPolicy := PrincipalTrustPolicy("123456789012")
+
+	t.Run("should match documents reformatted by IAM", func(t *testing.T) {
+		fromIAM := url.PathEscape(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["arn:aws:iam::123456789012:root"]},"Action":"sts:AssumeRole"}]}`)
+
+		equal, err := PolicyDocumentsEqual(trustPolicy, fromIAM)
+		require.Nil(t, err)
+		require.True(t, equal)
+	})
+
+	t.Run("should not match changed documents", func(t *testing.T) {
+		changed := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":"*"},"Action":"sts:AssumeRole"}]}`
+
+		equal, err := PolicyDocumentsEqual(trustPolicy, changed)
+		require.Nil(t, err)
+		require.False(t, equal)
+	})
+
+	t.Run("should return an error for invalid documents", func(t *testing.T) {
+		_, err := PolicyDocumentsEqual(trustPolicy, "not a policy")
+		require.NotNil(t, err)
+	})
+}
+
+func TestPermissionsBoundaryArn(t *testing.T) {
+	require.Equal(t, "", PermissionsBoundaryArn("", "123456789012"))
+	require.Equal(t, "arn:aws:iam::123456789012:policy/DCEBoundary",
+		PermissionsBoundaryArn("DCEBoundary", "123456789012"))
+	require.Equal(t, "arn:aws:iam::aws:policy/PowerUserAccess",
+		PermissionsBoundaryArn("arn:aws:iam::aws:policy/PowerUserAccess", "123456789012"))
+}
+
+func TestFrozenPrincipalPolicy(t *testing.T) {
+	statements := func(policy string) []map[string]interface{} {
+		var document struct {
+			Statement []map[string]interface{}
+		}
+		require.Nil(t, json.Unmarshal([]byte(policy), &document))
+		return document.Statement
+	}
+
+	t.Run("should deny all actions", func(t *testing.T) {
+		denyAll := statements(FrozenPrincipalPolicy(FrozenPolicyDenyAll))
+		require.Len(t, denyAll, 1)
+		require.Equal(t, "Deny", denyAll[0]["Effect"])
+		require.Equal(t, "*", denyAll[0]["Action"])
+
+		require.Equal(t, FrozenPrincipalPolicy(FrozenPolicyDenyAll), FrozenPrincipalPolicy(""))
+	})
+
+	t.Run("should only allow read-only actions", func(t *testing.T) {
+		readOnly := statements(FrozenPrincipalPolicy(FrozenPolicyReadOnly))
+		require.Len(t, readOnly, 2)
+		require.Equal(t, "Allow", readOnly[0]["Effect"])
+		require.Contains(t, readOnly[0]["Action"], "s3:Get*")
+		require.Equal(t, "Deny", readOnly[1]["Effect"])
+		require.Equal(t, readOnly[0]["Action"], readOnly[1]["NotAction"])
+	})
+}
+
+func TestPrincipalTrustPolicyTemplate(t *testing.T) {
+	t.Run("should default to the master account trust policy", func(t *testing.T) {
+		var trustTemplate *PrincipalTrustPolicyTemplate
+		policy, hash, err := trustTemplate.Render("111111111111", "123456789012")
+		require.Nil(t, err)
+		require.Equal(t, PrincipalTrustPolicy("123456789012"), policy)
+		require.Len(t, hash, 64)
+
+		policy, _, err = NewPrincipalTrustPolicyTemplate(nil, "artifacts", "", "").Render("111111111111", "123456789012")
+		require.Nil(t, err)
+		require.Equal(t, PrincipalTrustPolicy("123456789012"), policy)
+	})
+
+	t.Run("should render the template, with the identity providers", func(t *testing.T) {
+		storager := &commonMocks.Storager{}
+		storager.On("GetTemplateObject", "artifacts", "trust_policy.tmpl", PrincipalTrustPolicyInput{
+			AccountID:        "111111111111",
+			MasterAccountID:  "123456789012",
+			IdpArns:          []string{"arn:aws:iam::111111111111:saml-provider/Okta", "arn:aws:iam::111111111111:oidc-provider/example.com"},
+			SAMLProviderArns: []string{"arn:aws:iam::111111111111:saml-provider/Okta"},
+			OIDCProviderArns: []string{"arn:aws:iam::111111111111:oidc-provider/example.com"},
+		}).Return("rendered policy", "etag", nil)
+		trustTemplate := NewPrincipalTrustPolicyTemplate(storager, "artifacts", "trust_policy.tmpl",
+			"arn:aws:iam::111111111111:saml-provider/Okta, arn:aws:iam::111111111111:oidc-provider/example.com")
+
+		policy, hash, err := trustTemplate.Render("111111111111", "123456789012")
+		require.Nil(t, err)
+		require.Equal(t, "rendered policy", policy)
+		// The hash is the hash of the rendered policy, not the template ETag
+		require.Equal(t, "4875b268c75fd646d4b8e59f9f7bd4984930b912b696bb7f9ec44d164ea443eb", hash)
+	})
+}
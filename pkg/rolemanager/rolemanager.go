@@ -38,6 +38,9 @@ type CreateRoleWithPolicyInput struct {
 	PolicyDocument           string
 	PolicyDescription        string
 	Tags                     []*iam.Tag
+	// ARN of a managed policy, to set as the permissions boundary of the role.
+	// The role has no permissions boundary, if empty.
+	PermissionsBoundary string
 	// If false, method will fail if the role/policy/attachment already exists.
 	// If true, these errors will be logged and ignored
 	IgnoreAlreadyExistsErrors bool
@@ -54,13 +57,17 @@ type CreateRoleWithPolicyOutput struct {
 // CreateRoleWithPolicy - Create a Role, and attach a policy to it
 func (rm *IAMRoleManager) CreateRoleWithPolicy(input *CreateRoleWithPolicyInput) (*CreateRoleWithPolicyOutput, error) {
 
-	createRoleRes, err := rm.IAM.CreateRole(&iam.CreateRoleInput{
+	createRoleInput := &iam.CreateRoleInput{
 		RoleName:                 aws.String(input.RoleName),
 		AssumeRolePolicyDocument: aws.String(input.AssumeRolePolicyDocument),
 		Description:              aws.String(input.RoleDescription),
 		MaxSessionDuration:       aws.Int64(input.MaxSessionDuration),
 		Tags:                     input.Tags,
-	})
+	}
+	if input.PermissionsBoundary != "" {
+		createRoleInput.PermissionsBoundary = aws.String(input.PermissionsBoundary)
+	}
+	createRoleRes, err := rm.IAM.CreateRole(createRoleInput)
 	var roleArn *string
 	if err != nil {
 		if isAWSAlreadyExistsError(err) && input.IgnoreAlreadyExistsErrors {
@@ -77,6 +84,17 @@ func (rm *IAMRoleManager) CreateRoleWithPolicy(input *CreateRoleWithPolicyInput)
 			return nil, err
 		}
 		roleArn = getRoleRes.Role.Arn
+
+		// The existing role may not have the permissions boundary
+		if input.PermissionsBoundary != "" {
+			_, err = rm.IAM.PutRolePermissionsBoundary(&iam.PutRolePermissionsBoundaryInput{
+				RoleName:            aws.String(input.RoleName),
+				PermissionsBoundary: aws.String(input.PermissionsBoundary),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
 	} else {
 		roleArn = createRoleRes.Role.Arn
 	}
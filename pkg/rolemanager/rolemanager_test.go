@@ -210,6 +210,73 @@ func TestCreateRoleWithPolicy(t *testing.T) {
 		require.NotNil(t, res)
 	})
 
+	t.Run("should set the permissions boundary of the role", func(t *testing.T) {
+		mockIAM := &mocks.IAM{}
+		roleManager := IAMRoleManager{
+			IAM: mockIAM,
+		}
+
+		// Mock iam.CreateRole(), with the permissions boundary
+		mockIAM.On("CreateRole", mock.MatchedBy(func(input *iam.CreateRoleInput) bool {
+			return aws.StringValue(input.PermissionsBoundary) == "arn:aws:iam::123456789012:policy/Boundary"
+		})).Return(&iam.CreateRoleOutput{
+			Role: &iam.Role{Arn: aws.String("arn:aws:iam::123456789012:role/TestRole")},
+		}, nil)
+		mockIAM.On("CreatePolicy", mock.Anything).
+			Return(&iam.CreatePolicyOutput{
+				Policy: &iam.Policy{Arn: aws.String("arn:aws:iam::123456789012:policy/TestPolicy")},
+			}, nil)
+		mockIAM.On("AttachRolePolicy", mock.Anything).
+			Return(&iam.AttachRolePolicyOutput{}, nil)
+
+		// Call roleManager.CreateRoleWithPolicy()
+		res, err := roleManager.CreateRoleWithPolicy(&CreateRoleWithPolicyInput{
+			RoleName:            "TestRole",
+			PolicyName:          "TestPolicy",
+			PermissionsBoundary: "arn:aws:iam::123456789012:policy/Boundary",
+		})
+		require.Nil(t, err)
+		require.NotNil(t, res)
+		mockIAM.AssertExpectations(t)
+	})
+
+	t.Run("should set the permissions boundary of an existing role", func(t *testing.T) {
+		mockIAM := &mocks.IAM{}
+		roleManager := IAMRoleManager{
+			IAM: mockIAM,
+		}
+
+		mockIAM.On("CreateRole", mock.Anything).
+			Return(nil, AwsAlreadyExistsError{})
+		mockIAM.On("GetRole", mock.Anything).
+			Return(&iam.GetRoleOutput{
+				Role: &iam.Role{Arn: aws.String("arn:aws:iam::123456789012:role/TestRole")},
+			}, nil)
+
+		// Mock iam.PutRolePermissionsBoundary()
+		mockIAM.On("PutRolePermissionsBoundary", &iam.PutRolePermissionsBoundaryInput{
+			RoleName:            aws.String("TestRole"),
+			PermissionsBoundary: aws.String("arn:aws:iam::123456789012:policy/Boundary"),
+		}).Return(&iam.PutRolePermissionsBoundaryOutput{}, nil)
+		mockIAM.On("CreatePolicy", mock.Anything).
+			Return(&iam.CreatePolicyOutput{
+				Policy: &iam.Policy{Arn: aws.String("arn:aws:iam::123456789012:policy/TestPolicy")},
+			}, nil)
+		mockIAM.On("AttachRolePolicy", mock.Anything).
+			Return(&iam.AttachRolePolicyOutput{}, nil)
+
+		// Call roleManager.CreateRoleWithPolicy()
+		res, err := roleManager.CreateRoleWithPolicy(&CreateRoleWithPolicyInput{
+			RoleName:                  "TestRole",
+			PolicyName:                "TestPolicy",
+			PermissionsBoundary:       "arn:aws:iam::123456789012:policy/Boundary",
+			IgnoreAlreadyExistsErrors: true,
+		})
+		require.Nil(t, err)
+		require.NotNil(t, res)
+		mockIAM.AssertExpectations(t)
+	})
+
 	t.Run("should return other AWS errors, if IgnoreAlreadyExistsErrors=true", func(t *testing.T) {
 		mockIAM := &mocks.IAM{}
 		roleManager := IAMRoleManager{
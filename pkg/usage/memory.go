@@ -0,0 +1,80 @@
+package usage
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Memory is an in-memory implementation of the usage Service,
+// for local runs and tests.
+type Memory struct {
+	mu sync.RWMutex
+	// Usage records are keyed by StartDate and PrincipalId,
+	// the same as the Usage table's hash and range keys
+	records map[memoryKey]Usage
+}
+
+type memoryKey struct {
+	StartDate   int64
+	PrincipalID string
+}
+
+// NewMemory creates an empty in-memory usage store
+func NewMemory() *Memory {
+	return &Memory{
+		records: map[memoryKey]Usage{},
+	}
+}
+
+// PutUsage adds a usage record, replacing any existing record
+// for the same start date and principal
+func (m *Memory) PutUsage(input Usage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records[memoryKey{input.StartDate, input.PrincipalID}] = input
+	return nil
+}
+
+// GetUsageByDateRange returns usage amount for all leases for input date range
+func (m *Memory) GetUsageByDateRange(startDate time.Time, endDate time.Time) ([]*Usage, error) {
+	usageStartDate := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	usageEndDate := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 0, time.UTC)
+
+	return m.find(func(record Usage) bool {
+		return record.StartDate >= usageStartDate.Unix() && record.StartDate <= usageEndDate.Unix()
+	}), nil
+}
+
+// GetUsageByPrincipal returns usage amount for all leases for input Principal,
+// from the start date until today
+func (m *Memory) GetUsageByPrincipal(startDate time.Time, principalID string) ([]*Usage, error) {
+	usageStartDate := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+
+	return m.find(func(record Usage) bool {
+		return record.PrincipalID == principalID && record.StartDate >= usageStartDate.Unix()
+	}), nil
+}
+
+// find returns all usage records matching the filter,
+// ordered by start date and principal
+func (m *Memory) find(filter func(record Usage) bool) []*Usage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	records := []*Usage{}
+	for _, record := range m.records {
+		if filter(record) {
+			r := record
+			records = append(records, &r)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].StartDate != records[j].StartDate {
+			return records[i].StartDate < records[j].StartDate
+		}
+		return records[i].PrincipalID < records[j].PrincipalID
+	})
+	return records
+}
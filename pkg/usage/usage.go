@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/currency"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -32,13 +33,30 @@ type DB struct {
 
 // Usage item
 type Usage struct {
-	PrincipalID  string  `json:"PrincipalId"`  // User Principal ID
-	AccountID    string  `json:"AccountId"`    // AWS Account ID
-	StartDate    int64   `json:"StartDate"`    // Usage start date Epoch Timestamp
-	EndDate      int64   `json:"EndDate"`      // Usage ends date Epoch Timestamp
-	CostAmount   float64 `json:"CostAmount"`   // Cost Amount for given period
-	CostCurrency string  `json:"CostCurrency"` // Cost currency
-	TimeToLive   int64   `json:"TimeToLive"`   // ttl attribute
+	PrincipalID           string  `json:"PrincipalId"`           // User Principal ID
+	AccountID             string  `json:"AccountId"`             // AWS Account ID
+	StartDate             int64   `json:"StartDate"`             // Usage start date Epoch Timestamp
+	EndDate               int64   `json:"EndDate"`               // Usage ends date Epoch Timestamp
+	CostAmount            float64 `json:"CostAmount"`            // Cost Amount for given period, as billed by AWS
+	CostCurrency          string  `json:"CostCurrency"`          // Cost currency, as billed by AWS
+	TimeToLive            int64   `json:"TimeToLive"`            // ttl attribute
+	ConvertedCostAmount   float64 `json:"ConvertedCostAmount"`   // Cost Amount, converted to the lease budget currency
+	ConvertedCostCurrency string  `json:"ConvertedCostCurrency"` // Lease budget currency
+}
+
+// AmountIn returns the cost amount of the usage in the given currency.
+// Uses the converted cost amount, if it is in that currency,
+// and otherwise converts the cost amount billed by AWS.
+func (u *Usage) AmountIn(code string, rates currency.RateProvider) (float64, error) {
+	if u.ConvertedCostCurrency == code {
+		return u.ConvertedCostAmount, nil
+	}
+	// Older usage records may not have a currency
+	costCurrency := u.CostCurrency
+	if costCurrency == "" {
+		costCurrency = currency.USD
+	}
+	return currency.Convert(rates, u.CostAmount, costCurrency, code)
 }
 
 // The Service interface includes all methods used by the DB struct to interact with
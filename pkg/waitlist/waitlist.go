@@ -0,0 +1,316 @@
+// Package waitlist manages Pending leases, which are waiting
+// for an account to become available.
+//
+// Pending leases are stored in the Lease table
+// with AccountId=db.PendingLeaseAccountID, and are fulfilled
+// in the order they were created, as accounts become Ready.
+package waitlist
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/Optum/dce/pkg/api/response"
+	"github.com/Optum/dce/pkg/common"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+)
+
+// Queue returns all Pending leases, ordered by their position in the waitlist
+// (oldest first).
+//
+// Pending leases which have passed their PendingExpiresOn
+// are not returned. They are marked as Inactive by Expire.
+func Queue(dbSvc db.DBer) ([]*db.Lease, error) {
+	leases, err := dbSvc.FindLeasesByStatus(db.Pending)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to find pending leases")
+	}
+
+	now := time.Now().Unix()
+	queue := []*db.Lease{}
+	for _, lease := range leases {
+		if IsTimedOut(lease, now) {
+			continue
+		}
+		queue = append(queue, lease)
+	}
+
+	sort.SliceStable(queue, func(i, j int) bool {
+		if queue[i].CreatedOn != queue[j].CreatedOn {
+			return queue[i].CreatedOn < queue[j].CreatedOn
+		}
+		return queue[i].ID < queue[j].ID
+	})
+
+	for i, lease := range queue {
+		lease.QueuePosition = i + 1
+	}
+
+	return queue, nil
+}
+
+// IsTimedOut returns true if the lease is Pending,
+// and has passed its PendingExpiresOn
+func IsTimedOut(lease *db.Lease, now int64) bool {
+	return lease.LeaseStatus == db.Pending &&
+		lease.PendingExpiresOn != 0 && lease.PendingExpiresOn <= now
+}
+
+// ExpireInput is the input for the Expire function
+type ExpireInput struct {
+	DbSvc                db.DBer
+	SnsSvc               common.Notificationer
+	LeaseRemovedTopicArn string
+}
+
+// Expire marks Pending leases which have passed their PendingExpiresOn
+// as Inactive, and publishes each timed out lease to the lease-removed topic,
+// so the principal is notified that no account became available.
+//
+// Returns the timed out leases.
+func Expire(input *ExpireInput) ([]*db.Lease, error) {
+	dbSvc := input.DbSvc
+	leases, err := dbSvc.FindLeasesByStatus(db.Pending)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to find pending leases")
+	}
+
+	now := time.Now().Unix()
+	expired := []*db.Lease{}
+	for _, pendingLease := range leases {
+		if !IsTimedOut(pendingLease, now) {
+			continue
+		}
+		log.Printf("Pending lease %s for %s timed out", pendingLease.ID, pendingLease.PrincipalID)
+		lease, err := dbSvc.TransitionLeaseStatus(pendingLease.AccountID, pendingLease.PrincipalID,
+			db.Pending, db.Inactive, db.LeasePendingTimeout)
+		if err != nil {
+			// Ignore StatusTransitionErrors
+			// (just means the lease was already cancelled or fulfilled)
+			if _, ok := err.(*db.StatusTransitionError); ok {
+				continue
+			}
+			return expired, errors.Wrapf(err, "Failed to time out pending lease %s", pendingLease.ID)
+		}
+		expired = append(expired, lease)
+
+		// The lease is already timed out, so publish errors are logged
+		message, err := common.PrepareSNSMessageJSON(response.CreateLeaseResponse(lease))
+		if err == nil {
+			_, err = input.SnsSvc.PublishMessage(aws.String(input.LeaseRemovedTopicArn), aws.String(message), true)
+		}
+		if err != nil {
+			log.Printf("ERROR: Failed to publish lease %s to SNS topic %s: %s",
+				lease.ID, input.LeaseRemovedTopicArn, err)
+		}
+	}
+	return expired, nil
+}
+
+// SetQueuePositions sets the QueuePosition of each of the Pending leases
+func SetQueuePositions(dbSvc db.DBer, leases ...*db.Lease) error {
+	hasPending := false
+	for _, lease := range leases {
+		if lease.LeaseStatus == db.Pending {
+			hasPending = true
+		}
+	}
+	if !hasPending {
+		return nil
+	}
+
+	queue, err := Queue(dbSvc)
+	if err != nil {
+		return err
+	}
+	positions := map[string]int{}
+	for _, pendingLease := range queue {
+		positions[pendingLease.ID] = pendingLease.QueuePosition
+	}
+
+	for _, lease := range leases {
+		if lease.LeaseStatus != db.Pending {
+			continue
+		}
+		position, ok := positions[lease.ID]
+		if !ok {
+			// The lease is no longer in the queue,
+			// because it timed out, and is waiting for Expire
+			lease.LeaseStatus = db.Inactive
+			lease.LeaseStatusReason = db.LeasePendingTimeout
+			continue
+		}
+		lease.QueuePosition = position
+	}
+
+	return nil
+}
+
+// FulfillInput is the input for the Fulfill function
+type FulfillInput struct {
+	DbSvc              db.DBer
+	SnsSvc             common.Notificationer
+	AccountID          string
+	LeaseAddedTopicArn string
+}
+
+// Fulfill leases a Ready account to the oldest Pending lease in the waitlist
+// whose pool and labels match the account,
+// and publishes the new lease to the lease-added topic.
+//
+// Returns the newly Active lease, or nil if there are no matching Pending leases.
+func Fulfill(input *FulfillInput) (*db.Lease, error) {
+	dbSvc := input.DbSvc
+
+	account, err := dbSvc.GetAccount(input.AccountID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get account %s", input.AccountID)
+	}
+	if account == nil {
+		return nil, errors.Errorf("Unable to fulfill pending leases: account %s does not exist", input.AccountID)
+	}
+
+	queue, err := Queue(dbSvc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pendingLease := range queue {
+		if !pendingLease.AccountSelector().Matches(account) {
+			continue
+		}
+
+		// Claim the pending lease, so it may not be
+		// fulfilled or cancelled by another process
+		_, err := dbSvc.TransitionLeaseStatus(pendingLease.AccountID, pendingLease.PrincipalID,
+			db.Pending, db.Inactive, db.LeaseFulfilled)
+		if err != nil {
+			if _, ok := err.(*db.StatusTransitionError); ok {
+				log.Printf("Pending lease %s is no longer pending, skipping", pendingLease.ID)
+				continue
+			}
+			return nil, errors.Wrapf(err, "Failed to claim pending lease %s", pendingLease.ID)
+		}
+
+		return fulfillLease(input, pendingLease)
+	}
+
+	log.Printf("No pending leases to fulfill for account %s", input.AccountID)
+	return nil, nil
+}
+
+func fulfillLease(input *FulfillInput, pendingLease *db.Lease) (*db.Lease, error) {
+	dbSvc := input.DbSvc
+
+	// Mark the account as Status=Leased
+	_, err := dbSvc.TransitionAccountStatus(input.AccountID, db.Ready, db.Leased)
+	if err != nil {
+		rollbackFulfill(dbSvc, input.AccountID, pendingLease, false, false)
+		return nil, errors.Wrapf(err, "Failed to lease account %s to pending lease %s",
+			input.AccountID, pendingLease.ID)
+	}
+
+	// Create the Active lease record, with the same ID as the pending lease.
+	// The lease is given the same duration that was originally requested.
+	now := time.Now().Unix()
+	lease, err := dbSvc.UpsertLease(db.Lease{
+		AccountID:                input.AccountID,
+		PrincipalID:              pendingLease.PrincipalID,
+		ID:                       pendingLease.ID,
+		LeaseStatus:              db.Active,
+		LeaseStatusReason:        db.LeaseActive,
+		BudgetAmount:             pendingLease.BudgetAmount,
+		BudgetCurrency:           pendingLease.BudgetCurrency,
+		BudgetNotificationEmails: pendingLease.BudgetNotificationEmails,
+		CreatedOn:                now,
+		LastModifiedOn:           now,
+		LeaseStatusModifiedOn:    now,
+		ExpiresOn:                now + (pendingLease.ExpiresOn - pendingLease.CreatedOn),
+		Metadata:                 pendingLease.Metadata,
+		AccountPool:              pendingLease.AccountPool,
+		AccountLabels:            pendingLease.AccountLabels,
+		PolicyProfile:            pendingLease.PolicyProfile,
+		PolicyStatements:         pendingLease.PolicyStatements,
+	})
+	if err != nil {
+		rollbackFulfill(dbSvc, input.AccountID, pendingLease, true, false)
+		return nil, errors.Wrapf(err, "Failed to create lease %s for %s @ %s",
+			pendingLease.ID, pendingLease.PrincipalID, input.AccountID)
+	}
+
+	// Remove the pending lease record, so the lease ID is unique
+	_, err = dbSvc.DeleteLease(pendingLease.AccountID, pendingLease.PrincipalID)
+	if err != nil {
+		rollbackFulfill(dbSvc, input.AccountID, pendingLease, true, true)
+		return nil, errors.Wrapf(err, "Failed to remove pending lease record %s", pendingLease.ID)
+	}
+
+	// Notify the principal that their lease is ready.
+	// The lease is already fulfilled, so publish errors are logged,
+	// rather than failing the reset which fulfilled the lease.
+	log.Printf("Fulfilled pending lease %s for %s @ %s", lease.ID, lease.PrincipalID, lease.AccountID)
+	message, err := common.PrepareSNSMessageJSON(response.CreateLeaseResponse(lease))
+	if err == nil {
+		_, err = input.SnsSvc.PublishMessage(aws.String(input.LeaseAddedTopicArn), aws.String(message), true)
+	}
+	if err != nil {
+		log.Printf("ERROR: Failed to publish lease %s to SNS topic %s: %s",
+			lease.ID, input.LeaseAddedTopicArn, err)
+	}
+
+	return lease, nil
+}
+
+// rollbackFulfill undoes the steps of fulfillLease which succeeded,
+// returning the pending lease to the waitlist. If the account was leased,
+// it is returned to Ready, and the Active lease record is removed.
+// Rollback errors are logged, so the original error may be returned.
+func rollbackFulfill(dbSvc db.DBer, accountID string, pendingLease *db.Lease, accountLeased bool, leaseCreated bool) {
+	if leaseCreated {
+		_, err := dbSvc.DeleteLease(accountID, pendingLease.PrincipalID)
+		if err != nil {
+			log.Printf("Failed to remove lease %s for %s @ %s: %s",
+				pendingLease.ID, pendingLease.PrincipalID, accountID, err)
+		}
+	}
+
+	if accountLeased {
+		_, err := dbSvc.TransitionAccountStatus(accountID, db.Leased, db.Ready)
+		if err != nil {
+			log.Printf("Failed to return account %s to Ready: %s", accountID, err)
+		}
+	}
+
+	// Return the lease to the waitlist
+	_, err := dbSvc.TransitionLeaseStatus(pendingLease.AccountID, pendingLease.PrincipalID,
+		db.Inactive, db.Pending, db.LeasePending)
+	if err != nil {
+		log.Printf("Failed to return lease %s to the waitlist: %s", pendingLease.ID, err)
+	}
+}
+
+// NewPendingLease creates a Pending lease record, to be fulfilled
+// when an account becomes available.
+// The lease is placed at the end of the waitlist.
+func NewPendingLease(dbSvc db.DBer, lease db.Lease, timeout time.Duration) (*db.Lease, error) {
+	now := time.Now()
+	lease.AccountID = db.PendingLeaseAccountID
+	lease.LeaseStatus = db.Pending
+	lease.LeaseStatusReason = db.LeasePending
+	lease.PendingExpiresOn = now.Add(timeout).Unix()
+
+	pendingLease, err := dbSvc.UpsertLease(lease)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to add lease for %s to the waitlist", lease.PrincipalID)
+	}
+
+	err = SetQueuePositions(dbSvc, pendingLease)
+	if err != nil {
+		return nil, err
+	}
+
+	return pendingLease, nil
+}
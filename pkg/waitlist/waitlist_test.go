@@ -0,0 +1,336 @@
+package waitlist
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	commonMocks "github.com/Optum/dce/pkg/common/mocks"
+	"github.com/Optum/dce/pkg/db"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+
+	t.Run("should order pending leases by creation date", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		addPendingLease(t, dbSvc, "second", 2000, time.Hour)
+		addPendingLease(t, dbSvc, "first", 1000, time.Hour)
+		addPendingLease(t, dbSvc, "third", 3000, time.Hour)
+
+		queue, err := Queue(dbSvc)
+		require.Nil(t, err)
+		require.Len(t, queue, 3)
+		for i, principalID := range []string{"first", "second", "third"} {
+			assert.Equal(t, principalID, queue[i].PrincipalID)
+			assert.Equal(t, i+1, queue[i].QueuePosition)
+		}
+	})
+
+	t.Run("should skip timed out pending leases", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		addPendingLease(t, dbSvc, "expired", 1000, -time.Hour)
+		addPendingLease(t, dbSvc, "pending", 2000, time.Hour)
+
+		queue, err := Queue(dbSvc)
+		require.Nil(t, err)
+		require.Len(t, queue, 1)
+		assert.Equal(t, "pending", queue[0].PrincipalID)
+		assert.Equal(t, 1, queue[0].QueuePosition)
+	})
+}
+
+func TestExpire(t *testing.T) {
+
+	t.Run("should time out expired pending leases, and notify the principal", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		snsSvc := &commonMocks.Notificationer{}
+		addPendingLease(t, dbSvc, "expired", 1000, -time.Hour)
+		addPendingLease(t, dbSvc, "pending", 2000, time.Hour)
+
+		snsSvc.On("PublishMessage", aws.String("lease-removed"),
+			mock.MatchedBy(func(message *string) bool {
+				return strings.Contains(*message, `"principalId\":\"expired\"`)
+			}), true).
+			Return(aws.String("message-id"), nil)
+
+		expired, err := Expire(&ExpireInput{
+			DbSvc:                dbSvc,
+			SnsSvc:               snsSvc,
+			LeaseRemovedTopicArn: "lease-removed",
+		})
+		require.Nil(t, err)
+		require.Len(t, expired, 1)
+		assert.Equal(t, "expired", expired[0].PrincipalID)
+
+		lease, err := dbSvc.GetLease(db.PendingLeaseAccountID, "expired")
+		require.Nil(t, err)
+		assert.Equal(t, db.Inactive, lease.LeaseStatus)
+		assert.Equal(t, db.LeasePendingTimeout, lease.LeaseStatusReason)
+
+		lease, err = dbSvc.GetLease(db.PendingLeaseAccountID, "pending")
+		require.Nil(t, err)
+		assert.Equal(t, db.Pending, lease.LeaseStatus)
+
+		snsSvc.AssertExpectations(t)
+		snsSvc.AssertNumberOfCalls(t, "PublishMessage", 1)
+	})
+
+	t.Run("should time out the lease if it fails to publish", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		snsSvc := &commonMocks.Notificationer{}
+		addPendingLease(t, dbSvc, "expired", 1000, -time.Hour)
+
+		snsSvc.On("PublishMessage", aws.String("lease-removed"), mock.Anything, true).
+			Return(nil, errors.New("publish failed"))
+
+		expired, err := Expire(&ExpireInput{
+			DbSvc:                dbSvc,
+			SnsSvc:               snsSvc,
+			LeaseRemovedTopicArn: "lease-removed",
+		})
+		require.Nil(t, err)
+		require.Len(t, expired, 1)
+
+		lease, err := dbSvc.GetLease(db.PendingLeaseAccountID, "expired")
+		require.Nil(t, err)
+		assert.Equal(t, db.Inactive, lease.LeaseStatus)
+	})
+}
+
+func TestSetQueuePositions(t *testing.T) {
+	dbSvc := db.NewMemoryDB(7)
+	addPendingLease(t, dbSvc, "first", 1000, time.Hour)
+	addPendingLease(t, dbSvc, "second", 2000, time.Hour)
+
+	leases := []*db.Lease{
+		{ID: "second-lease", LeaseStatus: db.Pending},
+		{ID: "active-lease", LeaseStatus: db.Active},
+	}
+	err := SetQueuePositions(dbSvc, leases...)
+	require.Nil(t, err)
+	assert.Equal(t, 2, leases[0].QueuePosition)
+	assert.Equal(t, 0, leases[1].QueuePosition)
+}
+
+func TestFulfill(t *testing.T) {
+
+	t.Run("should lease the account to the oldest pending lease", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		snsSvc := &commonMocks.Notificationer{}
+		require.Nil(t, dbSvc.PutAccount(db.Account{ID: "123", AccountStatus: db.Ready}))
+		addPendingLease(t, dbSvc, "first", 1000, time.Hour)
+		addPendingLease(t, dbSvc, "second", 2000, time.Hour)
+
+		snsSvc.On("PublishMessage", aws.String("lease-added"), mock.Anything, true).
+			Return(aws.String("message-id"), nil)
+
+		lease, err := Fulfill(&FulfillInput{
+			DbSvc:              dbSvc,
+			SnsSvc:             snsSvc,
+			AccountID:          "123",
+			LeaseAddedTopicArn: "lease-added",
+		})
+		require.Nil(t, err)
+		require.NotNil(t, lease)
+		assert.Equal(t, "123", lease.AccountID)
+		assert.Equal(t, "first", lease.PrincipalID)
+		assert.Equal(t, "first-lease", lease.ID)
+		assert.Equal(t, db.Active, lease.LeaseStatus)
+		// Should keep the requested lease duration
+		assert.InDelta(t, time.Now().Unix()+500, lease.ExpiresOn, 2)
+
+		account, err := dbSvc.GetAccount("123")
+		require.Nil(t, err)
+		assert.Equal(t, db.Leased, account.AccountStatus)
+
+		// Should remove the pending lease record
+		pendingLease, err := dbSvc.GetLease(db.PendingLeaseAccountID, "first")
+		require.Nil(t, err)
+		assert.Nil(t, pendingLease)
+		activeLease, err := dbSvc.GetLeaseByID("first-lease")
+		require.Nil(t, err)
+		assert.Equal(t, "123", activeLease.AccountID)
+
+		// The next lease should move up the waitlist
+		queue, err := Queue(dbSvc)
+		require.Nil(t, err)
+		require.Len(t, queue, 1)
+		assert.Equal(t, "second", queue[0].PrincipalID)
+		assert.Equal(t, 1, queue[0].QueuePosition)
+
+		snsSvc.AssertExpectations(t)
+	})
+
+	t.Run("should keep the lease if it fails to publish the lease", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		snsSvc := &commonMocks.Notificationer{}
+		require.Nil(t, dbSvc.PutAccount(db.Account{ID: "123", AccountStatus: db.Ready}))
+		addPendingLease(t, dbSvc, "first", 1000, time.Hour)
+
+		snsSvc.On("PublishMessage", aws.String("lease-added"), mock.Anything, true).
+			Return(nil, errors.New("publish failed"))
+
+		lease, err := Fulfill(&FulfillInput{
+			DbSvc:              dbSvc,
+			SnsSvc:             snsSvc,
+			AccountID:          "123",
+			LeaseAddedTopicArn: "lease-added",
+		})
+		require.Nil(t, err)
+		require.NotNil(t, lease)
+		assert.Equal(t, db.Active, lease.LeaseStatus)
+
+		account, err := dbSvc.GetAccount("123")
+		require.Nil(t, err)
+		assert.Equal(t, db.Leased, account.AccountStatus)
+	})
+
+	t.Run("should do nothing if there are no pending leases", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		snsSvc := &commonMocks.Notificationer{}
+		require.Nil(t, dbSvc.PutAccount(db.Account{ID: "123", AccountStatus: db.Ready}))
+
+		lease, err := Fulfill(&FulfillInput{
+			DbSvc:     dbSvc,
+			SnsSvc:    snsSvc,
+			AccountID: "123",
+		})
+		require.Nil(t, err)
+		assert.Nil(t, lease)
+
+		account, err := dbSvc.GetAccount("123")
+		require.Nil(t, err)
+		assert.Equal(t, db.Ready, account.AccountStatus)
+		snsSvc.AssertNotCalled(t, "PublishMessage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should skip pending leases which do not match the account pool", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		snsSvc := &commonMocks.Notificationer{}
+		require.Nil(t, dbSvc.PutAccount(db.Account{ID: "123", AccountStatus: db.Ready, Pool: "gpu"}))
+		_, err := dbSvc.UpsertLease(db.Lease{
+			ID:               "first-lease",
+			AccountID:        db.PendingLeaseAccountID,
+			PrincipalID:      "first",
+			LeaseStatus:      db.Pending,
+			CreatedOn:        1000,
+			ExpiresOn:        1500,
+			PendingExpiresOn: time.Now().Add(time.Hour).Unix(),
+			AccountPool:      db.DefaultAccountPool,
+		})
+		require.Nil(t, err)
+		addPendingLease(t, dbSvc, "second", 2000, time.Hour)
+
+		snsSvc.On("PublishMessage", mock.Anything, mock.Anything, true).
+			Return(aws.String("message-id"), nil)
+
+		lease, err := Fulfill(&FulfillInput{
+			DbSvc:     dbSvc,
+			SnsSvc:    snsSvc,
+			AccountID: "123",
+		})
+		require.Nil(t, err)
+		require.NotNil(t, lease)
+		assert.Equal(t, "second", lease.PrincipalID)
+
+		queue, err := Queue(dbSvc)
+		require.Nil(t, err)
+		require.Len(t, queue, 1)
+		assert.Equal(t, "first", queue[0].PrincipalID)
+	})
+
+	t.Run("should return the lease to the waitlist if the account is not ready", func(t *testing.T) {
+		dbSvc := db.NewMemoryDB(7)
+		snsSvc := &commonMocks.Notificationer{}
+		require.Nil(t, dbSvc.PutAccount(db.Account{ID: "123", AccountStatus: db.Leased}))
+		addPendingLease(t, dbSvc, "first", 1000, time.Hour)
+
+		_, err := Fulfill(&FulfillInput{
+			DbSvc:     dbSvc,
+			SnsSvc:    snsSvc,
+			AccountID: "123",
+		})
+		require.NotNil(t, err)
+		assert.IsType(t, &db.StatusTransitionError{}, errors.Cause(err))
+
+		lease, err := dbSvc.GetLease(db.PendingLeaseAccountID, "first")
+		require.Nil(t, err)
+		assert.Equal(t, db.Pending, lease.LeaseStatus)
+	})
+
+	for _, failingMethod := range []string{"UpsertLease", "DeleteLease"} {
+		t.Run("should roll back the account and lease if "+failingMethod+" fails", func(t *testing.T) {
+			memoryDB := db.NewMemoryDB(7)
+			dbSvc := &failingDB{DBer: memoryDB, failingMethod: failingMethod}
+			require.Nil(t, dbSvc.PutAccount(db.Account{ID: "123", AccountStatus: db.Ready}))
+			addPendingLease(t, dbSvc, "first", 1000, time.Hour)
+
+			_, err := Fulfill(&FulfillInput{
+				DbSvc:     dbSvc,
+				SnsSvc:    &commonMocks.Notificationer{},
+				AccountID: "123",
+			})
+			require.NotNil(t, err)
+
+			account, err := dbSvc.GetAccount("123")
+			require.Nil(t, err)
+			assert.Equal(t, db.Ready, account.AccountStatus)
+
+			lease, err := memoryDB.GetLease(db.PendingLeaseAccountID, "first")
+			require.Nil(t, err)
+			assert.Equal(t, db.Pending, lease.LeaseStatus)
+
+			lease, err = memoryDB.GetLease("123", "first")
+			require.Nil(t, err)
+			assert.Nil(t, lease)
+		})
+	}
+}
+
+// failingDB fails the first call to one of its methods,
+// after the call is made
+type failingDB struct {
+	db.DBer
+	failingMethod string
+	failed        bool
+}
+
+func (f *failingDB) fail(method string) bool {
+	if f.failingMethod == method && !f.failed {
+		f.failed = true
+		return true
+	}
+	return false
+}
+
+func (f *failingDB) UpsertLease(lease db.Lease) (*db.Lease, error) {
+	if lease.LeaseStatus == db.Active && f.fail("UpsertLease") {
+		return nil, errors.New("upsert failed")
+	}
+	return f.DBer.UpsertLease(lease)
+}
+
+func (f *failingDB) DeleteLease(accountID string, principalID string) (*db.Lease, error) {
+	if f.fail("DeleteLease") {
+		return nil, errors.New("delete failed")
+	}
+	return f.DBer.DeleteLease(accountID, principalID)
+}
+
+func addPendingLease(t *testing.T, dbSvc db.DBer, principalID string, createdOn int64, timeout time.Duration) {
+	_, err := dbSvc.UpsertLease(db.Lease{
+		ID:               principalID + "-lease",
+		AccountID:        db.PendingLeaseAccountID,
+		PrincipalID:      principalID,
+		LeaseStatus:      db.Pending,
+		CreatedOn:        createdOn,
+		ExpiresOn:        createdOn + 500,
+		PendingExpiresOn: time.Now().Add(timeout).Unix(),
+	})
+	require.Nil(t, err)
+}
@@ -697,6 +697,7 @@ func TestApi(t *testing.T) {
 				json: createAccountRequest{
 					ID:           accountID,
 					AdminRoleArn: adminRoleArn,
+					Labels:       map[string]string{"team": "acceptance"},
 				},
 				maxAttempts: 15,
 				f: func(r *testutil.R, apiResp *apiResponse) {
@@ -713,19 +714,26 @@ func TestApi(t *testing.T) {
 			require.Equal(t, expectedPrincipalRoleArn, postResJSON["principalRoleArn"])
 			require.True(t, postResJSON["lastModifiedOn"].(float64) > 1561518000)
 			require.True(t, postResJSON["createdOn"].(float64) > 1561518000)
+			// Accounts created without a pool are added to the default pool
+			require.Equal(t, db.DefaultAccountPool, postResJSON["pool"])
+			require.Equal(t, map[string]interface{}{"team": "acceptance"}, postResJSON["labels"])
 
 			// Check that the account is added to the DB
 			dbAccount, err := dbSvc.GetAccount(accountID)
 			require.Nil(t, err)
 			require.Equal(t, &db.Account{
-				ID:                  accountID,
-				AccountStatus:       "NotReady",
-				LastModifiedOn:      int64(postResJSON["lastModifiedOn"].(float64)),
-				CreatedOn:           int64(postResJSON["createdOn"].(float64)),
-				AdminRoleArn:        adminRoleArn,
-				PrincipalRoleArn:    expectedPrincipalRoleArn,
-				PrincipalPolicyHash: dbAccount.PrincipalPolicyHash,
+				ID:                       accountID,
+				AccountStatus:            "NotReady",
+				LastModifiedOn:           int64(postResJSON["lastModifiedOn"].(float64)),
+				CreatedOn:                int64(postResJSON["createdOn"].(float64)),
+				AdminRoleArn:             adminRoleArn,
+				PrincipalRoleArn:         expectedPrincipalRoleArn,
+				PrincipalPolicyHash:      dbAccount.PrincipalPolicyHash,
+				PrincipalTrustPolicyHash: dbAccount.PrincipalTrustPolicyHash,
+				Pool:                     db.DefaultAccountPool,
+				Labels:                   map[string]string{"team": "acceptance"},
 			}, dbAccount)
+			require.NotEmpty(t, dbAccount.PrincipalTrustPolicyHash)
 
 			// Check that the IAM Principal Role was created
 			// Lookup the principal IAM Role
@@ -762,6 +770,8 @@ func TestApi(t *testing.T) {
 						assert.Equal(r, expectedPrincipalRoleArn, getResJSON["principalRoleArn"])
 						assert.True(r, getResJSON["lastModifiedOn"].(float64) > 1561518000)
 						assert.True(r, getResJSON["createdOn"].(float64) > 1561518000)
+						assert.Equal(r, db.DefaultAccountPool, getResJSON["pool"])
+						assert.Equal(r, map[string]interface{}{"team": "acceptance"}, getResJSON["labels"])
 					},
 				})
 
@@ -1664,8 +1674,9 @@ type inputLeaseRequest struct {
 }
 
 type createAccountRequest struct {
-	ID           string `json:"id"`
-	AdminRoleArn string `json:"adminRoleArn"`
+	ID           string            `json:"id"`
+	AdminRoleArn string            `json:"adminRoleArn"`
+	Labels       map[string]string `json:"labels,omitempty"`
 }
 
 type apiRequestInput struct {